@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"xhub-agent/internal/config"
+)
+
+// runConfigCommand runs "config validate" (load and validate the config file), "config show"
+// (print the effective config after defaults, secrets redacted) or "config encrypt" (encrypt
+// the plaintext secrets of the config file)
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "validate" && args[0] != "show" && args[0] != "encrypt") {
+		fmt.Fprintln(stderr, "Usage: xhub-agent config validate|show|encrypt [-c /path/to/config.yml]")
+		return 2
+	}
+	command := args[0]
+	fs := flag.NewFlagSet("config "+command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("c", defaultConfigPath, "Config file path")
+	var keySource *string
+	if command == "encrypt" {
+		keySource = fs.String("key", "", "Key source: file:<path> or keyring:<name> (default: the config's secrets_key, or config.key next to it)")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if command == "encrypt" {
+		return runConfigEncrypt(*configPath, *keySource, stdout, stderr)
+	}
+
+	cfg, err := config.LoadFromEnv(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %s: %v\n", *configPath, err)
+		return 1
+	}
+
+	if command == "show" {
+		out, err := cfg.EffectiveYAML()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "# Effective config of %s (fingerprint %s)\n", *configPath, cfg.Fingerprint())
+		stdout.Write(out)
+		return 0
+	}
+
+	unknown, err := config.UnknownFields(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %s: %v\n", *configPath, err)
+		return 1
+	}
+	for _, msg := range unknown {
+		fmt.Fprintf(stdout, "Warning: %s: %s, ignored\n", *configPath, msg)
+	}
+	if migration := cfg.LegacyMigration(); migration != nil {
+		for _, line := range migration.Warning() {
+			fmt.Fprintf(stdout, "Warning: %s\n", line)
+		}
+	}
+	fmt.Fprintf(stdout, "Config %s is valid (fingerprint %s)\n", *configPath, cfg.Fingerprint())
+	return 0
+}
+
+// runConfigEncrypt encrypts the plaintext secrets of the config file, creating the key if needed
+func runConfigEncrypt(configPath, keySource string, stdout, stderr io.Writer) int {
+	result, err := config.EncryptFile(configPath, keySource)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %s: %v\n", configPath, err)
+		return 1
+	}
+	if result.KeyCreated {
+		fmt.Fprintf(stdout, "Created key %s, back it up: the secrets cannot be decrypted without it\n", result.Key)
+	}
+	if len(result.Fields) == 0 {
+		fmt.Fprintf(stdout, "No plaintext secret in %s, nothing to encrypt\n", configPath)
+		return 0
+	}
+	fmt.Fprintf(stdout, "Encrypted %s in %s with key %s\n", strings.Join(result.Fields, ", "), configPath, result.Key)
+	if _, err := config.LoadFromEnv(configPath); err != nil {
+		fmt.Fprintf(stderr, "Error: %s: %v\n", configPath, err)
+		return 1
+	}
+	return 0
+}
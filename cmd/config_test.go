@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `uuid: test-uuid
+xui_user: admin
+xui_pass: secret-password
+xhub_api_key: secret-key
+grpcServer: localhost
+rootPath: /panel
+port: 2053
+`
+
+// runConfig runs the config subcommand on a config file with content
+func runConfig(t *testing.T, command, content string) (int, string, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	var stdout, stderr bytes.Buffer
+	code := runConfigCommand([]string{command, "-c", path}, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestConfigCommand_Validate(t *testing.T) {
+	code, stdout, _ := runConfig(t, "validate", testConfig+"poll_intervall: 5\n")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "is valid (fingerprint ")
+	assert.Contains(t, stdout, "line 8: field poll_intervall not found, ignored")
+
+	// Malformed YAML points at the line
+	code, _, stderr := runConfig(t, "validate", testConfig+"port 2053\nlog_level: info\n")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "line 8: could not find expected ':'")
+
+	code, _, stderr = runConfig(t, "validate", testConfig+"poll_interval: soon\n")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "line 8: cannot unmarshal")
+
+	code, _, stderr = runConfig(t, "validate", "uuid: test-uuid\n")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "XUI username cannot be empty")
+}
+
+func TestConfigCommand_Show(t *testing.T) {
+	code, stdout, _ := runConfig(t, "show", testConfig)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "grpcPort: 9090")
+	assert.Contains(t, stdout, "poll_interval: 2")
+	assert.NotContains(t, stdout, "secret-password")
+	assert.NotContains(t, stdout, "secret-key")
+}
+
+func TestConfigCommand_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, runConfigCommand(nil, &stdout, &stderr))
+	assert.Equal(t, 2, runConfigCommand([]string{"edit"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage: xhub-agent config validate|show")
+}
+
+func TestConfigCommand_Encrypt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfig), 0600))
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runConfigCommand([]string{"encrypt", "-c", path}, &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "Created key file:"+filepath.Join(filepath.Dir(path), "config.key"))
+	assert.Contains(t, stdout.String(), "Encrypted xhub_api_key, xui_pass in "+path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret-password")
+
+	// The agent reads the encrypted config transparently
+	stdout.Reset()
+	assert.Equal(t, 0, runConfigCommand([]string{"validate", "-c", path}, &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "is valid")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/service"
+	"xhub-agent/pkg/logger"
+)
+
+// Exit codes of "health", following the Nagios plugin convention
+const (
+	healthExitOK       = 0
+	healthExitCritical = 2
+	healthExitUnknown  = 3
+)
+
+// checkHealth runs the health checks of a config (replaced in tests)
+var checkHealth = service.CheckHealth
+
+// runHealthCommand runs "health": it checks the 3x-ui login, /server/status, the gRPC
+// connection and the Hysteria2 config, prints the result as JSON and exits 0 (ok),
+// 2 (critical) or 3 (unknown, the checks could not run)
+func runHealthCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("c", defaultConfigPath, "Config file path")
+	logLevel := fs.String("log-level", "error", "Level of the logs written to stderr while checking")
+	if err := fs.Parse(args); err != nil {
+		return healthExitUnknown
+	}
+
+	report, code := healthReport(*configPath, *logLevel)
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(stderr, "Error: failed to encode the health report: %v\n", err)
+		return healthExitUnknown
+	}
+	return code
+}
+
+// healthReport loads the config and runs the checks, returning the report and exit code
+func healthReport(configPath, logLevel string) (*service.HealthReport, int) {
+	unknown := func(err error) (*service.HealthReport, int) {
+		return &service.HealthReport{Status: service.HealthUnknown, Error: err.Error()}, healthExitUnknown
+	}
+	cfg, err := config.LoadFromEnv(configPath)
+	if err != nil {
+		return unknown(fmt.Errorf("%s: %w", configPath, err))
+	}
+	log, err := logger.NewStdoutLogger(logLevel)
+	if err != nil {
+		return unknown(err)
+	}
+	defer log.Close()
+
+	report := checkHealth(cfg, log)
+	if !report.Healthy() {
+		return report, healthExitCritical
+	}
+	return report, healthExitOK
+}
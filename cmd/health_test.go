@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/service"
+	"xhub-agent/pkg/logger"
+)
+
+func TestRunHealthCommand(t *testing.T) {
+	original := checkHealth
+	defer func() { checkHealth = original }()
+
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte(testConfig), 0644))
+
+	run := func(status string) (int, map[string]any) {
+		checkHealth = func(cfg *config.Config, log *logger.Logger) *service.HealthReport {
+			return &service.HealthReport{Status: status, Checks: []service.HealthCheck{{Name: service.HealthGRPC, Status: service.HealthOK}}}
+		}
+		var stdout, stderr bytes.Buffer
+		code := runHealthCommand([]string{"-c", configPath}, &stdout, &stderr)
+		var printed map[string]any
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &printed))
+		return code, printed
+	}
+
+	code, printed := run(service.HealthOK)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "ok", printed["status"])
+
+	code, printed = run(service.HealthCritical)
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "critical", printed["status"])
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 3, runHealthCommand([]string{"-c", filepath.Join(t.TempDir(), "missing.yml")}, &stdout, &stderr))
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &printed))
+	assert.Equal(t, "unknown", printed["status"])
+	assert.Contains(t, printed["error"], "missing.yml")
+}
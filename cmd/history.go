@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/datadir"
+	"xhub-agent/internal/history"
+)
+
+// runHistoryCommand runs "history": it prints the status collected by the agent (history_hours)
+// as JSON or CSV, read from the running agent through local_api or, when the agent does not
+// answer, from the persisted history (history_persist)
+func runHistoryCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("c", defaultConfigPath, "Config file path")
+	logPath := fs.String("l", defaultLogPath, "Log file path, locates the data directory when data_dir is unset")
+	since := fs.String("since", "1h", "Start of the history: a duration back from now (1h, 30m) or an RFC 3339 time")
+	format := fs.String("format", "json", "Output format: json or csv")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "json" && *format != "csv" {
+		fmt.Fprintf(stderr, "Error: unknown format %q (expected json or csv)\n", *format)
+		return 2
+	}
+	start, err := history.ParseSince(*since, time.Now())
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	cfg, err := config.LoadFromEnv(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %s: %v\n", *configPath, err)
+		return 1
+	}
+	if cfg.HistoryHours <= 0 {
+		fmt.Fprintln(stderr, "Error: the status history is disabled, set history_hours")
+		return 1
+	}
+
+	samples, err := readHistory(cfg, *logPath, *since, start, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *format == "csv" {
+		err = history.WriteCSV(stdout, samples)
+	} else {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(samples)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// readHistory asks the running agent for its history since start, falling back to the
+// persisted history file
+func readHistory(cfg *config.Config, logPath, since string, start time.Time, stderr io.Writer) ([]history.Sample, error) {
+	var apiErr error
+	if cfg.LocalAPI != "" {
+		samples, err := fetchHistory(cfg.LocalAPI, since)
+		if err == nil {
+			return samples, nil
+		}
+		apiErr = fmt.Errorf("local API: %w", err)
+	} else {
+		apiErr = fmt.Errorf("local_api is not set")
+	}
+	if !cfg.HistoryPersist {
+		return nil, fmt.Errorf("cannot read the history of the agent: %w (and history_persist is off)", apiErr)
+	}
+
+	path := filepath.Join(datadir.Resolve(cfg.DataDir, logPath).Path(datadir.ArtifactHistory), history.FileName)
+	fmt.Fprintf(stderr, "Reading the persisted history %s (%v)\n", path, apiErr)
+	samples, err := history.ReadFile(path, start)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the persisted history: %w", err)
+	}
+	return samples, nil
+}
+
+// fetchHistory gets the history since from the local API at address (host:port or
+// unix:<path>)
+func fetchHistory(address, since string) ([]history.Sample, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	base := "http://" + address
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		client.Transport = &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		}}
+		base = "http://local-api"
+	}
+
+	resp, err := client.Get(base + "/history?since=" + url.QueryEscape(since))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiError)
+		return nil, fmt.Errorf("%s: %s", resp.Status, apiError.Error)
+	}
+
+	var samples []history.Sample
+	if err := json.NewDecoder(resp.Body).Decode(&samples); err != nil {
+		return nil, fmt.Errorf("invalid history response: %w", err)
+	}
+	return samples, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/history"
+	"xhub-agent/internal/monitor"
+)
+
+// writeHistoryConfig writes a config with the persisted history in dataDir, holding samples
+// taken 90 and 30 minutes ago
+func writeHistoryConfig(t *testing.T, extra string) string {
+	t.Helper()
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	path := filepath.Join(dataDir, "history", history.FileName)
+	buffer, err := history.Open(path, 24*time.Hour, 10)
+	require.NoError(t, err)
+	for i, age := range []time.Duration{90 * time.Minute, 30 * time.Minute} {
+		require.NoError(t, buffer.Add(history.Sample{
+			Time:   time.Now().Add(-age),
+			Source: "panel",
+			Status: &monitor.ServerStatusData{CPU: float64(i + 1)},
+		}))
+	}
+	require.NoError(t, buffer.Close())
+
+	configPath := filepath.Join(dir, "config.yml")
+	content := testConfig + "history_hours: 24\ndata_dir: " + dataDir + "\n" + extra
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0600))
+	return configPath
+}
+
+func runHistory(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := runHistoryCommand(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestHistoryCommand_Persisted(t *testing.T) {
+	configPath := writeHistoryConfig(t, "history_persist: true\n")
+
+	code, stdout, stderr := runHistory("-c", configPath)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stderr, "local_api is not set")
+	var samples []history.Sample
+	require.NoError(t, json.Unmarshal([]byte(stdout), &samples))
+	require.Len(t, samples, 1, "only the last hour by default")
+	assert.Equal(t, 2.0, samples[0].Status.CPU)
+
+	code, stdout, _ = runHistory("-c", configPath, "-since", "2h", "-format", "csv")
+	assert.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "time,source,cpu,"))
+	assert.Contains(t, lines[1], ",panel,1,")
+}
+
+func TestHistoryCommand_Errors(t *testing.T) {
+	configPath := writeHistoryConfig(t, "")
+
+	code, _, stderr := runHistory("-c", configPath, "-format", "xml")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown format "xml"`)
+
+	code, _, stderr = runHistory("-c", configPath, "-since", "yesterday")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `invalid since "yesterday"`)
+
+	// Neither the local API nor the persisted history is available
+	code, _, stderr = runHistory("-c", configPath)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "history_persist is off")
+}
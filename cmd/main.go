@@ -3,12 +3,15 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
+	"time"
 
+	"xhub-agent/internal/config"
 	"xhub-agent/internal/service"
+	"xhub-agent/internal/version"
 )
 
 const (
@@ -17,18 +20,41 @@ const (
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "migrate-config" {
+		os.Exit(runMigrateConfig(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "health" {
+		// The logs mirrored to the console go to stderr, stdout holds only the JSON result
+		result := os.Stdout
+		os.Stdout = os.Stderr
+		os.Exit(runHealthCommand(os.Args[2:], result, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		os.Exit(runHistoryCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && serviceCommands[os.Args[1]] {
+		os.Exit(runServiceCommand(os.Args[1], os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Command line arguments
 	var (
-		configPath = flag.String("c", defaultConfigPath, "Config file path")
-		logPath    = flag.String("l", defaultLogPath, "Log file path")
-		version    = flag.Bool("v", false, "Show version information")
-		help       = flag.Bool("h", false, "Show help information")
+		configPath  = flag.String("c", defaultConfigPath, "Config file path")
+		logPath     = flag.String("l", defaultLogPath, "Log file path")
+		showVersion = flag.Bool("v", false, "Show version information")
+		help        = flag.Bool("h", false, "Show help information")
+		quiet       = flag.Bool("q", false, "Quiet mode, suppress decorative startup messages on stdout")
+		once        = flag.Bool("once", false, "Run a single cycle, report it, print the payloads as JSON and exit")
+		dryRun      = flag.Bool("dry-run", false, "Like -once, without reporting anything to xhub")
 	)
 	flag.Parse()
 
 	// Show version information
-	if *version {
-		fmt.Println("xhub-agent v1.0.0")
+	if *showVersion {
+		fmt.Println("xhub-agent " + version.Version)
 		fmt.Println("A monitoring agent for 3x-ui servers")
 		return
 	}
@@ -45,21 +71,45 @@ func main() {
 		fmt.Println()
 		fmt.Println("Examples:")
 		fmt.Println("  xhub-agent -c /path/to/config.yml -l /path/to/agent.log")
+		fmt.Println("  xhub-agent -q -c /path/to/config.yml")
+		fmt.Println("  xhub-agent -dry-run -c /path/to/config.yml   Check the config and 3x-ui access after install")
+		fmt.Println()
+		fmt.Println("Signals:")
+		fmt.Println("  SIGHUP   Reload the config file (changed fields are logged; poll interval,")
+		fmt.Println("           log level and format and gRPC target are applied without a restart)")
+		fmt.Println("  SIGUSR1  Toggle debug logging (back to log_level on the next SIGUSR1)")
+		fmt.Println("  SIGUSR2  Run the pipeline self-test now (result is logged and reported)")
+		fmt.Println()
+		fmt.Println("Commands:")
+		fmt.Println("  xhub-agent migrate-config -c /path/to/config.yml   Rewrite legacy reportUrl to grpcServer/grpcPort")
+		fmt.Println("  xhub-agent config validate -c /path/to/config.yml       Check the config file and report errors")
+		fmt.Println("  xhub-agent config show -c /path/to/config.yml           Print the effective config, secrets redacted")
+		fmt.Println("  xhub-agent config encrypt -c /path/to/config.yml [-key file:<path>|keyring:<name>]")
+		fmt.Println("                                                          Encrypt xui_pass, xhub_api_key and other secrets")
+		fmt.Println("  xhub-agent health -c /path/to/config.yml                Check 3x-ui, xhub and Hysteria2, print JSON,")
+		fmt.Println("                                                          exit 0 (ok), 2 (critical) or 3 (unknown)")
+		fmt.Println("  xhub-agent history -c /path/to/config.yml [-since 1h] [-format json|csv]")
+		fmt.Println("                                                          Print the status history (history_hours)")
+		fmt.Println("  xhub-agent install [-dir /opt/xhub-agent] [-no-start]   Install the binary and the systemd service")
+		fmt.Println("  xhub-agent uninstall [-dir /opt/xhub-agent] [-purge]    Remove the service and the binary")
+		fmt.Println("  xhub-agent start | stop | status                        Control the systemd service")
 		return
 	}
 
-	// Check if config file exists
-	if _, err := os.Stat(*configPath); os.IsNotExist(err) {
+	// Check if config file exists, unless the options are set by XHUB_AGENT_* variables
+	if _, err := os.Stat(*configPath); os.IsNotExist(err) && !config.EnvConfigured() {
 		fmt.Fprintf(os.Stderr, "Error: config file does not exist: %s\n", *configPath)
-		fmt.Fprintf(os.Stderr, "Please ensure the config file exists, or use -c parameter to specify the correct config file path\n")
+		fmt.Fprintf(os.Stderr, "Please ensure the config file exists, use -c parameter to specify the correct config file path, or set the options with %s* environment variables\n", config.EnvPrefix)
 		os.Exit(1)
 	}
 
-	// Ensure log directory exists
-	logDir := filepath.Dir(*logPath)
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to create log directory: %v\n", err)
-		os.Exit(1)
+	// The payloads of -once and -dry-run are the only output on stdout: the logs the agent
+	// mirrors to the console go to stderr instead
+	payloads := os.Stdout
+	if *once || *dryRun {
+		os.Stdout = os.Stderr
+	} else {
+		printStartupBanner(os.Stdout, *quiet, *configPath, *logPath)
 	}
 
 	// Create Agent service
@@ -68,19 +118,156 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Error: failed to create Agent service: %v\n", err)
 		os.Exit(1)
 	}
-	defer agent.Close()
+	if *once || *dryRun {
+		os.Exit(runOnce(agent, !*dryRun, payloads, os.Stderr))
+	}
 
-	// Setup signal handling
+	// Setup signal handling (SIGHUP reloads the config file, SIGUSR1 toggles debug logging,
+	// SIGUSR2 runs the self-test)
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2)
 
 	// Start Agent service (in goroutine)
-	go agent.Start()
+	finished := startAgent(agent)
 
 	// Wait for signal
 	sig := <-sigChan
-	fmt.Printf("Received signal %v, gracefully shutting down...\n", sig)
+	for sig == syscall.SIGHUP || sig == syscall.SIGUSR1 || sig == syscall.SIGUSR2 {
+		switch sig {
+		case syscall.SIGHUP:
+			agent, finished = reloadAgent(agent, finished, *configPath, *logPath)
+		case syscall.SIGUSR1:
+			agent.ToggleDebugLogging("SIGUSR1")
+		default:
+			go agent.RunSelfTest()
+		}
+		sig = <-sigChan
+	}
+	agent.Logger().Infof("Received signal %v, gracefully shutting down...", sig)
+	agent.SetShutdownReason(fmt.Sprintf("signal %v", sig))
+
+	// Stop service, force exit if a wedged cycle keeps it from finishing in time
+	timeout := agent.ShutdownTimeout()
+	if !shutdownAgent(agent, finished, timeout) {
+		agent.Logger().Warnf("⚠️  Shutdown did not finish within %s, forcing exit", timeout)
+		fmt.Fprintf(os.Stderr, "Warning: shutdown did not finish within %s, forcing exit\n", timeout)
+		os.Exit(1)
+	}
+}
+
+// startAgent runs the agent in a goroutine; the returned channel is closed when Start returns
+func startAgent(agent *service.AgentService) chan struct{} {
+	finished := make(chan struct{})
+	go func() {
+		agent.Start()
+		close(finished)
+	}()
+	return finished
+}
+
+// reloadAgent re-reads the config file and logs which fields changed (secrets redacted).
+// Changes the running agent can apply live (poll interval, log level and format, gRPC target) are
+// applied in place; any other change replaces the running agent by one built from the new
+// config. An invalid or unchanged config keeps the current agent.
+func reloadAgent(agent *service.AgentService, finished chan struct{}, configPath, logPath string) (*service.AgentService, chan struct{}) {
+	log := agent.Logger()
+	next, err := config.LoadFromEnv(configPath)
+	if err != nil {
+		log.Errorf("❌ Config reload failed, keeping the current config: %v", err)
+		return agent, finished
+	}
+
+	current := agent.Config()
+	changes := config.Diff(current, next)
+	if len(changes) == 0 {
+		log.Infof("🔄 Config reloaded, no changes (fingerprint %s)", current.Fingerprint())
+		return agent, finished
+	}
+	log.Infof("🔄 Config reloaded, fingerprint %s -> %s, %d field(s) changed:",
+		current.Fingerprint(), next.Fingerprint(), len(changes))
+	for _, change := range changes {
+		log.Infof("   %s", change)
+	}
+	if agent.Reload(next) {
+		log.Infof("🔄 Config changes applied without restarting the agent")
+		return agent, finished
+	}
+
+	agent.SetShutdownReason("config reload")
+	timeout := agent.ShutdownTimeout()
+	if !shutdownAgent(agent, finished, timeout) {
+		fmt.Fprintf(os.Stderr, "Warning: shutdown for reload did not finish within %s, forcing exit\n", timeout)
+		os.Exit(1)
+	}
+
+	restarted, err := service.NewAgentService(configPath, logPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to restart Agent service with the reloaded config: %v\n", err)
+		os.Exit(1)
+	}
+	return restarted, startAgent(restarted)
+}
+
+// stoppableAgent is the part of the Agent service driven on shutdown
+type stoppableAgent interface {
+	Stop()
+	Close()
+}
+
+// shutdownAgent stops the agent, waits for its work loop to finish (finished is closed
+// when Start returns) and closes it. It returns false if that takes longer than timeout.
+func shutdownAgent(agent stoppableAgent, finished <-chan struct{}, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		agent.Stop()
+		<-finished
+		agent.Close()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// printStartupBanner prints the decorative startup messages unless quiet mode is enabled
+func printStartupBanner(w io.Writer, quiet bool, configPath, logPath string) {
+	if quiet {
+		return
+	}
+	fmt.Fprintf(w, "xhub-agent %s - 3x-ui monitoring agent\n", version.Version)
+	fmt.Fprintf(w, "Config file: %s\n", configPath)
+	fmt.Fprintf(w, "Log file: %s\n", logPath)
+}
+
+// runMigrateConfig rewrites a legacy reportUrl config to grpcServer/grpcPort, keeping a backup
+func runMigrateConfig(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate-config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("c", defaultConfigPath, "Config file path")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	backupPath, migration, err := config.MigrateLegacyFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if migration == nil {
+		fmt.Fprintf(stdout, "No legacy reportUrl found in %s, nothing to migrate\n", *configPath)
+		return 0
+	}
 
-	// Stop service
-	agent.Stop()
+	fmt.Fprintf(stdout, "Migrated %s\n", *configPath)
+	fmt.Fprintf(stdout, "  reportUrl: %s\n", migration.ReportURL)
+	fmt.Fprintf(stdout, "  -> grpcServer: %s\n", migration.GRPCServer)
+	fmt.Fprintf(stdout, "  -> grpcPort: %d\n", migration.GRPCPort)
+	fmt.Fprintf(stdout, "Backup saved to %s\n", backupPath)
+	return 0
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintStartupBanner(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		var buf bytes.Buffer
+		printStartupBanner(&buf, false, "/tmp/config.yml", "/tmp/agent.log")
+		assert.Contains(t, buf.String(), "xhub-agent v1.0.0")
+		assert.Contains(t, buf.String(), "/tmp/config.yml")
+		assert.Contains(t, buf.String(), "/tmp/agent.log")
+	})
+
+	t.Run("Quiet", func(t *testing.T) {
+		var buf bytes.Buffer
+		printStartupBanner(&buf, true, "/tmp/config.yml", "/tmp/agent.log")
+		assert.Empty(t, buf.String(), "quiet mode should suppress decorative startup messages")
+	})
+}
+
+// fakeAgent simulates the agent service with a configurable Close delay
+type fakeAgent struct {
+	closeDelay time.Duration
+	stopped    chan struct{}
+	closed     chan struct{}
+}
+
+func newFakeAgent(closeDelay time.Duration) *fakeAgent {
+	return &fakeAgent{closeDelay: closeDelay, stopped: make(chan struct{}), closed: make(chan struct{})}
+}
+
+func (f *fakeAgent) Stop() { close(f.stopped) }
+
+func (f *fakeAgent) Close() {
+	time.Sleep(f.closeDelay)
+	close(f.closed)
+}
+
+func TestShutdownAgent(t *testing.T) {
+	t.Run("Clean", func(t *testing.T) {
+		agent := newFakeAgent(0)
+		finished := make(chan struct{})
+		go func() {
+			// The work loop finishes once stopped
+			<-agent.stopped
+			close(finished)
+		}()
+
+		assert.True(t, shutdownAgent(agent, finished, time.Second))
+		select {
+		case <-agent.closed:
+		default:
+			t.Fatal("agent should be closed after a clean shutdown")
+		}
+	})
+
+	t.Run("SlowCloseForcesExit", func(t *testing.T) {
+		agent := newFakeAgent(time.Hour)
+		finished := make(chan struct{})
+		close(finished)
+
+		start := time.Now()
+		assert.False(t, shutdownAgent(agent, finished, 50*time.Millisecond))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("WedgedCycleForcesExit", func(t *testing.T) {
+		agent := newFakeAgent(0)
+		finished := make(chan struct{}) // never closed: the work loop is stuck
+
+		assert.False(t, shutdownAgent(agent, finished, 50*time.Millisecond))
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"xhub-agent/internal/service"
+)
+
+// onceAgent is the part of the Agent service driven by -once and -dry-run
+type onceAgent interface {
+	RunOnce(send bool) *service.OnceResult
+	Close()
+}
+
+// runOnce runs a single collection cycle (-once reports it, -dry-run only collects), prints
+// the payloads as JSON to stdout and returns 1 if any stage failed
+func runOnce(agent onceAgent, send bool, stdout, stderr io.Writer) int {
+	result := agent.RunOnce(send)
+	agent.Close()
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		fmt.Fprintf(stderr, "Error: failed to encode the payloads: %v\n", err)
+		return 1
+	}
+	for _, failure := range result.Failures {
+		fmt.Fprintf(stderr, "Error: %s: %s\n", failure.Stage, failure.Error)
+	}
+	if !result.Passed() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/service"
+)
+
+// fakeOnceAgent returns a fixed one-shot result
+type fakeOnceAgent struct {
+	result *service.OnceResult
+	send   bool
+	closed bool
+}
+
+func (f *fakeOnceAgent) RunOnce(send bool) *service.OnceResult {
+	f.send = send
+	f.result.Sent = send
+	return f.result
+}
+
+func (f *fakeOnceAgent) Close() { f.closed = true }
+
+func TestRunOnce(t *testing.T) {
+	t.Run("Passed", func(t *testing.T) {
+		agent := &fakeOnceAgent{result: &service.OnceResult{OnlineUsers: []string{"alice@example.com"}}}
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, runOnce(agent, false, &stdout, &stderr))
+		assert.False(t, agent.send)
+		assert.True(t, agent.closed)
+		assert.Empty(t, stderr.String())
+
+		var printed map[string]any
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &printed), "stdout holds only the payloads")
+		assert.Equal(t, false, printed["sent"])
+		assert.Equal(t, []any{"alice@example.com"}, printed["online_users"])
+	})
+
+	t.Run("FailedStage", func(t *testing.T) {
+		agent := &fakeOnceAgent{result: &service.OnceResult{Failures: []service.StageFailure{{Stage: service.StageAuth, Error: "login failed"}}}}
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 1, runOnce(agent, true, &stdout, &stderr))
+		assert.True(t, agent.send)
+		assert.Contains(t, stdout.String(), `"stage": "auth"`)
+		assert.Equal(t, "Error: auth: login failed\n", stderr.String())
+	})
+}
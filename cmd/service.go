@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const (
+	serviceName       = "xhub-agent"
+	defaultInstallDir = "/opt/xhub-agent"
+	defaultUnitDir    = "/etc/systemd/system"
+)
+
+// serviceCommands are the subcommands managing the systemd service
+var serviceCommands = map[string]bool{"install": true, "uninstall": true, "start": true, "stop": true, "status": true}
+
+// serviceManager installs the agent as a systemd service and controls it with systemctl
+type serviceManager struct {
+	installDir string // Binary, config.yml and logs/
+	unitDir    string // systemd unit directory
+	executable string // Binary copied by install
+	systemctl  func(stdout, stderr io.Writer, args ...string) error
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+// runSystemctl runs systemctl with args
+func runSystemctl(stdout, stderr io.Writer, args ...string) error {
+	cmd := exec.Command("systemctl", args...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	return cmd.Run()
+}
+
+// runServiceCommand runs the service subcommand command (install, uninstall, start, stop, status)
+func runServiceCommand(command string, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	installDir := fs.String("dir", defaultInstallDir, "Installation directory")
+	var noStart, purge *bool
+	switch command {
+	case "install":
+		noStart = fs.Bool("no-start", false, "Install and enable the service without starting it")
+	case "uninstall":
+		purge = fs.Bool("purge", false, "Also remove config.yml, logs and data in the installation directory")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if command != "status" && os.Geteuid() != 0 {
+		fmt.Fprintf(stderr, "Error: xhub-agent %s needs to be run as root\n", command)
+		return 1
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to locate the xhub-agent binary: %v\n", err)
+		return 1
+	}
+	m := &serviceManager{
+		installDir: *installDir,
+		unitDir:    defaultUnitDir,
+		executable: executable,
+		systemctl:  runSystemctl,
+		stdout:     stdout,
+		stderr:     stderr,
+	}
+
+	switch command {
+	case "install":
+		err = m.install(!*noStart)
+	case "uninstall":
+		err = m.uninstall(*purge)
+	case "start", "stop":
+		err = m.systemctl(stdout, stderr, command, serviceName)
+	case "status":
+		err = m.systemctl(stdout, stderr, "status", "--no-pager", serviceName)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 1
+	}
+	return 0
+}
+
+// unitPath returns the path of the systemd unit file
+func (m *serviceManager) unitPath() string {
+	return filepath.Join(m.unitDir, serviceName+".service")
+}
+
+// unit returns the systemd unit running the installed binary
+func (m *serviceManager) unit() string {
+	return fmt.Sprintf(`[Unit]
+Description=XHub Agent
+After=network.target
+
+[Service]
+Type=simple
+User=root
+ExecStart=%[1]s -c %[2]s -l %[3]s
+ExecReload=/bin/kill -HUP $MAINPID
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`, filepath.Join(m.installDir, serviceName), filepath.Join(m.installDir, "config.yml"), filepath.Join(m.installDir, "logs", "agent.log"))
+}
+
+// install copies the binary, creates the config directory, writes the unit and enables the
+// service. It is started (restarted when already running) if start is set and the config exists.
+func (m *serviceManager) install(start bool) error {
+	if err := os.MkdirAll(filepath.Join(m.installDir, "logs"), 0755); err != nil {
+		return fmt.Errorf("failed to create installation directory: %w", err)
+	}
+
+	binary := filepath.Join(m.installDir, serviceName)
+	if err := copyExecutable(m.executable, binary); err != nil {
+		return err
+	}
+	fmt.Fprintf(m.stdout, "Installed binary to %s\n", binary)
+
+	if err := os.WriteFile(m.unitPath(), []byte(m.unit()), 0644); err != nil {
+		return fmt.Errorf("failed to write systemd unit: %w", err)
+	}
+	fmt.Fprintf(m.stdout, "Wrote systemd unit %s\n", m.unitPath())
+	if err := m.systemctl(m.stdout, m.stderr, "daemon-reload"); err != nil {
+		return fmt.Errorf("systemctl daemon-reload failed: %w", err)
+	}
+	if err := m.systemctl(m.stdout, m.stderr, "enable", serviceName); err != nil {
+		return fmt.Errorf("systemctl enable failed: %w", err)
+	}
+
+	configPath := filepath.Join(m.installDir, "config.yml")
+	if _, err := os.Stat(configPath); err != nil {
+		fmt.Fprintf(m.stdout, "Config file %s does not exist yet, create it (see config.example.yml) and run: xhub-agent start\n", configPath)
+		return nil
+	}
+	if !start {
+		fmt.Fprintf(m.stdout, "Service enabled, start it with: xhub-agent start\n")
+		return nil
+	}
+	// restart also starts a stopped service and picks up the new binary of a running one
+	if err := m.systemctl(m.stdout, m.stderr, "restart", serviceName); err != nil {
+		return fmt.Errorf("systemctl restart failed: %w", err)
+	}
+	fmt.Fprintf(m.stdout, "Service %s started, check it with: xhub-agent status\n", serviceName)
+	return nil
+}
+
+// uninstall stops and disables the service and removes the unit and the binary. The config,
+// logs and data are kept unless purge is set.
+func (m *serviceManager) uninstall(purge bool) error {
+	if _, err := os.Stat(m.unitPath()); err == nil {
+		// A stopped or disabled service is fine
+		m.systemctl(io.Discard, io.Discard, "stop", serviceName)
+		m.systemctl(io.Discard, io.Discard, "disable", serviceName)
+		if err := os.Remove(m.unitPath()); err != nil {
+			return fmt.Errorf("failed to remove systemd unit: %w", err)
+		}
+		if err := m.systemctl(m.stdout, m.stderr, "daemon-reload"); err != nil {
+			return fmt.Errorf("systemctl daemon-reload failed: %w", err)
+		}
+		fmt.Fprintf(m.stdout, "Removed service %s\n", serviceName)
+	}
+
+	if purge {
+		if err := os.RemoveAll(m.installDir); err != nil {
+			return fmt.Errorf("failed to remove installation directory: %w", err)
+		}
+		fmt.Fprintf(m.stdout, "Removed %s\n", m.installDir)
+		return nil
+	}
+	binary := filepath.Join(m.installDir, serviceName)
+	if err := os.Remove(binary); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove binary: %w", err)
+	}
+	fmt.Fprintf(m.stdout, "Removed %s, config and logs kept in %s (remove them with -purge)\n", binary, m.installDir)
+	return nil
+}
+
+// copyExecutable copies the binary src to dst through a temporary file, so a running
+// binary at dst is replaced rather than overwritten. Copying a binary onto itself is a no-op.
+func copyExecutable(src, dst string) error {
+	if srcInfo, err := os.Stat(src); err == nil {
+		if dstInfo, err := os.Stat(dst); err == nil && os.SameFile(srcInfo, dstInfo) {
+			return nil
+		}
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to read binary: %w", err)
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	if err := tmp.Chmod(0755); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	return nil
+}
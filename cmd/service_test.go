@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServiceManager returns a manager installing into temporary directories and
+// recording its systemctl calls
+func newTestServiceManager(t *testing.T) (*serviceManager, *[]string) {
+	t.Helper()
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "build", "xhub-agent")
+	require.NoError(t, os.MkdirAll(filepath.Dir(executable), 0755))
+	require.NoError(t, os.WriteFile(executable, []byte("binary"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "systemd"), 0755))
+
+	var calls []string
+	return &serviceManager{
+		installDir: filepath.Join(dir, "opt", "xhub-agent"),
+		unitDir:    filepath.Join(dir, "systemd"),
+		executable: executable,
+		systemctl: func(stdout, stderr io.Writer, args ...string) error {
+			calls = append(calls, strings.Join(args, " "))
+			return nil
+		},
+		stdout: &bytes.Buffer{},
+		stderr: &bytes.Buffer{},
+	}, &calls
+}
+
+func TestServiceManager_Install(t *testing.T) {
+	m, calls := newTestServiceManager(t)
+
+	// Without a config the service is enabled but not started
+	require.NoError(t, m.install(true))
+	binary, err := os.ReadFile(filepath.Join(m.installDir, "xhub-agent"))
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(binary))
+	assert.DirExists(t, filepath.Join(m.installDir, "logs"))
+	unit, err := os.ReadFile(m.unitPath())
+	require.NoError(t, err)
+	assert.Contains(t, string(unit), "ExecStart="+filepath.Join(m.installDir, "xhub-agent")+" -c "+filepath.Join(m.installDir, "config.yml"))
+	assert.Equal(t, []string{"daemon-reload", "enable xhub-agent"}, *calls)
+	assert.Contains(t, m.stdout.(*bytes.Buffer).String(), "does not exist yet")
+
+	// With a config it is (re)started, replacing the installed binary
+	*calls = nil
+	require.NoError(t, os.WriteFile(filepath.Join(m.installDir, "config.yml"), []byte("uuid: x\n"), 0600))
+	require.NoError(t, os.WriteFile(m.executable, []byte("binary v2"), 0755))
+	require.NoError(t, m.install(true))
+	binary, err = os.ReadFile(filepath.Join(m.installDir, "xhub-agent"))
+	require.NoError(t, err)
+	assert.Equal(t, "binary v2", string(binary))
+	assert.Equal(t, []string{"daemon-reload", "enable xhub-agent", "restart xhub-agent"}, *calls)
+
+	*calls = nil
+	require.NoError(t, m.install(false))
+	assert.Equal(t, []string{"daemon-reload", "enable xhub-agent"}, *calls)
+}
+
+func TestServiceManager_InstallFromInstalledBinary(t *testing.T) {
+	m, _ := newTestServiceManager(t)
+	require.NoError(t, m.install(false))
+
+	// Re-running install from the installed binary leaves it in place
+	m.executable = filepath.Join(m.installDir, "xhub-agent")
+	require.NoError(t, m.install(false))
+	binary, err := os.ReadFile(m.executable)
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(binary))
+}
+
+func TestServiceManager_Uninstall(t *testing.T) {
+	m, calls := newTestServiceManager(t)
+	require.NoError(t, m.install(false))
+	configPath := filepath.Join(m.installDir, "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte("uuid: x\n"), 0600))
+
+	*calls = nil
+	require.NoError(t, m.uninstall(false))
+	assert.Equal(t, []string{"stop xhub-agent", "disable xhub-agent", "daemon-reload"}, *calls)
+	assert.NoFileExists(t, m.unitPath())
+	assert.NoFileExists(t, filepath.Join(m.installDir, "xhub-agent"))
+	assert.FileExists(t, configPath, "the config is kept without -purge")
+
+	// Uninstalling again is a no-op; -purge removes the rest
+	*calls = nil
+	require.NoError(t, m.uninstall(true))
+	assert.Empty(t, *calls)
+	assert.NoDirExists(t, m.installDir)
+}
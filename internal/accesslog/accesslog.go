@@ -0,0 +1,63 @@
+package accesslog
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/sanitize"
+)
+
+// Entry is an accepted connection of an Xray access log
+type Entry struct {
+	Time        time.Time
+	SourceIP    string
+	Destination string // Domain or IP the user connected to, lowercased
+	Port        string // Destination port
+	Email       string
+}
+
+// ParseLine parses an accepted connection of an Xray access log:
+//
+//	2024/05/01 12:00:00[.123456] from [tcp:]1.2.3.4:51234 accepted tcp:example.com:443 [in >> out] email: user@example.com
+//
+// Rejected connections, lines without a user email and other log lines are not entries.
+func ParseLine(line string) (Entry, bool) {
+	before, email, found := strings.Cut(line, " email: ")
+	if !found {
+		return Entry{}, false
+	}
+	fields := strings.Fields(before)
+	if len(fields) < 6 || fields[2] != "from" || fields[4] != "accepted" {
+		return Entry{}, false
+	}
+	clock, _, _ := strings.Cut(fields[1], ".")
+	at, err := time.ParseInLocation("2006/01/02 15:04:05", fields[0]+" "+clock, time.Local)
+	if err != nil {
+		return Entry{}, false
+	}
+	source, _, err := net.SplitHostPort(trimNetwork(fields[3]))
+	if err != nil || net.ParseIP(source) == nil {
+		return Entry{}, false
+	}
+	destination, port, err := net.SplitHostPort(trimNetwork(fields[5]))
+	if err != nil || destination == "" {
+		return Entry{}, false
+	}
+	email = sanitize.String(strings.TrimSpace(email))
+	if email == "" {
+		return Entry{}, false
+	}
+	return Entry{
+		Time:        at,
+		SourceIP:    source,
+		Destination: sanitize.String(strings.ToLower(destination)),
+		Port:        port,
+		Email:       email,
+	}, true
+}
+
+// trimNetwork removes the "tcp:" or "udp:" prefix of an access log address
+func trimNetwork(addr string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(addr, "tcp:"), "udp:")
+}
@@ -0,0 +1,159 @@
+package accesslog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/pkg/logger"
+)
+
+func createTestLogger(t *testing.T) *logger.Logger {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+	return log
+}
+
+func TestParseLine(t *testing.T) {
+	entry, ok := ParseLine("2024/05/01 11:59:30.123456 from tcp:203.0.113.7:51240 accepted tcp:Example.ORG:443 [vless-in >> direct] email: alice@example.com")
+	require.True(t, ok)
+	assert.Equal(t, Entry{
+		Time:        time.Date(2024, 5, 1, 11, 59, 30, 0, time.Local),
+		SourceIP:    "203.0.113.7",
+		Destination: "example.org",
+		Port:        "443",
+		Email:       "alice@example.com",
+	}, entry)
+
+	entry, ok = ParseLine("2024/05/01 11:59:40 from udp:[2001:db8::1]:6000 accepted udp:[2606:4700::1111]:53 [in >> direct] email: bob")
+	require.True(t, ok)
+	assert.Equal(t, "2001:db8::1", entry.SourceIP)
+	assert.Equal(t, "2606:4700::1111", entry.Destination)
+
+	for _, line := range []string{
+		"",
+		"2024/05/01 11:59:40 from 192.0.2.1:6000 accepted tcp:example.com:443 [in >> direct]",
+		"2024/05/01 11:59:40 from 192.0.2.1:6000 rejected  proxy/vless/encoding: invalid request user id email: bob",
+		"2024/05/01 11:59:40 from somewhere:6000 accepted tcp:example.com:443 [in >> direct] email: bob",
+		"2024/05/01 11:59:40 from 192.0.2.1:6000 accepted example.com [in >> direct] email: bob",
+		"2024/05/01 11:59:40 from 192.0.2.1:6000 accepted tcp:example.com:443 [in >> direct] email: ",
+	} {
+		_, ok := ParseLine(line)
+		assert.False(t, ok, line)
+	}
+}
+
+// accessLine formats an accepted connection of user from ip to domain
+func accessLine(user, ip, domain string) string {
+	return "2024/05/01 12:00:00 from " + ip + ":40000 accepted tcp:" + domain + ":443 [in >> direct] email: " + user + "\n"
+}
+
+func appendLog(t *testing.T, path string, lines ...string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString(strings.Join(lines, ""))
+	require.NoError(t, err)
+}
+
+func TestSummarizer_Summarize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	appendLog(t, path, accessLine("old", "192.0.2.1", "before.example"))
+
+	clock := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSummarizer(path, 2, createTestLogger(t))
+	s.now = func() time.Time { return clock }
+	s.start = clock
+
+	appendLog(t, path,
+		accessLine("alice", "192.0.2.1", "a.example"),
+		accessLine("alice", "192.0.2.2", "a.example"),
+		accessLine("alice", "192.0.2.1", "b.example"),
+		accessLine("alice", "192.0.2.1", "c.example"),
+		accessLine("bob", "198.51.100.1", "c.example"),
+		"2024/05/01 12:00:00 from 192.0.2.9:40000 rejected invalid request\n",
+		"2024/05/01 12:00:01 from 192.0.2.1:40000 accepted tcp:partial", // Still being written
+	)
+	clock = clock.Add(5 * time.Minute)
+
+	summary, err := s.Summarize()
+	require.NoError(t, err)
+	assert.Equal(t, clock.Add(-5*time.Minute), summary.Start)
+	assert.Equal(t, clock, summary.End)
+	assert.Equal(t, 5, summary.Connections, "lines before the start are not summarized")
+	assert.Equal(t, []UserSummary{
+		{Email: "alice", Connections: 4, SourceIPs: 2, TopDomains: []DomainCount{{"a.example", 2}, {"b.example", 1}}},
+		{Email: "bob", Connections: 1, SourceIPs: 1, TopDomains: []DomainCount{{"c.example", 1}}},
+	}, summary.Users)
+	assert.Equal(t, []DomainCount{{"a.example", 2}, {"c.example", 2}}, summary.TopDomains)
+
+	// The partial line is completed and read with the next interval
+	appendLog(t, path, ".example:443 [in >> direct] email: carol\n")
+	summary, err = s.Summarize()
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Connections)
+	require.Len(t, summary.Users, 1)
+	assert.Equal(t, "carol", summary.Users[0].Email)
+	assert.Equal(t, "partial.example", summary.Users[0].TopDomains[0].Domain)
+
+	summary, err = s.Summarize()
+	require.NoError(t, err)
+	assert.Zero(t, summary.Connections)
+	assert.Empty(t, summary.Users)
+}
+
+func TestSummarizer_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	appendLog(t, path, accessLine("alice", "192.0.2.1", "a.example"), accessLine("alice", "192.0.2.1", "a.example"))
+	s := NewSummarizer(path, 10, createTestLogger(t))
+
+	// Truncated in place
+	require.NoError(t, os.Truncate(path, 0))
+	appendLog(t, path, accessLine("bob", "192.0.2.1", "a.example"))
+	summary, err := s.Summarize()
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Connections)
+
+	// Moved away and recreated
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendLog(t, path, accessLine("carol", "192.0.2.1", "a.example"))
+	summary, err = s.Summarize()
+	require.NoError(t, err)
+	require.Len(t, summary.Users, 1)
+	assert.Equal(t, "carol", summary.Users[0].Email)
+}
+
+func TestSummarizer_MissingLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	s := NewSummarizer(path, 10, createTestLogger(t))
+
+	_, err := s.Summarize()
+	assert.ErrorContains(t, err, "failed to open Xray access log")
+
+	// A log created later is read from its start
+	appendLog(t, path, accessLine("alice", "192.0.2.1", "a.example"))
+	summary, err := s.Summarize()
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Connections)
+}
+
+func TestSummarizer_ReadCap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	s := NewSummarizer(path, 10, createTestLogger(t))
+
+	line := accessLine("alice", "192.0.2.1", "a.example")
+	lines := maxRead/len(line) + 100
+	appendLog(t, path, strings.Repeat(line, lines))
+
+	summary, err := s.Summarize()
+	require.NoError(t, err)
+	assert.Greater(t, summary.SkippedBytes, int64(100*len(line)-len(line)))
+	assert.Less(t, summary.Connections, lines)
+	assert.Equal(t, int64(lines*len(line)), summary.SkippedBytes+int64(summary.Connections*len(line)), "every byte is either summarized or skipped")
+}
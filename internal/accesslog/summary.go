@@ -0,0 +1,186 @@
+package accesslog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"xhub-agent/pkg/logger"
+)
+
+// Summary defaults
+const (
+	DefaultInterval   = 5 * time.Minute
+	DefaultTopDomains = 10
+	// maxRead caps the log growth read per interval; older lines of a busier interval are skipped
+	maxRead = 16 << 20
+)
+
+// DomainCount is the number of connections to a destination
+type DomainCount struct {
+	Domain      string
+	Connections int
+}
+
+// UserSummary is the activity of one user within a summary interval
+type UserSummary struct {
+	Email       string
+	Connections int
+	SourceIPs   int           // Distinct source IPs
+	TopDomains  []DomainCount // Most connected destinations, most connections first
+}
+
+// Summary aggregates the accepted connections of an interval of the access log
+type Summary struct {
+	Start        time.Time
+	End          time.Time
+	Connections  int
+	Users        []UserSummary // Most connections first
+	TopDomains   []DomainCount // Across all users
+	SkippedBytes int64         // Log growth beyond the read cap, not summarized
+}
+
+// Summarizer tails an Xray access log and summarizes the lines appended between calls
+type Summarizer struct {
+	path       string
+	topDomains int
+	logger     *logger.Logger
+	now        func() time.Time // injectable for tests
+
+	file   os.FileInfo // Log file read last, to detect rotation
+	offset int64       // End of the last complete line read
+	start  time.Time   // Start of the current interval
+}
+
+// NewSummarizer creates a summarizer of the log at path keeping topDomains destinations per
+// user and overall. Lines already in the log are not summarized.
+func NewSummarizer(path string, topDomains int, logger *logger.Logger) *Summarizer {
+	if topDomains <= 0 {
+		topDomains = DefaultTopDomains
+	}
+	s := &Summarizer{path: path, topDomains: topDomains, logger: logger, now: time.Now}
+	if info, err := os.Stat(path); err == nil {
+		s.file, s.offset = info, info.Size()
+	}
+	s.start = s.now()
+	return s
+}
+
+// Summarize summarizes the lines appended since the last call (or since the summarizer was
+// created) and starts a new interval. A rotated or truncated log is read from its start;
+// lines written to the old file after the last call are lost.
+func (s *Summarizer) Summarize() (Summary, error) {
+	end := s.now()
+	summary := Summary{Start: s.start, End: end}
+	s.start = end
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return summary, fmt.Errorf("failed to open Xray access log: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return summary, fmt.Errorf("failed to read Xray access log: %w", err)
+	}
+	if s.file == nil || !os.SameFile(s.file, info) || info.Size() < s.offset {
+		if s.file != nil {
+			s.logger.Debugf("Xray access log %s was rotated, reading it from the start", s.path)
+		}
+		s.offset = 0
+	}
+	s.file = info
+
+	offset := s.offset
+	if info.Size()-offset > maxRead {
+		summary.SkippedBytes = info.Size() - maxRead - offset
+		offset = info.Size() - maxRead
+	}
+	data := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(data, offset); err != nil && err != io.EOF {
+		return summary, fmt.Errorf("failed to read Xray access log: %w", err)
+	}
+	// A partly written last line is read with the next interval
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	if offset > s.offset {
+		// Skip ahead to the first complete line after the cut
+		cut := bytes.IndexByte(data, '\n') + 1
+		summary.SkippedBytes += int64(cut)
+		data = data[cut:complete]
+	} else {
+		data = data[:complete]
+	}
+	s.offset = offset + int64(complete)
+
+	s.aggregate(&summary, data)
+	return summary, nil
+}
+
+// userActivity accumulates the connections of a user
+type userActivity struct {
+	connections int
+	sourceIPs   map[string]bool
+	domains     map[string]int
+}
+
+// aggregate counts the accepted connections of the log lines in data
+func (s *Summarizer) aggregate(summary *Summary, data []byte) {
+	users := make(map[string]*userActivity)
+	domains := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		entry, ok := ParseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		user := users[entry.Email]
+		if user == nil {
+			user = &userActivity{sourceIPs: make(map[string]bool), domains: make(map[string]int)}
+			users[entry.Email] = user
+		}
+		user.connections++
+		user.sourceIPs[entry.SourceIP] = true
+		user.domains[entry.Destination]++
+		domains[entry.Destination]++
+		summary.Connections++
+	}
+
+	for email, user := range users {
+		summary.Users = append(summary.Users, UserSummary{
+			Email:       email,
+			Connections: user.connections,
+			SourceIPs:   len(user.sourceIPs),
+			TopDomains:  topDomains(user.domains, s.topDomains),
+		})
+	}
+	sort.Slice(summary.Users, func(i, j int) bool {
+		if summary.Users[i].Connections != summary.Users[j].Connections {
+			return summary.Users[i].Connections > summary.Users[j].Connections
+		}
+		return summary.Users[i].Email < summary.Users[j].Email
+	})
+	summary.TopDomains = topDomains(domains, s.topDomains)
+}
+
+// topDomains returns the n destinations with the most connections
+func topDomains(counts map[string]int, n int) []DomainCount {
+	top := make([]DomainCount, 0, len(counts))
+	for domain, connections := range counts {
+		top = append(top, DomainCount{Domain: domain, Connections: connections})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Connections != top[j].Connections {
+			return top[i].Connections > top[j].Connections
+		}
+		return top[i].Domain < top[j].Domain
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
@@ -0,0 +1,217 @@
+package alert
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"xhub-agent/internal/monitor"
+)
+
+// Alert names
+const (
+	AlertCPU        = "cpu"
+	AlertMemory     = "memory"
+	AlertDisk       = "disk"
+	AlertXrayDown   = "xray_down"
+	AlertCertExpiry = "cert_expiry"
+)
+
+// Alert states passed to the hooks
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Thresholds are the alert conditions (alert_*). A zero threshold disables its alert.
+type Thresholds struct {
+	CPU            float64 // CPU usage percent
+	Memory         float64 // Memory usage percent
+	Disk           float64 // Disk usage percent
+	XrayDown       bool    // Xray reported not running by 3x-ui
+	CertExpiryDays int     // Days left before a certificate file expires
+	Cycles         int     // Consecutive statuses breaching a threshold before it fires, at least 1
+}
+
+// Enabled reports whether any alert is configured
+func (t Thresholds) Enabled() bool {
+	return t.CPU > 0 || t.Memory > 0 || t.Disk > 0 || t.XrayDown || t.CertExpiryDays > 0
+}
+
+// Event is an alert that fired or resolved
+type Event struct {
+	Alert     string    `json:"alert"`             // AlertCPU, AlertMemory, ...
+	Subject   string    `json:"subject,omitempty"` // Certificate path of AlertCertExpiry
+	State     string    `json:"state"`             // StateFiring or StateResolved
+	Value     float64   `json:"value"`             // Observed value: percent, days left, 1 when Xray is down
+	Threshold float64   `json:"threshold"`
+	Message   string    `json:"message"`
+	UUID      string    `json:"uuid"`
+	Host      string    `json:"host"`
+	Time      time.Time `json:"time"`
+}
+
+// Text formats the event as a one-line notification
+func (e Event) Text() string {
+	icon := "🚨"
+	if e.State == StateResolved {
+		icon = "✅"
+	}
+	return fmt.Sprintf("%s [%s] %s: %s", icon, e.Host, e.State, e.Message)
+}
+
+// check is the outcome of one alert condition on a status
+type check struct {
+	key       string // Alert, plus the subject for certificates
+	alert     string
+	subject   string
+	breached  bool
+	value     float64
+	threshold float64
+	message   string
+}
+
+// Evaluator turns statuses into alert events: an alert fires once its threshold was breached
+// by Cycles statuses in a row and resolves on the first status within it
+type Evaluator struct {
+	thresholds Thresholds
+	now        func() time.Time
+
+	breaches map[string]int   // Consecutive breaching statuses by key
+	firing   map[string]check // Fired alerts by key
+}
+
+// NewEvaluator creates an evaluator of thresholds
+func NewEvaluator(thresholds Thresholds) *Evaluator {
+	thresholds.Cycles = max(thresholds.Cycles, 1)
+	return &Evaluator{thresholds: thresholds, now: time.Now, breaches: map[string]int{}, firing: map[string]check{}}
+}
+
+// SetClockForTesting replaces the clock (for testing only)
+func (e *Evaluator) SetClockForTesting(now func() time.Time) {
+	e.now = now
+}
+
+// Firing returns the keys of the alerts firing, sorted
+func (e *Evaluator) Firing() []string {
+	keys := make([]string, 0, len(e.firing))
+	for key := range e.firing {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Evaluate checks a status against the thresholds and returns the alerts that fired or
+// resolved. Conditions the status says nothing about (e.g. Xray while 3x-ui is unreachable)
+// keep their state.
+func (e *Evaluator) Evaluate(data *monitor.ServerStatusData) []Event {
+	checks := e.checks(data)
+	seen := make(map[string]bool, len(checks))
+	var events []Event
+	for _, c := range checks {
+		seen[c.key] = true
+		if !c.breached {
+			delete(e.breaches, c.key)
+			if _, ok := e.firing[c.key]; ok {
+				delete(e.firing, c.key)
+				events = append(events, e.event(c, StateResolved))
+			}
+			continue
+		}
+		e.breaches[c.key]++
+		if _, ok := e.firing[c.key]; !ok && e.breaches[c.key] >= e.thresholds.Cycles {
+			e.firing[c.key] = c
+			events = append(events, e.event(c, StateFiring))
+		}
+	}
+
+	// Certificates no longer referenced by the configs
+	if e.thresholds.CertExpiryDays > 0 {
+		for _, key := range e.Firing() {
+			c := e.firing[key]
+			if c.alert != AlertCertExpiry || seen[key] {
+				continue
+			}
+			delete(e.breaches, key)
+			delete(e.firing, key)
+			c.message = fmt.Sprintf("certificate %s is no longer checked", c.subject)
+			events = append(events, e.event(c, StateResolved))
+		}
+	}
+	return events
+}
+
+// checks evaluates the configured conditions on a status
+func (e *Evaluator) checks(data *monitor.ServerStatusData) []check {
+	t := e.thresholds
+	var checks []check
+	if t.CPU > 0 {
+		checks = append(checks, usageCheck(AlertCPU, "CPU", data.CPU, t.CPU))
+	}
+	if t.Memory > 0 && data.Memory.Total > 0 {
+		checks = append(checks, usageCheck(AlertMemory, "memory", percent(data.Memory.Current, data.Memory.Total), t.Memory))
+	}
+	if t.Disk > 0 && data.Disk.Total > 0 {
+		checks = append(checks, usageCheck(AlertDisk, "disk", percent(data.Disk.Current, data.Disk.Total), t.Disk))
+	}
+	if t.XrayDown && data.Xray.State != "" && data.Xray.State != monitor.XrayStateUnreachable {
+		c := check{key: AlertXrayDown, alert: AlertXrayDown, breached: data.Xray.State != "running", threshold: 1}
+		if c.breached {
+			c.value = 1
+			c.message = fmt.Sprintf("Xray is not running (%s)", data.Xray.State)
+			if data.Xray.ErrorMsg != "" {
+				c.message += ": " + data.Xray.ErrorMsg
+			}
+		} else {
+			c.message = "Xray is running"
+		}
+		checks = append(checks, c)
+	}
+	if t.CertExpiryDays > 0 {
+		for _, cert := range data.CertExpiry {
+			if cert.NotAfter == 0 {
+				continue // Unreadable, see its error in the status
+			}
+			days := time.Unix(cert.NotAfter, 0).Sub(e.now()).Hours() / 24
+			c := check{
+				key: AlertCertExpiry + ":" + cert.Path, alert: AlertCertExpiry, subject: cert.Path,
+				breached: days < float64(t.CertExpiryDays), value: float64(int(days)), threshold: float64(t.CertExpiryDays),
+			}
+			expiry := time.Unix(cert.NotAfter, 0).UTC().Format(time.DateOnly)
+			switch {
+			case days <= 0:
+				c.message = fmt.Sprintf("certificate %s (%s) expired on %s", cert.Path, cert.Origin, expiry)
+			case c.breached:
+				c.message = fmt.Sprintf("certificate %s (%s) expires on %s, in %.0f days", cert.Path, cert.Origin, expiry, days)
+			default:
+				c.message = fmt.Sprintf("certificate %s (%s) is valid until %s", cert.Path, cert.Origin, expiry)
+			}
+			checks = append(checks, c)
+		}
+	}
+	return checks
+}
+
+// usageCheck compares a usage percent with its threshold
+func usageCheck(alert, label string, value, threshold float64) check {
+	c := check{key: alert, alert: alert, breached: value >= threshold, value: value, threshold: threshold}
+	relation := "below"
+	if c.breached {
+		relation = "at or above"
+	}
+	c.message = fmt.Sprintf("%s usage %.1f%% is %s %g%%", label, value, relation, threshold)
+	return c
+}
+
+func percent(current, total int64) float64 {
+	return float64(current) / float64(total) * 100
+}
+
+// event creates the event of a check entering state
+func (e *Evaluator) event(c check, state string) Event {
+	return Event{
+		Alert: c.alert, Subject: c.subject, State: state, Value: c.value, Threshold: c.threshold,
+		Message: c.message, Time: e.now(),
+	}
+}
@@ -0,0 +1,92 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+)
+
+var now = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+func status(cpu float64, memUsed int64, xray string) *monitor.ServerStatusData {
+	return &monitor.ServerStatusData{
+		CPU:    cpu,
+		Memory: monitor.MemoryInfo{Current: memUsed, Total: 1000},
+		Disk:   monitor.DiskInfo{Current: 100, Total: 1000},
+		Xray:   monitor.XrayInfo{State: xray},
+	}
+}
+
+func states(events []Event) []string {
+	var result []string
+	for _, event := range events {
+		result = append(result, event.Alert+" "+event.State)
+	}
+	return result
+}
+
+func TestEvaluator_Cycles(t *testing.T) {
+	e := NewEvaluator(Thresholds{CPU: 90, Memory: 80, Disk: 90, Cycles: 2})
+	e.SetClockForTesting(func() time.Time { return now })
+
+	assert.Empty(t, e.Evaluate(status(95, 500, "running")), "one breaching status is not enough")
+	events := e.Evaluate(status(97, 850, "running"))
+	require.Equal(t, []string{"cpu firing"}, states(events))
+	assert.Equal(t, 97.0, events[0].Value)
+	assert.Equal(t, 90.0, events[0].Threshold)
+	assert.Equal(t, "CPU usage 97.0% is at or above 90%", events[0].Message)
+	assert.Equal(t, now, events[0].Time)
+
+	assert.Empty(t, e.Evaluate(status(99, 500, "running")), "fired once")
+	assert.Equal(t, []string{AlertCPU}, e.Firing())
+
+	// The memory breach was interrupted, so it starts over
+	assert.Equal(t, []string{"cpu resolved"}, states(e.Evaluate(status(10, 900, "running"))))
+	assert.Equal(t, []string{"memory firing"}, states(e.Evaluate(status(10, 900, "running"))))
+}
+
+func TestEvaluator_XrayDown(t *testing.T) {
+	e := NewEvaluator(Thresholds{XrayDown: true})
+
+	events := e.Evaluate(&monitor.ServerStatusData{Xray: monitor.XrayInfo{State: "error", ErrorMsg: "config invalid"}})
+	require.Equal(t, []string{"xray_down firing"}, states(events))
+	assert.Equal(t, "Xray is not running (error): config invalid", events[0].Message)
+
+	// Host metrics while 3x-ui is unreachable say nothing about Xray
+	assert.Empty(t, e.Evaluate(&monitor.ServerStatusData{Xray: monitor.XrayInfo{State: monitor.XrayStateUnreachable}}))
+	assert.Equal(t, []string{AlertXrayDown}, e.Firing())
+
+	assert.Equal(t, []string{"xray_down resolved"}, states(e.Evaluate(status(0, 0, "running"))))
+}
+
+func TestEvaluator_CertExpiry(t *testing.T) {
+	e := NewEvaluator(Thresholds{CertExpiryDays: 7})
+	e.SetClockForTesting(func() time.Time { return now })
+	data := &monitor.ServerStatusData{CertExpiry: []monitor.CertExpiry{
+		{Path: "/etc/ssl/a.pem", Origin: "inbound:443", NotAfter: now.Add(3 * 24 * time.Hour).Unix()},
+		{Path: "/etc/ssl/b.pem", Origin: "hysteria2", NotAfter: now.Add(30 * 24 * time.Hour).Unix()},
+		{Path: "/etc/ssl/missing.pem", Origin: "hysteria2"},
+	}}
+
+	events := e.Evaluate(data)
+	require.Equal(t, []string{"cert_expiry firing"}, states(events))
+	assert.Equal(t, "/etc/ssl/a.pem", events[0].Subject)
+	assert.Equal(t, 3.0, events[0].Value)
+	assert.Equal(t, "certificate /etc/ssl/a.pem (inbound:443) expires on 2026-10-19, in 3 days", events[0].Message)
+
+	// A certificate no longer referenced resolves its alert
+	events = e.Evaluate(&monitor.ServerStatusData{CertExpiry: data.CertExpiry[1:]})
+	require.Equal(t, []string{"cert_expiry resolved"}, states(events))
+	assert.Equal(t, "certificate /etc/ssl/a.pem is no longer checked", events[0].Message)
+	assert.Empty(t, e.Firing())
+}
+
+func TestThresholds_Enabled(t *testing.T) {
+	assert.False(t, Thresholds{Cycles: 3}.Enabled())
+	assert.True(t, Thresholds{Disk: 90}.Enabled())
+	assert.True(t, Thresholds{XrayDown: true}.Enabled())
+}
@@ -0,0 +1,163 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/runner"
+)
+
+// Hook defaults
+const (
+	HookTimeout     = 15 * time.Second // Longest a hook may take per event
+	maxOutput       = 1 << 10          // Tail of a script or response kept in errors
+	telegramBaseURL = "https://api.telegram.org"
+)
+
+// Hook delivers alert events on the node, independently of xhub
+type Hook interface {
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// ExecHook runs a script for each event, with the event in XHUB_ALERT_* environment
+// variables and as JSON on stdin. The script gets the agent environment without the
+// XHUB_AGENT_* variables, which may hold the secrets of the config.
+type ExecHook struct {
+	path string
+	run  func(cmd *exec.Cmd) ([]byte, error) // injectable for tests
+}
+
+// NewExecHook creates a hook running the executable at path (no shell is involved)
+func NewExecHook(path string) *ExecHook {
+	return &ExecHook{path: path, run: func(cmd *exec.Cmd) ([]byte, error) { return cmd.CombinedOutput() }}
+}
+
+// Name returns "exec"
+func (h *ExecHook) Name() string {
+	return "exec"
+}
+
+// Send runs the script
+func (h *ExecHook) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, h.path)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(hookEnviron(),
+		"XHUB_ALERT="+event.Alert,
+		"XHUB_ALERT_STATE="+event.State,
+		"XHUB_ALERT_SUBJECT="+event.Subject,
+		"XHUB_ALERT_VALUE="+strconv.FormatFloat(event.Value, 'f', -1, 64),
+		"XHUB_ALERT_THRESHOLD="+strconv.FormatFloat(event.Threshold, 'f', -1, 64),
+		"XHUB_ALERT_MESSAGE="+event.Message,
+		"XHUB_ALERT_UUID="+event.UUID,
+		"XHUB_ALERT_HOST="+event.Host,
+	)
+	if out, err := h.run(cmd); err != nil {
+		return fmt.Errorf("%s failed: %w (%s)", h.path, err, strings.TrimSpace(runner.Tail(string(out), maxOutput)))
+	}
+	return nil
+}
+
+// hookEnviron returns the agent environment without the config variables (config.EnvPrefix)
+func hookEnviron() []string {
+	var env []string
+	for _, variable := range os.Environ() {
+		if !strings.HasPrefix(variable, config.EnvPrefix) {
+			env = append(env, variable)
+		}
+	}
+	return env
+}
+
+// WebhookHook posts each event as JSON to a URL
+type WebhookHook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookHook creates a hook posting to rawURL
+func NewWebhookHook(rawURL string) *WebhookHook {
+	return &WebhookHook{url: rawURL, client: &http.Client{Timeout: HookTimeout}}
+}
+
+// Name returns "webhook"
+func (h *WebhookHook) Name() string {
+	return "webhook"
+}
+
+// Send posts the event
+func (h *WebhookHook) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return post(ctx, h.client, h.url, "application/json", body)
+}
+
+// TelegramHook sends each event as a message through the Telegram Bot API
+type TelegramHook struct {
+	token   string
+	chatID  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewTelegramHook creates a hook sending through the bot token to chatID
+func NewTelegramHook(token, chatID string) *TelegramHook {
+	return &TelegramHook{token: token, chatID: chatID, baseURL: telegramBaseURL, client: &http.Client{Timeout: HookTimeout}}
+}
+
+// SetBaseURLForTesting replaces the Bot API address (for testing only)
+func (h *TelegramHook) SetBaseURLForTesting(baseURL string) {
+	h.baseURL = baseURL
+}
+
+// Name returns "telegram"
+func (h *TelegramHook) Name() string {
+	return "telegram"
+}
+
+// Send sends the event text to the chat
+func (h *TelegramHook) Send(ctx context.Context, event Event) error {
+	form := url.Values{"chat_id": {h.chatID}, "text": {event.Text()}, "disable_web_page_preview": {"true"}}
+	err := post(ctx, h.client, h.baseURL+"/bot"+h.token+"/sendMessage", "application/x-www-form-urlencoded", []byte(form.Encode()))
+	if err != nil {
+		// The request URL carries the bot token
+		return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), h.token, "[redacted]"))
+	}
+	return nil
+}
+
+// post sends body to target and expects a 2xx answer
+func post(ctx context.Context, client *http.Client, target, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(answer)))
+	}
+	return nil
+}
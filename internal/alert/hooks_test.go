@@ -0,0 +1,104 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEvent = Event{
+	Alert: AlertDisk, State: StateFiring, Value: 91.5, Threshold: 90,
+	Message: "disk usage 91.5% is at or above 90%", UUID: "test-uuid", Host: "node-1", Time: now,
+}
+
+func TestExecHook(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	script := filepath.Join(dir, "alert.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\n"+
+		`echo "$XHUB_ALERT $XHUB_ALERT_STATE $XHUB_ALERT_VALUE $XHUB_ALERT_HOST" > `+out+"\n"+
+		"cat >> "+out+"\n"), 0755))
+
+	require.NoError(t, NewExecHook(script).Send(context.Background(), testEvent))
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	lines := strings.SplitN(string(content), "\n", 2)
+	assert.Equal(t, "disk firing 91.5 node-1", lines[0])
+	var event Event
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, testEvent, event)
+
+	hook := NewExecHook(script)
+	hook.run = func(cmd *exec.Cmd) ([]byte, error) { return []byte("no route\n"), &exec.ExitError{} }
+	assert.ErrorContains(t, hook.Send(context.Background(), testEvent), "(no route)")
+}
+
+func TestExecHook_NoConfigEnvironment(t *testing.T) {
+	t.Setenv("XHUB_AGENT_XUI_PASS", "panel-secret")
+	t.Setenv("XHUB_AGENT_XHUB_API_KEY", "api-secret")
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	script := filepath.Join(dir, "alert.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nenv > "+out+"\n"), 0755))
+
+	require.NoError(t, NewExecHook(script).Send(context.Background(), testEvent))
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "XHUB_AGENT_")
+	assert.NotContains(t, string(content), "secret")
+	assert.Contains(t, string(content), "XHUB_ALERT_UUID=test-uuid")
+}
+
+func TestWebhookHook(t *testing.T) {
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received.State == StateResolved {
+			http.Error(w, "not accepted", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	hook := NewWebhookHook(server.URL)
+	require.NoError(t, hook.Send(context.Background(), testEvent))
+	assert.Equal(t, testEvent, received)
+
+	resolved := testEvent
+	resolved.State = StateResolved
+	assert.EqualError(t, hook.Send(context.Background(), resolved), "HTTP 400: not accepted")
+}
+
+func TestTelegramHook(t *testing.T) {
+	var path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	hook := NewTelegramHook("123:secret", "-100")
+	hook.SetBaseURLForTesting(server.URL)
+	require.NoError(t, hook.Send(context.Background(), testEvent))
+	assert.Equal(t, "/bot123:secret/sendMessage", path)
+	assert.Contains(t, body, "chat_id=-100")
+	assert.Contains(t, body, "text=%F0%9F%9A%A8+%5Bnode-1%5D+firing%3A+disk+usage")
+
+	// The token never shows in errors
+	hook = NewTelegramHook("bad-token", "-100")
+	hook.SetBaseURLForTesting("http://127.0.0.1:1")
+	err := hook.Send(context.Background(), testEvent)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "bad-token")
+}
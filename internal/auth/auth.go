@@ -1,27 +1,98 @@
 package auth
 
 import (
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"xhub-agent/internal/faultinject"
+	"xhub-agent/internal/metrics"
 )
 
+// DefaultSessionTTL is the assumed lifetime of a 3x-ui session
+const DefaultSessionTTL = time.Hour
+
+// sessionCheckPath is the panel endpoint ValidateSession asks, present on every 3x-ui
+// version and answering a small JSON object
+const sessionCheckPath = "/panel/setting/defaultSettings"
+
+// ErrAuthExpired is returned (wrapped) by panel calls rejected with HTTP 401 or made without
+// a session: the agent must log in again
+var ErrAuthExpired = errors.New("not authenticated, session may have expired")
+
+// ErrPanelUnreachable is matched by the errors of panel requests that got no HTTP response
+// (connection refused, DNS failure, timeout)
+var ErrPanelUnreachable = errors.New("3x-ui unreachable")
+
+// unreachableError is a panel request that got no HTTP response, keeping the message of the
+// transport error
+type unreachableError struct {
+	err error
+}
+
+func (e *unreachableError) Error() string { return e.err.Error() }
+func (e *unreachableError) Unwrap() error { return e.err }
+func (e *unreachableError) Is(target error) bool {
+	return target == ErrPanelUnreachable
+}
+
+// Unreachable tags the error of a panel request that got no HTTP response so that it matches
+// ErrPanelUnreachable. A nil err stays nil.
+func Unreachable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &unreachableError{err: err}
+}
+
+// StatusError is returned (wrapped) by panel calls answered with an unexpected HTTP status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed, HTTP status code: %d", e.StatusCode)
+}
+
+// LoginError is returned when the panel refuses the login
+type LoginError struct {
+	Message string
+}
+
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("login failed: %s", e.Message)
+}
+
 // XUIAuth 3x-ui authentication client
 type XUIAuth struct {
 	baseURL      string
 	username     string
 	password     string
-	client       *http.Client
+	client       *http.Client // Shared by every panel request, see newPanelClient
 	sessionToken string
 	cookieName   string // Store the actual cookie name used
 	lastLogin    time.Time
+	sessionTTL   time.Duration
 	mutex        sync.RWMutex
+
+	reloginMutex sync.Mutex    // Single-flights Relogin
+	relogins     atomic.Uint64 // Logins done by Do after a 401
+
+	faults *faultinject.Injector // Testing-only failure injection (fail_inject), nil when off
+
+	csrf *csrfState // CSRF token handling for protected forks (xui_csrf_mode), nil when off
+
+	loginSecret string // Panel secret token (xui_login_secret), empty when off
+	totpSecret  []byte // Two-factor TOTP secret (xui_totp_secret), nil when off
+
+	metrics *metrics.Registry // Panel request durations (metrics_listen), nil when off
 }
 
 // LoginResponse 3x-ui login response structure
@@ -33,16 +104,11 @@ type LoginResponse struct {
 // NewXUIAuth creates a new 3x-ui authentication client
 func NewXUIAuth(baseURL, username, password string) *XUIAuth {
 	return &XUIAuth{
-		baseURL:  strings.TrimSuffix(baseURL, "/"),
-		username: username,
-		password: password,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-			// Skip HTTPS certificate verification (since 3x-ui usually uses self-signed certificates)
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		username:   username,
+		password:   password,
+		sessionTTL: DefaultSessionTTL,
+		client:     newPanelClient(),
 	}
 }
 
@@ -51,10 +117,15 @@ func (a *XUIAuth) Login() error {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
+	if err := a.faults.Fail(faultinject.PointAuth); err != nil {
+		return fmt.Errorf("login request failed: %w", err)
+	}
+
 	// Prepare login data
 	data := url.Values{}
 	data.Set("username", a.username)
 	data.Set("password", a.password)
+	a.loginFactors(data)
 
 	// Create login request
 	req, err := http.NewRequest("POST", a.baseURL+"/login", strings.NewReader(data.Encode()))
@@ -65,7 +136,7 @@ func (a *XUIAuth) Login() error {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	// Send request
-	resp, err := a.client.Do(req)
+	resp, err := a.send(a.client, req)
 	if err != nil {
 		return fmt.Errorf("login request failed: %w", err)
 	}
@@ -73,7 +144,7 @@ func (a *XUIAuth) Login() error {
 
 	// Check HTTP status code
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("login failed, HTTP status code: %d", resp.StatusCode)
+		return &LoginError{Message: fmt.Sprintf("HTTP status code: %d", resp.StatusCode)}
 	}
 
 	// Read response body
@@ -90,7 +161,7 @@ func (a *XUIAuth) Login() error {
 
 	// Check if login was successful
 	if !loginResp.Success {
-		return fmt.Errorf("login failed: %s", loginResp.Message)
+		return &LoginError{Message: loginResp.Message}
 	}
 
 	// Extract session cookie - first try to parse from Set-Cookie header
@@ -117,6 +188,14 @@ func (a *XUIAuth) Login() error {
 		}
 	}
 
+	if a.sessionToken != "" && a.csrf != nil {
+		if err := a.fetchCSRFTokenLocked(resp); err != nil {
+			// Without a token every panel POST would be rejected: retry the login next cycle
+			a.sessionToken = ""
+			return err
+		}
+	}
+
 	if a.sessionToken == "" {
 		// Add debug information
 		allHeaders := ""
@@ -136,6 +215,73 @@ func (a *XUIAuth) RefreshSession() error {
 	return a.Login()
 }
 
+// Relogin logs in again after staleToken was rejected by the panel.
+// Concurrent callers holding the same stale token share a single login: if the
+// session already changed since staleToken was read, it returns immediately.
+func (a *XUIAuth) Relogin(staleToken string) error {
+	_, err := a.relogin(staleToken)
+	return err
+}
+
+// relogin is Relogin, also reporting whether this call logged in
+func (a *XUIAuth) relogin(staleToken string) (bool, error) {
+	a.reloginMutex.Lock()
+	defer a.reloginMutex.Unlock()
+
+	if current := a.GetSessionToken(); current != "" && current != staleToken {
+		return false, nil
+	}
+	return true, a.Login()
+}
+
+// SetFaultInjector enables testing-only failure injection on login
+func (a *XUIAuth) SetFaultInjector(injector *faultinject.Injector) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.faults = injector
+}
+
+// SetMetrics records the duration of every panel request in m
+func (a *XUIAuth) SetMetrics(m *metrics.Registry) {
+	a.metrics = m
+}
+
+// send sends a panel request with client, recording its duration by endpoint (the URL
+// path below the panel root path)
+func (a *XUIAuth) send(client *http.Client, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := client.Do(req)
+	if a.metrics != nil {
+		endpoint := req.URL.Path
+		if base, parseErr := url.Parse(a.baseURL); parseErr == nil {
+			endpoint = strings.TrimPrefix(endpoint, strings.TrimSuffix(base.Path, "/"))
+		}
+		a.metrics.ObserveXUIRequest(endpoint, time.Since(start))
+	}
+	return resp, Unreachable(err)
+}
+
+// SetSessionTTL sets the assumed session lifetime (0 keeps the default)
+func (a *XUIAuth) SetSessionTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.sessionTTL = ttl
+}
+
+// SessionRemaining returns the remaining assumed session lifetime (0 if not logged in)
+func (a *XUIAuth) SessionRemaining() time.Duration {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.sessionToken == "" {
+		return 0
+	}
+	return max(a.sessionTTL-time.Since(a.lastLogin), 0)
+}
+
 // IsAuthenticated checks if authenticated
 func (a *XUIAuth) IsAuthenticated() bool {
 	a.mutex.RLock()
@@ -152,8 +298,57 @@ func (a *XUIAuth) IsSessionExpired() bool {
 		return true
 	}
 
-	// Assume session is valid for 1 hour
-	return time.Since(a.lastLogin) > time.Hour
+	// Assume session is valid for sessionTTL (1 hour by default)
+	return time.Since(a.lastLogin) > a.sessionTTL
+}
+
+// ValidateSession asks the panel whether the session is still valid, with one small
+// authenticated request. A valid session starts a new assumed lifetime (xui_session_ttl), so
+// it is reused instead of logging in again; a rejected one is dropped and ErrAuthExpired
+// returned.
+func (a *XUIAuth) ValidateSession() error {
+	session := a.GetSessionToken()
+	req, err := a.GetAuthenticatedRequest("POST", sessionCheckPath, nil)
+	if err != nil {
+		return err
+	}
+	// 3x-ui answers XHR requests without a valid session with 401 instead of redirecting
+	// them to the login page
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+
+	// Sent without Do, which would log in again on a 401
+	resp, err := a.do(a.client, req)
+	if err != nil {
+		return fmt.Errorf("session check failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	// Forks without the XHR handling redirect to the login page, which the client follows
+	if resp.StatusCode == http.StatusUnauthorized || resp.Request.URL.Path != req.URL.Path {
+		a.Expire(session)
+		return fmt.Errorf("session check failed: %w", ErrAuthExpired)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("session check failed: %w", &StatusError{StatusCode: resp.StatusCode})
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.sessionToken == session {
+		a.lastLogin = time.Now()
+	}
+	return nil
+}
+
+// Expire drops the session after staleToken was rejected by the panel, so that the next
+// cycle logs in again. A session that changed since staleToken was read is kept.
+func (a *XUIAuth) Expire(staleToken string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.sessionToken == staleToken {
+		a.sessionToken = ""
+	}
 }
 
 // GetSessionToken gets session token
@@ -163,15 +358,20 @@ func (a *XUIAuth) GetSessionToken() string {
 	return a.sessionToken
 }
 
-// GetAuthenticatedRequest creates HTTP request with authentication info
+// GetAuthenticatedRequest creates a panel request for Do, carrying the CSRF token of
+// protected forks. The session cookie is added by the shared client from its jar.
 func (a *XUIAuth) GetAuthenticatedRequest(method, path string, body io.Reader) (*http.Request, error) {
 	a.mutex.RLock()
 	sessionToken := a.sessionToken
-	cookieName := a.cookieName
+	csrf := a.csrf
+	var csrfToken string
+	if csrf != nil {
+		csrfToken = csrf.token
+	}
 	a.mutex.RUnlock()
 
 	if sessionToken == "" {
-		return nil, fmt.Errorf("not authenticated, please login first")
+		return nil, fmt.Errorf("%w: please login first", ErrAuthExpired)
 	}
 
 	// Create request
@@ -180,18 +380,22 @@ func (a *XUIAuth) GetAuthenticatedRequest(method, path string, body io.Reader) (
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Add session cookie with the correct name
-	if cookieName == "" {
-		cookieName = "3x-ui" // Default fallback
+	// Add CSRF token for protected forks
+	if csrf != nil {
+		a.attachCSRF(req, csrf, csrfToken)
 	}
-	req.AddCookie(&http.Cookie{
-		Name:  cookieName,
-		Value: sessionToken,
-	})
 
 	return req, nil
 }
 
+// sessionCookieName returns the session cookie name. Callers hold a.mutex.
+func (a *XUIAuth) sessionCookieName() string {
+	if a.cookieName == "" {
+		return "3x-ui" // Default fallback
+	}
+	return a.cookieName
+}
+
 // SetSessionForTesting sets session token (for testing only)
 func (a *XUIAuth) SetSessionForTesting(token string) {
 	a.mutex.Lock()
@@ -199,6 +403,7 @@ func (a *XUIAuth) SetSessionForTesting(token string) {
 	a.sessionToken = token
 	a.cookieName = "session" // Default for testing
 	a.lastLogin = time.Now()
+	a.setSessionCookie()
 }
 
 // extractSessionFromSetCookie extracts session token from Set-Cookie header
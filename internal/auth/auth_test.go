@@ -88,6 +88,7 @@ func TestXUIAuth_Login_ServerError(t *testing.T) {
 
 func TestXUIAuth_GetAuthenticatedRequest(t *testing.T) {
 	// 先进行登录
+	var received []*http.Cookie
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/login" {
 			http.SetCookie(w, &http.Cookie{
@@ -97,7 +98,9 @@ func TestXUIAuth_GetAuthenticatedRequest(t *testing.T) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`{"success": true, "msg": ""}`))
+			return
 		}
+		received = r.Cookies()
 	}))
 	defer server.Close()
 
@@ -108,17 +111,14 @@ func TestXUIAuth_GetAuthenticatedRequest(t *testing.T) {
 	// 测试获取认证请求
 	req, err := auth.GetAuthenticatedRequest("GET", "/server/status", nil)
 	require.NoError(t, err)
+	resp, err := auth.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
 
-	// 验证请求包含session cookie
-	cookies := req.Cookies()
-	found := false
-	for _, cookie := range cookies {
-		if cookie.Name == "session" && cookie.Value == "test-session-token" {
-			found = true
-			break
-		}
-	}
-	assert.True(t, found, "Request should contain session cookie")
+	// 验证请求包含session cookie (from the jar of the shared client)
+	require.Len(t, received, 1, "the session cookie is sent once")
+	assert.Equal(t, "session", received[0].Name)
+	assert.Equal(t, "test-session-token", received[0].Value)
 }
 
 func TestXUIAuth_GetAuthenticatedRequest_NotAuthenticated(t *testing.T) {
@@ -128,6 +128,28 @@ func TestXUIAuth_GetAuthenticatedRequest_NotAuthenticated(t *testing.T) {
 	_, err := auth.GetAuthenticatedRequest("GET", "/server/status", nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not authenticated")
+	assert.ErrorIs(t, err, ErrAuthExpired)
+}
+
+func TestXUIAuth_Login_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	err := NewXUIAuth(server.URL, "admin", "password123").Login()
+	assert.ErrorIs(t, err, ErrPanelUnreachable)
+	assert.Contains(t, err.Error(), "login request failed: Post")
+	assert.Nil(t, Unreachable(nil))
+}
+
+func TestXUIAuth_Expire(t *testing.T) {
+	auth := NewXUIAuth("http://localhost:54321", "admin", "password123")
+	auth.SetSessionForTesting("current")
+
+	auth.Expire("stale")
+	assert.True(t, auth.IsAuthenticated(), "a session replaced since the rejection is kept")
+
+	auth.Expire("current")
+	assert.False(t, auth.IsAuthenticated())
 }
 
 func TestXUIAuth_RefreshSession(t *testing.T) {
@@ -180,3 +202,43 @@ func TestXUIAuth_IsSessionExpired(t *testing.T) {
 	auth.lastLogin = time.Now().Add(-30 * time.Minute) // 30分钟前登录
 	assert.False(t, auth.IsSessionExpired())
 }
+
+func TestXUIAuth_ValidateSession(t *testing.T) {
+	valid := true
+	redirect := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/login":
+			w.Write([]byte(`<html>login</html>`))
+		case r.URL.Path != sessionCheckPath:
+			w.WriteHeader(http.StatusNotFound)
+		case redirect:
+			http.Redirect(w, r, "/login", http.StatusTemporaryRedirect)
+		case !valid:
+			assert.Equal(t, "XMLHttpRequest", r.Header.Get("X-Requested-With"))
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Write([]byte(`{"success": true, "obj": {}}`))
+		}
+	}))
+	defer server.Close()
+
+	auth := NewXUIAuth(server.URL, "admin", "password123")
+	auth.SetSessionForTesting("token")
+	auth.lastLogin = time.Now().Add(-2 * time.Hour)
+	require.True(t, auth.IsSessionExpired())
+
+	// A valid session starts a new assumed lifetime
+	require.NoError(t, auth.ValidateSession())
+	assert.False(t, auth.IsSessionExpired())
+	assert.Equal(t, "token", auth.GetSessionToken())
+
+	valid = false
+	assert.ErrorIs(t, auth.ValidateSession(), ErrAuthExpired)
+	assert.False(t, auth.IsAuthenticated(), "a rejected session is dropped")
+
+	auth.SetSessionForTesting("token")
+	redirect = true
+	assert.ErrorIs(t, auth.ValidateSession(), ErrAuthExpired, "redirected to the login page")
+	assert.False(t, auth.IsAuthenticated())
+}
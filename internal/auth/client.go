@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"time"
+)
+
+// DefaultRequestTimeout is the longest a panel request may take, unless the caller asks for
+// more with DoWithTimeout
+const DefaultRequestTimeout = 30 * time.Second
+
+// newPanelClient creates the HTTP client shared by every panel request: one connection pool
+// and a cookie jar holding the session and any cookie the panel sets (CSRF tokens, sticky
+// sessions of a load balancer in front of it)
+func newPanelClient() *http.Client {
+	jar, _ := cookiejar.New(nil) // Never fails without options
+	return &http.Client{
+		Timeout: DefaultRequestTimeout,
+		Jar:     jar,
+		Transport: &http.Transport{
+			// Skip HTTPS certificate verification (since 3x-ui usually uses self-signed certificates)
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+			MaxIdleConns:        16,
+			MaxIdleConnsPerHost: 8,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// Post sends a body-less authenticated POST to a panel API path with Do
+func (a *XUIAuth) Post(path string) (*http.Response, error) {
+	req, err := a.GetAuthenticatedRequest("POST", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return a.Do(req)
+}
+
+// Do sends a panel request made by GetAuthenticatedRequest with the shared client. A request
+// the panel rejects with HTTP 401 logs in again (once for all the callers holding the same
+// session) and is retried with the new session; a request still rejected returns
+// ErrAuthExpired.
+func (a *XUIAuth) Do(req *http.Request) (*http.Response, error) {
+	return a.DoWithTimeout(req, 0)
+}
+
+// DoWithTimeout is Do for requests that may take longer than DefaultRequestTimeout (0)
+func (a *XUIAuth) DoWithTimeout(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	client := a.client
+	if timeout > 0 {
+		custom := *a.client // Same pool and jar
+		custom.Timeout = timeout
+		client = &custom
+	}
+
+	staleToken := a.GetSessionToken()
+	resp, err := a.do(client, req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	loggedIn, err := a.relogin(staleToken)
+	if err != nil {
+		return nil, errors.Join(ErrAuthExpired, err)
+	}
+	if loggedIn {
+		a.relogins.Add(1)
+	}
+	retry, err := a.rebuildRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err = a.do(client, retry)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, ErrAuthExpired
+	}
+	return resp, nil
+}
+
+// Relogins returns the number of logins done by Do after a panel request was rejected with
+// HTTP 401
+func (a *XUIAuth) Relogins() uint64 {
+	return a.relogins.Load()
+}
+
+// rebuildRequest copies req for a retry with the current CSRF token. A body carrying only a
+// stale token form field is regenerated, any other body is replayed; the session cookie comes
+// from the jar.
+func (a *XUIAuth) rebuildRequest(req *http.Request) (*http.Request, error) {
+	a.mutex.RLock()
+	csrf := a.csrf
+	var token string
+	if csrf != nil {
+		token = csrf.token
+	}
+	a.mutex.RUnlock()
+
+	retry := req.Clone(req.Context())
+	retry.Header.Del("Cookie") // Added from the jar by the first send
+	if csrf != nil {
+		retry.Header.Del(csrf.config.Header)
+	}
+	if req.GetBody == nil {
+		if csrf != nil {
+			a.attachCSRF(retry, csrf, token)
+		}
+		return retry, nil
+	}
+	original, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay request body: %w", err)
+	}
+	data, err := io.ReadAll(original)
+	original.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay request body: %w", err)
+	}
+	retry.Body, retry.GetBody, retry.ContentLength = nil, nil, 0
+
+	formField := ""
+	if csrf != nil {
+		formField = csrf.config.FormField
+	}
+	if formField == "" || !isFormField(data, formField) {
+		retry.Body = io.NopCloser(bytes.NewReader(data))
+		retry.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+		retry.ContentLength = int64(len(data))
+	}
+	if csrf != nil {
+		a.attachCSRF(retry, csrf, token)
+	}
+	return retry, nil
+}
+
+// isFormField reports whether body is a form holding only field
+func isFormField(body []byte, field string) bool {
+	values, err := url.ParseQuery(string(body))
+	return err == nil && len(values) == 1 && values.Has(field)
+}
+
+// setSessionCookie stores the session cookie in the jar. Callers hold a.mutex.
+func (a *XUIAuth) setSessionCookie() {
+	u, err := url.Parse(a.baseURL)
+	if err != nil {
+		return
+	}
+	a.client.Jar.SetCookies(u, []*http.Cookie{{Name: a.sessionCookieName(), Value: a.sessionToken, Path: "/"}})
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXUIAuth_Do_ReloginOn401(t *testing.T) {
+	var logins atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			n := logins.Add(1)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "token-" + strconv.Itoa(int(n))})
+			w.Write([]byte(`{"success": true}`))
+			return
+		}
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	auth := NewXUIAuth(server.URL, "admin", "password123")
+	require.NoError(t, auth.Login())
+
+	req, err := auth.GetAuthenticatedRequest("POST", "/panel/api/echo", strings.NewReader("payload"))
+	require.NoError(t, err)
+	resp, err := auth.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// The body is replayed with the new session
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "payload", string(body))
+	assert.Equal(t, "token-2", auth.GetSessionToken())
+	assert.Equal(t, uint64(1), auth.Relogins())
+}
+
+func TestXUIAuth_Do_StillRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "test-session-token"})
+			w.Write([]byte(`{"success": true}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	auth := NewXUIAuth(server.URL, "admin", "password123")
+	require.NoError(t, auth.Login())
+
+	_, err := auth.Post("/panel/api/server/status")
+	assert.ErrorIs(t, err, ErrAuthExpired)
+}
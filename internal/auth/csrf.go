@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// CSRFMode selects where the panel's CSRF token is extracted from
+type CSRFMode string
+
+const (
+	CSRFOff    CSRFMode = ""       // No CSRF handling (standard 3x-ui)
+	CSRFCookie CSRFMode = "cookie" // Token delivered in a cookie
+	CSRFHTML   CSRFMode = "html"   // Token embedded in the panel HTML (meta tag)
+)
+
+// CSRF defaults matching the known forks
+const (
+	DefaultCSRFPath         = "/panel/"
+	DefaultCSRFCookie       = "XSRF-TOKEN"
+	DefaultCSRFPattern      = `<meta\s+name="csrf-token"\s+content="([^"]+)"`
+	DefaultCSRFCookieHeader = "X-XSRF-TOKEN" // Header echoing a cookie token
+	DefaultCSRFHTMLHeader   = "X-CSRF-Token" // Header carrying an HTML token
+)
+
+// maxCSRFPageSize caps the panel page read while extracting a token
+const maxCSRFPageSize = 2 * 1024 * 1024
+
+// ErrCSRFTokenNotFound is returned when the configured extraction finds no token
+var ErrCSRFTokenNotFound = errors.New("CSRF token not found")
+
+// CSRFConfig configures CSRF token handling for forks protecting panel POSTs.
+// Empty fields use the defaults of the selected mode.
+type CSRFConfig struct {
+	Mode      CSRFMode
+	Path      string // Page fetched after login to obtain the token
+	Cookie    string // Cookie carrying the token (cookie mode)
+	Pattern   string // Regex with one capture group extracting the token (html mode)
+	Header    string // Request header carrying the token
+	FormField string // Form field carrying the token instead of the header (body-less POSTs)
+}
+
+// csrfState compiled CSRF settings and the current token
+type csrfState struct {
+	config    CSRFConfig
+	pattern   *regexp.Regexp
+	token     string
+	refreshed bool // A 403-triggered refresh happened in the current cycle
+}
+
+// ParseCSRFMode parses an xui_csrf_mode value
+func ParseCSRFMode(value string) (CSRFMode, error) {
+	switch mode := CSRFMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case CSRFOff, CSRFCookie, CSRFHTML:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown xui_csrf_mode %q (known: cookie, html)", value)
+	}
+}
+
+// SetCSRF enables CSRF handling (inert when config.Mode is CSRFOff)
+func (a *XUIAuth) SetCSRF(config CSRFConfig) error {
+	if config.Mode == CSRFOff {
+		a.mutex.Lock()
+		a.csrf = nil
+		a.mutex.Unlock()
+		return nil
+	}
+	if _, err := ParseCSRFMode(string(config.Mode)); err != nil {
+		return err
+	}
+
+	if config.Path == "" {
+		config.Path = DefaultCSRFPath
+	}
+	state := &csrfState{config: config}
+	switch config.Mode {
+	case CSRFCookie:
+		if config.Cookie == "" {
+			config.Cookie = DefaultCSRFCookie
+		}
+		if config.Header == "" {
+			config.Header = DefaultCSRFCookieHeader
+		}
+	case CSRFHTML:
+		if config.Pattern == "" {
+			config.Pattern = DefaultCSRFPattern
+		}
+		if config.Header == "" {
+			config.Header = DefaultCSRFHTMLHeader
+		}
+		pattern, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid CSRF pattern: %w", err)
+		}
+		if pattern.NumSubexp() < 1 {
+			return fmt.Errorf("invalid CSRF pattern: needs a capture group for the token")
+		}
+		state.pattern = pattern
+	}
+	state.config = config
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.csrf = state
+	return nil
+}
+
+// StartCycle allows one 403-triggered CSRF token refresh in the coming cycle
+func (a *XUIAuth) StartCycle() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.csrf != nil {
+		a.csrf.refreshed = false
+	}
+}
+
+// CSRFToken returns the current CSRF token ("" when off or not fetched)
+func (a *XUIAuth) CSRFToken() string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.csrf == nil {
+		return ""
+	}
+	return a.csrf.token
+}
+
+// fetchCSRFTokenLocked fetches the configured page with the session and extracts the
+// token. loginResp is checked first for a cookie token. Callers hold a.mutex.
+func (a *XUIAuth) fetchCSRFTokenLocked(loginResp *http.Response) error {
+	config := a.csrf.config
+	if config.Mode == CSRFCookie && loginResp != nil {
+		if token := cookieValue(loginResp.Cookies(), config.Cookie); token != "" {
+			a.csrf.token = token
+			return nil
+		}
+	}
+
+	// The session cookie comes from the jar
+	req, err := http.NewRequest("GET", a.baseURL+config.Path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create CSRF token request: %w", err)
+	}
+
+	resp, err := a.send(a.client, req)
+	if err != nil {
+		return fmt.Errorf("CSRF token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token string
+	switch config.Mode {
+	case CSRFCookie:
+		token = cookieValue(resp.Cookies(), config.Cookie)
+	case CSRFHTML:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxCSRFPageSize))
+		if err != nil {
+			return fmt.Errorf("failed to read CSRF token page: %w", err)
+		}
+		if match := a.csrf.pattern.FindSubmatch(body); match != nil {
+			token = string(match[1])
+		}
+	}
+	if token == "" {
+		return fmt.Errorf("%w on %s (mode %s)", ErrCSRFTokenNotFound, config.Path, config.Mode)
+	}
+
+	a.csrf.token = token
+	return nil
+}
+
+// attachCSRF adds the token to a panel request (header, or form field for body-less POSTs).
+// In cookie mode the token cookie is added too: the jar scopes it to the page that set it.
+func (a *XUIAuth) attachCSRF(req *http.Request, csrf *csrfState, token string) {
+	if token == "" {
+		return
+	}
+	config := csrf.config
+	if config.Mode == CSRFCookie {
+		req.AddCookie(&http.Cookie{Name: config.Cookie, Value: token})
+	}
+
+	if config.FormField != "" && req.Method == http.MethodPost && req.Body == nil {
+		form := url.Values{config.FormField: {token}}.Encode()
+		req.Body = io.NopCloser(strings.NewReader(form))
+		req.ContentLength = int64(len(form))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(form)), nil }
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return
+	}
+	req.Header.Set(config.Header, token)
+}
+
+// do sends a panel request with client. When CSRF handling is on and the panel answers with
+// a CSRF 403, the token is refreshed and the request retried, at most once per cycle.
+func (a *XUIAuth) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := a.send(client, req)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+
+	a.mutex.RLock()
+	enabled := a.csrf != nil
+	a.mutex.RUnlock()
+	if !enabled {
+		return resp, nil
+	}
+
+	// Peek at the body to recognize a CSRF rejection, then restore it for the caller
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if !isCSRFRejection(body) {
+		return resp, nil
+	}
+
+	a.mutex.Lock()
+	if a.csrf == nil || a.csrf.refreshed || a.sessionToken == "" {
+		a.mutex.Unlock()
+		return resp, nil
+	}
+	a.csrf.refreshed = true
+	refreshErr := a.fetchCSRFTokenLocked(nil)
+	a.mutex.Unlock()
+	if refreshErr != nil {
+		return nil, fmt.Errorf("failed to refresh CSRF token: %w", refreshErr)
+	}
+
+	retry, err := a.rebuildRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return a.send(client, retry)
+}
+
+// isCSRFRejection recognizes the CSRF error bodies of the known forks
+func isCSRFRejection(body []byte) bool {
+	lower := strings.ToLower(string(body))
+	return strings.Contains(lower, "csrf") || strings.Contains(lower, "xsrf")
+}
+
+// cookieValue returns the value of the named cookie
+func cookieValue(cookies []*http.Cookie, name string) string {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return cookie.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panelEndpoints are the panel POSTs made by the monitor and subscription clients
+var panelEndpoints = []string{
+	"/server/status",
+	"/panel/inbound/onlines",
+	"/panel/setting/defaultSettings",
+	"/panel/inbound/list",
+}
+
+// csrfPanel is a fake fork requiring a CSRF token on every panel POST
+type csrfPanel struct {
+	mode      CSRFMode
+	formField string // Token expected as form field instead of header
+
+	mutex      sync.Mutex
+	token      string
+	issued     int  // Tokens handed out
+	emptyPage  bool // Index page carries no token
+	rotateNext bool // Invalidate the token before the next POST
+	accepted   map[string]int
+}
+
+func newCSRFPanel(mode CSRFMode) *csrfPanel {
+	return &csrfPanel{mode: mode, accepted: make(map[string]int)}
+}
+
+func (p *csrfPanel) issue() string {
+	p.issued++
+	p.token = fmt.Sprintf("token-%d", p.issued)
+	return p.token
+}
+
+func (p *csrfPanel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	switch r.URL.Path {
+	case "/login":
+		http.SetCookie(w, &http.Cookie{Name: "3x-ui", Value: "session-token"})
+		w.Write([]byte(`{"success": true, "msg": ""}`))
+		return
+	case "/panel/":
+		if p.emptyPage {
+			w.Write([]byte(`<html><head></head></html>`))
+			return
+		}
+		token := p.issue()
+		if p.mode == CSRFCookie {
+			http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: token})
+			w.Write([]byte(`<html></html>`))
+			return
+		}
+		fmt.Fprintf(w, `<html><head><meta name="csrf-token" content="%s"></head></html>`, token)
+		return
+	}
+
+	if p.rotateNext {
+		p.rotateNext = false
+		p.token = "rotated"
+	}
+
+	var got string
+	switch {
+	case p.formField != "":
+		got = r.FormValue(p.formField)
+	case p.mode == CSRFCookie:
+		got = r.Header.Get("X-XSRF-TOKEN")
+		if cookie, err := r.Cookie("XSRF-TOKEN"); err != nil || cookie.Value != got {
+			got = ""
+		}
+	default:
+		got = r.Header.Get("X-CSRF-Token")
+	}
+	if got == "" || got != p.token {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"success": false, "msg": "invalid CSRF token"}`))
+		return
+	}
+	p.accepted[r.URL.Path]++
+	w.Write([]byte(`{"success": true, "obj": null}`))
+}
+
+func (p *csrfPanel) acceptedCount(path string) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.accepted[path]
+}
+
+func loginWithCSRF(t *testing.T, panel *csrfPanel, config CSRFConfig) (*XUIAuth, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(panel)
+	t.Cleanup(server.Close)
+
+	auth := NewXUIAuth(server.URL, "admin", "password123")
+	require.NoError(t, auth.SetCSRF(config))
+	require.NoError(t, auth.Login())
+	return auth, server
+}
+
+func doPanelPost(t *testing.T, auth *XUIAuth, path string) int {
+	t.Helper()
+	req, err := auth.GetAuthenticatedRequest("POST", path, nil)
+	require.NoError(t, err)
+	resp, err := auth.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestXUIAuth_CSRF_Cookie(t *testing.T) {
+	panel := newCSRFPanel(CSRFCookie)
+	auth, _ := loginWithCSRF(t, panel, CSRFConfig{Mode: CSRFCookie})
+	assert.Equal(t, "token-1", auth.CSRFToken())
+
+	for _, path := range panelEndpoints {
+		assert.Equal(t, http.StatusOK, doPanelPost(t, auth, path), path)
+		assert.Equal(t, 1, panel.acceptedCount(path), path)
+	}
+}
+
+func TestXUIAuth_CSRF_HTML(t *testing.T) {
+	panel := newCSRFPanel(CSRFHTML)
+	auth, _ := loginWithCSRF(t, panel, CSRFConfig{Mode: CSRFHTML})
+	assert.Equal(t, "token-1", auth.CSRFToken())
+
+	for _, path := range panelEndpoints {
+		assert.Equal(t, http.StatusOK, doPanelPost(t, auth, path), path)
+	}
+}
+
+func TestXUIAuth_CSRF_FormField(t *testing.T) {
+	panel := newCSRFPanel(CSRFHTML)
+	panel.formField = "_csrf"
+	auth, _ := loginWithCSRF(t, panel, CSRFConfig{Mode: CSRFHTML, FormField: "_csrf"})
+
+	assert.Equal(t, http.StatusOK, doPanelPost(t, auth, "/server/status"))
+
+	// The regenerated form carries the refreshed token
+	panel.mutex.Lock()
+	panel.rotateNext = true
+	panel.mutex.Unlock()
+	auth.StartCycle()
+	assert.Equal(t, http.StatusOK, doPanelPost(t, auth, "/server/status"))
+	assert.Equal(t, "token-2", auth.CSRFToken())
+	assert.Equal(t, 2, panel.acceptedCount("/server/status"))
+}
+
+func TestXUIAuth_CSRF_RefreshOn403(t *testing.T) {
+	panel := newCSRFPanel(CSRFHTML)
+	auth, _ := loginWithCSRF(t, panel, CSRFConfig{Mode: CSRFHTML})
+
+	// The panel rotates its token: the 403 triggers one refresh and a successful retry
+	panel.mutex.Lock()
+	panel.token = "expired"
+	panel.mutex.Unlock()
+	auth.StartCycle()
+	assert.Equal(t, http.StatusOK, doPanelPost(t, auth, "/server/status"))
+	assert.Equal(t, "token-2", auth.CSRFToken())
+
+	// At most one refresh per cycle
+	panel.mutex.Lock()
+	panel.token = "expired"
+	panel.mutex.Unlock()
+	assert.Equal(t, http.StatusForbidden, doPanelPost(t, auth, "/panel/inbound/list"))
+	assert.Equal(t, "token-2", auth.CSRFToken())
+
+	auth.StartCycle()
+	assert.Equal(t, http.StatusOK, doPanelPost(t, auth, "/panel/inbound/list"))
+	assert.Equal(t, "token-3", auth.CSRFToken())
+}
+
+func TestXUIAuth_CSRF_ExtractionFails(t *testing.T) {
+	panel := newCSRFPanel(CSRFHTML)
+	panel.emptyPage = true
+	server := httptest.NewServer(panel)
+	defer server.Close()
+
+	auth := NewXUIAuth(server.URL, "admin", "password123")
+	require.NoError(t, auth.SetCSRF(CSRFConfig{Mode: CSRFHTML}))
+
+	err := auth.Login()
+	assert.ErrorIs(t, err, ErrCSRFTokenNotFound)
+	assert.False(t, auth.IsAuthenticated(), "login must be retried while no token is available")
+}
+
+func TestXUIAuth_CSRF_OffIsInert(t *testing.T) {
+	var headers http.Header
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "3x-ui", Value: "session-token"})
+			w.Write([]byte(`{"success": true, "msg": ""}`))
+			return
+		}
+		headers = r.Header.Clone()
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`invalid csrf token`))
+	}))
+	defer server.Close()
+
+	auth := NewXUIAuth(server.URL, "admin", "password123")
+	require.NoError(t, auth.SetCSRF(CSRFConfig{}))
+	require.NoError(t, auth.Login())
+	assert.Empty(t, auth.CSRFToken())
+
+	// No index fetch, no token header, no retry on 403
+	assert.Equal(t, http.StatusForbidden, doPanelPost(t, auth, "/server/status"))
+	assert.Equal(t, 2, calls)
+	assert.Empty(t, headers.Get("X-CSRF-Token"))
+	assert.Empty(t, headers.Get("X-XSRF-TOKEN"))
+}
+
+func TestXUIAuth_SetCSRF_Invalid(t *testing.T) {
+	auth := NewXUIAuth("http://127.0.0.1:1", "admin", "password123")
+	assert.Error(t, auth.SetCSRF(CSRFConfig{Mode: "meta"}))
+	assert.Error(t, auth.SetCSRF(CSRFConfig{Mode: CSRFHTML, Pattern: "("}))
+	assert.Error(t, auth.SetCSRF(CSRFConfig{Mode: CSRFHTML, Pattern: "csrf-token"}))
+
+	_, err := ParseCSRFMode("html")
+	assert.NoError(t, err)
+	_, err = ParseCSRFMode("meta")
+	assert.Error(t, err)
+}
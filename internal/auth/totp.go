@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters of the 3x-ui two-factor authentication (RFC 6238 defaults)
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+)
+
+// ParseTOTPSecret decodes a base32 TOTP secret as shown by the panel when two-factor
+// authentication is enabled, or an otpauth:// URI carrying it. Case, spaces and padding are
+// ignored.
+func ParseTOTPSecret(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "otpauth://") {
+		uri, err := url.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TOTP URI: %w", err)
+		}
+		value = uri.Query().Get("secret")
+	}
+	value = strings.TrimRight(strings.ToUpper(strings.ReplaceAll(value, " ", "")), "=")
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(value)
+	if err != nil || len(secret) == 0 {
+		return nil, fmt.Errorf("invalid TOTP secret (expected base32)")
+	}
+	return secret, nil
+}
+
+// TOTPCode returns the 6-digit TOTP code (HMAC-SHA1, 30 s period) of secret at t
+func TOTPCode(secret []byte, t time.Time) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(t.Unix()/int64(totpPeriod/time.Second)))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// SetLoginSecret sends the panel's secret token with every login (older 3x-ui versions with
+// "loginSecret" enabled), empty for none
+func (a *XUIAuth) SetLoginSecret(secret string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.loginSecret = secret
+}
+
+// SetTOTPSecret sends a two-factor code generated from secret with every login (3x-ui with
+// two-factor authentication enabled), nil for none
+func (a *XUIAuth) SetTOTPSecret(secret []byte) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.totpSecret = secret
+}
+
+// loginFactors adds the login secret and the current two-factor code to the login form
+func (a *XUIAuth) loginFactors(data url.Values) {
+	if a.loginSecret != "" {
+		data.Set("loginSecret", a.loginSecret)
+	}
+	if len(a.totpSecret) > 0 {
+		data.Set("twoFactorCode", TOTPCode(a.totpSecret, time.Now()))
+	}
+}
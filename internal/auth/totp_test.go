@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	// SHA1 test vectors of RFC 6238 appendix B, truncated to 6 digits
+	secret := []byte("12345678901234567890")
+	for unix, code := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		assert.Equal(t, code, TOTPCode(secret, time.Unix(unix, 0)), unix)
+	}
+}
+
+func TestParseTOTPSecret(t *testing.T) {
+	expected := []byte("12345678901234567890")
+	for _, value := range []string{
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"gezd gnbv gy3t qojq gezd gnbv gy3t qojq",
+		"otpauth://totp/3x-ui:admin?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=3x-ui",
+	} {
+		secret, err := ParseTOTPSecret(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, secret)
+	}
+
+	secret, err := ParseTOTPSecret("JBSWY3DPEE======")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("Hello!"), secret)
+
+	_, err = ParseTOTPSecret("not base32!")
+	assert.Error(t, err)
+	_, err = ParseTOTPSecret("")
+	assert.Error(t, err)
+}
+
+func TestXUIAuth_Login_SecondFactors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	var form map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "test-session-token"})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success": true, "msg": ""}`))
+	}))
+	defer server.Close()
+
+	// Without extra factors only the credentials are sent
+	auth := NewXUIAuth(server.URL, "admin", "password123")
+	require.NoError(t, auth.Login())
+	assert.Equal(t, map[string]string{"username": "admin", "password": "password123"}, form)
+
+	auth.SetLoginSecret("panel-secret")
+	auth.SetTOTPSecret(secret)
+	before := TOTPCode(secret, time.Now())
+	require.NoError(t, auth.Login())
+	assert.Equal(t, "panel-secret", form["loginSecret"])
+	assert.Contains(t, []string{before, TOTPCode(secret, time.Now())}, form["twoFactorCode"])
+}
@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"xhub-agent/internal/sanitize"
+)
+
+// volatileFields are the inbound keys left out of snapshots: traffic counters change every
+// cycle without the configuration changing, and are not needed to restore the panel
+var volatileFields = []string{"up", "down", "allTime", "clientStats"}
+
+// Snapshot is the 3x-ui inbound configuration at one point in time
+type Snapshot struct {
+	Inbounds   []byte    // JSON array of the inbounds, keys sorted, volatile fields removed
+	SHA256     string    // Hex SHA-256 of Inbounds
+	Count      int       // Number of inbounds
+	CapturedAt time.Time // When the inbound list was fetched
+}
+
+// NewSnapshot builds a snapshot from a /panel/inbound/list response body. Every inbound is
+// kept in full (settings, streamSettings, sniffing, ...), including client credentials.
+func NewSnapshot(body []byte, capturedAt time.Time) (*Snapshot, error) {
+	if err := sanitize.CheckJSON(body); err != nil {
+		return nil, fmt.Errorf("failed to parse inbound list: %w", err)
+	}
+	var resp struct {
+		Success bool                         `json:"success"`
+		Msg     string                       `json:"msg"`
+		Obj     []map[string]json.RawMessage `json:"obj"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse inbound list: %w", err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("API error: %s", sanitize.String(resp.Msg))
+	}
+
+	inbounds := make([]map[string]json.RawMessage, 0, len(resp.Obj))
+	for _, inbound := range resp.Obj {
+		if inbound == nil {
+			continue
+		}
+		for _, field := range volatileFields {
+			delete(inbound, field)
+		}
+		inbounds = append(inbounds, inbound)
+	}
+
+	// Map keys are sorted, so an unchanged configuration always gives the same bytes
+	data, err := json.Marshal(inbounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return &Snapshot{
+		Inbounds:   data,
+		SHA256:     hex.EncodeToString(sum[:]),
+		Count:      len(inbounds),
+		CapturedAt: capturedAt,
+	}, nil
+}
+
+// Tracker decides which snapshots are sent: the first one and every one whose configuration
+// differs from the last delivered one. After a failed send no snapshot is due before the
+// retry time, so an unreachable xhub does not get the full configuration every cycle.
+type Tracker struct {
+	mutex     sync.Mutex
+	delivered string    // SHA-256 of the last delivered snapshot
+	retryAt   time.Time // No send before this time after a failure
+}
+
+// NewTracker creates a tracker for which the first snapshot is due
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Due reports whether snapshot must be sent at now
+func (t *Tracker) Due(snapshot *Snapshot, now time.Time) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return snapshot.SHA256 != t.delivered && !now.Before(t.retryAt)
+}
+
+// Delivered records that xhub accepted snapshot
+func (t *Tracker) Delivered(snapshot *Snapshot) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.delivered = snapshot.SHA256
+	t.retryAt = time.Time{}
+}
+
+// Failed records a failed send; no snapshot is due before now+wait
+func (t *Tracker) Failed(now time.Time, wait time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.retryAt = now.Add(wait)
+}
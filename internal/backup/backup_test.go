@@ -0,0 +1,72 @@
+package backup
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const inboundList = `{"success":true,"msg":"","obj":[
+	{"id":1,"up":1024,"down":2048,"allTime":3072,"remark":"vless","port":443,"protocol":"vless",
+	 "settings":"{\"clients\":[{\"id\":\"0c4b\",\"email\":\"alice@example.com\"}]}",
+	 "clientStats":[{"email":"alice@example.com","up":1024,"down":2048}]},
+	null
+]}`
+
+func TestNewSnapshot(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	snapshot, err := NewSnapshot([]byte(inboundList), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, snapshot.Count)
+	assert.Equal(t, now, snapshot.CapturedAt)
+	assert.Len(t, snapshot.SHA256, 64)
+
+	var inbounds []map[string]interface{}
+	require.NoError(t, json.Unmarshal(snapshot.Inbounds, &inbounds))
+	require.Len(t, inbounds, 1)
+	assert.Equal(t, "vless", inbounds[0]["remark"])
+	assert.Contains(t, inbounds[0]["settings"], `"id":"0c4b"`, "the configuration is kept in full")
+	for _, field := range volatileFields {
+		assert.NotContains(t, inbounds[0], field)
+	}
+
+	// Traffic counters do not change the snapshot, the configuration does
+	traffic, err := NewSnapshot([]byte(`{"success":true,"obj":[{"port":443,"up":99,"id":1,"remark":"vless","protocol":"vless",
+		"settings":"{\"clients\":[{\"id\":\"0c4b\",\"email\":\"alice@example.com\"}]}"}]}`), now)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.SHA256, traffic.SHA256, "key order and counters are ignored")
+
+	changed, err := NewSnapshot([]byte(`{"success":true,"obj":[{"id":1,"remark":"vless","port":8443,"protocol":"vless",
+		"settings":"{\"clients\":[{\"id\":\"0c4b\",\"email\":\"alice@example.com\"}]}"}]}`), now)
+	require.NoError(t, err)
+	assert.NotEqual(t, snapshot.SHA256, changed.SHA256)
+}
+
+func TestNewSnapshot_Errors(t *testing.T) {
+	_, err := NewSnapshot([]byte(`{"success":false,"msg":"denied"}`), time.Now())
+	assert.ErrorContains(t, err, "API error: denied")
+	_, err = NewSnapshot([]byte(`<html>`), time.Now())
+	assert.Error(t, err)
+}
+
+func TestTracker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	first := &Snapshot{SHA256: "a"}
+	second := &Snapshot{SHA256: "b"}
+	tracker := NewTracker()
+
+	assert.True(t, tracker.Due(first, now), "the first snapshot is due")
+	tracker.Delivered(first)
+	assert.False(t, tracker.Due(first, now), "unchanged configuration")
+	assert.True(t, tracker.Due(second, now))
+
+	// A failed send waits before the next attempt
+	tracker.Failed(now, time.Minute)
+	assert.False(t, tracker.Due(second, now.Add(30*time.Second)))
+	assert.True(t, tracker.Due(second, now.Add(time.Minute)))
+	tracker.Delivered(second)
+	assert.False(t, tracker.Due(second, now.Add(time.Minute)))
+}
@@ -0,0 +1,109 @@
+package certfile
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/sanitize"
+	"xhub-agent/pkg/logger"
+)
+
+// Collector registry name and default interval (certificates change rarely)
+const (
+	CollectorName   = "cert_expiry"
+	DefaultInterval = time.Hour
+)
+
+// WarnBefore is how long before expiry a certificate is logged as expiring soon
+const WarnBefore = 14 * 24 * time.Hour
+
+// maxCertSize caps a certificate file read (1 MiB)
+const maxCertSize = 1 << 20
+
+// Source a certificate file referenced by a proxy config
+type Source struct {
+	Path   string // Certificate file on disk
+	Origin string // Referencing config, e.g. "hysteria2" or "inbound:443"
+}
+
+// Collector reads the certificate files referenced by the Hysteria2 and inbound TLS configs
+// and reports their expiry
+type Collector struct {
+	sources func() []Source // Current certificate files (re-evaluated each collection)
+	logger  *logger.Logger
+	now     func() time.Time // injectable for tests
+}
+
+// NewCollector creates a collector reading the files returned by sources
+func NewCollector(sources func() []Source, logger *logger.Logger) *Collector {
+	return &Collector{sources: sources, logger: logger, now: time.Now}
+}
+
+// Collect returns the expiry of every referenced certificate file (sorted by path).
+// Missing or unreadable files are reported with their error instead of an expiry.
+func (c *Collector) Collect() []monitor.CertExpiry {
+	seen := make(map[string]bool)
+	var result []monitor.CertExpiry
+	for _, source := range c.sources() {
+		if source.Path == "" || seen[source.Path] {
+			continue
+		}
+		seen[source.Path] = true
+
+		expiry := monitor.CertExpiry{Path: source.Path, Origin: source.Origin}
+		cert, err := ReadCertificate(source.Path)
+		if err != nil {
+			expiry.Error = err.Error()
+			c.logger.Debugf("Failed to read certificate %s (%s): %v", source.Path, source.Origin, err)
+			result = append(result, expiry)
+			continue
+		}
+
+		expiry.Subject = cert.Subject.CommonName
+		expiry.NotAfter = cert.NotAfter.Unix()
+		if remaining := cert.NotAfter.Sub(c.now()); remaining <= 0 {
+			c.logger.Warnf("⚠️  Certificate %s (%s) expired on %s", source.Path, source.Origin, cert.NotAfter.Format(time.DateOnly))
+		} else if remaining < WarnBefore {
+			c.logger.Warnf("⚠️  Certificate %s (%s) expires on %s", source.Path, source.Origin, cert.NotAfter.Format(time.DateOnly))
+		}
+		result = append(result, expiry)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+// ReadCertificate parses the first certificate (the leaf) of a PEM file
+func ReadCertificate(path string) (*x509.Certificate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := sanitize.ReadLimited(f, maxCertSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM certificate in %s", path)
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate in %s: %w", path, err)
+		}
+		return cert, nil
+	}
+}
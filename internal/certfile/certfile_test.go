@@ -0,0 +1,104 @@
+package certfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/report"
+	"xhub-agent/pkg/logger"
+)
+
+// fixtureCert is a self-signed certificate for node.example.com expiring 2035-01-01
+const fixtureCert = "testdata/node.example.com.pem"
+
+var fixtureNotAfter = time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestLogger(t *testing.T) (*logger.Logger, string) {
+	t.Helper()
+	logPath := filepath.Join(t.TempDir(), "test.log")
+	log, err := logger.NewLogger(logPath, "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+	return log, logPath
+}
+
+func TestReadCertificate(t *testing.T) {
+	cert, err := ReadCertificate(fixtureCert)
+	require.NoError(t, err)
+	assert.Equal(t, "node.example.com", cert.Subject.CommonName)
+	assert.True(t, fixtureNotAfter.Equal(cert.NotAfter))
+
+	_, err = ReadCertificate(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+
+	notPEM := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0600))
+	_, err = ReadCertificate(notPEM)
+	assert.Error(t, err)
+}
+
+func TestCollector_ReportsExpiry(t *testing.T) {
+	log, _ := newTestLogger(t)
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	c := NewCollector(func() []Source {
+		return []Source{
+			{Path: fixtureCert, Origin: "hysteria2"},
+			{Path: fixtureCert, Origin: "inbound:443"}, // Same file, reported once
+			{Path: missing, Origin: "inbound:8443"},
+			{Path: "", Origin: "inbound:2053"},
+		}
+	}, log)
+
+	expiry := c.Collect()
+	require.Len(t, expiry, 2)
+
+	byPath := map[string]monitor.CertExpiry{}
+	for _, e := range expiry {
+		byPath[e.Path] = e
+	}
+	assert.Equal(t, monitor.CertExpiry{
+		Path:     fixtureCert,
+		Origin:   "hysteria2",
+		Subject:  "node.example.com",
+		NotAfter: fixtureNotAfter.Unix(),
+	}, byPath[fixtureCert])
+
+	assert.Equal(t, "inbound:8443", byPath[missing].Origin)
+	assert.Zero(t, byPath[missing].NotAfter)
+	assert.NotEmpty(t, byPath[missing].Error, "unreadable files are reported, not dropped")
+
+	// The expiry reaches the report
+	data := &monitor.ServerStatusData{CertExpiry: expiry}
+	pbData := report.ConvertToProto(data)
+	require.Len(t, pbData.CertExpiry, 2)
+	assert.Equal(t, fixtureNotAfter.Unix(), pbData.CertExpiry[1].NotAfter)
+}
+
+func TestCollector_WarnsBeforeExpiry(t *testing.T) {
+	log, logPath := newTestLogger(t)
+	c := NewCollector(func() []Source { return []Source{{Path: fixtureCert, Origin: "hysteria2"}} }, log)
+
+	c.now = func() time.Time { return fixtureNotAfter.Add(-365 * 24 * time.Hour) }
+	c.Collect()
+	content, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "Certificate")
+
+	c.now = func() time.Time { return fixtureNotAfter.Add(-3 * 24 * time.Hour) }
+	c.Collect()
+	content, err = os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "expires on 2035-01-01")
+
+	c.now = func() time.Time { return fixtureNotAfter.Add(time.Hour) }
+	c.Collect()
+	content, err = os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "expired on 2035-01-01")
+}
@@ -0,0 +1,189 @@
+package certrenew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/runner"
+)
+
+// Supported ACME clients
+const (
+	ClientCertbot = "certbot"
+	ClientAcmeSh  = "acme.sh"
+)
+
+// Renewal triggers, reported to xhub
+const (
+	TriggerExpiry  = "expiry"
+	TriggerCommand = "command"
+)
+
+// Renewal defaults
+const (
+	DefaultHysteria2Service  = "hysteria-server" // systemd unit of the official Hysteria2 installer
+	renewTimeout             = 5 * time.Minute   // Longest an ACME client run may take
+	serviceTimeout           = 30 * time.Second  // Longest a service restart may take
+	acmeShSkipped            = 2                 // acme.sh --renew exit status of a certificate not due
+	acmeShDefaultInstallPath = "/root/.acme.sh/acme.sh"
+)
+
+// domainPattern matches the domain argument of a renewal, so it cannot be taken for an option
+var domainPattern = regexp.MustCompile(`^[A-Za-z0-9*][A-Za-z0-9.*-]*$`)
+
+// ParseClient validates a cert_renew_client value ("" disables renewal)
+func ParseClient(value string) (string, error) {
+	switch value {
+	case "", ClientCertbot, ClientAcmeSh:
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown cert_renew_client %q (known: certbot, acme.sh)", value)
+	}
+}
+
+// CheckDomain validates the domain of a renewal ("" renews every due certificate)
+func CheckDomain(domain string) error {
+	if domain != "" && !domainPattern.MatchString(domain) {
+		return fmt.Errorf("invalid domain %q", domain)
+	}
+	return nil
+}
+
+// Certificate is a certificate file replaced by a renewal
+type Certificate struct {
+	Path             string
+	Origin           string    // Referencing config, e.g. "hysteria2" or "inbound:443"
+	PreviousNotAfter time.Time // Expiry before the renewal, zero when the file was unreadable
+	NotAfter         time.Time
+}
+
+// Result is the outcome of a renewal
+type Result struct {
+	Trigger      string // TriggerExpiry or TriggerCommand
+	CommandID    string // Command that requested the renewal, empty when triggered by expiry
+	Client       string // ACME client run
+	Domain       string // Domain asked for, empty for every due certificate
+	StartedAt    time.Time
+	Duration     time.Duration
+	Certificates []Certificate // Certificate files that changed
+	Reloaded     []string      // Services reloaded to pick up the new certificates
+	Output       string        // Tail of the client output
+	Error        string        // Why the renewal or a reload failed, empty on success
+}
+
+// Summary describes the result in one line
+func (r Result) Summary() string {
+	var parts []string
+	if len(r.Certificates) == 0 {
+		parts = append(parts, "no certificate renewed")
+	}
+	for _, cert := range r.Certificates {
+		parts = append(parts, fmt.Sprintf("%s (%s) renewed until %s", cert.Path, cert.Origin, cert.NotAfter.Format(time.DateOnly)))
+	}
+	if len(r.Reloaded) > 0 {
+		parts = append(parts, "reloaded "+strings.Join(r.Reloaded, ", "))
+	}
+	if r.Error != "" {
+		parts = append(parts, "error: "+r.Error)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Renewer renews certificates through certbot or acme.sh and restarts services
+type Renewer struct {
+	client string
+	run    runner.Func // injectable for tests
+}
+
+// NewRenewer creates a renewer running client (ClientCertbot or ClientAcmeSh)
+func NewRenewer(client string) (*Renewer, error) {
+	if _, err := ParseClient(client); err != nil || client == "" {
+		return nil, fmt.Errorf("unknown ACME client %q", client)
+	}
+	return &Renewer{client: client, run: runner.Run}, nil
+}
+
+// SetRunnerForTesting replaces the command runner (for testing only)
+func (r *Renewer) SetRunnerForTesting(run runner.Func) {
+	r.run = run
+}
+
+// Client returns the ACME client run by the renewer
+func (r *Renewer) Client() string {
+	return r.client
+}
+
+// Renew runs the ACME client for domain, or for every certificate it considers due when
+// domain is empty. force renews even certificates that are not due yet. It returns the tail
+// of the client output, also on failure.
+func (r *Renewer) Renew(ctx context.Context, domain string, force bool) (string, error) {
+	if err := CheckDomain(domain); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, renewTimeout)
+	defer cancel()
+
+	name, args := r.command(domain, force)
+	out, err := r.run(ctx, name, args...)
+	output := runner.Tail(string(out), runner.MaxOutput)
+	var exitErr *exec.ExitError
+	if r.client == ClientAcmeSh && errors.As(err, &exitErr) && exitErr.ExitCode() == acmeShSkipped {
+		return output, nil
+	}
+	if err != nil {
+		return output, fmt.Errorf("%s failed: %w", r.client, err)
+	}
+	return output, nil
+}
+
+// command returns the ACME client invocation of a renewal
+func (r *Renewer) command(domain string, force bool) (string, []string) {
+	if r.client == ClientCertbot {
+		args := []string{"renew", "--non-interactive"}
+		if domain != "" {
+			args = append(args, "--cert-name", domain)
+		}
+		if force {
+			args = append(args, "--force-renewal")
+		}
+		return "certbot", args
+	}
+
+	var args []string
+	if domain != "" {
+		args = []string{"--renew", "-d", domain}
+	} else {
+		args = []string{"--cron"}
+	}
+	if force {
+		args = append(args, "--force")
+	}
+	return acmeShPath(), args
+}
+
+// acmeShPath returns acme.sh from PATH, or from its default install directory
+func acmeShPath() string {
+	if path, err := exec.LookPath(ClientAcmeSh); err == nil {
+		return path
+	}
+	if _, err := os.Stat(acmeShDefaultInstallPath); err == nil {
+		return acmeShDefaultInstallPath
+	}
+	return ClientAcmeSh
+}
+
+// RestartService restarts a systemd unit, e.g. to load a renewed Hysteria2 certificate
+func (r *Renewer) RestartService(ctx context.Context, unit string) error {
+	ctx, cancel := context.WithTimeout(ctx, serviceTimeout)
+	defer cancel()
+	if out, err := r.run(ctx, "systemctl", "restart", unit); err != nil {
+		return fmt.Errorf("systemctl restart %s failed: %w (%s)", unit, err, strings.TrimSpace(runner.Tail(string(out), runner.MaxOutput)))
+	}
+	return nil
+}
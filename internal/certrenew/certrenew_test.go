@@ -0,0 +1,116 @@
+package certrenew
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/runner"
+)
+
+// recordingRenewer creates a renewer recording its invocations and answering with out and err
+func recordingRenewer(t *testing.T, client string, out string, err error) (*Renewer, *[][]string) {
+	r, newErr := NewRenewer(client)
+	require.NoError(t, newErr)
+	var calls [][]string
+	r.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return []byte(out), err
+	})
+	return r, &calls
+}
+
+func TestParseClient(t *testing.T) {
+	for _, value := range []string{"", ClientCertbot, ClientAcmeSh} {
+		_, err := ParseClient(value)
+		assert.NoError(t, err, value)
+	}
+	_, err := ParseClient("lego")
+	assert.ErrorContains(t, err, `unknown cert_renew_client "lego"`)
+
+	_, err = NewRenewer("")
+	assert.Error(t, err)
+}
+
+func TestRenewer_Renew_Certbot(t *testing.T) {
+	r, calls := recordingRenewer(t, ClientCertbot, "Congratulations, all renewals succeeded", nil)
+
+	out, err := r.Renew(context.Background(), "", false)
+	require.NoError(t, err)
+	assert.Contains(t, out, "all renewals succeeded")
+	_, err = r.Renew(context.Background(), "node.example.com", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{
+		{"certbot", "renew", "--non-interactive"},
+		{"certbot", "renew", "--non-interactive", "--cert-name", "node.example.com", "--force-renewal"},
+	}, *calls)
+}
+
+func TestRenewer_Renew_AcmeSh(t *testing.T) {
+	r, calls := recordingRenewer(t, ClientAcmeSh, "", nil)
+
+	_, err := r.Renew(context.Background(), "", true)
+	require.NoError(t, err)
+	_, err = r.Renew(context.Background(), "*.example.com", false)
+	require.NoError(t, err)
+
+	require.Len(t, *calls, 2)
+	assert.Equal(t, []string{"--cron", "--force"}, (*calls)[0][1:])
+	assert.Equal(t, []string{"--renew", "-d", "*.example.com"}, (*calls)[1][1:])
+}
+
+func TestRenewer_Renew_Errors(t *testing.T) {
+	r, calls := recordingRenewer(t, ClientCertbot, "", nil)
+	_, err := r.Renew(context.Background(), "--config-dir=/tmp", false)
+	assert.ErrorContains(t, err, "invalid domain")
+	assert.Empty(t, *calls, "options are never passed as the domain")
+
+	long := strings.Repeat("x", 2*runner.MaxOutput) + "Challenge failed"
+	r, _ = recordingRenewer(t, ClientCertbot, long, errors.New("exit status 1"))
+	out, err := r.Renew(context.Background(), "", false)
+	assert.ErrorContains(t, err, "certbot failed: exit status 1")
+	assert.Len(t, out, runner.MaxOutput)
+	assert.True(t, strings.HasSuffix(out, "Challenge failed"))
+}
+
+func TestRenewer_Renew_AcmeShSkipped(t *testing.T) {
+	skipped := exec.Command("sh", "-c", "exit 2").Run()
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, skipped, &exitErr)
+
+	r, _ := recordingRenewer(t, ClientAcmeSh, "Skipped node.example.com", skipped)
+	_, err := r.Renew(context.Background(), "node.example.com", false)
+	assert.NoError(t, err, "a certificate that is not due yet is not a failure")
+
+	r, _ = recordingRenewer(t, ClientCertbot, "", skipped)
+	_, err = r.Renew(context.Background(), "", false)
+	assert.Error(t, err)
+}
+
+func TestRenewer_RestartService(t *testing.T) {
+	r, calls := recordingRenewer(t, ClientCertbot, "", nil)
+	require.NoError(t, r.RestartService(context.Background(), DefaultHysteria2Service))
+	assert.Equal(t, [][]string{{"systemctl", "restart", "hysteria-server"}}, *calls)
+
+	r, _ = recordingRenewer(t, ClientCertbot, "Unit foo.service not found.\n", errors.New("exit status 5"))
+	err := r.RestartService(context.Background(), "foo")
+	assert.EqualError(t, err, "systemctl restart foo failed: exit status 5 (Unit foo.service not found.)")
+}
+
+func TestResult_Summary(t *testing.T) {
+	assert.Equal(t, "no certificate renewed; error: certbot failed: exit status 1",
+		Result{Error: "certbot failed: exit status 1"}.Summary())
+
+	result := Result{
+		Certificates: []Certificate{{Path: "/etc/hysteria/cert.pem", Origin: "hysteria2", NotAfter: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}},
+		Reloaded:     []string{"hysteria2"},
+	}
+	assert.Equal(t, "/etc/hysteria/cert.pem (hysteria2) renewed until 2025-03-01; reloaded hysteria2", result.Summary())
+}
@@ -0,0 +1,271 @@
+package clocksync
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// Collector registry name and default interval (clocks drift slowly)
+const (
+	CollectorName   = "clock"
+	DefaultInterval = 10 * time.Minute
+)
+
+// DefaultServer is the NTP server queried over SNTP; "none" disables the query
+const DefaultServer = "pool.ntp.org"
+
+// Offset sources, reported to xhub
+const (
+	SourceSNTP   = "sntp"
+	SourceChrony = "chrony"
+)
+
+const (
+	queryTimeout = 5 * time.Second // Bounds an SNTP query and each chronyc/timedatectl run
+	warnOffset   = time.Second     // Offsets logged as a warning, TLS and expiry checks suffer
+	ntpEpochDiff = 2208988800      // Seconds from 1900 (NTP era 0) to 1970
+)
+
+// Collector measures the offset of the system clock against NTP, with an SNTP query or from
+// chrony, and reads whether the NTP daemon considers the clock synchronized
+type Collector struct {
+	server string
+	logger *logger.Logger
+
+	query func(ctx context.Context, server string) (offset, rtt time.Duration, err error) // injectable for tests
+	run   func(ctx context.Context, name string, args ...string) ([]byte, error)          // injectable for tests
+
+	warned bool // Large offset has been logged
+}
+
+// NewCollector creates a collector querying server ("" for DefaultServer, "none" to rely on
+// chrony only)
+func NewCollector(server string, logger *logger.Logger) *Collector {
+	if server == "" {
+		server = DefaultServer
+	}
+	if server == "none" {
+		server = ""
+	}
+	return &Collector{server: server, logger: logger, query: QuerySNTP, run: runCommand}
+}
+
+// runCommand runs name and returns its standard output
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// SetQueryForTesting replaces the SNTP query (for testing only)
+func (c *Collector) SetQueryForTesting(query func(ctx context.Context, server string) (time.Duration, time.Duration, error)) {
+	c.query = query
+}
+
+// SetRunnerForTesting replaces the command runner (for testing only)
+func (c *Collector) SetRunnerForTesting(run func(ctx context.Context, name string, args ...string) ([]byte, error)) {
+	c.run = run
+}
+
+// Collect returns the clock status. The SNTP offset is preferred, chrony's estimate is used
+// when the query is disabled or fails.
+func (c *Collector) Collect() *monitor.ClockStatus {
+	status := &monitor.ClockStatus{}
+	var errs []string
+
+	tracking, trackingErr := c.output("chronyc", "tracking")
+	if trackingErr == nil {
+		if chrony, ok := ParseChronyTracking(tracking); ok {
+			status.NTPService = "chronyd"
+			status.Synchronized = chrony.Synchronized
+			status.Server = chrony.Server
+			status.OffsetMs = durationMs(chrony.Offset)
+			status.OffsetSource = SourceChrony
+		}
+	}
+	if status.NTPService == "" {
+		if show, err := c.output("timedatectl", "show"); err == nil {
+			enabled, synchronized := ParseTimedatectl(show)
+			status.Synchronized = synchronized
+			if enabled {
+				status.NTPService = "systemd-timesyncd"
+			}
+		}
+	}
+
+	if c.server != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		offset, rtt, err := c.query(ctx, c.server)
+		cancel()
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			status.OffsetMs = durationMs(offset)
+			status.RoundTripMs = durationMs(rtt)
+			status.OffsetSource = SourceSNTP
+			status.Server = c.server
+		}
+	}
+	if status.OffsetSource == "" && status.NTPService == "" && c.server == "" {
+		errs = append(errs, "no NTP server configured and chrony is not running")
+	}
+	status.Error = strings.Join(errs, "; ")
+
+	offset := time.Duration(math.Abs(status.OffsetMs) * float64(time.Millisecond))
+	if status.OffsetSource != "" && offset >= warnOffset && !c.warned {
+		c.logger.Warnf("🕰️  System clock is off by %v (%s %s), TLS and expiry checks may fail", offset.Round(time.Millisecond), status.OffsetSource, status.Server)
+		c.warned = true
+	} else if offset < warnOffset {
+		c.warned = false
+	}
+	return status
+}
+
+// output runs a command with queryTimeout
+func (c *Collector) output(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	out, err := c.run(ctx, name, args...)
+	return string(out), err
+}
+
+// durationMs converts d to milliseconds with a fraction
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ChronyTracking is the part of "chronyc tracking" the collector reports
+type ChronyTracking struct {
+	Offset       time.Duration // System clock minus NTP time
+	Synchronized bool          // Leap status is not "Not synchronised"
+	Server       string        // Reference source name
+}
+
+// ParseChronyTracking parses the output of "chronyc tracking"
+func ParseChronyTracking(output string) (ChronyTracking, bool) {
+	var tracking ChronyTracking
+	found := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Reference ID":
+			// "C0A80001 (ntp.example.com)"
+			if start, end := strings.Index(value, "("), strings.LastIndex(value, ")"); start >= 0 && end > start {
+				tracking.Server = value[start+1 : end]
+			}
+		case "System time":
+			// "0.000012345 seconds fast of NTP time"
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				continue
+			}
+			seconds, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				continue
+			}
+			if fields[2] == "slow" {
+				seconds = -seconds
+			}
+			tracking.Offset = time.Duration(seconds * float64(time.Second))
+			found = true
+		case "Leap status":
+			tracking.Synchronized = value != "Not synchronised"
+		}
+	}
+	return tracking, found
+}
+
+// ParseTimedatectl parses the output of "timedatectl show": whether an NTP service is enabled
+// and whether the clock is synchronized
+func ParseTimedatectl(output string) (enabled, synchronized bool) {
+	for _, line := range strings.Split(output, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "NTP":
+			enabled = value == "yes"
+		case "NTPSynchronized":
+			synchronized = value == "yes"
+		}
+	}
+	return enabled, synchronized
+}
+
+// QuerySNTP sends one SNTP (RFC 4330) request to server and returns the offset of the
+// system clock (positive when it is ahead) and the round trip time
+func QuerySNTP(ctx context.Context, server string) (offset, rtt time.Duration, err error) {
+	address := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		address = net.JoinHostPort(server, "123")
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return 0, 0, fmt.Errorf("SNTP query to %s failed: %w", server, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	request := make([]byte, 48)
+	request[0] = 0x23 // LI 0, version 4, mode 3 (client)
+	sent := time.Now()
+	binary.BigEndian.PutUint64(request[40:], ntpTimestamp(sent))
+	if _, err := conn.Write(request); err != nil {
+		return 0, 0, fmt.Errorf("SNTP query to %s failed: %w", server, err)
+	}
+
+	response := make([]byte, 48)
+	n, err := conn.Read(response)
+	received := time.Now()
+	if err != nil {
+		return 0, 0, fmt.Errorf("SNTP query to %s failed: %w", server, err)
+	}
+	if n < 48 {
+		return 0, 0, fmt.Errorf("SNTP query to %s failed: short response", server)
+	}
+	if mode := response[0] & 0x07; mode != 4 {
+		return 0, 0, fmt.Errorf("SNTP query to %s failed: unexpected mode %d", server, mode)
+	}
+	if response[1] == 0 {
+		return 0, 0, fmt.Errorf("SNTP query to %s failed: kiss-o'-death %q", server, response[12:16])
+	}
+	if binary.BigEndian.Uint64(response[24:]) != binary.BigEndian.Uint64(request[40:]) {
+		return 0, 0, fmt.Errorf("SNTP query to %s failed: response does not match the request", server)
+	}
+
+	serverReceived := ntpTime(binary.BigEndian.Uint64(response[32:]))
+	serverSent := ntpTime(binary.BigEndian.Uint64(response[40:]))
+	// NTP offset is server minus local; the system clock offset is the opposite
+	ntpOffset := (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2
+	rtt = received.Sub(sent) - serverSent.Sub(serverReceived)
+	return -ntpOffset, rtt, nil
+}
+
+// ntpTimestamp encodes t as a 64-bit NTP timestamp
+func ntpTimestamp(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpEpochDiff)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// ntpTime decodes a 64-bit NTP timestamp (era 0)
+func ntpTime(timestamp uint64) time.Time {
+	seconds := int64(timestamp>>32) - ntpEpochDiff
+	nanos := (timestamp & 0xffffffff) * uint64(time.Second) >> 32
+	return time.Unix(seconds, int64(nanos))
+}
@@ -0,0 +1,147 @@
+package clocksync
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/pkg/logger"
+)
+
+const chronyTracking = `Reference ID    : A9FEA97B (169.254.169.123)
+Stratum         : 4
+Ref time (UTC)  : Fri Oct 16 06:37:12 2026
+System time     : 0.000123456 seconds slow of NTP time
+Last offset     : -0.000004321 seconds
+RMS offset      : 0.000010000 seconds
+Frequency       : 12.345 ppm fast
+Leap status     : Normal
+`
+
+func newTestCollector(t *testing.T, server string) *Collector {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+	return NewCollector(server, log)
+}
+
+func TestParseChronyTracking(t *testing.T) {
+	tracking, ok := ParseChronyTracking(chronyTracking)
+	require.True(t, ok)
+	assert.Equal(t, -123456*time.Nanosecond, tracking.Offset)
+	assert.True(t, tracking.Synchronized)
+	assert.Equal(t, "169.254.169.123", tracking.Server)
+
+	tracking, ok = ParseChronyTracking("System time     : 2.5 seconds fast of NTP time\nLeap status     : Not synchronised\n")
+	require.True(t, ok)
+	assert.Equal(t, 2500*time.Millisecond, tracking.Offset)
+	assert.False(t, tracking.Synchronized)
+
+	_, ok = ParseChronyTracking("506 Cannot talk to daemon\n")
+	assert.False(t, ok)
+}
+
+func TestParseTimedatectl(t *testing.T) {
+	enabled, synchronized := ParseTimedatectl("Timezone=UTC\nNTP=yes\nNTPSynchronized=no\n")
+	assert.True(t, enabled)
+	assert.False(t, synchronized)
+}
+
+func TestQuerySNTP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// A server 3 seconds ahead of the local clock
+	go func() {
+		request := make([]byte, 48)
+		_, addr, err := conn.ReadFrom(request)
+		if err != nil {
+			return
+		}
+		response := make([]byte, 48)
+		response[0] = 0x24 // Version 4, mode 4 (server)
+		response[1] = 2    // Stratum
+		copy(response[24:32], request[40:48])
+		now := ntpTimestamp(time.Now().Add(3 * time.Second))
+		binary.BigEndian.PutUint64(response[32:], now)
+		binary.BigEndian.PutUint64(response[40:], now)
+		conn.WriteTo(response, addr)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	offset, rtt, err := QuerySNTP(ctx, conn.LocalAddr().String())
+	require.NoError(t, err)
+	assert.InDelta(t, -3*time.Second, offset, float64(100*time.Millisecond))
+	assert.GreaterOrEqual(t, rtt, time.Duration(0))
+}
+
+func TestNTPTimestamp(t *testing.T) {
+	at := time.Date(2026, 10, 16, 6, 37, 12, 500_000_000, time.UTC)
+	assert.WithinDuration(t, at, ntpTime(ntpTimestamp(at)), time.Microsecond)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	c := newTestCollector(t, "")
+	c.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "chronyc" {
+			return []byte(chronyTracking), nil
+		}
+		return nil, errors.New("unexpected command")
+	})
+	c.SetQueryForTesting(func(ctx context.Context, server string) (time.Duration, time.Duration, error) {
+		assert.Equal(t, DefaultServer, server)
+		return 1500 * time.Millisecond, 20 * time.Millisecond, nil
+	})
+
+	// The SNTP offset wins over chrony's estimate
+	status := c.Collect()
+	assert.Equal(t, 1500.0, status.OffsetMs)
+	assert.Equal(t, 20.0, status.RoundTripMs)
+	assert.Equal(t, SourceSNTP, status.OffsetSource)
+	assert.Equal(t, DefaultServer, status.Server)
+	assert.Equal(t, "chronyd", status.NTPService)
+	assert.True(t, status.Synchronized)
+	assert.Empty(t, status.Error)
+	assert.True(t, c.warned)
+}
+
+func TestCollector_CollectFallbacks(t *testing.T) {
+	// Failed query: chrony's estimate is reported with the query error
+	c := newTestCollector(t, "")
+	c.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "chronyc" {
+			return []byte(chronyTracking), nil
+		}
+		return nil, errors.New("unexpected command")
+	})
+	c.SetQueryForTesting(func(ctx context.Context, server string) (time.Duration, time.Duration, error) {
+		return 0, 0, errors.New("SNTP query to pool.ntp.org failed: i/o timeout")
+	})
+	status := c.Collect()
+	assert.InDelta(t, -0.123456, status.OffsetMs, 1e-9)
+	assert.Equal(t, SourceChrony, status.OffsetSource)
+	assert.Equal(t, "SNTP query to pool.ntp.org failed: i/o timeout", status.Error)
+
+	// No query and no chrony: timedatectl only tells whether the clock is synchronized
+	c = newTestCollector(t, "none")
+	c.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "timedatectl" {
+			return []byte("NTP=yes\nNTPSynchronized=yes\n"), nil
+		}
+		return nil, errors.New("exec: \"chronyc\": executable file not found in $PATH")
+	})
+	status = c.Collect()
+	assert.Empty(t, status.OffsetSource)
+	assert.Equal(t, "systemd-timesyncd", status.NTPService)
+	assert.True(t, status.Synchronized)
+	assert.Empty(t, status.Error)
+}
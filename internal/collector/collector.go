@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"xhub-agent/internal/monitor"
+)
+
+// Apply attaches collected values to a status report
+type Apply func(data *monitor.ServerStatusData)
+
+// Collector gathers agent-side metrics that are not part of the 3x-ui status
+type Collector struct {
+	Name     string        // Unique name, used as key in collector_intervals
+	Interval time.Duration // Default interval, 0 collects every cycle
+	Collect  func() Apply  // Collects and returns how to attach the values
+}
+
+// entry registered collector with its cached result
+type entry struct {
+	collector Collector
+	interval  time.Duration
+	ran       bool
+	lastRun   time.Time
+	apply     Apply // Cached result of the last run (nil attaches nothing)
+}
+
+// Registry runs each collector at its own interval and reuses cached values in between
+type Registry struct {
+	entries   []*entry
+	overrides map[string]time.Duration
+	now       func() time.Time // injectable for tests
+	mutex     sync.Mutex
+}
+
+// NewRegistry creates a registry; overrides replace the default interval of collectors by name
+func NewRegistry(overrides map[string]time.Duration) *Registry {
+	return &Registry{overrides: overrides, now: time.Now}
+}
+
+// SetClockForTesting replaces the time source (for testing only)
+func (r *Registry) SetClockForTesting(now func() time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.now = now
+}
+
+// Register adds a collector
+func (r *Registry) Register(c Collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	interval := c.Interval
+	if override, ok := r.overrides[c.Name]; ok {
+		interval = override
+	}
+	r.entries = append(r.entries, &entry{collector: c, interval: interval})
+}
+
+// Interval returns the effective interval of the named collector
+func (r *Registry) Interval(name string) (time.Duration, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, e := range r.entries {
+		if e.collector.Name == name {
+			return e.interval, true
+		}
+	}
+	return 0, false
+}
+
+// UnknownOverrides returns override names matching no registered collector (sorted)
+func (r *Registry) UnknownOverrides() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var unknown []string
+	for name := range r.overrides {
+		found := false
+		for _, e := range r.entries {
+			if e.collector.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// Apply runs the collectors whose interval has elapsed and attaches the latest values
+// of every collector to data
+func (r *Registry) Apply(data *monitor.ServerStatusData) {
+	if data == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	for _, e := range r.entries {
+		if !e.ran || now.Sub(e.lastRun) >= e.interval {
+			e.apply = e.collector.Collect()
+			e.ran = true
+			e.lastRun = now
+		}
+		if e.apply != nil {
+			e.apply(data)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"xhub-agent/internal/monitor"
+)
+
+// countingCollector returns a collector reporting how often it ran as the jail ban count
+func countingCollector(name string, interval time.Duration, runs *int) Collector {
+	return Collector{
+		Name:     name,
+		Interval: interval,
+		Collect: func() Apply {
+			*runs++
+			count := *runs
+			return func(data *monitor.ServerStatusData) {
+				if data.Fail2banBans == nil {
+					data.Fail2banBans = make(map[string]int)
+				}
+				data.Fail2banBans[name] = count
+			}
+		},
+	}
+}
+
+func TestRegistry_SlowCollectorUsesCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	registry := NewRegistry(nil)
+	registry.now = func() time.Time { return now }
+
+	var fastRuns, slowRuns int
+	registry.Register(countingCollector("fast", 0, &fastRuns))
+	registry.Register(countingCollector("slow", 10*time.Second, &slowRuns))
+
+	// 10 poll cycles of 2 seconds
+	for i := 0; i < 10; i++ {
+		data := &monitor.ServerStatusData{}
+		registry.Apply(data)
+
+		// Cached values of the slow collector appear in every report
+		assert.Contains(t, data.Fail2banBans, "slow", "cycle %d", i)
+		assert.Equal(t, slowRuns, data.Fail2banBans["slow"])
+		assert.Equal(t, fastRuns, data.Fail2banBans["fast"])
+
+		now = now.Add(2 * time.Second)
+	}
+
+	assert.Equal(t, 10, fastRuns)
+	assert.Equal(t, 2, slowRuns)
+}
+
+func TestRegistry_IntervalOverrides(t *testing.T) {
+	registry := NewRegistry(map[string]time.Duration{"fail2ban": time.Minute, "geoip": time.Hour})
+
+	var runs int
+	registry.Register(countingCollector("fail2ban", 10*time.Second, &runs))
+	registry.Register(countingCollector("other", 5*time.Second, &runs))
+
+	interval, ok := registry.Interval("fail2ban")
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, interval)
+
+	interval, ok = registry.Interval("other")
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, interval)
+
+	assert.Equal(t, []string{"geoip"}, registry.UnknownOverrides())
+}
+
+func TestRegistry_NilApply(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	registry := NewRegistry(nil)
+	registry.now = func() time.Time { return now }
+
+	runs := 0
+	registry.Register(Collector{Name: "empty", Interval: time.Minute, Collect: func() Apply {
+		runs++
+		return nil
+	}})
+
+	registry.Apply(&monitor.ServerStatusData{})
+	registry.Apply(&monitor.ServerStatusData{})
+	registry.Apply(nil)
+	assert.Equal(t, 1, runs, "an empty result is cached too")
+}
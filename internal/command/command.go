@@ -0,0 +1,193 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Built-in commands xhub can issue
+const (
+	RestartXray         = "restart_xray"         // Restart Xray through the 3x-ui panel
+	ResyncSubscriptions = "resync_subscriptions" // Run a report cycle resending all subscriptions
+	AdjustPollInterval  = "adjust_poll_interval" // Change the status interval until the next config reload (arg: seconds)
+	FetchLogs           = "fetch_logs"           // Return the last lines of the agent log (arg: lines)
+	SetLogLevel         = "set_log_level"        // Change the log level until the next config reload (arg: level)
+	InboundCreate       = "inbound_create"       // Add a 3x-ui inbound (arg: inbound, JSON object)
+	InboundUpdate       = "inbound_update"       // Change fields of a 3x-ui inbound (args: id, inbound)
+	InboundDelete       = "inbound_delete"       // Delete a 3x-ui inbound (arg: id)
+	InboundEnable       = "inbound_enable"       // Enable or disable a 3x-ui inbound (args: id, enable)
+	ResetTraffic        = "reset_traffic"        // Reset client traffic of an inbound (args: inbound_id, email, dry_run)
+	EnforceQuota        = "enforce_quota"        // Disable over-quota clients (args: emails, dry_run)
+	NetworkProbe        = "network_probe"        // Measure latency, jitter, loss and throughput (arg: throughput)
+	XrayVersions        = "xray_versions"        // List the running and installable Xray versions
+	XrayInstall         = "xray_install"         // Install an Xray version, rolled back if unhealthy (arg: version)
+	CertRenew           = "cert_renew"           // Renew certificates through the ACME client (args: domain, force)
+)
+
+// Names are the built-in commands, in the order they are documented
+var Names = []string{
+	RestartXray, ResyncSubscriptions, AdjustPollInterval, FetchLogs, SetLogLevel,
+	InboundCreate, InboundUpdate, InboundDelete, InboundEnable,
+	ResetTraffic, EnforceQuota, NetworkProbe, XrayVersions, XrayInstall, CertRenew,
+}
+
+// DefaultAllowlist are the commands allowed when command_allowlist is unset: the ones that
+// neither disrupt traffic nor change the agent's behavior
+var DefaultAllowlist = []string{ResyncSubscriptions, FetchLogs}
+
+// MaxOutput caps the output sent back to xhub
+const MaxOutput = 64 << 10
+
+// Command is a command issued by xhub
+type Command struct {
+	ID   string
+	Name string
+	Args map[string]string
+}
+
+// Result is the outcome of a command
+type Result struct {
+	ID       string
+	Name     string
+	Output   string        // Capped at MaxOutput
+	Err      string        // Empty on success
+	Duration time.Duration // Execution time
+}
+
+// Handler runs a command with its arguments and returns its output
+type Handler func(ctx context.Context, args map[string]string) (string, error)
+
+// Executor runs the commands of the allowlist that have a handler
+type Executor struct {
+	allowed  map[string]bool
+	handlers map[string]Handler
+	timeout  time.Duration
+	timeouts map[string]time.Duration // Per-command overrides of timeout
+}
+
+// ParseAllowlist checks that every name is a built-in command; nil selects DefaultAllowlist
+func ParseAllowlist(names []string) ([]string, error) {
+	if names == nil {
+		return DefaultAllowlist, nil
+	}
+	for _, name := range names {
+		if !slices.Contains(Names, name) {
+			return nil, fmt.Errorf("unknown command %q (expected one of %s)", name, strings.Join(Names, ", "))
+		}
+	}
+	return names, nil
+}
+
+// NewExecutor creates an executor running the allowed commands, each for at most timeout
+func NewExecutor(allowlist []string, timeout time.Duration) *Executor {
+	allowed := make(map[string]bool, len(allowlist))
+	for _, name := range allowlist {
+		allowed[name] = true
+	}
+	return &Executor{allowed: allowed, handlers: make(map[string]Handler), timeout: timeout, timeouts: make(map[string]time.Duration)}
+}
+
+// Register sets the handler of the named command
+func (e *Executor) Register(name string, handler Handler) {
+	e.handlers[name] = handler
+}
+
+// SetTimeout overrides the timeout of the named command, e.g. for slow downloads
+func (e *Executor) SetTimeout(name string, timeout time.Duration) {
+	e.timeouts[name] = timeout
+}
+
+// Supported returns the commands that are allowed and have a handler (sorted)
+func (e *Executor) Supported() []string {
+	var names []string
+	for name := range e.handlers {
+		if e.allowed[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Execute runs cmd if it is allowed. Refused and failed commands have Err set.
+func (e *Executor) Execute(ctx context.Context, cmd Command) Result {
+	result := Result{ID: cmd.ID, Name: cmd.Name}
+	handler, ok := e.handlers[cmd.Name]
+	switch {
+	case !ok:
+		result.Err = fmt.Sprintf("unknown command %q", cmd.Name)
+		return result
+	case !e.allowed[cmd.Name]:
+		result.Err = fmt.Sprintf("command %q is not in the agent's command_allowlist", cmd.Name)
+		return result
+	}
+
+	ctx = context.WithValue(ctx, idKey{}, cmd.ID)
+	timeout := e.timeout
+	if override, ok := e.timeouts[cmd.Name]; ok {
+		timeout = override
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	output, err := handler(ctx, cmd.Args)
+	result.Duration = time.Since(start)
+	if len(output) > MaxOutput {
+		output = output[len(output)-MaxOutput:] // Keep the end, e.g. the latest log lines
+	}
+	result.Output = output
+	if err != nil {
+		result.Err = err.Error()
+	}
+	return result
+}
+
+// idKey is the context key of the id of the running command
+type idKey struct{}
+
+// ID returns the id of the command a handler runs for, from the handler's context
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// TailFile returns the last lines of the file at path, reading at most MaxOutput bytes
+func TailFile(path string, lines int) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	offset := max(0, info.Size()-MaxOutput)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.TrimSuffix(string(data), "\n")
+	all := strings.Split(text, "\n")
+	if offset > 0 && len(all) > 1 {
+		all = all[1:] // The first line was cut by the read window
+	}
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n"), nil
+}
@@ -0,0 +1,125 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowlist(t *testing.T) {
+	allowlist, err := ParseAllowlist(nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAllowlist, allowlist)
+
+	allowlist, err = ParseAllowlist([]string{})
+	require.NoError(t, err)
+	assert.Empty(t, allowlist, "an empty list allows nothing")
+
+	allowlist, err = ParseAllowlist([]string{RestartXray, FetchLogs})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RestartXray, FetchLogs}, allowlist)
+
+	_, err = ParseAllowlist([]string{"rm_rf"})
+	assert.ErrorContains(t, err, `unknown command "rm_rf"`)
+}
+
+func TestExecutor_Execute(t *testing.T) {
+	executor := NewExecutor([]string{FetchLogs}, time.Second)
+	executor.Register(FetchLogs, func(ctx context.Context, args map[string]string) (string, error) {
+		return "lines=" + args["lines"] + " id=" + ID(ctx), nil
+	})
+	executor.Register(RestartXray, func(ctx context.Context, args map[string]string) (string, error) {
+		t.Fatal("a command outside the allowlist ran")
+		return "", nil
+	})
+	assert.Equal(t, []string{FetchLogs}, executor.Supported())
+
+	result := executor.Execute(context.Background(), Command{ID: "1", Name: FetchLogs, Args: map[string]string{"lines": "5"}})
+	assert.Equal(t, "1", result.ID)
+	assert.Equal(t, FetchLogs, result.Name)
+	assert.Equal(t, "lines=5 id=1", result.Output, "handlers see the command id")
+	assert.Empty(t, result.Err)
+
+	result = executor.Execute(context.Background(), Command{ID: "2", Name: RestartXray})
+	assert.Contains(t, result.Err, "not in the agent's command_allowlist")
+
+	result = executor.Execute(context.Background(), Command{ID: "3", Name: "shell"})
+	assert.Equal(t, `unknown command "shell"`, result.Err)
+}
+
+func TestExecutor_ExecuteFailureAndTimeout(t *testing.T) {
+	executor := NewExecutor([]string{ResyncSubscriptions, AdjustPollInterval}, 10*time.Millisecond)
+	executor.Register(ResyncSubscriptions, func(ctx context.Context, args map[string]string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	executor.Register(AdjustPollInterval, func(ctx context.Context, args map[string]string) (string, error) {
+		return "partial", errors.New("bad seconds")
+	})
+
+	result := executor.Execute(context.Background(), Command{Name: ResyncSubscriptions})
+	assert.Equal(t, context.DeadlineExceeded.Error(), result.Err)
+	assert.GreaterOrEqual(t, result.Duration, 10*time.Millisecond)
+
+	executor.SetTimeout(ResyncSubscriptions, 50*time.Millisecond)
+	result = executor.Execute(context.Background(), Command{Name: ResyncSubscriptions})
+	assert.Equal(t, context.DeadlineExceeded.Error(), result.Err)
+	assert.GreaterOrEqual(t, result.Duration, 50*time.Millisecond, "per-command timeout")
+
+	result = executor.Execute(context.Background(), Command{Name: AdjustPollInterval})
+	assert.Equal(t, "bad seconds", result.Err)
+	assert.Equal(t, "partial", result.Output)
+}
+
+func TestExecutor_OutputCapped(t *testing.T) {
+	executor := NewExecutor([]string{FetchLogs}, 0)
+	executor.Register(FetchLogs, func(ctx context.Context, args map[string]string) (string, error) {
+		return strings.Repeat("a", MaxOutput) + "end", nil
+	})
+
+	result := executor.Execute(context.Background(), Command{Name: FetchLogs})
+	assert.Len(t, result.Output, MaxOutput)
+	assert.True(t, strings.HasSuffix(result.Output, "end"), "the end of the output is kept")
+}
+
+func TestTailFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	var content strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	require.NoError(t, os.WriteFile(path, []byte(content.String()), 0644))
+
+	tail, err := TailFile(path, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "line 8\nline 9\nline 10", tail)
+
+	tail, err = TailFile(path, 100)
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimSuffix(content.String(), "\n"), tail)
+
+	_, err = TailFile(filepath.Join(t.TempDir(), "missing.log"), 3)
+	assert.Error(t, err)
+}
+
+func TestTailFile_LargeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	line := strings.Repeat("x", 99) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat(line, 2*MaxOutput/len(line))), 0644))
+
+	tail, err := TailFile(path, 1000)
+	require.NoError(t, err)
+	lines := strings.Split(tail, "\n")
+	assert.Less(t, len(lines), 1000, "at most MaxOutput bytes are read")
+	for _, l := range lines {
+		assert.Len(t, l, 99, "no partial first line")
+	}
+}
@@ -2,9 +2,16 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"xhub-agent/internal/certrenew"
+	"xhub-agent/internal/container"
+	"xhub-agent/internal/faultinject"
+	"xhub-agent/internal/privacy"
 )
 
 // Config represents the Agent configuration structure
@@ -15,17 +22,257 @@ type Config struct {
 	XUIPass        string `yaml:"xui_pass"`       // 3x-ui login password
 	XHubAPIKey     string `yaml:"xhub_api_key"`   // xhub API key
 	ResolvedDomain string `yaml:"resolvedDomain"` // DNS resolved domain for subscription reporting
-	GRPCServer     string `yaml:"grpcServer"`     // gRPC server address
-	GRPCPort       int    `yaml:"grpcPort"`       // gRPC server port
+
+	// Key decrypting the enc:v1: secret values (xhub-agent config encrypt): file:<path> or
+	// keyring:<name>, default the config.key file next to the config file
+	SecretsKey string `yaml:"secrets_key"`
+
+	ResolvedDomainCheck   string `yaml:"resolved_domain_check"`   // off, warn (default) or strict (skip subscription reports while unresolvable)
+	ResolvedDomainRewrite bool   `yaml:"resolved_domain_rewrite"` // Rewrite the node addresses of the subscriptions to resolvedDomain
+	GeoLookupURL          string `yaml:"geo_lookup_url"`          // JSON geo/ASN lookup of the public IP reported with the host metadata, "" disables
+	GRPCServer            string `yaml:"grpcServer"`              // gRPC server address
+	GRPCPort              int    `yaml:"grpcPort"`                // gRPC server port
+
+	GRPCWaitForReady bool   `yaml:"grpc_wait_for_ready"` // Wait for the connection instead of failing fast on reconnects
+	GRPCDialStrategy string `yaml:"grpc_dial_strategy"`  // Address family strategy: auto, ipv4-only, ipv6-only, ipv4-first
+	PreferIPv6       *bool  `yaml:"prefer_ipv6"`         // Give IPv6 the head start under the auto strategy, default true
+	GRPCProxy        string `yaml:"grpc_proxy"`          // http:// or socks5:// proxy URL, "direct", default HTTPS_PROXY
+	Transport        string `yaml:"transport"`           // grpc (default) or https
+	XHubHTTPSURL     string `yaml:"xhub_https_url"`      // Base URL of the xhub HTTPS/JSON API, enables transport failover
+	ReportCombined   bool   `yaml:"report_combined"`     // Send each cycle in one combined RPC when xhub supports it
+	ReportStream     bool   `yaml:"report_stream"`       // Send status reports over one long-lived stream
+	ReportDelta      bool   `yaml:"report_delta"`        // Send only the changed status fields when xhub supports it
+	ReportDeltaFull  int    `yaml:"report_delta_full"`   // Seconds between full status snapshots under report_delta, default 60
+	ReportBatchSize  int    `yaml:"report_batch_size"`   // Status samples sent together in one batched RPC, 0 or 1 sends every cycle
+	GRPCClientCert   string `yaml:"grpc_client_cert"`    // PEM client certificate for mutual TLS, reloaded when renewed
+	GRPCClientKey    string `yaml:"grpc_client_key"`     // PEM private key of grpc_client_cert
+	GRPCCA           string `yaml:"grpc_ca"`             // PEM CA bundle verifying xhub, default system roots
+
+	// Detection of dead connections to xhub and reconnection
+	GRPCKeepaliveTime          int   `yaml:"grpc_keepalive_time"`           // Seconds of inactivity before a ping, default 60 (10 at least)
+	GRPCKeepaliveTimeout       int   `yaml:"grpc_keepalive_timeout"`        // Seconds to wait for the ping ack before reconnecting, default 20
+	GRPCKeepaliveWithoutStream *bool `yaml:"grpc_keepalive_without_stream"` // Also ping between reports, default true
+	GRPCReconnectMaxBackoff    int   `yaml:"grpc_reconnect_max_backoff"`    // Seconds, cap of the delay between reconnection attempts, default 30
+
+	// Verification of the xhub certificate beyond grpc_ca
+	GRPCCAFile        string `yaml:"grpc_ca_file"`         // Same as grpc_ca
+	GRPCCertPinSHA256 string `yaml:"grpc_cert_pin_sha256"` // Accepted xhub (or, with grpc_ca, CA) certificate SHA-256 fingerprints, comma-separated
+
+	// Size and bandwidth budget of the reports sent to xhub
+	ReportCompression       string `yaml:"report_compression"`          // gzip (default) or none
+	ReportCompressionMinKB  *int   `yaml:"report_compression_min_kb"`   // Send smaller requests uncompressed, default 1, 0 compresses all
+	SubscriptionChunkKB     *int   `yaml:"subscription_chunk_kb"`       // Split larger subscription reports, default 2048, 0 disables
+	ReportBandwidthKBPerMin int    `yaml:"report_bandwidth_kb_per_min"` // Outbound KiB per minute, 0 (default) for no limit
+
+	// Legacy pre-gRPC HTTP report URL, only used to derive grpcServer when it is absent
+	ReportURL string `yaml:"reportUrl"`
 
 	// 3x-ui connection configuration
 	RootPath string `yaml:"rootPath"` // 3x-ui rootPath
 	Port     int    `yaml:"port"`     // 3x-ui port number
 
 	// Optional configuration (with default values)
-	XUIBaseURL   string `yaml:"xui_base_url"`  // 3x-ui base URL, default 127.0.0.1 (without port)
-	PollInterval int    `yaml:"poll_interval"` // Poll interval (seconds), default 2
-	LogLevel     string `yaml:"log_level"`     // Log level, default info
+	XUIBaseURL    string `yaml:"xui_base_url"`    // 3x-ui base URL, default 127.0.0.1 (without port)
+	PollInterval  int    `yaml:"poll_interval"`   // Poll interval (seconds), default 2; default of status_interval
+	LogLevel      string `yaml:"log_level"`       // Log level, default info
+	LogFormat     string `yaml:"log_format"`      // Log record format: text (default) or json
+	XUISessionTTL int    `yaml:"xui_session_ttl"` // Assumed 3x-ui session lifetime (seconds), default 3600
+
+	// Intervals of the report types (seconds): status_interval paces the report cycles, the
+	// subscriptions and online users are collected in the cycles where their interval elapsed
+	StatusInterval       int `yaml:"status_interval"`       // Default poll_interval
+	SubscriptionInterval int `yaml:"subscription_interval"` // Default status_interval
+	OnlineUsersInterval  int `yaml:"online_users_interval"` // Default status_interval
+	// Percentage of status_interval each cycle start moves by, at random, after a phase offset
+	// derived from the UUID, so that agents with the same interval spread their reports
+	PollJitter int `yaml:"poll_jitter"` // 0 (default) disables, at most 50
+
+	// Data flows sent to xhub; a disabled type is neither collected nor sent
+	ReportStatus        *bool `yaml:"report_status"`        // Status reports, default true
+	ReportSubscriptions *bool `yaml:"report_subscriptions"` // Subscription reports, default true
+	ReportOnlineUsers   *bool `yaml:"report_online_users"`  // Online users reports, default true
+	ReportHysteria2     *bool `yaml:"report_hysteria2"`     // Hysteria2 status and subscription nodes, default true
+
+	// Debug dump of the status sent each cycle
+	LogStatusDump    *bool `yaml:"log_status_dump"`    // Log the status at debug level, default true
+	LogStatusCompact bool  `yaml:"log_status_compact"` // Single-line JSON instead of indented
+
+	// Report host metrics read from /proc while /server/status fails, with Xray "unreachable"
+	HostStatusFallback *bool `yaml:"host_status_fallback"` // Default true
+
+	// Send the full inbound configuration to xhub whenever it changes, for disaster recovery
+	InboundBackup bool `yaml:"inbound_backup"`
+
+	// Commands pushed by xhub over a server stream (restart Xray, fetch logs, ...)
+	CommandChannel   bool     `yaml:"command_channel"`   // Default false
+	CommandAllowlist []string `yaml:"command_allowlist"` // Commands xhub may run, default resync_subscriptions and fetch_logs
+
+	// 3x-ui client (user) changes pushed by xhub over a server stream
+	ProvisioningChannel bool `yaml:"provisioning_channel"` // Default false
+
+	// Lightweight liveness RPC sent independently of the report cycles, so a broken panel
+	// does not make the agent look offline
+	HeartbeatInterval *int `yaml:"heartbeat_interval"` // Seconds, default 5, 0 disables
+
+	// Network quality probes (TCP connect latency, jitter and loss, optional download throughput),
+	// run on schedule and on the network_probe command
+	NetworkProbeTargets       []string `yaml:"network_probe_targets"`        // host:port endpoints, e.g. "1.1.1.1:443"
+	NetworkProbeCount         int      `yaml:"network_probe_count"`          // Connects per target, default 10
+	NetworkProbeInterval      int      `yaml:"network_probe_interval"`       // Seconds between scheduled runs, 0 (default) runs on command only
+	NetworkProbeThroughputURL string   `yaml:"network_probe_throughput_url"` // File downloaded to measure throughput, "" disables
+	NetworkProbeThroughputMB  int      `yaml:"network_probe_throughput_mb"`  // Download cap in MB, default 10
+
+	ShutdownTimeout int  `yaml:"shutdown_timeout"` // Seconds to wait for a clean shutdown before forcing exit, default 10
+	DrainTimeout    *int `yaml:"drain_timeout"`    // Seconds to deliver queued reports and the shutdown notice, default 5, 0 disables
+
+	MetricsListen string `yaml:"metrics_listen"` // host:port of the Prometheus /metrics endpoint, empty (default) disables it
+	LocalAPI      string `yaml:"local_api"`      // Local status API on a loopback host:port or unix:<socket path>, empty (default) disables it
+
+	// Status history for troubleshooting without xhub (local API /history, "history" command)
+	HistoryHours   int  `yaml:"history_hours"`   // Hours of collected status kept in memory, 0 (default) disables, at most 168
+	HistoryPersist bool `yaml:"history_persist"` // Also keep the history in the data directory across restarts
+
+	// Spacing between the subscription and online users sends after the status report
+	SendSpacingMs int `yaml:"send_spacing_ms"` // Milliseconds, default poll_interval/4 (clamped to the interval), -1 disables
+
+	// Reuse of the last online users list when GetOnlineUsers fails
+	OnlineUsersMaxStaleness int `yaml:"online_users_max_staleness"` // Seconds, 0 skips the send instead; older lists are reported as empty
+
+	// Source IPs of the online users, reported with the online users list
+	OnlineUserDetails string `yaml:"online_user_details"` // off (default), panel (client IP API) or access_log
+	XrayAccessLog     string `yaml:"xray_access_log"`     // Access log read by access_log and access_summary, default /usr/local/x-ui/access.log
+
+	// Per-user connection counts and top destinations of the Xray access log, sent every interval
+	AccessSummary           bool `yaml:"access_summary"`             // Summarize xray_access_log for abuse and policy analysis
+	AccessSummaryInterval   int  `yaml:"access_summary_interval"`    // Seconds per summary, default 300
+	AccessSummaryTopDomains int  `yaml:"access_summary_top_domains"` // Destinations kept per user and overall, default 10
+
+	// Retry for the subscription prerequisite calls (default settings, inbound list)
+	SubscriptionRetryAttempts  int `yaml:"subscription_retry_attempts"`   // Attempts per call, default 3
+	SubscriptionRetryBackoffMs int `yaml:"subscription_retry_backoff_ms"` // Initial backoff in ms (doubles per retry), default 500
+
+	// Concurrent fetching of the subscription content of every SubID from the panel
+	SubscriptionFetchConcurrency int `yaml:"subscription_fetch_concurrency"` // Requests in flight, default 4
+	SubscriptionFetchTimeout     int `yaml:"subscription_fetch_timeout"`     // Seconds per subscription content request, default 30
+
+	// Retry of report RPCs that fail with a retryable gRPC code, within the request timeout
+	ReportRetryAttempts     int      `yaml:"report_retry_attempts"`       // Attempts per RPC, default 3, 1 disables retries
+	ReportRetryBackoffMs    int      `yaml:"report_retry_backoff_ms"`     // Initial backoff in ms (doubles per retry, jittered), default 250
+	ReportRetryMaxBackoffMs int      `yaml:"report_retry_max_backoff_ms"` // Backoff cap in ms, default 5000
+	ReportRetryCodes        []string `yaml:"report_retry_codes"`          // Retried gRPC codes, default unavailable and deadline_exceeded
+
+	// Timeouts of the requests to xhub
+	ReportTimeout       int `yaml:"report_timeout"`       // Seconds per report RPC, default 30
+	SubscriptionTimeout int `yaml:"subscription_timeout"` // Seconds per subscription report request or chunk, default 30
+	ConnectTimeout      int `yaml:"connect_timeout"`      // Seconds to establish a connection (TCP, proxy, TLS), default 10
+
+	// DNS cache TTL for subscription URLs that use hostnames
+	SubscriptionDNSTTL int `yaml:"subscription_dns_ttl"` // Seconds, default 60
+
+	// Also report the JSON (sing-box) subscription of every SubID, served by the panel's subJsonURI
+	SubscriptionJSON bool `yaml:"subscription_json"`
+
+	// Panel schema selection for 3x-ui forks
+	XUIPanelProfile string            `yaml:"xui_panel_profile"` // Known fork profile, default "standard"
+	XUIFieldMapping map[string]string `yaml:"xui_field_mapping"` // Extra alternative-key -> canonical-key mappings
+	XUIPanelVersion string            `yaml:"xui_panel_version"` // 3x-ui release (e.g. 2.6.2), detected from the panel page when empty
+
+	// CSRF token handling for forks protecting panel POSTs (inert unless xui_csrf_mode is set)
+	XUICSRFMode      string `yaml:"xui_csrf_mode"`       // cookie or html, empty disables
+	XUICSRFPath      string `yaml:"xui_csrf_path"`       // Page fetched after login for the token, default /panel/
+	XUICSRFCookie    string `yaml:"xui_csrf_cookie"`     // Token cookie name (cookie mode), default XSRF-TOKEN
+	XUICSRFPattern   string `yaml:"xui_csrf_pattern"`    // Regex with one capture group over the HTML (html mode)
+	XUICSRFHeader    string `yaml:"xui_csrf_header"`     // Request header, default X-XSRF-TOKEN / X-CSRF-Token
+	XUICSRFFormField string `yaml:"xui_csrf_form_field"` // Send the token as this form field instead of a header
+
+	// Extra login factors of hardened panels
+	XUILoginSecret string `yaml:"xui_login_secret"` // Panel secret token sent as loginSecret
+	XUITOTPSecret  string `yaml:"xui_totp_secret"`  // Base32 two-factor secret (or otpauth:// URI)
+
+	// User email privacy in outgoing payloads
+	EmailReporting string `yaml:"email_reporting"` // plain (default), hashed or pseudonym
+	EmailHashKey   string `yaml:"email_hash_key"`  // HMAC key, required for hashed
+
+	// Writable data directory (log, state, history, queue, failed payloads and crash dumps live under it)
+	DataDir                string `yaml:"data_dir"`                  // Data directory, default the log file's directory
+	RequireWritableDataDir bool   `yaml:"require_writable_data_dir"` // Exit instead of degrading when data_dir is read-only
+	PersistFailedPayloads  bool   `yaml:"persist_failed_payloads"`   // Keep the payloads of failed report RPCs under data_dir
+	OfflineQueueMaxMB      *int   `yaml:"offline_queue_max_mb"`      // Reports queued under data_dir while xhub is unreachable, default 16, 0 disables
+
+	// Container mode: logs on stdout only, shorter shutdown, secrets from /run/secrets
+	Container        bool   `yaml:"container"`         // Running as a container image (XHUB_AGENT_CONTAINER=true)
+	ContainerNetwork string `yaml:"container_network"` // host (default, panel on 127.0.0.1) or bridge (panel on the gateway)
+
+	// Port frontend detection (optional, needs privileges to read /proc/<pid>/fd)
+	DetectPortFrontends bool `yaml:"detect_port_frontends"` // Report which process listens on each inbound port
+
+	// fail2ban ban statistics (optional, skipped when fail2ban is not installed)
+	CollectFail2ban bool   `yaml:"collect_fail2ban"` // Report currently banned IPs per jail
+	Fail2banSocket  string `yaml:"fail2ban_socket"`  // fail2ban server socket, default /var/run/fail2ban/fail2ban.sock
+
+	// Disk IO rates and SMART health of the primary disk (SMART skipped when smartctl is not installed)
+	CollectDiskIO bool   `yaml:"collect_disk_io"` // Report IO rates every cycle and SMART health hourly
+	DiskIODevice  string `yaml:"disk_io_device"`  // Disk to report (e.g. "nvme0n1"), default the disk holding /
+
+	// Pressure stall information (/proc/pressure) and thermal zone temperatures (Linux)
+	CollectPressure bool `yaml:"collect_pressure"`
+
+	// System clock offset against NTP, through an SNTP query or chrony (interval via collector_intervals.clock)
+	CollectClock   bool   `yaml:"collect_clock"`
+	ClockNTPServer string `yaml:"clock_ntp_server"` // SNTP server queried, default pool.ntp.org, "none" relies on chrony
+
+	// WireGuard peer counts, handshake freshness and transfer through wg (skipped when wg is not installed)
+	CollectWireGuard bool `yaml:"collect_wireguard"`
+	// Client config stubs of hub-managed WireGuard peers, attached to the subscriptions
+	WireGuardPeersFile string `yaml:"wireguard_peers_file"` // Peer public key per subscription email (YAML map), enables the stubs
+	WireGuardInterface string `yaml:"wireguard_interface"`  // Interface of the peers, default the only one
+	WireGuardEndpoint  string `yaml:"wireguard_endpoint"`   // External server address (domain or IP)
+	WireGuardDNS       string `yaml:"wireguard_dns"`        // DNS servers of the clients, e.g. "1.1.1.1"
+
+	// Certificate expiry of the cert files referenced by the Hysteria2 and inbound TLS configs
+	CollectCertExpiry bool `yaml:"collect_cert_expiry"`
+
+	// Renewal of those certificates through an ACME client when one nears expiry or on the
+	// cert_renew command, followed by a restart of Xray or Hysteria2
+	CertRenewClient     string `yaml:"cert_renew_client"`      // certbot or acme.sh, "" (default) disables
+	CertRenewBeforeDays int    `yaml:"cert_renew_before_days"` // Days before expiry a renewal starts, default 14
+	Hysteria2Service    string `yaml:"hysteria2_service"`      // systemd unit restarted for a renewed Hysteria2 certificate, default hysteria-server
+
+	// Restart of the 3x-ui service when its status requests keep failing
+	PanelWatchdog            bool   `yaml:"panel_watchdog"`              // Enable the watchdog
+	PanelWatchdogService     string `yaml:"panel_watchdog_service"`      // systemd unit restarted, default x-ui
+	PanelWatchdogFailures    int    `yaml:"panel_watchdog_failures"`     // Consecutive failed status requests before a restart, default 3
+	PanelWatchdogCooldown    int    `yaml:"panel_watchdog_cooldown"`     // Seconds between restarts, default 600
+	PanelWatchdogMaxRestarts int    `yaml:"panel_watchdog_max_restarts"` // Restarts until the panel answers again, default 3
+
+	// Restart of Xray when the status reports it not running, through 3x-ui or systemd
+	XraySupervision       bool   `yaml:"xray_supervision"`        // Enable the supervision
+	XraySupervisionCycles int    `yaml:"xray_supervision_cycles"` // Consecutive cycles Xray is not running before a restart, default 3
+	XrayRestartService    string `yaml:"xray_restart_service"`    // systemd unit restarted when 3x-ui cannot restart Xray (e.g. x-ui), "" disables
+
+	// Local alerts on threshold breaches, delivered by hooks on the node independently of xhub
+	AlertCPU            float64 `yaml:"alert_cpu"`              // CPU usage percent, 0 (default) disables
+	AlertMemory         float64 `yaml:"alert_memory"`           // Memory usage percent, 0 (default) disables
+	AlertDisk           float64 `yaml:"alert_disk"`             // Disk usage percent, 0 (default) disables
+	AlertXrayDown       bool    `yaml:"alert_xray_down"`        // Alert when 3x-ui reports Xray not running
+	AlertCertExpiryDays int     `yaml:"alert_cert_expiry_days"` // Days before a certificate expires, needs collect_cert_expiry, 0 (default) disables
+	AlertCycles         int     `yaml:"alert_cycles"`           // Consecutive cycles a threshold is breached before alerting, default 1
+	AlertExec           string  `yaml:"alert_exec"`             // Script run per alert, with XHUB_ALERT_* variables and JSON on stdin
+	AlertWebhook        string  `yaml:"alert_webhook"`          // URL receiving each alert as a JSON POST
+	AlertTelegramToken  string  `yaml:"alert_telegram_token"`   // Telegram bot token
+	AlertTelegramChat   string  `yaml:"alert_telegram_chat"`    // Telegram chat ID receiving the alerts
+
+	// DNS self-check of the domains users connect to (interval via collector_intervals.dns_check)
+	DNSCheck          bool     `yaml:"dns_check"`            // Enable the check
+	DNSCheckDomains   []string `yaml:"dns_check_domains"`    // Domains, default resolvedDomain and hysteria2_server_addr
+	DNSCheckResolver  string   `yaml:"dns_check_resolver"`   // External resolver compared with the system one, default 1.1.1.1, "none" disables
+	DNSCheckPublicIPs []string `yaml:"dns_check_public_ips"` // Public IPs of the node, default those reported by 3x-ui
+
+	// Pipeline self-test against fixture data and an in-process loopback xhub
+	SelfTestInterval *int `yaml:"selftest_interval"` // Seconds, default 86400 (daily), 0 disables the schedule
+
+	// Per-collector interval overrides in seconds (e.g. fail2ban: 300), 0 collects every cycle
+	CollectorIntervals map[string]int `yaml:"collector_intervals"`
 
 	// Hysteria2 configuration (optional)
 	Hysteria2Enabled          bool   `yaml:"hysteria2_enabled"`            // Enable Hysteria2 support
@@ -35,6 +282,27 @@ type Config struct {
 	Hysteria2Insecure         bool   `yaml:"hysteria2_insecure"`           // Skip TLS verification
 	Hysteria2PortHopping      bool   `yaml:"hysteria2_port_hopping"`       // Enable port hopping to evade UDP blocking
 	Hysteria2PortHoppingRange string `yaml:"hysteria2_port_hopping_range"` // Port range for hopping, e.g. "20000-50000"
+	Hysteria2UsersFile        string `yaml:"hysteria2_users_file"`         // Auth string per subscription email for auth.type http (YAML map)
+
+	// TUIC v5 configuration (optional, tuic-server)
+	TUICEnabled    bool   `yaml:"tuic_enabled"`     // Enable TUIC support
+	TUICConfigPath string `yaml:"tuic_config_path"` // Path to tuic-server config, default /etc/tuic/config.json
+	TUICNodeName   string `yaml:"tuic_node_name"`   // Node display name
+	TUICServerAddr string `yaml:"tuic_server_addr"` // External server address (domain or IP)
+	TUICInsecure   bool   `yaml:"tuic_insecure"`    // Skip TLS verification
+	TUICUsersFile  string `yaml:"tuic_users_file"`  // User UUID per subscription email (YAML map), needed with several users
+
+	// ShadowTLS configuration (optional, sing-box)
+	ShadowTLSEnabled    bool   `yaml:"shadowtls_enabled"`     // Enable ShadowTLS support
+	ShadowTLSConfigPath string `yaml:"shadowtls_config_path"` // Path to sing-box config, default /etc/sing-box/config.json
+	ShadowTLSNodeName   string `yaml:"shadowtls_node_name"`   // Node display name
+	ShadowTLSServerAddr string `yaml:"shadowtls_server_addr"` // External server address (domain or IP)
+
+	// Testing only: random failure injection, e.g. "report=20%,auth=5%" (default off).
+	// The XHUB_AGENT_FAIL_INJECT environment variable overrides it.
+	FailInject string `yaml:"fail_inject"`
+
+	legacyMigration *LegacyMigration // Set when grpcServer was derived from reportUrl
 }
 
 // LoadFromFile loads configuration from YAML file
@@ -44,26 +312,52 @@ func LoadFromFile(filepath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Substitute ${ENV_VAR} references and read the <secret>_file keys
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := decodeConfig(data, filepath, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	return config.finish(filepath)
+}
+
+// finish decrypts, completes and validates a config read from configPath
+func (c *Config) finish(configPath string) (*Config, error) {
+	// Decrypt the secrets encrypted by "xhub-agent config encrypt"
+	if err := c.decryptSecrets(configPath); err != nil {
+		return nil, err
+	}
+
+	// Derive gRPC endpoint from a legacy reportUrl if needed
+	if err := c.deriveFromReportURL(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	// Environment override for failure injection
+	if spec, ok := os.LookupEnv(faultinject.EnvVar); ok {
+		c.FailInject = spec
+	}
 
 	// Apply default values
-	config.applyDefaults()
+	c.applyDefaults()
 
 	// Validate configuration
-	if err := config.Validate(); err != nil {
+	if err := c.Validate(); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
 
-	return &config, nil
+	return c, nil
 }
 
 // applyDefaults applies default configuration values
 func (c *Config) applyDefaults() {
+	if c.Container && c.ContainerNetwork == "" {
+		c.ContainerNetwork = container.NetworkHost
+	}
 	if c.XUIBaseURL == "" {
 		c.XUIBaseURL = "127.0.0.1"
+		if c.Container {
+			c.XUIBaseURL = container.PanelHost(c.ContainerNetwork)
+		}
 	}
 	if c.PollInterval == 0 {
 		c.PollInterval = 2 // gRPC 时代默认 2 秒轮询，提高响应速度
@@ -71,6 +365,93 @@ func (c *Config) applyDefaults() {
 	if c.LogLevel == "" {
 		c.LogLevel = "info"
 	}
+	if c.XUISessionTTL == 0 {
+		c.XUISessionTTL = 3600
+	}
+	if c.SubscriptionRetryAttempts == 0 {
+		c.SubscriptionRetryAttempts = 3
+	}
+	if c.SubscriptionRetryBackoffMs == 0 {
+		c.SubscriptionRetryBackoffMs = 500
+	}
+	if c.SubscriptionFetchConcurrency == 0 {
+		c.SubscriptionFetchConcurrency = 4
+	}
+	if c.SubscriptionFetchTimeout == 0 {
+		c.SubscriptionFetchTimeout = 30
+	}
+	if c.SubscriptionDNSTTL == 0 {
+		c.SubscriptionDNSTTL = 60
+	}
+	if c.ReportRetryAttempts == 0 {
+		c.ReportRetryAttempts = 3
+	}
+	if c.ReportRetryBackoffMs == 0 {
+		c.ReportRetryBackoffMs = 250
+	}
+	if c.ReportRetryMaxBackoffMs == 0 {
+		c.ReportRetryMaxBackoffMs = 5000
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = 10
+		if c.Container {
+			c.ShutdownTimeout = 8 // Within the 10s docker stop waits before SIGKILL
+		}
+	}
+	if c.ReportTimeout == 0 {
+		c.ReportTimeout = 30
+	}
+	if c.SubscriptionTimeout == 0 {
+		c.SubscriptionTimeout = 30
+	}
+	if c.ConnectTimeout == 0 {
+		c.ConnectTimeout = 10
+	}
+	if c.GRPCKeepaliveTime == 0 {
+		c.GRPCKeepaliveTime = 60
+	}
+	if c.GRPCKeepaliveTimeout == 0 {
+		c.GRPCKeepaliveTimeout = 20
+	}
+	if c.GRPCReconnectMaxBackoff == 0 {
+		c.GRPCReconnectMaxBackoff = 30
+	}
+	if c.NetworkProbeCount == 0 {
+		c.NetworkProbeCount = 10
+	}
+	if c.NetworkProbeThroughputMB == 0 {
+		c.NetworkProbeThroughputMB = 10
+	}
+	if c.AccessSummaryInterval == 0 {
+		c.AccessSummaryInterval = 300
+	}
+	if c.AccessSummaryTopDomains == 0 {
+		c.AccessSummaryTopDomains = 10
+	}
+	if c.CertRenewBeforeDays == 0 {
+		c.CertRenewBeforeDays = 14
+	}
+	if c.PanelWatchdogService == "" {
+		c.PanelWatchdogService = "x-ui"
+	}
+	if c.PanelWatchdogFailures == 0 {
+		c.PanelWatchdogFailures = 3
+	}
+	if c.PanelWatchdogCooldown == 0 {
+		c.PanelWatchdogCooldown = 600
+	}
+	if c.PanelWatchdogMaxRestarts == 0 {
+		c.PanelWatchdogMaxRestarts = 3
+	}
+	if c.XraySupervisionCycles == 0 {
+		c.XraySupervisionCycles = 3
+	}
+	if c.AlertCycles == 0 {
+		c.AlertCycles = 1
+	}
+	if c.ReportDeltaFull == 0 {
+		c.ReportDeltaFull = 60
+	}
 
 	// Apply smart gRPC port defaults based on server type and TLS usage
 	c.applySmartGRPCPortDefaults()
@@ -96,9 +477,20 @@ func (c *Config) applySmartGRPCPortDefaults() {
 
 // isLocalServer checks if the gRPC server is a local development server
 func (c *Config) isLocalServer() bool {
-	return c.GRPCServer == "localhost" ||
-		c.GRPCServer == "127.0.0.1" ||
-		c.GRPCServer == "::1"
+	host := c.grpcHost()
+	return host == "localhost" ||
+		host == "127.0.0.1" ||
+		host == "::1"
+}
+
+// grpcHost returns the gRPC server host without the brackets of an IPv6 literal
+func (c *Config) grpcHost() string {
+	return strings.TrimSuffix(strings.TrimPrefix(c.GRPCServer, "["), "]")
+}
+
+// GRPCAddress returns the host:port of the gRPC server, bracketing IPv6 literals
+func (c *Config) GRPCAddress() string {
+	return net.JoinHostPort(c.grpcHost(), strconv.Itoa(c.GRPCPort))
 }
 
 // Validate validates the configuration
@@ -113,8 +505,11 @@ func (c *Config) Validate() error {
 	if c.XUIPass == "" {
 		return fmt.Errorf("XUI password cannot be empty")
 	}
-	if c.XHubAPIKey == "" {
-		return fmt.Errorf("XHub API key cannot be empty")
+	if (c.GRPCClientCert == "") != (c.GRPCClientKey == "") {
+		return fmt.Errorf("grpc_client_cert and grpc_client_key must be set together")
+	}
+	if c.XHubAPIKey == "" && c.GRPCClientCert == "" {
+		return fmt.Errorf("XHub API key cannot be empty (unless a gRPC client certificate is set)")
 	}
 	if c.GRPCServer == "" {
 		return fmt.Errorf("gRPC server cannot be empty")
@@ -128,9 +523,356 @@ func (c *Config) Validate() error {
 	if c.Port <= 0 {
 		return fmt.Errorf("port must be greater than 0")
 	}
+	if _, err := faultinject.Parse(c.FailInject); err != nil {
+		return err
+	}
+	if c.XUISessionTTL < 0 {
+		return fmt.Errorf("XUI session TTL cannot be negative")
+	}
+	if c.StatusInterval < 0 || c.SubscriptionInterval < 0 || c.OnlineUsersInterval < 0 {
+		return fmt.Errorf("report intervals cannot be negative")
+	}
+	if c.SubscriptionRetryAttempts < 0 {
+		return fmt.Errorf("subscription retry attempts cannot be negative")
+	}
+	if c.SubscriptionRetryBackoffMs < 0 {
+		return fmt.Errorf("subscription retry backoff cannot be negative")
+	}
+	if c.SubscriptionFetchConcurrency < 0 || c.SubscriptionFetchTimeout < 0 {
+		return fmt.Errorf("subscription fetch concurrency and timeout cannot be negative")
+	}
+	if c.ReportRetryAttempts < 0 {
+		return fmt.Errorf("report retry attempts cannot be negative")
+	}
+	if c.ReportRetryBackoffMs < 0 || c.ReportRetryMaxBackoffMs < 0 {
+		return fmt.Errorf("report retry backoff cannot be negative")
+	}
+	if c.SubscriptionDNSTTL < 0 {
+		return fmt.Errorf("subscription DNS TTL cannot be negative")
+	}
+	if c.ResolvedDomainRewrite && c.ResolvedDomain == "" {
+		return fmt.Errorf("resolved_domain_rewrite requires resolvedDomain")
+	}
+	if c.WireGuardPeersFile != "" && c.WireGuardEndpoint == "" {
+		return fmt.Errorf("wireguard_peers_file requires wireguard_endpoint")
+	}
+	if c.GeoLookupURL != "" && !strings.HasPrefix(c.GeoLookupURL, "https://") && !strings.HasPrefix(c.GeoLookupURL, "http://") {
+		return fmt.Errorf("geo_lookup_url must be an http:// or https:// URL")
+	}
+	if err := c.validateNetworkProbe(); err != nil {
+		return err
+	}
+	if c.AccessSummaryInterval < 0 || (c.AccessSummaryInterval > 0 && c.AccessSummaryInterval < 60) {
+		return fmt.Errorf("access_summary_interval must be at least 60 seconds")
+	}
+	if c.AccessSummaryTopDomains < 0 || c.AccessSummaryTopDomains > 100 {
+		return fmt.Errorf("access_summary_top_domains must be between 1 and 100")
+	}
+	if _, err := certrenew.ParseClient(c.CertRenewClient); err != nil {
+		return err
+	}
+	if c.CertRenewBeforeDays < 0 || c.CertRenewBeforeDays > 60 {
+		return fmt.Errorf("cert_renew_before_days must be between 1 and 60")
+	}
+	if c.PanelWatchdogFailures < 0 || c.PanelWatchdogMaxRestarts < 0 {
+		return fmt.Errorf("panel_watchdog_failures and panel_watchdog_max_restarts cannot be negative")
+	}
+	if c.PanelWatchdogCooldown < 0 || (c.PanelWatchdogCooldown > 0 && c.PanelWatchdogCooldown < 60) {
+		return fmt.Errorf("panel_watchdog_cooldown must be at least 60 seconds")
+	}
+	if c.XraySupervisionCycles < 0 {
+		return fmt.Errorf("xray_supervision_cycles cannot be negative")
+	}
+	if err := c.validateAlerts(); err != nil {
+		return err
+	}
+	if c.ContainerNetwork != "" && c.ContainerNetwork != container.NetworkHost && c.ContainerNetwork != container.NetworkBridge {
+		return fmt.Errorf("container_network must be host or bridge (set xui_base_url for other networks)")
+	}
+	if c.PollJitter < 0 || c.PollJitter > 50 {
+		return fmt.Errorf("poll_jitter must be between 0 and 50 (percent)")
+	}
+	if !c.StatusReportEnabled() && !c.SubscriptionReportEnabled() && !c.OnlineUsersReportEnabled() {
+		return fmt.Errorf("report_status, report_subscriptions and report_online_users cannot all be false")
+	}
+	if c.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdown timeout cannot be negative")
+	}
+	if c.ReportTimeout < 0 || c.SubscriptionTimeout < 0 || c.ConnectTimeout < 0 {
+		return fmt.Errorf("report, subscription and connect timeouts cannot be negative")
+	}
+	if c.GRPCKeepaliveTime < 0 || c.GRPCKeepaliveTimeout < 0 || c.GRPCReconnectMaxBackoff < 0 {
+		return fmt.Errorf("gRPC keepalive and reconnect settings cannot be negative")
+	}
+	if c.DrainTimeout != nil && *c.DrainTimeout < 0 {
+		return fmt.Errorf("drain timeout cannot be negative")
+	}
+	if c.DrainTimeout != nil && *c.DrainTimeout > 0 && *c.DrainTimeout >= c.ShutdownTimeout {
+		return fmt.Errorf("drain timeout (%ds) must be shorter than the shutdown timeout (%ds)", *c.DrainTimeout, c.ShutdownTimeout)
+	}
+	if c.OnlineUsersMaxStaleness < 0 {
+		return fmt.Errorf("online users max staleness cannot be negative")
+	}
+	if c.SendSpacingMs < -1 {
+		return fmt.Errorf("send spacing must be -1 (disabled), 0 (default) or positive")
+	}
+	emailMode, err := privacy.ParseEmailMode(c.EmailReporting)
+	if err != nil {
+		return err
+	}
+	if emailMode == privacy.EmailHashed && c.EmailHashKey == "" {
+		return fmt.Errorf("email_hash_key is required for email_reporting: hashed")
+	}
+	for _, ip := range c.DNSCheckPublicIPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid dns_check_public_ips entry %q", ip)
+		}
+	}
+	if c.MetricsListen != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsListen); err != nil {
+			return fmt.Errorf("invalid metrics_listen %q: %w", c.MetricsListen, err)
+		}
+	}
+	if c.LocalAPI != "" {
+		if err := validateLocalAPI(c.LocalAPI); err != nil {
+			return err
+		}
+	}
+	if c.HistoryHours < 0 || c.HistoryHours > 168 {
+		return fmt.Errorf("history_hours must be between 0 and 168")
+	}
+	if c.OfflineQueueMaxMB != nil && *c.OfflineQueueMaxMB < 0 {
+		return fmt.Errorf("offline queue size cannot be negative")
+	}
+	if c.GRPCCA != "" && c.GRPCCAFile != "" && c.GRPCCA != c.GRPCCAFile {
+		return fmt.Errorf("grpc_ca and grpc_ca_file name different files, set only one")
+	}
+	if strings.EqualFold(strings.TrimSpace(c.Transport), "https") && c.XHubHTTPSURL == "" {
+		return fmt.Errorf("xhub_https_url is required for transport: https")
+	}
+	if c.SubscriptionChunkKB != nil && *c.SubscriptionChunkKB < 0 {
+		return fmt.Errorf("subscription chunk size cannot be negative")
+	}
+	if c.ReportCompressionMinKB != nil && *c.ReportCompressionMinKB < 0 {
+		return fmt.Errorf("report_compression_min_kb cannot be negative")
+	}
+	if c.ReportDeltaFull < 0 {
+		return fmt.Errorf("report_delta_full cannot be negative")
+	}
+	if c.ReportBatchSize < 0 || c.ReportBatchSize > 120 {
+		return fmt.Errorf("report_batch_size must be between 0 and 120")
+	}
+	if c.ReportBandwidthKBPerMin < 0 {
+		return fmt.Errorf("report bandwidth limit cannot be negative")
+	}
+	if c.HeartbeatInterval != nil && *c.HeartbeatInterval < 0 {
+		return fmt.Errorf("heartbeat interval cannot be negative")
+	}
+	if c.SelfTestInterval != nil && *c.SelfTestInterval < 0 {
+		return fmt.Errorf("selftest interval cannot be negative")
+	}
+	for name, seconds := range c.CollectorIntervals {
+		if seconds < 0 {
+			return fmt.Errorf("collector interval of %s cannot be negative", name)
+		}
+	}
 	return nil
 }
 
+// StatusDumpEnabled reports whether the status is logged at debug level (log_status_dump)
+func (c *Config) StatusDumpEnabled() bool {
+	return c.LogStatusDump == nil || *c.LogStatusDump
+}
+
+// StatusReportEnabled reports whether status reports are sent (report_status)
+func (c *Config) StatusReportEnabled() bool {
+	return c.ReportStatus == nil || *c.ReportStatus
+}
+
+// SubscriptionReportEnabled reports whether subscription reports are sent (report_subscriptions)
+func (c *Config) SubscriptionReportEnabled() bool {
+	return c.ReportSubscriptions == nil || *c.ReportSubscriptions
+}
+
+// OnlineUsersReportEnabled reports whether online users reports are sent (report_online_users)
+func (c *Config) OnlineUsersReportEnabled() bool {
+	return c.ReportOnlineUsers == nil || *c.ReportOnlineUsers
+}
+
+// Hysteria2ReportEnabled reports whether the Hysteria2 status and nodes are reported
+// (report_hysteria2), when hysteria2_enabled
+func (c *Config) Hysteria2ReportEnabled() bool {
+	return c.ReportHysteria2 == nil || *c.ReportHysteria2
+}
+
+// GRPCKeepaliveWithoutStreamEnabled reports whether the xhub connection is also pinged
+// while no RPC is in flight (grpc_keepalive_without_stream)
+func (c *Config) GRPCKeepaliveWithoutStreamEnabled() bool {
+	return c.GRPCKeepaliveWithoutStream == nil || *c.GRPCKeepaliveWithoutStream
+}
+
+// PreferIPv6Enabled reports whether IPv6 is dialed first under the auto dial strategy (prefer_ipv6)
+func (c *Config) PreferIPv6Enabled() bool {
+	return c.PreferIPv6 == nil || *c.PreferIPv6
+}
+
+// HostStatusFallbackEnabled reports whether host metrics are reported while the panel is
+// unavailable (host_status_fallback)
+func (c *Config) HostStatusFallbackEnabled() bool {
+	return c.HostStatusFallback == nil || *c.HostStatusFallback
+}
+
+// StatusPeriod returns the interval of the report cycles, each of which reports the status
+func (c *Config) StatusPeriod() time.Duration {
+	if c.StatusInterval > 0 {
+		return time.Duration(c.StatusInterval) * time.Second
+	}
+	return time.Duration(c.PollInterval) * time.Second
+}
+
+// SubscriptionPeriod returns how often the subscriptions are collected and reported
+func (c *Config) SubscriptionPeriod() time.Duration {
+	if c.SubscriptionInterval > 0 {
+		return time.Duration(c.SubscriptionInterval) * time.Second
+	}
+	return c.StatusPeriod()
+}
+
+// OnlineUsersPeriod returns how often the online users are collected and reported
+func (c *Config) OnlineUsersPeriod() time.Duration {
+	if c.OnlineUsersInterval > 0 {
+		return time.Duration(c.OnlineUsersInterval) * time.Second
+	}
+	return c.StatusPeriod()
+}
+
+// DrainPeriod returns how long a shutdown may spend delivering the queued reports and the
+// shutdown notice, 0 when disabled. The default leaves half of shutdown_timeout to the rest,
+// and is 3s in container mode so that SIGTERM is answered quickly.
+func (c *Config) DrainPeriod() time.Duration {
+	if c.DrainTimeout == nil && c.Container {
+		return min(3*time.Second, time.Duration(c.ShutdownTimeout)*time.Second/2)
+	}
+	if c.DrainTimeout == nil {
+		return min(5*time.Second, time.Duration(c.ShutdownTimeout)*time.Second/2)
+	}
+	return time.Duration(*c.DrainTimeout) * time.Second
+}
+
+// validateLocalAPI checks that local_api is a unix socket or a loopback address: the API
+// shows the configuration and changes the log level without authentication
+func validateLocalAPI(address string) error {
+	if socket, ok := strings.CutPrefix(address, "unix:"); ok {
+		if socket == "" {
+			return fmt.Errorf("local_api needs a socket path after unix:")
+		}
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid local_api %q: %w", address, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("local_api must listen on a loopback address or unix:<path>, got %q", address)
+	}
+	return nil
+}
+
+// validateAlerts checks the alert thresholds and hooks
+func (c *Config) validateAlerts() error {
+	if c.AlertCPU < 0 || c.AlertCPU > 100 || c.AlertMemory < 0 || c.AlertMemory > 100 || c.AlertDisk < 0 || c.AlertDisk > 100 {
+		return fmt.Errorf("alert_cpu, alert_memory and alert_disk must be between 0 and 100 (percent)")
+	}
+	if c.AlertCertExpiryDays < 0 || c.AlertCertExpiryDays > 365 {
+		return fmt.Errorf("alert_cert_expiry_days must be between 0 and 365")
+	}
+	if c.AlertCycles < 0 {
+		return fmt.Errorf("alert_cycles cannot be negative")
+	}
+	if c.AlertWebhook != "" && !strings.HasPrefix(c.AlertWebhook, "https://") && !strings.HasPrefix(c.AlertWebhook, "http://") {
+		return fmt.Errorf("alert_webhook must be an http:// or https:// URL")
+	}
+	if (c.AlertTelegramToken == "") != (c.AlertTelegramChat == "") {
+		return fmt.Errorf("alert_telegram_token and alert_telegram_chat must be set together")
+	}
+	return nil
+}
+
+// validateNetworkProbe checks the network probe targets and limits
+func (c *Config) validateNetworkProbe() error {
+	for _, target := range c.NetworkProbeTargets {
+		if host, port, err := net.SplitHostPort(target); err != nil || host == "" || port == "" {
+			return fmt.Errorf("invalid network_probe_targets entry %q (expected host:port)", target)
+		}
+	}
+	if c.NetworkProbeCount < 0 || c.NetworkProbeInterval < 0 || c.NetworkProbeThroughputMB < 0 {
+		return fmt.Errorf("network probe count, interval and throughput cap cannot be negative")
+	}
+	if c.NetworkProbeThroughputURL != "" && !strings.HasPrefix(c.NetworkProbeThroughputURL, "https://") && !strings.HasPrefix(c.NetworkProbeThroughputURL, "http://") {
+		return fmt.Errorf("network_probe_throughput_url must be an http:// or https:// URL")
+	}
+	if c.NetworkProbeInterval > 0 && !c.NetworkProbeEnabled() {
+		return fmt.Errorf("network_probe_interval requires network_probe_targets or network_probe_throughput_url")
+	}
+	return nil
+}
+
+// NetworkProbeEnabled reports whether there is anything for the network probe to measure
+func (c *Config) NetworkProbeEnabled() bool {
+	return len(c.NetworkProbeTargets) > 0 || c.NetworkProbeThroughputURL != ""
+}
+
+// HeartbeatPeriod returns the interval of the heartbeat RPC, 0 when disabled
+func (c *Config) HeartbeatPeriod() time.Duration {
+	if c.HeartbeatInterval == nil {
+		return 5 * time.Second
+	}
+	return time.Duration(*c.HeartbeatInterval) * time.Second
+}
+
+// SelfTestPeriod returns the interval of the scheduled self-test, 0 when disabled
+func (c *Config) SelfTestPeriod() time.Duration {
+	if c.SelfTestInterval == nil {
+		return 24 * time.Hour
+	}
+	return time.Duration(*c.SelfTestInterval) * time.Second
+}
+
+// OfflineQueueMaxBytes returns the size limit of the offline report queue, 0 when disabled
+func (c *Config) OfflineQueueMaxBytes() int64 {
+	if c.OfflineQueueMaxMB == nil {
+		return 16 << 20
+	}
+	return int64(*c.OfflineQueueMaxMB) << 20
+}
+
+// CAFile returns the CA bundle verifying xhub (grpc_ca or grpc_ca_file), empty for the
+// system roots
+func (c *Config) CAFile() string {
+	if c.GRPCCA != "" {
+		return c.GRPCCA
+	}
+	return c.GRPCCAFile
+}
+
+// SubscriptionChunkBytes returns the size above which subscription reports are split, 0 when
+// they are never split
+func (c *Config) SubscriptionChunkBytes() int {
+	if c.SubscriptionChunkKB == nil {
+		return 2048 << 10
+	}
+	return *c.SubscriptionChunkKB << 10
+}
+
+// ReportCompressionMinBytes returns the size below which requests are sent uncompressed, 0
+// when every request is compressed
+func (c *Config) ReportCompressionMinBytes() int {
+	if c.ReportCompressionMinKB == nil {
+		return 1 << 10
+	}
+	return *c.ReportCompressionMinKB << 10
+}
+
 // GetFullXUIURL gets the complete 3x-ui URL
 func (c *Config) GetFullXUIURL() string {
 	return fmt.Sprintf("https://%s:%d%s", c.XUIBaseURL, c.Port, c.RootPath)
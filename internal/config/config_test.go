@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -77,6 +79,159 @@ port: 22799
 	assert.Equal(t, "127.0.0.1", config.XUIBaseURL)
 	assert.Equal(t, 2, config.PollInterval) // gRPC 时代默认 2 秒
 	assert.Equal(t, "info", config.LogLevel)
+	assert.Equal(t, 3600, config.XUISessionTTL)
+	assert.Equal(t, 3, config.SubscriptionRetryAttempts)
+	assert.Equal(t, 500, config.SubscriptionRetryBackoffMs)
+	assert.Equal(t, 4, config.SubscriptionFetchConcurrency)
+	assert.Equal(t, 30, config.SubscriptionFetchTimeout)
+	assert.Equal(t, 60, config.SubscriptionDNSTTL)
+	assert.Equal(t, 3, config.ReportRetryAttempts)
+	assert.Equal(t, 250, config.ReportRetryBackoffMs)
+	assert.Equal(t, 5000, config.ReportRetryMaxBackoffMs)
+	assert.Equal(t, 10, config.ShutdownTimeout)
+	assert.Equal(t, 30, config.ReportTimeout)
+	assert.Equal(t, 30, config.SubscriptionTimeout)
+	assert.Equal(t, 10, config.ConnectTimeout)
+	assert.Equal(t, 60, config.GRPCKeepaliveTime)
+	assert.Equal(t, 20, config.GRPCKeepaliveTimeout)
+	assert.Equal(t, 30, config.GRPCReconnectMaxBackoff)
+	assert.Equal(t, 60, config.ReportDeltaFull)
+	assert.True(t, config.GRPCKeepaliveWithoutStreamEnabled())
+	assert.True(t, config.StatusDumpEnabled())
+	assert.Equal(t, 24*time.Hour, config.SelfTestPeriod())
+	assert.Equal(t, int64(16<<20), config.OfflineQueueMaxBytes())
+	assert.False(t, config.LogStatusCompact)
+	assert.Equal(t, 2*time.Second, config.StatusPeriod())
+	assert.Equal(t, 2*time.Second, config.SubscriptionPeriod())
+	assert.Equal(t, 2*time.Second, config.OnlineUsersPeriod())
+}
+
+func TestConfig_DrainPeriod(t *testing.T) {
+	config := Config{ShutdownTimeout: 10}
+	assert.Equal(t, 5*time.Second, config.DrainPeriod())
+	config.ShutdownTimeout = 4
+	assert.Equal(t, 2*time.Second, config.DrainPeriod(), "the default leaves half of a short shutdown timeout")
+
+	drain := 3
+	config.DrainTimeout = &drain
+	assert.Equal(t, 3*time.Second, config.DrainPeriod())
+	drain = 0
+	assert.Zero(t, config.DrainPeriod())
+}
+
+func TestConfig_HeartbeatPeriod(t *testing.T) {
+	config := Config{}
+	assert.Equal(t, 5*time.Second, config.HeartbeatPeriod())
+
+	interval := 30
+	config.HeartbeatInterval = &interval
+	assert.Equal(t, 30*time.Second, config.HeartbeatPeriod())
+	interval = 0
+	assert.Zero(t, config.HeartbeatPeriod(), "0 disables heartbeats")
+}
+
+func TestConfig_SubscriptionChunkBytes(t *testing.T) {
+	config := Config{}
+	assert.Equal(t, 2<<20, config.SubscriptionChunkBytes())
+
+	size := 512
+	config.SubscriptionChunkKB = &size
+	assert.Equal(t, 512<<10, config.SubscriptionChunkBytes())
+	size = 0
+	assert.Zero(t, config.SubscriptionChunkBytes(), "0 never splits")
+}
+
+func TestConfig_ReportCompressionMinBytes(t *testing.T) {
+	config := Config{}
+	assert.Equal(t, 1<<10, config.ReportCompressionMinBytes())
+
+	size := 4
+	config.ReportCompressionMinKB = &size
+	assert.Equal(t, 4<<10, config.ReportCompressionMinBytes())
+	size = 0
+	assert.Zero(t, config.ReportCompressionMinBytes(), "0 compresses every request")
+}
+
+func TestConfig_ReportPeriods(t *testing.T) {
+	config := Config{PollInterval: 5}
+	assert.Equal(t, 5*time.Second, config.StatusPeriod(), "status_interval defaults to poll_interval")
+
+	config.StatusInterval = 10
+	assert.Equal(t, 10*time.Second, config.StatusPeriod())
+	assert.Equal(t, 10*time.Second, config.SubscriptionPeriod(), "the other intervals default to status_interval")
+	assert.Equal(t, 10*time.Second, config.OnlineUsersPeriod())
+
+	config.SubscriptionInterval = 300
+	config.OnlineUsersInterval = 30
+	assert.Equal(t, 5*time.Minute, config.SubscriptionPeriod())
+	assert.Equal(t, 30*time.Second, config.OnlineUsersPeriod())
+}
+
+func TestConfig_GRPCAddress(t *testing.T) {
+	config := Config{GRPCServer: "xhub.example.com", GRPCPort: 443}
+	assert.Equal(t, "xhub.example.com:443", config.GRPCAddress())
+
+	for _, server := range []string{"2001:db8::1", "[2001:db8::1]"} {
+		config = Config{GRPCServer: server, GRPCPort: 443}
+		assert.Equal(t, "[2001:db8::1]:443", config.GRPCAddress(), server)
+	}
+
+	assert.True(t, config.PreferIPv6Enabled())
+	prefer := false
+	config.PreferIPv6 = &prefer
+	assert.False(t, config.PreferIPv6Enabled())
+}
+
+func TestConfig_ReportTypes(t *testing.T) {
+	var config Config
+	assert.True(t, config.StatusReportEnabled())
+	assert.True(t, config.SubscriptionReportEnabled())
+	assert.True(t, config.OnlineUsersReportEnabled())
+	assert.True(t, config.Hysteria2ReportEnabled())
+
+	path := writeConfig(t, plaintextConfig+"report_status: false\nreport_hysteria2: false\n")
+	cfg, err := LoadFromFile(path)
+	require.NoError(t, err)
+	assert.False(t, cfg.StatusReportEnabled())
+	assert.False(t, cfg.Hysteria2ReportEnabled())
+	assert.True(t, cfg.SubscriptionReportEnabled())
+
+	path = writeConfig(t, plaintextConfig+"report_status: false\nreport_subscriptions: false\nreport_online_users: false\n")
+	_, err = LoadFromFile(path)
+	assert.ErrorContains(t, err, "cannot all be false")
+}
+
+func TestConfig_ReportBatchSize(t *testing.T) {
+	cfg, err := LoadFromFile(writeConfig(t, plaintextConfig+"report_batch_size: 30\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 30, cfg.ReportBatchSize)
+
+	_, err = LoadFromFile(writeConfig(t, plaintextConfig+"report_batch_size: 121\n"))
+	assert.EqualError(t, err, "configuration validation failed: report_batch_size must be between 0 and 120")
+}
+
+func TestConfig_HistoryHours(t *testing.T) {
+	cfg, err := LoadFromFile(writeConfig(t, plaintextConfig+"history_hours: 24\nhistory_persist: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 24, cfg.HistoryHours)
+	assert.True(t, cfg.HistoryPersist)
+
+	_, err = LoadFromFile(writeConfig(t, plaintextConfig+"history_hours: 169\n"))
+	assert.EqualError(t, err, "configuration validation failed: history_hours must be between 0 and 168")
+}
+
+func TestConfig_Alerts(t *testing.T) {
+	cfg, err := LoadFromFile(writeConfig(t, plaintextConfig+"alert_cpu: 90\nalert_telegram_token: \"1:abc\"\nalert_telegram_chat: \"-100\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 90.0, cfg.AlertCPU)
+	assert.Equal(t, 1, cfg.AlertCycles, "default")
+
+	_, err = LoadFromFile(writeConfig(t, plaintextConfig+"alert_disk: 120\n"))
+	assert.EqualError(t, err, "configuration validation failed: alert_cpu, alert_memory and alert_disk must be between 0 and 100 (percent)")
+	_, err = LoadFromFile(writeConfig(t, plaintextConfig+"alert_telegram_token: \"1:abc\"\n"))
+	assert.EqualError(t, err, "configuration validation failed: alert_telegram_token and alert_telegram_chat must be set together")
+	_, err = LoadFromFile(writeConfig(t, plaintextConfig+"alert_webhook: hooks.example.com\n"))
+	assert.EqualError(t, err, "configuration validation failed: alert_webhook must be an http:// or https:// URL")
 }
 
 func TestConfig_LoadFromFile_NonExistentFile(t *testing.T) {
@@ -153,6 +308,13 @@ func TestConfig_SmartGRPCPortDefaults(t *testing.T) {
 			expectedPort: 443,  // Should be overridden to 443
 			description:  "Domain server should override backend port to 443",
 		},
+		{
+			name:         "bracketed_ipv6_loopback_default_port",
+			grpcServer:   "[::1]",
+			grpcPort:     0, // Not set
+			expectedPort: 9090,
+			description:  "The bracketed IPv6 loopback should default to port 9090",
+		},
 	}
 
 	for _, tt := range tests {
@@ -177,6 +339,8 @@ func TestConfig_SmartGRPCPortDefaults(t *testing.T) {
 }
 
 func TestConfig_Validate(t *testing.T) {
+	negativeQueueSize := -1
+	longDrain := 10
 	tests := []struct {
 		name    string
 		config  Config
@@ -264,6 +428,394 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "hashed email reporting without key",
+			config: Config{
+				UUID:           "test-uuid",
+				XUIUser:        "admin",
+				XUIPass:        "password",
+				XHubAPIKey:     "api-key",
+				GRPCServer:     "example.com",
+				GRPCPort:       9090,
+				RootPath:       "/wIqhNNPV3lC3ZzAHdd",
+				Port:           22799,
+				XUIBaseURL:     "127.0.0.1",
+				EmailReporting: "hashed",
+			},
+			wantErr: true,
+		},
+		{
+			name: "hashed email reporting with key",
+			config: Config{
+				UUID:           "test-uuid",
+				XUIUser:        "admin",
+				XUIPass:        "password",
+				XHubAPIKey:     "api-key",
+				GRPCServer:     "example.com",
+				GRPCPort:       9090,
+				RootPath:       "/wIqhNNPV3lC3ZzAHdd",
+				Port:           22799,
+				XUIBaseURL:     "127.0.0.1",
+				EmailReporting: "hashed",
+				EmailHashKey:   "secret",
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown email reporting mode",
+			config: Config{
+				UUID:           "test-uuid",
+				XUIUser:        "admin",
+				XUIPass:        "password",
+				XHubAPIKey:     "api-key",
+				GRPCServer:     "example.com",
+				GRPCPort:       9090,
+				RootPath:       "/wIqhNNPV3lC3ZzAHdd",
+				Port:           22799,
+				XUIBaseURL:     "127.0.0.1",
+				EmailReporting: "encrypted",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative online users max staleness",
+			config: Config{
+				UUID:                    "test-uuid",
+				XUIUser:                 "admin",
+				XUIPass:                 "password",
+				XHubAPIKey:              "api-key",
+				GRPCServer:              "example.com",
+				GRPCPort:                9090,
+				RootPath:                "/wIqhNNPV3lC3ZzAHdd",
+				Port:                    22799,
+				XUIBaseURL:              "127.0.0.1",
+				OnlineUsersMaxStaleness: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative offline queue size",
+			config: Config{
+				UUID:              "test-uuid",
+				XUIUser:           "admin",
+				XUIPass:           "password",
+				XHubAPIKey:        "api-key",
+				GRPCServer:        "example.com",
+				GRPCPort:          9090,
+				RootPath:          "/wIqhNNPV3lC3ZzAHdd",
+				Port:              22799,
+				XUIBaseURL:        "127.0.0.1",
+				OfflineQueueMaxMB: &negativeQueueSize,
+			},
+			wantErr: true,
+		},
+		{
+			name: "grpc_ca and grpc_ca_file differ",
+			config: Config{
+				UUID:       "test-uuid",
+				XUIUser:    "admin",
+				XUIPass:    "password",
+				XHubAPIKey: "api-key",
+				GRPCServer: "example.com",
+				GRPCPort:   9090,
+				RootPath:   "/wIqhNNPV3lC3ZzAHdd",
+				Port:       22799,
+				XUIBaseURL: "127.0.0.1",
+				GRPCCA:     "/etc/xhub/ca.pem",
+				GRPCCAFile: "/etc/xhub/other.pem",
+			},
+			wantErr: true,
+		},
+		{
+			name: "https transport without URL",
+			config: Config{
+				UUID:       "test-uuid",
+				XUIUser:    "admin",
+				XUIPass:    "password",
+				XHubAPIKey: "api-key",
+				GRPCServer: "example.com",
+				GRPCPort:   9090,
+				RootPath:   "/wIqhNNPV3lC3ZzAHdd",
+				Port:       22799,
+				XUIBaseURL: "127.0.0.1",
+				Transport:  "https",
+			},
+			wantErr: true,
+		},
+		{
+			name: "metrics listen without port",
+			config: Config{
+				UUID:          "test-uuid",
+				XUIUser:       "admin",
+				XUIPass:       "password",
+				XHubAPIKey:    "api-key",
+				GRPCServer:    "example.com",
+				GRPCPort:      9090,
+				RootPath:      "/wIqhNNPV3lC3ZzAHdd",
+				Port:          22799,
+				XUIBaseURL:    "127.0.0.1",
+				MetricsListen: "127.0.0.1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "local API on a public address",
+			config: Config{
+				UUID:       "test-uuid",
+				XUIUser:    "admin",
+				XUIPass:    "password",
+				XHubAPIKey: "api-key",
+				GRPCServer: "example.com",
+				GRPCPort:   9090,
+				RootPath:   "/wIqhNNPV3lC3ZzAHdd",
+				Port:       22799,
+				XUIBaseURL: "127.0.0.1",
+				LocalAPI:   "0.0.0.0:9101",
+			},
+			wantErr: true,
+		},
+		{
+			name: "local API on a unix socket",
+			config: Config{
+				UUID:       "test-uuid",
+				XUIUser:    "admin",
+				XUIPass:    "password",
+				XHubAPIKey: "api-key",
+				GRPCServer: "example.com",
+				GRPCPort:   9090,
+				RootPath:   "/wIqhNNPV3lC3ZzAHdd",
+				Port:       22799,
+				XUIBaseURL: "127.0.0.1",
+				LocalAPI:   "unix:/run/xhub-agent/api.sock",
+			},
+			wantErr: false,
+		},
+		{
+			name: "client certificate without API key",
+			config: Config{
+				UUID:           "test-uuid",
+				XUIUser:        "admin",
+				XUIPass:        "password",
+				GRPCServer:     "example.com",
+				GRPCPort:       9090,
+				RootPath:       "/wIqhNNPV3lC3ZzAHdd",
+				Port:           22799,
+				XUIBaseURL:     "127.0.0.1",
+				GRPCClientCert: "/etc/xhub-agent/client.pem",
+				GRPCClientKey:  "/etc/xhub-agent/client.key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "client certificate without key",
+			config: Config{
+				UUID:           "test-uuid",
+				XUIUser:        "admin",
+				XUIPass:        "password",
+				XHubAPIKey:     "api-key",
+				GRPCServer:     "example.com",
+				GRPCPort:       9090,
+				RootPath:       "/wIqhNNPV3lC3ZzAHdd",
+				Port:           22799,
+				XUIBaseURL:     "127.0.0.1",
+				GRPCClientCert: "/etc/xhub-agent/client.pem",
+			},
+			wantErr: true,
+		},
+		{
+			name: "drain timeout not shorter than shutdown timeout",
+			config: Config{
+				UUID:            "test-uuid",
+				XUIUser:         "admin",
+				XUIPass:         "password",
+				XHubAPIKey:      "api-key",
+				GRPCServer:      "example.com",
+				GRPCPort:        9090,
+				RootPath:        "/wIqhNNPV3lC3ZzAHdd",
+				Port:            22799,
+				XUIBaseURL:      "127.0.0.1",
+				ShutdownTimeout: 10,
+				DrainTimeout:    &longDrain,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative report timeout",
+			config: Config{
+				UUID:          "test-uuid",
+				XUIUser:       "admin",
+				XUIPass:       "password",
+				XHubAPIKey:    "api-key",
+				GRPCServer:    "example.com",
+				GRPCPort:      9090,
+				RootPath:      "/wIqhNNPV3lC3ZzAHdd",
+				Port:          22799,
+				XUIBaseURL:    "127.0.0.1",
+				ReportTimeout: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative subscription interval",
+			config: Config{
+				UUID:                 "test-uuid",
+				XUIUser:              "admin",
+				XUIPass:              "password",
+				XHubAPIKey:           "api-key",
+				GRPCServer:           "example.com",
+				GRPCPort:             9090,
+				RootPath:             "/wIqhNNPV3lC3ZzAHdd",
+				Port:                 22799,
+				XUIBaseURL:           "127.0.0.1",
+				SubscriptionInterval: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative subscription fetch concurrency",
+			config: Config{
+				UUID:                         "test-uuid",
+				XUIUser:                      "admin",
+				XUIPass:                      "password",
+				XHubAPIKey:                   "api-key",
+				GRPCServer:                   "example.com",
+				GRPCPort:                     9090,
+				RootPath:                     "/wIqhNNPV3lC3ZzAHdd",
+				Port:                         22799,
+				XUIBaseURL:                   "127.0.0.1",
+				SubscriptionFetchConcurrency: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "geo lookup URL without scheme",
+			config: Config{
+				UUID:         "test-uuid",
+				XUIUser:      "admin",
+				XUIPass:      "password",
+				XHubAPIKey:   "api-key",
+				GRPCServer:   "example.com",
+				GRPCPort:     9090,
+				RootPath:     "/wIqhNNPV3lC3ZzAHdd",
+				Port:         22799,
+				XUIBaseURL:   "127.0.0.1",
+				GeoLookupURL: "ipinfo.io/json",
+			},
+			wantErr: true,
+		},
+		{
+			name: "wireguard peers file without endpoint",
+			config: Config{
+				UUID:               "test-uuid",
+				XUIUser:            "admin",
+				XUIPass:            "password",
+				XHubAPIKey:         "api-key",
+				GRPCServer:         "example.com",
+				GRPCPort:           9090,
+				RootPath:           "/wIqhNNPV3lC3ZzAHdd",
+				Port:               22799,
+				XUIBaseURL:         "127.0.0.1",
+				WireGuardPeersFile: "/etc/wireguard/peers.yaml",
+			},
+			wantErr: true,
+		},
+		{
+			name: "network probe target without port",
+			config: Config{
+				UUID:                "test-uuid",
+				XUIUser:             "admin",
+				XUIPass:             "password",
+				XHubAPIKey:          "api-key",
+				GRPCServer:          "example.com",
+				GRPCPort:            9090,
+				RootPath:            "/wIqhNNPV3lC3ZzAHdd",
+				Port:                22799,
+				XUIBaseURL:          "127.0.0.1",
+				NetworkProbeTargets: []string{"1.1.1.1"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "network probe interval without target",
+			config: Config{
+				UUID:                 "test-uuid",
+				XUIUser:              "admin",
+				XUIPass:              "password",
+				XHubAPIKey:           "api-key",
+				GRPCServer:           "example.com",
+				GRPCPort:             9090,
+				RootPath:             "/wIqhNNPV3lC3ZzAHdd",
+				Port:                 22799,
+				XUIBaseURL:           "127.0.0.1",
+				NetworkProbeInterval: 3600,
+			},
+			wantErr: true,
+		},
+		{
+			name: "network probe throughput URL without scheme",
+			config: Config{
+				UUID:                      "test-uuid",
+				XUIUser:                   "admin",
+				XUIPass:                   "password",
+				XHubAPIKey:                "api-key",
+				GRPCServer:                "example.com",
+				GRPCPort:                  9090,
+				RootPath:                  "/wIqhNNPV3lC3ZzAHdd",
+				Port:                      22799,
+				XUIBaseURL:                "127.0.0.1",
+				NetworkProbeThroughputURL: "speed.example.com/10MB.bin",
+			},
+			wantErr: true,
+		},
+		{
+			name: "access summary interval too short",
+			config: Config{
+				UUID:                  "test-uuid",
+				XUIUser:               "admin",
+				XUIPass:               "password",
+				XHubAPIKey:            "api-key",
+				GRPCServer:            "example.com",
+				GRPCPort:              9090,
+				RootPath:              "/wIqhNNPV3lC3ZzAHdd",
+				Port:                  22799,
+				XUIBaseURL:            "127.0.0.1",
+				AccessSummary:         true,
+				AccessSummaryInterval: 10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown cert renew client",
+			config: Config{
+				UUID:            "test-uuid",
+				XUIUser:         "admin",
+				XUIPass:         "password",
+				XHubAPIKey:      "api-key",
+				GRPCServer:      "example.com",
+				GRPCPort:        9090,
+				RootPath:        "/wIqhNNPV3lC3ZzAHdd",
+				Port:            22799,
+				XUIBaseURL:      "127.0.0.1",
+				CertRenewClient: "lego",
+			},
+			wantErr: true,
+		},
+		{
+			name: "resolved domain rewrite without resolved domain",
+			config: Config{
+				UUID:                  "test-uuid",
+				XUIUser:               "admin",
+				XUIPass:               "password",
+				XHubAPIKey:            "api-key",
+				GRPCServer:            "example.com",
+				GRPCPort:              9090,
+				RootPath:              "/wIqhNNPV3lC3ZzAHdd",
+				Port:                  22799,
+				XUIBaseURL:            "127.0.0.1",
+				ResolvedDomainRewrite: true,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -277,3 +829,129 @@ func TestConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+const legacyConfigBase = `uuid: test-uuid-123
+xui_user: admin
+xui_pass: password123
+xhub_api_key: abcd1234apikey
+rootPath: /test
+port: 22799
+`
+
+func TestConfig_LegacyReportURL_Derivation(t *testing.T) {
+	tests := []struct {
+		name         string
+		reportURL    string
+		extra        string
+		expectedHost string
+		expectedPort int
+	}{
+		{"https with path", "https://xhub.example.com/agent/report", "", "xhub.example.com", 443},
+		{"https with port and path", "https://xhub.example.com:8443/agent/report", "", "xhub.example.com", 443},
+		{"http without path", "http://xhub.example.com", "", "xhub.example.com", 443},
+		{"http localhost with port", "http://localhost:8080/agent/report", "", "localhost", 9090},
+		{"http localhost with configured grpc port", "http://127.0.0.1:8080/agent/report", "grpcPort: 9191\n", "127.0.0.1", 9191},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yml")
+			content := legacyConfigBase + "reportUrl: " + tt.reportURL + "\n" + tt.extra
+			require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+			config, err := LoadFromFile(configPath)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedHost, config.GRPCServer)
+			assert.Equal(t, tt.expectedPort, config.GRPCPort)
+
+			migration := config.LegacyMigration()
+			require.NotNil(t, migration)
+			assert.Equal(t, tt.reportURL, migration.ReportURL)
+			assert.Equal(t, tt.expectedHost, migration.GRPCServer)
+			assert.Equal(t, tt.expectedPort, migration.GRPCPort)
+		})
+	}
+}
+
+func TestConfig_LegacyReportURL_IgnoredWhenGRPCServerSet(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	content := legacyConfigBase + "reportUrl: https://old.example.com/agent/report\ngrpcServer: new.example.com\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+	config, err := LoadFromFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "new.example.com", config.GRPCServer)
+	assert.Nil(t, config.LegacyMigration())
+}
+
+func TestConfig_LegacyReportURL_Warning(t *testing.T) {
+	migration := &LegacyMigration{
+		ReportURL:  "https://xhub.example.com/agent/report",
+		GRPCServer: "xhub.example.com",
+		GRPCPort:   443,
+	}
+
+	warning := strings.Join(migration.Warning(), "\n")
+	assert.Contains(t, warning, "reportUrl: https://xhub.example.com/agent/report")
+	assert.Contains(t, warning, "xhub.example.com:443")
+	assert.Contains(t, warning, `grpcServer: "xhub.example.com"`)
+	assert.Contains(t, warning, "grpcPort: 443")
+	assert.Contains(t, warning, "migrate-config")
+
+	assert.Contains(t, migration.ConnectionHint(), "legacy reportUrl")
+}
+
+func TestMigrateLegacyFile_RoundTrip(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	original := legacyConfigBase + "# old endpoint\nreportUrl: https://xhub.example.com:8080/agent/report\nlog_level: debug\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(original), 0600))
+
+	backupPath, migration, err := MigrateLegacyFile(configPath)
+	require.NoError(t, err)
+	require.NotNil(t, migration)
+
+	// Backup keeps the original content
+	backup, err := os.ReadFile(backupPath)
+	require.NoError(t, err)
+	assert.Equal(t, original, string(backup))
+
+	// Rewritten file loads without any legacy migration
+	rewritten, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(rewritten), "\nreportUrl:")
+	assert.Contains(t, string(rewritten), "# migrated from reportUrl")
+
+	config, err := LoadFromFile(configPath)
+	require.NoError(t, err)
+	assert.Nil(t, config.LegacyMigration())
+	assert.Equal(t, "xhub.example.com", config.GRPCServer)
+	assert.Equal(t, 443, config.GRPCPort)
+	assert.Equal(t, "debug", config.LogLevel)
+
+	// Second run is a no-op
+	backupPath, migration, err = MigrateLegacyFile(configPath)
+	require.NoError(t, err)
+	assert.Nil(t, migration)
+	assert.Empty(t, backupPath)
+}
+
+func TestConfig_FailInject(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	content := legacyConfigBase + "grpcServer: example.com\nfail_inject: \"report=20%\"\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+	config, err := LoadFromFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "report=20%", config.FailInject)
+
+	// Environment variable overrides the config file
+	t.Setenv("XHUB_AGENT_FAIL_INJECT", "auth=5%")
+	config, err = LoadFromFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "auth=5%", config.FailInject)
+
+	t.Setenv("XHUB_AGENT_FAIL_INJECT", "report=lots")
+	_, err = LoadFromFile(configPath)
+	assert.Error(t, err)
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"xhub-agent/internal/container"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envReference matches ${NAME} and ${NAME:-default} in config values; $${ escapes a literal ${
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// secretsDir holds the mounted secrets read in container mode (variable for tests)
+var secretsDir = container.SecretsDir
+
+// secretFileSuffix marks a key whose value is the path of a file holding a secret field,
+// e.g. xhub_api_key_file for xhub_api_key
+const secretFileSuffix = "_file"
+
+// EnvPrefix prefixes the environment variables setting config options, e.g. XHUB_AGENT_UUID
+const EnvPrefix = "XHUB_AGENT_"
+
+// EnvName returns the environment variable setting a config key: XHUB_AGENT_GRPC_SERVER for
+// grpcServer, XHUB_AGENT_XUI_PASS for xui_pass
+func EnvName(key string) string {
+	var name strings.Builder
+	name.WriteString(EnvPrefix)
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 && !unicode.IsUpper(rune(key[i-1])) {
+			name.WriteByte('_')
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
+
+// EnvConfigured reports whether an environment variable sets a config option, in which case
+// LoadFromEnv does not need a config file
+func EnvConfigured() bool {
+	for _, field := range fields(&Config{}) {
+		if os.Getenv(EnvName(field.name)) != "" {
+			return true
+		}
+		if secretFields[field.name] && os.Getenv(EnvName(field.name)+"_FILE") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadFromEnv loads the config file at path like LoadFromFile, then sets the options given by
+// XHUB_AGENT_* environment variables (see EnvName) over the file values. Secret fields can be
+// read from the file named by <variable>_FILE instead, or in container mode from the mounted
+// secrets directory. Empty variables are ignored. The config file is optional when a variable
+// is set.
+func LoadFromEnv(path string) (*Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := decodeConfig(data, path, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	case errors.Is(err, fs.ErrNotExist) && EnvConfigured():
+		// Configured by the environment only
+	default:
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+	if config.Container {
+		if err := config.readMountedSecrets(secretsDir); err != nil {
+			return nil, err
+		}
+	}
+	return config.finish(path)
+}
+
+// applyEnv sets the options given by environment variables
+func (c *Config) applyEnv() error {
+	for _, field := range fields(c) {
+		name := EnvName(field.name)
+		value := os.Getenv(name)
+		if secretFields[field.name] {
+			if path := os.Getenv(name + "_FILE"); path != "" {
+				if value != "" {
+					return fmt.Errorf("both %s and %s_FILE are set", name, name)
+				}
+				content, err := os.ReadFile(path)
+				if err != nil {
+					return fmt.Errorf("failed to read %s_FILE: %w", name, err)
+				}
+				value = strings.TrimRight(string(content), "\r\n")
+			}
+		}
+		if value == "" {
+			continue
+		}
+		if err := setField(field.value, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// readMountedSecrets sets the secret fields still empty from the files of dir named after them
+// (e.g. /run/secrets/xhub_api_key), as Docker and Kubernetes mount secrets
+func (c *Config) readMountedSecrets(dir string) error {
+	for _, field := range fields(c) {
+		if !secretFields[field.name] || field.value.Kind() != reflect.String || field.value.String() != "" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, field.name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read mounted secret %s: %w", field.name, err)
+		}
+		field.value.SetString(strings.TrimRight(string(content), "\r\n"))
+	}
+	return nil
+}
+
+// setField sets a config field from an environment variable: strings as is, string lists
+// comma-separated, other types parsed as YAML (numbers, booleans, [a, b], {key: value})
+func setField(field reflect.Value, value string) error {
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+		return nil
+	case field.Kind() == reflect.Pointer && field.Type().Elem().Kind() == reflect.String:
+		ptr := reflect.New(field.Type().Elem())
+		ptr.Elem().SetString(value)
+		field.Set(ptr)
+		return nil
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(value, "["):
+		items := strings.Split(value, ",")
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		field.Set(reflect.ValueOf(items))
+		return nil
+	}
+	target := reflect.New(field.Type())
+	if err := yaml.Unmarshal([]byte(value), target.Interface()); err != nil {
+		return err
+	}
+	field.Set(target.Elem())
+	return nil
+}
+
+// decodeConfig parses a config file into config. Environment references in the values are
+// substituted first, then the secrets given as <field>_file are read (paths relative to the
+// config file).
+func decodeConfig(data []byte, configPath string, config *Config) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil // Empty file
+	}
+	if err := expandEnv(&doc); err != nil {
+		return err
+	}
+	if root := doc.Content[0]; root.Kind == yaml.MappingNode {
+		if err := readSecretFiles(root, configPath); err != nil {
+			return err
+		}
+	}
+	return doc.Decode(config)
+}
+
+// expandEnv substitutes the environment references of the scalar values below node. A plain
+// value is resolved again after substitution, so "grpcPort: ${PORT}" decodes as a number.
+func expandEnv(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${") {
+		var missing []string
+		node.Value = envReference.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			match := envReference.FindStringSubmatch(ref)
+			value, ok := os.LookupEnv(match[1])
+			if strings.Contains(ref, ":-") && value == "" {
+				return match[2] // Default for an unset or empty variable
+			}
+			if !ok {
+				missing = append(missing, match[1])
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			return fmt.Errorf("line %d: environment variable %s is not set", node.Line, strings.Join(missing, ", "))
+		}
+		if node.Style == 0 {
+			node.Tag = "" // Resolve the substituted value
+		}
+	}
+	for _, child := range node.Content {
+		if err := expandEnv(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSecretFiles replaces the <field>_file keys of the secret fields with the field set to
+// the content of the file, without its trailing newline
+func readSecretFiles(root *yaml.Node, configPath string) error {
+	set := map[string]bool{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		set[root.Content[i].Value] = root.Content[i+1].Value != ""
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		field, ok := secretFileField(key.Value)
+		if !ok || value.Value == "" {
+			continue
+		}
+		if set[field] {
+			return fmt.Errorf("both %s and %s are set", field, key.Value)
+		}
+		path := value.Value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(configPath), path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", key.Value, err)
+		}
+		key.Value = field
+		value.Value, value.Tag, value.Style = strings.TrimRight(string(content), "\r\n"), "!!str", 0
+	}
+	return nil
+}
+
+// secretFileField returns the secret field read from the file of key, if key is one
+func secretFileField(key string) (string, bool) {
+	field, ok := strings.CutSuffix(key, secretFileSuffix)
+	return field, ok && secretFields[field]
+}
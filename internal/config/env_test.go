@@ -0,0 +1,176 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFromFile_EnvSubstitution(t *testing.T) {
+	t.Setenv("TEST_XHUB_UUID", "env-uuid")
+	t.Setenv("TEST_XHUB_API_KEY", "env-key: with colon")
+	t.Setenv("TEST_XHUB_PORT", "9090")
+	path := writeConfig(t, `uuid: ${TEST_XHUB_UUID}
+xui_user: admin
+xui_pass: "pa$${TEST_XHUB_UNSET:-word}"
+xhub_api_key: ${TEST_XHUB_API_KEY}
+grpcServer: ${TEST_XHUB_SERVER:-localhost}
+grpcPort: ${TEST_XHUB_PORT}
+rootPath: /panel
+port: 2053
+# grpc_proxy: ${TEST_XHUB_COMMENTED}
+`)
+
+	cfg, err := LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "env-uuid", cfg.UUID)
+	assert.Equal(t, "env-key: with colon", cfg.XHubAPIKey, "substituted values are not parsed as YAML")
+	assert.Equal(t, "pa${TEST_XHUB_UNSET:-word}", cfg.XUIPass, "$${ escapes a reference")
+	assert.Equal(t, "localhost", cfg.GRPCServer)
+	assert.Equal(t, 9090, cfg.GRPCPort)
+}
+
+func TestLoadFromFile_EnvUnset(t *testing.T) {
+	path := writeConfig(t, plaintextConfig+"email_hash_key: ${TEST_XHUB_UNSET}\n")
+	_, err := LoadFromFile(path)
+	assert.ErrorContains(t, err, "line 8: environment variable TEST_XHUB_UNSET is not set")
+}
+
+func TestLoadFromFile_SecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api_key"), []byte("file-key\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "secrets"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets", "xui_pass"), []byte("file-password"), 0o600))
+	path := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`uuid: test-uuid
+xui_user: admin
+xui_pass_file: secrets/xui_pass
+xhub_api_key_file: `+filepath.Join(dir, "api_key")+`
+grpcServer: localhost
+rootPath: /panel
+port: 2053
+`), 0o600))
+
+	cfg, err := LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "file-password", cfg.XUIPass, "relative to the config file")
+	assert.Equal(t, "file-key", cfg.XHubAPIKey, "trailing newline trimmed")
+
+	unknown, err := UnknownFields(path)
+	require.NoError(t, err)
+	assert.Empty(t, unknown)
+
+	// Both the value and its file
+	path = writeConfig(t, plaintextConfig+"xhub_api_key_file: /run/secrets/xhub_api_key\n")
+	_, err = LoadFromFile(path)
+	assert.ErrorContains(t, err, "both xhub_api_key and xhub_api_key_file are set")
+
+	// Missing file
+	path = writeConfig(t, "uuid: test-uuid\nxhub_api_key_file: missing\n")
+	_, err = LoadFromFile(path)
+	assert.ErrorContains(t, err, "failed to read xhub_api_key_file")
+}
+
+func TestEncryptFile_SkipsEnvReferences(t *testing.T) {
+	path := writeConfig(t, plaintextConfig+"email_hash_key: ${TEST_XHUB_HASH_KEY:-}\n")
+	result, err := EncryptFile(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"xhub_api_key", "xui_pass"}, result.Fields)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "${TEST_XHUB_HASH_KEY:-}")
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "XHUB_AGENT_UUID", EnvName("uuid"))
+	assert.Equal(t, "XHUB_AGENT_GRPC_SERVER", EnvName("grpcServer"))
+	assert.Equal(t, "XHUB_AGENT_XHUB_API_KEY", EnvName("xhub_api_key"))
+
+	seen := map[string]string{}
+	for _, field := range fields(&Config{}) {
+		name := EnvName(field.name)
+		assert.Empty(t, seen[name], "%s and %s share %s", seen[name], field.name, name)
+		seen[name] = field.name
+	}
+}
+
+func TestLoadFromEnv_NoFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api_key"), []byte("file-key\n"), 0o600))
+	t.Setenv("XHUB_AGENT_UUID", "env-uuid")
+	t.Setenv("XHUB_AGENT_XUI_USER", "admin")
+	t.Setenv("XHUB_AGENT_XUI_PASS", "env-password")
+	t.Setenv("XHUB_AGENT_XHUB_API_KEY_FILE", filepath.Join(dir, "api_key"))
+	t.Setenv("XHUB_AGENT_GRPC_SERVER", "localhost")
+	t.Setenv("XHUB_AGENT_GRPC_PORT", "9091")
+	t.Setenv("XHUB_AGENT_ROOT_PATH", "/panel")
+	t.Setenv("XHUB_AGENT_PORT", "2053")
+	t.Setenv("XHUB_AGENT_NETWORK_PROBE_TARGETS", "1.1.1.1:443, 8.8.8.8:53")
+	t.Setenv("XHUB_AGENT_COLLECTOR_INTERVALS", "{clock: 300}")
+
+	cfg, err := LoadFromEnv(filepath.Join(dir, "config.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "env-uuid", cfg.UUID)
+	assert.Equal(t, "env-password", cfg.XUIPass)
+	assert.Equal(t, "file-key", cfg.XHubAPIKey)
+	assert.Equal(t, "localhost", cfg.GRPCServer)
+	assert.Equal(t, 9091, cfg.GRPCPort)
+	assert.Equal(t, []string{"1.1.1.1:443", "8.8.8.8:53"}, cfg.NetworkProbeTargets)
+	assert.Equal(t, map[string]int{"clock": 300}, cfg.CollectorIntervals)
+	assert.Equal(t, 2, cfg.PollInterval, "defaults applied")
+
+	t.Setenv("XHUB_AGENT_GRPC_PORT", "https")
+	_, err = LoadFromEnv(filepath.Join(dir, "config.yml"))
+	assert.ErrorContains(t, err, "invalid XHUB_AGENT_GRPC_PORT")
+
+	t.Setenv("XHUB_AGENT_GRPC_PORT", "")
+	t.Setenv("XHUB_AGENT_XHUB_API_KEY", "env-key")
+	_, err = LoadFromEnv(filepath.Join(dir, "config.yml"))
+	assert.ErrorContains(t, err, "both XHUB_AGENT_XHUB_API_KEY and XHUB_AGENT_XHUB_API_KEY_FILE are set")
+}
+
+func TestLoadFromEnv_MergesFile(t *testing.T) {
+	path := writeConfig(t, plaintextConfig)
+	t.Setenv("XHUB_AGENT_GRPC_SERVER", "xhub.example.com")
+	t.Setenv("XHUB_AGENT_XUI_USER", "") // Empty: the file value is kept
+
+	cfg, err := LoadFromEnv(path)
+	require.NoError(t, err)
+	assert.Equal(t, "xhub.example.com", cfg.GRPCServer)
+	assert.Equal(t, "admin", cfg.XUIUser)
+	assert.Equal(t, "secret-password", cfg.XUIPass)
+}
+
+func TestLoadFromEnv_MissingFile(t *testing.T) {
+	_, err := LoadFromEnv(filepath.Join(t.TempDir(), "config.yml"))
+	assert.ErrorContains(t, err, "failed to read config file")
+}
+
+func TestLoadFromEnv_Container(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "email_hash_key"), []byte("mounted-key\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "xui_pass"), []byte("mounted-password\n"), 0o600))
+	previous := secretsDir
+	secretsDir = dir
+	t.Cleanup(func() { secretsDir = previous })
+
+	path := writeConfig(t, plaintextConfig)
+	t.Setenv("XHUB_AGENT_CONTAINER", "true")
+	cfg, err := LoadFromEnv(path)
+	require.NoError(t, err)
+	assert.Equal(t, "mounted-key", cfg.EmailHashKey, "empty secrets read from the mounts")
+	assert.Equal(t, "secret-password", cfg.XUIPass, "configured secrets kept")
+	assert.Equal(t, "host", cfg.ContainerNetwork)
+	assert.Equal(t, "127.0.0.1", cfg.XUIBaseURL)
+	assert.Equal(t, 8, cfg.ShutdownTimeout)
+	assert.Equal(t, 3*time.Second, cfg.DrainPeriod())
+
+	t.Setenv("XHUB_AGENT_CONTAINER_NETWORK", "macvlan")
+	_, err = LoadFromEnv(path)
+	assert.ErrorContains(t, err, "container_network must be host or bridge")
+}
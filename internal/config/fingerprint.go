@@ -0,0 +1,106 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// secretFields are the config keys whose values are never logged
+var secretFields = map[string]bool{
+	"xui_pass":             true,
+	"xui_login_secret":     true,
+	"xui_totp_secret":      true,
+	"xhub_api_key":         true,
+	"email_hash_key":       true,
+	"grpc_proxy":           true, // May carry proxy credentials
+	"alert_webhook":        true, // May carry a token
+	"alert_telegram_token": true,
+}
+
+// redacted replaces secret values in diffs
+const redacted = "[redacted]"
+
+// FieldChange is a config field whose value differs between two configs
+type FieldChange struct {
+	Field string // YAML key
+	Old   string // Previous value (redacted for secrets)
+	New   string // New value (redacted for secrets)
+}
+
+// String formats the change as "field: old -> new"
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+// Fingerprint returns a short stable hash of the effective configuration (after defaults).
+// Secrets are part of the hash, so rotating a key changes the fingerprint without revealing it.
+func (c *Config) Fingerprint() string {
+	values := make(map[string]interface{})
+	for _, field := range fields(c) {
+		values[field.name] = field.value.Interface()
+	}
+	data, err := json.Marshal(values) // Map keys are sorted
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// Diff returns the fields that differ between old and new, in declaration order
+func Diff(old, new *Config) []FieldChange {
+	oldFields, newFields := fields(old), fields(new)
+	var changes []FieldChange
+	for i, field := range oldFields {
+		if reflect.DeepEqual(field.value.Interface(), newFields[i].value.Interface()) {
+			continue
+		}
+		change := FieldChange{Field: field.name, Old: redacted, New: redacted}
+		if !secretFields[field.name] {
+			change.Old = formatValue(field.value)
+			change.New = formatValue(newFields[i].value)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// configField an exported config field with its YAML key
+type configField struct {
+	name  string
+	value reflect.Value
+}
+
+// fields returns the YAML-mapped fields of c
+func fields(c *Config) []configField {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	var result []configField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if !sf.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		result = append(result, configField{name: name, value: v.Field(i)})
+	}
+	return result
+}
+
+// formatValue renders a field value for logs
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "<unset>"
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.String())
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fingerprintConfig = `uuid: test-uuid-123
+xui_user: admin
+xui_pass: password123
+xhub_api_key: abcd1234apikey
+grpcServer: xhub.example.com
+grpcPort: 9090
+rootPath: /test
+port: 22799
+poll_interval: 5
+`
+
+func TestConfig_Fingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(fingerprintConfig), 0644))
+
+	first, err := LoadFromFile(path)
+	require.NoError(t, err)
+	second, err := LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Len(t, first.Fingerprint(), 16)
+	assert.Equal(t, first.Fingerprint(), second.Fingerprint(), "stable for the same config")
+
+	// Rotating a secret changes the fingerprint
+	second.XHubAPIKey = "rotated-key"
+	assert.NotEqual(t, first.Fingerprint(), second.Fingerprint())
+}
+
+func TestConfig_Diff_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(fingerprintConfig), 0644))
+	old, err := LoadFromFile(path)
+	require.NoError(t, err)
+
+	changed := strings.NewReplacer(
+		"poll_interval: 5", "poll_interval: 10\nlog_level: debug\nlog_status_compact: true",
+		"xhub_api_key: abcd1234apikey", "xhub_api_key: newkey5678",
+	).Replace(fingerprintConfig)
+	require.NoError(t, os.WriteFile(path, []byte(changed), 0644))
+	reloaded, err := LoadFromFile(path)
+	require.NoError(t, err)
+
+	changes := Diff(old, reloaded)
+	var fields []string
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+	assert.Equal(t, []string{"xhub_api_key", "poll_interval", "log_level", "log_status_compact"}, fields)
+
+	byField := make(map[string]FieldChange)
+	for _, change := range changes {
+		byField[change.Field] = change
+	}
+	assert.Equal(t, "poll_interval: 5 -> 10", byField["poll_interval"].String())
+	assert.Equal(t, `log_level: "info" -> "debug"`, byField["log_level"].String())
+	assert.Equal(t, "xhub_api_key: [redacted] -> [redacted]", byField["xhub_api_key"].String())
+	for _, change := range changes {
+		assert.NotContains(t, change.String(), "abcd1234apikey")
+		assert.NotContains(t, change.String(), "newkey5678")
+	}
+
+	assert.Empty(t, Diff(old, old))
+	assert.NotEqual(t, old.Fingerprint(), reloaded.Fingerprint())
+}
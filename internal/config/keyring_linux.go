@@ -0,0 +1,35 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// readKeyring reads the payload of a "user" key of the kernel user keyring
+func readKeyring(name string) ([]byte, error) {
+	id, err := unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, "user", name, 0)
+	if errors.Is(err, unix.ENOKEY) {
+		return nil, errKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keyring: %w", err)
+	}
+	buf := make([]byte, 256)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, buf, 0)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: %w", err)
+	}
+	if n > len(buf) {
+		return nil, fmt.Errorf("keyring: key %q is too large", name)
+	}
+	return buf[:n], nil
+}
+
+// writeKeyring stores payload as a "user" key of the kernel user keyring. The keyring is
+// kept in memory only: the key must be added again after a reboot.
+func writeKeyring(name string, payload []byte) error {
+	_, err := unix.AddKey("user", name, payload, unix.KEY_SPEC_USER_KEYRING)
+	return err
+}
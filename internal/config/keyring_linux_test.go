@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestKeySource_Keyring(t *testing.T) {
+	source := KeySource{Keyring: true, Name: fmt.Sprintf("xhub-agent-test-%d", time.Now().UnixNano())}
+	key, created, err := source.loadOrCreate()
+	if err != nil {
+		t.Skipf("kernel keyring unavailable: %v", err)
+	}
+	t.Cleanup(func() {
+		if id, err := unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, "user", source.Name, 0); err == nil {
+			unix.KeyctlInt(unix.KEYCTL_UNLINK, id, unix.KEY_SPEC_USER_KEYRING, 0, 0)
+		}
+	})
+	assert.True(t, created)
+
+	loaded, err := source.load()
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package config
+
+import "errors"
+
+// errNoKeyring is returned where the kernel keyring is unavailable
+var errNoKeyring = errors.New("the keyring key source is only supported on Linux")
+
+func readKeyring(name string) ([]byte, error) {
+	return nil, errNoKeyring
+}
+
+func writeKeyring(name string, payload []byte) error {
+	return errNoKeyring
+}
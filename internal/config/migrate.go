@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LegacyMigration describes a gRPC endpoint derived from a pre-gRPC reportUrl
+type LegacyMigration struct {
+	ReportURL  string // Original legacy reportUrl value
+	GRPCServer string // Derived gRPC server host
+	GRPCPort   int    // Derived gRPC port after smart port rules
+}
+
+// deriveFromReportURL fills GRPCServer from the legacy reportUrl when grpcServer is absent.
+// The HTTP port of the legacy URL is not a gRPC port, so the smart port rules decide the port.
+func (c *Config) deriveFromReportURL() error {
+	if c.ReportURL == "" || c.GRPCServer != "" {
+		return nil
+	}
+
+	host, err := hostFromReportURL(c.ReportURL)
+	if err != nil {
+		return err
+	}
+
+	c.GRPCServer = host
+	c.applySmartGRPCPortDefaults()
+	c.legacyMigration = &LegacyMigration{
+		ReportURL:  c.ReportURL,
+		GRPCServer: c.GRPCServer,
+		GRPCPort:   c.GRPCPort,
+	}
+	return nil
+}
+
+// hostFromReportURL extracts the host from a legacy HTTP report URL
+func hostFromReportURL(reportURL string) (string, error) {
+	raw := strings.TrimSpace(reportURL)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid legacy reportUrl %q: %w", reportURL, err)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid legacy reportUrl %q: missing host", reportURL)
+	}
+	return u.Hostname(), nil
+}
+
+// LegacyMigration returns the derived endpoint if the config still uses reportUrl, or nil
+func (c *Config) LegacyMigration() *LegacyMigration {
+	return c.legacyMigration
+}
+
+// Warning returns the multi-line operator warning explaining the derived endpoint
+func (m *LegacyMigration) Warning() []string {
+	return []string{
+		fmt.Sprintf("Config uses legacy 'reportUrl: %s' from the pre-gRPC HTTP era", m.ReportURL),
+		fmt.Sprintf("Derived gRPC endpoint: %s:%d (host taken from reportUrl, port from smart port rules)", m.GRPCServer, m.GRPCPort),
+		"The URL path and HTTP port of reportUrl are ignored by gRPC",
+		"Please replace reportUrl in the config with:",
+		fmt.Sprintf("  grpcServer: \"%s\"", m.GRPCServer),
+		fmt.Sprintf("  grpcPort: %d", m.GRPCPort),
+		"or run: xhub-agent migrate-config -c <config path>",
+	}
+}
+
+// ConnectionHint returns the hint appended to the first gRPC connection error
+func (m *LegacyMigration) ConnectionHint() string {
+	return fmt.Sprintf("gRPC endpoint %s:%d was derived from legacy reportUrl %s; "+
+		"the legacy migration is a likely cause, set grpcServer/grpcPort explicitly", m.GRPCServer, m.GRPCPort, m.ReportURL)
+}
+
+// MigrateLegacyFile rewrites a config file that uses reportUrl to use grpcServer/grpcPort.
+// The original file is kept as a timestamped backup. Returns the backup path, or "" if
+// nothing needed migration.
+func MigrateLegacyFile(path string) (string, *LegacyMigration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return "", nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := cfg.deriveFromReportURL(); err != nil {
+		return "", nil, err
+	}
+	migration := cfg.LegacyMigration()
+	if migration == nil {
+		return "", nil, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return "", nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return "", nil, fmt.Errorf("config file is not a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	// Replace the reportUrl entry in place with grpcServer/grpcPort
+	var content []*yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "reportUrl":
+			content = append(content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "grpcServer", HeadComment: key.HeadComment},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: migration.GRPCServer, Style: yaml.DoubleQuotedStyle,
+					LineComment: "migrated from reportUrl: " + migration.ReportURL},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "grpcPort"},
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(migration.GRPCPort)},
+			)
+		case "grpcServer", "grpcPort":
+			// Dropped, replaced by the migrated values
+		default:
+			content = append(content, key, value)
+		}
+	}
+	root.Content = content
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode migrated config: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to stat config file: %w", err)
+	}
+
+	backupPath := fmt.Sprintf("%s.backup.%s", path, time.Now().Format("20060102_150405"))
+	if err := os.WriteFile(backupPath, data, info.Mode().Perm()); err != nil {
+		return "", nil, fmt.Errorf("failed to write config backup: %w", err)
+	}
+	if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return "", nil, fmt.Errorf("failed to write migrated config: %w", err)
+	}
+
+	return backupPath, migration, nil
+}
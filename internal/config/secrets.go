@@ -0,0 +1,307 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// encryptedPrefix marks an encrypted secret value: enc:v1:base64(nonce || AES-256-GCM ciphertext)
+const encryptedPrefix = "enc:v1:"
+
+// DefaultKeyFile is the key file used when secrets_key is unset, next to the config file
+const DefaultKeyFile = "config.key"
+
+// errKeyNotFound is returned by the key stores when the key does not exist yet
+var errKeyNotFound = errors.New("key not found")
+
+// KeySource locates the key decrypting the secret fields (secrets_key): a key file, or an
+// entry of the OS keyring
+type KeySource struct {
+	Keyring bool   // Name is a keyring entry instead of a file path
+	Name    string // Key file path, or keyring entry description
+}
+
+// ParseKeySource parses a secrets_key value: "file:<path>" (relative to the config file
+// directory), "keyring:<name>", or empty for the config.key file next to the config file
+func ParseKeySource(value, configPath string) (KeySource, error) {
+	value = strings.TrimSpace(value)
+	kind, name, _ := strings.Cut(value, ":")
+	switch {
+	case value == "":
+		return KeySource{Name: filepath.Join(filepath.Dir(configPath), DefaultKeyFile)}, nil
+	case kind == "file" && name != "":
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(configPath), name)
+		}
+		return KeySource{Name: name}, nil
+	case kind == "keyring" && name != "":
+		return KeySource{Keyring: true, Name: name}, nil
+	default:
+		return KeySource{}, fmt.Errorf("invalid secrets_key %q (expected file:<path> or keyring:<name>)", value)
+	}
+}
+
+// String returns the secrets_key value selecting s
+func (s KeySource) String() string {
+	if s.Keyring {
+		return "keyring:" + s.Name
+	}
+	return "file:" + s.Name
+}
+
+// load reads the key, errKeyNotFound if it does not exist
+func (s KeySource) load() ([]byte, error) {
+	var encoded []byte
+	if s.Keyring {
+		var err error
+		if encoded, err = readKeyring(s.Name); err != nil {
+			return nil, err
+		}
+	} else {
+		info, err := os.Stat(s.Name)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errKeyNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode().Perm()&0o077 != 0 {
+			return nil, fmt.Errorf("key file %s is accessible by group or others (mode %04o), expected 0600", s.Name, info.Mode().Perm())
+		}
+		if encoded, err = os.ReadFile(s.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s does not hold a base64 encoded 32-byte key", s)
+	}
+	return key, nil
+}
+
+// loadOrCreate reads the key, generating and storing a new one if it does not exist
+func (s KeySource) loadOrCreate() (key []byte, created bool, err error) {
+	key, err = s.load()
+	if !errors.Is(err, errKeyNotFound) {
+		return key, false, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, false, err
+	}
+	encoded := []byte(base64.StdEncoding.EncodeToString(key) + "\n")
+	if s.Keyring {
+		err = writeKeyring(s.Name, encoded)
+	} else {
+		err = os.WriteFile(s.Name, encoded, 0o600)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to store the key in %s: %w", s, err)
+	}
+	return key, true, nil
+}
+
+// IsEncrypted reports whether a config value is an encrypted secret
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// encryptValue encrypts the value of field, which is bound to the ciphertext so encrypted
+// values cannot be swapped between fields
+func encryptValue(key []byte, field, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(field))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptValue decrypts an encrypted value of field
+func decryptValue(key []byte, field, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptSecrets replaces the encrypted secret fields with their plaintext, loading the key
+// of secrets_key only when a field is encrypted
+func (c *Config) decryptSecrets(configPath string) error {
+	var key []byte
+	for _, field := range fields(c) {
+		if !secretFields[field.name] || field.value.Kind() != reflect.String || !IsEncrypted(field.value.String()) {
+			continue
+		}
+		if key == nil {
+			source, err := ParseKeySource(c.SecretsKey, configPath)
+			if err != nil {
+				return err
+			}
+			if key, err = source.load(); err != nil {
+				if errors.Is(err, errKeyNotFound) {
+					err = fmt.Errorf("%s not found", source)
+				}
+				return fmt.Errorf("failed to load the key decrypting %s: %w", field.name, err)
+			}
+		}
+		plaintext, err := decryptValue(key, field.name, field.value.String())
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", field.name, err)
+		}
+		field.value.SetString(plaintext)
+	}
+	return nil
+}
+
+// EncryptResult describes what EncryptFile changed
+type EncryptResult struct {
+	Fields     []string  // Secret fields encrypted by this run (sorted)
+	Key        KeySource // Key the fields are encrypted with
+	KeyCreated bool      // The key did not exist and was generated
+}
+
+// EncryptFile encrypts the plaintext secret fields (xui_pass, xhub_api_key, ...) of a config
+// file in place. The key is the one of keySource, recorded as secrets_key in the file, or of
+// the file's secrets_key when keySource is empty; it is generated if it does not exist.
+// Encrypted fields are left as they are. No backup is kept, it would hold the plaintext.
+func EncryptFile(path, keySource string) (*EncryptResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file is not a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	var secretsKey *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "secrets_key" {
+			secretsKey = root.Content[i+1]
+		}
+	}
+	if keySource == "" && secretsKey != nil {
+		keySource = secretsKey.Value
+	}
+	source, err := ParseKeySource(keySource, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Secrets already encrypted must be readable with the selected key
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	cfg.SecretsKey = source.String()
+	if err := cfg.decryptSecrets(path); err != nil {
+		return nil, err
+	}
+
+	key, created, err := source.loadOrCreate()
+	if err != nil {
+		return nil, err
+	}
+	result := &EncryptResult{Key: source, KeyCreated: created}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		name, value := root.Content[i].Value, root.Content[i+1]
+		if !secretFields[name] || value.Kind != yaml.ScalarNode || value.Value == "" || IsEncrypted(value.Value) ||
+			envReference.MatchString(value.Value) {
+			continue
+		}
+		encrypted, err := encryptValue(key, name, value.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %w", name, err)
+		}
+		value.Value, value.Tag, value.Style = encrypted, "!!str", yaml.DoubleQuotedStyle
+		result.Fields = append(result.Fields, name)
+	}
+	sort.Strings(result.Fields)
+
+	// Record an explicitly chosen key so the agent finds it
+	if keySource != "" {
+		if secretsKey == nil {
+			secretsKey = &yaml.Node{}
+			root.Content = append(root.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "secrets_key", HeadComment: "Key decrypting the enc:v1: values"},
+				secretsKey)
+		}
+		secretsKey.Kind, secretsKey.Tag, secretsKey.Style, secretsKey.Value = yaml.ScalarNode, "!!str", yaml.DoubleQuotedStyle, source.String()
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode encrypted config: %w", err)
+	}
+	if err := replaceFile(path, out); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// replaceFile atomically replaces the content of path, keeping its permissions
+func replaceFile(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat config file: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
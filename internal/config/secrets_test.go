@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const plaintextConfig = `uuid: test-uuid
+xui_user: admin
+xui_pass: "secret-password" # panel login
+xhub_api_key: secret-key
+grpcServer: localhost
+rootPath: /panel
+port: 2053
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestParseKeySource(t *testing.T) {
+	source, err := ParseKeySource("", "/opt/xhub-agent/config.yml")
+	require.NoError(t, err)
+	assert.Equal(t, KeySource{Name: "/opt/xhub-agent/config.key"}, source)
+
+	source, err = ParseKeySource("file:keys/agent.key", "/opt/xhub-agent/config.yml")
+	require.NoError(t, err)
+	assert.Equal(t, "file:/opt/xhub-agent/keys/agent.key", source.String(), "relative to the config file")
+
+	source, err = ParseKeySource("keyring:xhub-agent", "/opt/xhub-agent/config.yml")
+	require.NoError(t, err)
+	assert.Equal(t, KeySource{Keyring: true, Name: "xhub-agent"}, source)
+
+	for _, value := range []string{"/etc/key", "file:", "vault:agent"} {
+		_, err = ParseKeySource(value, "/opt/xhub-agent/config.yml")
+		assert.ErrorContains(t, err, "invalid secrets_key", value)
+	}
+}
+
+func TestEncryptValue(t *testing.T) {
+	key := make([]byte, 32)
+	encrypted, err := encryptValue(key, "xui_pass", "secret-password")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "secret-password")
+
+	plaintext, err := decryptValue(key, "xui_pass", encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-password", plaintext)
+
+	_, err = decryptValue(key, "xhub_api_key", encrypted)
+	assert.ErrorContains(t, err, "wrong key", "values are bound to their field")
+	key[0] = 1
+	_, err = decryptValue(key, "xui_pass", encrypted)
+	assert.ErrorContains(t, err, "wrong key")
+}
+
+func TestEncryptFile(t *testing.T) {
+	path := writeConfig(t, plaintextConfig)
+
+	result, err := EncryptFile(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"xhub_api_key", "xui_pass"}, result.Fields)
+	assert.True(t, result.KeyCreated)
+	keyPath := filepath.Join(filepath.Dir(path), DefaultKeyFile)
+	assert.Equal(t, "file:"+keyPath, result.Key.String())
+	info, err := os.Stat(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret-password")
+	assert.NotContains(t, string(data), "secret-key")
+	assert.Contains(t, string(data), "# panel login", "comments are kept")
+	assert.NotContains(t, string(data), "secrets_key", "the default key is not recorded")
+	matches, _ := filepath.Glob(path + "*")
+	assert.Len(t, matches, 1, "no plaintext backup is left behind")
+
+	cfg, err := LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-password", cfg.XUIPass)
+	assert.Equal(t, "secret-key", cfg.XHubAPIKey)
+
+	// Running again changes nothing
+	result, err = EncryptFile(path, "")
+	require.NoError(t, err)
+	assert.Empty(t, result.Fields)
+	assert.False(t, result.KeyCreated)
+}
+
+func TestEncryptFile_ExplicitKey(t *testing.T) {
+	path := writeConfig(t, plaintextConfig)
+	keyPath := filepath.Join(t.TempDir(), "agent.key")
+
+	_, err := EncryptFile(path, "file:"+keyPath)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), fmt.Sprintf("secrets_key: \"file:%s\"", keyPath))
+
+	cfg, err := LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-key", cfg.XHubAPIKey)
+
+	// Secrets encrypted with another key are refused rather than double-encrypted
+	_, err = EncryptFile(path, "file:"+filepath.Join(t.TempDir(), "other.key"))
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestLoadFromFile_EncryptedSecretErrors(t *testing.T) {
+	path := writeConfig(t, plaintextConfig)
+	_, err := EncryptFile(path, "")
+	require.NoError(t, err)
+	keyPath := filepath.Join(filepath.Dir(path), DefaultKeyFile)
+
+	require.NoError(t, os.Chmod(keyPath, 0o644))
+	_, err = LoadFromFile(path)
+	assert.ErrorContains(t, err, "accessible by group or others")
+
+	require.NoError(t, os.Remove(keyPath))
+	_, err = LoadFromFile(path)
+	assert.ErrorContains(t, err, "failed to load the key decrypting")
+	assert.ErrorContains(t, err, "not found")
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EffectiveYAML renders the configuration after defaults as YAML, in declaration order, with
+// secrets redacted. Options whose default is applied by an accessor show that default.
+func (c *Config) EffectiveYAML() ([]byte, error) {
+	defaults := map[string]interface{}{
+		"log_status_dump":               c.StatusDumpEnabled(),
+		"report_status":                 c.StatusReportEnabled(),
+		"report_subscriptions":          c.SubscriptionReportEnabled(),
+		"report_online_users":           c.OnlineUsersReportEnabled(),
+		"report_hysteria2":              c.Hysteria2ReportEnabled(),
+		"host_status_fallback":          c.HostStatusFallbackEnabled(),
+		"prefer_ipv6":                   c.PreferIPv6Enabled(),
+		"grpc_keepalive_without_stream": c.GRPCKeepaliveWithoutStreamEnabled(),
+		"offline_queue_max_mb":          c.OfflineQueueMaxBytes() >> 20,
+		"selftest_interval":             int(c.SelfTestPeriod().Seconds()),
+		"drain_timeout":                 int(c.DrainPeriod().Seconds()),
+		"heartbeat_interval":            int(c.HeartbeatPeriod().Seconds()),
+		"subscription_chunk_kb":         c.SubscriptionChunkBytes() >> 10,
+		"report_compression_min_kb":     c.ReportCompressionMinBytes() >> 10,
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range fields(c) {
+		var value interface{}
+		comment := ""
+		switch {
+		case field.value.Kind() == reflect.Pointer && field.value.IsNil():
+			value, comment = defaults[field.name], "default"
+		case secretFields[field.name] && !field.value.IsZero():
+			value = redacted
+		default:
+			value = field.value.Interface()
+		}
+
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(value); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", field.name, err)
+		}
+		valueNode.LineComment = comment
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.name}, valueNode)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnknownFields returns the keys of the config file that map to no option (usually typos),
+// as "line N: field x not found" messages. They are ignored when the config is loaded.
+func UnknownFields(filepath string) ([]string, error) {
+	data, err := os.ReadFile(filepath)
+	if errors.Is(err, fs.ErrNotExist) && EnvConfigured() {
+		return nil, nil // Configured by the environment only
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var config Config
+	err = decoder.Decode(&config)
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return nil, nil // Empty file, or a syntax error LoadFromFile reports
+	}
+
+	var unknown []string
+	for _, msg := range typeErr.Errors {
+		if strings.Contains(msg, " not found in type ") && !isSecretFileError(msg) {
+			unknown = append(unknown, strings.Replace(msg, " in type config.Config", "", 1))
+		}
+	}
+	return unknown, nil
+}
+
+// isSecretFileError reports whether msg is about a <secret>_file key, which LoadFromFile reads
+func isSecretFileError(msg string) bool {
+	_, rest, _ := strings.Cut(msg, " field ")
+	key, _, _ := strings.Cut(rest, " ")
+	_, ok := secretFileField(key)
+	return ok
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfig_EffectiveYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(fingerprintConfig+"selftest_interval: 0\n"), 0644))
+	cfg, err := LoadFromFile(path)
+	require.NoError(t, err)
+
+	out, err := cfg.EffectiveYAML()
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "password123")
+	assert.NotContains(t, string(out), "abcd1234apikey")
+	assert.Contains(t, string(out), "log_status_dump: true # default")
+
+	var shown map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(out, &shown))
+	assert.Equal(t, redacted, shown["xui_pass"])
+	assert.Equal(t, redacted, shown["xhub_api_key"])
+	assert.Equal(t, "", shown["email_hash_key"], "unset secrets are shown empty")
+	assert.Equal(t, "info", shown["log_level"], "defaults are applied")
+	assert.Equal(t, 443, shown["grpcPort"])
+	assert.Equal(t, 16, shown["offline_queue_max_mb"])
+	assert.Equal(t, 0, shown["selftest_interval"], "set pointer options keep their value")
+}
+
+func TestUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(fingerprintConfig+"pol_interval: 5\nlog_levl: debug\n"), 0644))
+
+	unknown, err := UnknownFields(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 10: field pol_interval not found", "line 11: field log_levl not found"}, unknown)
+
+	require.NoError(t, os.WriteFile(path, []byte(fingerprintConfig), 0644))
+	unknown, err = UnknownFields(path)
+	require.NoError(t, err)
+	assert.Empty(t, unknown)
+}
@@ -0,0 +1,71 @@
+package container
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Networks the 3x-ui panel of the host is reached through (container_network)
+const (
+	NetworkHost   = "host"   // --network host: the panel listens on the container's loopback
+	NetworkBridge = "bridge" // Bridge network: the panel is reached through the host's gateway address
+)
+
+// FallbackHost names the host on Docker Desktop and on Docker with host-gateway mappings,
+// used when the gateway cannot be read
+const FallbackHost = "host.docker.internal"
+
+// SecretsDir is where Docker and Kubernetes mount secrets
+const SecretsDir = "/run/secrets"
+
+// routeFile lists the IPv4 routes (variable for tests)
+var routeFile = "/proc/net/route"
+
+// PanelHost returns the address of the host's 3x-ui panel for a container_network value
+func PanelHost(network string) string {
+	if network != NetworkBridge {
+		return "127.0.0.1"
+	}
+	gateway, err := DefaultGateway()
+	if err != nil {
+		return FallbackHost
+	}
+	return gateway
+}
+
+// DefaultGateway returns the gateway of the default IPv4 route, the host on a bridge network
+func DefaultGateway() (string, error) {
+	file, err := os.Open(routeFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read routes: %w", err)
+	}
+	defer file.Close()
+
+	// Iface Destination Gateway Flags ..., addresses in little-endian hex
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		gateway := make(net.IP, 4)
+		binary.BigEndian.PutUint32(gateway, binary.LittleEndian.Uint32(raw))
+		if gateway.IsUnspecified() {
+			continue
+		}
+		return gateway.String(), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read routes: %w", err)
+	}
+	return "", fmt.Errorf("no default route")
+}
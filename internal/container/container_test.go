@@ -0,0 +1,38 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setRoutes(t *testing.T, routes string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "route")
+	require.NoError(t, os.WriteFile(path, []byte(routes), 0o644))
+	previous := routeFile
+	routeFile = path
+	t.Cleanup(func() { routeFile = previous })
+}
+
+func TestDefaultGateway(t *testing.T) {
+	setRoutes(t, `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	000011AC	00000000	0001	0	0	0	0000FFFF	0	0	0
+eth0	00000000	010011AC	0003	0	0	0	00000000	0	0	0
+`)
+	gateway, err := DefaultGateway()
+	require.NoError(t, err)
+	assert.Equal(t, "172.17.0.1", gateway)
+	assert.Equal(t, "172.17.0.1", PanelHost(NetworkBridge))
+	assert.Equal(t, "127.0.0.1", PanelHost(NetworkHost))
+}
+
+func TestPanelHost_NoDefaultRoute(t *testing.T) {
+	setRoutes(t, "Iface	Destination	Gateway 	Flags\neth0	000011AC	00000000	0001\n")
+	_, err := DefaultGateway()
+	assert.EqualError(t, err, "no default route")
+	assert.Equal(t, FallbackHost, PanelHost(NetworkBridge))
+}
@@ -0,0 +1,161 @@
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/version"
+	"xhub-agent/pkg/logger"
+)
+
+const (
+	// MaxStack caps the stack trace kept in a dump
+	MaxStack = 64 << 10
+	// MaxDumps is the number of crash dumps kept until they are reported
+	MaxDumps = 10
+	// ExitCode is the exit status of an agent ending with a panic
+	ExitCode = 2
+)
+
+// Dump describes a panic that ended an agent run
+type Dump struct {
+	Time          time.Time `json:"time"`
+	Component     string    `json:"component"` // Goroutine that panicked
+	Panic         string    `json:"panic"`
+	Stack         string    `json:"stack"`
+	Version       string    `json:"version"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	RestartCount  int64     `json:"restart_count"`
+}
+
+// Pending is a crash dump on disk not yet reported to xhub
+type Pending struct {
+	Path string
+	Dump Dump
+}
+
+// Recorder turns a panic of an agent goroutine into a logged stack trace and a crash dump,
+// then ends the process: a goroutine may have panicked while holding a lock, so carrying on
+// risks a hung agent, while the service manager restarts a dead one.
+type Recorder struct {
+	dir          string // Crash dump directory, empty when the data directory is not writable
+	logger       *logger.Logger
+	started      time.Time
+	restartCount int64
+	exit         func(code int)
+}
+
+// NewRecorder creates a recorder writing dumps to dir (none when empty) for the run started
+// at started, the restartCount-th start of the agent
+func NewRecorder(dir string, logger *logger.Logger, started time.Time, restartCount int64) *Recorder {
+	return &Recorder{dir: dir, logger: logger, started: started, restartCount: restartCount, exit: os.Exit}
+}
+
+// Recover is deferred by agent goroutines: it handles a panic of the goroutine, named
+// component in the dump. A nil recorder lets the panic through.
+func (r *Recorder) Recover(component string) {
+	if r == nil {
+		return
+	}
+	if value := recover(); value != nil {
+		r.crash(component, value, debug.Stack())
+	}
+}
+
+// crash logs and records a panic, then exits
+func (r *Recorder) crash(component string, value any, stack []byte) {
+	dump := Dump{
+		Time:          time.Now(),
+		Component:     component,
+		Panic:         fmt.Sprint(value),
+		Stack:         string(stack),
+		Version:       version.Version,
+		UptimeSeconds: int64(time.Since(r.started).Seconds()),
+		RestartCount:  r.restartCount,
+	}
+	if len(dump.Stack) > MaxStack {
+		dump.Stack = dump.Stack[:MaxStack]
+	}
+
+	r.logger.Errorf("💥 Panic in %s: %s\n%s", component, dump.Panic, dump.Stack)
+	if r.dir != "" {
+		if path, err := Write(r.dir, dump); err != nil {
+			r.logger.Errorf("❌ Failed to write crash dump: %v", err)
+		} else {
+			r.logger.Errorf("💾 Crash dump written to %s, it is reported to xhub on the next start", path)
+		}
+	}
+	r.logger.Sync()
+	r.exit(ExitCode)
+}
+
+// Write saves dump in dir and removes the oldest dumps over MaxDumps
+func Write(dir string, dump Dump) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("crash-%d.json", dump.Time.UnixNano()))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+
+	names, _ := dumpNames(dir)
+	for len(names) > MaxDumps {
+		os.Remove(filepath.Join(dir, names[0]))
+		names = names[1:]
+	}
+	return path, nil
+}
+
+// List returns the dumps in dir, oldest first. Unreadable dumps are removed.
+func List(dir string) ([]Pending, error) {
+	names, err := dumpNames(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var pending []Pending
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		var dump Dump
+		if err == nil {
+			err = json.Unmarshal(data, &dump)
+		}
+		if err != nil {
+			os.Remove(path)
+			continue
+		}
+		pending = append(pending, Pending{Path: path, Dump: dump})
+	}
+	return pending, nil
+}
+
+// dumpNames returns the dump file names of dir, oldest first
+func dumpNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "crash-") && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	// Names embed the crash time in nanoseconds, all with the same number of digits
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,87 @@
+package crash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/version"
+	"xhub-agent/pkg/logger"
+)
+
+func createTestLogger(t *testing.T) *logger.Logger {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "agent.log"), "error")
+	require.NoError(t, err)
+	t.Cleanup(log.Close)
+	return log
+}
+
+func TestRecorder_Recover(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crash-dumps")
+	recorder := NewRecorder(dir, createTestLogger(t), time.Now().Add(-time.Minute), 7)
+	exitCode := -1
+	recorder.exit = func(code int) { exitCode = code }
+
+	func() {
+		defer recorder.Recover("work loop")
+		var data map[string]int
+		data["boom"]++ // Assignment to a nil map panics
+	}()
+	assert.Equal(t, ExitCode, exitCode)
+
+	pending, err := List(dir)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	dump := pending[0].Dump
+	assert.Equal(t, "work loop", dump.Component)
+	assert.Contains(t, dump.Panic, "assignment to entry in nil map")
+	assert.Contains(t, dump.Stack, "TestRecorder_Recover")
+	assert.Equal(t, version.Version, dump.Version)
+	assert.Equal(t, int64(7), dump.RestartCount)
+	assert.GreaterOrEqual(t, dump.UptimeSeconds, int64(60))
+}
+
+func TestRecorder_NoPanic(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crash-dumps")
+	recorder := NewRecorder(dir, createTestLogger(t), time.Now(), 1)
+	recorder.exit = func(code int) { t.Fatal("exited without a panic") }
+	func() {
+		defer recorder.Recover("work loop")
+	}()
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	// Without a recorder the panic goes through
+	var none *Recorder
+	assert.Panics(t, func() {
+		defer none.Recover("work loop")
+		panic("boom")
+	})
+}
+
+func TestWriteAndList(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < MaxDumps+3; i++ {
+		_, err := Write(dir, Dump{Time: start.Add(time.Duration(i) * time.Second), Component: "work loop", Panic: "boom", Stack: strings.Repeat("x", 10)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "crash-1.json"), []byte("{broken"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("kept"), 0600))
+
+	pending, err := List(dir)
+	require.NoError(t, err)
+	require.Len(t, pending, MaxDumps, "only the latest dumps are kept")
+	assert.Equal(t, start.Add(3*time.Second).Unix(), pending[0].Dump.Time.Unix(), "oldest first")
+	assert.NoFileExists(t, filepath.Join(dir, "crash-1.json"), "unreadable dumps are removed")
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"))
+
+	pending, err = List(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
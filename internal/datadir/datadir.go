@@ -0,0 +1,186 @@
+package datadir
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Artifact identifies a kind of writable file kept under the data directory
+type Artifact string
+
+const (
+	ArtifactLog     Artifact = "log"     // Agent log file
+	ArtifactState   Artifact = "state"   // Small state file (restart counter, pseudonyms, ...)
+	ArtifactHistory Artifact = "history" // Persisted metrics history
+	ArtifactFailed  Artifact = "failed"  // Persisted payloads of failed report RPCs
+	ArtifactQueue   Artifact = "queue"   // Reports queued while xhub is unreachable
+	ArtifactCrash   Artifact = "crash"   // Crash dumps waiting to be reported to xhub
+)
+
+// artifactNames maps artifacts to their file or directory names under the data directory
+var artifactNames = map[Artifact]string{
+	ArtifactState:   "state.json",
+	ArtifactHistory: "history",
+	ArtifactFailed:  "failed-payloads",
+	ArtifactQueue:   "report-queue",
+	ArtifactCrash:   "crash-dumps",
+}
+
+// ProbeReason classifies why a directory is not writable
+type ProbeReason int
+
+const (
+	ReasonOther ProbeReason = iota
+	ReasonReadOnly
+	ReasonPermission
+)
+
+// ProbeError describes a failed writability probe
+type ProbeError struct {
+	Dir    string
+	Reason ProbeReason
+	Err    error
+}
+
+func (e *ProbeError) Error() string {
+	switch e.Reason {
+	case ReasonReadOnly:
+		return fmt.Sprintf("data directory %s is on a read-only filesystem: %v", e.Dir, e.Err)
+	case ReasonPermission:
+		return fmt.Sprintf("permission denied writing to data directory %s (check ownership or the service user): %v", e.Dir, e.Err)
+	default:
+		return fmt.Sprintf("data directory %s is not writable: %v", e.Dir, e.Err)
+	}
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// Prober checks whether a directory can be written to
+type Prober interface {
+	Probe(dir string) error
+}
+
+// FSProber probes writability by creating the directory and a temporary file in it
+type FSProber struct{}
+
+// Probe creates dir if needed and writes a temporary file into it
+func (FSProber) Probe(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return classify(dir, err)
+	}
+
+	f, err := os.CreateTemp(dir, ".xhub-agent-probe-*")
+	if err != nil {
+		return classify(dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	os.Remove(name)
+
+	return nil
+}
+
+// classify wraps a filesystem error into a ProbeError with the matching reason
+func classify(dir string, err error) error {
+	reason := ReasonOther
+	switch {
+	case errors.Is(err, syscall.EROFS):
+		reason = ReasonReadOnly
+	case errors.Is(err, fs.ErrPermission):
+		reason = ReasonPermission
+	}
+	return &ProbeError{Dir: dir, Reason: reason, Err: err}
+}
+
+// DataDir is the single root under which all writable agent artifacts live
+type DataDir struct {
+	root     string
+	logName  string
+	writable bool
+	probeErr error
+}
+
+// Prepare resolves the data directory and probes its writability.
+// An empty root defaults to the directory of logFile for compatibility.
+// Probe failures are not returned as errors; they are recorded so callers can degrade.
+func Prepare(root, logFile string, prober Prober) *DataDir {
+	if root == "" {
+		root = filepath.Dir(logFile)
+	}
+	if prober == nil {
+		prober = FSProber{}
+	}
+
+	d := &DataDir{
+		root:    root,
+		logName: filepath.Base(logFile),
+	}
+	if err := prober.Probe(root); err != nil {
+		d.probeErr = err
+		return d
+	}
+	d.writable = true
+	return d
+}
+
+// Resolve resolves the data directory like Prepare without probing it, for commands reading
+// the artifacts of an agent
+func Resolve(root, logFile string) *DataDir {
+	if root == "" {
+		root = filepath.Dir(logFile)
+	}
+	return &DataDir{root: root, logName: filepath.Base(logFile)}
+}
+
+// Root returns the data directory path
+func (d *DataDir) Root() string {
+	return d.root
+}
+
+// Writable reports whether the probe succeeded
+func (d *DataDir) Writable() bool {
+	return d.writable
+}
+
+// Err returns the probe error when the directory is not writable
+func (d *DataDir) Err() error {
+	return d.probeErr
+}
+
+// Path returns the location of the given artifact under the data directory
+func (d *DataDir) Path(a Artifact) string {
+	if a == ArtifactLog {
+		return filepath.Join(d.root, d.logName)
+	}
+	if name, ok := artifactNames[a]; ok {
+		return filepath.Join(d.root, name)
+	}
+	return filepath.Join(d.root, string(a))
+}
+
+// Enabled reports whether the artifact can be written: it is a known artifact and the
+// directory is writable
+func (d *DataDir) Enabled(a Artifact) bool {
+	if _, ok := artifactNames[a]; !ok && a != ArtifactLog {
+		return false
+	}
+	return d.writable
+}
+
+// DisabledArtifacts lists the artifacts of used, the features the config turned on, that
+// cannot be written
+func (d *DataDir) DisabledArtifacts(used []Artifact) []Artifact {
+	var disabled []Artifact
+	for _, a := range used {
+		if !d.Enabled(a) {
+			disabled = append(disabled, a)
+		}
+	}
+	return disabled
+}
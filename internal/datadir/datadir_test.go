@@ -0,0 +1,101 @@
+package datadir
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProber returns a fixed error for every probe
+type fakeProber struct {
+	err    error
+	probed []string
+}
+
+func (f *fakeProber) Probe(dir string) error {
+	f.probed = append(f.probed, dir)
+	return f.err
+}
+
+func TestPrepare_DefaultsToLogDir(t *testing.T) {
+	prober := &fakeProber{}
+	d := Prepare("", "/opt/xhub-agent/logs/agent.log", prober)
+
+	assert.True(t, d.Writable())
+	assert.NoError(t, d.Err())
+	assert.Equal(t, "/opt/xhub-agent/logs", d.Root())
+	assert.Equal(t, []string{"/opt/xhub-agent/logs"}, prober.probed)
+	assert.Equal(t, "/opt/xhub-agent/logs/agent.log", d.Path(ArtifactLog))
+	assert.Empty(t, d.DisabledArtifacts([]Artifact{ArtifactState, ArtifactCrash}))
+}
+
+func TestDataDir_PathDerivation(t *testing.T) {
+	d := Prepare("/var/lib/xhub-agent", "/opt/xhub-agent/logs/agent.log", &fakeProber{})
+
+	tests := []struct {
+		artifact Artifact
+		expected string
+	}{
+		{ArtifactLog, "/var/lib/xhub-agent/agent.log"},
+		{ArtifactState, "/var/lib/xhub-agent/state.json"},
+		{ArtifactHistory, "/var/lib/xhub-agent/history"},
+		{ArtifactFailed, "/var/lib/xhub-agent/failed-payloads"},
+		{ArtifactQueue, "/var/lib/xhub-agent/report-queue"},
+		{ArtifactCrash, "/var/lib/xhub-agent/crash-dumps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.artifact), func(t *testing.T) {
+			assert.Equal(t, tt.expected, d.Path(tt.artifact))
+			assert.True(t, d.Enabled(tt.artifact))
+		})
+	}
+	assert.False(t, d.Enabled(Artifact("mirror")), "unknown artifacts are not written")
+}
+
+func TestPrepare_ReadOnly(t *testing.T) {
+	probeErr := &ProbeError{Dir: "/data", Reason: ReasonReadOnly, Err: syscall.EROFS}
+	d := Prepare("/data", "/data/agent.log", &fakeProber{err: probeErr})
+
+	assert.False(t, d.Writable())
+	assert.False(t, d.Enabled(ArtifactState))
+	assert.Equal(t, []Artifact{ArtifactState, ArtifactQueue}, d.DisabledArtifacts([]Artifact{ArtifactState, ArtifactQueue}))
+	assert.Contains(t, d.Err().Error(), "read-only filesystem")
+}
+
+func TestClassify(t *testing.T) {
+	readOnly := classify("/data", &os.PathError{Op: "open", Path: "/data/x", Err: syscall.EROFS})
+	var probeErr *ProbeError
+	require.True(t, errors.As(readOnly, &probeErr))
+	assert.Equal(t, ReasonReadOnly, probeErr.Reason)
+	assert.Contains(t, readOnly.Error(), "read-only filesystem")
+
+	denied := classify("/data", &os.PathError{Op: "open", Path: "/data/x", Err: syscall.EACCES})
+	require.True(t, errors.As(denied, &probeErr))
+	assert.Equal(t, ReasonPermission, probeErr.Reason)
+	assert.Contains(t, denied.Error(), "permission denied")
+	assert.True(t, errors.Is(denied, fs.ErrPermission))
+
+	other := classify("/data", errors.New("disk on fire"))
+	require.True(t, errors.As(other, &probeErr))
+	assert.Equal(t, ReasonOther, probeErr.Reason)
+	assert.Contains(t, other.Error(), "not writable")
+}
+
+func TestFSProber_Writable(t *testing.T) {
+	tmpDir := t.TempDir()
+	dir := filepath.Join(tmpDir, "nested", "data")
+
+	require.NoError(t, FSProber{}.Probe(dir))
+
+	// The directory is created and no probe file is left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,232 @@
+package diskio
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// Collector registry names and default intervals. IO rates are sampled every cycle; SMART
+// health changes slowly and needs a smartctl run.
+const (
+	CollectorName        = "disk_io"
+	DefaultInterval      = 0
+	SMARTCollectorName   = "smart"
+	DefaultSMARTInterval = time.Hour
+)
+
+// sectorSize is the unit of the /proc/diskstats sector counters, whatever the disk's sector size
+const sectorSize = 512
+
+// virtualDevices are the /proc/diskstats device prefixes that are not physical disks
+var virtualDevices = []string{"loop", "ram", "zram", "sr", "fd", "dm-", "md", "nbd"}
+
+// sample is the /proc/diskstats counters of a device at a point in time
+type sample struct {
+	at           time.Time
+	reads        uint64 // Completed reads
+	readSectors  uint64
+	readMs       uint64 // Time spent reading
+	writes       uint64 // Completed writes
+	writeSectors uint64
+	writeMs      uint64 // Time spent writing
+	ioMs         uint64 // Time the device had IOs in flight
+}
+
+// Collector reports the IO rates of the primary disk between consecutive collections
+type Collector struct {
+	procRoot string
+	sysRoot  string
+	device   string // Disk to sample, "" until detected
+	logger   *logger.Logger
+
+	previous *sample          // Last sample, rates are computed against it
+	warned   bool             // A missing device has been logged
+	now      func() time.Time // injectable for tests
+}
+
+// NewCollector creates a collector of the IO rates of device (e.g. "vda"), "" detecting the
+// disk holding the root filesystem
+func NewCollector(device string, logger *logger.Logger) *Collector {
+	return &Collector{procRoot: "/proc", sysRoot: "/sys", device: strings.TrimPrefix(device, "/dev/"), logger: logger, now: time.Now}
+}
+
+// Device returns the sampled disk, detecting it on first use ("" if none was found)
+func (c *Collector) Device() string {
+	if c.device == "" {
+		c.device = c.detectDevice()
+	}
+	return c.device
+}
+
+// Collect returns the IO rates since the previous collection. The first collection only
+// takes the baseline and returns nil, as does a missing device or a counter reset.
+func (c *Collector) Collect() *monitor.DiskIOStats {
+	device := c.Device()
+	current, err := c.read(device)
+	if err != nil {
+		if !c.warned {
+			c.logger.Debugf("Disk IO statistics unavailable: %v", err)
+			c.warned = true
+		}
+		c.previous = nil
+		return nil
+	}
+
+	previous := c.previous
+	c.previous = current
+	if previous == nil || !current.after(previous) {
+		return nil
+	}
+
+	elapsed := current.at.Sub(previous.at)
+	seconds := elapsed.Seconds()
+	reads := float64(current.reads - previous.reads)
+	writes := float64(current.writes - previous.writes)
+	stats := &monitor.DiskIOStats{
+		Device:           device,
+		ReadBytesPerSec:  float64(current.readSectors-previous.readSectors) * sectorSize / seconds,
+		WriteBytesPerSec: float64(current.writeSectors-previous.writeSectors) * sectorSize / seconds,
+		ReadIOPS:         reads / seconds,
+		WriteIOPS:        writes / seconds,
+		Utilization:      min(100, float64(current.ioMs-previous.ioMs)/float64(elapsed.Milliseconds())*100),
+		IntervalMs:       elapsed.Milliseconds(),
+	}
+	if ios := reads + writes; ios > 0 {
+		stats.AwaitMs = float64(current.readMs-previous.readMs+current.writeMs-previous.writeMs) / ios
+	}
+	return stats
+}
+
+// after reports whether s is a later sample of the same counters as previous: time went on
+// and no counter went back (device replaced or counters wrapped)
+func (s *sample) after(previous *sample) bool {
+	return s.at.Sub(previous.at) >= time.Millisecond &&
+		s.reads >= previous.reads && s.readSectors >= previous.readSectors && s.readMs >= previous.readMs &&
+		s.writes >= previous.writes && s.writeSectors >= previous.writeSectors && s.writeMs >= previous.writeMs &&
+		s.ioMs >= previous.ioMs
+}
+
+// read returns the current /proc/diskstats counters of device
+func (c *Collector) read(device string) (*sample, error) {
+	if device == "" {
+		return nil, fmt.Errorf("no disk found (set disk_io_device)")
+	}
+	file, err := os.Open(filepath.Join(c.procRoot, "diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	at := c.now()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 13 || fields[2] != device {
+			continue
+		}
+		var counters [11]uint64
+		for i := range counters {
+			if counters[i], err = strconv.ParseUint(fields[3+i], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid /proc/diskstats line for %s: %w", device, err)
+			}
+		}
+		return &sample{
+			at:           at,
+			reads:        counters[0],
+			readSectors:  counters[2],
+			readMs:       counters[3],
+			writes:       counters[4],
+			writeSectors: counters[6],
+			writeMs:      counters[7],
+			ioMs:         counters[9],
+		}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("disk %s not found in /proc/diskstats", device)
+}
+
+// detectDevice returns the disk holding the root filesystem, or else the first physical
+// disk of /proc/diskstats
+func (c *Collector) detectDevice() string {
+	if device := c.rootDevice(); device != "" {
+		return device
+	}
+
+	file, err := os.Open(filepath.Join(c.procRoot, "diskstats"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || isVirtual(fields[2]) {
+			continue
+		}
+		// Whole disks only, not their partitions
+		if _, err := os.Stat(filepath.Join(c.sysRoot, "block", fields[2])); err == nil {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+// rootDevice returns the disk of the filesystem mounted on / (through a partition or a
+// device-mapper volume), "" if it is not backed by a block device (overlay, container)
+func (c *Collector) rootDevice() string {
+	file, err := os.Open(filepath.Join(c.procRoot, "self", "mountinfo"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var majorMinor string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 22 1 252:1 / / rw,relatime shared:1 - ext4 /dev/vda1 rw
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 5 && fields[4] == "/" {
+			majorMinor = fields[2] // The last mount on / wins
+		}
+	}
+	if majorMinor == "" || strings.HasPrefix(majorMinor, "0:") {
+		return ""
+	}
+	return c.diskOf(filepath.Join(c.sysRoot, "dev", "block", majorMinor), 0)
+}
+
+// diskOf returns the disk of the block device at a /sys path, following partitions to their
+// disk and device-mapper volumes to their first underlying device
+func (c *Collector) diskOf(path string, depth int) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil || depth > 4 {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(resolved, "partition")); err == nil {
+		resolved = filepath.Dir(resolved)
+	}
+	if slaves, err := os.ReadDir(filepath.Join(resolved, "slaves")); err == nil && len(slaves) > 0 {
+		return c.diskOf(filepath.Join(resolved, "slaves", slaves[0].Name()), depth+1)
+	}
+	return filepath.Base(resolved)
+}
+
+// isVirtual reports whether a device name is a loop, RAM, optical or stacked device
+func isVirtual(device string) bool {
+	for _, prefix := range virtualDevices {
+		if strings.HasPrefix(device, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package diskio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/pkg/logger"
+)
+
+func createTestLogger(t *testing.T) *logger.Logger {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	return log
+}
+
+// writeFile creates a file under root, with its directories
+func writeFile(t *testing.T, root, name, content string) {
+	path := filepath.Join(root, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+// newTestCollector creates a collector of device reading a fake /proc and /sys under root,
+// with a clock advanced by tick on every sample
+func newTestCollector(t *testing.T, root, device string, tick time.Duration) *Collector {
+	c := NewCollector(device, createTestLogger(t))
+	c.procRoot = filepath.Join(root, "proc")
+	c.sysRoot = filepath.Join(root, "sys")
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time {
+		now = now.Add(tick)
+		return now
+	}
+	return c
+}
+
+func TestCollector_Collect(t *testing.T) {
+	root := t.TempDir()
+	c := newTestCollector(t, root, "/dev/vda", 10*time.Second)
+
+	// reads merged sectors ms writes merged sectors ms in_flight io_ms weighted
+	writeFile(t, root, "proc/diskstats",
+		" 252       0 vda 1000 0 20000 500 2000 0 40000 1500 0 3000 2000\n"+
+			" 252       1 vda1 900 0 18000 450 2000 0 40000 1500 0 2900 1950\n")
+	assert.Nil(t, c.Collect(), "the first collection only takes the baseline")
+
+	writeFile(t, root, "proc/diskstats",
+		" 252       0 vda 1100 0 22048 700 2400 0 60480 3300 0 5500 4000\n")
+	stats := c.Collect()
+	require.NotNil(t, stats)
+	assert.Equal(t, "vda", stats.Device)
+	assert.InDelta(t, 2048*512/10.0, stats.ReadBytesPerSec, 0.001)
+	assert.InDelta(t, 20480*512/10.0, stats.WriteBytesPerSec, 0.001)
+	assert.InDelta(t, 10.0, stats.ReadIOPS, 0.001)
+	assert.InDelta(t, 40.0, stats.WriteIOPS, 0.001)
+	assert.InDelta(t, 25.0, stats.Utilization, 0.001)
+	assert.InDelta(t, 2000.0/500, stats.AwaitMs, 0.001)
+	assert.Equal(t, int64(10000), stats.IntervalMs)
+
+	// Counters going back (device replaced) restart the baseline
+	writeFile(t, root, "proc/diskstats", " 252       0 vda 10 0 20 5 20 0 40 15 0 30 20\n")
+	assert.Nil(t, c.Collect())
+	writeFile(t, root, "proc/diskstats", " 252       0 vda 10 0 20 5 20 0 40 15 0 30 20\n")
+	stats = c.Collect()
+	require.NotNil(t, stats)
+	assert.Zero(t, stats.ReadIOPS)
+	assert.Zero(t, stats.AwaitMs, "no IO, no await")
+}
+
+func TestCollector_Collect_MissingDevice(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "proc/diskstats", " 252       0 vda 1 0 2 3 4 0 5 6 0 7 8\n")
+
+	c := newTestCollector(t, root, "sdz", time.Second)
+	assert.Nil(t, c.Collect())
+	assert.Nil(t, c.Collect())
+}
+
+func TestCollector_Device(t *testing.T) {
+	t.Run("partition of the root filesystem", func(t *testing.T) {
+		root := t.TempDir()
+		writeFile(t, root, "proc/self/mountinfo",
+			"22 1 252:1 / / rw,relatime shared:1 - ext4 /dev/vda1 rw\n"+
+				"23 22 0:21 / /proc rw - proc proc rw\n")
+		writeFile(t, root, "sys/devices/pci0000:00/virtio1/block/vda/vda1/partition", "1\n")
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "sys/dev/block"), 0755))
+		require.NoError(t, os.Symlink("../../devices/pci0000:00/virtio1/block/vda/vda1", filepath.Join(root, "sys/dev/block/252:1")))
+
+		assert.Equal(t, "vda", newTestCollector(t, root, "", time.Second).Device())
+	})
+
+	t.Run("LVM volume", func(t *testing.T) {
+		root := t.TempDir()
+		writeFile(t, root, "proc/self/mountinfo", "22 1 253:0 / / rw,relatime shared:1 - ext4 /dev/mapper/vg-root rw\n")
+		writeFile(t, root, "sys/devices/pci0000:00/nvme/nvme0/nvme0n1/nvme0n1p3/partition", "3\n")
+		writeFile(t, root, "sys/devices/virtual/block/dm-0/dm/name", "vg-root\n")
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "sys/devices/virtual/block/dm-0/slaves"), 0755))
+		require.NoError(t, os.Symlink("../../../../pci0000:00/nvme/nvme0/nvme0n1/nvme0n1p3", filepath.Join(root, "sys/devices/virtual/block/dm-0/slaves/nvme0n1p3")))
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "sys/dev/block"), 0755))
+		require.NoError(t, os.Symlink("../../devices/virtual/block/dm-0", filepath.Join(root, "sys/dev/block/253:0")))
+
+		assert.Equal(t, "nvme0n1", newTestCollector(t, root, "", time.Second).Device())
+	})
+
+	t.Run("overlay root falls back to the first disk", func(t *testing.T) {
+		root := t.TempDir()
+		writeFile(t, root, "proc/self/mountinfo", "500 400 0:52 / / rw,relatime - overlay overlay rw\n")
+		writeFile(t, root, "proc/diskstats",
+			"   7       0 loop0 1 0 2 3 4 0 5 6 0 7 8\n"+
+				"   8       1 sda1 1 0 2 3 4 0 5 6 0 7 8\n"+
+				"   8       0 sda 1 0 2 3 4 0 5 6 0 7 8\n")
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "sys/block/loop0"), 0755))
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "sys/block/sda"), 0755))
+
+		assert.Equal(t, "sda", newTestCollector(t, root, "", time.Second).Device())
+	})
+
+	t.Run("configured device", func(t *testing.T) {
+		assert.Equal(t, "nvme1n1", newTestCollector(t, t.TempDir(), "/dev/nvme1n1", time.Second).Device())
+	})
+
+	t.Run("no disk", func(t *testing.T) {
+		assert.Equal(t, "", newTestCollector(t, t.TempDir(), "", time.Second).Device())
+	})
+}
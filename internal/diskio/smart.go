@@ -0,0 +1,153 @@
+package diskio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// smartctlTimeout bounds a single smartctl invocation (it may spin up a sleeping disk)
+const smartctlTimeout = 20 * time.Second
+
+// SMARTSource returns the JSON report of smartctl for a device
+type SMARTSource interface {
+	Report(ctx context.Context, device string) ([]byte, error)
+}
+
+// smartctlSource runs smartctl (smartmontools 7 or later, for JSON output)
+type smartctlSource struct{}
+
+// Report runs smartctl with the health, identity and attribute sections
+func (smartctlSource) Report(ctx context.Context, device string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, "smartctl", "--json", "-H", "-i", "-A", "/dev/"+device).Output()
+	// smartctl sets bits of its exit status for disk problems while still printing the report
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(out) > 0 {
+		return out, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("smartctl /dev/%s: %w", device, err)
+	}
+	return out, nil
+}
+
+// SMARTCollector reports the basic SMART health of the disk sampled by a Collector
+type SMARTCollector struct {
+	source SMARTSource
+	device func() string
+	logger *logger.Logger
+
+	available func() bool // Whether smartctl is installed (injectable for tests)
+	warned    bool        // Unavailability has been logged
+}
+
+// NewSMARTCollector creates a collector of the SMART health of the disk of disk
+func NewSMARTCollector(disk *Collector, logger *logger.Logger) *SMARTCollector {
+	return &SMARTCollector{
+		source: smartctlSource{},
+		device: disk.Device,
+		logger: logger,
+		available: func() bool {
+			_, err := exec.LookPath("smartctl")
+			return err == nil
+		},
+	}
+}
+
+// Collect returns the SMART health of the disk. It returns nil when smartctl is not installed
+// or no disk was found; a disk without SMART data (most virtual disks) is reported as
+// unavailable with the reason.
+func (c *SMARTCollector) Collect() *monitor.DiskSMART {
+	device := c.device()
+	if device == "" || !c.available() {
+		if !c.warned {
+			c.logger.Debugf("smartctl not available or no disk found, skipping SMART health")
+			c.warned = true
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), smartctlTimeout)
+	defer cancel()
+	output, err := c.source.Report(ctx, device)
+	if err != nil {
+		return &monitor.DiskSMART{Device: device, Error: err.Error()}
+	}
+	smart, err := ParseSMART(device, output)
+	if err != nil {
+		return &monitor.DiskSMART{Device: device, Error: err.Error()}
+	}
+	return smart
+}
+
+// smartctlReport is the part of the smartctl JSON output that is reported
+type smartctlReport struct {
+	Smartctl struct {
+		Messages []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	ModelName   string `json:"model_name"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	NVMeHealth struct {
+		PercentageUsed int `json:"percentage_used"`
+	} `json:"nvme_smart_health_information_log"`
+	ATAAttributes struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+}
+
+// ataReallocatedSectors is the ATA SMART attribute counting remapped bad sectors
+const ataReallocatedSectors = 5
+
+// ParseSMART extracts the health of device from "smartctl --json -H -i -A" output
+func ParseSMART(device string, output []byte) (*monitor.DiskSMART, error) {
+	var report smartctlReport
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("invalid smartctl output: %w", err)
+	}
+
+	smart := &monitor.DiskSMART{Device: device, Model: report.ModelName}
+	if report.SmartStatus == nil {
+		smart.Error = "no SMART data"
+		for _, message := range report.Smartctl.Messages {
+			if message.Severity == "error" {
+				smart.Error = message.String
+				break
+			}
+		}
+		return smart, nil
+	}
+
+	smart.Available = true
+	smart.Passed = report.SmartStatus.Passed
+	smart.TemperatureCelsius = report.Temperature.Current
+	smart.PowerOnHours = report.PowerOnTime.Hours
+	smart.PercentageUsed = report.NVMeHealth.PercentageUsed
+	for _, attribute := range report.ATAAttributes.Table {
+		if attribute.ID == ataReallocatedSectors {
+			smart.ReallocatedSectors = attribute.Raw.Value
+		}
+	}
+	return smart, nil
+}
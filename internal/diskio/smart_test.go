@@ -0,0 +1,132 @@
+package diskio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+)
+
+// fakeSMARTSource returns canned smartctl output
+type fakeSMARTSource struct {
+	output  string
+	err     error
+	devices []string
+}
+
+func (f *fakeSMARTSource) Report(ctx context.Context, device string) ([]byte, error) {
+	f.devices = append(f.devices, device)
+	return []byte(f.output), f.err
+}
+
+const ataSMARTOutput = `{
+  "smartctl": {"version": [7, 3], "exit_status": 0},
+  "model_name": "Samsung SSD 870 EVO 1TB",
+  "smart_status": {"passed": true},
+  "ata_smart_attributes": {"table": [
+    {"id": 5, "name": "Reallocated_Sector_Ct", "raw": {"value": 8, "string": "8"}},
+    {"id": 9, "name": "Power_On_Hours", "raw": {"value": 12000, "string": "12000"}}
+  ]},
+  "power_on_time": {"hours": 12000},
+  "temperature": {"current": 34}
+}`
+
+const nvmeSMARTOutput = `{
+  "smartctl": {"version": [7, 3], "exit_status": 8},
+  "model_name": "SAMSUNG MZVL2512HCJQ",
+  "smart_status": {"passed": false, "nvme": {"value": 4}},
+  "nvme_smart_health_information_log": {"percentage_used": 97, "temperature": 51},
+  "power_on_time": {"hours": 30000},
+  "temperature": {"current": 51}
+}`
+
+const virtualDiskOutput = `{
+  "smartctl": {"version": [7, 3], "exit_status": 4, "messages": [
+    {"string": "Read Device Identity failed: Unsupported command", "severity": "information"},
+    {"string": "/dev/vda: Unable to detect device type", "severity": "error"}
+  ]}
+}`
+
+func TestParseSMART(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   monitor.DiskSMART
+	}{
+		{
+			name:   "ATA",
+			output: ataSMARTOutput,
+			want:   monitor.DiskSMART{Device: "sda", Available: true, Passed: true, Model: "Samsung SSD 870 EVO 1TB", TemperatureCelsius: 34, PowerOnHours: 12000, ReallocatedSectors: 8},
+		},
+		{
+			name:   "failing NVMe",
+			output: nvmeSMARTOutput,
+			want:   monitor.DiskSMART{Device: "sda", Available: true, Passed: false, Model: "SAMSUNG MZVL2512HCJQ", TemperatureCelsius: 51, PowerOnHours: 30000, PercentageUsed: 97},
+		},
+		{
+			name:   "virtual disk",
+			output: virtualDiskOutput,
+			want:   monitor.DiskSMART{Device: "sda", Error: "/dev/vda: Unable to detect device type"},
+		},
+		{
+			name:   "no message",
+			output: `{"smartctl": {"exit_status": 2}}`,
+			want:   monitor.DiskSMART{Device: "sda", Error: "no SMART data"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smart, err := ParseSMART("sda", []byte(tt.output))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *smart)
+		})
+	}
+
+	_, err := ParseSMART("sda", []byte("smartctl 6.6"))
+	assert.ErrorContains(t, err, "invalid smartctl output")
+}
+
+func TestSMARTCollector_Collect(t *testing.T) {
+	root := t.TempDir()
+	disk := newTestCollector(t, root, "nvme0n1", 0)
+	source := &fakeSMARTSource{output: nvmeSMARTOutput}
+	c := NewSMARTCollector(disk, createTestLogger(t))
+	c.source = source
+	c.available = func() bool { return true }
+
+	smart := c.Collect()
+	require.NotNil(t, smart)
+	assert.True(t, smart.Available)
+	assert.False(t, smart.Passed)
+	assert.Equal(t, []string{"nvme0n1"}, source.devices)
+
+	source.err = errors.New("smartctl /dev/nvme0n1: signal: killed")
+	smart = c.Collect()
+	require.NotNil(t, smart)
+	assert.False(t, smart.Available)
+	assert.Equal(t, "smartctl /dev/nvme0n1: signal: killed", smart.Error)
+
+	source.output, source.err = "not json", nil
+	smart = c.Collect()
+	require.NotNil(t, smart)
+	assert.Contains(t, smart.Error, "invalid smartctl output")
+}
+
+func TestSMARTCollector_Collect_Unavailable(t *testing.T) {
+	source := &fakeSMARTSource{output: ataSMARTOutput}
+
+	c := NewSMARTCollector(newTestCollector(t, t.TempDir(), "sda", 0), createTestLogger(t))
+	c.source = source
+	c.available = func() bool { return false }
+	assert.Nil(t, c.Collect(), "smartctl not installed")
+
+	c = NewSMARTCollector(newTestCollector(t, t.TempDir(), "", 0), createTestLogger(t))
+	c.source = source
+	c.available = func() bool { return true }
+	assert.Nil(t, c.Collect(), "no disk")
+	assert.Empty(t, source.devices)
+}
@@ -0,0 +1,204 @@
+package dnscheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// Collector registry name and default interval
+const (
+	CollectorName   = "dns_check"
+	DefaultInterval = 10 * time.Minute
+)
+
+// DefaultExternalResolver is the public resolver compared against the system resolver
+const DefaultExternalResolver = "1.1.1.1"
+
+// lookupTimeout bounds the lookups of one domain per resolver
+const lookupTimeout = 5 * time.Second
+
+// Resolver looks up the addresses of a host
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewExternalResolver returns a resolver querying server (host or host:port, port 53 by default)
+// directly instead of the system configuration
+func NewExternalResolver(server string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// state the health of one domain, used to log transitions only
+type state int
+
+const (
+	stateHealthy state = iota
+	stateUnresolvable
+	stateElsewhere    // Resolves, but not to this node
+	stateDisagreement // Resolvers return different addresses
+)
+
+// Checker resolves the domains users connect to with the system and an external resolver
+// and compares the result with the node's public IPs
+type Checker struct {
+	domains  []string
+	system   Resolver
+	external Resolver // nil compares nothing
+	logger   *logger.Logger
+
+	states map[string]state // Last state per domain
+}
+
+// NewChecker creates a checker for domains. external may be nil to use only the system resolver.
+func NewChecker(domains []string, system, external Resolver, logger *logger.Logger) *Checker {
+	return &Checker{
+		domains:  domains,
+		system:   system,
+		external: external,
+		logger:   logger,
+		states:   make(map[string]state),
+	}
+}
+
+// DefaultDomains returns the domains checked when none are configured: resolvedDomain and the
+// Hysteria2 server address, skipping IP literals and duplicates
+func DefaultDomains(candidates ...string) []string {
+	var domains []string
+	for _, candidate := range candidates {
+		host := strings.TrimSpace(candidate)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" || net.ParseIP(host) != nil || slices.Contains(domains, host) {
+			continue
+		}
+		domains = append(domains, host)
+	}
+	return domains
+}
+
+// Check resolves every domain and reports whether it points at one of publicIPs.
+// The addresses users see are those of the external resolver when it answers.
+func (c *Checker) Check(publicIPs []string) []monitor.DNSCheck {
+	results := make([]monitor.DNSCheck, 0, len(c.domains))
+	for _, domain := range c.domains {
+		result := c.checkDomain(domain, publicIPs)
+		c.logTransition(result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkDomain resolves one domain with both resolvers
+func (c *Checker) checkDomain(domain string, publicIPs []string) monitor.DNSCheck {
+	result := monitor.DNSCheck{Domain: domain}
+
+	systemIPs, systemErr := lookup(c.system, domain)
+	seen, seenErr := systemIPs, systemErr
+	if c.external != nil {
+		externalIPs, externalErr := lookup(c.external, domain)
+		result.ResolverDisagreement = (systemErr == nil) != (externalErr == nil) ||
+			(systemErr == nil && !slices.Equal(systemIPs, externalIPs))
+		if externalErr == nil {
+			seen, seenErr = externalIPs, nil
+		}
+		if systemErr != nil && externalErr != nil {
+			seenErr = fmt.Errorf("system: %v; external: %v", systemErr, externalErr)
+		}
+	}
+
+	if seenErr != nil {
+		result.Error = seenErr.Error()
+		return result
+	}
+	result.Resolves = true
+	result.ResolvedIPs = seen
+	for _, ip := range seen {
+		if containsIP(publicIPs, ip) {
+			result.PointsHere = true
+			break
+		}
+	}
+	return result
+}
+
+// logTransition logs a warning when a domain enters a broken state and a note on recovery
+func (c *Checker) logTransition(result monitor.DNSCheck) {
+	current := stateHealthy
+	switch {
+	case !result.Resolves:
+		current = stateUnresolvable
+	case !result.PointsHere:
+		current = stateElsewhere
+	case result.ResolverDisagreement:
+		current = stateDisagreement
+	}
+
+	previous, known := c.states[result.Domain]
+	c.states[result.Domain] = current
+	if (known && previous == current) || (!known && current == stateHealthy) {
+		return
+	}
+
+	switch current {
+	case stateHealthy:
+		c.logger.Infof("✅ DNS check: %s resolves to this node again", result.Domain)
+	case stateUnresolvable:
+		c.logger.Warnf("⚠️  DNS check: %s does not resolve: %s", result.Domain, result.Error)
+	case stateElsewhere:
+		c.logger.Warnf("⚠️  DNS check: %s resolves to %v, not to this node", result.Domain, result.ResolvedIPs)
+	case stateDisagreement:
+		c.logger.Warnf("⚠️  DNS check: system and external resolvers disagree on %s", result.Domain)
+	}
+}
+
+// lookup resolves host and returns its sorted, deduplicated addresses
+func lookup(resolver Resolver, host string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+
+	var ips []string
+	for _, addr := range addrs {
+		if ip := addr.IP.String(); !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Strings(ips)
+	return ips, nil
+}
+
+// containsIP reports whether ips contains ip (compared as addresses, not strings)
+func containsIP(ips []string, ip string) bool {
+	parsed := net.ParseIP(ip)
+	for _, candidate := range ips {
+		if other := net.ParseIP(strings.TrimSpace(candidate)); other != nil && other.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
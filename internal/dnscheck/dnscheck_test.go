@@ -0,0 +1,184 @@
+package dnscheck
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// stubResolver answers from a host -> addresses table; unknown hosts are NXDOMAIN
+type stubResolver struct {
+	hosts   map[string][]string
+	lookups int
+}
+
+func (r *stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.lookups++
+	ips, ok := r.hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	var addrs []net.IPAddr
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+var nodeIPs = []string{"203.0.113.10", "2001:db8::10"}
+
+func newTestLogger(t *testing.T) (*logger.Logger, string) {
+	t.Helper()
+	logPath := filepath.Join(t.TempDir(), "test.log")
+	log, err := logger.NewLogger(logPath, "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+	return log, logPath
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestChecker_Results(t *testing.T) {
+	system := &stubResolver{hosts: map[string][]string{
+		"ok.example.com":    {"203.0.113.10", "203.0.113.10"},
+		"moved.example.com": {"198.51.100.7"},
+		"split.example.com": {"10.0.0.5"},
+		"v6.example.com":    {"2001:db8:0:0:0:0:0:10"},
+	}}
+	external := &stubResolver{hosts: map[string][]string{
+		"ok.example.com":    {"203.0.113.10"},
+		"moved.example.com": {"198.51.100.7"},
+		"split.example.com": {"203.0.113.10"},
+		"v6.example.com":    {"2001:db8::10"},
+	}}
+	log, _ := newTestLogger(t)
+	domains := []string{"ok.example.com", "moved.example.com", "gone.example.com", "split.example.com", "v6.example.com"}
+	results := NewChecker(domains, system, external, log).Check(nodeIPs)
+	require.Len(t, results, 5)
+
+	// Matching
+	assert.Equal(t, monitor.DNSCheck{
+		Domain: "ok.example.com", Resolves: true, PointsHere: true, ResolvedIPs: []string{"203.0.113.10"},
+	}, results[0])
+
+	// Resolves elsewhere
+	assert.True(t, results[1].Resolves)
+	assert.False(t, results[1].PointsHere)
+	assert.False(t, results[1].ResolverDisagreement)
+	assert.Equal(t, []string{"198.51.100.7"}, results[1].ResolvedIPs)
+
+	// NXDOMAIN: a failure, not a disagreement
+	assert.False(t, results[2].Resolves)
+	assert.False(t, results[2].ResolverDisagreement)
+	assert.Contains(t, results[2].Error, "no such host")
+
+	// Split horizon: users see the external answer, the disagreement is flagged
+	assert.True(t, results[3].Resolves)
+	assert.True(t, results[3].PointsHere)
+	assert.True(t, results[3].ResolverDisagreement)
+	assert.Equal(t, []string{"203.0.113.10"}, results[3].ResolvedIPs)
+
+	// Addresses are compared as IPs, not strings
+	assert.True(t, results[4].PointsHere)
+}
+
+func TestChecker_SystemOnly(t *testing.T) {
+	system := &stubResolver{hosts: map[string][]string{"ok.example.com": {"203.0.113.10"}}}
+	log, _ := newTestLogger(t)
+
+	results := NewChecker([]string{"ok.example.com", "gone.example.com"}, system, nil, log).Check(nodeIPs)
+	assert.True(t, results[0].PointsHere)
+	assert.False(t, results[1].Resolves)
+	assert.NotEmpty(t, results[1].Error)
+
+	// Configured public IPs decide what "here" is
+	results = NewChecker([]string{"ok.example.com"}, system, nil, log).Check([]string{"198.51.100.1"})
+	assert.False(t, results[0].PointsHere)
+}
+
+func TestChecker_ExternalOnlyFailure(t *testing.T) {
+	system := &stubResolver{hosts: map[string][]string{"internal.example.com": {"203.0.113.10"}}}
+	external := &stubResolver{hosts: map[string][]string{}}
+	log, _ := newTestLogger(t)
+
+	results := NewChecker([]string{"internal.example.com"}, system, external, log).Check(nodeIPs)
+	assert.True(t, results[0].Resolves, "the system answer is used when the external resolver fails")
+	assert.True(t, results[0].ResolverDisagreement)
+}
+
+func TestChecker_LogsTransitionsOnly(t *testing.T) {
+	system := &stubResolver{hosts: map[string][]string{"node.example.com": {"203.0.113.10"}}}
+	log, logPath := newTestLogger(t)
+	checker := NewChecker([]string{"node.example.com"}, system, nil, log)
+
+	checker.Check(nodeIPs)
+	assert.NotContains(t, readLog(t, logPath), "DNS check", "healthy at startup is not logged")
+
+	delete(system.hosts, "node.example.com")
+	checker.Check(nodeIPs)
+	checker.Check(nodeIPs)
+	assert.Equal(t, 1, strings.Count(readLog(t, logPath), "does not resolve"))
+
+	system.hosts["node.example.com"] = []string{"198.51.100.7"}
+	checker.Check(nodeIPs)
+	checker.Check(nodeIPs)
+	assert.Equal(t, 1, strings.Count(readLog(t, logPath), "not to this node"))
+
+	system.hosts["node.example.com"] = []string{"203.0.113.10"}
+	checker.Check(nodeIPs)
+	checker.Check(nodeIPs)
+	assert.Equal(t, 1, strings.Count(readLog(t, logPath), "resolves to this node again"))
+}
+
+func TestChecker_IntervalGating(t *testing.T) {
+	system := &stubResolver{hosts: map[string][]string{"node.example.com": {"203.0.113.10"}}}
+	log, _ := newTestLogger(t)
+	checker := NewChecker([]string{"node.example.com"}, system, nil, log)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	registry := collector.NewRegistry(nil)
+	registry.SetClockForTesting(func() time.Time { return now })
+	registry.Register(collector.Collector{
+		Name:     CollectorName,
+		Interval: DefaultInterval,
+		Collect: func() collector.Apply {
+			checks := checker.Check(nodeIPs)
+			return func(data *monitor.ServerStatusData) { data.DNSChecks = checks }
+		},
+	})
+
+	for _, step := range []time.Duration{0, time.Minute, 8 * time.Minute} {
+		now = now.Add(step)
+		data := &monitor.ServerStatusData{}
+		registry.Apply(data)
+		require.Len(t, data.DNSChecks, 1, "cached result is reported between checks")
+	}
+	assert.Equal(t, 1, system.lookups)
+
+	now = now.Add(time.Minute)
+	registry.Apply(&monitor.ServerStatusData{})
+	assert.Equal(t, 2, system.lookups)
+}
+
+func TestDefaultDomains(t *testing.T) {
+	assert.Equal(t, []string{"node.example.com", "hy2.example.com"},
+		DefaultDomains("node.example.com", "hy2.example.com:443"))
+	assert.Equal(t, []string{"node.example.com"}, DefaultDomains("node.example.com", "node.example.com"))
+	assert.Empty(t, DefaultDomains("", "203.0.113.10"))
+}
@@ -0,0 +1,206 @@
+package errstats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/auth"
+)
+
+// ErrorCategory is the fixed error taxonomy reported to xhub.
+// Values match the reportpb.ErrorCategory enum.
+type ErrorCategory int32
+
+const (
+	Unknown           ErrorCategory = 0  // Error could not be mapped to a category
+	PanelTimeout      ErrorCategory = 1  // 3x-ui request timed out
+	PanelAuth         ErrorCategory = 2  // 3x-ui session rejected or login failed
+	PanelDecode       ErrorCategory = 3  // 3x-ui response could not be decoded
+	PanelHTTP         ErrorCategory = 4  // 3x-ui returned a non-200 status
+	GRPCUnavailable   ErrorCategory = 5  // xhub unreachable
+	GRPCAuth          ErrorCategory = 6  // xhub rejected the API key
+	GRPCDeadline      ErrorCategory = 7  // xhub request timed out
+	GRPCOther         ErrorCategory = 8  // Other gRPC status errors
+	SubscriptionFetch ErrorCategory = 9  // Subscription content could not be fetched
+	InternalPanic     ErrorCategory = 10 // Recovered panic in the agent loop
+	SelfTest          ErrorCategory = 11 // Scheduled or on-demand self-test failed
+	PanelUnreachable  ErrorCategory = 12 // 3x-ui refused the connection or could not be resolved
+	ReportRejected    ErrorCategory = 13 // xhub answered a report with success false
+)
+
+var categoryNames = map[ErrorCategory]string{
+	Unknown:           "unknown",
+	PanelTimeout:      "panel_timeout",
+	PanelAuth:         "panel_auth",
+	PanelDecode:       "panel_decode",
+	PanelHTTP:         "panel_http",
+	GRPCUnavailable:   "grpc_unavailable",
+	GRPCAuth:          "grpc_auth",
+	GRPCDeadline:      "grpc_deadline",
+	GRPCOther:         "grpc_other",
+	SubscriptionFetch: "subscription_fetch",
+	InternalPanic:     "internal_panic",
+	SelfTest:          "selftest",
+	PanelUnreachable:  "panel_unreachable",
+	ReportRejected:    "report_rejected",
+}
+
+// Categories returns all categories in enum order
+func Categories() []ErrorCategory {
+	var result []ErrorCategory
+	for c := range categoryNames {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// String returns the category name, e.g. "panel_timeout"
+func (c ErrorCategory) String() string {
+	if name, ok := categoryNames[c]; ok {
+		return name
+	}
+	return categoryNames[Unknown]
+}
+
+// categorizedError carries an explicit category chosen by the failing code path
+type categorizedError struct {
+	category ErrorCategory
+	err      error
+}
+
+func (e *categorizedError) Error() string { return e.err.Error() }
+func (e *categorizedError) Unwrap() error { return e.err }
+
+// Wrap tags err with a category, used where the path knows better than the error type
+// (e.g. subscription fetches). A nil err stays nil.
+func Wrap(category ErrorCategory, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &categorizedError{category: category, err: err}
+}
+
+// Categorize maps an error to its category
+func Categorize(err error) ErrorCategory {
+	if err == nil {
+		return Unknown
+	}
+
+	var tagged *categorizedError
+	if errors.As(err, &tagged) {
+		return tagged.category
+	}
+
+	// gRPC status errors (report client)
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		switch grpcErr.GRPCStatus().Code() {
+		case codes.Unavailable:
+			return GRPCUnavailable
+		case codes.Unauthenticated, codes.PermissionDenied:
+			return GRPCAuth
+		case codes.DeadlineExceeded:
+			return GRPCDeadline
+		default:
+			return GRPCOther
+		}
+	}
+
+	// 3x-ui panel errors
+	if errors.Is(err, auth.ErrAuthExpired) {
+		return PanelAuth
+	}
+	var loginErr *auth.LoginError
+	if errors.As(err, &loginErr) {
+		return PanelAuth
+	}
+	var statusErr *auth.StatusError
+	if errors.As(err, &statusErr) {
+		return PanelHTTP
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return PanelDecode
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return PanelTimeout
+	}
+	if errors.Is(err, auth.ErrPanelUnreachable) {
+		return PanelUnreachable
+	}
+
+	return Unknown
+}
+
+// Counters accumulates error counts per category until they are acknowledged by xhub
+type Counters struct {
+	counts map[ErrorCategory]uint64
+	mutex  sync.Mutex
+}
+
+// NewCounters creates empty counters
+func NewCounters() *Counters {
+	return &Counters{counts: make(map[ErrorCategory]uint64)}
+}
+
+// Record categorizes err, counts it and returns the category
+func (c *Counters) Record(err error) ErrorCategory {
+	category := Categorize(err)
+	c.RecordCategory(category)
+	return category
+}
+
+// RecordCategory counts one error of the given category
+func (c *Counters) RecordCategory(category ErrorCategory) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.counts[category]++
+}
+
+// Pending returns the counts accumulated since the last acknowledged send
+func (c *Counters) Pending() map[ErrorCategory]uint64 {
+	if c == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result := make(map[ErrorCategory]uint64, len(c.counts))
+	for category, count := range c.counts {
+		result[category] = count
+	}
+	return result
+}
+
+// Ack subtracts counts that xhub acknowledged, keeping anything recorded since.
+// Unacknowledged counts simply carry over to the next send.
+func (c *Counters) Ack(sent map[ErrorCategory]uint64) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for category, count := range sent {
+		if c.counts[category] <= count {
+			delete(c.counts, category)
+			continue
+		}
+		c.counts[category] -= count
+	}
+}
@@ -0,0 +1,108 @@
+package errstats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/auth"
+)
+
+// timeoutError is a net.Error reporting a timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestCategorize(t *testing.T) {
+	var syntaxErr error = &json.SyntaxError{}
+	var typeErr error = &json.UnmarshalTypeError{Value: "string", Field: "cpu"}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorCategory
+	}{
+		{"nil", nil, Unknown},
+		{"plain error", errors.New("something odd"), Unknown},
+		{"panel unauthorized", fmt.Errorf("failed to get default settings: %w", auth.ErrAuthExpired), PanelAuth},
+		{"panel login refused", fmt.Errorf("login failed: %w", &auth.LoginError{Message: "wrong password"}), PanelAuth},
+		{"panel http status", fmt.Errorf("failed to get inbound list: %w", &auth.StatusError{StatusCode: 502}), PanelHTTP},
+		{"panel json syntax", fmt.Errorf("failed to parse response: %w", syntaxErr), PanelDecode},
+		{"panel json type", fmt.Errorf("failed to parse response: %w", typeErr), PanelDecode},
+		{"panel client timeout", fmt.Errorf("failed to request server status: %w",
+			&url.Error{Op: "Post", URL: "https://127.0.0.1/server/status", Err: timeoutError{}}), PanelTimeout},
+		{"context deadline", fmt.Errorf("request: %w", context.DeadlineExceeded), PanelTimeout},
+		{"panel unreachable", fmt.Errorf("failed to request server status: %w",
+			auth.Unreachable(&url.Error{Op: "Post", URL: "https://127.0.0.1/server/status", Err: errors.New("connection refused")})), PanelUnreachable},
+		{"unreachable panel timeout", auth.Unreachable(&url.Error{Op: "Post", URL: "https://127.0.0.1/login", Err: timeoutError{}}), PanelTimeout},
+		{"explicit report rejected", Wrap(ReportRejected, errors.New("report failed: unknown node")), ReportRejected},
+		{"grpc unavailable", status.Error(codes.Unavailable, "connection refused"), GRPCUnavailable},
+		{"grpc unauthenticated", status.Error(codes.Unauthenticated, "bad key"), GRPCAuth},
+		{"grpc permission denied", status.Error(codes.PermissionDenied, "bad key"), GRPCAuth},
+		{"grpc deadline", fmt.Errorf("gRPC request failed: %w", status.Error(codes.DeadlineExceeded, "slow")), GRPCDeadline},
+		{"grpc other", status.Error(codes.InvalidArgument, "bad data"), GRPCOther},
+		{"explicit subscription fetch", Wrap(SubscriptionFetch, errors.New("HTTP status code: 404")), SubscriptionFetch},
+		{"explicit wins over type", Wrap(SubscriptionFetch, auth.ErrAuthExpired), SubscriptionFetch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Categorize(tt.err))
+		})
+	}
+
+	assert.Nil(t, Wrap(PanelAuth, nil))
+}
+
+func TestErrorCategory_String(t *testing.T) {
+	assert.Equal(t, "panel_timeout", PanelTimeout.String())
+	assert.Equal(t, "internal_panic", InternalPanic.String())
+	assert.Equal(t, "unknown", ErrorCategory(99).String())
+	assert.Len(t, Categories(), 14)
+	assert.Equal(t, Unknown, Categories()[0])
+}
+
+func TestCounters_AccumulateAndAck(t *testing.T) {
+	counters := NewCounters()
+	counters.Record(auth.ErrAuthExpired)
+	counters.Record(auth.ErrAuthExpired)
+	counters.Record(errors.New("mystery"))
+	counters.RecordCategory(InternalPanic)
+
+	sent := counters.Pending()
+	assert.Equal(t, map[ErrorCategory]uint64{PanelAuth: 2, Unknown: 1, InternalPanic: 1}, sent)
+
+	// Errors recorded while the report is in flight survive the ack
+	counters.Record(auth.ErrAuthExpired)
+	counters.Ack(sent)
+	assert.Equal(t, map[ErrorCategory]uint64{PanelAuth: 1}, counters.Pending())
+
+	counters.Ack(counters.Pending())
+	assert.Empty(t, counters.Pending())
+}
+
+func TestCounters_CarryOverWithoutAck(t *testing.T) {
+	counters := NewCounters()
+	counters.RecordCategory(GRPCUnavailable)
+
+	// Failed send: nothing acknowledged, counts carry over and keep growing
+	_ = counters.Pending()
+	counters.RecordCategory(GRPCUnavailable)
+	assert.Equal(t, map[ErrorCategory]uint64{GRPCUnavailable: 2}, counters.Pending())
+}
+
+func TestCounters_Nil(t *testing.T) {
+	var counters *Counters
+	counters.RecordCategory(PanelAuth)
+	counters.Ack(map[ErrorCategory]uint64{PanelAuth: 1})
+	assert.Nil(t, counters.Pending())
+}
@@ -0,0 +1,158 @@
+package fail2ban
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"xhub-agent/pkg/logger"
+)
+
+// DefaultSocketPath is the fail2ban server socket of the distribution packages
+const DefaultSocketPath = "/var/run/fail2ban/fail2ban.sock"
+
+// Collector registry name and default interval (ban counts change slowly)
+const (
+	CollectorName   = "fail2ban"
+	DefaultInterval = 60 * time.Second
+)
+
+// commandTimeout bounds a single fail2ban-client invocation
+const commandTimeout = 5 * time.Second
+
+// StatusSource returns the output of "fail2ban-client status [jail]"
+type StatusSource interface {
+	Status(ctx context.Context, jail string) (string, error)
+}
+
+// clientSource queries the fail2ban server through fail2ban-client and its socket
+type clientSource struct {
+	socketPath string
+}
+
+// Status runs fail2ban-client against the configured socket
+func (s clientSource) Status(ctx context.Context, jail string) (string, error) {
+	args := []string{"-s", s.socketPath, "status"}
+	if jail != "" {
+		args = append(args, jail)
+	}
+	out, err := exec.CommandContext(ctx, "fail2ban-client", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("fail2ban-client %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+// Collector reports the number of currently banned IPs per fail2ban jail
+type Collector struct {
+	source     StatusSource
+	socketPath string
+	logger     *logger.Logger
+
+	available func() bool // Whether fail2ban is installed and running (injectable for tests)
+	warned    bool        // Unavailability has been logged
+}
+
+// NewCollector creates a collector using fail2ban-client with the given socket path
+func NewCollector(socketPath string, logger *logger.Logger) *Collector {
+	if socketPath == "" {
+		socketPath = DefaultSocketPath
+	}
+	c := &Collector{
+		source:     clientSource{socketPath: socketPath},
+		socketPath: socketPath,
+		logger:     logger,
+	}
+	c.available = c.installed
+	return c
+}
+
+// installed checks that fail2ban-client exists and the server socket is present
+func (c *Collector) installed() bool {
+	if _, err := exec.LookPath("fail2ban-client"); err != nil {
+		return false
+	}
+	_, err := os.Stat(c.socketPath)
+	return err == nil
+}
+
+// Collect returns the currently banned IP count per jail.
+// It returns nil when fail2ban is not installed or not reachable.
+func (c *Collector) Collect() map[string]int {
+	if !c.available() {
+		if !c.warned {
+			c.logger.Debugf("fail2ban not available (socket %s), skipping ban statistics", c.socketPath)
+			c.warned = true
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	status, err := c.source.Status(ctx, "")
+	if err != nil {
+		c.logger.Debugf("Failed to query fail2ban status: %v", err)
+		return nil
+	}
+
+	bans := make(map[string]int)
+	for _, jail := range ParseJailList(status) {
+		jailStatus, err := c.source.Status(ctx, jail)
+		if err != nil {
+			c.logger.Debugf("Failed to query fail2ban jail %s: %v", jail, err)
+			continue
+		}
+		count, err := ParseCurrentlyBanned(jailStatus)
+		if err != nil {
+			c.logger.Debugf("Failed to parse fail2ban jail %s: %v", jail, err)
+			continue
+		}
+		bans[jail] = count
+	}
+	return bans
+}
+
+// ParseJailList extracts the jail names from "fail2ban-client status" output
+func ParseJailList(output string) []string {
+	value, ok := statusField(output, "Jail list")
+	if !ok {
+		return nil
+	}
+
+	var jails []string
+	for _, jail := range strings.Split(value, ",") {
+		if jail = strings.TrimSpace(jail); jail != "" {
+			jails = append(jails, jail)
+		}
+	}
+	return jails
+}
+
+// ParseCurrentlyBanned extracts the banned IP count from "fail2ban-client status <jail>" output
+func ParseCurrentlyBanned(output string) (int, error) {
+	value, ok := statusField(output, "Currently banned")
+	if !ok {
+		return 0, fmt.Errorf("missing \"Currently banned\" field")
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid banned count %q", value)
+	}
+	return count, nil
+}
+
+// statusField returns the value of a "|- Name:\tvalue" line of fail2ban-client output
+func statusField(output, name string) (string, bool) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimLeft(line, " |`-")
+		if value, ok := strings.CutPrefix(line, name+":"); ok {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
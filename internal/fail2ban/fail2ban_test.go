@@ -0,0 +1,115 @@
+package fail2ban
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/pkg/logger"
+)
+
+const statusOutput = "Status\n" +
+	"|- Number of jail:\t3\n" +
+	"`- Jail list:\tsshd, nginx-http-auth, recidive\n"
+
+const sshdOutput = "Status for the jail: sshd\n" +
+	"|- Filter\n" +
+	"|  |- Currently failed:\t2\n" +
+	"|  |- Total failed:\t57\n" +
+	"|  `- File list:\t/var/log/auth.log\n" +
+	"`- Actions\n" +
+	"   |- Currently banned:\t3\n" +
+	"   |- Total banned:\t12\n" +
+	"   `- Banned IP list:\t192.0.2.1 192.0.2.2 192.0.2.3\n"
+
+const nginxOutput = "Status for the jail: nginx-http-auth\n" +
+	"|- Filter\n" +
+	"|  `- Currently failed:\t0\n" +
+	"`- Actions\n" +
+	"   |- Currently banned:\t0\n" +
+	"   `- Banned IP list:\t\n"
+
+// stubSource returns canned fail2ban-client output per jail
+type stubSource map[string]string
+
+func (s stubSource) Status(ctx context.Context, jail string) (string, error) {
+	out, ok := s[jail]
+	if !ok {
+		return "", errors.New("exit status 255")
+	}
+	return out, nil
+}
+
+func createTestLogger(t *testing.T) *logger.Logger {
+	tmpDir := t.TempDir()
+	testLogger, err := logger.NewLogger(filepath.Join(tmpDir, "test.log"), "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { testLogger.Close() })
+	return testLogger
+}
+
+func TestParseJailList(t *testing.T) {
+	assert.Equal(t, []string{"sshd", "nginx-http-auth", "recidive"}, ParseJailList(statusOutput))
+	assert.Nil(t, ParseJailList("Status\n|- Number of jail:\t0\n`- Jail list:\t\n"))
+	assert.Nil(t, ParseJailList("garbage"))
+}
+
+func TestParseCurrentlyBanned(t *testing.T) {
+	count, err := ParseCurrentlyBanned(sshdOutput)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = ParseCurrentlyBanned(nginxOutput)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = ParseCurrentlyBanned("Status for the jail: sshd\n")
+	assert.Error(t, err)
+	_, err = ParseCurrentlyBanned("   |- Currently banned:\tmany\n")
+	assert.Error(t, err)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	c := NewCollector("", createTestLogger(t))
+	c.available = func() bool { return true }
+	c.source = stubSource{
+		"":                statusOutput,
+		"sshd":            sshdOutput,
+		"nginx-http-auth": nginxOutput,
+		// recidive fails and is skipped
+	}
+
+	bans := c.Collect()
+	assert.Equal(t, map[string]int{"sshd": 3, "nginx-http-auth": 0}, bans)
+}
+
+func TestCollector_NotInstalled(t *testing.T) {
+	// A socket path that does not exist degrades to no statistics
+	c := NewCollector(filepath.Join(t.TempDir(), "missing.sock"), createTestLogger(t))
+	assert.Nil(t, c.Collect())
+	assert.Nil(t, c.Collect())
+	assert.True(t, c.warned)
+}
+
+func TestCollector_StatusFailure(t *testing.T) {
+	c := NewCollector("", createTestLogger(t))
+	c.available = func() bool { return true }
+	c.source = stubSource{}
+
+	assert.Nil(t, c.Collect())
+}
+
+func TestNewCollector_DefaultSocket(t *testing.T) {
+	c := NewCollector("", createTestLogger(t))
+	assert.Equal(t, DefaultSocketPath, c.socketPath)
+
+	socket := filepath.Join(t.TempDir(), "fail2ban.sock")
+	require.NoError(t, os.WriteFile(socket, nil, 0600))
+	c = NewCollector(socket, createTestLogger(t))
+	assert.Equal(t, clientSource{socketPath: socket}, c.source)
+}
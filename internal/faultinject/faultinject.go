@@ -0,0 +1,118 @@
+package faultinject
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvVar overrides the fail_inject config value when set
+const EnvVar = "XHUB_AGENT_FAIL_INJECT"
+
+// Injection points
+const (
+	PointReport = "report" // gRPC report calls
+	PointAuth   = "auth"   // 3x-ui login
+)
+
+var knownPoints = map[string]bool{PointReport: true, PointAuth: true}
+
+// Injector randomly fails operations at configured rates (testing only, default off)
+type Injector struct {
+	rates map[string]float64
+	rng   *rand.Rand
+	mutex sync.Mutex
+}
+
+// Parse parses a spec like "report=20%,auth=5%" (rates may also be given as 0.2).
+// An empty spec returns a nil injector, which never fails.
+func Parse(spec string) (*Injector, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	rates := make(map[string]float64)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		point, value, ok := strings.Cut(part, "=")
+		point = strings.TrimSpace(point)
+		if !ok || !knownPoints[point] {
+			return nil, fmt.Errorf("invalid fail_inject entry %q (expected report=N%% or auth=N%%)", part)
+		}
+
+		rate, err := parseRate(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid fail_inject rate for %s: %w", point, err)
+		}
+		rates[point] = rate
+	}
+
+	return New(rates, time.Now().UnixNano()), nil
+}
+
+// parseRate parses "20%" or "0.2" into a probability
+func parseRate(value string) (float64, error) {
+	percent := strings.HasSuffix(value, "%")
+	rate, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if percent {
+		rate /= 100
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate %s out of range 0-100%%", value)
+	}
+	return rate, nil
+}
+
+// New creates an injector with explicit rates and random seed
+func New(rates map[string]float64, seed int64) *Injector {
+	return &Injector{
+		rates: rates,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Fail returns an error if a failure should be injected at point
+func (i *Injector) Fail(point string) error {
+	if i == nil {
+		return nil
+	}
+
+	rate := i.rates[point]
+	if rate <= 0 {
+		return nil
+	}
+
+	i.mutex.Lock()
+	hit := i.rng.Float64() < rate
+	i.mutex.Unlock()
+
+	if !hit {
+		return nil
+	}
+	return fmt.Errorf("injected %s failure (fail_inject)", point)
+}
+
+// String describes the configured rates, e.g. "auth=5%, report=20%"
+func (i *Injector) String() string {
+	if i == nil {
+		return "off"
+	}
+	var parts []string
+	for point, rate := range i.rates {
+		parts = append(parts, fmt.Sprintf("%s=%g%%", point, rate*100))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,51 @@
+package faultinject
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	injector, err := Parse("report=20%, auth=0.05")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.2, injector.rates[PointReport], 1e-9)
+	assert.InDelta(t, 0.05, injector.rates[PointAuth], 1e-9)
+	assert.Equal(t, "auth=5%, report=20%", injector.String())
+
+	injector, err = Parse("")
+	require.NoError(t, err)
+	assert.Nil(t, injector)
+	assert.NoError(t, injector.Fail(PointReport)) // nil injector never fails
+
+	for _, spec := range []string{"report", "unknown=5%", "report=abc", "report=150%", "auth=-1"} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestInjector_FailureRate(t *testing.T) {
+	injector := New(map[string]float64{PointReport: 0.2, PointAuth: 0.05}, 42)
+
+	const trials = 20000
+	failures := map[string]int{}
+	for i := 0; i < trials; i++ {
+		for _, point := range []string{PointReport, PointAuth} {
+			if err := injector.Fail(point); err != nil {
+				assert.Contains(t, err.Error(), "injected "+point)
+				failures[point]++
+			}
+		}
+	}
+
+	assert.InDelta(t, 0.2, float64(failures[PointReport])/trials, 0.02)
+	assert.InDelta(t, 0.05, float64(failures[PointAuth])/trials, 0.01)
+}
+
+func TestInjector_ZeroRateNeverFails(t *testing.T) {
+	injector := New(map[string]float64{PointReport: 0.5}, 1)
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, injector.Fail(PointAuth))
+	}
+}
@@ -0,0 +1,55 @@
+package history
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader are the columns written by WriteCSV, the main figures of each status
+var csvHeader = []string{
+	"time", "source", "cpu", "mem_used", "mem_total", "swap_used", "swap_total", "disk_used", "disk_total",
+	"load1", "load5", "load15", "tcp", "udp", "net_up", "net_down", "net_sent", "net_recv", "xray_state", "uptime",
+}
+
+// WriteCSV writes the samples as CSV with a header row
+func WriteCSV(w io.Writer, samples []Sample) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		s := sample.Status
+		loads := make([]string, 3)
+		for i := range loads {
+			if i < len(s.Loads) {
+				loads[i] = formatFloat(s.Loads[i])
+			}
+		}
+		record := []string{
+			sample.Time.UTC().Format(time.RFC3339), sample.Source, formatFloat(s.CPU),
+			formatInt(s.Memory.Current), formatInt(s.Memory.Total),
+			formatInt(s.Swap.Current), formatInt(s.Swap.Total),
+			formatInt(s.Disk.Current), formatInt(s.Disk.Total),
+			loads[0], loads[1], loads[2],
+			strconv.Itoa(s.TCPCount), strconv.Itoa(s.UDPCount),
+			formatInt(s.NetIO.Up), formatInt(s.NetIO.Down),
+			formatInt(s.NetTraffic.Sent), formatInt(s.NetTraffic.Recv),
+			s.Xray.State, strconv.Itoa(s.Uptime),
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
@@ -0,0 +1,238 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"xhub-agent/internal/monitor"
+)
+
+// FileName is the file of the persisted history under the history directory of the data
+// directory (history_persist)
+const FileName = "status.jsonl"
+
+// Sample is the status collected by one report cycle
+type Sample struct {
+	Time   time.Time                 `json:"time"`
+	Source string                    `json:"source"` // panel, or host while 3x-ui was unavailable
+	Status *monitor.ServerStatusData `json:"status"`
+}
+
+// Buffer keeps the samples of the last retention period (history_hours) in a ring of
+// capacity samples, oldest first. A persisted buffer also appends every sample to a JSON
+// Lines file, read back on start and compacted once it holds twice the capacity.
+// A nil *Buffer records nothing, so callers need not check whether the history is enabled.
+type Buffer struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mutex   sync.Mutex
+	samples []Sample // Ring of capacity samples
+	start   int      // Index of the oldest sample
+	count   int
+	path    string   // Persisted file, "" when in memory only
+	file    *os.File // Open for appending
+	lines   int      // Lines in the file
+}
+
+// New creates an in-memory buffer keeping up to capacity samples of the last retention
+func New(retention time.Duration, capacity int) *Buffer {
+	return &Buffer{retention: retention, now: time.Now, samples: make([]Sample, max(capacity, 1))}
+}
+
+// Open creates a buffer persisted to path, loading the samples still within retention.
+// Unreadable lines are skipped; if the file cannot be read or written the error is returned
+// alongside a usable in-memory buffer.
+func Open(path string, retention time.Duration, capacity int) (*Buffer, error) {
+	b := New(retention, capacity)
+	loaded, err := ReadFile(path, b.now().Add(-retention))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return b, err
+	}
+	for _, sample := range loaded {
+		b.push(sample)
+	}
+
+	b.path = path
+	if err := b.compact(); err != nil {
+		b.path = ""
+		return b, err
+	}
+	return b, nil
+}
+
+// ReadFile reads the samples of a persisted history taken at or after since, skipping
+// unreadable lines. It is used by the "history" command while the agent is not running.
+func ReadFile(path string, since time.Time) ([]Sample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var samples []Sample
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var sample Sample
+		if json.Unmarshal(scanner.Bytes(), &sample) != nil || sample.Status == nil {
+			continue // Torn write of a crash
+		}
+		if !sample.Time.Before(since) {
+			samples = append(samples, sample)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return samples, fmt.Errorf("failed to read history: %w", err)
+	}
+	return samples, nil
+}
+
+// Add records a sample, dropping the oldest when the ring is full. A persisted buffer keeps
+// the sample in memory even if writing it fails.
+func (b *Buffer) Add(sample Sample) error {
+	if b == nil {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.push(sample)
+	if b.file == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return fmt.Errorf("failed to encode history sample: %w", err)
+	}
+	if _, err := b.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	b.lines++
+	if b.lines >= 2*len(b.samples) {
+		return b.compact()
+	}
+	return nil
+}
+
+// Since returns the samples taken at or after since and within the retention, oldest first
+func (b *Buffer) Since(since time.Time) []Sample {
+	if b == nil {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if cutoff := b.now().Add(-b.retention); since.Before(cutoff) {
+		since = cutoff
+	}
+	samples := make([]Sample, 0, b.count)
+	for i := range b.count {
+		sample := b.samples[(b.start+i)%len(b.samples)]
+		if !sample.Time.Before(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// Len returns the number of samples held
+func (b *Buffer) Len() int {
+	if b == nil {
+		return 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.count
+}
+
+// Close closes the persisted file
+func (b *Buffer) Close() error {
+	if b == nil {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.file == nil {
+		return nil
+	}
+	err := b.file.Close()
+	b.file = nil
+	return err
+}
+
+// push adds a sample to the ring
+func (b *Buffer) push(sample Sample) {
+	if b.count < len(b.samples) {
+		b.samples[(b.start+b.count)%len(b.samples)] = sample
+		b.count++
+		return
+	}
+	b.samples[b.start] = sample
+	b.start = (b.start + 1) % len(b.samples)
+}
+
+// compact rewrites the persisted file with the samples held and reopens it for appending
+func (b *Buffer) compact() error {
+	if b.file != nil {
+		b.file.Close()
+		b.file = nil
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	var content strings.Builder
+	lines := 0
+	for i := range b.count {
+		line, err := json.Marshal(b.samples[(b.start+i)%len(b.samples)])
+		if err != nil {
+			continue
+		}
+		content.Write(line)
+		content.WriteByte('\n')
+		lines++
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+
+	file, err := os.OpenFile(b.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	b.file = file
+	b.lines = lines
+	return nil
+}
+
+// ParseSince parses the start of a history query: a duration back from now (e.g. "1h",
+// "90m") or an RFC 3339 time. An empty value means the whole history.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("invalid since %q: negative duration", value)
+		}
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q: expected a duration such as 1h or an RFC 3339 time", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,117 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+)
+
+var baseTime = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+// keepAll is a retention keeping the samples at baseTime whenever the tests run
+const keepAll = 100 * 365 * 24 * time.Hour
+
+func sampleAt(minute int, cpu float64) Sample {
+	return Sample{
+		Time:   baseTime.Add(time.Duration(minute) * time.Minute),
+		Source: "panel",
+		Status: &monitor.ServerStatusData{CPU: cpu, Loads: []float64{0.5, 0.25}, Xray: monitor.XrayInfo{State: "running"}},
+	}
+}
+
+func cpus(samples []Sample) []float64 {
+	var values []float64
+	for _, sample := range samples {
+		values = append(values, sample.Status.CPU)
+	}
+	return values
+}
+
+func TestBuffer_Ring(t *testing.T) {
+	b := New(time.Hour, 3)
+	b.now = func() time.Time { return baseTime.Add(10 * time.Minute) }
+	for i := range 5 {
+		require.NoError(t, b.Add(sampleAt(i, float64(i))))
+	}
+
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, []float64{2, 3, 4}, cpus(b.Since(time.Time{})), "the oldest samples are dropped")
+	assert.Equal(t, []float64{3, 4}, cpus(b.Since(baseTime.Add(3*time.Minute))))
+
+	// Samples older than the retention are left out
+	b.now = func() time.Time { return baseTime.Add(63*time.Minute + 30*time.Second) }
+	assert.Equal(t, []float64{4}, cpus(b.Since(time.Time{})))
+}
+
+func TestBuffer_Nil(t *testing.T) {
+	var b *Buffer
+	assert.NotPanics(t, func() {
+		assert.NoError(t, b.Add(sampleAt(0, 1)))
+		assert.Empty(t, b.Since(time.Time{}))
+		assert.Zero(t, b.Len())
+		assert.NoError(t, b.Close())
+	})
+}
+
+func TestBuffer_Persisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", FileName)
+	b, err := Open(path, keepAll, 2)
+	require.NoError(t, err)
+	for i := range 4 {
+		require.NoError(t, b.Add(sampleAt(i, float64(i))))
+	}
+	require.NoError(t, b.Close())
+
+	// Compacted once the file held twice the capacity
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), "\n"))
+
+	// A torn line is skipped on reload
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	file.WriteString(`{"time":"2026-10`)
+	file.Close()
+
+	reopened, err := Open(path, keepAll, 2)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, []float64{2, 3}, cpus(reopened.Since(time.Time{})))
+
+	samples, err := ReadFile(path, baseTime.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3}, cpus(samples))
+}
+
+func TestParseSince(t *testing.T) {
+	since, err := ParseSince("1h", baseTime)
+	require.NoError(t, err)
+	assert.Equal(t, baseTime.Add(-time.Hour), since)
+
+	since, err = ParseSince("2026-10-16T11:30:00Z", baseTime)
+	require.NoError(t, err)
+	assert.Equal(t, baseTime.Add(-30*time.Minute), since)
+
+	since, err = ParseSince("", baseTime)
+	require.NoError(t, err)
+	assert.True(t, since.IsZero())
+
+	_, err = ParseSince("yesterday", baseTime)
+	assert.EqualError(t, err, `invalid since "yesterday": expected a duration such as 1h or an RFC 3339 time`)
+	_, err = ParseSince("-1h", baseTime)
+	assert.Error(t, err)
+}
+
+func TestWriteCSV(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, WriteCSV(&out, []Sample{sampleAt(0, 12.5)}))
+	assert.Equal(t, strings.Join(csvHeader, ",")+"\n"+
+		"2026-10-16T12:00:00Z,panel,12.5,0,0,0,0,0,0,0.5,0.25,,0,0,0,0,0,0,running,0\n", out.String())
+}
@@ -0,0 +1,45 @@
+package hysteria2
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzParseConfig(f *testing.F) {
+	f.Add([]byte("listen: :443\nauth:\n  type: password\n  password: testpassword\n"))
+	f.Add([]byte("listen: 0.0.0.0:8443\nobfs:\n  type: salamander\n  salamander:\n    password: obfs\n"))
+	f.Add([]byte("listen: \"[::]:99999\"\n"))
+	f.Add([]byte("listen: \"a:b c\"\nauth:\n  password: \"\\xff\"\n"))
+	f.Add([]byte("a: &a [*a, *a]\nb: &b [*a, *a]\nc: [*b, *b]\n"))
+
+	client := &Client{enabled: true, nodeName: "Fuzz", serverAddr: "example.com"}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		config, err := parseConfig(data)
+		if err != nil {
+			return
+		}
+		uri, err := client.BuildURI(config)
+		if err != nil {
+			return
+		}
+		if !utf8.ValidString(uri) || !strings.HasPrefix(uri, "hysteria2://") {
+			t.Fatalf("invalid URI: %q", uri)
+		}
+	})
+}
+
+func TestBuildURIInvalidListenPort(t *testing.T) {
+	client := &Client{enabled: true, nodeName: "Test", serverAddr: "example.com"}
+
+	for _, listen := range []string{"a:b c", ":99999", ":0", "[::]:-1"} {
+		if uri, err := client.BuildURI(&Hysteria2Config{Listen: listen}); err == nil {
+			t.Errorf("listen %q accepted, got URI %s", listen, uri)
+		}
+	}
+
+	if _, err := client.BuildURI(&Hysteria2Config{Listen: "[::]:443"}); err != nil {
+		t.Errorf("valid listen rejected: %v", err)
+	}
+}
@@ -5,13 +5,31 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/nodeprovider"
+	"xhub-agent/internal/sanitize"
+	"xhub-agent/internal/sharelink"
 	"xhub-agent/pkg/logger"
 )
 
+// ProtocolName is the protocol of Hysteria2 nodes, as reported in the inbound protocols
+const ProtocolName = "hysteria2"
+
+// maxConfigSize caps the Hysteria2 config file read (1 MiB)
+const maxConfigSize = 1 << 20
+
+// Hysteria2 auth types
+const (
+	AuthPassword = "password" // One password shared by every user
+	AuthUserPass = "userpass" // Username and password per user, listed in the config
+	AuthHTTP     = "http"     // Auth strings checked by an external HTTP backend
+)
+
 // Hysteria2Config represents the Hysteria2 server configuration structure
 type Hysteria2Config struct {
 	Listen string `yaml:"listen"` // e.g., ":443" or "0.0.0.0:443"
@@ -27,8 +45,12 @@ type Hysteria2Config struct {
 	} `yaml:"acme"`
 
 	Auth struct {
-		Type     string `yaml:"type"`     // "password" or "userpass"
-		Password string `yaml:"password"` // for password auth
+		Type     string            `yaml:"type"`     // "password", "userpass" or "http"
+		Password string            `yaml:"password"` // for password auth
+		UserPass map[string]string `yaml:"userpass"` // Password by username, for userpass auth
+		HTTP     struct {
+			URL string `yaml:"url"`
+		} `yaml:"http"` // Auth backend, for http auth
 	} `yaml:"auth"`
 
 	Obfs struct {
@@ -55,6 +77,7 @@ type Client struct {
 	insecure         bool   // Whether to skip TLS verification
 	portHopping      bool   // Enable port hopping
 	portHoppingRange string // Port range for hopping, e.g. "20000-50000"
+	usersFile        string // Auth string per user for http auth (YAML map)
 }
 
 // NewClient creates a new Hysteria2 configuration client
@@ -86,22 +109,74 @@ func (c *Client) Configure(enabled bool, configPath, nodeName, serverAddr string
 	}
 }
 
+// SetUsersFile sets the YAML map of user (subscription email) to auth string used with http
+// auth, as accepted by the external auth backend
+func (c *Client) SetUsersFile(path string) {
+	c.usersFile = path
+}
+
 // IsEnabled returns whether Hysteria2 support is enabled
 func (c *Client) IsEnabled() bool {
 	return c.enabled
 }
 
+// Name returns the protocol of the Hysteria2 node provider
+func (c *Client) Name() string {
+	return ProtocolName
+}
+
+// Enabled returns whether Hysteria2 support is enabled, like IsEnabled
+func (c *Client) Enabled() bool {
+	return c.enabled
+}
+
+// CollectStatus adds hysteria2 to the inbound protocols: it runs outside 3x-ui, so it never
+// shows up in the inbound list
+func (c *Client) CollectStatus() collector.Apply {
+	return nodeprovider.ProtocolStatus(ProtocolName)
+}
+
+// BuildShareLinks builds the share links of the Hysteria2 node
+func (c *Client) BuildShareLinks() (nodeprovider.ShareLinks, error) {
+	nodes, err := c.Nodes()
+	return nodeprovider.ShareLinks{Nodes: nodes}, err
+}
+
 // ParseConfig parses the Hysteria2 configuration file
 func (c *Client) ParseConfig() (*Hysteria2Config, error) {
 	if !c.enabled {
 		return nil, fmt.Errorf("hysteria2 support is not enabled")
 	}
 
-	data, err := os.ReadFile(c.configPath)
+	f, err := os.Open(c.configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read hysteria2 config file %s: %w", c.configPath, err)
 	}
+	defer f.Close()
 
+	data, err := sanitize.ReadLimited(f, maxConfigSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hysteria2 config file %s: %w", c.configPath, err)
+	}
+
+	return parseConfig(data)
+}
+
+// CertFile returns the TLS certificate file of the Hysteria2 config ("" when disabled,
+// unreadable or using ACME)
+func (c *Client) CertFile() string {
+	if !c.enabled {
+		return ""
+	}
+	config, err := c.ParseConfig()
+	if err != nil {
+		return ""
+	}
+	return config.TLS.Cert
+}
+
+// parseConfig decodes the Hysteria2 YAML config
+func parseConfig(data []byte) (*Hysteria2Config, error) {
 	var config Hysteria2Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse hysteria2 config: %w", err)
@@ -110,12 +185,28 @@ func (c *Client) ParseConfig() (*Hysteria2Config, error) {
 	return &config, nil
 }
 
-// BuildURI constructs a hysteria2:// share link from the configuration
+// BuildURI constructs the hysteria2:// share link of a password auth configuration
 func (c *Client) BuildURI(config *Hysteria2Config) (string, error) {
 	if config == nil {
 		return "", fmt.Errorf("config is nil")
 	}
+	if perUserAuth(config) {
+		return "", fmt.Errorf("hysteria2 %s auth has per-user credentials", sanitize.String(config.Auth.Type))
+	}
+	var auth string
+	if config.Auth.Password != "" {
+		auth = url.PathEscape(config.Auth.Password)
+	}
+	return c.buildURI(config, auth)
+}
+
+// perUserAuth reports whether each user of config has their own credentials
+func perUserAuth(config *Hysteria2Config) bool {
+	return config.Auth.Type == AuthUserPass || config.Auth.Type == AuthHTTP
+}
 
+// buildURI constructs a hysteria2:// share link with the escaped auth string
+func (c *Client) buildURI(config *Hysteria2Config, auth string) (string, error) {
 	// Extract port from listen address
 	port := "443" // default
 	if config.Listen != "" {
@@ -127,6 +218,9 @@ func (c *Client) BuildURI(config *Hysteria2Config) (string, error) {
 			}
 		}
 	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid listen port in hysteria2 config: %q", sanitize.String(port))
+	}
 
 	// Determine server address
 	serverAddr := c.serverAddr
@@ -139,8 +233,8 @@ func (c *Client) BuildURI(config *Hysteria2Config) (string, error) {
 	uriBuilder.WriteString("hysteria2://")
 
 	// Add authentication
-	if config.Auth.Password != "" {
-		uriBuilder.WriteString(url.PathEscape(config.Auth.Password))
+	if auth != "" {
+		uriBuilder.WriteString(auth)
 		uriBuilder.WriteString("@")
 	}
 
@@ -194,6 +288,74 @@ func (c *Client) BuildURI(config *Hysteria2Config) (string, error) {
 	return uriBuilder.String(), nil
 }
 
+// Nodes builds the share links of the Hysteria2 node: one shared link for password auth, one
+// link per user for userpass auth (from the config) and http auth (from the users file)
+func (c *Client) Nodes() (sharelink.Nodes, error) {
+	config, err := c.ParseConfig()
+	if err != nil {
+		return sharelink.Nodes{}, err
+	}
+
+	var credentials map[string]string // Escaped auth string by user
+	switch config.Auth.Type {
+	case AuthUserPass:
+		if len(config.Auth.UserPass) == 0 {
+			return sharelink.Nodes{}, fmt.Errorf("hysteria2 userpass auth without users")
+		}
+		credentials = make(map[string]string, len(config.Auth.UserPass))
+		for user, password := range config.Auth.UserPass {
+			credentials[user] = sharelink.EscapeUser(user) + ":" + sharelink.EscapeAuth(password)
+		}
+	case AuthHTTP:
+		users, err := c.readUsersFile()
+		if err != nil {
+			return sharelink.Nodes{}, err
+		}
+		credentials = make(map[string]string, len(users))
+		for user, auth := range users {
+			credentials[user] = sharelink.EscapeAuth(auth)
+		}
+	default:
+		uri, err := c.BuildURI(config)
+		if err != nil {
+			return sharelink.Nodes{}, err
+		}
+		return sharelink.Nodes{Shared: uri}, nil
+	}
+
+	nodes := sharelink.Nodes{PerUser: make(map[string]string, len(credentials))}
+	for user, auth := range credentials {
+		uri, err := c.buildURI(config, auth)
+		if err != nil {
+			return sharelink.Nodes{}, err
+		}
+		nodes.PerUser[user] = uri
+	}
+	return nodes, nil
+}
+
+// readUsersFile reads the auth string per user of http auth
+func (c *Client) readUsersFile() (map[string]string, error) {
+	if c.usersFile == "" {
+		return nil, fmt.Errorf("hysteria2 http auth requires hysteria2_users_file")
+	}
+	f, err := os.Open(c.usersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hysteria2 users file %s: %w", c.usersFile, err)
+	}
+	defer f.Close()
+
+	data, err := sanitize.ReadLimited(f, maxConfigSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hysteria2 users file %s: %w", c.usersFile, err)
+	}
+	var users map[string]string
+	if err := yaml.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("failed to parse hysteria2 users file %s: %w", c.usersFile, err)
+	}
+	return users, nil
+}
+
 // GetNodeConfig returns the Hysteria2 node as a base64-encoded string
 // suitable for inclusion in subscription data
 func (c *Client) GetNodeConfig() (string, error) {
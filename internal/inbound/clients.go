@@ -0,0 +1,80 @@
+package inbound
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"xhub-agent/internal/sanitize"
+)
+
+// User is a client of a 3x-ui inbound as a JSON object (the entries of settings.clients),
+// keeping protocol-specific fields such as id, password, flow or limitIp
+type User map[string]any
+
+// Email returns the client email, which 3x-ui requires to be unique across inbounds
+func (u User) Email() string {
+	email, _ := u["email"].(string)
+	return email
+}
+
+// ParseUser decodes a client sent by xhub: a JSON object with at least an email
+func ParseUser(data []byte) (User, error) {
+	if err := sanitize.CheckJSON(data); err != nil {
+		return nil, fmt.Errorf("invalid client: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var user User
+	if err := decoder.Decode(&user); err != nil || user == nil {
+		return nil, fmt.Errorf("invalid client: expected a JSON object")
+	}
+	if strings.TrimSpace(user.Email()) == "" {
+		return nil, fmt.Errorf("invalid client: email is required")
+	}
+	if enable, ok := user["enable"]; ok {
+		if _, ok := enable.(bool); !ok {
+			return nil, fmt.Errorf("invalid client: enable must be a boolean")
+		}
+	}
+	return user, nil
+}
+
+// clientRequest is the body of the 3x-ui client endpoints: the inbound id and a settings
+// JSON text holding the clients
+func clientRequest(inboundID int, user User) (map[string]any, error) {
+	settings, err := json.Marshal(map[string]any{"clients": []User{user}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode client: %w", err)
+	}
+	return map[string]any{"id": inboundID, "settings": string(settings)}, nil
+}
+
+// AddClient adds user to an inbound
+func (c *Client) AddClient(inboundID int, user User) error {
+	body, err := clientRequest(inboundID, user)
+	if err != nil {
+		return err
+	}
+	_, err = c.post("/panel/inbound/addClient", body)
+	return err
+}
+
+// UpdateClient replaces the client clientID of an inbound with user. The client id is its
+// UUID (vless, vmess), password (trojan) or email (shadowsocks).
+func (c *Client) UpdateClient(inboundID int, clientID string, user User) error {
+	body, err := clientRequest(inboundID, user)
+	if err != nil {
+		return err
+	}
+	_, err = c.post("/panel/inbound/updateClient/"+url.PathEscape(clientID), body)
+	return err
+}
+
+// RemoveClient deletes the client clientID from an inbound
+func (c *Client) RemoveClient(inboundID int, clientID string) error {
+	_, err := c.post(fmt.Sprintf("/panel/inbound/%d/delClient/%s", inboundID, url.PathEscape(clientID)), nil)
+	return err
+}
@@ -0,0 +1,68 @@
+package inbound
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+)
+
+func TestParseUser(t *testing.T) {
+	user, err := ParseUser([]byte(`{"id": "u1", "email": "alice", "limitIp": 2, "flow": "xtls-rprx-vision"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Email())
+	assert.Equal(t, json.Number("2"), user["limitIp"])
+
+	for input, message := range map[string]string{
+		`"alice"`:                        "expected a JSON object",
+		`{"id": "u1"}`:                   "email is required",
+		`{"email": " "}`:                 "email is required",
+		`{"email": "a", "enable": "no"}`: "enable must be a boolean",
+	} {
+		_, err := ParseUser([]byte(input))
+		assert.ErrorContains(t, err, message, input)
+	}
+}
+
+func TestClient_ClientCalls(t *testing.T) {
+	type call struct {
+		path string
+		body map[string]any
+	}
+	var calls []call
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var decoded map[string]any
+		json.Unmarshal(body, &decoded)
+		calls = append(calls, call{path: r.URL.EscapedPath(), body: decoded})
+		if decoded != nil && decoded["id"] == float64(9) {
+			w.Write([]byte(`{"success": false, "msg": "Duplicate email: alice"}`))
+			return
+		}
+		w.Write([]byte(`{"success": true, "msg": ""}`))
+	}))
+	defer panel.Close()
+	authClient := auth.NewXUIAuth(panel.URL, "admin", "admin")
+	authClient.SetSessionForTesting("test-session")
+	client := NewClient(authClient)
+
+	user := User{"id": "u1", "email": "alice"}
+	require.NoError(t, client.AddClient(3, user))
+	require.NoError(t, client.UpdateClient(3, "u1/x", user))
+	require.NoError(t, client.RemoveClient(3, "u1"))
+	assert.ErrorContains(t, client.AddClient(9, user), "API error: Duplicate email: alice")
+
+	require.Len(t, calls, 4)
+	assert.Equal(t, "/panel/inbound/addClient", calls[0].path)
+	assert.Equal(t, float64(3), calls[0].body["id"])
+	assert.JSONEq(t, `{"clients": [{"id": "u1", "email": "alice"}]}`, calls[0].body["settings"].(string), "3x-ui expects settings as JSON text")
+	assert.Equal(t, "/panel/inbound/updateClient/u1%2Fx", calls[1].path, "client ids are escaped")
+	assert.Equal(t, "/panel/inbound/3/delClient/u1", calls[2].path)
+	assert.Nil(t, calls[2].body)
+}
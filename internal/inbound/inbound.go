@@ -0,0 +1,253 @@
+package inbound
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/sanitize"
+)
+
+// stringFields are the inbound fields 3x-ui stores as JSON text. xhub may send them as JSON
+// objects; they are encoded to strings before reaching the panel.
+var stringFields = []string{"settings", "streamSettings", "sniffing", "allocate"}
+
+// readOnlyFields are managed by 3x-ui and never taken from xhub: the id comes from the URL
+// and the traffic counters belong to the panel
+var readOnlyFields = []string{"id", "up", "down", "clientStats"}
+
+// Inbound is a 3x-ui inbound as a JSON object (model.Inbound), keeping fields the agent does
+// not know about. Numbers are json.Number so that they survive a round trip unchanged.
+type Inbound map[string]any
+
+// ID returns the inbound id, 0 when missing
+func (i Inbound) ID() int {
+	id, _ := intField(i["id"])
+	return id
+}
+
+// Port returns the inbound port, 0 when missing
+func (i Inbound) Port() int {
+	port, _ := intField(i["port"])
+	return port
+}
+
+// Protocol returns the inbound protocol, e.g. vless
+func (i Inbound) Protocol() string {
+	protocol, _ := i["protocol"].(string)
+	return protocol
+}
+
+// intField converts a decoded JSON number
+func intField(value any) (int, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err == nil
+	case float64:
+		return int(v), v == float64(int(v))
+	case int:
+		return v, true
+	}
+	return 0, false
+}
+
+// Parse decodes an inbound sent by xhub: a JSON object with the fields of a 3x-ui inbound,
+// where settings, streamSettings, sniffing and allocate may be objects or JSON strings.
+// Read-only fields (id, traffic counters) are dropped.
+func Parse(data string) (Inbound, error) {
+	if err := sanitize.CheckJSON([]byte(data)); err != nil {
+		return nil, fmt.Errorf("invalid inbound: %w", err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var inbound Inbound
+	if err := decoder.Decode(&inbound); err != nil || inbound == nil {
+		return nil, fmt.Errorf("invalid inbound: expected a JSON object")
+	}
+
+	for _, field := range readOnlyFields {
+		delete(inbound, field)
+	}
+	for _, field := range stringFields {
+		value, ok := inbound[field]
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			if !json.Valid([]byte(v)) {
+				return nil, fmt.Errorf("invalid inbound: %s is not valid JSON", field)
+			}
+		case map[string]any, []any:
+			text, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid inbound: %s: %w", field, err)
+			}
+			inbound[field] = string(text)
+		default:
+			return nil, fmt.Errorf("invalid inbound: %s must be a JSON object or string", field)
+		}
+	}
+	if port, ok := inbound["port"]; ok {
+		if n, ok := intField(port); !ok || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid inbound: port must be between 1 and 65535")
+		}
+	}
+	if enable, ok := inbound["enable"]; ok {
+		if _, ok := enable.(bool); !ok {
+			return nil, fmt.Errorf("invalid inbound: enable must be a boolean")
+		}
+	}
+	return inbound, nil
+}
+
+// Client manages the inbounds of 3x-ui through its panel API
+type Client struct {
+	auth *auth.XUIAuth // Also sends the panel requests, with its shared client
+}
+
+// apiResponse is the envelope of the 3x-ui panel API responses
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"msg"`
+	Obj     json.RawMessage `json:"obj"`
+}
+
+// NewClient creates an inbound client using the session of authClient
+func NewClient(authClient *auth.XUIAuth) *Client {
+	return &Client{auth: authClient}
+}
+
+// List returns the inbounds of the panel
+func (c *Client) List() ([]Inbound, error) {
+	obj, err := c.post("/panel/inbound/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(obj))
+	decoder.UseNumber()
+	var inbounds []Inbound
+	if err := decoder.Decode(&inbounds); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	result := make([]Inbound, 0, len(inbounds))
+	for _, inbound := range inbounds {
+		if inbound != nil {
+			result = append(result, inbound)
+		}
+	}
+	return result, nil
+}
+
+// Get returns the inbound with the given id
+func (c *Client) Get(id int) (Inbound, error) {
+	inbounds, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, inbound := range inbounds {
+		if inbound.ID() == id {
+			return inbound, nil
+		}
+	}
+	return nil, fmt.Errorf("inbound %d not found", id)
+}
+
+// Create adds inbound to the panel and returns its id. Protocol and port are required.
+func (c *Client) Create(inbound Inbound) (int, error) {
+	if inbound.Protocol() == "" || inbound.Port() == 0 {
+		return 0, fmt.Errorf("invalid inbound: protocol and port are required")
+	}
+	if _, ok := inbound["enable"]; !ok {
+		inbound["enable"] = true
+	}
+	obj, err := c.post("/panel/inbound/add", inbound)
+	if err != nil {
+		return 0, err
+	}
+	var created struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(obj, &created); err != nil {
+		return 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return created.ID, nil
+}
+
+// Update changes the given fields of an inbound, keeping the others. 3x-ui replaces the whole
+// inbound on update, so the current one is fetched and merged first.
+func (c *Client) Update(id int, changes Inbound) error {
+	current, err := c.Get(id)
+	if err != nil {
+		return err
+	}
+	for field, value := range changes {
+		current[field] = value
+	}
+	_, err = c.post(fmt.Sprintf("/panel/inbound/update/%d", id), current)
+	return err
+}
+
+// SetEnabled enables or disables an inbound
+func (c *Client) SetEnabled(id int, enabled bool) error {
+	return c.Update(id, Inbound{"enable": enabled})
+}
+
+// Delete removes an inbound and its clients from the panel
+func (c *Client) Delete(id int) error {
+	_, err := c.post(fmt.Sprintf("/panel/inbound/del/%d", id), nil)
+	return err
+}
+
+// post sends body as JSON to a panel API path and returns the obj of a successful response
+func (c *Client) post(path string, body any) (json.RawMessage, error) {
+	// Check authentication status
+	if !c.auth.IsAuthenticated() {
+		return nil, fmt.Errorf("%w: please login first", auth.ErrAuthExpired)
+	}
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+	req, err := c.auth.GetAuthenticatedRequest("POST", path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.auth.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &auth.StatusError{StatusCode: resp.StatusCode}
+	}
+
+	data, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := sanitize.CheckJSON(data); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	var apiResp apiResponse
+	if err := json.Unmarshal(data, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if !apiResp.Success {
+		return nil, fmt.Errorf("API error: %s", sanitize.String(apiResp.Message))
+	}
+	return apiResp.Obj, nil
+}
@@ -0,0 +1,184 @@
+package inbound
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+)
+
+func TestParse(t *testing.T) {
+	in, err := Parse(`{"id": 7, "up": 100, "protocol": "vless", "port": 443, "remark": "edge",
+		"settings": {"clients": []}, "streamSettings": "{\"network\":\"tcp\"}", "expiryTime": 1700000000000}`)
+	require.NoError(t, err)
+	assert.NotContains(t, in, "id")
+	assert.NotContains(t, in, "up")
+	assert.Equal(t, "vless", in.Protocol())
+	assert.Equal(t, 443, in.Port())
+	assert.Equal(t, `{"clients":[]}`, in["settings"], "objects are sent to 3x-ui as JSON text")
+	assert.Equal(t, `{"network":"tcp"}`, in["streamSettings"])
+	assert.Equal(t, json.Number("1700000000000"), in["expiryTime"])
+
+	for input, message := range map[string]string{
+		`[1, 2]`:                          "expected a JSON object",
+		`null`:                            "expected a JSON object",
+		`{"port": 70000}`:                 "port must be between 1 and 65535",
+		`{"port": "443"}`:                 "port must be between 1 and 65535",
+		`{"enable": "yes"}`:               "enable must be a boolean",
+		`{"settings": "{broken"}`:         "settings is not valid JSON",
+		`{"sniffing": 1}`:                 "sniffing must be a JSON object or string",
+		`{"protocol": "vless", "port": }`: "invalid inbound",
+	} {
+		_, err := Parse(input)
+		assert.ErrorContains(t, err, message, input)
+	}
+}
+
+// fakePanel serves the inbound API of 3x-ui from memory
+type fakePanel struct {
+	mutex    sync.Mutex
+	inbounds map[int]map[string]any
+	nextID   int
+	requests []string
+}
+
+func (p *fakePanel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.requests = append(p.requests, r.URL.Path)
+	if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "test-session" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	reply := func(success bool, msg string, obj any) {
+		json.NewEncoder(w).Encode(map[string]any{"success": success, "msg": msg, "obj": obj})
+	}
+	decode := func() map[string]any {
+		body, _ := io.ReadAll(r.Body)
+		var inbound map[string]any
+		json.Unmarshal(body, &inbound)
+		return inbound
+	}
+
+	var id int
+	switch {
+	case r.URL.Path == "/panel/inbound/list":
+		list := []map[string]any{}
+		for id := 1; id < p.nextID; id++ {
+			if inbound, ok := p.inbounds[id]; ok {
+				list = append(list, inbound)
+			}
+		}
+		reply(true, "", list)
+	case r.URL.Path == "/panel/inbound/add":
+		inbound := decode()
+		if r.Header.Get("Content-Type") != "application/json" || inbound == nil {
+			reply(false, "bad request", nil)
+			return
+		}
+		p.nextID++
+		inbound["id"] = p.nextID - 1
+		p.inbounds[p.nextID-1] = inbound
+		reply(true, "Create Successfully", inbound)
+	case scan(r.URL.Path, "/panel/inbound/update/%d", &id):
+		if _, ok := p.inbounds[id]; !ok {
+			reply(false, "record not found", nil)
+			return
+		}
+		inbound := decode()
+		inbound["id"] = id
+		p.inbounds[id] = inbound
+		reply(true, "Update Successfully", inbound)
+	case scan(r.URL.Path, "/panel/inbound/del/%d", &id):
+		delete(p.inbounds, id)
+		reply(true, "Delete Successfully", id)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func scan(path, format string, id *int) bool {
+	_, err := fmt.Sscanf(path, format, id)
+	return err == nil
+}
+
+func newTestClient(t *testing.T) (*Client, *fakePanel) {
+	t.Helper()
+	panel := &fakePanel{inbounds: map[int]map[string]any{}, nextID: 1}
+	server := httptest.NewServer(panel)
+	t.Cleanup(server.Close)
+	authClient := auth.NewXUIAuth(server.URL, "admin", "admin")
+	authClient.SetSessionForTesting("test-session")
+	return NewClient(authClient), panel
+}
+
+func TestClient_Lifecycle(t *testing.T) {
+	client, panel := newTestClient(t)
+
+	in, err := Parse(`{"protocol": "vless", "port": 443, "remark": "edge", "settings": {"clients": []}}`)
+	require.NoError(t, err)
+	id, err := client.Create(in)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	created, err := client.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, true, created["enable"], "new inbounds are enabled by default")
+	assert.Equal(t, `{"clients":[]}`, created["settings"])
+
+	// Updates keep the fields they do not mention
+	changes, err := Parse(`{"remark": "edge-2", "port": 8443}`)
+	require.NoError(t, err)
+	require.NoError(t, client.Update(id, changes))
+	updated, err := client.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, "edge-2", updated["remark"])
+	assert.Equal(t, 8443, updated.Port())
+	assert.Equal(t, "vless", updated.Protocol())
+	assert.Equal(t, `{"clients":[]}`, updated["settings"])
+
+	require.NoError(t, client.SetEnabled(id, false))
+	disabled, err := client.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, false, disabled["enable"])
+	assert.Equal(t, "edge-2", disabled["remark"])
+
+	require.NoError(t, client.Delete(id))
+	_, err = client.Get(id)
+	assert.ErrorContains(t, err, "inbound 1 not found")
+	assert.ErrorContains(t, client.SetEnabled(id, true), "inbound 1 not found")
+	assert.Contains(t, panel.requests, "/panel/inbound/del/1")
+}
+
+func TestClient_Errors(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.Create(Inbound{"remark": "no port"})
+	assert.ErrorContains(t, err, "protocol and port are required")
+
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/add") {
+			w.Write([]byte(`{"success": false, "msg": "port 443 is already in use"}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer panel.Close()
+	authClient := auth.NewXUIAuth(panel.URL, "admin", "admin")
+	authClient.SetSessionForTesting("test-session")
+	client = NewClient(authClient)
+
+	_, err = client.Create(Inbound{"protocol": "vless", "port": 443})
+	assert.ErrorContains(t, err, "API error: port 443 is already in use")
+	assert.ErrorIs(t, client.Delete(1), auth.ErrAuthExpired)
+}
@@ -0,0 +1,214 @@
+package inbound
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Audit actions
+const (
+	AuditResetTraffic  = "reset_traffic"  // Client traffic counters set to zero
+	AuditDisableClient = "disable_client" // Over-quota client disabled
+)
+
+// ClientTraffic is the traffic of a client as counted by 3x-ui (clientStats of an inbound)
+type ClientTraffic struct {
+	InboundID int    `json:"inboundId"`
+	Email     string `json:"email"`
+	Enable    bool   `json:"enable"`
+	Up        int64  `json:"up"`
+	Down      int64  `json:"down"`
+	Total     int64  `json:"total"` // Quota in bytes, 0 for unlimited
+}
+
+// OverQuota tells whether the client used up its quota
+func (t ClientTraffic) OverQuota() bool {
+	return t.Total > 0 && t.Up+t.Down >= t.Total
+}
+
+// AuditEntry records one traffic reset or quota action for the audit trail sent to xhub
+type AuditEntry struct {
+	Action    string `json:"action"`
+	InboundID int    `json:"inbound_id"`
+	Email     string `json:"email"`
+	Up        int64  `json:"up"`    // Traffic before the action
+	Down      int64  `json:"down"`  // Traffic before the action
+	Total     int64  `json:"total"` // Quota in bytes, 0 for unlimited
+	DryRun    bool   `json:"dry_run,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ClientTraffics returns the traffic of every client of the panel
+func (c *Client) ClientTraffics() ([]ClientTraffic, error) {
+	inbounds, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var traffics []ClientTraffic
+	for _, inbound := range inbounds {
+		stats, ok := inbound["clientStats"]
+		if !ok || stats == nil {
+			continue
+		}
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client traffic: %w", err)
+		}
+		var clients []ClientTraffic
+		if err := json.Unmarshal(data, &clients); err != nil {
+			return nil, fmt.Errorf("failed to parse client traffic of inbound %d: %w", inbound.ID(), err)
+		}
+		for _, client := range clients {
+			client.InboundID = inbound.ID()
+			traffics = append(traffics, client)
+		}
+	}
+	return traffics, nil
+}
+
+// ResetTraffic resets the traffic of the client with the given email of an inbound, or of
+// all its clients when email is empty. With dryRun the panel is left unchanged.
+func (c *Client) ResetTraffic(inboundID int, email string, dryRun bool) ([]AuditEntry, error) {
+	traffics, err := c.ClientTraffics()
+	if err != nil {
+		return nil, err
+	}
+	var entries []AuditEntry
+	for _, traffic := range traffics {
+		if traffic.InboundID == inboundID && (email == "" || traffic.Email == email) {
+			entries = append(entries, auditEntry(AuditResetTraffic, traffic, dryRun))
+		}
+	}
+	if len(entries) == 0 {
+		if email != "" {
+			return nil, fmt.Errorf("client %s not found in inbound %d", email, inboundID)
+		}
+		return nil, fmt.Errorf("inbound %d has no clients", inboundID)
+	}
+	if dryRun {
+		return entries, nil
+	}
+
+	if email == "" {
+		err = c.postAction(fmt.Sprintf("/panel/inbound/resetAllClientTraffics/%d", inboundID))
+		for i := range entries {
+			entries[i].Error = errorText(err)
+		}
+		return entries, err
+	}
+	err = c.postAction(fmt.Sprintf("/panel/inbound/%d/resetClientTraffic/%s", inboundID, url.PathEscape(email)))
+	entries[0].Error = errorText(err)
+	return entries, err
+}
+
+// EnforceQuota disables the enabled clients over their quota: the clients with the given
+// emails (over quota as judged by xhub), or the ones whose traffic reached their 3x-ui quota
+// when emails is empty. With dryRun the panel is left unchanged. Failed clients do not stop
+// the others; their entries carry the error, as do the entries of the emails of no client.
+func (c *Client) EnforceQuota(emails []string, dryRun bool) ([]AuditEntry, error) {
+	traffics, err := c.ClientTraffics()
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		selected[email] = true
+	}
+
+	var entries []AuditEntry
+	found := make(map[string]bool, len(emails))
+	for _, traffic := range traffics {
+		over := traffic.OverQuota()
+		if len(selected) > 0 {
+			over = selected[traffic.Email]
+			found[traffic.Email] = true
+		}
+		if !traffic.Enable || !over {
+			continue
+		}
+		entry := auditEntry(AuditDisableClient, traffic, dryRun)
+		if !dryRun {
+			entry.Error = errorText(c.DisableClient(traffic.InboundID, traffic.Email))
+		}
+		entries = append(entries, entry)
+	}
+	for _, email := range emails {
+		if !found[email] {
+			found[email] = true // Reported once
+			entries = append(entries, AuditEntry{Action: AuditDisableClient, Email: email, DryRun: dryRun, Error: fmt.Sprintf("client %s not found", email)})
+		}
+	}
+	return entries, nil
+}
+
+// DisableClient disables the client with the given email of an inbound, keeping its settings
+func (c *Client) DisableClient(inboundID int, email string) error {
+	inbound, err := c.Get(inboundID)
+	if err != nil {
+		return err
+	}
+	settings, _ := inbound["settings"].(string)
+	decoder := json.NewDecoder(strings.NewReader(settings))
+	decoder.UseNumber()
+	var parsed struct {
+		Clients []User `json:"clients"`
+	}
+	if err := decoder.Decode(&parsed); err != nil {
+		return fmt.Errorf("failed to parse settings of inbound %d: %w", inboundID, err)
+	}
+	for _, user := range parsed.Clients {
+		if user.Email() != email {
+			continue
+		}
+		clientID := ClientID(inbound.Protocol(), user)
+		if clientID == "" {
+			return fmt.Errorf("client %s of inbound %d has no id", email, inboundID)
+		}
+		user["enable"] = false
+		return c.UpdateClient(inboundID, clientID, user)
+	}
+	return fmt.Errorf("client %s not found in inbound %d", email, inboundID)
+}
+
+// ClientID returns the id 3x-ui uses in client URLs for a protocol: the UUID (vless, vmess),
+// the password (trojan) or the email (shadowsocks and others)
+func ClientID(protocol string, user User) string {
+	field := "email"
+	switch protocol {
+	case "vless", "vmess":
+		field = "id"
+	case "trojan":
+		field = "password"
+	}
+	id, _ := user[field].(string)
+	return id
+}
+
+// postAction posts an action without body to the panel
+func (c *Client) postAction(path string) error {
+	_, err := c.post(path, nil)
+	return err
+}
+
+// auditEntry creates the audit entry of an action on a client
+func auditEntry(action string, traffic ClientTraffic, dryRun bool) AuditEntry {
+	return AuditEntry{
+		Action:    action,
+		InboundID: traffic.InboundID,
+		Email:     traffic.Email,
+		Up:        traffic.Up,
+		Down:      traffic.Down,
+		Total:     traffic.Total,
+		DryRun:    dryRun,
+	}
+}
+
+// errorText returns the message of err, empty for nil
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,134 @@
+package inbound
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+)
+
+// quotaInbounds are two inbounds with clients under, over and without quota
+const quotaInbounds = `{"success": true, "obj": [
+	{"id": 1, "protocol": "vless", "port": 443,
+	 "settings": "{\"clients\":[{\"id\":\"u-alice\",\"email\":\"alice\",\"enable\":true,\"totalGB\":1000},{\"id\":\"u-bob\",\"email\":\"bob\",\"enable\":true}]}",
+	 "clientStats": [
+		{"id": 1, "inboundId": 1, "enable": true, "email": "alice", "up": 600, "down": 500, "total": 1000},
+		{"id": 2, "inboundId": 1, "enable": true, "email": "bob", "up": 10, "down": 20, "total": 0}]},
+	{"id": 2, "protocol": "trojan", "port": 8443,
+	 "settings": "{\"clients\":[{\"password\":\"p-carol\",\"email\":\"carol\",\"enable\":true}]}",
+	 "clientStats": [
+		{"id": 3, "inboundId": 2, "enable": true, "email": "carol", "up": 50, "down": 50, "total": 100},
+		{"id": 4, "inboundId": 2, "enable": false, "email": "dave", "up": 500, "down": 0, "total": 100}]}]}`
+
+// newQuotaClient returns a client of a panel serving quotaInbounds, and the non-list
+// requests it receives (path and body)
+func newQuotaClient(t *testing.T) (*Client, *[]string) {
+	t.Helper()
+	var requests []string
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panel/inbound/list" {
+			w.Write([]byte(quotaInbounds))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.URL.EscapedPath()+" "+string(body)))
+		w.Write([]byte(`{"success": true}`))
+	}))
+	t.Cleanup(panel.Close)
+	authClient := auth.NewXUIAuth(panel.URL, "admin", "admin")
+	authClient.SetSessionForTesting("test-session")
+	return NewClient(authClient), &requests
+}
+
+func TestClient_ClientTraffics(t *testing.T) {
+	client, _ := newQuotaClient(t)
+	traffics, err := client.ClientTraffics()
+	require.NoError(t, err)
+	require.Len(t, traffics, 4)
+	assert.Equal(t, ClientTraffic{InboundID: 1, Email: "alice", Enable: true, Up: 600, Down: 500, Total: 1000}, traffics[0])
+	assert.True(t, traffics[0].OverQuota())
+	assert.False(t, traffics[1].OverQuota(), "no quota")
+	assert.True(t, traffics[2].OverQuota(), "quota reached exactly")
+}
+
+func TestClient_ResetTraffic(t *testing.T) {
+	client, requests := newQuotaClient(t)
+
+	entries, err := client.ResetTraffic(1, "alice", true)
+	require.NoError(t, err)
+	assert.Equal(t, []AuditEntry{{Action: AuditResetTraffic, InboundID: 1, Email: "alice", Up: 600, Down: 500, Total: 1000, DryRun: true}}, entries)
+	assert.Empty(t, *requests, "a dry run leaves the panel unchanged")
+
+	entries, err = client.ResetTraffic(1, "alice", false)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	entries, err = client.ResetTraffic(1, "", false)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, []string{"/panel/inbound/1/resetClientTraffic/alice", "/panel/inbound/resetAllClientTraffics/1"}, *requests)
+
+	_, err = client.ResetTraffic(1, "carol", false)
+	assert.ErrorContains(t, err, "client carol not found in inbound 1")
+	_, err = client.ResetTraffic(7, "", false)
+	assert.ErrorContains(t, err, "inbound 7 has no clients")
+}
+
+func TestClient_EnforceQuota(t *testing.T) {
+	client, requests := newQuotaClient(t)
+
+	// Dry run over the 3x-ui quotas: dave is already disabled
+	entries, err := client.EnforceQuota(nil, true)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "alice", entries[0].Email)
+	assert.Equal(t, "carol", entries[1].Email)
+	assert.True(t, entries[1].DryRun)
+	assert.Empty(t, *requests)
+
+	entries, err = client.EnforceQuota(nil, false)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Empty(t, entries[0].Error)
+	require.Len(t, *requests, 2)
+	assert.True(t, strings.HasPrefix((*requests)[0], "/panel/inbound/updateClient/u-alice "))
+	assert.True(t, strings.HasPrefix((*requests)[1], "/panel/inbound/updateClient/p-carol "), "trojan clients are addressed by password")
+
+	var body struct {
+		ID       int    `json:"id"`
+		Settings string `json:"settings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(strings.SplitN((*requests)[0], " ", 2)[1]), &body))
+	assert.Equal(t, 1, body.ID)
+	assert.JSONEq(t, `{"clients": [{"id": "u-alice", "email": "alice", "enable": false, "totalGB": 1000}]}`, body.Settings)
+
+	// Clients selected by xhub regardless of the panel quota
+	*requests = nil
+	entries, err = client.EnforceQuota([]string{"bob", "dave"}, false)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, AuditEntry{Action: AuditDisableClient, InboundID: 1, Email: "bob", Up: 10, Down: 20}, entries[0])
+	assert.Len(t, *requests, 1)
+
+	// Emails of no client are reported, not dropped
+	*requests = nil
+	entries, err = client.EnforceQuota([]string{"erin", "dave", "erin"}, true)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, AuditEntry{Action: AuditDisableClient, Email: "erin", DryRun: true, Error: "client erin not found"}, entries[0])
+	assert.Empty(t, *requests)
+}
+
+func TestClientID(t *testing.T) {
+	user := User{"id": "uuid", "password": "secret", "email": "alice"}
+	assert.Equal(t, "uuid", ClientID("vless", user))
+	assert.Equal(t, "uuid", ClientID("vmess", user))
+	assert.Equal(t, "secret", ClientID("trojan", user))
+	assert.Equal(t, "alice", ClientID("shadowsocks", user))
+}
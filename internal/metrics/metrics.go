@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds (seconds) of the duration histograms
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Registry holds the agent health metrics served in the Prometheus text format (metrics_listen).
+// A nil *Registry records nothing, so clients need not check whether metrics are enabled.
+type Registry struct {
+	mutex       sync.Mutex
+	reports     map[[2]string]uint64  // Report RPCs by {rpc, code}
+	cycles      histogram             // Report cycle durations
+	stages      map[string]*histogram // Report cycle stage durations by stage
+	xuiRequests map[string]*histogram // 3x-ui request durations by endpoint
+	lastSuccess time.Time             // Last report RPC xhub accepted
+	valueFuncs  []valueFunc
+}
+
+// valueFunc is a gauge or counter read when the metrics are served
+type valueFunc struct {
+	name, kind, help string
+	value            func() float64
+}
+
+// histogram is a cumulative Prometheus histogram with durationBuckets
+type histogram struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		reports:     make(map[[2]string]uint64),
+		xuiRequests: make(map[string]*histogram),
+		stages:      make(map[string]*histogram),
+	}
+}
+
+// ObserveReport records a report RPC and its gRPC status code name ("OK" for success)
+func (m *Registry) ObserveReport(rpc, code string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.reports[[2]string{rpc, code}]++
+	if code == "OK" {
+		m.lastSuccess = time.Now()
+	}
+}
+
+// ObserveCycle records the duration of a report cycle
+func (m *Registry) ObserveCycle(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.cycles.observe(d)
+}
+
+// ObserveCycleStage records the duration of a stage of a report cycle (e.g. "auth")
+func (m *Registry) ObserveCycleStage(stage string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	h := m.stages[stage]
+	if h == nil {
+		h = &histogram{}
+		m.stages[stage] = h
+	}
+	h.observe(d)
+}
+
+// ObserveXUIRequest records the duration of a 3x-ui request to endpoint (e.g. "/login")
+func (m *Registry) ObserveXUIRequest(endpoint string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	h := m.xuiRequests[endpoint]
+	if h == nil {
+		h = &histogram{}
+		m.xuiRequests[endpoint] = h
+	}
+	h.observe(d)
+}
+
+// GaugeFunc registers a gauge whose value is read by value when the metrics are served
+func (m *Registry) GaugeFunc(name, help string, value func() float64) {
+	m.valueFunc(name, "gauge", help, value)
+}
+
+// CounterFunc registers a counter whose value is read by value when the metrics are served.
+// value must never decrease.
+func (m *Registry) CounterFunc(name, help string, value func() float64) {
+	m.valueFunc(name, "counter", help, value)
+}
+
+// valueFunc registers a metric of kind read by value
+func (m *Registry) valueFunc(name, kind, help string, value func() float64) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.valueFuncs = append(m.valueFuncs, valueFunc{name: name, kind: kind, help: help, value: value})
+}
+
+// observe adds d to the histogram
+func (h *histogram) observe(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(durationBuckets))
+	}
+	seconds := d.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// write writes the histogram series of name with the given labels ("" for none)
+func (h *histogram) write(w io.Writer, name, labels string) {
+	var cumulative uint64
+	for i, bound := range durationBuckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, labels, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+	braces := ""
+	if labels != "" {
+		braces = "{" + strings.TrimSuffix(labels, ",") + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, braces, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, braces, h.count)
+}
+
+// Write writes all metrics in the Prometheus text exposition format
+func (m *Registry) Write(w io.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	header(w, "xhub_agent_reports_total", "counter", "Report RPCs by RPC and gRPC status code.")
+	keys := make([][2]string, 0, len(m.reports))
+	for key := range m.reports {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || (keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "xhub_agent_reports_total{rpc=%q,code=%q} %d\n", key[0], key[1], m.reports[key])
+	}
+
+	header(w, "xhub_agent_last_successful_report_timestamp_seconds", "gauge",
+		"Unix time of the last report accepted by xhub, 0 before the first.")
+	var lastSuccess float64
+	if !m.lastSuccess.IsZero() {
+		lastSuccess = float64(m.lastSuccess.UnixMilli()) / 1000
+	}
+	fmt.Fprintf(w, "xhub_agent_last_successful_report_timestamp_seconds %s\n", formatFloat(lastSuccess))
+
+	header(w, "xhub_agent_cycle_duration_seconds", "histogram", "Duration of report cycles.")
+	m.cycles.write(w, "xhub_agent_cycle_duration_seconds", "")
+
+	header(w, "xhub_agent_cycle_stage_duration_seconds", "histogram", "Duration of the stages of report cycles.")
+	stages := make([]string, 0, len(m.stages))
+	for stage := range m.stages {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	for _, stage := range stages {
+		m.stages[stage].write(w, "xhub_agent_cycle_stage_duration_seconds", fmt.Sprintf("stage=%q,", stage))
+	}
+
+	header(w, "xhub_agent_xui_request_duration_seconds", "histogram", "Duration of 3x-ui panel requests by endpoint.")
+	endpoints := make([]string, 0, len(m.xuiRequests))
+	for endpoint := range m.xuiRequests {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		m.xuiRequests[endpoint].write(w, "xhub_agent_xui_request_duration_seconds", fmt.Sprintf("endpoint=%q,", endpoint))
+	}
+
+	for _, f := range m.valueFuncs {
+		header(w, f.name, f.kind, f.help)
+		fmt.Fprintf(w, "%s %s\n", f.name, formatFloat(f.value()))
+	}
+}
+
+// ServeHTTP serves the metrics (GET /metrics)
+func (m *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.Write(w)
+}
+
+// header writes the HELP and TYPE lines of a metric
+func header(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// formatFloat formats a sample value
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	m := NewRegistry()
+	m.ObserveReport("SendReport", "OK")
+	m.ObserveReport("SendReport", "OK")
+	m.ObserveReport("SendOnlineUsersReport", "Unavailable")
+	m.ObserveCycle(300 * time.Millisecond)
+	m.ObserveXUIRequest("/login", 20*time.Millisecond)
+	m.ObserveXUIRequest("/login", 2*time.Second)
+	m.ObserveCycleStage("auth", 40*time.Millisecond)
+	depth := 3
+	m.GaugeFunc("xhub_agent_report_queue_depth", "Queued reports.", func() float64 { return float64(depth) })
+	m.CounterFunc("xhub_agent_skipped_ticks_total", "Skipped ticks.", func() float64 { return 2 })
+
+	var out strings.Builder
+	m.Write(&out)
+	text := out.String()
+
+	assert.Contains(t, text, "# TYPE xhub_agent_reports_total counter\n")
+	assert.Contains(t, text, `xhub_agent_reports_total{rpc="SendOnlineUsersReport",code="Unavailable"} 1`+"\n")
+	assert.Contains(t, text, `xhub_agent_reports_total{rpc="SendReport",code="OK"} 2`+"\n")
+	assert.NotContains(t, text, "xhub_agent_last_successful_report_timestamp_seconds 0\n")
+
+	assert.Contains(t, text, `xhub_agent_cycle_duration_seconds_bucket{le="0.25"} 0`+"\n")
+	assert.Contains(t, text, `xhub_agent_cycle_duration_seconds_bucket{le="0.5"} 1`+"\n")
+	assert.Contains(t, text, `xhub_agent_cycle_duration_seconds_bucket{le="+Inf"} 1`+"\n")
+	assert.Contains(t, text, "xhub_agent_cycle_duration_seconds_count 1\n")
+
+	assert.Contains(t, text, `xhub_agent_xui_request_duration_seconds_bucket{endpoint="/login",le="0.025"} 1`+"\n")
+	assert.Contains(t, text, `xhub_agent_xui_request_duration_seconds_bucket{endpoint="/login",le="2.5"} 2`+"\n")
+	assert.Contains(t, text, `xhub_agent_xui_request_duration_seconds_sum{endpoint="/login"} 2.02`+"\n")
+	assert.Contains(t, text, `xhub_agent_xui_request_duration_seconds_count{endpoint="/login"} 2`+"\n")
+
+	assert.Contains(t, text, `xhub_agent_cycle_stage_duration_seconds_bucket{stage="auth",le="0.05"} 1`+"\n")
+	assert.Contains(t, text, `xhub_agent_cycle_stage_duration_seconds_count{stage="auth"} 1`+"\n")
+
+	assert.Contains(t, text, "xhub_agent_report_queue_depth 3\n")
+	assert.Contains(t, text, "# TYPE xhub_agent_skipped_ticks_total counter\nxhub_agent_skipped_ticks_total 2\n")
+	depth = 0
+	out.Reset()
+	m.Write(&out)
+	assert.Contains(t, out.String(), "xhub_agent_report_queue_depth 0\n", "gauges are read when served")
+}
+
+func TestRegistry_Empty(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewRegistry().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "xhub_agent_last_successful_report_timestamp_seconds 0\n")
+	assert.Contains(t, recorder.Body.String(), "xhub_agent_cycle_duration_seconds_count 0\n")
+}
+
+func TestRegistry_Nil(t *testing.T) {
+	var m *Registry
+	assert.NotPanics(t, func() {
+		m.ObserveReport("SendReport", "OK")
+		m.ObserveCycle(time.Second)
+		m.ObserveXUIRequest("/login", time.Second)
+		m.ObserveCycleStage("auth", time.Second)
+		m.GaugeFunc("gauge", "help", func() float64 { return 0 })
+		m.CounterFunc("counter", "help", func() float64 { return 0 })
+	})
+}
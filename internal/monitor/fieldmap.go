@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldMapping remaps alternative JSON keys to the canonical 3x-ui keys of ServerStatusData.
+// Keys are dotted paths relative to the response "obj" (e.g. "memory" or "appStats.memory"),
+// values are the canonical key name at the same level (e.g. "mem").
+type FieldMapping map[string]string
+
+// DefaultPanelProfile is the standard 3x-ui response schema
+const DefaultPanelProfile = "standard"
+
+// panelProfiles are the known fork profiles selectable via xui_panel_profile
+var panelProfiles = map[string]FieldMapping{
+	DefaultPanelProfile: {},
+	// Forks that serialize status fields in snake_case and spell out "memory"
+	"snake_case": {
+		"cpu_cores":        "cpuCores",
+		"logical_pro":      "logicalPro",
+		"cpu_speed_mhz":    "cpuSpeedMhz",
+		"memory":           "mem",
+		"tcp_count":        "tcpCount",
+		"udp_count":        "udpCount",
+		"net_io":           "netIO",
+		"net_traffic":      "netTraffic",
+		"public_ip":        "publicIP",
+		"app_stats":        "appStats",
+		"xray.error_msg":   "errorMsg",
+		"app_stats.memory": "mem",
+	},
+}
+
+// PanelProfiles returns the names of the known panel profiles
+func PanelProfiles() []string {
+	var names []string
+	for name := range panelProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveFieldMapping combines a named profile with custom mappings (custom entries win)
+func ResolveFieldMapping(profile string, custom map[string]string) (FieldMapping, error) {
+	if profile == "" {
+		profile = DefaultPanelProfile
+	}
+
+	base, ok := panelProfiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown xui_panel_profile %q (known: %s)", profile, strings.Join(PanelProfiles(), ", "))
+	}
+
+	mapping := make(FieldMapping, len(base)+len(custom))
+	for k, v := range base {
+		mapping[k] = v
+	}
+	for k, v := range custom {
+		mapping[k] = v
+	}
+	return mapping, nil
+}
+
+// Apply rewrites the keys under "obj" of a panel response body according to the mapping.
+// An empty mapping returns the body unchanged.
+func (m FieldMapping) Apply(body []byte) ([]byte, error) {
+	if len(m) == 0 {
+		return body, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var envelope map[string]interface{}
+	if err := decoder.Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	obj, ok := envelope["obj"].(map[string]interface{})
+	if !ok {
+		return body, nil
+	}
+
+	// Rename nested keys first, so that parent renames move already-fixed children
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return strings.Count(paths[i], ".") > strings.Count(paths[j], ".")
+	})
+
+	for _, path := range paths {
+		renameKey(obj, strings.Split(path, "."), m[path])
+	}
+
+	return json.Marshal(envelope)
+}
+
+// renameKey renames the key at path (alternative names) to canonical
+func renameKey(node map[string]interface{}, path []string, canonical string) {
+	if len(path) == 1 {
+		value, ok := node[path[0]]
+		if !ok {
+			return
+		}
+		// Never overwrite a canonical value that is already present
+		if _, exists := node[canonical]; !exists {
+			node[canonical] = value
+		}
+		delete(node, path[0])
+		return
+	}
+
+	child, ok := node[path[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	renameKey(child, path[1:], canonical)
+}
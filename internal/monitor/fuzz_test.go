@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"xhub-agent/internal/sanitize"
+)
+
+// statusFixture is the standard /server/status fixture used as fuzz seed
+const statusFixture = `{"success":true,"msg":"","obj":{"cpu":4.02,"cpuCores":1,"logicalPro":1,"cpuSpeedMhz":1996.249,` +
+	`"mem":{"current":131829760,"total":498667520},"swap":{"current":0,"total":0},` +
+	`"disk":{"current":1316593664,"total":29416628224},"xray":{"state":"running","errorMsg":"","version":"25.8.3"},` +
+	`"uptime":269583,"loads":[0.08,0.03,0.01],"tcpCount":412,"udpCount":5,"netIO":{"up":94682,"down":101147},` +
+	`"netTraffic":{"sent":14558825693,"recv":15208860756},"publicIP":{"ipv4":"31.57.172.16","ipv6":"2a12:bec0:689:1154::"},` +
+	`"appStats":{"threads":73,"mem":53761288,"uptime":226013}}}`
+
+// forkStatusFixture is the snake_case fork fixture used as fuzz seed
+const forkStatusFixture = `{"success":true,"obj":{"cpu":12.5,"cpu_cores":4,"memory":{"current":1,"total":2},` +
+	`"xray":{"state":"running","error_msg":"","version":"1.8.0"},"app_stats":{"threads":10,"memory":100,"uptime":50}}}`
+
+func FuzzDecodeServerStatus(f *testing.F) {
+	f.Add([]byte(statusFixture), false)
+	f.Add([]byte(forkStatusFixture), true)
+	f.Add([]byte(`{"success":true,"obj":{"uptime":99999999999999999999}}`), false)
+	f.Add([]byte(`{"success":true,"obj":{"xray":{"errorMsg":"\xff\xfe"}}}`), false)
+	f.Add([]byte(`{"success":true,"obj":null}`), false)
+	f.Add([]byte(`{"success":true,"obj":{"app_stats":{"memory":{"memory":1}}}}`), true)
+	f.Add([]byte(strings.Repeat(`{"obj":`, 100)), false)
+
+	snakeCase, err := ResolveFieldMapping("snake_case", nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte, fork bool) {
+		mapping := FieldMapping(nil)
+		if fork {
+			mapping = snakeCase
+		}
+
+		resp, err := DecodeServerStatus(body, mapping)
+		if len(body) > sanitize.MaxResponseSize && err == nil {
+			t.Fatalf("oversized body accepted")
+		}
+		if err != nil {
+			return
+		}
+
+		d := resp.Data
+		for _, s := range []string{d.Xray.State, d.Xray.ErrorMsg, d.Xray.Version, d.PublicIP.IPv4, d.PublicIP.IPv6} {
+			if !utf8.ValidString(s) {
+				t.Fatalf("invalid UTF-8 passed onward: %q", s)
+			}
+		}
+	})
+}
+
+func FuzzDecodeOnlineUsers(f *testing.F) {
+	f.Add([]byte(`{"success":true,"msg":"","obj":["user1@example.com","user2@example.com"]}`))
+	f.Add([]byte(`{"success":true,"obj":null}`))
+	f.Add([]byte(`{"success":true,"obj":["\xff"]}`))
+	f.Add([]byte(`{"success":false,"msg":"\xc3\x28"}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		resp, err := DecodeOnlineUsers(body)
+		if err != nil {
+			return
+		}
+		for _, email := range resp.Data {
+			if !utf8.ValidString(email) {
+				t.Fatalf("invalid UTF-8 passed onward: %q", email)
+			}
+		}
+	})
+}
+
+func FuzzDecodeClientIPs(f *testing.F) {
+	f.Add([]byte(`{"success":true,"obj":"[\"203.0.113.7 (2024-05-01 12:00:00)\"]"}`))
+	f.Add([]byte(`{"success":true,"obj":"No IP Record"}`))
+	f.Add([]byte(`{"success":true,"obj":"[` + strings.Repeat(`[`, 100) + `"}`))
+	f.Add([]byte(`{"success":true,"obj":["\xff"]}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		ips, err := DecodeClientIPs(body)
+		if err != nil {
+			return
+		}
+		if len(ips) > MaxIPsPerUser {
+			t.Fatalf("%d IPs passed onward", len(ips))
+		}
+		for _, ip := range ips {
+			if !utf8.ValidString(ip.IP) {
+				t.Fatalf("invalid UTF-8 passed onward: %q", ip.IP)
+			}
+		}
+	})
+}
+
+func TestDecodeServerStatus_Hardening(t *testing.T) {
+	// Deep nesting is rejected before decoding
+	deep := `{"success":true,"obj":{"xray":` + strings.Repeat(`[`, 100) + strings.Repeat(`]`, 100) + `}}`
+	if _, err := DecodeServerStatus([]byte(deep), nil); err == nil {
+		t.Fatal("deeply nested JSON accepted")
+	}
+
+	// success without obj is an error, not a nil Data passed onward
+	if _, err := DecodeServerStatus([]byte(`{"success":true}`), nil); err == nil {
+		t.Fatal("missing obj accepted")
+	}
+
+	// Invalid UTF-8 in strings is replaced
+	resp, err := DecodeServerStatus([]byte("{\"success\":true,\"obj\":{\"xray\":{\"errorMsg\":\"bad\xffmsg\"}}}"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.Xray.ErrorMsg != "bad\uFFFDmsg" {
+		t.Fatalf("unexpected sanitized message %q", resp.Data.Xray.ErrorMsg)
+	}
+}
@@ -0,0 +1,251 @@
+package monitor
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// XrayStateUnreachable is the Xray state reported with host metrics collected while the
+// 3x-ui panel is unavailable
+const XrayStateUnreachable = "unreachable"
+
+// HostCollector reads host health directly from procfs as a fallback when /server/status
+// fails, so the node keeps reporting CPU, memory, disk, load, uptime and traffic
+type HostCollector struct {
+	procRoot  string        // procfs mount, "/proc" outside tests
+	diskPath  string        // Filesystem reported as disk usage
+	sampleCPU time.Duration // CPU sampling window of the first collection
+
+	mutex    sync.Mutex
+	lastCPU  cpuTimes   // Previous /proc/stat sample, for usage between collections
+	lastNet  NetTraffic // Previous traffic counters, for the IO rate
+	lastTime time.Time  // Time of the previous collection
+}
+
+// cpuTimes are the aggregated jiffies of the "cpu" line of /proc/stat
+type cpuTimes struct {
+	idle, total uint64
+}
+
+// NewHostCollector creates a collector reading procRoot and the disk usage of diskPath
+func NewHostCollector(procRoot, diskPath string) *HostCollector {
+	return &HostCollector{procRoot: procRoot, diskPath: diskPath, sampleCPU: 250 * time.Millisecond}
+}
+
+// Collect returns the host status with Xray marked unreachable and errorMsg as its error
+// message. Values that cannot be read are left zero.
+func (h *HostCollector) Collect(errorMsg string) *ServerStatusData {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	data := &ServerStatusData{
+		LogicalPro: runtime.NumCPU(),
+		Xray:       XrayInfo{State: XrayStateUnreachable, ErrorMsg: errorMsg},
+	}
+
+	data.CPU = h.cpuUsage()
+	data.CPUCores, data.CPUSpeedMhz = h.cpuInfo()
+	if data.CPUCores == 0 {
+		data.CPUCores = data.LogicalPro
+	}
+	data.Memory, data.Swap = h.memory()
+	data.Disk = h.disk()
+	data.Uptime = h.uptime()
+	data.Loads = h.loads()
+	data.TCPCount = h.countSockets("tcp") + h.countSockets("tcp6")
+	data.UDPCount = h.countSockets("udp") + h.countSockets("udp6")
+
+	now := time.Now()
+	data.NetTraffic = h.traffic()
+	if !h.lastTime.IsZero() {
+		if seconds := now.Sub(h.lastTime).Seconds(); seconds > 0 {
+			data.NetIO.Up = max(0, int64(float64(data.NetTraffic.Sent-h.lastNet.Sent)/seconds))
+			data.NetIO.Down = max(0, int64(float64(data.NetTraffic.Recv-h.lastNet.Recv)/seconds))
+		}
+	}
+	h.lastNet, h.lastTime = data.NetTraffic, now
+	return data
+}
+
+// readFields returns the whitespace-separated fields of each line of a procfs file
+func (h *HostCollector) readFields(name string) [][]string {
+	file, err := os.Open(filepath.Join(h.procRoot, name))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines [][]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, strings.Fields(scanner.Text()))
+	}
+	return lines
+}
+
+// readCPUTimes reads the aggregated CPU times
+func (h *HostCollector) readCPUTimes() (cpuTimes, bool) {
+	for _, fields := range h.readFields("stat") {
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		var times cpuTimes
+		for i, field := range fields[1:] {
+			value, _ := strconv.ParseUint(field, 10, 64)
+			// user nice system idle iowait irq softirq steal; guest time is included in user
+			if i >= 8 {
+				break
+			}
+			times.total += value
+			if i == 3 || i == 4 {
+				times.idle += value
+			}
+		}
+		return times, true
+	}
+	return cpuTimes{}, false
+}
+
+// cpuUsage returns the CPU usage in percent since the previous collection, or over the
+// sampling window on the first one
+func (h *HostCollector) cpuUsage() float64 {
+	previous := h.lastCPU
+	if previous.total == 0 {
+		var ok bool
+		if previous, ok = h.readCPUTimes(); !ok {
+			return 0
+		}
+		time.Sleep(h.sampleCPU)
+	}
+	current, ok := h.readCPUTimes()
+	if !ok {
+		return 0
+	}
+	h.lastCPU = current
+	if current.total <= previous.total {
+		return 0
+	}
+	busy := float64((current.total - previous.total) - (current.idle - previous.idle))
+	return busy * 100 / float64(current.total-previous.total)
+}
+
+// cpuInfo returns the physical core count and the frequency of the first CPU
+func (h *HostCollector) cpuInfo() (cores int, mhz float64) {
+	physicalCores := make(map[string]bool)
+	physicalID := ""
+	for _, line := range h.readLines("cpuinfo") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "physical id":
+			physicalID = value
+		case "core id":
+			physicalCores[physicalID+"/"+value] = true
+		case "cpu MHz":
+			if mhz == 0 {
+				mhz, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+	}
+	return len(physicalCores), mhz
+}
+
+// readLines returns the lines of a procfs file
+func (h *HostCollector) readLines(name string) []string {
+	content, err := os.ReadFile(filepath.Join(h.procRoot, name))
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(content), "\n")
+}
+
+// memory returns the used and total memory and swap in bytes
+func (h *HostCollector) memory() (MemoryInfo, SwapInfo) {
+	values := make(map[string]int64)
+	for _, fields := range h.readFields("meminfo") {
+		if len(fields) >= 2 {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			values[strings.TrimSuffix(fields[0], ":")] = kb * 1024
+		}
+	}
+	return MemoryInfo{Current: values["MemTotal"] - values["MemAvailable"], Total: values["MemTotal"]},
+		SwapInfo{Current: values["SwapTotal"] - values["SwapFree"], Total: values["SwapTotal"]}
+}
+
+// disk returns the used and total bytes of the disk path's filesystem
+func (h *HostCollector) disk() DiskInfo {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(h.diskPath, &stat); err != nil {
+		return DiskInfo{}
+	}
+	blockSize := int64(stat.Bsize)
+	return DiskInfo{
+		Current: int64(stat.Blocks-stat.Bfree) * blockSize,
+		Total:   int64(stat.Blocks) * blockSize,
+	}
+}
+
+// uptime returns the host uptime in seconds
+func (h *HostCollector) uptime() int {
+	fields := h.readFields("uptime")
+	if len(fields) == 0 || len(fields[0]) == 0 {
+		return 0
+	}
+	seconds, _ := strconv.ParseFloat(fields[0][0], 64)
+	return int(seconds)
+}
+
+// loads returns the 1, 5 and 15 minute load averages
+func (h *HostCollector) loads() []float64 {
+	fields := h.readFields("loadavg")
+	if len(fields) == 0 || len(fields[0]) < 3 {
+		return nil
+	}
+	loads := make([]float64, 3)
+	for i := range loads {
+		loads[i], _ = strconv.ParseFloat(fields[0][i], 64)
+	}
+	return loads
+}
+
+// countSockets counts the sockets listed in /proc/net/<protocol>
+func (h *HostCollector) countSockets(protocol string) int {
+	lines := h.readFields(filepath.Join("net", protocol))
+	if len(lines) <= 1 {
+		return 0
+	}
+	return len(lines) - 1 // Header line
+}
+
+// traffic returns the bytes sent and received by all interfaces except loopback
+func (h *HostCollector) traffic() NetTraffic {
+	var traffic NetTraffic
+	for _, line := range h.readLines(filepath.Join("net", "dev")) {
+		name, counters, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		recv, errRecv := strconv.ParseInt(fields[0], 10, 64)
+		sent, errSent := strconv.ParseInt(fields[8], 10, 64)
+		if errRecv != nil || errSent != nil {
+			continue
+		}
+		traffic.Recv += recv
+		traffic.Sent += sent
+	}
+	return traffic
+}
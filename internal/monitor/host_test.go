@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProcFiles writes procfs files (relative path -> content) under a temporary root
+func writeProcFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return root
+}
+
+const testNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    5000      10    0    0    0     0          0         0     5000      10    0    0    0     0       0          0
+  eth0: %d     100    0    0    0     0          0         0   %d     200    0    0    0     0       0          0
+`
+
+// sprintfNetDev returns /proc/net/dev with eth0 counters
+func sprintfNetDev(recv, sent int) string {
+	return fmt.Sprintf(testNetDev, recv, sent)
+}
+
+func TestHostCollector_Collect(t *testing.T) {
+	root := writeProcFiles(t, map[string]string{
+		"stat":     "cpu  100 0 100 800 0 0 0 0 0 0\ncpu0 100 0 100 800 0 0 0 0 0 0\n",
+		"cpuinfo":  "processor\t: 0\nphysical id\t: 0\ncore id\t\t: 0\ncpu MHz\t\t: 2400.000\n\nprocessor\t: 1\nphysical id\t: 0\ncore id\t\t: 0\ncpu MHz\t\t: 2400.000\n\nprocessor\t: 2\nphysical id\t: 0\ncore id\t\t: 1\ncpu MHz\t\t: 1800.000\n",
+		"meminfo":  "MemTotal:       2048 kB\nMemFree:         512 kB\nMemAvailable:   1024 kB\nSwapTotal:      1000 kB\nSwapFree:        600 kB\n",
+		"uptime":   "3600.55 7000.00\n",
+		"loadavg":  "0.50 0.25 0.10 1/100 1234\n",
+		"net/tcp":  "  sl  local_address rem_address   st\n   0: 00000000:0016 00000000:0000 0A\n   1: 00000000:0050 00000000:0000 0A\n",
+		"net/tcp6": "  sl  local_address rem_address   st\n   0: 00000000000000000000000000000000:0016 00000000000000000000000000000000:0000 0A\n",
+		"net/udp":  "  sl  local_address rem_address   st\n",
+		"net/dev":  sprintfNetDev(1000, 2000),
+	})
+	collector := NewHostCollector(root, t.TempDir())
+	collector.sampleCPU = 0
+
+	data := collector.Collect("3x-ui unavailable (panel_timeout)")
+	assert.Equal(t, XrayInfo{State: XrayStateUnreachable, ErrorMsg: "3x-ui unavailable (panel_timeout)"}, data.Xray)
+	assert.Equal(t, 2, data.CPUCores)
+	assert.Equal(t, 2400.0, data.CPUSpeedMhz)
+	assert.Positive(t, data.LogicalPro)
+	assert.Equal(t, MemoryInfo{Current: 1024 * 1024, Total: 2048 * 1024}, data.Memory)
+	assert.Equal(t, SwapInfo{Current: 400 * 1024, Total: 1000 * 1024}, data.Swap)
+	assert.Positive(t, data.Disk.Total)
+	assert.Equal(t, 3600, data.Uptime)
+	assert.Equal(t, []float64{0.5, 0.25, 0.1}, data.Loads)
+	assert.Equal(t, 3, data.TCPCount)
+	assert.Equal(t, 0, data.UDPCount)
+	assert.Equal(t, NetTraffic{Sent: 2000, Recv: 1000}, data.NetTraffic, "loopback is excluded")
+	assert.Zero(t, data.NetIO, "no rate before a previous collection")
+
+	// The next collection measures CPU usage and traffic since this one
+	require.NoError(t, os.WriteFile(filepath.Join(root, "stat"), []byte("cpu  200 0 200 900 0 0 0 0 0 0\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "net/dev"), []byte(sprintfNetDev(5000, 6000)), 0644))
+	data = collector.Collect("")
+	assert.InDelta(t, 66.67, data.CPU, 0.01)
+	assert.Positive(t, data.NetIO.Up)
+	assert.Positive(t, data.NetIO.Down)
+}
+
+func TestHostCollector_MissingProc(t *testing.T) {
+	collector := NewHostCollector(filepath.Join(t.TempDir(), "missing"), filepath.Join(t.TempDir(), "missing"))
+	collector.sampleCPU = 0
+
+	data := collector.Collect("")
+	assert.Equal(t, XrayStateUnreachable, data.Xray.State)
+	assert.Zero(t, data.CPU)
+	assert.Zero(t, data.Memory)
+	assert.Zero(t, data.Disk)
+	assert.Nil(t, data.Loads)
+	assert.Equal(t, data.LogicalPro, data.CPUCores, "cores default to the logical processors")
+}
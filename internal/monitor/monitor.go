@@ -1,22 +1,31 @@
 package monitor
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
-	"time"
+	"slices"
+	"strings"
+	"sync"
 
 	"xhub-agent/internal/auth"
+	"xhub-agent/internal/sanitize"
 	"xhub-agent/pkg/logger"
 )
 
 // MonitorClient monitoring data client
 type MonitorClient struct {
-	auth   *auth.XUIAuth
-	client *http.Client
-	logger *logger.Logger
+	auth         *auth.XUIAuth // Also sends the panel requests, with its shared client
+	logger       *logger.Logger
+	fieldMapping FieldMapping      // Key remapping for forked panel schemas
+	userDetails  UserDetailsSource // Connection details of online users (online_user_details)
+	accessLog    string            // Xray access log read by the access_log source
+
+	schemaMutex    sync.Mutex
+	panelVersion   PanelVersion // Detected or pinned 3x-ui release, zero when unknown
+	schemaDetected bool         // Version pinned or panel page already read
+	warnedMissing  string       // Fields of the layout last reported missing
 }
 
 // ServerStatusResponse server status response structure
@@ -44,6 +53,24 @@ type ServerStatusData struct {
 	PublicIP    PublicIPInfo `json:"publicIP"`    // Public IP information
 	Xray        XrayInfo     `json:"xray"`        // Xray status
 	AppStats    AppStats     `json:"appStats"`    // Application status
+
+	// Decoding outcome (not part of the 3x-ui response)
+	PanelVersion string   `json:"panelVersion,omitempty"` // Detected or pinned 3x-ui release
+	Unreported   []string `json:"unreported,omitempty"`   // Status keys missing from the response, left zero
+
+	// Agent-side collected data (not part of the 3x-ui response)
+	PortListeners    []PortListener       `json:"portListeners,omitempty"`    // Listener process per inbound port
+	InboundProtocols []string             `json:"inboundProtocols,omitempty"` // Protocols configured on enabled inbounds
+	Fail2banBans     map[string]int       `json:"fail2banBans,omitempty"`     // Currently banned IPs per fail2ban jail
+	CertExpiry       []CertExpiry         `json:"certExpiry,omitempty"`       // Expiry of certificate files referenced by configs
+	DNSChecks        []DNSCheck           `json:"dnsChecks,omitempty"`        // DNS health of the domains users connect to
+	SelfTest         *SelfTestStatus      `json:"selfTest,omitempty"`         // Outcome of the last pipeline self-test
+	DiskIO           *DiskIOStats         `json:"diskIO,omitempty"`           // IO rates of the primary disk
+	DiskSMART        *DiskSMART           `json:"diskSMART,omitempty"`        // SMART health of the primary disk
+	Pressure         *HostPressure        `json:"pressure,omitempty"`         // Pressure stall and thermal zone readings
+	WireGuard        []WireGuardInterface `json:"wireguard,omitempty"`        // WireGuard interfaces and their peers
+	XrayRestart      *XrayRestart         `json:"xrayRestart,omitempty"`      // Xray restart since the previous report
+	Clock            *ClockStatus         `json:"clock,omitempty"`            // System clock offset against NTP
 }
 
 // MemoryInfo memory information
@@ -89,6 +116,36 @@ type PublicIPInfo struct {
 	IPv6 string `json:"ipv6"` // IPv6 address
 }
 
+// IPStack is the IP-stack capability of a node derived from its public IPs
+type IPStack string
+
+const (
+	IPStackUnknown   IPStack = "unknown"
+	IPStackIPv4Only  IPStack = "ipv4-only"
+	IPStackIPv6Only  IPStack = "ipv6-only"
+	IPStackDualStack IPStack = "dual-stack"
+)
+
+// Stack classifies the node by the public IPs that are valid addresses of their family.
+// Placeholders such as "N/A" (3x-ui could not discover the address) count as absent.
+func (p PublicIPInfo) Stack() IPStack {
+	ip4 := net.ParseIP(strings.TrimSpace(p.IPv4))
+	ip6 := net.ParseIP(strings.TrimSpace(p.IPv6))
+	hasV4 := ip4 != nil && ip4.To4() != nil
+	hasV6 := ip6 != nil && ip6.To4() == nil
+
+	switch {
+	case hasV4 && hasV6:
+		return IPStackDualStack
+	case hasV4:
+		return IPStackIPv4Only
+	case hasV6:
+		return IPStackIPv6Only
+	default:
+		return IPStackUnknown
+	}
+}
+
 // AppStats application status information
 type AppStats struct {
 	Threads int   `json:"threads"` // Thread count
@@ -96,6 +153,127 @@ type AppStats struct {
 	Uptime  int   `json:"uptime"`  // Application uptime
 }
 
+// PortListener describes which local process listens on an inbound port
+type PortListener struct {
+	Port            int    `json:"port"`            // Inbound port
+	ListenerProcess string `json:"listenerProcess"` // Process owning the listening socket
+	IsXray          bool   `json:"isXray"`          // Listener is xray itself
+	IsFrontend      bool   `json:"isFrontend"`      // Listener is another process (nginx stream, sing-box, haproxy ...)
+	Unknown         bool   `json:"unknown"`         // Owner could not be determined (e.g. insufficient privileges)
+	ListenerLost    bool   `json:"listenerLost"`    // A previously-frontended port has no listener anymore
+}
+
+// CertExpiry describes a certificate file referenced by the Hysteria2 or an inbound TLS config
+type CertExpiry struct {
+	Path     string `json:"path"`               // Certificate file
+	Origin   string `json:"origin"`             // Referencing config ("hysteria2", "inbound:<port>")
+	Subject  string `json:"subject,omitempty"`  // Certificate common name
+	NotAfter int64  `json:"notAfter,omitempty"` // Expiry (unix seconds), 0 when unreadable
+	Error    string `json:"error,omitempty"`    // Why the file could not be read or parsed
+}
+
+// DNSCheck is the DNS health of a domain users connect to
+type DNSCheck struct {
+	Domain               string   `json:"domain"`
+	Resolves             bool     `json:"resolves"`             // At least one resolver returned addresses
+	PointsHere           bool     `json:"pointsHere"`           // An address users see is a public IP of this node
+	ResolvedIPs          []string `json:"resolvedIPs"`          // Addresses users see (external resolver when it answers)
+	ResolverDisagreement bool     `json:"resolverDisagreement"` // System and external resolvers differ (e.g. split horizon)
+	Error                string   `json:"error,omitempty"`      // Resolution failure
+}
+
+// DiskIOStats are the IO rates of a disk between two samples
+type DiskIOStats struct {
+	Device           string  `json:"device"`
+	ReadBytesPerSec  float64 `json:"readBytesPerSec"`
+	WriteBytesPerSec float64 `json:"writeBytesPerSec"`
+	ReadIOPS         float64 `json:"readIOPS"`
+	WriteIOPS        float64 `json:"writeIOPS"`
+	Utilization      float64 `json:"utilization"` // Percentage of the time the disk was busy
+	AwaitMs          float64 `json:"awaitMs"`     // Average time per completed IO, queueing included
+	IntervalMs       int64   `json:"intervalMs"`  // Duration of the sampled window
+}
+
+// DiskSMART is the basic SMART health of a disk
+type DiskSMART struct {
+	Device             string `json:"device"`
+	Available          bool   `json:"available"` // smartctl returned SMART data
+	Passed             bool   `json:"passed"`    // Overall health self-assessment
+	Model              string `json:"model,omitempty"`
+	TemperatureCelsius int    `json:"temperatureCelsius,omitempty"`
+	PowerOnHours       int64  `json:"powerOnHours,omitempty"`
+	PercentageUsed     int    `json:"percentageUsed,omitempty"`     // NVMe wear estimate
+	ReallocatedSectors int64  `json:"reallocatedSectors,omitempty"` // ATA attribute 5
+	Error              string `json:"error,omitempty"`              // Why no SMART data is available
+}
+
+// HostPressure is the pressure stall information (PSI) and thermal zone temperatures of the host
+type HostPressure struct {
+	CPU          *PressureStall `json:"cpu,omitempty"` // nil when the kernel has no PSI
+	Memory       *PressureStall `json:"memory,omitempty"`
+	IO           *PressureStall `json:"io,omitempty"`
+	ThermalZones []ThermalZone  `json:"thermalZones,omitempty"`
+}
+
+// ClockStatus is the offset of the system clock against NTP and the state of the NTP daemon
+type ClockStatus struct {
+	OffsetMs     float64 `json:"offsetMs"`              // System clock minus NTP time, positive when ahead
+	OffsetSource string  `json:"offsetSource"`          // "sntp" or "chrony", empty when no offset was measured
+	RoundTripMs  float64 `json:"roundTripMs,omitempty"` // Of the SNTP query
+	Server       string  `json:"server,omitempty"`      // NTP server the offset was measured against
+	Synchronized bool    `json:"synchronized"`          // The NTP daemon reports the clock synchronized
+	NTPService   string  `json:"ntpService,omitempty"`  // "chronyd" or "systemd-timesyncd", empty when none runs
+	Error        string  `json:"error,omitempty"`       // Why the offset could not be measured
+}
+
+// PressureStall is the share of time tasks stalled on a resource, in percent
+type PressureStall struct {
+	SomeAvg10   float64 `json:"someAvg10"` // At least one task stalled
+	SomeAvg60   float64 `json:"someAvg60"`
+	SomeAvg300  float64 `json:"someAvg300"`
+	FullAvg10   float64 `json:"fullAvg10"` // All non-idle tasks stalled
+	FullAvg60   float64 `json:"fullAvg60"`
+	FullAvg300  float64 `json:"fullAvg300"`
+	SomeTotalUs uint64  `json:"someTotalUs"` // Stall time since boot
+	FullTotalUs uint64  `json:"fullTotalUs"`
+}
+
+// ThermalZone is the temperature of a kernel thermal zone
+type ThermalZone struct {
+	Zone               string  `json:"zone"` // e.g. "thermal_zone0"
+	Type               string  `json:"type"` // Sensor, e.g. "x86_pkg_temp"
+	TemperatureCelsius float64 `json:"temperatureCelsius"`
+}
+
+// WireGuardInterface is a WireGuard interface of the host and its peers
+type WireGuardInterface struct {
+	Name        string          `json:"name"` // e.g. "wg0"
+	PublicKey   string          `json:"publicKey"`
+	ListenPort  int             `json:"listenPort"`
+	Peers       int             `json:"peers"`       // Configured peers
+	ActivePeers int             `json:"activePeers"` // Peers with a recent handshake
+	RxBytes     uint64          `json:"rxBytes"`     // Received from all peers
+	TxBytes     uint64          `json:"txBytes"`     // Sent to all peers
+	PeerStats   []WireGuardPeer `json:"peerStats,omitempty"`
+}
+
+// WireGuardPeer is the handshake freshness and transfer of a WireGuard peer
+type WireGuardPeer struct {
+	PublicKey       string `json:"publicKey"`
+	LatestHandshake int64  `json:"latestHandshake"` // Unix seconds, 0 when never
+	RxBytes         uint64 `json:"rxBytes"`
+	TxBytes         uint64 `json:"txBytes"`
+}
+
+// SelfTestStatus is the outcome of the last pipeline self-test
+type SelfTestStatus struct {
+	LastRun    int64    `json:"lastRun"`            // Unix seconds
+	Passed     bool     `json:"passed"`             // Every invariant held
+	Failures   []string `json:"failures,omitempty"` // Invariant violations of the last run
+	Runs       uint64   `json:"runs"`               // Runs since agent start
+	FailedRuns uint64   `json:"failedRuns"`         // Failed runs since agent start
+}
+
 // OnlineUsersResponse online users API response structure
 type OnlineUsersResponse struct {
 	Success bool     `json:"success"`
@@ -108,73 +286,156 @@ func NewMonitorClient(authClient *auth.XUIAuth, logger *logger.Logger) *MonitorC
 	return &MonitorClient{
 		auth:   authClient,
 		logger: logger,
-		client: &http.Client{
-			Timeout: 30 * time.Second, // 30 second timeout
-			// Skip HTTPS certificate verification (since 3x-ui usually uses self-signed certificates)
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		},
 	}
 }
 
-// GetServerStatus gets server status
-func (m *MonitorClient) GetServerStatus() (*ServerStatusResponse, error) {
-	// Check authentication status
-	if !m.auth.IsAuthenticated() {
-		return nil, fmt.Errorf("not authenticated, please login first")
-	}
+// SetFieldMapping sets the key remapping applied to status responses of non-standard panels
+func (m *MonitorClient) SetFieldMapping(mapping FieldMapping) {
+	m.fieldMapping = mapping
+}
 
-	// Create authenticated request
-	req, err := m.auth.GetAuthenticatedRequest("POST", "/server/status", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+// FieldMapping returns the field mapping applied to the server status
+func (m *MonitorClient) FieldMapping() FieldMapping {
+	return m.fieldMapping
+}
 
+// GetServerStatus gets server status
+func (m *MonitorClient) GetServerStatus() (*ServerStatusResponse, error) {
 	// Send request
-	resp, err := m.client.Do(req)
+	resp, err := m.auth.Post("/server/status")
 	if err != nil {
 		return nil, fmt.Errorf("failed to request server status: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check HTTP status code
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("not authenticated, session may have expired")
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed, HTTP status code: %d", resp.StatusCode)
+		return nil, &auth.StatusError{StatusCode: resp.StatusCode}
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	// Read response body (size capped)
+	body, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Print raw response body for debugging
-	m.logger.Debugf("3x-ui server status response body: %s", string(body))
+	m.logger.Debugf("3x-ui server status response body: %s", sanitize.String(string(body)))
+
+	version := m.PanelVersion()
+	var schema *StatusSchema
+	if !version.IsZero() {
+		schema = SchemaFor(version)
+	}
+	statusResp, schema, err := decodeServerStatus(body, m.fieldMapping, schema)
+	if err != nil {
+		return nil, err
+	}
+	if !version.IsZero() {
+		statusResp.Data.PanelVersion = version.String()
+	}
+	m.warnMissingFields(schema, statusResp.Data.Unreported)
+	return statusResp, nil
+}
+
+// warnMissingFields warns about fields the panel layout has but the response lacks, once
+// per distinct set. Fields the release never reports are expected to be missing.
+func (m *MonitorClient) warnMissingFields(schema *StatusSchema, unreported []string) {
+	var missing []string
+	for _, field := range unreported {
+		if slices.Contains(schema.Fields, field) {
+			missing = append(missing, field)
+		}
+	}
+	key := strings.Join(missing, ",")
+
+	m.schemaMutex.Lock()
+	defer m.schemaMutex.Unlock()
+	if key == m.warnedMissing {
+		return
+	}
+	m.warnedMissing = key
+	if key != "" {
+		m.logger.Warnf("⚠️ 3x-ui status lacks fields of the %s layout, reporting them as unreported: %s", schema.Name, key)
+	}
+}
+
+// DecodeServerStatus decodes a /server/status response body, inferring the panel layout from
+// it. Fork field names are remapped first; strings in the result are valid UTF-8.
+func DecodeServerStatus(body []byte, mapping FieldMapping) (*ServerStatusResponse, error) {
+	statusResp, _, err := decodeServerStatus(body, mapping, nil)
+	return statusResp, err
+}
+
+// decodeServerStatus decodes a /server/status response body with the layout of the panel
+// release (nil infers it) and returns the layout used
+func decodeServerStatus(body []byte, mapping FieldMapping, schema *StatusSchema) (*ServerStatusResponse, *StatusSchema, error) {
+	if err := sanitize.CheckJSON(body); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// Remap fork-specific field names to the standard schema
+	body, err := mapping.Apply(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// Convert the encodings of older releases and note the fields left zero
+	envelope, obj, err := schemaObj(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	var unreported []string
+	if obj != nil {
+		if schema == nil {
+			schema = inferSchema(obj)
+		}
+		if schema.normalize != nil {
+			schema.normalize(obj)
+			if body, err = json.Marshal(envelope); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse response: %w", err)
+			}
+		}
+		unreported = unreportedFields(obj)
+	}
+	if schema == nil {
+		schema = SchemaFor(PanelVersion{})
+	}
 
 	// Parse response
 	var statusResp ServerStatusResponse
 	if err := json.Unmarshal(body, &statusResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	// Check if API response is successful
 	if !statusResp.Success {
-		return nil, fmt.Errorf("API error: %s", statusResp.Message)
+		return nil, nil, fmt.Errorf("API error: %s", sanitize.String(statusResp.Message))
+	}
+
+	if statusResp.Data == nil {
+		return nil, nil, fmt.Errorf("failed to parse response: missing obj")
 	}
+	statusResp.Data.Unreported = unreported
+	statusResp.Data.sanitize()
+
+	return &statusResp, schema, nil
+}
 
-	return &statusResp, nil
+// sanitize makes all panel-provided strings valid UTF-8
+func (d *ServerStatusData) sanitize() {
+	d.PublicIP.IPv4 = sanitize.String(d.PublicIP.IPv4)
+	d.PublicIP.IPv6 = sanitize.String(d.PublicIP.IPv6)
+	d.Xray.State = sanitize.String(d.Xray.State)
+	d.Xray.ErrorMsg = sanitize.String(d.Xray.ErrorMsg)
+	d.Xray.Version = sanitize.String(d.Xray.Version)
 }
 
 // GetOnlineUsers gets online users from 3x-ui panel
 func (m *MonitorClient) GetOnlineUsers() (*OnlineUsersResponse, error) {
 	// Check authentication status
 	if !m.auth.IsAuthenticated() {
-		return nil, fmt.Errorf("not authenticated, please login first")
+		return nil, fmt.Errorf("%w: please login first", auth.ErrAuthExpired)
 	}
 
 	// Create authenticated request
@@ -189,29 +450,35 @@ func (m *MonitorClient) GetOnlineUsers() (*OnlineUsersResponse, error) {
 	req.Header.Set("X-Requested-With", "XMLHttpRequest")
 
 	// Send request
-	resp, err := m.client.Do(req)
+	resp, err := m.auth.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to request online users: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check HTTP status code
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("not authenticated, session may have expired")
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed, HTTP status code: %d", resp.StatusCode)
+		return nil, &auth.StatusError{StatusCode: resp.StatusCode}
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	// Read response body (size capped)
+	body, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Print raw response body for debugging
-	m.logger.Debugf("3x-ui online users response body: %s", string(body))
+	m.logger.Debugf("3x-ui online users response body: %s", sanitize.String(string(body)))
+
+	return DecodeOnlineUsers(body)
+}
+
+// DecodeOnlineUsers decodes a /panel/inbound/onlines response body.
+// Emails in the result are valid UTF-8.
+func DecodeOnlineUsers(body []byte) (*OnlineUsersResponse, error) {
+	if err := sanitize.CheckJSON(body); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
 
 	// Parse response
 	var onlineResp OnlineUsersResponse
@@ -221,8 +488,9 @@ func (m *MonitorClient) GetOnlineUsers() (*OnlineUsersResponse, error) {
 
 	// Check if API response is successful
 	if !onlineResp.Success {
-		return nil, fmt.Errorf("API error: %s", onlineResp.Message)
+		return nil, fmt.Errorf("API error: %s", sanitize.String(onlineResp.Message))
 	}
 
+	onlineResp.Data = sanitize.Strings(onlineResp.Data)
 	return &onlineResp, nil
 }
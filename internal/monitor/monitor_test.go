@@ -230,3 +230,91 @@ func TestMonitorClient_GetServerStatus_APIError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "获取服务器状态失败")
 }
+
+func TestMonitorClient_GetServerStatus_ForkProfile(t *testing.T) {
+	// Fork that uses snake_case keys and "memory" instead of "mem"
+	forkResponse := `{
+		"success": true,
+		"msg": "",
+		"obj": {
+			"cpu": 12.5,
+			"cpu_cores": 4,
+			"logical_pro": 8,
+			"memory": {"current": 1024, "total": 4096},
+			"tcp_count": 99,
+			"net_io": {"up": 10, "down": 20},
+			"public_ip": {"ipv4": "203.0.113.7", "ipv6": ""},
+			"xray": {"state": "running", "error_msg": "none", "version": "25.8.3"},
+			"app_stats": {"threads": 7, "memory": 2048, "uptime": 60}
+		}
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(forkResponse))
+	}))
+	defer server.Close()
+
+	authClient := auth.NewXUIAuth(server.URL, "admin", "password123")
+	authClient.SetSessionForTesting("test-session-token")
+
+	monitorClient := NewMonitorClient(authClient, createTestLogger(t))
+	mapping, err := ResolveFieldMapping("snake_case", map[string]string{"tcp_count": "tcpCount"})
+	require.NoError(t, err)
+	monitorClient.SetFieldMapping(mapping)
+
+	status, err := monitorClient.GetServerStatus()
+	require.NoError(t, err)
+
+	assert.Equal(t, 12.5, status.Data.CPU)
+	assert.Equal(t, 4, status.Data.CPUCores)
+	assert.Equal(t, 8, status.Data.LogicalPro)
+	assert.Equal(t, int64(1024), status.Data.Memory.Current)
+	assert.Equal(t, int64(4096), status.Data.Memory.Total)
+	assert.Equal(t, 99, status.Data.TCPCount)
+	assert.Equal(t, int64(20), status.Data.NetIO.Down)
+	assert.Equal(t, "203.0.113.7", status.Data.PublicIP.IPv4)
+	assert.Equal(t, "none", status.Data.Xray.ErrorMsg)
+	assert.Equal(t, 7, status.Data.AppStats.Threads)
+	assert.Equal(t, int64(2048), status.Data.AppStats.Memory)
+}
+
+func TestResolveFieldMapping(t *testing.T) {
+	// Default is the standard schema with no remapping
+	mapping, err := ResolveFieldMapping("", nil)
+	require.NoError(t, err)
+	assert.Empty(t, mapping)
+
+	// Custom mappings are merged over the profile
+	mapping, err = ResolveFieldMapping("standard", map[string]string{"memory": "mem"})
+	require.NoError(t, err)
+	assert.Equal(t, FieldMapping{"memory": "mem"}, mapping)
+
+	// Unknown profiles are rejected
+	_, err = ResolveFieldMapping("no-such-fork", nil)
+	assert.Error(t, err)
+}
+
+func TestPublicIPInfo_Stack(t *testing.T) {
+	tests := []struct {
+		name     string
+		ipv4     string
+		ipv6     string
+		expected IPStack
+	}{
+		{"both present", "203.0.113.10", "2001:db8::10", IPStackDualStack},
+		{"ipv4 only", "203.0.113.10", "", IPStackIPv4Only},
+		{"ipv6 only", "", "2001:db8::10", IPStackIPv6Only},
+		{"both absent", "", "", IPStackUnknown},
+		{"placeholders count as absent", "N/A", "N/A", IPStackUnknown},
+		{"placeholder ipv6", " 203.0.113.10 ", "N/A", IPStackIPv4Only},
+		{"address in the wrong field", "2001:db8::10", "203.0.113.10", IPStackUnknown},
+		{"ipv4-mapped ipv6 is not ipv6", "", "::ffff:203.0.113.10", IPStackUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, PublicIPInfo{IPv4: tt.ipv4, IPv6: tt.ipv6}.Stack())
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/sanitize"
+)
+
+// panelPagePath is the panel page read for the 3x-ui version
+const panelPagePath = "/panel/"
+
+// panelAssetVersion matches the version 3x-ui appends to its asset URLs against stale
+// browser caches, e.g. "assets/js/util/index.js?2.6.2"
+var panelAssetVersion = regexp.MustCompile(`\.(?:js|css)\?v?(\d+)\.(\d+)\.(\d+)\b`)
+
+// PanelVersion is a 3x-ui release; the zero value is an unknown release
+type PanelVersion struct {
+	Major, Minor, Patch int
+}
+
+// ParsePanelVersion parses a release version such as "2.6.2" or "v2.6.2"
+func ParsePanelVersion(value string) (PanelVersion, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(value), "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return PanelVersion{}, fmt.Errorf("invalid 3x-ui version %q (expected e.g. 2.6.2)", value)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return PanelVersion{}, fmt.Errorf("invalid 3x-ui version %q (expected e.g. 2.6.2)", value)
+		}
+		numbers[i] = n
+	}
+	return PanelVersion{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// ParsePanelPageVersion extracts the 3x-ui version from the HTML of a panel page
+func ParsePanelPageVersion(page []byte) (PanelVersion, bool) {
+	match := panelAssetVersion.FindSubmatch(page)
+	if match == nil {
+		return PanelVersion{}, false
+	}
+	version, err := ParsePanelVersion(fmt.Sprintf("%s.%s.%s", match[1], match[2], match[3]))
+	return version, err == nil
+}
+
+// IsZero reports whether the version is unknown
+func (v PanelVersion) IsZero() bool {
+	return v == PanelVersion{}
+}
+
+// Less reports whether v is an older release than other
+func (v PanelVersion) Less(other PanelVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+func (v PanelVersion) String() string {
+	if v.IsZero() {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// StatusSchema is the /server/status layout of a range of 3x-ui releases
+type StatusSchema struct {
+	Name   string
+	Since  PanelVersion // First release with this layout
+	Fields []string     // Canonical obj keys the releases report
+
+	// normalize converts values the releases encode differently to the current encoding
+	normalize func(obj map[string]interface{})
+}
+
+// baseStatusFields are reported by every release, x-ui included
+var baseStatusFields = []string{
+	"cpu", "mem", "swap", "disk", "uptime", "loads", "tcpCount", "udpCount", "netIO", "netTraffic", "xray",
+}
+
+// statusSchemas are the known layouts, oldest first. Newer releases only add fields.
+var statusSchemas = []StatusSchema{
+	{
+		Name:      "x-ui",
+		Fields:    baseStatusFields,
+		normalize: normalizePublicIP,
+	},
+	{
+		Name:      "3x-ui 1.x",
+		Since:     PanelVersion{Major: 1},
+		Fields:    append(append([]string{}, baseStatusFields...), "publicIP", "appStats"),
+		normalize: normalizePublicIP,
+	},
+	{
+		Name:   "3x-ui 2.x",
+		Since:  PanelVersion{Major: 2},
+		Fields: append(append([]string{}, baseStatusFields...), "publicIP", "appStats", "cpuCores", "logicalPro", "cpuSpeedMhz"),
+	},
+}
+
+// StatusFields returns the obj keys of the newest known layout, the ones ServerStatusData decodes
+func StatusFields() []string {
+	return statusSchemas[len(statusSchemas)-1].Fields
+}
+
+// SchemaFor returns the layout of a release; an unknown release gets the newest layout
+func SchemaFor(version PanelVersion) *StatusSchema {
+	if version.IsZero() {
+		return &statusSchemas[len(statusSchemas)-1]
+	}
+	schema := &statusSchemas[0]
+	for i := range statusSchemas {
+		if !version.Less(statusSchemas[i].Since) {
+			schema = &statusSchemas[i]
+		}
+	}
+	return schema
+}
+
+// inferSchema returns the newest layout whose fields are all present in obj, for releases
+// of unknown version
+func inferSchema(obj map[string]interface{}) *StatusSchema {
+	for i := len(statusSchemas) - 1; i > 0; i-- {
+		if hasFields(obj, statusSchemas[i].Fields) {
+			return &statusSchemas[i]
+		}
+	}
+	return &statusSchemas[0]
+}
+
+func hasFields(obj map[string]interface{}, fields []string) bool {
+	for _, field := range fields {
+		if _, ok := obj[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// unreportedFields returns the keys of the newest layout missing from obj, sorted
+func unreportedFields(obj map[string]interface{}) []string {
+	var missing []string
+	for _, field := range StatusFields() {
+		if _, ok := obj[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// normalizePublicIP turns a publicIP reported as a bare address into the ipv4/ipv6 object
+func normalizePublicIP(obj map[string]interface{}) {
+	address, ok := obj["publicIP"].(string)
+	if !ok {
+		return
+	}
+	publicIP := map[string]interface{}{}
+	if ip := net.ParseIP(strings.TrimSpace(address)); ip != nil {
+		if ip.To4() != nil {
+			publicIP["ipv4"] = ip.String()
+		} else {
+			publicIP["ipv6"] = ip.String()
+		}
+	}
+	obj["publicIP"] = publicIP
+}
+
+// SetPanelVersion pins the 3x-ui release (xui_panel_version) instead of detecting it
+func (m *MonitorClient) SetPanelVersion(version PanelVersion) {
+	m.schemaMutex.Lock()
+	defer m.schemaMutex.Unlock()
+	m.panelVersion = version
+	m.schemaDetected = !version.IsZero()
+}
+
+// PanelVersion returns the detected or pinned 3x-ui release, zero when unknown
+func (m *MonitorClient) PanelVersion() PanelVersion {
+	m.schemaMutex.Lock()
+	defer m.schemaMutex.Unlock()
+	return m.panelVersion
+}
+
+// DetectPanelVersion reads the 3x-ui release from the panel page, once per client. A panel
+// page without version leaves it unknown and the layout is inferred from each response.
+func (m *MonitorClient) DetectPanelVersion() (PanelVersion, error) {
+	m.schemaMutex.Lock()
+	defer m.schemaMutex.Unlock()
+	if m.schemaDetected {
+		return m.panelVersion, nil
+	}
+
+	req, err := m.auth.GetAuthenticatedRequest("GET", panelPagePath, nil)
+	if err != nil {
+		return PanelVersion{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := m.auth.Do(req)
+	if err != nil {
+		return PanelVersion{}, fmt.Errorf("failed to request panel page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		m.schemaDetected = true // Not served by this panel, no point asking again
+		return PanelVersion{}, &auth.StatusError{StatusCode: resp.StatusCode}
+	}
+	page, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
+	if err != nil {
+		return PanelVersion{}, fmt.Errorf("failed to read panel page: %w", err)
+	}
+
+	m.schemaDetected = true
+	version, ok := ParsePanelPageVersion(page)
+	if !ok {
+		m.logger.Debug("🧩 3x-ui version not found on the panel page, inferring the status layout")
+		return PanelVersion{}, nil
+	}
+	m.panelVersion = version
+	m.logger.Infof("🧩 Detected 3x-ui %s (status layout %s)", version, SchemaFor(version).Name)
+	return version, nil
+}
+
+// schemaObj decodes the obj of a status response body for the layout checks; nil when obj is
+// not an object
+func schemaObj(body []byte) (map[string]interface{}, map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var envelope map[string]interface{}
+	if err := decoder.Decode(&envelope); err != nil {
+		return nil, nil, err
+	}
+	obj, _ := envelope["obj"].(map[string]interface{})
+	return envelope, obj, nil
+}
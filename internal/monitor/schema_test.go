@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+)
+
+func TestParsePanelVersion(t *testing.T) {
+	version, err := ParsePanelVersion("v2.6.2")
+	require.NoError(t, err)
+	assert.Equal(t, PanelVersion{Major: 2, Minor: 6, Patch: 2}, version)
+	assert.Equal(t, "2.6.2", version.String())
+
+	version, err = ParsePanelVersion("1.8")
+	require.NoError(t, err)
+	assert.Equal(t, PanelVersion{Major: 1, Minor: 8}, version)
+
+	for _, invalid := range []string{"", "2", "2.x.1", "1.2.3.4", "2.-1.0"} {
+		_, err := ParsePanelVersion(invalid)
+		assert.Error(t, err, invalid)
+	}
+	assert.Equal(t, "unknown", PanelVersion{}.String())
+}
+
+func TestParsePanelPageVersion(t *testing.T) {
+	page := []byte(`<link rel="stylesheet" href="/assets/ant-design-vue/antd.min.css?2.6.2">
+<script src="/assets/js/util/index.js?2.6.2"></script>`)
+	version, ok := ParsePanelPageVersion(page)
+	require.True(t, ok)
+	assert.Equal(t, PanelVersion{Major: 2, Minor: 6, Patch: 2}, version)
+
+	_, ok = ParsePanelPageVersion([]byte(`<script src="/assets/vue/vue.min.js"></script>`))
+	assert.False(t, ok)
+}
+
+func TestSchemaFor(t *testing.T) {
+	assert.Equal(t, "x-ui", SchemaFor(PanelVersion{Minor: 3, Patch: 2}).Name)
+	assert.Equal(t, "3x-ui 1.x", SchemaFor(PanelVersion{Major: 1, Minor: 8, Patch: 9}).Name)
+	assert.Equal(t, "3x-ui 2.x", SchemaFor(PanelVersion{Major: 2, Minor: 6, Patch: 2}).Name)
+	assert.Equal(t, "3x-ui 2.x", SchemaFor(PanelVersion{Major: 3}).Name)
+	assert.Equal(t, "3x-ui 2.x", SchemaFor(PanelVersion{}).Name) // Unknown: newest
+}
+
+func TestDecodeServerStatus_LegacyLayout(t *testing.T) {
+	body := `{"success": true, "obj": {"cpu": 12.5, "mem": {"current": 1, "total": 2}, "swap": {}, "disk": {},
+		"uptime": 10, "loads": [0.1], "tcpCount": 3, "udpCount": 1, "netIO": {}, "netTraffic": {},
+		"xray": {"state": "running"}, "publicIP": "203.0.113.7"}}`
+
+	status, err := DecodeServerStatus([]byte(body), nil)
+	require.NoError(t, err)
+
+	// The bare address is converted and the newer fields are reported as missing, not zero
+	assert.Equal(t, "203.0.113.7", status.Data.PublicIP.IPv4)
+	assert.Equal(t, []string{"appStats", "cpuCores", "cpuSpeedMhz", "logicalPro"}, status.Data.Unreported)
+	assert.Equal(t, 12.5, status.Data.CPU)
+}
+
+func TestDecodeServerStatus_CurrentLayout(t *testing.T) {
+	status, err := DecodeServerStatus([]byte(statusFixture), nil)
+	require.NoError(t, err)
+	assert.Empty(t, status.Data.Unreported)
+}
+
+func TestMonitorClient_DetectPanelVersion(t *testing.T) {
+	pageRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/panel/":
+			pageRequests++
+			w.Write([]byte(`<script src="/assets/js/util/index.js?1.8.9"></script>`))
+		case "/server/status":
+			w.Write([]byte(`{"success": true, "obj": {"cpu": 1, "publicIP": {"ipv4": "203.0.113.7"}, "appStats": {"threads": 3}}}`))
+		}
+	}))
+	defer server.Close()
+
+	authClient := auth.NewXUIAuth(server.URL, "admin", "password123")
+	authClient.SetSessionForTesting("test-session-token")
+	testLogger := createTestLogger(t)
+	defer testLogger.Close()
+	monitorClient := NewMonitorClient(authClient, testLogger)
+
+	version, err := monitorClient.DetectPanelVersion()
+	require.NoError(t, err)
+	assert.Equal(t, PanelVersion{Major: 1, Minor: 8, Patch: 9}, version)
+
+	// Detected once per client
+	_, err = monitorClient.DetectPanelVersion()
+	require.NoError(t, err)
+	assert.Equal(t, 1, pageRequests)
+
+	status, err := monitorClient.GetServerStatus()
+	require.NoError(t, err)
+	assert.Equal(t, "1.8.9", status.Data.PanelVersion)
+	assert.Contains(t, status.Data.Unreported, "cpuCores")
+	assert.Contains(t, status.Data.Unreported, "mem")
+
+	// Only the fields of the 1.x layout are warned about
+	assert.Contains(t, monitorClient.warnedMissing, "mem")
+	assert.NotContains(t, monitorClient.warnedMissing, "cpuCores")
+}
+
+func TestMonitorClient_SetPanelVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	authClient := auth.NewXUIAuth(server.URL, "admin", "password123")
+	authClient.SetSessionForTesting("test-session-token")
+	testLogger := createTestLogger(t)
+	defer testLogger.Close()
+	monitorClient := NewMonitorClient(authClient, testLogger)
+
+	// A pinned version skips the panel page
+	monitorClient.SetPanelVersion(PanelVersion{Major: 2, Minor: 6, Patch: 2})
+	version, err := monitorClient.DetectPanelVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "2.6.2", version.String())
+}
@@ -0,0 +1,275 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/accesslog"
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/sanitize"
+)
+
+// UserDetailsSource selects where the connection details of online users are read from
+type UserDetailsSource string
+
+const (
+	UserDetailsOff       UserDetailsSource = "off"        // Emails only
+	UserDetailsPanel     UserDetailsSource = "panel"      // 3x-ui client IP API (needs the IP limit feature)
+	UserDetailsAccessLog UserDetailsSource = "access_log" // Xray access log
+)
+
+const (
+	// DefaultAccessLog is where 3x-ui configures the Xray access log
+	DefaultAccessLog = "/usr/local/x-ui/access.log"
+	// AccessLogWindow is how far back the access log is read for the online users
+	AccessLogWindow = 5 * time.Minute
+	// maxAccessLogRead caps the bytes read from the end of the access log
+	maxAccessLogRead = 4 << 20
+	// MaxClientIPLookups caps the client IP API requests per collection
+	MaxClientIPLookups = 100
+	// MaxIPsPerUser caps the IPs reported per user, most recently seen first
+	MaxIPsPerUser = 32
+)
+
+// ClientIP is a source IP an online user connected from
+type ClientIP struct {
+	IP          string
+	LastSeen    time.Time // Zero when the source does not tell
+	Connections int       // Connections accepted in the access log window, 0 when unknown
+}
+
+// OnlineUser is an online user with its connection details
+type OnlineUser struct {
+	Email string
+	IPs   []ClientIP
+}
+
+// ParseUserDetailsSource parses an online_user_details value (empty means off)
+func ParseUserDetailsSource(value string) (UserDetailsSource, error) {
+	switch source := UserDetailsSource(strings.ToLower(strings.TrimSpace(value))); source {
+	case "":
+		return UserDetailsOff, nil
+	case UserDetailsOff, UserDetailsPanel, UserDetailsAccessLog:
+		return source, nil
+	default:
+		return "", fmt.Errorf("unknown online_user_details %q (known: off, panel, access_log)", value)
+	}
+}
+
+// SetUserDetails selects where GetOnlineUserDetails reads the connection details from;
+// accessLog is the Xray access log path (empty for DefaultAccessLog)
+func (m *MonitorClient) SetUserDetails(source UserDetailsSource, accessLog string) {
+	if accessLog == "" {
+		accessLog = DefaultAccessLog
+	}
+	m.userDetails = source
+	m.accessLog = accessLog
+}
+
+// UserDetailsEnabled reports whether GetOnlineUserDetails collects anything
+func (m *MonitorClient) UserDetailsEnabled() bool {
+	return m.userDetails == UserDetailsPanel || m.userDetails == UserDetailsAccessLog
+}
+
+// GetOnlineUserDetails returns the source IPs of the given online users, nil when details
+// are off. The panel source looks up at most MaxClientIPLookups users.
+func (m *MonitorClient) GetOnlineUserDetails(emails []string) ([]OnlineUser, error) {
+	switch m.userDetails {
+	case UserDetailsPanel:
+		if len(emails) > MaxClientIPLookups {
+			m.logger.Debugf("👥 Looking up the IPs of the first %d of %d online users", MaxClientIPLookups, len(emails))
+			emails = emails[:MaxClientIPLookups]
+		}
+		users := make([]OnlineUser, 0, len(emails))
+		for _, email := range emails {
+			ips, err := m.GetClientIPs(email)
+			if err != nil {
+				return nil, err
+			}
+			users = append(users, OnlineUser{Email: email, IPs: ips})
+		}
+		return users, nil
+	case UserDetailsAccessLog:
+		file, err := os.Open(m.accessLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open Xray access log: %w", err)
+		}
+		defer file.Close()
+		return ReadAccessLog(file, emails, time.Now())
+	default:
+		return nil, nil
+	}
+}
+
+// GetClientIPs gets the IPs 3x-ui recorded for the client with the given email
+func (m *MonitorClient) GetClientIPs(email string) ([]ClientIP, error) {
+	// Check authentication status
+	if !m.auth.IsAuthenticated() {
+		return nil, fmt.Errorf("%w: please login first", auth.ErrAuthExpired)
+	}
+
+	req, err := m.auth.GetAuthenticatedRequest("POST", "/panel/inbound/clientIps/"+url.PathEscape(email), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+
+	resp, err := m.auth.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request client IPs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &auth.StatusError{StatusCode: resp.StatusCode}
+	}
+
+	body, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return DecodeClientIPs(body)
+}
+
+// DecodeClientIPs decodes a /panel/inbound/clientIps response body. Its obj is a JSON array
+// of "ip" or "ip (2006-01-02 15:04:05)" entries, encoded as a string by 3x-ui, or the text
+// "No IP Record".
+func DecodeClientIPs(body []byte) ([]ClientIP, error) {
+	if err := sanitize.CheckJSON(body); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	var ipsResp struct {
+		Success bool            `json:"success"`
+		Message string          `json:"msg"`
+		Data    json.RawMessage `json:"obj"`
+	}
+	if err := json.Unmarshal(body, &ipsResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if !ipsResp.Success {
+		return nil, fmt.Errorf("API error: %s", sanitize.String(ipsResp.Message))
+	}
+
+	raw := bytes.TrimSpace(ipsResp.Data)
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if !strings.HasPrefix(strings.TrimSpace(text), "[") {
+			return nil, nil // "No IP Record"
+		}
+		raw = []byte(text)
+		if err := sanitize.CheckJSON(raw); err != nil {
+			return nil, fmt.Errorf("failed to parse client IPs: %w", err)
+		}
+	}
+	var entries []string
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse client IPs: %w", err)
+		}
+	}
+
+	var ips []ClientIP
+	for _, entry := range entries {
+		host, seen, _ := strings.Cut(strings.TrimSpace(entry), " ")
+		if net.ParseIP(host) == nil {
+			continue
+		}
+		ip := ClientIP{IP: host}
+		if seen = strings.Trim(strings.TrimSpace(seen), "()"); seen != "" {
+			if at, err := time.ParseInLocation(time.DateTime, seen, time.Local); err == nil {
+				ip.LastSeen = at
+			}
+		}
+		ips = append(ips, ip)
+	}
+	return limitIPs(ips), nil
+}
+
+// ReadAccessLog returns the source IPs of the given users found in the last AccessLogWindow
+// of an Xray access log, reading at most the last 4 MiB. Every user is returned, without
+// IPs when none was found.
+func ReadAccessLog(file *os.File, emails []string, now time.Time) ([]OnlineUser, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Xray access log: %w", err)
+	}
+	offset := max(0, info.Size()-maxAccessLogRead)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read Xray access log: %w", err)
+	}
+	return parseAccessLog(file, offset > 0, emails, now.Add(-AccessLogWindow))
+}
+
+// parseAccessLog aggregates the accepted connections of the given users since since.
+// skipFirst drops the first line, cut by the read window.
+func parseAccessLog(r io.Reader, skipFirst bool, emails []string, since time.Time) ([]OnlineUser, error) {
+	seen := make(map[string]map[string]*ClientIP, len(emails))
+	for _, email := range emails {
+		seen[email] = make(map[string]*ClientIP)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		if skipFirst {
+			skipFirst = false
+			continue
+		}
+		entry, ok := accesslog.ParseLine(scanner.Text())
+		if !ok || entry.Time.Before(since) {
+			continue
+		}
+		host, at := entry.SourceIP, entry.Time
+		ips, online := seen[entry.Email]
+		if !online {
+			continue
+		}
+		ip := ips[host]
+		if ip == nil {
+			ip = &ClientIP{IP: host}
+			ips[host] = ip
+		}
+		ip.Connections++
+		if at.After(ip.LastSeen) {
+			ip.LastSeen = at
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Xray access log: %w", err)
+	}
+
+	users := make([]OnlineUser, 0, len(emails))
+	for _, email := range emails {
+		var ips []ClientIP
+		for _, ip := range seen[email] {
+			ips = append(ips, *ip)
+		}
+		users = append(users, OnlineUser{Email: email, IPs: limitIPs(ips)})
+	}
+	return users, nil
+}
+
+// limitIPs sorts ips by last seen, most recent first, and keeps MaxIPsPerUser
+func limitIPs(ips []ClientIP) []ClientIP {
+	sort.SliceStable(ips, func(i, j int) bool {
+		if !ips[i].LastSeen.Equal(ips[j].LastSeen) {
+			return ips[i].LastSeen.After(ips[j].LastSeen)
+		}
+		return ips[i].IP < ips[j].IP
+	})
+	if len(ips) > MaxIPsPerUser {
+		ips = ips[:MaxIPsPerUser]
+	}
+	return ips
+}
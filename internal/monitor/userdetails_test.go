@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserDetailsSource(t *testing.T) {
+	source, err := ParseUserDetailsSource("")
+	require.NoError(t, err)
+	assert.Equal(t, UserDetailsOff, source)
+
+	source, err = ParseUserDetailsSource(" Access_Log ")
+	require.NoError(t, err)
+	assert.Equal(t, UserDetailsAccessLog, source)
+
+	_, err = ParseUserDetailsSource("syslog")
+	assert.ErrorContains(t, err, `unknown online_user_details "syslog"`)
+}
+
+func TestDecodeClientIPs(t *testing.T) {
+	ips, err := DecodeClientIPs([]byte(`{"success":true,"obj":"[\"198.51.100.2\",\"203.0.113.7 (2024-05-01 12:00:00)\",\"not-an-ip\"]"}`))
+	require.NoError(t, err)
+	require.Len(t, ips, 2)
+	assert.Equal(t, "203.0.113.7", ips[0].IP, "most recently seen first")
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local), ips[0].LastSeen)
+	assert.Equal(t, "198.51.100.2", ips[1].IP)
+	assert.True(t, ips[1].LastSeen.IsZero())
+
+	ips, err = DecodeClientIPs([]byte(`{"success":true,"obj":"No IP Record"}`))
+	require.NoError(t, err)
+	assert.Empty(t, ips)
+
+	// Forks returning the array itself
+	ips, err = DecodeClientIPs([]byte(`{"success":true,"obj":["2001:db8::1"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []ClientIP{{IP: "2001:db8::1"}}, ips)
+
+	_, err = DecodeClientIPs([]byte(`{"success":false,"msg":"client not found"}`))
+	assert.ErrorContains(t, err, "client not found")
+}
+
+const accessLogFixture = `2024/05/01 11:50:00 from 192.0.2.9:40000 accepted tcp:example.com:443 [vless-in >> direct] email: alice@example.com
+2024/05/01 11:58:00 from 203.0.113.7:51234 accepted tcp:example.com:443 [vless-in >> direct] email: alice@example.com
+2024/05/01 11:59:30.123456 from tcp:203.0.113.7:51240 accepted tcp:example.org:443 [vless-in >> direct] email: alice@example.com
+2024/05/01 11:59:40 from udp:[2001:db8::1]:6000 accepted udp:1.1.1.1:53 [vless-in >> direct] email: alice@example.com
+2024/05/01 11:59:45 from 198.51.100.2:6001 rejected  proxy/vless/encoding: invalid request user id
+2024/05/01 11:59:50 from 198.51.100.3:6002 accepted tcp:example.com:443 [vmess-in >> direct] email: carol@example.com
+`
+
+func TestReadAccessLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(path, []byte(accessLogFixture), 0o644))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	users, err := ReadAccessLog(file, []string{"alice@example.com", "bob@example.com"}, now)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+
+	alice := users[0]
+	assert.Equal(t, "alice@example.com", alice.Email)
+	assert.Equal(t, []ClientIP{
+		{IP: "2001:db8::1", LastSeen: time.Date(2024, 5, 1, 11, 59, 40, 0, time.Local), Connections: 1},
+		{IP: "203.0.113.7", LastSeen: time.Date(2024, 5, 1, 11, 59, 30, 0, time.Local), Connections: 2},
+	}, alice.IPs, "lines older than the window are ignored")
+	assert.Equal(t, OnlineUser{Email: "bob@example.com"}, users[1], "online users without connections are kept")
+}
+
+func TestReadAccessLog_ReadsTheEnd(t *testing.T) {
+	var log strings.Builder
+	line := "2024/05/01 11:59:00 from 192.0.2.%d:40000 accepted tcp:example.com:443 [in >> direct] email: alice@example.com\n"
+	for i := 0; log.Len() < maxAccessLogRead+len(line)*10; i++ {
+		fmt.Fprintf(&log, line, i%200)
+	}
+	path := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(path, []byte(log.String()), 0o644))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	users, err := ReadAccessLog(file, []string{"alice@example.com"}, time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+	assert.Len(t, users[0].IPs, MaxIPsPerUser)
+}
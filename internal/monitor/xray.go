@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/sanitize"
+)
+
+// xrayInstallTimeout bounds an Xray install, which downloads the release from GitHub
+const xrayInstallTimeout = 3 * time.Minute
+
+// Xray restart triggers and methods, reported to xhub
+const (
+	XrayRestartSupervision = "supervision" // Xray not running for xray_supervision_cycles cycles
+	XrayRestartCommand     = "command"     // restart_xray command of xhub
+	XrayRestartPanel       = "panel"       // 3x-ui restartXrayService API
+	XrayRestartSystemd     = "systemd"     // systemctl restart of xray_restart_service
+)
+
+// XrayRestart is the outcome of an Xray restart by the agent, reported with the next status
+type XrayRestart struct {
+	Trigger    string `json:"trigger"`              // XrayRestartSupervision or XrayRestartCommand
+	Method     string `json:"method"`               // XrayRestartPanel or XrayRestartSystemd, the last one tried
+	At         int64  `json:"at"`                   // Unix seconds
+	DownCycles int    `json:"downCycles,omitempty"` // Cycles Xray was not running (supervision)
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"` // Why the restart failed
+}
+
+// RestartXray asks 3x-ui to restart the Xray service
+func (m *MonitorClient) RestartXray() error {
+	_, err := m.serverAction("/server/restartXrayService", "Xray restart")
+	return err
+}
+
+// XrayVersions returns the Xray releases 3x-ui offers to install, newest first (e.g. "v25.8.3")
+func (m *MonitorClient) XrayVersions() ([]string, error) {
+	obj, err := m.serverAction("/server/getXrayVersion", "Xray versions")
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	if err := json.Unmarshal(obj, &versions); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	for i, version := range versions {
+		versions[i] = sanitize.String(version)
+	}
+	return versions, nil
+}
+
+// InstallXray asks 3x-ui to download and install an Xray release (e.g. "v25.8.3") and restart
+// Xray with it
+func (m *MonitorClient) InstallXray(version string) error {
+	_, err := m.serverActionWithTimeout("/server/installXray/"+version, "Xray install", xrayInstallTimeout)
+	return err
+}
+
+// XrayStatus returns the state and version of the running Xray
+func (m *MonitorClient) XrayStatus() (XrayInfo, error) {
+	status, err := m.GetServerStatus()
+	if err != nil {
+		return XrayInfo{}, err
+	}
+	if status.Data == nil {
+		return XrayInfo{}, fmt.Errorf("server status without data")
+	}
+	return status.Data.Xray, nil
+}
+
+// serverAction posts to a 3x-ui server API path and returns the obj of a successful response
+func (m *MonitorClient) serverAction(path, action string) (json.RawMessage, error) {
+	return m.serverActionWithTimeout(path, action, 0)
+}
+
+// serverActionWithTimeout is serverAction for actions that may take longer than the default
+// panel request timeout (0)
+func (m *MonitorClient) serverActionWithTimeout(path, action string, timeout time.Duration) (json.RawMessage, error) {
+	// Check authentication status
+	if !m.auth.IsAuthenticated() {
+		return nil, fmt.Errorf("%w: please login first", auth.ErrAuthExpired)
+	}
+
+	req, err := m.auth.GetAuthenticatedRequest("POST", path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := m.auth.DoWithTimeout(req, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &auth.StatusError{StatusCode: resp.StatusCode}
+	}
+
+	body, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := sanitize.CheckJSON(body); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	var actionResp struct {
+		Success bool            `json:"success"`
+		Message string          `json:"msg"`
+		Obj     json.RawMessage `json:"obj"`
+	}
+	if err := json.Unmarshal(body, &actionResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if !actionResp.Success {
+		return nil, fmt.Errorf("API error: %s", sanitize.String(actionResp.Message))
+	}
+	return actionResp.Obj, nil
+}
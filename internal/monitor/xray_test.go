@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+)
+
+// newXrayTestClient creates a monitor client against a 3x-ui stub answering the given
+// responses by path suffix, recording the requested paths
+func newXrayTestClient(t *testing.T, responses map[string]string) (*MonitorClient, *[]string) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		for suffix, body := range responses {
+			if strings.HasSuffix(r.URL.Path, suffix) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(body))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	authClient := auth.NewXUIAuth(server.URL, "admin", "password123")
+	authClient.SetSessionForTesting("test-session-token")
+	testLogger := createTestLogger(t)
+	t.Cleanup(func() { testLogger.Close() })
+	return NewMonitorClient(authClient, testLogger), &paths
+}
+
+func TestMonitorClient_XrayVersions(t *testing.T) {
+	client, paths := newXrayTestClient(t, map[string]string{
+		"/server/getXrayVersion": `{"success": true, "obj": ["v25.8.3", "v25.7.26", "v1.8.24"]}`,
+	})
+
+	versions, err := client.XrayVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v25.8.3", "v25.7.26", "v1.8.24"}, versions)
+	assert.Equal(t, []string{"/server/getXrayVersion"}, *paths)
+}
+
+func TestMonitorClient_InstallXray(t *testing.T) {
+	client, paths := newXrayTestClient(t, map[string]string{
+		"/server/installXray/v25.8.3": `{"success": true, "msg": "Installed"}`,
+		"/server/installXray/v0.0.1":  `{"success": false, "msg": "release not found"}`,
+	})
+
+	require.NoError(t, client.InstallXray("v25.8.3"))
+	assert.Equal(t, []string{"/server/installXray/v25.8.3"}, *paths)
+
+	err := client.InstallXray("v0.0.1")
+	assert.ErrorContains(t, err, "release not found")
+
+	err = client.InstallXray("v9.9.9")
+	var statusErr *auth.StatusError
+	assert.ErrorAs(t, err, &statusErr)
+}
@@ -0,0 +1,237 @@
+package netprobe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Probe defaults
+const (
+	DefaultCount              = 10               // TCP connects per target
+	DefaultProbeTimeout       = 3 * time.Second  // Longest a connect may take before it counts as lost
+	DefaultThroughputMaxBytes = 10 << 20         // Download cap of the throughput test
+	DefaultThroughputTimeout  = 30 * time.Second // Longest the throughput test may run
+	probeSpacing              = 200 * time.Millisecond
+)
+
+// Run triggers, reported to xhub
+const (
+	TriggerSchedule = "schedule"
+	TriggerCommand  = "command"
+)
+
+// Config selects what a Prober measures
+type Config struct {
+	Targets            []string      // host:port endpoints probed with TCP connects
+	Count              int           // Connects per target, default DefaultCount
+	Timeout            time.Duration // Per connect, default DefaultProbeTimeout
+	ThroughputURL      string        // HTTP(S) file downloaded to measure throughput, "" disables
+	ThroughputMaxBytes int64         // Download cap, default DefaultThroughputMaxBytes
+}
+
+// TargetResult is the latency, jitter and loss of the TCP connects to a target
+type TargetResult struct {
+	Target      string
+	Sent        int
+	Received    int     // Connects that completed
+	LossPercent float64 // Failed connects, in percent of Sent
+	MinMs       float64
+	AvgMs       float64
+	MaxMs       float64
+	JitterMs    float64 // Mean difference between consecutive connect times
+	Error       string  // Last connect error, empty when no connect failed
+}
+
+// Throughput is the outcome of the download throughput test
+type Throughput struct {
+	URL           string
+	Bytes         int64 // Bytes downloaded
+	Duration      time.Duration
+	BitsPerSecond float64
+	Error         string // Why the download failed or ended early
+}
+
+// Report is the outcome of a probe run
+type Report struct {
+	Trigger    string // TriggerSchedule or TriggerCommand
+	CommandID  string // Command that requested the run, empty when scheduled
+	StartedAt  time.Time
+	Duration   time.Duration
+	Targets    []TargetResult
+	Throughput *Throughput // nil when not measured
+}
+
+// Prober measures the network quality of the node towards its targets
+type Prober struct {
+	config  Config
+	dial    func(ctx context.Context, network, address string) (net.Conn, error) // injectable for tests
+	client  *http.Client
+	spacing time.Duration // Wait between the connects to a target
+
+	mutex sync.Mutex // One run at a time, concurrent runs would skew each other
+}
+
+// NewProber creates a prober, filling the unset fields of config with the defaults
+func NewProber(config Config) *Prober {
+	if config.Count <= 0 {
+		config.Count = DefaultCount
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultProbeTimeout
+	}
+	if config.ThroughputMaxBytes <= 0 {
+		config.ThroughputMaxBytes = DefaultThroughputMaxBytes
+	}
+	dialer := &net.Dialer{}
+	return &Prober{
+		config: config,
+		dial:   dialer.DialContext,
+		// Direct connections only: a proxy would measure its own path
+		client:  &http.Client{Transport: &http.Transport{Proxy: nil, DisableCompression: true}, Timeout: DefaultThroughputTimeout},
+		spacing: probeSpacing,
+	}
+}
+
+// ThroughputEnabled reports whether a throughput URL is configured
+func (p *Prober) ThroughputEnabled() bool {
+	return p.config.ThroughputURL != ""
+}
+
+// Run probes every target concurrently and, when throughput is set and a URL is configured,
+// measures the download throughput afterwards
+func (p *Prober) Run(ctx context.Context, trigger string, throughput bool) Report {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	report := Report{Trigger: trigger, StartedAt: time.Now()}
+	report.Targets = make([]TargetResult, len(p.config.Targets))
+	var wg sync.WaitGroup
+	for i, target := range p.config.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Targets[i] = p.probeTarget(ctx, target)
+		}()
+	}
+	wg.Wait()
+
+	if throughput && p.ThroughputEnabled() {
+		report.Throughput = p.measureThroughput(ctx)
+	}
+	report.Duration = time.Since(report.StartedAt)
+	return report
+}
+
+// probeTarget connects to target Count times and summarizes the connect times
+func (p *Prober) probeTarget(ctx context.Context, target string) TargetResult {
+	result := TargetResult{Target: target}
+	var rtts []float64
+	for i := 0; i < p.config.Count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(p.spacing):
+			}
+		}
+		if ctx.Err() != nil {
+			result.Error = ctx.Err().Error()
+			break
+		}
+
+		result.Sent++
+		probeCtx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+		start := time.Now()
+		conn, err := p.dial(probeCtx, "tcp", target)
+		rtt := time.Since(start)
+		cancel()
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		conn.Close()
+		rtts = append(rtts, float64(rtt.Microseconds())/1000)
+	}
+
+	result.Received = len(rtts)
+	if result.Sent > 0 {
+		result.LossPercent = float64(result.Sent-result.Received) / float64(result.Sent) * 100
+	}
+	if len(rtts) == 0 {
+		return result
+	}
+	result.MinMs, result.MaxMs = rtts[0], rtts[0]
+	var sum, deltas float64
+	for i, rtt := range rtts {
+		sum += rtt
+		result.MinMs = min(result.MinMs, rtt)
+		result.MaxMs = max(result.MaxMs, rtt)
+		if i > 0 {
+			deltas += math.Abs(rtt - rtts[i-1])
+		}
+	}
+	result.AvgMs = sum / float64(len(rtts))
+	if len(rtts) > 1 {
+		result.JitterMs = deltas / float64(len(rtts)-1)
+	}
+	return result
+}
+
+// measureThroughput downloads up to ThroughputMaxBytes of ThroughputURL
+func (p *Prober) measureThroughput(ctx context.Context) *Throughput {
+	result := &Throughput{URL: p.config.ThroughputURL}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.ThroughputURL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		result.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		return result
+	}
+
+	result.Bytes, err = io.Copy(io.Discard, io.LimitReader(resp.Body, p.config.ThroughputMaxBytes))
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if seconds := result.Duration.Seconds(); seconds > 0 {
+		result.BitsPerSecond = float64(result.Bytes) * 8 / seconds
+	}
+	return result
+}
+
+// Summary is a one-line human readable summary of the report, the output of the command
+func (r Report) Summary() string {
+	var parts []string
+	for _, target := range r.Targets {
+		if target.Received == 0 {
+			parts = append(parts, fmt.Sprintf("%s: unreachable (%s)", target.Target, target.Error))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: avg %.1fms, jitter %.1fms, loss %.0f%%",
+			target.Target, target.AvgMs, target.JitterMs, target.LossPercent))
+	}
+	if r.Throughput != nil {
+		if r.Throughput.Bytes == 0 && r.Throughput.Error != "" {
+			parts = append(parts, fmt.Sprintf("download failed (%s)", r.Throughput.Error))
+		} else {
+			parts = append(parts, fmt.Sprintf("download %.1f Mbit/s", r.Throughput.BitsPerSecond/1e6))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,135 @@
+package netprobe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestProber creates a prober whose connects to a target take the delays of outcomes in
+// turn, a negative delay failing the connect
+func newTestProber(config Config, outcomes map[string][]time.Duration) *Prober {
+	p := NewProber(config)
+	p.spacing = 0
+	var mutex sync.Mutex // Targets are probed concurrently
+	calls := make(map[string]int)
+	p.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		mutex.Lock()
+		delay := outcomes[address][calls[address]]
+		calls[address]++
+		mutex.Unlock()
+		if delay < 0 {
+			return nil, errors.New("connect: connection refused")
+		}
+		time.Sleep(delay)
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+	return p
+}
+
+func TestProber_Run_Latency(t *testing.T) {
+	p := newTestProber(Config{Targets: []string{"a:443", "b:443"}, Count: 4}, map[string][]time.Duration{
+		"a:443": {10 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond, -1},
+		"b:443": {-1, -1, -1, -1},
+	})
+
+	report := p.Run(context.Background(), TriggerSchedule, true)
+	assert.Equal(t, TriggerSchedule, report.Trigger)
+	assert.Nil(t, report.Throughput, "no throughput URL configured")
+	require.Len(t, report.Targets, 2)
+
+	a := report.Targets[0]
+	assert.Equal(t, "a:443", a.Target)
+	assert.Equal(t, 4, a.Sent)
+	assert.Equal(t, 3, a.Received)
+	assert.Equal(t, 25.0, a.LossPercent)
+	assert.GreaterOrEqual(t, a.MinMs, 10.0)
+	assert.GreaterOrEqual(t, a.MaxMs, 30.0)
+	assert.Less(t, a.MinMs, a.AvgMs)
+	assert.Less(t, a.AvgMs, a.MaxMs)
+	assert.Greater(t, a.JitterMs, 5.0) // About (|30-10| + |20-30|) / 2
+	assert.Contains(t, a.Error, "connection refused")
+
+	b := report.Targets[1]
+	assert.Equal(t, 0, b.Received)
+	assert.Equal(t, 100.0, b.LossPercent)
+	assert.Zero(t, b.AvgMs)
+
+	summary := report.Summary()
+	assert.Contains(t, summary, "a:443: avg ")
+	assert.Contains(t, summary, "loss 25%")
+	assert.Contains(t, summary, "b:443: unreachable (connect: connection refused)")
+}
+
+func TestProber_Run_Canceled(t *testing.T) {
+	p := newTestProber(Config{Targets: []string{"a:443"}, Count: 3}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := p.Run(ctx, TriggerCommand, false)
+	require.Len(t, report.Targets, 1)
+	assert.Equal(t, 0, report.Targets[0].Sent)
+	assert.Equal(t, context.Canceled.Error(), report.Targets[0].Error)
+}
+
+func TestProber_Run_Throughput(t *testing.T) {
+	payload := strings.Repeat("x", 64<<10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	p := NewProber(Config{ThroughputURL: server.URL + "/file", ThroughputMaxBytes: 16 << 10})
+	assert.True(t, p.ThroughputEnabled())
+	report := p.Run(context.Background(), TriggerCommand, true)
+	require.NotNil(t, report.Throughput)
+	assert.Equal(t, int64(16<<10), report.Throughput.Bytes, "capped at ThroughputMaxBytes")
+	assert.Empty(t, report.Throughput.Error)
+	assert.Greater(t, report.Throughput.BitsPerSecond, 0.0)
+	assert.Contains(t, report.Summary(), "download ")
+
+	assert.Nil(t, p.Run(context.Background(), TriggerCommand, false).Throughput, "throughput skipped")
+
+	p = NewProber(Config{ThroughputURL: server.URL + "/missing"})
+	report = p.Run(context.Background(), TriggerCommand, true)
+	require.NotNil(t, report.Throughput)
+	assert.Equal(t, "HTTP 404", report.Throughput.Error)
+	assert.Equal(t, "download failed (HTTP 404)", report.Summary())
+}
+
+func TestProber_Run_RealConnect(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	p := NewProber(Config{Targets: []string{lis.Addr().String()}, Count: 3})
+	p.spacing = time.Millisecond
+	report := p.Run(context.Background(), TriggerSchedule, false)
+	require.Len(t, report.Targets, 1)
+	assert.Equal(t, 3, report.Targets[0].Received)
+	assert.Zero(t, report.Targets[0].LossPercent)
+}
@@ -0,0 +1,47 @@
+package nodeprovider
+
+import (
+	"slices"
+
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/sharelink"
+)
+
+// Provider is a protocol served outside 3x-ui (Hysteria2, TUIC, ShadowTLS, WireGuard): its
+// status is attached to the status reports and its share links to the subscriptions
+type Provider interface {
+	// Name is the protocol, e.g. "tuic", also the collector_intervals key of its status
+	Name() string
+	// Enabled reports whether the protocol is configured on this agent
+	Enabled() bool
+	// CollectStatus collects the status of the protocol and returns how to attach it to a
+	// status report (nil attaches nothing)
+	CollectStatus() collector.Apply
+	// BuildShareLinks builds the links of the subscribers, shared or per user
+	BuildShareLinks() (ShareLinks, error)
+}
+
+// ShareLinks are the links of a provider for the subscriptions
+type ShareLinks struct {
+	sharelink.Nodes
+	// WireGuardConfig marks wg-quick client configs, reported in their own subscription field
+	// instead of being appended to the node config
+	WireGuardConfig bool
+}
+
+// Empty reports whether there is no link for any subscriber
+func (l ShareLinks) Empty() bool {
+	return l.Shared == "" && len(l.PerUser) == 0
+}
+
+// ProtocolStatus returns the status of a protocol that never shows up in the 3x-ui inbound
+// list: the protocol added to the inbound protocols
+func ProtocolStatus(protocol string) collector.Apply {
+	return func(data *monitor.ServerStatusData) {
+		if !slices.Contains(data.InboundProtocols, protocol) {
+			data.InboundProtocols = append(data.InboundProtocols, protocol)
+			slices.Sort(data.InboundProtocols)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package nodeprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/sharelink"
+)
+
+func TestProtocolStatus(t *testing.T) {
+	data := &monitor.ServerStatusData{InboundProtocols: []string{"trojan", "vless"}}
+	ProtocolStatus("tuic")(data)
+	ProtocolStatus("vless")(data)
+	assert.Equal(t, []string{"trojan", "tuic", "vless"}, data.InboundProtocols)
+
+	data = &monitor.ServerStatusData{}
+	ProtocolStatus("hysteria2")(data)
+	assert.Equal(t, []string{"hysteria2"}, data.InboundProtocols)
+}
+
+func TestShareLinks_Empty(t *testing.T) {
+	assert.True(t, ShareLinks{}.Empty())
+	assert.True(t, ShareLinks{Nodes: sharelink.Nodes{PerUser: map[string]string{}}, WireGuardConfig: true}.Empty())
+	assert.False(t, ShareLinks{Nodes: sharelink.Nodes{Shared: "tuic://"}}.Empty())
+	assert.False(t, ShareLinks{Nodes: sharelink.Nodes{PerUser: map[string]string{"alice": "tuic://"}}}.Empty())
+}
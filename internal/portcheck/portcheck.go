@@ -0,0 +1,229 @@
+package portcheck
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// tcpListenState is the /proc/net/tcp state code for LISTEN
+const tcpListenState = "0A"
+
+// Detector finds which local process owns the listening socket of each inbound port
+type Detector struct {
+	procRoot string
+	logger   *logger.Logger
+
+	// Ports that had a non-xray listener in the previous cycle
+	prevFrontend map[int]bool
+	mutex        sync.Mutex
+
+	readDir func(name string) ([]os.DirEntry, error) // injectable for tests
+}
+
+// NewDetector creates a new detector reading from procRoot (normally "/proc")
+func NewDetector(procRoot string, logger *logger.Logger) *Detector {
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	return &Detector{
+		procRoot:     procRoot,
+		logger:       logger,
+		prevFrontend: make(map[int]bool),
+		readDir:      os.ReadDir,
+	}
+}
+
+// Detect returns the listener of each port.
+// Ports whose owner cannot be determined because of permissions are marked Unknown.
+func (d *Detector) Detect(ports []int) []monitor.PortListener {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// port -> socket inodes of LISTEN sockets
+	listening, err := d.listeningInodes()
+	if err != nil {
+		d.logger.Debugf("Port frontend detection unavailable: %v", err)
+		result := make([]monitor.PortListener, 0, len(ports))
+		for _, port := range uniqueSorted(ports) {
+			result = append(result, monitor.PortListener{Port: port, Unknown: true})
+		}
+		return result
+	}
+
+	owners, denied := d.socketOwners()
+
+	result := make([]monitor.PortListener, 0, len(ports))
+	frontend := make(map[int]bool)
+	for _, port := range uniqueSorted(ports) {
+		entry := monitor.PortListener{Port: port}
+		inodes := listening[port]
+
+		switch {
+		case len(inodes) == 0:
+			// Nothing listens: warn if a frontend used to own this port
+			if d.prevFrontend[port] {
+				entry.ListenerLost = true
+				d.logger.Warnf("⚠️  Port %d lost its frontend listener (no process is listening anymore)", port)
+			}
+		default:
+			process := ""
+			for _, inode := range inodes {
+				if name, ok := owners[inode]; ok {
+					process = name
+					break
+				}
+			}
+			if process == "" {
+				// Listening socket exists but its owner is hidden from us
+				// (permission denied on /proc/<pid>/fd or another PID namespace)
+				entry.Unknown = true
+				if denied {
+					d.logger.Debugf("Owner of port %d unknown: insufficient privileges to inspect /proc", port)
+				}
+				break
+			}
+			entry.ListenerProcess = process
+			entry.IsXray = isXrayProcess(process)
+			entry.IsFrontend = !entry.IsXray
+			if entry.IsFrontend {
+				frontend[port] = true
+			}
+		}
+
+		// Keep the previous frontend flag when the owner is unknown this cycle
+		if entry.Unknown && d.prevFrontend[port] {
+			frontend[port] = true
+		}
+		result = append(result, entry)
+	}
+
+	d.prevFrontend = frontend
+	return result
+}
+
+// listeningInodes parses /proc/net/tcp and /proc/net/tcp6 for LISTEN sockets
+func (d *Detector) listeningInodes() (map[int][]string, error) {
+	result := make(map[int][]string)
+	readAny := false
+	var lastErr error
+
+	for _, name := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(filepath.Join(d.procRoot, "net", name))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		readAny = true
+
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // skip header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != tcpListenState {
+				continue
+			}
+			// local_address is "ADDR:PORT" in hex
+			idx := strings.LastIndex(fields[1], ":")
+			if idx < 0 {
+				continue
+			}
+			port, err := strconv.ParseInt(fields[1][idx+1:], 16, 32)
+			if err != nil {
+				continue
+			}
+			inode := fields[9]
+			if inode == "0" {
+				continue
+			}
+			result[int(port)] = append(result[int(port)], inode)
+		}
+		f.Close()
+	}
+
+	if !readAny {
+		return nil, lastErr
+	}
+	return result, nil
+}
+
+// socketOwners maps socket inodes to process names by scanning /proc/<pid>/fd.
+// denied reports whether any process could not be inspected due to permissions.
+func (d *Detector) socketOwners() (map[string]string, bool) {
+	owners := make(map[string]string)
+	denied := false
+
+	entries, err := d.readDir(d.procRoot)
+	if err != nil {
+		return owners, errors.Is(err, fs.ErrPermission)
+	}
+
+	for _, entry := range entries {
+		pid := entry.Name()
+		if _, err := strconv.Atoi(pid); err != nil {
+			continue
+		}
+
+		fdDir := filepath.Join(d.procRoot, pid, "fd")
+		fds, err := d.readDir(fdDir)
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				denied = true
+			}
+			continue
+		}
+
+		var name string
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+			if name == "" {
+				name = processName(d.procRoot, pid)
+			}
+			owners[inode] = name
+		}
+	}
+
+	return owners, denied
+}
+
+// processName reads the process command name from /proc/<pid>/comm
+func processName(procRoot, pid string) string {
+	data, err := os.ReadFile(filepath.Join(procRoot, pid, "comm"))
+	if err != nil {
+		return "pid:" + pid
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// isXrayProcess checks whether the process name belongs to xray-core
+func isXrayProcess(name string) bool {
+	return strings.Contains(strings.ToLower(name), "xray")
+}
+
+// uniqueSorted deduplicates and sorts ports, dropping invalid ones
+func uniqueSorted(ports []int) []int {
+	seen := make(map[int]bool)
+	var result []int
+	for _, port := range ports {
+		if port <= 0 || seen[port] {
+			continue
+		}
+		seen[port] = true
+		result = append(result, port)
+	}
+	sort.Ints(result)
+	return result
+}
@@ -0,0 +1,152 @@
+package portcheck
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/pkg/logger"
+)
+
+const tcpHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+
+// createTestLogger creates a logger for testing
+func createTestLogger(t *testing.T) *logger.Logger {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+	return log
+}
+
+// fakeProc builds a fixture /proc tree
+type fakeProc struct {
+	t    *testing.T
+	root string
+}
+
+func newFakeProc(t *testing.T) *fakeProc {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "net"), 0755))
+	return &fakeProc{t: t, root: root}
+}
+
+// setTCP writes /proc/net/tcp with LISTEN sockets port->inode
+func (p *fakeProc) setTCP(listeners map[int]string) {
+	var b strings.Builder
+	b.WriteString(tcpHeader)
+	i := 0
+	for port, inode := range listeners {
+		b.WriteString(formatTCPLine(i, port, inode))
+		i++
+	}
+	// A non-listening (ESTABLISHED) socket that must be ignored
+	b.WriteString("  99: 0100007F:1F90 0100007F:D2F0 01 00000000:00000000 00:00000000 00000000     0        0 99999 1 0000000000000000 20 4 30 10 -1\n")
+	require.NoError(p.t, os.WriteFile(filepath.Join(p.root, "net", "tcp"), []byte(b.String()), 0644))
+}
+
+func formatTCPLine(sl, port int, inode string) string {
+	return fmt.Sprintf("  %2d: 00000000:%04X 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 %s 1 0000000000000000 100 0 0 10 0\n",
+		sl, port, inode)
+}
+
+// addProcess creates /proc/<pid>/comm and fd symlinks to the given socket inodes
+func (p *fakeProc) addProcess(pid, comm string, inodes ...string) {
+	dir := filepath.Join(p.root, pid)
+	require.NoError(p.t, os.MkdirAll(filepath.Join(dir, "fd"), 0755))
+	require.NoError(p.t, os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644))
+	for i, inode := range inodes {
+		require.NoError(p.t, os.Symlink("socket:["+inode+"]", filepath.Join(dir, "fd", strconv.Itoa(i+3))))
+	}
+	// A non-socket fd
+	_ = os.Symlink("/dev/null", filepath.Join(dir, "fd", "0"))
+}
+
+func TestDetector_OwnerClassification(t *testing.T) {
+	proc := newFakeProc(t)
+	proc.setTCP(map[int]string{443: "1001", 8443: "1002"})
+	proc.addProcess("100", "nginx", "1001")
+	proc.addProcess("200", "xray-linux-amd64", "1002")
+
+	d := NewDetector(proc.root, createTestLogger(t))
+	result := d.Detect([]int{8443, 443, 9999, 443})
+
+	require.Len(t, result, 3)
+
+	// nginx-owned port is a frontend
+	assert.Equal(t, 443, result[0].Port)
+	assert.Equal(t, "nginx", result[0].ListenerProcess)
+	assert.True(t, result[0].IsFrontend)
+	assert.False(t, result[0].IsXray)
+
+	// xray-owned port
+	assert.Equal(t, 8443, result[1].Port)
+	assert.Equal(t, "xray-linux-amd64", result[1].ListenerProcess)
+	assert.True(t, result[1].IsXray)
+	assert.False(t, result[1].IsFrontend)
+
+	// no listener at all
+	assert.Equal(t, 9999, result[2].Port)
+	assert.Empty(t, result[2].ListenerProcess)
+	assert.False(t, result[2].Unknown)
+	assert.False(t, result[2].ListenerLost)
+}
+
+func TestDetector_FrontendLostAcrossCycles(t *testing.T) {
+	proc := newFakeProc(t)
+	proc.setTCP(map[int]string{443: "1001"})
+	proc.addProcess("100", "haproxy", "1001")
+
+	d := NewDetector(proc.root, createTestLogger(t))
+
+	first := d.Detect([]int{443})
+	require.Len(t, first, 1)
+	assert.True(t, first[0].IsFrontend)
+	assert.False(t, first[0].ListenerLost)
+
+	// The frontend dies: nothing listens on 443 anymore
+	proc.setTCP(map[int]string{})
+	second := d.Detect([]int{443})
+	require.Len(t, second, 1)
+	assert.True(t, second[0].ListenerLost)
+
+	// The warning flag is only raised on the transition
+	third := d.Detect([]int{443})
+	require.Len(t, third, 1)
+	assert.False(t, third[0].ListenerLost)
+}
+
+func TestDetector_PermissionDegraded(t *testing.T) {
+	proc := newFakeProc(t)
+	proc.setTCP(map[int]string{443: "1001"})
+	proc.addProcess("100", "nginx", "1001")
+
+	d := NewDetector(proc.root, createTestLogger(t))
+	d.readDir = func(name string) ([]os.DirEntry, error) {
+		if strings.HasSuffix(name, filepath.Join("100", "fd")) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+		}
+		return os.ReadDir(name)
+	}
+
+	result := d.Detect([]int{443})
+	require.Len(t, result, 1)
+	assert.True(t, result[0].Unknown)
+	assert.Empty(t, result[0].ListenerProcess)
+}
+
+func TestDetector_ProcNetUnreadable(t *testing.T) {
+	d := NewDetector(filepath.Join(t.TempDir(), "missing"), createTestLogger(t))
+
+	result := d.Detect([]int{443, 80})
+	require.Len(t, result, 2)
+	for _, entry := range result {
+		assert.True(t, entry.Unknown)
+	}
+}
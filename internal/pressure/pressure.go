@@ -0,0 +1,160 @@
+package pressure
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// Collector registry name and default interval (PSI averages are cheap to read every cycle)
+const (
+	CollectorName   = "pressure"
+	DefaultInterval = 0
+)
+
+// Collector reads the pressure stall information of /proc/pressure and the temperatures of
+// the thermal zones of /sys/class/thermal (Linux only)
+type Collector struct {
+	procRoot string
+	sysRoot  string
+	logger   *logger.Logger
+	warned   bool // Missing PSI has been logged
+}
+
+// NewCollector creates a collector reading the given /proc and /sys roots
+func NewCollector(procRoot, sysRoot string, logger *logger.Logger) *Collector {
+	return &Collector{procRoot: procRoot, sysRoot: sysRoot, logger: logger}
+}
+
+// Collect returns the current readings, nil when neither PSI nor a thermal zone is available
+func (c *Collector) Collect() *monitor.HostPressure {
+	pressure := &monitor.HostPressure{
+		CPU:          c.stall("cpu"),
+		Memory:       c.stall("memory"),
+		IO:           c.stall("io"),
+		ThermalZones: c.thermalZones(),
+	}
+	if pressure.CPU == nil && pressure.Memory == nil && pressure.IO == nil {
+		if !c.warned {
+			c.logger.Debugf("Pressure stall information unavailable (kernel before 4.20 or booted with psi=0)")
+			c.warned = true
+		}
+		if len(pressure.ThermalZones) == 0 {
+			return nil
+		}
+	}
+	return pressure
+}
+
+// stall reads /proc/pressure/<resource>, nil when unavailable
+func (c *Collector) stall(resource string) *monitor.PressureStall {
+	file, err := os.Open(filepath.Join(c.procRoot, "pressure", resource))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	stall, err := ParseStall(file)
+	if err != nil {
+		c.logger.Debugf("Invalid /proc/pressure/%s: %v", resource, err)
+		return nil
+	}
+	return stall
+}
+
+// ParseStall parses a PSI file:
+//
+//	some avg10=0.12 avg60=0.05 avg300=0.01 total=123456
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=7890
+func ParseStall(r io.Reader) (*monitor.PressureStall, error) {
+	stall := &monitor.PressureStall{}
+	found := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var avg10, avg60, avg300 *float64
+		var total *uint64
+		switch fields[0] {
+		case "some":
+			avg10, avg60, avg300, total = &stall.SomeAvg10, &stall.SomeAvg60, &stall.SomeAvg300, &stall.SomeTotalUs
+		case "full":
+			avg10, avg60, avg300, total = &stall.FullAvg10, &stall.FullAvg60, &stall.FullAvg300, &stall.FullTotalUs
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid field %q", field)
+			}
+			var err error
+			switch key {
+			case "avg10":
+				*avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				*avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				*avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				*total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid field %q: %w", field, err)
+			}
+		}
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no some/full line")
+	}
+	return stall, nil
+}
+
+// thermalZones reads the temperature of every readable thermal zone, ordered by zone number
+func (c *Collector) thermalZones() []monitor.ThermalZone {
+	paths, _ := filepath.Glob(filepath.Join(c.sysRoot, "class", "thermal", "thermal_zone*"))
+	sort.Slice(paths, func(i, j int) bool { return zoneNumber(paths[i]) < zoneNumber(paths[j]) })
+
+	var zones []monitor.ThermalZone
+	for _, path := range paths {
+		// Some sensors fail reads (EIO, ENODATA) while their device sleeps
+		raw, err := os.ReadFile(filepath.Join(path, "temp"))
+		if err != nil {
+			continue
+		}
+		milliCelsius, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+		if err != nil {
+			continue
+		}
+		zoneType, _ := os.ReadFile(filepath.Join(path, "type"))
+		zones = append(zones, monitor.ThermalZone{
+			Zone:               filepath.Base(path),
+			Type:               strings.TrimSpace(string(zoneType)),
+			TemperatureCelsius: float64(milliCelsius) / 1000,
+		})
+	}
+	return zones
+}
+
+// zoneNumber returns the number of a thermal_zone<N> path, -1 if it has none
+func zoneNumber(path string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "thermal_zone"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+package pressure
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+func createTestLogger(t *testing.T) *logger.Logger {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	return log
+}
+
+// fakeRoot creates a root filesystem holding files (path relative to the root -> content)
+func fakeRoot(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return root
+}
+
+func newTestCollector(t *testing.T, root string) *Collector {
+	return NewCollector(filepath.Join(root, "proc"), filepath.Join(root, "sys"), createTestLogger(t))
+}
+
+func TestParseStall(t *testing.T) {
+	stall, err := ParseStall(strings.NewReader(
+		"some avg10=12.50 avg60=4.02 avg300=1.10 total=98765432\n" +
+			"full avg10=3.00 avg60=0.75 avg300=0.20 total=1234567\n"))
+	require.NoError(t, err)
+	assert.Equal(t, monitor.PressureStall{
+		SomeAvg10: 12.5, SomeAvg60: 4.02, SomeAvg300: 1.1, SomeTotalUs: 98765432,
+		FullAvg10: 3, FullAvg60: 0.75, FullAvg300: 0.2, FullTotalUs: 1234567,
+	}, *stall)
+
+	// cpu has no "full" line before Linux 5.13
+	stall, err = ParseStall(strings.NewReader("some avg10=0.30 avg60=0.10 avg300=0.00 total=4242\n"))
+	require.NoError(t, err)
+	assert.Equal(t, monitor.PressureStall{SomeAvg10: 0.3, SomeAvg60: 0.1, SomeTotalUs: 4242}, *stall)
+
+	_, err = ParseStall(strings.NewReader("some avg10=high\n"))
+	assert.ErrorContains(t, err, `invalid field "avg10=high"`)
+
+	_, err = ParseStall(strings.NewReader(""))
+	assert.ErrorContains(t, err, "no some/full line")
+}
+
+func TestCollector_Collect(t *testing.T) {
+	root := fakeRoot(t, map[string]string{
+		"proc/pressure/cpu":    "some avg10=25.00 avg60=20.00 avg300=15.00 total=1000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		"proc/pressure/memory": "some avg10=1.00 avg60=0.50 avg300=0.10 total=200\nfull avg10=0.50 avg60=0.20 avg300=0.05 total=100\n",
+		"proc/pressure/io":     "garbage\n",
+
+		"sys/class/thermal/thermal_zone10/type": "iwlwifi_1\n",
+		"sys/class/thermal/thermal_zone10/temp": "41000\n",
+		"sys/class/thermal/thermal_zone2/type":  "x86_pkg_temp\n",
+		"sys/class/thermal/thermal_zone2/temp":  "87500\n",
+		"sys/class/thermal/thermal_zone0/type":  "acpitz\n",
+		"sys/class/thermal/thermal_zone0/temp":  "27800\n",
+		"sys/class/thermal/thermal_zone1/type":  "pch_skylake\n", // temp unreadable
+	})
+
+	pressure := newTestCollector(t, root).Collect()
+	require.NotNil(t, pressure)
+	require.NotNil(t, pressure.CPU)
+	assert.Equal(t, 25.0, pressure.CPU.SomeAvg10)
+	require.NotNil(t, pressure.Memory)
+	assert.Equal(t, 0.5, pressure.Memory.FullAvg10)
+	assert.Nil(t, pressure.IO, "an unparsable file is skipped")
+	assert.Equal(t, []monitor.ThermalZone{
+		{Zone: "thermal_zone0", Type: "acpitz", TemperatureCelsius: 27.8},
+		{Zone: "thermal_zone2", Type: "x86_pkg_temp", TemperatureCelsius: 87.5},
+		{Zone: "thermal_zone10", Type: "iwlwifi_1", TemperatureCelsius: 41},
+	}, pressure.ThermalZones)
+}
+
+func TestCollector_Collect_Unavailable(t *testing.T) {
+	// Thermal zones without PSI are still reported
+	root := fakeRoot(t, map[string]string{
+		"sys/class/thermal/thermal_zone0/type": "acpitz\n",
+		"sys/class/thermal/thermal_zone0/temp": "30000\n",
+	})
+	pressure := newTestCollector(t, root).Collect()
+	require.NotNil(t, pressure)
+	assert.Nil(t, pressure.CPU)
+	assert.Len(t, pressure.ThermalZones, 1)
+
+	assert.Nil(t, newTestCollector(t, t.TempDir()).Collect(), "nothing to report")
+}
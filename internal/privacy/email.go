@@ -0,0 +1,151 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"xhub-agent/pkg/logger"
+)
+
+// EmailMode selects how user emails appear in outgoing payloads
+type EmailMode string
+
+const (
+	EmailPlain     EmailMode = "plain"     // Emails are reported as-is
+	EmailHashed    EmailMode = "hashed"    // Keyed HMAC of the email
+	EmailPseudonym EmailMode = "pseudonym" // Stable generated label (user-0001), persisted in the state file
+)
+
+// HashLength is the number of hex characters kept from the email HMAC
+const HashLength = 16
+
+// ParseEmailMode parses an email_reporting value (empty means plain)
+func ParseEmailMode(value string) (EmailMode, error) {
+	switch mode := EmailMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return EmailPlain, nil
+	case EmailPlain, EmailHashed, EmailPseudonym:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown email_reporting %q (known: plain, hashed, pseudonym)", value)
+	}
+}
+
+// EmailMapper replaces a user email by the identifier reported to xhub
+type EmailMapper interface {
+	MapEmail(email string) string
+}
+
+// Hasher maps emails to a truncated HMAC-SHA256 under a secret key
+type Hasher struct {
+	key []byte
+}
+
+// NewHasher creates a hasher; key must not be empty
+func NewHasher(key string) (*Hasher, error) {
+	if key == "" {
+		return nil, fmt.Errorf("email_hash_key is required for email_reporting: hashed")
+	}
+	return &Hasher{key: []byte(key)}, nil
+}
+
+// MapEmail returns the hex HMAC of email truncated to HashLength ("" stays "")
+func (h *Hasher) MapEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(email))
+	return hex.EncodeToString(mac.Sum(nil))[:HashLength]
+}
+
+// PseudonymStore assigns and persists a label per email
+type PseudonymStore interface {
+	Pseudonym(email string) (string, error)
+}
+
+// Pseudonymizer maps emails to the stable labels of a PseudonymStore
+type Pseudonymizer struct {
+	store  PseudonymStore
+	logger *logger.Logger
+	warned bool // A persistence failure has been logged
+	mutex  sync.Mutex
+}
+
+// NewPseudonymizer creates a pseudonymizer backed by store
+func NewPseudonymizer(store PseudonymStore, logger *logger.Logger) *Pseudonymizer {
+	return &Pseudonymizer{store: store, logger: logger}
+}
+
+// MapEmail returns the label of email ("" stays ""). A label that could not be persisted
+// is still used; it only risks changing after a restart.
+func (p *Pseudonymizer) MapEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	label, err := p.store.Pseudonym(email)
+	if err != nil {
+		p.mutex.Lock()
+		if !p.warned {
+			p.logger.Warnf("⚠️  Failed to persist email pseudonyms, labels may change after a restart: %v", err)
+			p.warned = true
+		}
+		p.mutex.Unlock()
+	}
+	return label
+}
+
+// MapMessageEmails rewrites, in place, every string field of msg (and of its nested messages)
+// whose name contains "email". It is applied to every outgoing request, so new RPCs carrying
+// emails are covered without changes here.
+//
+// Out of scope: emails embedded in subscription headers or inside the node configs of the
+// subscription content are not rewritten.
+func MapMessageEmails(msg proto.Message, mapper EmailMapper) {
+	if msg == nil || mapper == nil {
+		return
+	}
+	mapFields(msg.ProtoReflect(), mapper)
+}
+
+// mapFields walks the populated fields of m
+func mapFields(m protoreflect.Message, mapper EmailMapper) {
+	var singular []protoreflect.FieldDescriptor // Set after the walk, not while ranging
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		isEmail := strings.Contains(strings.ToLower(string(fd.Name())), "email")
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Kind() == protoreflect.MessageKind {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					mapFields(mv.Message(), mapper)
+					return true
+				})
+			}
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				switch {
+				case fd.Kind() == protoreflect.MessageKind:
+					mapFields(list.Get(i).Message(), mapper)
+				case isEmail && fd.Kind() == protoreflect.StringKind:
+					list.Set(i, protoreflect.ValueOfString(mapper.MapEmail(list.Get(i).String())))
+				}
+			}
+		case fd.Kind() == protoreflect.MessageKind:
+			mapFields(v.Message(), mapper)
+		case isEmail && fd.Kind() == protoreflect.StringKind:
+			singular = append(singular, fd)
+		}
+		return true
+	})
+	for _, fd := range singular {
+		m.Set(fd, protoreflect.ValueOfString(mapper.MapEmail(m.Get(fd).String())))
+	}
+}
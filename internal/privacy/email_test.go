@@ -0,0 +1,97 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/pkg/logger"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// memoryStore numbers emails in order of first use
+type memoryStore struct {
+	labels map[string]string
+	err    error
+}
+
+func (s *memoryStore) Pseudonym(email string) (string, error) {
+	if label, ok := s.labels[email]; ok {
+		return label, nil
+	}
+	label := fmt.Sprintf("user-%d", len(s.labels)+1)
+	s.labels[email] = label
+	return label, s.err
+}
+
+func TestParseEmailMode(t *testing.T) {
+	for value, want := range map[string]EmailMode{"": EmailPlain, "plain": EmailPlain, "Hashed": EmailHashed, " pseudonym ": EmailPseudonym} {
+		mode, err := ParseEmailMode(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, mode, value)
+	}
+	_, err := ParseEmailMode("encrypted")
+	assert.Error(t, err)
+}
+
+func TestHasher(t *testing.T) {
+	_, err := NewHasher("")
+	assert.Error(t, err, "hashed mode requires a key")
+
+	hasher, err := NewHasher("key-1")
+	require.NoError(t, err)
+	other, err := NewHasher("key-2")
+	require.NoError(t, err)
+
+	hashed := hasher.MapEmail("alice@example.com")
+	assert.Len(t, hashed, HashLength)
+	assert.Equal(t, hashed, hasher.MapEmail("alice@example.com"), "stable for one key")
+	assert.NotEqual(t, hashed, hasher.MapEmail("bob@example.com"))
+	assert.NotEqual(t, hashed, other.MapEmail("alice@example.com"), "keyed")
+	assert.Empty(t, hasher.MapEmail(""))
+}
+
+func TestPseudonymizer(t *testing.T) {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	defer log.Close()
+
+	store := &memoryStore{labels: map[string]string{}, err: errors.New("read-only")}
+	p := NewPseudonymizer(store, log)
+
+	alice := p.MapEmail("alice@example.com")
+	assert.Equal(t, "user-1", alice, "labels are used even when they cannot be persisted")
+	assert.Equal(t, alice, p.MapEmail("alice@example.com"))
+	assert.Equal(t, "user-2", p.MapEmail("bob@example.com"))
+	assert.Empty(t, p.MapEmail(""))
+}
+
+func TestMapMessageEmails(t *testing.T) {
+	hasher, err := NewHasher("key")
+	require.NoError(t, err)
+
+	sub := &pb.SubscriptionReportRequest{
+		Uuid: "agent-uuid",
+		Subscriptions: []*pb.SubscriptionData{
+			{SubId: "sub-1", Email: "alice@example.com", NodeConfig: "bm9kZQ=="},
+			{SubId: "sub-2"},
+		},
+	}
+	MapMessageEmails(sub, hasher)
+	assert.Equal(t, hasher.MapEmail("alice@example.com"), sub.Subscriptions[0].Email)
+	assert.Equal(t, "sub-1", sub.Subscriptions[0].SubId, "other fields are untouched")
+	assert.Equal(t, "bm9kZQ==", sub.Subscriptions[0].NodeConfig)
+	assert.Empty(t, sub.Subscriptions[1].Email)
+	assert.Equal(t, "agent-uuid", sub.Uuid)
+
+	online := &pb.OnlineUsersReportRequest{Uuid: "agent-uuid", OnlineEmails: []string{"alice@example.com", "bob@example.com"}}
+	MapMessageEmails(online, hasher)
+	assert.Equal(t, []string{hasher.MapEmail("alice@example.com"), hasher.MapEmail("bob@example.com")}, online.OnlineEmails)
+
+	MapMessageEmails(nil, hasher)
+	MapMessageEmails(online, nil)
+}
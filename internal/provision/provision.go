@@ -0,0 +1,172 @@
+package provision
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"xhub-agent/internal/inbound"
+)
+
+// Actions of a provisioning request
+const (
+	AddClient    = "add_client"    // Add client_json to the inbound
+	UpdateClient = "update_client" // Replace client_id of the inbound with client_json
+	RemoveClient = "remove_client" // Delete client_id from the inbound
+)
+
+const (
+	// MaxKeyLength caps the idempotency key of a request
+	MaxKeyLength = 128
+	// MaxAppliedKeys is the number of applied keys remembered for replays
+	MaxAppliedKeys = 4096
+	// KeyRetention is how long an applied key is remembered
+	KeyRetention = 24 * time.Hour
+)
+
+// Request is a client change pushed by xhub
+type Request struct {
+	Key       string // Idempotency key
+	Action    string
+	InboundID int
+	ClientID  string // update_client, remove_client
+	Client    []byte // JSON client object (add_client, update_client)
+}
+
+// Result is the outcome of a request
+type Result struct {
+	Key      string
+	Action   string
+	Err      string        // Empty on success
+	Replayed bool          // The key was applied before, the change was not repeated
+	Duration time.Duration // Time spent applying the change
+}
+
+// Panel is the part of the 3x-ui inbound client the provisioner needs
+type Panel interface {
+	AddClient(inboundID int, user inbound.User) error
+	UpdateClient(inboundID int, clientID string, user inbound.User) error
+	RemoveClient(inboundID int, clientID string) error
+}
+
+// applied is a remembered successful result
+type applied struct {
+	result Result
+	at     time.Time
+}
+
+// Provisioner applies provisioning requests to the panel at most once per idempotency key.
+// Only successful changes are remembered, so a failed request may be resent with its key.
+// Keys are kept in memory: a restarted agent applies a resent request again, which 3x-ui
+// rejects for a client that already exists.
+type Provisioner struct {
+	panel        Panel
+	authenticate func() error // Logs into the panel if needed, before a change
+
+	mutex   sync.Mutex
+	applied map[string]applied
+	order   []string // Keys in the order they were applied, for eviction
+	now     func() time.Time
+}
+
+// NewProvisioner creates a provisioner changing panel, calling authenticate before each change
+func NewProvisioner(panel Panel, authenticate func() error) *Provisioner {
+	return &Provisioner{
+		panel:        panel,
+		authenticate: authenticate,
+		applied:      make(map[string]applied),
+		now:          time.Now,
+	}
+}
+
+// Apply applies req unless its key was applied before, in which case the stored result is
+// returned with Replayed set. Invalid and failed requests have Err set.
+func (p *Provisioner) Apply(req Request) Result {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	result := Result{Key: req.Key, Action: req.Action}
+	if err := validate(req); err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	if previous, ok := p.applied[req.Key]; ok && p.now().Sub(previous.at) < KeyRetention {
+		if previous.result.Action != req.Action {
+			result.Err = fmt.Sprintf("idempotency key %q was already used for %s", req.Key, previous.result.Action)
+			return result
+		}
+		replay := previous.result
+		replay.Replayed = true
+		return replay
+	}
+
+	start := p.now()
+	err := p.apply(req)
+	result.Duration = p.now().Sub(start)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	p.remember(result)
+	return result
+}
+
+// apply performs the change of a validated request
+func (p *Provisioner) apply(req Request) error {
+	var user inbound.User
+	if req.Action != RemoveClient {
+		var err error
+		if user, err = inbound.ParseUser(req.Client); err != nil {
+			return err
+		}
+	}
+	if p.authenticate != nil {
+		if err := p.authenticate(); err != nil {
+			return err
+		}
+	}
+
+	switch req.Action {
+	case AddClient:
+		return p.panel.AddClient(req.InboundID, user)
+	case UpdateClient:
+		return p.panel.UpdateClient(req.InboundID, req.ClientID, user)
+	default:
+		return p.panel.RemoveClient(req.InboundID, req.ClientID)
+	}
+}
+
+// remember stores a successful result, evicting expired keys and the oldest ones over
+// MaxAppliedKeys
+func (p *Provisioner) remember(result Result) {
+	now := p.now()
+	for len(p.order) > 0 {
+		oldest := p.order[0]
+		if len(p.order) < MaxAppliedKeys && now.Sub(p.applied[oldest].at) < KeyRetention {
+			break
+		}
+		delete(p.applied, oldest)
+		p.order = p.order[1:]
+	}
+	if _, ok := p.applied[result.Key]; !ok {
+		p.order = append(p.order, result.Key)
+	}
+	p.applied[result.Key] = applied{result: result, at: now}
+}
+
+// validate checks the fields a request needs for its action
+func validate(req Request) error {
+	switch {
+	case req.Key == "":
+		return fmt.Errorf("missing idempotency key")
+	case len(req.Key) > MaxKeyLength:
+		return fmt.Errorf("idempotency key longer than %d bytes", MaxKeyLength)
+	case req.Action != AddClient && req.Action != UpdateClient && req.Action != RemoveClient:
+		return fmt.Errorf("unknown action %q (expected %s, %s or %s)", req.Action, AddClient, UpdateClient, RemoveClient)
+	case req.InboundID < 1:
+		return fmt.Errorf("inbound_id must be a positive inbound id")
+	case req.Action != AddClient && req.ClientID == "":
+		return fmt.Errorf("%s needs a client_id", req.Action)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/inbound"
+)
+
+// fakePanel records the changes it receives and fails while err is set
+type fakePanel struct {
+	err     error
+	changes []string
+}
+
+func (p *fakePanel) AddClient(inboundID int, user inbound.User) error {
+	p.changes = append(p.changes, fmt.Sprintf("add %d %s", inboundID, user.Email()))
+	return p.err
+}
+
+func (p *fakePanel) UpdateClient(inboundID int, clientID string, user inbound.User) error {
+	p.changes = append(p.changes, fmt.Sprintf("update %d %s %s", inboundID, clientID, user.Email()))
+	return p.err
+}
+
+func (p *fakePanel) RemoveClient(inboundID int, clientID string) error {
+	p.changes = append(p.changes, fmt.Sprintf("remove %d %s", inboundID, clientID))
+	return p.err
+}
+
+func TestProvisioner_Apply(t *testing.T) {
+	panel := &fakePanel{}
+	logins := 0
+	provisioner := NewProvisioner(panel, func() error { logins++; return nil })
+
+	result := provisioner.Apply(Request{Key: "k1", Action: AddClient, InboundID: 3, Client: []byte(`{"id": "u1", "email": "alice"}`)})
+	assert.Empty(t, result.Err)
+	assert.False(t, result.Replayed)
+	result = provisioner.Apply(Request{Key: "k2", Action: UpdateClient, InboundID: 3, ClientID: "u1", Client: []byte(`{"id": "u1", "email": "alice", "enable": false}`)})
+	assert.Empty(t, result.Err)
+	result = provisioner.Apply(Request{Key: "k3", Action: RemoveClient, InboundID: 3, ClientID: "u1"})
+	assert.Empty(t, result.Err)
+
+	assert.Equal(t, []string{"add 3 alice", "update 3 u1 alice", "remove 3 u1"}, panel.changes)
+	assert.Equal(t, 3, logins)
+}
+
+func TestProvisioner_Idempotency(t *testing.T) {
+	panel := &fakePanel{}
+	provisioner := NewProvisioner(panel, nil)
+	now := time.Now()
+	provisioner.now = func() time.Time { return now }
+	add := Request{Key: "k1", Action: AddClient, InboundID: 3, Client: []byte(`{"email": "alice"}`)}
+
+	require.Empty(t, provisioner.Apply(add).Err)
+	result := provisioner.Apply(add)
+	assert.Empty(t, result.Err)
+	assert.True(t, result.Replayed)
+	assert.Len(t, panel.changes, 1, "a resent request is not applied again")
+
+	// A key reused for another action is refused
+	result = provisioner.Apply(Request{Key: "k1", Action: RemoveClient, InboundID: 3, ClientID: "alice"})
+	assert.Contains(t, result.Err, `idempotency key "k1" was already used for add_client`)
+
+	// Keys are forgotten after KeyRetention
+	now = now.Add(KeyRetention)
+	assert.False(t, provisioner.Apply(add).Replayed)
+	assert.Len(t, panel.changes, 2)
+}
+
+func TestProvisioner_FailedRequestsAreRetried(t *testing.T) {
+	panel := &fakePanel{err: errors.New("API error: duplicate email")}
+	provisioner := NewProvisioner(panel, nil)
+	add := Request{Key: "k1", Action: AddClient, InboundID: 3, Client: []byte(`{"email": "alice"}`)}
+
+	assert.Equal(t, "API error: duplicate email", provisioner.Apply(add).Err)
+	panel.err = nil
+	result := provisioner.Apply(add)
+	assert.Empty(t, result.Err)
+	assert.False(t, result.Replayed)
+	assert.Len(t, panel.changes, 2)
+
+	// The panel is not contacted when logging in fails
+	provisioner = NewProvisioner(panel, func() error { return errors.New("login failed") })
+	assert.Equal(t, "login failed", provisioner.Apply(Request{Key: "k2", Action: RemoveClient, InboundID: 3, ClientID: "alice"}).Err)
+	assert.Len(t, panel.changes, 2)
+}
+
+func TestProvisioner_Eviction(t *testing.T) {
+	provisioner := NewProvisioner(&fakePanel{}, nil)
+	for i := 0; i < MaxAppliedKeys+10; i++ {
+		require.Empty(t, provisioner.Apply(Request{Key: fmt.Sprint(i), Action: RemoveClient, InboundID: 1, ClientID: "c"}).Err)
+	}
+	assert.Len(t, provisioner.applied, MaxAppliedKeys)
+	assert.Len(t, provisioner.order, MaxAppliedKeys)
+	assert.NotContains(t, provisioner.applied, "0")
+	assert.Contains(t, provisioner.applied, fmt.Sprint(MaxAppliedKeys+9))
+}
+
+func TestProvisioner_Validation(t *testing.T) {
+	panel := &fakePanel{}
+	provisioner := NewProvisioner(panel, nil)
+	for message, req := range map[string]Request{
+		"missing idempotency key":                  {Action: AddClient, InboundID: 1},
+		"idempotency key longer than 128 bytes":    {Key: string(make([]byte, 129)), Action: AddClient, InboundID: 1},
+		`unknown action "drop_all"`:                {Key: "k", Action: "drop_all", InboundID: 1},
+		"inbound_id must be a positive inbound id": {Key: "k", Action: AddClient},
+		"remove_client needs a client_id":          {Key: "k", Action: RemoveClient, InboundID: 1},
+		"invalid client: email is required":        {Key: "k", Action: AddClient, InboundID: 1, Client: []byte(`{"id": "u1"}`)},
+		"invalid client: expected a JSON object":   {Key: "k", Action: UpdateClient, InboundID: 1, ClientID: "u1", Client: []byte(`[]`)},
+		"invalid client: enable must be a boolean": {Key: "k", Action: AddClient, InboundID: 1, Client: []byte(`{"email": "a", "enable": 1}`)},
+	} {
+		assert.Contains(t, provisioner.Apply(req).Err, message)
+	}
+	assert.Empty(t, panel.changes)
+}
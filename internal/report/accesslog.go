@@ -0,0 +1,68 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/accesslog"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// ErrAccessSummaryUnsupported is returned when xhub does not implement access summaries
+var ErrAccessSummaryUnsupported = errors.New("xhub does not support access summaries")
+
+// SendAccessSummary reports the access log summary of an interval to xhub. It returns
+// ErrAccessSummaryUnsupported if xhub does not implement the RPC.
+func (r *ReportClient) SendAccessSummary(uuid string, summary accesslog.Summary) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.AccessSummary{
+		Uuid:         uuid,
+		StartedAt:    summary.Start.Unix(),
+		EndedAt:      summary.End.Unix(),
+		Connections:  int64(summary.Connections),
+		TopDomains:   convertDomainCounts(summary.TopDomains),
+		SkippedBytes: summary.SkippedBytes,
+	}
+	for _, user := range summary.Users {
+		req.Users = append(req.Users, &pb.UserAccess{
+			Email:       user.Email,
+			Connections: int64(user.Connections),
+			SourceIps:   int32(user.SourceIPs),
+			TopDomains:  convertDomainCounts(user.TopDomains),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.reportTimeout())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.SendAccessSummary(ctx, req, r.callOptions()...)
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return ErrAccessSummaryUnsupported
+		}
+		return r.withConnectionHint(fmt.Errorf("gRPC access summary failed: %w", err))
+	}
+	if !resp.Success {
+		return rejectedf("access summary rejected: %s", resp.Message)
+	}
+	r.markSuccess("访问日志摘要")
+	return nil
+}
+
+// convertDomainCounts converts the destination counts of an access summary
+func convertDomainCounts(counts []accesslog.DomainCount) []*pb.DomainAccess {
+	var domains []*pb.DomainAccess
+	for _, count := range counts {
+		domains = append(domains, &pb.DomainAccess{Domain: count.Domain, Connections: int64(count.Connections)})
+	}
+	return domains
+}
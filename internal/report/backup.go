@@ -0,0 +1,90 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/backup"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// ErrBackupUnsupported is returned when xhub does not implement the backup report RPC
+var ErrBackupUnsupported = errors.New("xhub does not support backup reports")
+
+// SendBackupReport sends a snapshot of the inbound configuration. It returns
+// ErrBackupUnsupported if xhub does not implement the RPC.
+func (r *ReportClient) SendBackupReport(uuid string, snapshot *backup.Snapshot) error {
+	r.logger.Debugf("📊 Starting gRPC backup report transmission (%d inbounds, %d bytes)...", snapshot.Count, len(snapshot.Inbounds))
+
+	// Ensure connection is established
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.BackupReportRequest{
+		Uuid:         uuid,
+		InboundsJson: snapshot.Inbounds,
+		Sha256:       snapshot.SHA256,
+		InboundCount: int32(snapshot.Count),
+		CapturedAt:   snapshot.CapturedAt.Unix(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.reportTimeout())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.SendBackupReport(ctx, req, r.callOptions()...)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if !ok {
+			if r.shouldLogError(fmt.Sprintf("generic_backup_%s", r.serverAddr)) {
+				r.logger.Errorf("❌ gRPC backup request failed: %v", err)
+			}
+			return r.withConnectionHint(fmt.Errorf("gRPC backup request failed: %w", err))
+		}
+		if st.Code() == codes.Unimplemented {
+			return ErrBackupUnsupported
+		}
+
+		var errorMsg string
+		switch st.Code() {
+		case codes.Unauthenticated:
+			errorMsg = "authentication failed: API key invalid or expired"
+		case codes.InvalidArgument:
+			errorMsg = fmt.Sprintf("request error: invalid backup report format - %s", st.Message())
+		case codes.ResourceExhausted:
+			errorMsg = fmt.Sprintf("backup too large for xhub: %s", st.Message())
+		case codes.DeadlineExceeded:
+			errorMsg = fmt.Sprintf("request timeout: %s", st.Message())
+		case codes.Unavailable:
+			errorMsg = fmt.Sprintf("server unavailable: %s", st.Message())
+		default:
+			errorMsg = fmt.Sprintf("gRPC error [%s]: %s", st.Code(), st.Message())
+		}
+		if r.shouldLogError(fmt.Sprintf("grpc_backup_%s_%s", st.Code(), r.serverAddr)) {
+			r.logger.Errorf("❌ gRPC backup request failed!")
+			r.logger.Errorf("   Server: %s", r.serverAddr)
+			r.logger.Errorf("   UUID: %s", uuid)
+			r.logger.Errorf("   gRPC Status: %s", st.Code())
+			r.logger.Errorf("   Error Message: %s", st.Message())
+		}
+		return r.withConnectionHint(&RPCError{Code: st.Code(), Message: errorMsg})
+	}
+
+	if !resp.Success {
+		if r.shouldLogError(fmt.Sprintf("server_reject_backup_%s", r.serverAddr)) {
+			r.logger.Errorf("❌ Server rejected the backup report: %s", resp.Message)
+		}
+		return rejectedf("backup report failed: %s", resp.Message)
+	}
+
+	r.rpcSucceeded = true
+	r.markSuccess("配置备份上报")
+	r.logger.Debugf("🎉 Backup report successfully sent via gRPC!")
+	return nil
+}
@@ -0,0 +1,64 @@
+package report
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/backup"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// backupServer records the backup reports it receives
+type backupServer struct {
+	pb.UnimplementedReportServiceServer
+	mutex   sync.Mutex
+	backups []*pb.BackupReportRequest
+}
+
+func (s *backupServer) SendBackupReport(ctx context.Context, req *pb.BackupReportRequest) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.backups = append(s.backups, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestReportClient_SendBackupReport(t *testing.T) {
+	server := &backupServer{}
+	client := newStreamClient(t, server)
+	snapshot, err := backup.NewSnapshot([]byte(`{"success":true,"obj":[{"id":1,"port":443}]}`), time.Unix(1700000000, 0))
+	require.NoError(t, err)
+
+	require.NoError(t, client.SendBackupReport("test-uuid", snapshot))
+	require.Len(t, server.backups, 1)
+	req := server.backups[0]
+	assert.Equal(t, "test-uuid", req.Uuid)
+	assert.JSONEq(t, `[{"id":1,"port":443}]`, string(req.InboundsJson))
+	assert.Equal(t, snapshot.SHA256, req.Sha256)
+	assert.Equal(t, int32(1), req.InboundCount)
+	assert.Equal(t, int64(1700000000), req.CapturedAt)
+}
+
+func TestReportClient_SendBackupReportUnsupported(t *testing.T) {
+	client := newStreamClient(t, &pb.UnimplementedReportServiceServer{})
+	snapshot, err := backup.NewSnapshot([]byte(`{"success":true,"obj":[]}`), time.Now())
+	require.NoError(t, err)
+	assert.ErrorIs(t, client.SendBackupReport("test-uuid", snapshot), ErrBackupUnsupported)
+}
+
+func TestCaptureRequest_MasksBackup(t *testing.T) {
+	captured, truncated := captureRequest(&pb.BackupReportRequest{
+		Uuid:         "test-uuid",
+		InboundsJson: []byte(`[{"settings":"{\"clients\":[{\"id\":\"secret-client-id\"}]}"}]`),
+		Sha256:       "abc",
+		InboundCount: 1,
+	})
+	assert.False(t, truncated)
+	assert.NotContains(t, captured, "secret-client-id")
+	assert.NotContains(t, captured, "inboundsJson")
+	assert.Contains(t, captured, "abc")
+}
@@ -0,0 +1,99 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// ErrBatchUnsupported is returned when xhub does not implement batched status reports. The
+// samples must then be sent with SendReport.
+var ErrBatchUnsupported = errors.New("xhub does not support batched status reports")
+
+// StatusSample is the status collected by one cycle, sent in a batch (report_batch_size)
+type StatusSample struct {
+	CollectedAt time.Time
+	Data        *monitor.ServerStatusData
+}
+
+// batchState tracks the support of batched status reports
+type batchState struct {
+	// xhub answered a batch with Unimplemented; batches are not sent until the agent restarts
+	unimplemented atomic.Bool
+}
+
+// BatchReportsAvailable returns whether status samples can be sent with SendStatusBatch
+func (r *ReportClient) BatchReportsAvailable() bool {
+	return !r.batch.unimplemented.Load()
+}
+
+// SendStatusBatch sends status samples, oldest first, in one RPC within ctx and the request
+// timeout. It returns ErrBatchUnsupported if xhub does not implement the RPC.
+func (r *ReportClient) SendStatusBatch(ctx context.Context, uuid string, samples []StatusSample) error {
+	if err := checkEnabled(r.types.Status, "status"); err != nil {
+		return err
+	}
+	if r.batch.unimplemented.Load() {
+		return ErrBatchUnsupported
+	}
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	pendingErrors := r.errorCounters.Pending()
+	req := &pb.StatusBatchRequest{
+		Uuid:        uuid,
+		ErrorCounts: ConvertErrorCounts(pendingErrors),
+		Agent:       r.hostInfo.Load(),
+	}
+	for _, sample := range samples {
+		pbData := ConvertToProto(sample.Data)
+		if pbData == nil {
+			return fmt.Errorf("failed to convert data to protobuf format")
+		}
+		req.Samples = append(req.Samples, &pb.StatusSample{CollectedAtMs: sample.CollectedAt.UnixMilli(), Data: pbData})
+	}
+	r.logger.Debugf("📦 Sending batch of %d status samples", len(req.Samples))
+
+	ctx, cancel := context.WithTimeout(ctx, r.reportTimeout())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.SendStatusBatch(ctx, req, r.callOptions()...)
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			r.batch.unimplemented.Store(true)
+			r.logger.Warnf("⚠️  xhub does not implement batched status reports, sending every sample until restart")
+			return ErrBatchUnsupported
+		}
+		if st, ok := status.FromError(err); ok {
+			if r.shouldLogError(fmt.Sprintf("grpc_batch_%s_%s", st.Code(), r.serverAddr)) {
+				r.logger.Errorf("❌ gRPC status batch failed [%s]: %s", st.Code(), st.Message())
+			}
+			return r.withConnectionHint(&RPCError{Code: st.Code(), Message: fmt.Sprintf("gRPC error [%s]: %s", st.Code(), st.Message())})
+		}
+		return r.withConnectionHint(fmt.Errorf("gRPC status batch failed: %w", err))
+	}
+	if !resp.Success {
+		if r.shouldLogError(fmt.Sprintf("server_reject_batch_%s", r.serverAddr)) {
+			r.logger.Errorf("❌ Server rejected the status batch: %s", resp.Message)
+		}
+		return rejectedf("status batch rejected: %s", resp.Message)
+	}
+
+	// Error counts were delivered, start the next window
+	r.errorCounters.Ack(pendingErrors)
+	r.rpcSucceeded = true
+	r.markSuccess("批量监控数据上报")
+	r.logger.Debugf("🎉 Status batch successfully sent via gRPC!")
+	return nil
+}
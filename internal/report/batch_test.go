@@ -0,0 +1,94 @@
+package report
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"xhub-agent/internal/errstats"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// batchServer records the status batches, or does not implement them
+type batchServer struct {
+	pb.UnimplementedReportServiceServer
+	implemented bool
+
+	mutex   sync.Mutex
+	batches []*pb.StatusBatchRequest
+}
+
+func (s *batchServer) SendStatusBatch(ctx context.Context, req *pb.StatusBatchRequest) (*pb.ReportResponse, error) {
+	if !s.implemented {
+		return s.UnimplementedReportServiceServer.SendStatusBatch(ctx, req)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batches = append(s.batches, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func newBatchClient(t *testing.T, server *batchServer) *ReportClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestReportClient_SendStatusBatch(t *testing.T) {
+	server := &batchServer{implemented: true}
+	client := newBatchClient(t, server)
+	counters := errstats.NewCounters()
+	counters.RecordCategory(errstats.InternalPanic)
+	client.SetErrorCounters(counters)
+
+	start := time.UnixMilli(1760000000000)
+	samples := []StatusSample{
+		{CollectedAt: start, Data: combinedTestData()},
+		{CollectedAt: start.Add(2 * time.Second), Data: combinedTestData()},
+	}
+	require.NoError(t, client.SendStatusBatch(context.Background(), "test-uuid", samples))
+
+	require.Len(t, server.batches, 1)
+	batch := server.batches[0]
+	assert.Equal(t, "test-uuid", batch.Uuid)
+	require.Len(t, batch.Samples, 2)
+	assert.Equal(t, int64(1760000000000), batch.Samples[0].CollectedAtMs)
+	assert.Equal(t, int64(1760000002000), batch.Samples[1].CollectedAtMs)
+	assert.Equal(t, float64(10), batch.Samples[1].Data.Cpu)
+	assert.Len(t, batch.ErrorCounts, 1)
+	assert.Empty(t, counters.Pending(), "error counts are acknowledged")
+	assert.True(t, client.BatchReportsAvailable())
+}
+
+func TestReportClient_SendStatusBatch_Unimplemented(t *testing.T) {
+	client := newBatchClient(t, &batchServer{})
+	samples := []StatusSample{{CollectedAt: time.Now(), Data: combinedTestData()}}
+
+	assert.ErrorIs(t, client.SendStatusBatch(context.Background(), "test-uuid", samples), ErrBatchUnsupported)
+	assert.False(t, client.BatchReportsAvailable(), "not used again until restart")
+	assert.ErrorIs(t, client.SendStatusBatch(context.Background(), "test-uuid", samples), ErrBatchUnsupported)
+}
+
+func TestReportClient_SendStatusBatch_StatusDisabled(t *testing.T) {
+	server := &batchServer{implemented: true}
+	client := newBatchClient(t, server)
+	client.SetReportTypes(ReportTypes{Subscriptions: true, OnlineUsers: true})
+
+	err := client.SendStatusBatch(context.Background(), "test-uuid", []StatusSample{{CollectedAt: time.Now(), Data: combinedTestData()}})
+	assert.ErrorIs(t, err, ErrReportDisabled)
+	assert.Empty(t, server.batches)
+}
@@ -0,0 +1,258 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"xhub-agent/internal/sanitize"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// Failure capture limits
+const (
+	MaxCapturedRequest       = 64 * 1024 // Bytes of request JSON retained per failed RPC
+	MaxPersistedFailureFiles = 20        // Failure files kept in the data directory
+)
+
+// truncatedMarker is appended to captured requests cut at MaxCapturedRequest
+const truncatedMarker = "...[truncated]"
+
+// FailedRPC is the captured request/response pair of the last failed RPC of one type.
+// Secrets (subscription IDs, node configs) are masked; the API key travels in metadata
+// and is never captured.
+type FailedRPC struct {
+	Method    string    `json:"method"`            // RPC name (e.g. SendReport)
+	StartedAt time.Time `json:"startedAt"`         // When the RPC was sent
+	FailedAt  time.Time `json:"failedAt"`          // When the failure was observed
+	Code      string    `json:"code"`              // gRPC status code, "Rejected" when xhub answered success=false
+	Message   string    `json:"message"`           // Status or response message
+	Details   []string  `json:"details,omitempty"` // Status details
+	ConnState string    `json:"connState"`         // Connection state at failure time
+	Request   string    `json:"request"`           // Request protojson (masked, size capped)
+	Truncated bool      `json:"truncated"`         // Request was cut at MaxCapturedRequest
+}
+
+// failureCapture keeps the last failure per RPC type and optionally persists them
+type failureCapture struct {
+	last       map[string]FailedRPC
+	persistDir string // Empty disables persistence
+	maxFiles   int
+	mutex      sync.Mutex
+}
+
+// newFailureCapture creates an in-memory failure capture
+func newFailureCapture() *failureCapture {
+	return &failureCapture{last: make(map[string]FailedRPC), maxFiles: MaxPersistedFailureFiles}
+}
+
+// SetFailurePersistence writes every captured failure to dir as well, keeping the newest
+// maxFiles files (MaxPersistedFailureFiles if maxFiles <= 0). An empty dir disables it.
+func (r *ReportClient) SetFailurePersistence(dir string, maxFiles int) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create failed payload directory: %w", err)
+		}
+	}
+	if maxFiles <= 0 {
+		maxFiles = MaxPersistedFailureFiles
+	}
+
+	r.failures.mutex.Lock()
+	defer r.failures.mutex.Unlock()
+	r.failures.persistDir = dir
+	r.failures.maxFiles = maxFiles
+	return nil
+}
+
+// LastFailures returns the last captured failure of every RPC type, sorted by method
+func (r *ReportClient) LastFailures() []FailedRPC {
+	r.failures.mutex.Lock()
+	defer r.failures.mutex.Unlock()
+
+	result := make([]FailedRPC, 0, len(r.failures.last))
+	for _, failure := range r.failures.last {
+		result = append(result, failure)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Method < result[j].Method })
+	return result
+}
+
+// captureFailures is a unary interceptor recording failed and rejected RPCs
+func (r *ReportClient) captureFailures(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	startedAt := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	var failure FailedRPC
+	switch resp, _ := reply.(*pb.ReportResponse); {
+	case err != nil:
+		st := status.Convert(err)
+		failure = FailedRPC{Code: st.Code().String(), Message: st.Message()}
+		for _, detail := range st.Proto().GetDetails() {
+			failure.Details = append(failure.Details, detail.String())
+		}
+	case resp != nil && !resp.Success:
+		failure = FailedRPC{Code: "Rejected", Message: resp.Message}
+	default:
+		return nil
+	}
+
+	failure.Method = path.Base(method)
+	failure.StartedAt = startedAt
+	failure.FailedAt = time.Now()
+	failure.ConnState = cc.GetState().String()
+	failure.Request, failure.Truncated = captureRequest(req)
+	r.failures.record(failure, r.logf)
+	return err
+}
+
+// logf logs persistence problems at debug level (capture must never disturb reporting)
+func (r *ReportClient) logf(format string, args ...interface{}) {
+	if r.logger != nil {
+		r.logger.Debugf(format, args...)
+	}
+}
+
+// record stores failure as the last one of its method and persists it if enabled
+func (c *failureCapture) record(failure FailedRPC, logf func(string, ...interface{})) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.last[failure.Method] = failure
+	if c.persistDir == "" {
+		return
+	}
+	if err := c.persist(failure); err != nil {
+		logf("Failed to persist failed %s payload: %v", failure.Method, err)
+	}
+}
+
+// persist writes failure to a timestamped file and removes the oldest files beyond maxFiles
+func (c *failureCapture) persist(failure FailedRPC) error {
+	data, err := json.MarshalIndent(failure, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("failed-%s-%s.json", failure.FailedAt.UTC().Format("20060102T150405.000000000"), failure.Method)
+	if err := os.WriteFile(filepath.Join(c.persistDir, name), data, 0600); err != nil {
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(c.persistDir, "failed-*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for len(files) > c.maxFiles {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
+
+// captureRequest returns the masked protojson of req, capped at MaxCapturedRequest.
+// Subscription lists are masked element by element and stop once the cap is reached,
+// so a large subscription payload is never copied in full.
+func captureRequest(req interface{}) (string, bool) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return "", false
+	}
+
+	truncated := false
+	switch typed := msg.(type) {
+	case *pb.SubscriptionReportRequest:
+		msg, truncated = maskSubscriptions(typed)
+	case *pb.CombinedReportRequest:
+		masked := &pb.CombinedReportRequest{
+			Uuid:           typed.Uuid,
+			Data:           typed.Data,
+			ErrorCounts:    typed.ErrorCounts,
+			OnlineUsers:    typed.OnlineUsers,
+			OnlineUsersAge: typed.OnlineUsersAge,
+			Agent:          typed.Agent,
+		}
+		if typed.Subscriptions != nil {
+			masked.Subscriptions, truncated = maskSubscriptions(typed.Subscriptions)
+		}
+		msg = masked
+	case *pb.BackupReportRequest:
+		// The inbound configuration carries client credentials
+		msg = &pb.BackupReportRequest{
+			Uuid:         typed.Uuid,
+			Sha256:       typed.Sha256,
+			InboundCount: typed.InboundCount,
+			CapturedAt:   typed.CapturedAt,
+		}
+	}
+
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("<unserializable: %v>", err), false
+	}
+
+	request := string(data)
+	if len(request) > MaxCapturedRequest {
+		request = sanitize.String(request[:MaxCapturedRequest])
+		truncated = true
+	}
+	if truncated {
+		request += truncatedMarker
+	}
+	return request, truncated
+}
+
+// maskSubscriptions returns a copy of subReq with masked SubIDs and node configs
+func maskSubscriptions(subReq *pb.SubscriptionReportRequest) (*pb.SubscriptionReportRequest, bool) {
+	masked := &pb.SubscriptionReportRequest{Uuid: subReq.Uuid}
+	size := 0
+	for _, sub := range subReq.Subscriptions {
+		maskedSub := &pb.SubscriptionData{
+			SubId:      maskSecret(sub.SubId),
+			Email:      sub.Email,
+			NodeConfig: fmt.Sprintf("<masked %d bytes>", len(sub.NodeConfig)),
+			JsonConfig: maskedLength(sub.JsonConfig),
+			Headers:    sub.Headers,
+
+			WireguardConfig: maskedLength(sub.WireguardConfig),
+
+			ContentStaleSuspect: sub.ContentStaleSuspect,
+			StaleReason:         sub.StaleReason,
+		}
+		if size += proto.Size(maskedSub); size > MaxCapturedRequest {
+			return masked, true
+		}
+		masked.Subscriptions = append(masked.Subscriptions, maskedSub)
+	}
+	return masked, false
+}
+
+// maskedLength replaces content with its length, keeping an empty content empty
+func maskedLength(content string) string {
+	if content == "" {
+		return ""
+	}
+	return fmt.Sprintf("<masked %d bytes>", len(content))
+}
+
+// maskSecret keeps a short prefix of a secret for correlation
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + "****"
+}
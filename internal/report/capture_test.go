@@ -0,0 +1,142 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+func TestReportClient_CapturesFailurePerRPC(t *testing.T) {
+	testLogger := createTestLogger(t)
+
+	mockServer := &mockReportServer{shouldError: codes.InvalidArgument}
+	addr, cleanup := setupGRPCTestServer(t, mockServer)
+	defer cleanup()
+
+	client := NewReportClient(addr, "secret-api-key", testLogger)
+	defer client.Close()
+	assert.Empty(t, client.LastFailures())
+
+	require.Error(t, client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0}))
+
+	// The mock does not implement the subscription RPC
+	subs := []SubscriptionData{{SubID: "abcdef1234567890", Email: "user@example.com", NodeConfig: "dmxlc3M6Ly9zZWNyZXQ="}}
+	require.Error(t, client.SendSubscriptionReport("test-uuid-123", subs))
+
+	failures := client.LastFailures()
+	require.Len(t, failures, 2)
+
+	assert.Equal(t, "SendReport", failures[0].Method)
+	assert.Equal(t, "InvalidArgument", failures[0].Code)
+	assert.Equal(t, "test error", failures[0].Message)
+	assert.Contains(t, failures[0].Request, `"uuid":"test-uuid-123"`)
+	assert.NotEmpty(t, failures[0].ConnState)
+	assert.False(t, failures[0].StartedAt.After(failures[0].FailedAt))
+	assert.NotContains(t, failures[0].Request, "secret-api-key")
+
+	assert.Equal(t, "SendSubscriptionReport", failures[1].Method)
+	assert.Equal(t, "Unimplemented", failures[1].Code)
+
+	// A newer failure of the same RPC overwrites the previous one
+	mockServer.shouldError = codes.OK
+	mockServer.response = &pb.ReportResponse{Success: false, Message: "payload rejected"}
+	err := client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 20.0})
+	assert.ErrorIs(t, err, ErrReportRejected)
+	assert.EqualError(t, err, "report failed: payload rejected")
+	assert.Equal(t, errstats.ReportRejected, errstats.Categorize(err))
+
+	failures = client.LastFailures()
+	require.Len(t, failures, 2)
+	assert.Equal(t, "Rejected", failures[0].Code)
+	assert.Equal(t, "payload rejected", failures[0].Message)
+	assert.Contains(t, failures[0].Request, `"cpu":20`)
+}
+
+func TestCaptureRequest_MasksSecrets(t *testing.T) {
+	req := &pb.SubscriptionReportRequest{
+		Uuid: "test-uuid-123",
+		Subscriptions: []*pb.SubscriptionData{
+			{SubId: "abcdef1234567890", Email: "user@example.com", NodeConfig: "dmxlc3M6Ly9zZWNyZXQ="},
+			{SubId: "short", Email: "other@example.com"},
+		},
+	}
+
+	captured, truncated := captureRequest(req)
+	assert.False(t, truncated)
+	assert.NotContains(t, captured, "abcdef1234567890")
+	assert.NotContains(t, captured, "dmxlc3M6Ly9zZWNyZXQ=")
+	assert.NotContains(t, captured, "short")
+	assert.Contains(t, captured, "abcd****")
+	assert.Contains(t, captured, "<masked 20 bytes>")
+	assert.Contains(t, captured, "user@example.com")
+
+	// The original request is untouched
+	assert.Equal(t, "abcdef1234567890", req.Subscriptions[0].SubId)
+}
+
+func TestCaptureRequest_SizeCap(t *testing.T) {
+	req := &pb.SubscriptionReportRequest{Uuid: "test-uuid-123"}
+	for i := 0; i < 5000; i++ {
+		req.Subscriptions = append(req.Subscriptions, &pb.SubscriptionData{
+			SubId:      fmt.Sprintf("sub-%012d", i),
+			Email:      fmt.Sprintf("user-%d@example.com", i),
+			NodeConfig: strings.Repeat("x", 4096),
+		})
+	}
+
+	captured, truncated := captureRequest(req)
+	assert.True(t, truncated)
+	assert.True(t, strings.HasSuffix(captured, truncatedMarker))
+	assert.LessOrEqual(t, len(captured), MaxCapturedRequest+len(truncatedMarker))
+	assert.NotContains(t, captured, "user-4999@example.com")
+
+	// Non-subscription requests are cut at the cap as well
+	status := &pb.OnlineUsersReportRequest{Uuid: "test-uuid-123"}
+	for i := 0; i < 10000; i++ {
+		status.OnlineEmails = append(status.OnlineEmails, fmt.Sprintf("user-%d@example.com", i))
+	}
+	captured, truncated = captureRequest(status)
+	assert.True(t, truncated)
+	assert.Equal(t, MaxCapturedRequest+len(truncatedMarker), len(captured))
+}
+
+func TestFailureCapture_PersistenceRotation(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "failed-payloads")
+	client := NewReportClient("localhost:9090", "test-api-key", createTestLogger(t))
+	require.NoError(t, client.SetFailurePersistence(dir, 3))
+
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		client.failures.record(FailedRPC{
+			Method:   "SendReport",
+			FailedAt: base.Add(time.Duration(i) * time.Second),
+			Code:     "Unavailable",
+			Message:  fmt.Sprintf("failure %d", i),
+		}, client.logf)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "failed-*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	// The oldest files were rotated out
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "failure 2")
+
+	// Only the newest failure is kept in memory
+	failures := client.LastFailures()
+	require.Len(t, failures, 1)
+	assert.Equal(t, "failure 4", failures[0].Message)
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/certrenew"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// ErrCertRenewalUnsupported is returned when xhub does not implement certificate renewal reports
+var ErrCertRenewalUnsupported = errors.New("xhub does not support certificate renewal reports")
+
+// SendCertRenewalReport reports the outcome of a certificate renewal to xhub. It returns
+// ErrCertRenewalUnsupported if xhub does not implement the RPC.
+func (r *ReportClient) SendCertRenewalReport(uuid string, result certrenew.Result) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.CertRenewalReport{
+		Uuid:       uuid,
+		Trigger:    result.Trigger,
+		CommandId:  result.CommandID,
+		Client:     result.Client,
+		Domain:     result.Domain,
+		Success:    result.Error == "",
+		Error:      result.Error,
+		Reloaded:   result.Reloaded,
+		StartedAt:  result.StartedAt.Unix(),
+		DurationMs: result.Duration.Milliseconds(),
+		Output:     result.Output,
+	}
+	for _, cert := range result.Certificates {
+		renewed := &pb.RenewedCertificate{Path: cert.Path, Origin: cert.Origin, NotAfter: cert.NotAfter.Unix()}
+		if !cert.PreviousNotAfter.IsZero() {
+			renewed.PreviousNotAfter = cert.PreviousNotAfter.Unix()
+		}
+		req.Certificates = append(req.Certificates, renewed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.reportTimeout())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.SendCertRenewalReport(ctx, req, r.callOptions()...)
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return ErrCertRenewalUnsupported
+		}
+		return r.withConnectionHint(fmt.Errorf("gRPC certificate renewal report failed: %w", err))
+	}
+	if !resp.Success {
+		return rejectedf("certificate renewal report rejected: %s", resp.Message)
+	}
+	r.markSuccess("证书续期上报")
+	return nil
+}
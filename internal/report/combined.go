@@ -0,0 +1,255 @@
+package report
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// CombinedCapability is the capability name of the combined report RPC, sent by the agent
+// in x-agent-capabilities and advertised by xhub in the x-xhub-capabilities response header
+const CombinedCapability = "combined-report"
+
+// ErrCombinedUnsupported is returned when xhub does not implement the combined report RPC.
+// The cycle must then be sent as separate RPCs.
+var ErrCombinedUnsupported = errors.New("xhub does not support combined reports")
+
+// CombinedOnlineUsers is the online users part of a combined report
+type CombinedOnlineUsers struct {
+	Emails []string
+	Users  []monitor.OnlineUser // Connection details (online_user_details), nil when off
+	Age    time.Duration        // > 0 when a previously fetched list is reused
+}
+
+// combinedState tracks the report_combined negotiation
+type combinedState struct {
+	enabled   bool        // report_combined
+	supported atomic.Bool // xhub advertised the capability in its last response
+	// xhub answered a combined report with Unimplemented; its advertisement is ignored
+	// until the agent restarts
+	unimplemented atomic.Bool
+	// Fingerprint of the subscriptions xhub last accepted in a combined report; unchanged
+	// subscriptions are left out of the next one
+	subscriptions string
+}
+
+// SetCombinedReports enables the combined report RPC (report_combined). It is used only
+// once xhub advertises the capability in a response; until then cycles use separate RPCs.
+func (r *ReportClient) SetCombinedReports(enabled bool) {
+	r.combined.enabled = enabled
+}
+
+// ResendSubscriptions makes the next combined report include the subscriptions even if they
+// equal the ones xhub last accepted
+func (r *ReportClient) ResendSubscriptions() {
+	r.combined.subscriptions = ""
+}
+
+// CombinedReportsAvailable returns whether the next cycle can use SendCombinedReport
+func (r *ReportClient) CombinedReportsAvailable() bool {
+	return r.combined.enabled && r.combined.supported.Load() && !r.combined.unimplemented.Load()
+}
+
+// negotiateCapabilities is a unary interceptor reading the capabilities xhub advertises in
+// its response headers (combined and delta reports). A combined report answered with
+// Unimplemented disables the RPC.
+func (r *ReportClient) negotiateCapabilities(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	var header metadata.MD
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+	switch {
+	case err == nil:
+		r.setCapabilities(parseCapabilities(header))
+	case status.Code(err) == codes.Unimplemented && path.Base(method) == "SendCombinedReport":
+		r.combined.unimplemented.Store(true)
+	}
+	return err
+}
+
+// setCapabilities records the capabilities xhub advertised in a response
+func (r *ReportClient) setCapabilities(capabilities []string) {
+	r.setCombinedSupported(slices.Contains(capabilities, CombinedCapability))
+	r.setDeltaSupported(slices.Contains(capabilities, DeltaCapability))
+}
+
+// setCombinedSupported records the negotiated capability, logging changes when enabled
+func (r *ReportClient) setCombinedSupported(supported bool) {
+	if r.combined.supported.Swap(supported) == supported || !r.combined.enabled || r.combined.unimplemented.Load() {
+		return
+	}
+	if supported {
+		r.logger.Infof("🧩 xhub supports combined reports, sending one RPC per cycle")
+	} else {
+		r.logger.Infof("🧩 xhub no longer advertises combined reports, using separate RPCs")
+	}
+}
+
+// parseCapabilities returns the capabilities listed in the x-xhub-capabilities header
+func parseCapabilities(header metadata.MD) []string {
+	var capabilities []string
+	for _, value := range header.Get("x-xhub-capabilities") {
+		for _, capability := range strings.Split(value, ",") {
+			if capability = strings.TrimSpace(capability); capability != "" {
+				capabilities = append(capabilities, capability)
+			}
+		}
+	}
+	return capabilities
+}
+
+// subscriptionsFingerprint hashes the converted subscriptions (deterministic encoding)
+func subscriptionsFingerprint(subscriptions []*pb.SubscriptionData) string {
+	encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(&pb.SubscriptionReportRequest{Subscriptions: subscriptions})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// SendCombinedReport sends the status, the online users (nil leaves them out) and the
+// subscriptions in one RPC. Subscriptions are left out while they equal the ones xhub last
+// accepted, and sent as a split subscription report when they exceed the chunk size.
+// It returns ErrCombinedUnsupported if xhub does not implement the RPC.
+func (r *ReportClient) SendCombinedReport(uuid string, data *monitor.ServerStatusData, online *CombinedOnlineUsers, subscriptions []SubscriptionData) error {
+	if err := checkEnabled(r.types.Status, "status"); err != nil {
+		return err
+	}
+	if !r.types.OnlineUsers {
+		online = nil
+	}
+	if !r.types.Subscriptions {
+		subscriptions = nil
+	}
+	r.logger.Debugf("📊 Starting gRPC combined report transmission...")
+
+	// Ensure connection is established
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	pbData := ConvertToProto(data)
+	if pbData == nil {
+		r.logger.Errorf("❌ Failed to convert data to protobuf format")
+		return fmt.Errorf("failed to convert data to protobuf format")
+	}
+
+	pendingErrors := r.errorCounters.Pending()
+	req := &pb.CombinedReportRequest{
+		Uuid:        uuid,
+		Data:        pbData,
+		ErrorCounts: ConvertErrorCounts(pendingErrors),
+		Agent:       r.hostInfo.Load(),
+	}
+	if online != nil {
+		req.OnlineUsers = &pb.OnlineUsersReportRequest{Uuid: uuid, OnlineEmails: online.Emails, Users: convertOnlineUsers(online.Users)}
+		req.OnlineUsersAge = int64(online.Age / time.Second)
+	}
+	var fingerprint string
+	if len(subscriptions) > 0 {
+		pbSubscriptions := convertSubscriptions(subscriptions)
+		if fingerprint = subscriptionsFingerprint(pbSubscriptions); fingerprint != r.combined.subscriptions || fingerprint == "" {
+			req.Subscriptions = &pb.SubscriptionReportRequest{Uuid: uuid, Subscriptions: pbSubscriptions}
+		}
+	}
+	// Subscriptions too large for one request are sent separately, in chunks
+	separateSubscriptions := req.Subscriptions != nil && r.chunkBytes > 0 && proto.Size(req) > r.chunkBytes
+	if separateSubscriptions {
+		req.Subscriptions = nil
+	}
+	r.logger.Debugf("📦 Created gRPC combined request: online users=%t, subscriptions=%d",
+		req.OnlineUsers != nil, len(req.GetSubscriptions().GetSubscriptions()))
+
+	// A combined report carrying subscriptions gets the longer of both timeouts
+	timeout := r.reportTimeout()
+	if req.Subscriptions != nil {
+		timeout = max(timeout, r.subscriptionTimeout())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	md := r.outgoingMetadata()
+	if online != nil && online.Age > 0 {
+		md.Set("x-agent-online-users-age", strconv.FormatInt(req.OnlineUsersAge, 10))
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := r.client.SendCombinedReport(ctx, req, r.callOptions()...)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if !ok {
+			if r.shouldLogError(fmt.Sprintf("generic_combined_%s", r.serverAddr)) {
+				r.logger.Errorf("❌ gRPC combined request failed: %v", err)
+			}
+			return r.withConnectionHint(fmt.Errorf("gRPC combined request failed: %w", err))
+		}
+		if st.Code() == codes.Unimplemented {
+			r.logger.Warnf("⚠️  xhub advertised combined reports but does not implement them, using separate RPCs until restart")
+			return ErrCombinedUnsupported
+		}
+
+		var errorMsg string
+		switch st.Code() {
+		case codes.Unauthenticated:
+			errorMsg = "authentication failed: API key invalid or expired"
+		case codes.InvalidArgument:
+			errorMsg = fmt.Sprintf("request error: invalid combined report format - %s", st.Message())
+		case codes.NotFound:
+			errorMsg = fmt.Sprintf("API endpoint not found: check if UUID is registered - %s", st.Message())
+		case codes.DeadlineExceeded:
+			errorMsg = fmt.Sprintf("request timeout: %s", st.Message())
+		case codes.Unavailable:
+			errorMsg = fmt.Sprintf("server unavailable: %s", st.Message())
+		default:
+			errorMsg = fmt.Sprintf("gRPC error [%s]: %s", st.Code(), st.Message())
+		}
+		if r.shouldLogError(fmt.Sprintf("grpc_combined_%s_%s", st.Code(), r.serverAddr)) {
+			r.logger.Errorf("❌ gRPC combined request failed!")
+			r.logger.Errorf("   Server: %s", r.serverAddr)
+			r.logger.Errorf("   UUID: %s", uuid)
+			r.logger.Errorf("   gRPC Status: %s", st.Code())
+			r.logger.Errorf("   Error Message: %s", st.Message())
+		}
+		return r.withConnectionHint(&RPCError{Code: st.Code(), Message: errorMsg})
+	}
+
+	if !resp.Success {
+		if r.shouldLogError(fmt.Sprintf("server_reject_combined_%s", r.serverAddr)) {
+			r.logger.Errorf("❌ Server rejected the combined report: %s", resp.Message)
+		}
+		return rejectedf("combined report failed: %s", resp.Message)
+	}
+
+	// Error counts were delivered, start the next window
+	r.errorCounters.Ack(pendingErrors)
+	if req.Subscriptions != nil {
+		r.combined.subscriptions = fingerprint
+	}
+	if separateSubscriptions {
+		if err := r.SendSubscriptionReport(uuid, subscriptions); err != nil {
+			return err
+		}
+		r.combined.subscriptions = fingerprint
+	}
+
+	r.rpcSucceeded = true
+	r.markSuccess("合并数据上报")
+	r.logger.Debugf("🎉 Combined report successfully sent via gRPC!")
+	return nil
+}
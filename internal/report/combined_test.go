@@ -0,0 +1,161 @@
+package report
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// combinedServer advertises the combined report capability and records the requests.
+// With implemented false it advertises the capability without implementing the RPC.
+type combinedServer struct {
+	pb.UnimplementedReportServiceServer
+	advertise   bool
+	implemented bool
+
+	mutex        sync.Mutex
+	reports      int
+	combined     []*pb.CombinedReportRequest
+	capabilities []string // x-agent-capabilities of the status reports
+}
+
+func (s *combinedServer) header(ctx context.Context) {
+	if s.advertise {
+		grpc.SetHeader(ctx, metadata.Pairs("x-xhub-capabilities", "other, "+CombinedCapability))
+	}
+}
+
+func (s *combinedServer) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	s.header(ctx)
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reports++
+	s.capabilities = append(s.capabilities, md.Get("x-agent-capabilities")...)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *combinedServer) SendCombinedReport(ctx context.Context, req *pb.CombinedReportRequest) (*pb.ReportResponse, error) {
+	if !s.implemented {
+		return s.UnimplementedReportServiceServer.SendCombinedReport(ctx, req)
+	}
+	s.header(ctx)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.combined = append(s.combined, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func newCombinedClient(t *testing.T, server *combinedServer, enabled bool) *ReportClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	client.SetCombinedReports(enabled)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func combinedTestData() *monitor.ServerStatusData {
+	return &monitor.ServerStatusData{CPU: 10, CPUCores: 2, Memory: monitor.MemoryInfo{Current: 1, Total: 2}}
+}
+
+func TestReportClient_CombinedReport(t *testing.T) {
+	server := &combinedServer{advertise: true, implemented: true}
+	client := newCombinedClient(t, server, true)
+	counters := errstats.NewCounters()
+	counters.RecordCategory(errstats.PanelTimeout)
+	client.SetErrorCounters(counters)
+
+	// The first status report advertises the agent capability and negotiates
+	assert.False(t, client.CombinedReportsAvailable())
+	require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+	assert.Equal(t, []string{CombinedCapability}, server.capabilities)
+	assert.True(t, client.CombinedReportsAvailable())
+	counters.RecordCategory(errstats.PanelTimeout)
+
+	subscriptions := []SubscriptionData{{SubID: "sub1", Email: "user1", NodeConfig: "dmxlc3M6Ly9ub2Rl"}}
+	online := &CombinedOnlineUsers{Emails: []string{"user1"}, Age: 90 * time.Second}
+	require.NoError(t, client.SendCombinedReport("test-uuid", combinedTestData(), online, subscriptions))
+
+	require.Len(t, server.combined, 1)
+	req := server.combined[0]
+	assert.Equal(t, "test-uuid", req.Uuid)
+	assert.Equal(t, int32(2), req.Data.CpuCores)
+	assert.Len(t, req.ErrorCounts, 1)
+	assert.Equal(t, []string{"user1"}, req.OnlineUsers.OnlineEmails)
+	assert.Equal(t, int64(90), req.OnlineUsersAge)
+	require.NotNil(t, req.Subscriptions)
+	assert.Equal(t, "sub1", req.Subscriptions.Subscriptions[0].SubId)
+	assert.Empty(t, counters.Pending(), "error counts are acknowledged")
+
+	// Unchanged subscriptions are left out, changed ones are sent again
+	require.NoError(t, client.SendCombinedReport("test-uuid", combinedTestData(), nil, subscriptions))
+	require.Len(t, server.combined, 2)
+	assert.Nil(t, server.combined[1].Subscriptions)
+	assert.Nil(t, server.combined[1].OnlineUsers)
+
+	subscriptions[0].NodeConfig = "dHJvamFuOi8vbm9kZQ=="
+	require.NoError(t, client.SendCombinedReport("test-uuid", combinedTestData(), nil, subscriptions))
+	require.Len(t, server.combined, 3)
+	assert.NotNil(t, server.combined[2].Subscriptions)
+}
+
+func TestReportClient_CombinedReportNegotiation(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		server := &combinedServer{advertise: true, implemented: true}
+		client := newCombinedClient(t, server, false)
+		require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+		assert.False(t, client.CombinedReportsAvailable())
+		assert.Empty(t, server.capabilities)
+	})
+
+	t.Run("not advertised", func(t *testing.T) {
+		client := newCombinedClient(t, &combinedServer{implemented: true}, true)
+		require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+		assert.False(t, client.CombinedReportsAvailable())
+	})
+
+	t.Run("advertised but unimplemented", func(t *testing.T) {
+		client := newCombinedClient(t, &combinedServer{advertise: true}, true)
+		require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+		require.True(t, client.CombinedReportsAvailable())
+
+		err := client.SendCombinedReport("test-uuid", combinedTestData(), nil, nil)
+		assert.ErrorIs(t, err, ErrCombinedUnsupported)
+		assert.False(t, client.CombinedReportsAvailable(), "the capability is withdrawn")
+	})
+}
+
+func TestCaptureRequest_MasksCombinedSubscriptions(t *testing.T) {
+	req := &pb.CombinedReportRequest{
+		Uuid: "test-uuid",
+		Subscriptions: &pb.SubscriptionReportRequest{Subscriptions: []*pb.SubscriptionData{
+			{SubId: "secret-subscription-id", NodeConfig: "dmxlc3M6Ly9zZWNyZXQ=", JsonConfig: `{"uuid":"secret-uuid"}`},
+		}},
+	}
+
+	captured, truncated := captureRequest(req)
+	assert.False(t, truncated)
+	assert.NotContains(t, captured, "secret-subscription-id")
+	assert.NotContains(t, captured, "dmxlc3M6Ly9zZWNyZXQ=")
+	assert.NotContains(t, captured, "secret-uuid")
+	assert.Contains(t, captured, "secr****")
+	assert.Equal(t, "secret-subscription-id", req.Subscriptions.Subscriptions[0].SubId, "the request is not modified")
+}
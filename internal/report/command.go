@@ -0,0 +1,75 @@
+package report
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+
+	"xhub-agent/internal/command"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// CommandStream receives the commands xhub issues to the agent (command_channel)
+type CommandStream struct {
+	stream pb.ReportService_SubscribeCommandsClient
+}
+
+// OpenCommandStream subscribes to the commands of this agent, advertising the commands it
+// accepts. The stream ends when ctx is done or the connection breaks; an xhub without
+// the RPC answers codes.Unimplemented on the first Recv.
+func (r *ReportClient) OpenCommandStream(ctx context.Context, uuid string, commands []string) (*CommandStream, error) {
+	if err := r.Connect(); err != nil {
+		return nil, r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+	stream, err := r.client.SubscribeCommands(ctx, &pb.CommandSubscription{Uuid: uuid, Commands: commands}, r.callOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	return &CommandStream{stream: stream}, nil
+}
+
+// Recv waits for the next command
+func (s *CommandStream) Recv() (command.Command, error) {
+	cmd, err := s.stream.Recv()
+	if err != nil {
+		return command.Command{}, err
+	}
+	return command.Command{ID: cmd.Id, Name: cmd.Name, Args: cmd.Args}, nil
+}
+
+// SendCommandResult reports the outcome of a command to xhub
+func (r *ReportClient) SendCommandResult(uuid string, result command.Result) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.CommandResult{
+		Uuid:       uuid,
+		Id:         result.ID,
+		Name:       result.Name,
+		Success:    result.Err == "",
+		Output:     result.Output,
+		Error:      result.Err,
+		DurationMs: result.Duration.Milliseconds(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.reportTimeout())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.SendCommandResult(ctx, req, r.callOptions()...)
+	if err != nil {
+		if r.shouldLogError(fmt.Sprintf("command_result_%s", r.serverAddr)) {
+			r.logger.Errorf("❌ gRPC command result request failed: %v", err)
+		}
+		return r.withConnectionHint(fmt.Errorf("gRPC command result request failed: %w", err))
+	}
+	if !resp.Success {
+		return rejectedf("command result rejected: %s", resp.Message)
+	}
+	r.markSuccess("命令结果上报")
+	return nil
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/command"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// commandServer streams its commands to each subscriber and records the results
+type commandServer struct {
+	pb.UnimplementedReportServiceServer
+	commands []*pb.Command
+
+	mutex         sync.Mutex
+	subscriptions []*pb.CommandSubscription
+	results       []*pb.CommandResult
+}
+
+func (s *commandServer) SubscribeCommands(req *pb.CommandSubscription, stream pb.ReportService_SubscribeCommandsServer) error {
+	s.mutex.Lock()
+	s.subscriptions = append(s.subscriptions, req)
+	s.mutex.Unlock()
+	for _, cmd := range s.commands {
+		if err := stream.Send(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *commandServer) SendCommandResult(ctx context.Context, req *pb.CommandResult) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.results = append(s.results, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestReportClient_CommandStream(t *testing.T) {
+	server := &commandServer{commands: []*pb.Command{
+		{Id: "c1", Name: command.FetchLogs, Args: map[string]string{"lines": "20"}},
+		{Id: "c2", Name: command.ResyncSubscriptions},
+	}}
+	client := newStreamClient(t, server)
+
+	stream, err := client.OpenCommandStream(context.Background(), "test-uuid", []string{command.FetchLogs})
+	require.NoError(t, err)
+
+	cmd, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, command.Command{ID: "c1", Name: command.FetchLogs, Args: map[string]string{"lines": "20"}}, cmd)
+	cmd, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "c2", cmd.ID)
+	_, err = stream.Recv()
+	assert.Error(t, err, "the stream ends with the server handler")
+
+	require.Len(t, server.subscriptions, 1)
+	assert.Equal(t, "test-uuid", server.subscriptions[0].Uuid)
+	assert.Equal(t, []string{command.FetchLogs}, server.subscriptions[0].Commands)
+}
+
+func TestReportClient_CommandStreamUnimplemented(t *testing.T) {
+	client := newStreamClient(t, &streamServer{})
+
+	stream, err := client.OpenCommandStream(context.Background(), "test-uuid", nil)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestReportClient_SendCommandResult(t *testing.T) {
+	server := &commandServer{}
+	client := newStreamClient(t, server)
+
+	require.NoError(t, client.SendCommandResult("test-uuid", command.Result{ID: "c1", Name: command.FetchLogs, Output: "log", Duration: 1500 * time.Millisecond}))
+	require.NoError(t, client.SendCommandResult("test-uuid", command.Result{ID: "c2", Name: command.RestartXray, Err: "not allowed"}))
+
+	require.Len(t, server.results, 2)
+	assert.Equal(t, "test-uuid", server.results[0].Uuid)
+	assert.True(t, server.results[0].Success)
+	assert.Equal(t, "log", server.results[0].Output)
+	assert.Equal(t, int64(1500), server.results[0].DurationMs)
+	assert.False(t, server.results[1].Success)
+	assert.Equal(t, "not allowed", server.results[1].Error)
+}
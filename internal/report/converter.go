@@ -1,20 +1,109 @@
 package report
 
 import (
+	"sort"
+
+	"xhub-agent/internal/errstats"
 	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/sanitize"
+	"xhub-agent/internal/sysinfo"
 	pb "xhub-agent/proto/reportpb"
 )
 
-// ConvertToProto converts monitor.ServerStatusData to protobuf format
+// ConvertToProto converts monitor.ServerStatusData to protobuf format.
+// Strings are made valid UTF-8 (proto3 rejects invalid strings) and int32 fields are clamped.
 func ConvertToProto(data *monitor.ServerStatusData) *pb.ServerStatusData {
 	if data == nil {
 		return nil
 	}
 
+	var portListeners []*pb.PortListener
+	for _, l := range data.PortListeners {
+		portListeners = append(portListeners, &pb.PortListener{
+			Port:            sanitize.Int32(l.Port),
+			ListenerProcess: sanitize.String(l.ListenerProcess),
+			IsXray:          l.IsXray,
+			IsFrontend:      l.IsFrontend,
+			Unknown:         l.Unknown,
+			ListenerLost:    l.ListenerLost,
+		})
+	}
+
+	var fail2banBans map[string]int32
+	if len(data.Fail2banBans) > 0 {
+		fail2banBans = make(map[string]int32, len(data.Fail2banBans))
+		for jail, count := range data.Fail2banBans {
+			fail2banBans[sanitize.String(jail)] = sanitize.Int32(count)
+		}
+	}
+
+	var certExpiry []*pb.CertExpiry
+	for _, c := range data.CertExpiry {
+		certExpiry = append(certExpiry, &pb.CertExpiry{
+			Path:     sanitize.String(c.Path),
+			Origin:   sanitize.String(c.Origin),
+			Subject:  sanitize.String(c.Subject),
+			NotAfter: c.NotAfter,
+			Error:    sanitize.String(c.Error),
+		})
+	}
+
+	var dnsChecks []*pb.DNSCheck
+	for _, d := range data.DNSChecks {
+		dnsChecks = append(dnsChecks, &pb.DNSCheck{
+			Domain:               sanitize.String(d.Domain),
+			Resolves:             d.Resolves,
+			PointsHere:           d.PointsHere,
+			ResolvedIps:          sanitize.Strings(d.ResolvedIPs),
+			ResolverDisagreement: d.ResolverDisagreement,
+			Error:                sanitize.String(d.Error),
+		})
+	}
+
+	var selfTest *pb.SelfTestStatus
+	if data.SelfTest != nil {
+		selfTest = &pb.SelfTestStatus{
+			LastRun:    data.SelfTest.LastRun,
+			Passed:     data.SelfTest.Passed,
+			Failures:   sanitize.Strings(data.SelfTest.Failures),
+			Runs:       data.SelfTest.Runs,
+			FailedRuns: data.SelfTest.FailedRuns,
+		}
+	}
+
+	var diskIO *pb.DiskIOStats
+	if data.DiskIO != nil {
+		diskIO = &pb.DiskIOStats{
+			Device:           sanitize.String(data.DiskIO.Device),
+			ReadBytesPerSec:  data.DiskIO.ReadBytesPerSec,
+			WriteBytesPerSec: data.DiskIO.WriteBytesPerSec,
+			ReadIops:         data.DiskIO.ReadIOPS,
+			WriteIops:        data.DiskIO.WriteIOPS,
+			Utilization:      data.DiskIO.Utilization,
+			AwaitMs:          data.DiskIO.AwaitMs,
+			IntervalMs:       data.DiskIO.IntervalMs,
+		}
+	}
+
+	var diskSMART *pb.DiskSMART
+	if data.DiskSMART != nil {
+		diskSMART = &pb.DiskSMART{
+			Device:             sanitize.String(data.DiskSMART.Device),
+			Available:          data.DiskSMART.Available,
+			Passed:             data.DiskSMART.Passed,
+			Model:              sanitize.String(data.DiskSMART.Model),
+			TemperatureCelsius: sanitize.Int32(data.DiskSMART.TemperatureCelsius),
+			PowerOnHours:       data.DiskSMART.PowerOnHours,
+			PercentageUsed:     sanitize.Int32(data.DiskSMART.PercentageUsed),
+			ReallocatedSectors: data.DiskSMART.ReallocatedSectors,
+			Error:              sanitize.String(data.DiskSMART.Error),
+		}
+	}
+
 	return &pb.ServerStatusData{
 		Cpu:         data.CPU,
-		CpuCores:    int32(data.CPUCores),
-		LogicalPro:  int32(data.LogicalPro),
+		CpuCores:    sanitize.Int32(data.CPUCores),
+		LogicalPro:  sanitize.Int32(data.LogicalPro),
 		CpuSpeedMhz: data.CPUSpeedMhz,
 		Memory: &pb.MemoryInfo{
 			Current: data.Memory.Current,
@@ -28,10 +117,10 @@ func ConvertToProto(data *monitor.ServerStatusData) *pb.ServerStatusData {
 			Current: data.Disk.Current,
 			Total:   data.Disk.Total,
 		},
-		Uptime:   int32(data.Uptime),
+		Uptime:   sanitize.Int32(data.Uptime),
 		Loads:    data.Loads,
-		TcpCount: int32(data.TCPCount),
-		UdpCount: int32(data.UDPCount),
+		TcpCount: sanitize.Int32(data.TCPCount),
+		UdpCount: sanitize.Int32(data.UDPCount),
 		NetIo: &pb.NetIOInfo{
 			Up:   data.NetIO.Up,
 			Down: data.NetIO.Down,
@@ -41,18 +130,184 @@ func ConvertToProto(data *monitor.ServerStatusData) *pb.ServerStatusData {
 			Recv: data.NetTraffic.Recv,
 		},
 		PublicIp: &pb.PublicIPInfo{
-			Ipv4: data.PublicIP.IPv4,
-			Ipv6: data.PublicIP.IPv6,
+			Ipv4: sanitize.String(data.PublicIP.IPv4),
+			Ipv6: sanitize.String(data.PublicIP.IPv6),
 		},
 		Xray: &pb.XrayInfo{
-			State:    data.Xray.State,
-			ErrorMsg: data.Xray.ErrorMsg,
-			Version:  data.Xray.Version,
+			State:    sanitize.String(data.Xray.State),
+			ErrorMsg: sanitize.String(data.Xray.ErrorMsg),
+			Version:  sanitize.String(data.Xray.Version),
 		},
 		AppStats: &pb.AppStats{
-			Threads: int32(data.AppStats.Threads),
+			Threads: sanitize.Int32(data.AppStats.Threads),
 			Memory:  data.AppStats.Memory,
-			Uptime:  int32(data.AppStats.Uptime),
+			Uptime:  sanitize.Int32(data.AppStats.Uptime),
 		},
+		PortListeners:    portListeners,
+		InboundProtocols: sanitize.Strings(data.InboundProtocols),
+		Fail2BanBans:     fail2banBans,
+		CertExpiry:       certExpiry,
+		DnsChecks:        dnsChecks,
+		SelfTest:         selfTest,
+		IpStack:          string(data.PublicIP.Stack()),
+		DiskIo:           diskIO,
+		DiskSmart:        diskSMART,
+		Pressure:         convertPressure(data.Pressure),
+		Wireguard:        convertWireGuard(data.WireGuard),
+		PanelVersion:     sanitize.String(data.PanelVersion),
+		UnreportedFields: sanitize.Strings(data.Unreported),
+		XrayRestart:      convertXrayRestart(data.XrayRestart),
+		Clock:            convertClock(data.Clock),
+	}
+}
+
+// convertClock converts the clock status to protobuf format
+func convertClock(clock *monitor.ClockStatus) *pb.ClockStatus {
+	if clock == nil {
+		return nil
+	}
+	return &pb.ClockStatus{
+		OffsetMs:     clock.OffsetMs,
+		OffsetSource: clock.OffsetSource,
+		RoundTripMs:  clock.RoundTripMs,
+		Server:       sanitize.String(clock.Server),
+		Synchronized: clock.Synchronized,
+		NtpService:   clock.NTPService,
+		Error:        sanitize.String(clock.Error),
+	}
+}
+
+// convertXrayRestart converts the outcome of an Xray restart to protobuf format
+func convertXrayRestart(restart *monitor.XrayRestart) *pb.XrayRestart {
+	if restart == nil {
+		return nil
+	}
+	return &pb.XrayRestart{
+		Trigger:    restart.Trigger,
+		Method:     restart.Method,
+		At:         restart.At,
+		DownCycles: sanitize.Int32(restart.DownCycles),
+		Success:    restart.Success,
+		Error:      sanitize.String(restart.Error),
+	}
+}
+
+// convertWireGuard converts the WireGuard interfaces and their peers to protobuf format
+func convertWireGuard(interfaces []monitor.WireGuardInterface) []*pb.WireGuardInterface {
+	var result []*pb.WireGuardInterface
+	for _, iface := range interfaces {
+		converted := &pb.WireGuardInterface{
+			Name:        sanitize.String(iface.Name),
+			PublicKey:   sanitize.String(iface.PublicKey),
+			ListenPort:  sanitize.Int32(iface.ListenPort),
+			Peers:       sanitize.Int32(iface.Peers),
+			ActivePeers: sanitize.Int32(iface.ActivePeers),
+			RxBytes:     iface.RxBytes,
+			TxBytes:     iface.TxBytes,
+		}
+		for _, peer := range iface.PeerStats {
+			converted.PeerStats = append(converted.PeerStats, &pb.WireGuardPeer{
+				PublicKey:       sanitize.String(peer.PublicKey),
+				LatestHandshake: peer.LatestHandshake,
+				RxBytes:         peer.RxBytes,
+				TxBytes:         peer.TxBytes,
+			})
+		}
+		result = append(result, converted)
+	}
+	return result
+}
+
+// convertPressure converts the pressure stall and thermal zone readings to protobuf format
+func convertPressure(pressure *monitor.HostPressure) *pb.HostPressure {
+	if pressure == nil {
+		return nil
+	}
+	result := &pb.HostPressure{
+		Cpu:    convertPressureStall(pressure.CPU),
+		Memory: convertPressureStall(pressure.Memory),
+		Io:     convertPressureStall(pressure.IO),
+	}
+	for _, zone := range pressure.ThermalZones {
+		result.ThermalZones = append(result.ThermalZones, &pb.ThermalZone{
+			Zone:               sanitize.String(zone.Zone),
+			Type:               sanitize.String(zone.Type),
+			TemperatureCelsius: zone.TemperatureCelsius,
+		})
+	}
+	return result
+}
+
+// convertPressureStall converts the PSI of a resource to protobuf format
+func convertPressureStall(stall *monitor.PressureStall) *pb.PressureStall {
+	if stall == nil {
+		return nil
+	}
+	return &pb.PressureStall{
+		SomeAvg10:   stall.SomeAvg10,
+		SomeAvg60:   stall.SomeAvg60,
+		SomeAvg300:  stall.SomeAvg300,
+		FullAvg10:   stall.FullAvg10,
+		FullAvg60:   stall.FullAvg60,
+		FullAvg300:  stall.FullAvg300,
+		SomeTotalUs: stall.SomeTotalUs,
+		FullTotalUs: stall.FullTotalUs,
+	}
+}
+
+// ConvertErrorCounts converts per-category error counts to protobuf format (sorted by category)
+func ConvertErrorCounts(counts map[errstats.ErrorCategory]uint64) []*pb.ErrorCategoryCount {
+	var result []*pb.ErrorCategoryCount
+	for category, count := range counts {
+		if count == 0 {
+			continue
+		}
+		result = append(result, &pb.ErrorCategoryCount{
+			Category: pb.ErrorCategory(category),
+			Count:    count,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Category < result[j].Category })
+	return result
+}
+
+// ConvertHostInfo converts the agent and host metadata to protobuf format
+func ConvertHostInfo(info sysinfo.Info) *pb.AgentInfo {
+	agent := &pb.AgentInfo{
+		AgentVersion:   sanitize.String(info.AgentVersion),
+		GoVersion:      info.GoVersion,
+		Os:             info.OS,
+		Arch:           info.Arch,
+		Kernel:         sanitize.String(info.Kernel),
+		Hostname:       sanitize.String(info.Hostname),
+		Virtualization: sanitize.String(info.Virtualization),
+	}
+	if info.Geo != nil {
+		agent.Geo = &pb.GeoInfo{
+			Ip:      sanitize.String(info.Geo.IP),
+			Country: sanitize.String(info.Geo.Country),
+			Region:  sanitize.String(info.Geo.Region),
+			City:    sanitize.String(info.Geo.City),
+			Asn:     info.Geo.ASN,
+			AsOrg:   sanitize.String(info.Geo.ASOrg),
+		}
+	}
+	return agent
+}
+
+// convertOnlineUsers converts the connection details of online users to protobuf format
+func convertOnlineUsers(users []monitor.OnlineUser) []*pb.OnlineUser {
+	var pbUsers []*pb.OnlineUser
+	for _, user := range users {
+		pbUser := &pb.OnlineUser{Email: user.Email}
+		for _, ip := range user.IPs {
+			pbIP := &pb.ClientIP{Ip: ip.IP, Connections: sanitize.Int32(ip.Connections)}
+			if !ip.LastSeen.IsZero() {
+				pbIP.LastSeen = ip.LastSeen.Unix()
+			}
+			pbUser.Ips = append(pbUser.Ips, pbIP)
+		}
+		pbUsers = append(pbUsers, pbUser)
 	}
+	return pbUsers
 }
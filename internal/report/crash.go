@@ -0,0 +1,43 @@
+package report
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+
+	"xhub-agent/internal/crash"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// SendCrashReport reports a crash dump of a previous agent run to xhub
+func (r *ReportClient) SendCrashReport(uuid string, dump crash.Dump) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.CrashReport{
+		Uuid:          uuid,
+		CrashedAt:     dump.Time.Unix(),
+		Component:     dump.Component,
+		Panic:         dump.Panic,
+		Stack:         dump.Stack,
+		AgentVersion:  dump.Version,
+		UptimeSeconds: dump.UptimeSeconds,
+		RestartCount:  dump.RestartCount,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.reportTimeout())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.SendCrashReport(ctx, req, r.callOptions()...)
+	if err != nil {
+		return r.withConnectionHint(fmt.Errorf("gRPC crash report failed: %w", err))
+	}
+	if !resp.Success {
+		return rejectedf("crash report rejected: %s", resp.Message)
+	}
+	r.markSuccess("崩溃报告上报")
+	return nil
+}
@@ -0,0 +1,181 @@
+package report
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// DeltaCapability is the capability name of delta status reports, sent by the agent in
+// x-agent-capabilities and advertised by xhub in the x-xhub-capabilities response header
+const DeltaCapability = "status-delta"
+
+// deltaState tracks the delta encoding of status reports (report_delta)
+type deltaState struct {
+	enabled      bool
+	fullInterval time.Duration // Longest time between full snapshots
+	supported    atomic.Bool   // xhub advertised the capability in its last response
+
+	mutex        sync.Mutex // One status report encoded and in flight at a time
+	sequence     uint64     // Sequence of the last status report sent
+	base         *pb.ServerStatusData
+	baseSequence uint64    // Sequence of base, the last status xhub acknowledged
+	lastFull     time.Time // When xhub last acknowledged a full snapshot
+}
+
+// SetDeltaReports enables delta status reports (report_delta). Once xhub advertises the
+// capability, status reports carry only the fields changed since the last acknowledged one,
+// with a full snapshot at least every fullInterval.
+func (r *ReportClient) SetDeltaReports(enabled bool, fullInterval time.Duration) {
+	r.delta.enabled = enabled
+	r.delta.fullInterval = fullInterval
+}
+
+// DeltaReportsAvailable returns whether status reports are delta encoded
+func (r *ReportClient) DeltaReportsAvailable() bool {
+	return r.delta.enabled && r.delta.supported.Load()
+}
+
+// setDeltaSupported records the negotiated capability, logging changes when enabled
+func (r *ReportClient) setDeltaSupported(supported bool) {
+	if r.delta.supported.Swap(supported) == supported || !r.delta.enabled {
+		return
+	}
+	if supported {
+		r.logger.Infof("🧩 xhub supports delta status reports, sending changed fields between full snapshots")
+	} else {
+		r.logger.Infof("🧩 xhub no longer advertises delta status reports, sending full reports")
+	}
+}
+
+// resetDelta makes the next status report a full snapshot
+func (r *ReportClient) resetDelta() {
+	r.delta.mutex.Lock()
+	r.delta.base = nil
+	r.delta.mutex.Unlock()
+}
+
+// encodeDeltas is a unary interceptor replacing the status of status and combined reports by
+// its changes since the last acknowledged report. It runs after queueOffline, so queued
+// reports keep their full status, and after retryRPCs, so every attempt is encoded against
+// the current base. A delta whose base xhub no longer has is resent as a full snapshot.
+func (r *ReportClient) encodeDeltas(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	data := statusOf(req)
+	if data == nil || ctx.Value(replayKey{}) != nil {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if !r.DeltaReportsAvailable() {
+		// Plain reports are not numbered, the next delta report starts from a full snapshot
+		r.resetDelta()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	d := &r.delta
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	sent, delta := d.encode(data, now)
+	err := invoker(ctx, method, withStatus(req, sent, delta), reply, cc, opts...)
+	if status.Code(err) == codes.FailedPrecondition && delta.BaseSequence != 0 {
+		r.logger.Infof("🧩 xhub has no status report %d to apply the delta to, sending a full snapshot", delta.BaseSequence)
+		d.base = nil
+		sent, delta = d.encode(data, now)
+		err = invoker(ctx, method, withStatus(req, sent, delta), reply, cc, opts...)
+	}
+	if resp, ok := reply.(*pb.ReportResponse); err == nil && ok && resp.Success {
+		d.base, d.baseSequence = data, delta.Sequence
+		if delta.BaseSequence == 0 {
+			d.lastFull = now
+		}
+	}
+	return err
+}
+
+// encode numbers the next status report and returns the status to send: data itself for a
+// full snapshot, its changes since base otherwise
+func (d *deltaState) encode(data *pb.ServerStatusData, now time.Time) (*pb.ServerStatusData, *pb.StatusDelta) {
+	d.sequence++
+	delta := &pb.StatusDelta{Sequence: d.sequence}
+	if d.base == nil || now.Sub(d.lastFull) >= d.fullInterval {
+		return data, delta
+	}
+	changes, fields := diffStatus(d.base, data)
+	delta.BaseSequence, delta.ChangedFields = d.baseSequence, fields
+	return changes, delta
+}
+
+// diffStatus returns the top-level fields of current that differ from base, and their field
+// numbers. A field set in base but not in current is listed without a value.
+func diffStatus(base, current *pb.ServerStatusData) (*pb.ServerStatusData, []uint32) {
+	baseMessage, currentMessage := base.ProtoReflect(), current.ProtoReflect()
+	changes := &pb.ServerStatusData{}
+	changesMessage := changes.ProtoReflect()
+	var numbers []uint32
+
+	fields := currentMessage.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if fieldEqual(baseMessage, currentMessage, field) {
+			continue
+		}
+		if currentMessage.Has(field) {
+			changesMessage.Set(field, currentMessage.Get(field))
+		}
+		numbers = append(numbers, uint32(field.Number()))
+	}
+	return changes, numbers
+}
+
+// fieldEqual reports whether field has the same value in a and b
+func fieldEqual(a, b protoreflect.Message, field protoreflect.FieldDescriptor) bool {
+	if !a.Has(field) || !b.Has(field) {
+		return a.Has(field) == b.Has(field)
+	}
+	onlyA, onlyB := a.New(), b.New()
+	onlyA.Set(field, a.Get(field))
+	onlyB.Set(field, b.Get(field))
+	return proto.Equal(onlyA.Interface(), onlyB.Interface())
+}
+
+// statusOf returns the status carried by a status or combined report, nil for other requests
+func statusOf(req interface{}) *pb.ServerStatusData {
+	switch typed := req.(type) {
+	case *pb.ReportRequest:
+		return typed.Data
+	case *pb.CombinedReportRequest:
+		return typed.Data
+	}
+	return nil
+}
+
+// withStatus returns a copy of a status or combined report carrying data and delta. The
+// original request is left as-is for the offline queue.
+func withStatus(req interface{}, data *pb.ServerStatusData, delta *pb.StatusDelta) interface{} {
+	switch typed := req.(type) {
+	case *pb.ReportRequest:
+		return &pb.ReportRequest{Uuid: typed.Uuid, Data: data, ErrorCounts: typed.ErrorCounts, Agent: typed.Agent, Delta: delta}
+	case *pb.CombinedReportRequest:
+		return &pb.CombinedReportRequest{
+			Uuid:           typed.Uuid,
+			Data:           data,
+			ErrorCounts:    typed.ErrorCounts,
+			OnlineUsers:    typed.OnlineUsers,
+			OnlineUsersAge: typed.OnlineUsersAge,
+			Subscriptions:  typed.Subscriptions,
+			Agent:          typed.Agent,
+			Delta:          delta,
+		}
+	}
+	return req
+}
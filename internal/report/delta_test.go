@@ -0,0 +1,143 @@
+package report
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// deltaServer advertises the delta capability and records the status reports. With
+// lostBase set it rejects the next delta report as if xhub had restarted.
+type deltaServer struct {
+	pb.UnimplementedReportServiceServer
+
+	mutex        sync.Mutex
+	reports      []*pb.ReportRequest
+	capabilities []string
+	lostBase     bool
+}
+
+func (s *deltaServer) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	grpc.SetHeader(ctx, metadata.Pairs("x-xhub-capabilities", DeltaCapability))
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.capabilities = append(s.capabilities, md.Get("x-agent-capabilities")...)
+	if s.lostBase && req.GetDelta().GetBaseSequence() != 0 {
+		s.lostBase = false
+		return nil, status.Error(codes.FailedPrecondition, "unknown base report")
+	}
+	s.reports = append(s.reports, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *deltaServer) last() *pb.ReportRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.reports[len(s.reports)-1]
+}
+
+func newDeltaClient(t *testing.T, server *deltaServer, fullInterval time.Duration) *ReportClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	client.SetDeltaReports(true, fullInterval)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func deltaTestData(cpu float64) *monitor.ServerStatusData {
+	return &monitor.ServerStatusData{CPU: cpu, CPUCores: 4, TCPCount: 12, Memory: monitor.MemoryInfo{Current: 1, Total: 2}}
+}
+
+func TestReportClient_DeltaReports(t *testing.T) {
+	server := &deltaServer{}
+	client := newDeltaClient(t, server, time.Hour)
+
+	// The first report negotiates and is sent as before
+	require.NoError(t, client.SendReport("test-uuid", deltaTestData(10)))
+	assert.Equal(t, []string{DeltaCapability}, server.capabilities)
+	assert.Nil(t, server.last().Delta)
+	require.True(t, client.DeltaReportsAvailable())
+
+	require.NoError(t, client.SendReport("test-uuid", deltaTestData(10)))
+	full := server.last()
+	assert.Equal(t, uint64(1), full.Delta.Sequence)
+	assert.Zero(t, full.Delta.BaseSequence, "the first numbered report is a full snapshot")
+	assert.Equal(t, int32(4), full.Data.CpuCores)
+
+	data := deltaTestData(25)
+	data.TCPCount = 0
+	require.NoError(t, client.SendReport("test-uuid", data))
+	delta := server.last()
+	assert.Equal(t, uint64(2), delta.Delta.Sequence)
+	assert.Equal(t, uint64(1), delta.Delta.BaseSequence)
+	assert.ElementsMatch(t, []uint32{1, 10}, delta.Delta.ChangedFields, "cpu and the cleared tcp_count")
+	assert.Equal(t, 25.0, delta.Data.Cpu)
+	assert.Zero(t, delta.Data.CpuCores, "unchanged fields are left out")
+	assert.Nil(t, delta.Data.Memory)
+	assert.Equal(t, "test-uuid", delta.Uuid)
+}
+
+func TestReportClient_DeltaReportsFullSnapshots(t *testing.T) {
+	t.Run("interval", func(t *testing.T) {
+		server := &deltaServer{}
+		client := newDeltaClient(t, server, 0)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, client.SendReport("test-uuid", deltaTestData(10)))
+		}
+		assert.Zero(t, server.last().Delta.BaseSequence, "every report is full when the interval elapsed")
+		assert.Equal(t, int32(4), server.last().Data.CpuCores)
+	})
+
+	t.Run("lost base", func(t *testing.T) {
+		server := &deltaServer{}
+		client := newDeltaClient(t, server, time.Hour)
+		require.NoError(t, client.SendReport("test-uuid", deltaTestData(10)))
+		require.NoError(t, client.SendReport("test-uuid", deltaTestData(10)))
+
+		server.lostBase = true
+		require.NoError(t, client.SendReport("test-uuid", deltaTestData(20)))
+		resent := server.last()
+		assert.Zero(t, resent.Delta.BaseSequence, "the rejected delta is resent as a full snapshot")
+		assert.Equal(t, uint64(3), resent.Delta.Sequence)
+		assert.Equal(t, int32(4), resent.Data.CpuCores)
+
+		require.NoError(t, client.SendReport("test-uuid", deltaTestData(30)))
+		assert.Equal(t, uint64(3), server.last().Delta.BaseSequence)
+	})
+}
+
+func TestDiffStatus(t *testing.T) {
+	base := ConvertToProto(deltaTestData(10))
+	current := ConvertToProto(deltaTestData(10))
+	changes, fields := diffStatus(base, current)
+	assert.Empty(t, fields)
+	assert.Nil(t, changes.Memory)
+
+	current.Memory.Current = 2
+	current.Loads = []float64{0.5}
+	changes, fields = diffStatus(base, current)
+	assert.Equal(t, []uint32{5, 9}, fields)
+	assert.Equal(t, int64(2), changes.Memory.Current, "changed messages are sent whole")
+	assert.Equal(t, int64(2), changes.Memory.Total)
+	assert.Equal(t, []float64{0.5}, changes.Loads)
+}
@@ -0,0 +1,258 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"xhub-agent/pkg/logger"
+)
+
+// DialStrategy selects the address families used for the gRPC connection
+type DialStrategy string
+
+const (
+	DialAuto      DialStrategy = "auto"       // Happy Eyeballs: preferred family first, the other raced after a short delay
+	DialIPv4Only  DialStrategy = "ipv4-only"  // Only IPv4 addresses
+	DialIPv6Only  DialStrategy = "ipv6-only"  // Only IPv6 addresses
+	DialIPv4First DialStrategy = "ipv4-first" // IPv4 addresses, then IPv6 (no racing)
+)
+
+// Dialer timing defaults
+const (
+	DefaultFallbackDelay  = 300 * time.Millisecond // Head start of the preferred family under auto
+	DefaultAttemptTimeout = 5 * time.Second        // Timeout of a single address attempt
+)
+
+// Address families reported for the active connection
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// ParseDialStrategy parses a grpc_dial_strategy value (empty means auto)
+func ParseDialStrategy(value string) (DialStrategy, error) {
+	switch strategy := DialStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case "":
+		return DialAuto, nil
+	case DialAuto, DialIPv4Only, DialIPv6Only, DialIPv4First:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown grpc_dial_strategy %q (known: auto, ipv4-only, ipv6-only, ipv4-first)", value)
+	}
+}
+
+// hostResolver looks up host addresses (satisfied by *net.Resolver)
+type hostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// proxyFromEnvironment reports the proxy configured for a request (replaced in tests)
+var proxyFromEnvironment = http.ProxyFromEnvironment
+
+// familyDialer is the gRPC ContextDialer resolving both address families explicitly and
+// ordering/racing the connection attempts according to the strategy
+type familyDialer struct {
+	strategy       DialStrategy
+	resolver       hostResolver
+	dial           func(ctx context.Context, network, address string) (net.Conn, error)
+	fallbackDelay  time.Duration
+	attemptTimeout time.Duration
+	preferIPv4     bool // IPv4 gets the head start under auto (prefer_ipv6: false)
+	logger         *logger.Logger
+
+	family string // Family of the last established connection
+	mutex  sync.Mutex
+}
+
+// newFamilyDialer creates a dialer for strategy using the system resolver
+func newFamilyDialer(strategy DialStrategy, log *logger.Logger) *familyDialer {
+	return &familyDialer{
+		strategy:       strategy,
+		resolver:       net.DefaultResolver,
+		dial:           (&net.Dialer{KeepAlive: 30 * time.Second}).DialContext,
+		fallbackDelay:  DefaultFallbackDelay,
+		attemptTimeout: DefaultAttemptTimeout,
+		logger:         log,
+	}
+}
+
+// dialResult outcome of one family's attempts
+type dialResult struct {
+	conn   net.Conn
+	family string
+	err    error
+}
+
+// DialContext dials addr ("host:port") per the strategy
+func (d *familyDialer) DialContext(ctx context.Context, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		addrs = []net.IPAddr{{IP: ip}}
+	} else if addrs, err = d.resolver.LookupIPAddr(ctx, host); err != nil {
+		return nil, err
+	}
+
+	var v4, v6 []net.IPAddr
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			v4 = append(v4, a)
+		} else {
+			v6 = append(v6, a)
+		}
+	}
+
+	var result dialResult
+	switch d.strategy {
+	case DialIPv4Only:
+		result = d.dialFamily(ctx, FamilyIPv4, v4, port)
+	case DialIPv6Only:
+		result = d.dialFamily(ctx, FamilyIPv6, v6, port)
+	case DialIPv4First:
+		result = d.dialFamily(ctx, FamilyIPv4, v4, port)
+		if result.err != nil && len(v6) > 0 && ctx.Err() == nil {
+			result = d.dialFamily(ctx, FamilyIPv6, v6, port)
+		}
+	default:
+		if d.preferIPv4 {
+			result = d.race(ctx, FamilyIPv4, v4, FamilyIPv6, v6, port)
+		} else {
+			result = d.race(ctx, FamilyIPv6, v6, FamilyIPv4, v4, port)
+		}
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	d.recordFamily(result.family)
+	return result.conn, nil
+}
+
+// dialFamily tries addrs of one family in order, each with the per-attempt timeout
+func (d *familyDialer) dialFamily(ctx context.Context, family string, addrs []net.IPAddr, port string) dialResult {
+	if len(addrs) == 0 {
+		return dialResult{family: family, err: fmt.Errorf("no %s address", family)}
+	}
+
+	var lastErr error
+	for _, a := range addrs {
+		attemptCtx, cancel := context.WithTimeout(ctx, d.attemptTimeout)
+		conn, err := d.dial(attemptCtx, "tcp", net.JoinHostPort(a.String(), port))
+		cancel()
+		if err == nil {
+			return dialResult{conn: conn, family: family}
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return dialResult{family: family, err: lastErr}
+}
+
+// race dials the preferred addresses and, after the fallback delay or a failure of the
+// preferred family, the fallback addresses concurrently; the first established connection wins
+func (d *familyDialer) race(ctx context.Context, preferred string, first []net.IPAddr, fallback string, second []net.IPAddr, port string) dialResult {
+	if len(first) == 0 {
+		return d.dialFamily(ctx, fallback, second, port)
+	}
+	if len(second) == 0 {
+		return d.dialFamily(ctx, preferred, first, port)
+	}
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, 2)
+	start := func(family string, addrs []net.IPAddr) {
+		go func() { results <- d.dialFamily(raceCtx, family, addrs, port) }()
+	}
+
+	start(preferred, first)
+	pending := 1
+	fallbackStarted := false
+	timer := time.NewTimer(d.fallbackDelay)
+	defer timer.Stop()
+
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				start(fallback, second)
+				fallbackStarted = true
+				pending++
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				// Close the connection of a losing attempt that still completes
+				if pending > 0 {
+					go func() {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}()
+				}
+				return res
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if !fallbackStarted {
+				start(fallback, second)
+				fallbackStarted = true
+				pending++
+				continue
+			}
+			if pending == 0 {
+				return dialResult{err: firstErr}
+			}
+		}
+	}
+}
+
+// recordFamily stores the family of a new connection, logging when it changes
+func (d *familyDialer) recordFamily(family string) {
+	d.mutex.Lock()
+	previous := d.family
+	d.family = family
+	d.mutex.Unlock()
+
+	if previous != "" && previous != family && d.logger != nil {
+		d.logger.Infof("🌐 gRPC connection switched from %s to %s", previous, family)
+	}
+}
+
+// Family returns the family of the last established connection ("" before the first one)
+func (d *familyDialer) Family() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.family
+}
+
+// hasResolverScheme reports whether serverAddr is a gRPC target with a resolver scheme
+// (e.g. unix sockets) rather than a plain host:port
+func hasResolverScheme(serverAddr string) bool {
+	return strings.Contains(serverAddr, "://") || strings.HasPrefix(serverAddr, "unix:")
+}
+
+// usesCustomDialer reports whether the family dialer applies to serverAddr. Targets with a
+// resolver scheme (e.g. unix sockets) and proxied targets keep gRPC's own dialing.
+func usesCustomDialer(serverAddr string) bool {
+	if hasResolverScheme(serverAddr) {
+		return false
+	}
+	proxyURL, err := proxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: serverAddr}})
+	return err == nil && proxyURL == nil
+}
@@ -0,0 +1,272 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// staticResolver resolves every host to a fixed address list
+type staticResolver []net.IPAddr
+
+func (s staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return s, nil
+}
+
+var dualStack = staticResolver{{IP: net.ParseIP("2001:db8::1")}, {IP: net.ParseIP("192.0.2.1")}}
+
+// familyOutcomes is an injectable dial function with a per-family behavior
+type familyOutcomes struct {
+	v4, v6 string // "ok", "refused" or "blackhole" (blocks until the attempt times out)
+
+	mutex    sync.Mutex
+	attempts []string
+}
+
+func (f *familyOutcomes) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(address)
+	family, outcome := FamilyIPv4, f.v4
+	if net.ParseIP(host).To4() == nil {
+		family, outcome = FamilyIPv6, f.v6
+	}
+
+	f.mutex.Lock()
+	f.attempts = append(f.attempts, family)
+	f.mutex.Unlock()
+
+	switch outcome {
+	case "ok":
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	case "blackhole":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	default:
+		return nil, fmt.Errorf("dial %s: connection refused", address)
+	}
+}
+
+func (f *familyOutcomes) families() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.attempts...)
+}
+
+func newTestFamilyDialer(strategy DialStrategy, outcomes *familyOutcomes) *familyDialer {
+	d := newFamilyDialer(strategy, nil)
+	d.resolver = dualStack
+	d.dial = outcomes.dial
+	d.fallbackDelay = 50 * time.Millisecond
+	d.attemptTimeout = 2 * time.Second
+	return d
+}
+
+func TestParseDialStrategy(t *testing.T) {
+	for _, value := range []string{"", "auto", "ipv4-only", "IPv6-Only", "ipv4-first"} {
+		_, err := ParseDialStrategy(value)
+		assert.NoError(t, err, value)
+	}
+
+	strategy, err := ParseDialStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, DialAuto, strategy)
+
+	_, err = ParseDialStrategy("ipv6-first")
+	assert.Error(t, err)
+}
+
+func TestFamilyDialer_Auto_FallsBackFromBlackholedIPv6(t *testing.T) {
+	outcomes := &familyOutcomes{v4: "ok", v6: "blackhole"}
+	d := newTestFamilyDialer(DialAuto, outcomes)
+
+	start := time.Now()
+	conn, err := d.DialContext(context.Background(), "xhub.example.com:443")
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+	conn.Close()
+
+	// IPv4 wins after the fallback delay instead of waiting for the IPv6 attempt timeout
+	assert.Less(t, elapsed, 500*time.Millisecond)
+	assert.GreaterOrEqual(t, elapsed, d.fallbackDelay)
+	assert.Equal(t, FamilyIPv4, d.Family())
+}
+
+func TestFamilyDialer_Auto_FallsBackImmediatelyOnIPv6Failure(t *testing.T) {
+	outcomes := &familyOutcomes{v4: "ok", v6: "refused"}
+	d := newTestFamilyDialer(DialAuto, outcomes)
+	d.fallbackDelay = time.Hour
+
+	conn, err := d.DialContext(context.Background(), "xhub.example.com:443")
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, FamilyIPv4, d.Family())
+}
+
+func TestFamilyDialer_Auto_PrefersIPv6(t *testing.T) {
+	outcomes := &familyOutcomes{v4: "ok", v6: "ok"}
+	d := newTestFamilyDialer(DialAuto, outcomes)
+
+	conn, err := d.DialContext(context.Background(), "xhub.example.com:443")
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, FamilyIPv6, d.Family())
+	assert.Equal(t, []string{FamilyIPv6}, outcomes.families())
+}
+
+func TestFamilyDialer_ForcedStrategies(t *testing.T) {
+	t.Run("IPv4Only", func(t *testing.T) {
+		outcomes := &familyOutcomes{v4: "refused", v6: "ok"}
+		d := newTestFamilyDialer(DialIPv4Only, outcomes)
+		_, err := d.DialContext(context.Background(), "xhub.example.com:443")
+		assert.Error(t, err)
+		assert.Equal(t, []string{FamilyIPv4}, outcomes.families())
+	})
+
+	t.Run("IPv6Only", func(t *testing.T) {
+		outcomes := &familyOutcomes{v4: "ok", v6: "ok"}
+		d := newTestFamilyDialer(DialIPv6Only, outcomes)
+		conn, err := d.DialContext(context.Background(), "xhub.example.com:443")
+		require.NoError(t, err)
+		conn.Close()
+		assert.Equal(t, []string{FamilyIPv6}, outcomes.families())
+		assert.Equal(t, FamilyIPv6, d.Family())
+	})
+
+	t.Run("IPv6OnlyWithoutAddress", func(t *testing.T) {
+		outcomes := &familyOutcomes{v4: "ok", v6: "ok"}
+		d := newTestFamilyDialer(DialIPv6Only, outcomes)
+		_, err := d.DialContext(context.Background(), "192.0.2.1:443")
+		assert.Error(t, err)
+		assert.Empty(t, outcomes.families())
+	})
+
+	t.Run("IPv4First", func(t *testing.T) {
+		outcomes := &familyOutcomes{v4: "refused", v6: "ok"}
+		d := newTestFamilyDialer(DialIPv4First, outcomes)
+		conn, err := d.DialContext(context.Background(), "xhub.example.com:443")
+		require.NoError(t, err)
+		conn.Close()
+		assert.Equal(t, []string{FamilyIPv4, FamilyIPv6}, outcomes.families())
+		assert.Equal(t, FamilyIPv6, d.Family())
+	})
+}
+
+func TestFamilyDialer_AllFamiliesFail(t *testing.T) {
+	outcomes := &familyOutcomes{v4: "refused", v6: "refused"}
+	d := newTestFamilyDialer(DialAuto, outcomes)
+
+	_, err := d.DialContext(context.Background(), "xhub.example.com:443")
+	assert.Error(t, err)
+	assert.Empty(t, d.Family())
+}
+
+func TestUsesCustomDialer(t *testing.T) {
+	original := proxyFromEnvironment
+	defer func() { proxyFromEnvironment = original }()
+
+	proxyFromEnvironment = func(*http.Request) (*url.URL, error) { return nil, nil }
+	assert.True(t, usesCustomDialer("xhub.example.com:443"))
+	assert.False(t, usesCustomDialer("unix:/run/xhub.sock"))
+	assert.False(t, usesCustomDialer("unix:///run/xhub.sock"))
+
+	// A configured proxy keeps gRPC's proxy dialer
+	proxyFromEnvironment = func(*http.Request) (*url.URL, error) {
+		return url.Parse("http://proxy.local:3128")
+	}
+	assert.False(t, usesCustomDialer("xhub.example.com:443"))
+
+	proxyFromEnvironment = func(*http.Request) (*url.URL, error) { return nil, errors.New("bad proxy") }
+	assert.False(t, usesCustomDialer("xhub.example.com:443"))
+}
+
+func TestReportClient_gRPC_DialStrategy(t *testing.T) {
+	testLogger := createTestLogger(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	mockServer := &mockReportServer{}
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, mockServer)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	_, port, err := net.SplitHostPort(lis.Addr().String())
+	require.NoError(t, err)
+
+	client := NewReportClient("localhost:"+port, "test-api-key", testLogger)
+	defer client.Close()
+	client.SetDialStrategy(DialIPv4Only)
+	assert.Empty(t, client.AddressFamily())
+
+	err = client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0})
+	require.NoError(t, err)
+
+	assert.Equal(t, FamilyIPv4, client.AddressFamily())
+	info := client.GetSecurityInfo()
+	assert.Equal(t, "ipv4-only", info["dial_strategy"])
+	assert.Equal(t, FamilyIPv4, info["address_family"])
+}
+
+func TestFamilyDialer_Auto_PrefersIPv4(t *testing.T) {
+	outcomes := &familyOutcomes{v4: "blackhole", v6: "ok"}
+	d := newTestFamilyDialer(DialAuto, outcomes)
+	d.preferIPv4 = true
+
+	conn, err := d.DialContext(context.Background(), "xhub.example.com:443")
+	require.NoError(t, err)
+	conn.Close()
+
+	// IPv6 is raced once IPv4 had its head start
+	assert.Equal(t, []string{FamilyIPv4, FamilyIPv6}, outcomes.families())
+	assert.Equal(t, FamilyIPv6, d.Family())
+}
+
+func TestServerAddr_IPv6Literals(t *testing.T) {
+	assert.Equal(t, "2001:db8::1", serverHost("[2001:db8::1]:443"))
+	assert.Equal(t, "2001:db8::1", serverHost("[2001:db8::1]"))
+	assert.Equal(t, "xhub.example.com", serverHost("xhub.example.com:443"))
+
+	assert.True(t, isUnbracketedIPv6("2001:db8::1:443"))
+	assert.False(t, isUnbracketedIPv6("[2001:db8::1]:443"))
+	assert.False(t, isUnbracketedIPv6("xhub.example.com:443"))
+	assert.False(t, isUnbracketedIPv6("unix:///run/xhub.sock"))
+
+	assert.True(t, isLocalServer("[::1]:9090"))
+	assert.False(t, shouldUseTLS("[::1]:9090"))
+	assert.True(t, shouldUseTLS("[2001:db8::1]:443"))
+}
+
+func TestReportClient_gRPC_IPv6Literal(t *testing.T) {
+	lis, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	mockServer := &mockReportServer{}
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, mockServer)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	// Listener addresses of IPv6 literals are bracketed: [::1]:port
+	client := NewReportClient(lis.Addr().String(), "test-api-key", createTestLogger(t))
+	defer client.Close()
+	client.SetDialStrategy(DialAuto)
+	assert.False(t, client.IsTLSEnabled(), "the IPv6 loopback is a local server")
+
+	require.NoError(t, client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Equal(t, FamilyIPv6, client.AddressFamily())
+}
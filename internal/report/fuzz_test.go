@@ -0,0 +1,51 @@
+package report
+
+import (
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// FuzzStatusToProto checks that any status the panel can produce converts to a marshalable proto
+func FuzzStatusToProto(f *testing.F) {
+	f.Add([]byte(`{"success":true,"obj":{"cpu":4.02,"uptime":269583,"xray":{"state":"running","version":"25.8.3"}}}`))
+	f.Add([]byte(`{"success":true,"obj":{"uptime":9223372036854775807,"tcpCount":-9223372036854775808}}`))
+	f.Add([]byte("{\"success\":true,\"obj\":{\"xray\":{\"errorMsg\":\"\xff\"},\"publicIP\":{\"ipv4\":\"\xc3\x28\"}}}"))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		resp, err := monitor.DecodeServerStatus(body, nil)
+		if err != nil {
+			return
+		}
+
+		req := &pb.ReportRequest{Uuid: "fuzz", Data: ConvertToProto(resp.Data)}
+		if _, err := proto.Marshal(req); err != nil {
+			t.Fatalf("converted status does not marshal: %v", err)
+		}
+	})
+}
+
+func TestConvertToProto_Hardening(t *testing.T) {
+	data := &monitor.ServerStatusData{
+		Uptime:           math.MaxInt32 + 10, // Would wrap negative with a plain int32 conversion
+		TCPCount:         math.MinInt32 - 10,
+		Xray:             monitor.XrayInfo{ErrorMsg: "bad\xffmsg"},
+		InboundProtocols: []string{"vless", "\xfe"},
+		PortListeners:    []monitor.PortListener{{Port: 443, ListenerProcess: "ng\xffinx"}},
+	}
+
+	converted := ConvertToProto(data)
+	if converted.Uptime != math.MaxInt32 || converted.TcpCount != math.MinInt32 {
+		t.Fatalf("int32 fields not clamped: uptime=%d tcp=%d", converted.Uptime, converted.TcpCount)
+	}
+	if _, err := proto.Marshal(&pb.ReportRequest{Data: converted}); err != nil {
+		t.Fatalf("sanitized status does not marshal: %v", err)
+	}
+	if data.InboundProtocols[1] != "\xfe" {
+		t.Fatal("conversion must not modify the input")
+	}
+}
@@ -3,15 +3,22 @@ package report
 import (
 	"context"
 	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/faultinject"
 	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/sysinfo"
 	pb "xhub-agent/proto/reportpb"
 )
 
@@ -19,12 +26,15 @@ import (
 type mockReportServer struct {
 	pb.UnimplementedReportServiceServer
 	receivedRequests []*pb.ReportRequest
+	receivedMetadata []metadata.MD
 	response         *pb.ReportResponse
 	shouldError      codes.Code
 }
 
 func (m *mockReportServer) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
 	m.receivedRequests = append(m.receivedRequests, req)
+	md, _ := metadata.FromIncomingContext(ctx)
+	m.receivedMetadata = append(m.receivedMetadata, md)
 
 	if m.shouldError != codes.OK {
 		return nil, status.Error(m.shouldError, "test error")
@@ -161,6 +171,18 @@ func TestReportClient_gRPC_SendReport_Success(t *testing.T) {
 			Memory:  134217728,
 			Uptime:  1800,
 		},
+		InboundProtocols: []string{"shadowsocks", "trojan", "vless"},
+		Fail2banBans:     map[string]int{"sshd": 3, "recidive": 0},
+		DiskIO:           &monitor.DiskIOStats{Device: "vda", WriteBytesPerSec: 4096, Utilization: 12.5},
+		DiskSMART:        &monitor.DiskSMART{Device: "vda", Error: "no SMART data"},
+		Pressure: &monitor.HostPressure{
+			CPU:          &monitor.PressureStall{SomeAvg10: 12.5, SomeTotalUs: 4242},
+			ThermalZones: []monitor.ThermalZone{{Zone: "thermal_zone0", Type: "x86_pkg_temp", TemperatureCelsius: 87.5}},
+		},
+		WireGuard: []monitor.WireGuardInterface{{
+			Name: "wg0", ListenPort: 51820, Peers: 2, ActivePeers: 1, RxBytes: 300,
+			PeerStats: []monitor.WireGuardPeer{{PublicKey: "peer-a", LatestHandshake: 1700000000, RxBytes: 300}},
+		}},
 	}
 
 	// Send report
@@ -175,6 +197,212 @@ func TestReportClient_gRPC_SendReport_Success(t *testing.T) {
 	assert.NotNil(t, req.Data)
 	assert.Equal(t, float64(25.5), req.Data.Cpu)
 	assert.Equal(t, int64(1073741824), req.Data.Memory.Current)
+	assert.Equal(t, []string{"shadowsocks", "trojan", "vless"}, req.Data.InboundProtocols)
+	assert.Equal(t, map[string]int32{"sshd": 3, "recidive": 0}, req.Data.Fail2BanBans)
+	assert.Equal(t, "vda", req.Data.DiskIo.Device)
+	assert.Equal(t, float64(4096), req.Data.DiskIo.WriteBytesPerSec)
+	assert.Equal(t, float64(12.5), req.Data.DiskIo.Utilization)
+	assert.False(t, req.Data.DiskSmart.Available)
+	assert.Equal(t, "no SMART data", req.Data.DiskSmart.Error)
+	assert.Equal(t, float64(12.5), req.Data.Pressure.Cpu.SomeAvg10)
+	assert.Equal(t, uint64(4242), req.Data.Pressure.Cpu.SomeTotalUs)
+	assert.Nil(t, req.Data.Pressure.Memory)
+	require.Len(t, req.Data.Pressure.ThermalZones, 1)
+	assert.Equal(t, "x86_pkg_temp", req.Data.Pressure.ThermalZones[0].Type)
+	assert.Equal(t, float64(87.5), req.Data.Pressure.ThermalZones[0].TemperatureCelsius)
+	require.Len(t, req.Data.Wireguard, 1)
+	assert.Equal(t, int32(1), req.Data.Wireguard[0].ActivePeers)
+	assert.Equal(t, uint64(300), req.Data.Wireguard[0].RxBytes)
+	require.Len(t, req.Data.Wireguard[0].PeerStats, 1)
+	assert.Equal(t, int64(1700000000), req.Data.Wireguard[0].PeerStats[0].LatestHandshake)
+}
+
+func TestReportClient_gRPC_SendReport_AgentInfoMetadata(t *testing.T) {
+	testLogger := createTestLogger(t)
+
+	mockServer := &mockReportServer{}
+	addr, cleanup := setupGRPCTestServer(t, mockServer)
+	defer cleanup()
+
+	client := NewReportClient(addr, "test-api-key", testLogger)
+	defer client.Close()
+
+	client.SetAgentInfo(time.Unix(1700000000, 0), 7)
+	client.SetConfigFingerprint("0123456789abcdef")
+
+	err := client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0})
+	require.NoError(t, err)
+	require.Len(t, mockServer.receivedMetadata, 1)
+
+	md := mockServer.receivedMetadata[0]
+	assert.Equal(t, []string{"Bearer test-api-key"}, md.Get("authorization"))
+	assert.Equal(t, []string{"1700000000"}, md.Get("x-agent-start-time"))
+	assert.Equal(t, []string{"7"}, md.Get("x-agent-restart-count"))
+	assert.Equal(t, []string{"0123456789abcdef"}, md.Get("x-agent-config-fingerprint"))
+}
+
+func TestReportClient_gRPC_SendReport_HostInfo(t *testing.T) {
+	testLogger := createTestLogger(t)
+
+	mockServer := &mockReportServer{}
+	addr, cleanup := setupGRPCTestServer(t, mockServer)
+	defer cleanup()
+
+	client := NewReportClient(addr, "test-api-key", testLogger)
+	defer client.Close()
+
+	// Without host metadata the field is absent
+	require.NoError(t, client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Nil(t, mockServer.receivedRequests[0].Agent)
+
+	client.SetHostInfo(sysinfo.Info{
+		AgentVersion: "v1.2.3", GoVersion: "go1.24.2", OS: "linux", Arch: "amd64",
+		Kernel: "6.1.0-18-amd64", Hostname: "node-1", Virtualization: "kvm",
+		Geo: &sysinfo.Geo{IP: "203.0.113.7", Country: "DE", ASN: 24940, ASOrg: "Hetzner Online GmbH"},
+	})
+	require.NoError(t, client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0}))
+
+	agent := mockServer.receivedRequests[1].Agent
+	require.NotNil(t, agent)
+	assert.Equal(t, "v1.2.3", agent.AgentVersion)
+	assert.Equal(t, "linux", agent.Os)
+	assert.Equal(t, "6.1.0-18-amd64", agent.Kernel)
+	assert.Equal(t, "node-1", agent.Hostname)
+	assert.Equal(t, "kvm", agent.Virtualization)
+	require.NotNil(t, agent.Geo)
+	assert.Equal(t, uint32(24940), agent.Geo.Asn)
+	assert.Equal(t, "DE", agent.Geo.Country)
+}
+
+func TestReportClient_gRPC_ConnectionHint(t *testing.T) {
+	testLogger := createTestLogger(t)
+
+	// Reserve a port with nothing listening on it
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	deadAddr := lis.Addr().String()
+	lis.Close()
+
+	client := NewReportClient(deadAddr, "test-api-key", testLogger)
+	defer client.Close()
+	client.SetConnectionHint("derived from legacy reportUrl")
+
+	err = client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "derived from legacy reportUrl")
+
+	// Once an RPC succeeds, the hint is no longer appended
+	mockServer := &mockReportServer{}
+	addr, cleanup := setupGRPCTestServer(t, mockServer)
+	defer cleanup()
+
+	okClient := NewReportClient(addr, "test-api-key", testLogger)
+	defer okClient.Close()
+	okClient.SetConnectionHint("derived from legacy reportUrl")
+	require.NoError(t, okClient.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0}))
+
+	mockServer.shouldError = codes.Unavailable
+	err = okClient.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "legacy reportUrl")
+}
+
+func TestReportClient_gRPC_FaultInjection(t *testing.T) {
+	testLogger := createTestLogger(t)
+
+	mockServer := &mockReportServer{}
+	addr, cleanup := setupGRPCTestServer(t, mockServer)
+	defer cleanup()
+
+	client := NewReportClient(addr, "test-api-key", testLogger)
+	defer client.Close()
+	client.SetFaultInjector(faultinject.New(map[string]float64{faultinject.PointReport: 1}, 1))
+
+	err := client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "injected report failure")
+	assert.Empty(t, mockServer.receivedRequests) // never reached the server
+}
+
+func TestReportClient_gRPC_ErrorCounts_CarryOverAndAck(t *testing.T) {
+	testLogger := createTestLogger(t)
+
+	mockServer := &mockReportServer{shouldError: codes.Unavailable}
+	addr, cleanup := setupGRPCTestServer(t, mockServer)
+	defer cleanup()
+
+	client := NewReportClient(addr, "test-api-key", testLogger)
+	defer client.Close()
+
+	counters := errstats.NewCounters()
+	counters.RecordCategory(errstats.PanelTimeout)
+	client.SetErrorCounters(counters)
+
+	// Failed send: the error is categorized and counts carry over
+	err := client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0})
+	require.Error(t, err)
+	assert.Equal(t, errstats.GRPCUnavailable, counters.Record(err))
+	assert.Equal(t, map[errstats.ErrorCategory]uint64{errstats.PanelTimeout: 1, errstats.GRPCUnavailable: 1}, counters.Pending())
+
+	// Acknowledged send: counts are delivered and reset
+	mockServer.shouldError = codes.OK
+	require.NoError(t, client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0}))
+	require.NotEmpty(t, mockServer.receivedRequests)
+
+	last := mockServer.receivedRequests[len(mockServer.receivedRequests)-1]
+	require.Len(t, last.ErrorCounts, 2)
+	assert.Equal(t, pb.ErrorCategory_ERROR_CATEGORY_PANEL_TIMEOUT, last.ErrorCounts[0].Category)
+	assert.Equal(t, uint64(1), last.ErrorCounts[0].Count)
+	assert.Equal(t, pb.ErrorCategory_ERROR_CATEGORY_GRPC_UNAVAILABLE, last.ErrorCounts[1].Category)
+	assert.Empty(t, counters.Pending())
+}
+
+func TestErrorCategory_ProtoRoundTrip(t *testing.T) {
+	for _, category := range errstats.Categories() {
+		msg := &pb.ErrorCategoryCount{Category: pb.ErrorCategory(category), Count: 3}
+		assert.Equal(t, "ERROR_CATEGORY_"+strings.ToUpper(category.String()), msg.Category.String())
+
+		data, err := proto.Marshal(msg)
+		require.NoError(t, err)
+		var decoded pb.ErrorCategoryCount
+		require.NoError(t, proto.Unmarshal(data, &decoded))
+		assert.Equal(t, category, errstats.ErrorCategory(decoded.Category))
+		assert.Equal(t, uint64(3), decoded.Count)
+	}
+	assert.Len(t, pb.ErrorCategory_name, len(errstats.Categories()))
+}
+
+func TestReportClient_gRPC_WaitForReady(t *testing.T) {
+	testLogger := createTestLogger(t)
+
+	// Reserve an address, then leave it unserved for a moment
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	mockServer := &mockReportServer{}
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, mockServer)
+	defer s.Stop()
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		s.Serve(lis)
+	}()
+
+	client := NewReportClient(addr, "test-api-key", testLogger)
+	defer client.Close()
+	client.SetWaitForReady(true)
+
+	// The call blocks through the unavailable window instead of failing fast
+	err = client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0})
+	require.NoError(t, err)
+	assert.Len(t, mockServer.receivedRequests, 1)
 }
 
 func TestReportClient_gRPC_SendReport_AuthenticationError(t *testing.T) {
@@ -319,6 +547,7 @@ func TestConvertToProto(t *testing.T) {
 
 	assert.Equal(t, data.PublicIP.IPv4, pbData.PublicIp.Ipv4)
 	assert.Equal(t, data.PublicIP.IPv6, pbData.PublicIp.Ipv6)
+	assert.Equal(t, "dual-stack", pbData.IpStack)
 
 	assert.Equal(t, data.Xray.State, pbData.Xray.State)
 	assert.Equal(t, data.Xray.ErrorMsg, pbData.Xray.ErrorMsg)
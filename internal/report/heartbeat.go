@@ -0,0 +1,69 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// ErrHeartbeatUnsupported is returned when xhub does not implement the heartbeat RPC
+var ErrHeartbeatUnsupported = errors.New("xhub does not support heartbeats")
+
+// Heartbeat is the health of the agent itself, independent of the 3x-ui panel
+type Heartbeat struct {
+	Version        string
+	Uptime         time.Duration
+	PanelReachable bool      // Whether the last report cycle reached the panel
+	LastError      string    // Error of the last failed report cycle
+	LastErrorAt    time.Time // Zero if no cycle failed yet
+	LastSuccessAt  time.Time // Zero if no cycle succeeded yet
+}
+
+// SendHeartbeat tells xhub the agent is alive. It returns ErrHeartbeatUnsupported if xhub
+// does not implement the RPC.
+func (r *ReportClient) SendHeartbeat(uuid string, hb Heartbeat) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.HeartbeatRequest{
+		Uuid:           uuid,
+		AgentVersion:   hb.Version,
+		UptimeSeconds:  int64(hb.Uptime / time.Second),
+		PanelReachable: hb.PanelReachable,
+		LastError:      hb.LastError,
+		LastErrorAt:    unixOrZero(hb.LastErrorAt),
+		LastSuccessAt:  unixOrZero(hb.LastSuccessAt),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.Heartbeat(ctx, req, r.callOptions()...)
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return ErrHeartbeatUnsupported
+		}
+		return r.withConnectionHint(fmt.Errorf("gRPC heartbeat failed: %w", err))
+	}
+	if !resp.Success {
+		return rejectedf("heartbeat rejected: %s", resp.Message)
+	}
+	return nil
+}
+
+// unixOrZero returns the Unix time of t, 0 for the zero time
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// heartbeatServer records heartbeats
+type heartbeatServer struct {
+	pb.UnimplementedReportServiceServer
+	heartbeats []*pb.HeartbeatRequest
+}
+
+func (s *heartbeatServer) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.ReportResponse, error) {
+	s.heartbeats = append(s.heartbeats, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func newHeartbeatClient(t *testing.T, server pb.ReportServiceServer) *ReportClient {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestReportClient_SendHeartbeat(t *testing.T) {
+	server := &heartbeatServer{}
+	client := newHeartbeatClient(t, server)
+
+	failedAt := time.Unix(1700000000, 0)
+	require.NoError(t, client.SendHeartbeat("test-uuid", Heartbeat{
+		Version:     "v1.2.3",
+		Uptime:      90 * time.Second,
+		LastError:   "connection refused",
+		LastErrorAt: failedAt,
+	}))
+
+	require.Len(t, server.heartbeats, 1)
+	hb := server.heartbeats[0]
+	assert.Equal(t, "test-uuid", hb.Uuid)
+	assert.Equal(t, "v1.2.3", hb.AgentVersion)
+	assert.Equal(t, int64(90), hb.UptimeSeconds)
+	assert.False(t, hb.PanelReachable)
+	assert.Equal(t, "connection refused", hb.LastError)
+	assert.Equal(t, failedAt.Unix(), hb.LastErrorAt)
+	assert.Zero(t, hb.LastSuccessAt, "no successful cycle yet")
+}
+
+func TestReportClient_SendHeartbeatUnsupported(t *testing.T) {
+	client := newHeartbeatClient(t, &pb.UnimplementedReportServiceServer{})
+	assert.ErrorIs(t, client.SendHeartbeat("test-uuid", Heartbeat{}), ErrHeartbeatUnsupported)
+}
@@ -0,0 +1,185 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"xhub-agent/internal/sanitize"
+)
+
+// maxHTTPSResponse caps the body of an HTTPS transport response
+const maxHTTPSResponse = 1 << 20
+
+// httpsTransport posts RPCs as JSON to the REST endpoints of xhub: the request message
+// (protojson) goes to <base URL>/<full method name>, e.g. /reportpb.ReportService/SendReport,
+// with the gRPC metadata as HTTP headers. xhub answers 200 with the reply message, or an
+// error status with an optional google.rpc.Status JSON body ({"code": 5, "message": "..."}).
+type httpsTransport struct {
+	base   *url.URL
+	client *http.Client // Created on first use with the connection settings of the client
+}
+
+func (t *httpsTransport) Name() string {
+	return "HTTPS"
+}
+
+// httpsTransport returns the HTTPS transport, creating its HTTP client if needed
+func (r *ReportClient) httpsTransport() *httpsTransport {
+	t := r.transport.https
+	if t.client == nil {
+		t.client = r.newHTTPSClient(t.base)
+	}
+	return t
+}
+
+// newHTTPSClient creates the HTTP client of the HTTPS transport with the proxy, dial strategy
+// and client certificate settings of the gRPC connection
+func (r *ReportClient) newHTTPSClient(base *url.URL) *http.Client {
+	transport := &http.Transport{
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			switch {
+			case r.proxy.Direct:
+				return nil, nil
+			case r.proxy.URL != nil:
+				return r.proxy.URL, nil
+			default:
+				return proxyFromEnvironment(req)
+			}
+		},
+		DialContext:         (&net.Dialer{Timeout: r.connectTimeout(), KeepAlive: 30 * time.Second}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: r.connectTimeout(),
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if r.dialer != nil {
+		transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return r.dialer.DialContext(ctx, addr)
+		}
+	}
+	if r.clientTLS != nil {
+		transport.TLSClientConfig = r.clientTLS.tlsConfig(base.Hostname())
+	} else {
+		transport.TLSClientConfig = &tls.Config{ServerName: base.Hostname()}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// close releases the idle connections; the next RPC creates a new client
+func (t *httpsTransport) close() {
+	if t.client != nil {
+		t.client.CloseIdleConnections()
+		t.client = nil
+	}
+}
+
+// Invoke posts req and decodes the reply. Header call options receive the response headers.
+func (t *httpsTransport) Invoke(ctx context.Context, method string, req, reply interface{}, opts ...grpc.CallOption) error {
+	in, ok := req.(proto.Message)
+	out, ok2 := reply.(proto.Message)
+	if !ok || !ok2 {
+		return status.Errorf(codes.Internal, "HTTPS transport: %s does not use protobuf messages", method)
+	}
+	body, err := protojson.Marshal(in)
+	if err != nil {
+		return status.Errorf(codes.Internal, "HTTPS transport: failed to encode request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.base.JoinPath(method).String(), bytes.NewReader(body))
+	if err != nil {
+		return status.Errorf(codes.Internal, "HTTPS transport: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for key, values := range md {
+		if strings.HasSuffix(key, "-bin") {
+			continue
+		}
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return status.Errorf(codes.Unavailable, "HTTPS transport: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := sanitize.ReadLimited(resp.Body, maxHTTPSResponse)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "HTTPS transport: failed to read response: %v", err)
+	}
+
+	for _, opt := range opts {
+		if header, ok := opt.(grpc.HeaderCallOption); ok {
+			*header.HeaderAddr = headerMetadata(resp.Header)
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpStatusError(resp.StatusCode, data)
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, out); err != nil {
+		return status.Errorf(codes.Internal, "HTTPS transport: invalid response: %v", err)
+	}
+	return nil
+}
+
+// headerMetadata converts HTTP response headers to gRPC metadata (lowercase keys)
+func headerMetadata(header http.Header) metadata.MD {
+	md := metadata.MD{}
+	for key, values := range header {
+		md.Append(strings.ToLower(key), values...)
+	}
+	return md
+}
+
+// httpStatusError converts an HTTP error response to a gRPC status error, preferring the
+// google.rpc.Status of the body
+func httpStatusError(statusCode int, body []byte) error {
+	var rpcStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &rpcStatus) == nil && rpcStatus.Code > int(codes.OK) && rpcStatus.Code <= int(codes.Unauthenticated) {
+		return status.Error(codes.Code(rpcStatus.Code), sanitize.String(rpcStatus.Message))
+	}
+
+	code := codes.Unknown
+	switch {
+	case statusCode == http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case statusCode == http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case statusCode == http.StatusForbidden:
+		code = codes.PermissionDenied
+	case statusCode == http.StatusNotFound:
+		code = codes.NotFound
+	case statusCode == http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case statusCode == http.StatusNotImplemented:
+		code = codes.Unimplemented
+	case statusCode == http.StatusBadGateway, statusCode == http.StatusServiceUnavailable, statusCode == http.StatusGatewayTimeout:
+		code = codes.Unavailable
+	case statusCode >= 500:
+		code = codes.Internal
+	}
+	return status.Error(code, fmt.Sprintf("HTTPS transport: HTTP %d %s", statusCode, http.StatusText(statusCode)))
+}
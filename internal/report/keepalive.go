@@ -0,0 +1,104 @@
+package report
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Default keepalive and reconnection policy of the xhub connection
+const (
+	DefaultKeepaliveTime       = 60 * time.Second
+	DefaultKeepaliveTimeout    = 20 * time.Second
+	DefaultReconnectMaxBackoff = 30 * time.Second
+)
+
+// KeepalivePolicy detects dead connections to xhub (NAT timeouts, idle connections dropped
+// by a middlebox) with HTTP/2 pings and bounds the delay before they are re-established.
+// Zero durations select the defaults.
+type KeepalivePolicy struct {
+	Time                time.Duration // Inactivity before a ping (grpc_keepalive_time), at least 10s
+	Timeout             time.Duration // Wait for the ping ack before the connection is closed (grpc_keepalive_timeout)
+	PermitWithoutStream bool          // Also ping while no RPC is in flight (grpc_keepalive_without_stream)
+	MaxBackoff          time.Duration // Cap of the delay between reconnection attempts (grpc_reconnect_max_backoff)
+}
+
+// SetKeepalive sets the keepalive and reconnection policy. It applies to new connections.
+func (r *ReportClient) SetKeepalive(policy KeepalivePolicy) {
+	r.keepalive = policy
+}
+
+// Reconnects returns how many times a dropped connection was re-established proactively
+func (r *ReportClient) Reconnects() uint64 {
+	return r.reconnects.Load()
+}
+
+// connectionOptions returns the dial options of the keepalive and reconnection policy. Idle
+// mode is disabled: the connection is kept up between reports instead of being torn down.
+func (r *ReportClient) connectionOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithKeepaliveParams(r.keepaliveParams()),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: r.reconnectBackoff(), MinConnectTimeout: r.connectTimeout()}),
+		grpc.WithIdleTimeout(0),
+	}
+}
+
+// keepaliveParams returns the ping parameters of the connection
+func (r *ReportClient) keepaliveParams() keepalive.ClientParameters {
+	params := keepalive.ClientParameters{
+		Time:                r.keepalive.Time,
+		Timeout:             r.keepalive.Timeout,
+		PermitWithoutStream: r.keepalive.PermitWithoutStream,
+	}
+	if params.Time <= 0 {
+		params.Time = DefaultKeepaliveTime
+	}
+	if params.Timeout <= 0 {
+		params.Timeout = DefaultKeepaliveTimeout
+	}
+	return params
+}
+
+// reconnectBackoff returns gRPC's default exponential backoff capped at MaxBackoff
+func (r *ReportClient) reconnectBackoff() backoff.Config {
+	reconnect := backoff.DefaultConfig
+	reconnect.MaxDelay = r.keepalive.MaxBackoff
+	if reconnect.MaxDelay <= 0 {
+		reconnect.MaxDelay = DefaultReconnectMaxBackoff
+	}
+	return reconnect
+}
+
+// watchConnection re-establishes conn as soon as it drops (keepalive timeout, GOAWAY, reset)
+// instead of leaving the reconnection to the next report, until conn is closed. It only
+// touches conn, the logger and the reconnect counter, so it is safe alongside RPCs.
+func (r *ReportClient) watchConnection(conn *grpc.ClientConn, serverAddr string) {
+	state := conn.GetState()
+	dropped := false
+	for conn.WaitForStateChange(context.Background(), state) {
+		previous := state
+		state = conn.GetState()
+		switch state {
+		case connectivity.Idle:
+			if previous == connectivity.Ready {
+				r.logger.Warnf("🔌 gRPC connection to %s dropped, reconnecting", serverAddr)
+				dropped = true
+			}
+			if dropped {
+				conn.Connect()
+			}
+		case connectivity.Ready:
+			if dropped {
+				dropped = false
+				r.reconnects.Add(1)
+				r.logger.Infof("✅ gRPC connection to %s re-established", serverAddr)
+			}
+		case connectivity.Shutdown:
+			return
+		}
+	}
+}
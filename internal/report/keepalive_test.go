@@ -0,0 +1,90 @@
+package report
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// serveReports serves mock on addr until the returned server is stopped
+func serveReports(t *testing.T, addr string, mock *mockReportServer) *grpc.Server {
+	lis, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, mock)
+	go s.Serve(lis)
+	return s
+}
+
+func TestReportClient_KeepaliveReconnect(t *testing.T) {
+	testLogger := createTestLogger(t)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	server := serveReports(t, addr, &mockReportServer{})
+	client := NewReportClient(addr, "test-api-key", testLogger)
+	defer client.Close()
+	client.SetKeepalive(KeepalivePolicy{MaxBackoff: 200 * time.Millisecond})
+
+	require.NoError(t, client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0}))
+
+	// xhub restarts: the connection is re-established without waiting for a report
+	server.Stop()
+	restarted := &mockReportServer{}
+	server = serveReports(t, addr, restarted)
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return client.Reconnects() == 1 && client.conn.GetState() == connectivity.Ready
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Empty(t, restarted.receivedRequests)
+
+	require.NoError(t, client.SendReport("test-uuid-123", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Len(t, restarted.receivedRequests, 1)
+}
+
+func TestReportClient_WatchConnectionStopsOnClose(t *testing.T) {
+	testLogger := createTestLogger(t)
+	addr, cleanup := setupGRPCTestServer(t, &mockReportServer{})
+	defer cleanup()
+
+	client := NewReportClient(addr, "test-api-key", testLogger)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, client.CheckConnection(ctx))
+	conn := client.conn
+
+	require.NoError(t, client.Close())
+	assert.Equal(t, connectivity.Shutdown, conn.GetState())
+	assert.Zero(t, client.Reconnects(), "a closed connection is not re-established")
+}
+
+func TestReportClient_KeepalivePolicy(t *testing.T) {
+	client := NewReportClient("localhost:9090", "test-api-key", createTestLogger(t))
+
+	params, reconnect := client.keepaliveParams(), client.reconnectBackoff()
+	assert.Equal(t, DefaultKeepaliveTime, params.Time)
+	assert.Equal(t, DefaultKeepaliveTimeout, params.Timeout)
+	assert.False(t, params.PermitWithoutStream)
+	assert.Equal(t, DefaultReconnectMaxBackoff, reconnect.MaxDelay)
+	assert.Equal(t, time.Second, reconnect.BaseDelay)
+
+	client.SetKeepalive(KeepalivePolicy{Time: 15 * time.Second, Timeout: 5 * time.Second, PermitWithoutStream: true, MaxBackoff: time.Minute})
+	params, reconnect = client.keepaliveParams(), client.reconnectBackoff()
+	assert.Equal(t, 15*time.Second, params.Time)
+	assert.Equal(t, 5*time.Second, params.Timeout)
+	assert.True(t, params.PermitWithoutStream)
+	assert.Equal(t, time.Minute, reconnect.MaxDelay)
+}
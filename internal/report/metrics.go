@@ -0,0 +1,27 @@
+package report
+
+import (
+	"context"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/metrics"
+)
+
+// SetMetrics records the outcome of every report RPC in m and publishes the offline queue depth
+func (r *ReportClient) SetMetrics(m *metrics.Registry) {
+	r.metrics = m
+	m.GaugeFunc("xhub_agent_report_queue_depth", "Reports queued on disk while xhub is unreachable.",
+		func() float64 { return float64(r.QueuedReports()) })
+}
+
+// recordMetrics is the first unary interceptor: it records each report RPC once with its
+// final status code, after retries; replays of queued reports are recorded on their own
+func (r *ReportClient) recordMetrics(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	r.metrics.ObserveReport(path.Base(method), status.Code(err).String())
+	return err
+}
@@ -0,0 +1,31 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"xhub-agent/internal/metrics"
+)
+
+func TestReportClient_Metrics(t *testing.T) {
+	server := &flakyServer{code: codes.Unavailable, failures: 2}
+	client := newStreamClient(t, server)
+	client.SetStreaming(false)
+	// Retried attempts count once, with the final code
+	client.SetRetryPolicy(RetryPolicy{Attempts: 2, Backoff: time.Millisecond, Codes: DefaultRetryCodes})
+	registry := metrics.NewRegistry()
+	client.SetMetrics(registry)
+
+	assert.Error(t, client.SendOnlineUsersReport("test-uuid", []string{"user1"}))
+	assert.NoError(t, client.SendOnlineUsersReport("test-uuid", []string{"user1"}))
+
+	var out strings.Builder
+	registry.Write(&out)
+	assert.Contains(t, out.String(), `xhub_agent_reports_total{rpc="SendOnlineUsersReport",code="OK"} 1`+"\n")
+	assert.Contains(t, out.String(), `xhub_agent_reports_total{rpc="SendOnlineUsersReport",code="Unavailable"} 1`+"\n")
+	assert.Contains(t, out.String(), "xhub_agent_report_queue_depth 0\n")
+}
@@ -0,0 +1,157 @@
+package report
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// clientTLS is the mutual TLS and server verification configuration (grpc_client_cert,
+// grpc_client_key, grpc_ca, grpc_cert_pin_sha256)
+type clientTLS struct {
+	certFile string
+	keyFile  string
+	roots    *x509.CertPool   // nil verifies xhub against the system roots
+	pins     []CertificatePin // Accepted certificate fingerprints, nil for no pinning
+
+	mutex   sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time // Latest modification time of the certificate files when loaded
+	expired bool      // The expiry of the loaded certificate was logged
+}
+
+// SetClientTLS authenticates the connection with a client certificate (mutual TLS) and
+// verifies xhub against caFile. Either may be empty: certFile and keyFile go together, an
+// empty caFile keeps the system roots. Setting it forces TLS, also for local servers.
+// Renewed certificate files are loaded by the next RPC, which then reconnects.
+func (r *ReportClient) SetClientTLS(certFile, keyFile, caFile string) error {
+	config := &clientTLS{certFile: certFile, keyFile: keyFile}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read gRPC CA file: %w", err)
+		}
+		config.roots = x509.NewCertPool()
+		if !config.roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no PEM certificate found in gRPC CA file %s", caFile)
+		}
+	}
+	if certFile != "" {
+		if _, err := config.load(); err != nil {
+			return err
+		}
+	}
+
+	r.Close()
+	if r.clientTLS != nil {
+		config.pins = r.clientTLS.pins
+	}
+	r.clientTLS = config
+	r.useTLS = true
+	return nil
+}
+
+// ClientCertificateExpiry returns when the loaded client certificate expires, zero without one
+func (r *ReportClient) ClientCertificateExpiry() time.Time {
+	if r.clientTLS == nil {
+		return time.Time{}
+	}
+	r.clientTLS.mutex.Lock()
+	defer r.clientTLS.mutex.Unlock()
+	if r.clientTLS.cert == nil {
+		return time.Time{}
+	}
+	return r.clientTLS.cert.Leaf.NotAfter
+}
+
+// tlsConfig returns the TLS configuration of a new connection
+func (c *clientTLS) tlsConfig(serverName string) *tls.Config {
+	config := &tls.Config{ServerName: serverName, RootCAs: c.roots}
+	if c.certFile != "" {
+		config.GetClientCertificate = c.clientCertificate
+	}
+	if len(c.pins) > 0 {
+		config.VerifyPeerCertificate = c.verifyPins
+		// Without a CA file the pin alone identifies xhub
+		config.InsecureSkipVerify = c.roots == nil
+	}
+	return config
+}
+
+// clientCertificate returns the loaded certificate for TLS handshakes
+func (c *clientTLS) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.cert, nil
+}
+
+// filesModTime returns the latest modification time of the certificate and key files
+func (c *clientTLS) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to read gRPC client certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// load (re)loads the certificate files if they changed since the last load and reports
+// whether a new certificate was loaded. A failed load keeps the current certificate.
+func (c *clientTLS) load() (bool, error) {
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return false, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.cert != nil && modTime.Equal(c.modTime) {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load gRPC client certificate: %w", err)
+	}
+	c.cert, c.modTime, c.expired = &cert, modTime, false
+	return true, nil
+}
+
+// refreshClientCertificate loads renewed client certificate files before an RPC. A renewed
+// certificate closes the connection so that the next one presents it; an expired certificate
+// that was not renewed is logged once.
+func (r *ReportClient) refreshClientCertificate() {
+	c := r.clientTLS
+	if c == nil || c.certFile == "" {
+		return
+	}
+	renewed, err := c.load()
+	if err != nil {
+		if r.shouldLogError("client_certificate") {
+			r.logger.Warnf("⚠️  %v, keeping the current certificate", err)
+		}
+		return
+	}
+	if renewed {
+		r.logger.Infof("🔐 Loaded renewed gRPC client certificate (expires %s)", r.ClientCertificateExpiry().Format(time.RFC3339))
+		if r.conn != nil {
+			r.Close()
+		}
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.expired && time.Now().After(c.cert.Leaf.NotAfter) {
+		c.expired = true
+		r.logger.Errorf("❌ gRPC client certificate %s expired on %s, renew it to keep reporting",
+			c.certFile, c.cert.Leaf.NotAfter.Format(time.RFC3339))
+	}
+}
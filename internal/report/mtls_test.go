@@ -0,0 +1,184 @@
+package report
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// testCA issues certificates for the mutual TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a leaf certificate named commonName
+func (ca *testCA) issue(t *testing.T, commonName string, notAfter time.Time, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// mtlsServer records the client certificate name and authorization of each report
+type mtlsServer struct {
+	pb.UnimplementedReportServiceServer
+	mutex       sync.Mutex
+	clientNames []string
+	apiKeys     []string
+}
+
+func (s *mtlsServer) SendOnlineUsersReport(ctx context.Context, req *pb.OnlineUsersReportRequest) (*pb.ReportResponse, error) {
+	p, _ := peer.FromContext(ctx)
+	tlsInfo := p.AuthInfo.(credentials.TLSInfo)
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.clientNames = append(s.clientNames, tlsInfo.State.PeerCertificates[0].Subject.CommonName)
+	s.apiKeys = append(s.apiKeys, md.Get("authorization")...)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+// startMTLSServer serves server over TLS requiring client certificates issued by ca
+func startMTLSServer(t *testing.T, ca *testCA, server pb.ReportServiceServer) string {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "xhub", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth)
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// writeClientCert writes a client certificate named commonName to dir, dated modTime
+func writeClientCert(t *testing.T, ca *testCA, dir, commonName string, modTime time.Time) (certFile, keyFile string) {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, commonName, time.Now().Add(time.Hour), x509.ExtKeyUsageClientAuth)
+	certFile, keyFile = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	return certFile, keyFile
+}
+
+func TestReportClient_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	server := &mtlsServer{}
+	addr := startMTLSServer(t, ca, server)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+	loaded := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeClientCert(t, ca, dir, "agent-1", loaded)
+
+	// The certificate replaces the API key
+	client := NewReportClient(addr, "", createTestLogger(t))
+	require.NoError(t, client.SetClientTLS(certFile, keyFile, caFile))
+	defer client.Close()
+	assert.WithinDuration(t, time.Now().Add(time.Hour), client.ClientCertificateExpiry(), time.Minute)
+
+	require.NoError(t, client.SendOnlineUsersReport("test-uuid", []string{"user1"}))
+	require.NoError(t, client.SendOnlineUsersReport("test-uuid", []string{"user1"}))
+
+	// A renewed certificate is presented on a new connection without restarting
+	writeClientCert(t, ca, dir, "agent-2", loaded.Add(time.Second))
+	require.NoError(t, client.SendOnlineUsersReport("test-uuid", []string{"user1"}))
+
+	assert.Equal(t, []string{"agent-1", "agent-1", "agent-2"}, server.clientNames)
+	assert.Empty(t, server.apiKeys, "no authorization metadata without an API key")
+}
+
+func TestReportClient_MutualTLSRequiresCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	addr := startMTLSServer(t, ca, &mtlsServer{})
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+
+	// The CA alone verifies xhub, but xhub rejects the agent without a certificate
+	client := NewReportClient(addr, "test-key", createTestLogger(t))
+	require.NoError(t, client.SetClientTLS("", "", caFile))
+	defer client.Close()
+	assert.Error(t, client.SendOnlineUsersReport("test-uuid", []string{"user1"}))
+}
+
+func TestReportClient_SetClientTLSErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0600))
+	client := NewReportClient("localhost:9090", "test-key", createTestLogger(t))
+
+	assert.Error(t, client.SetClientTLS("", "", filepath.Join(dir, "missing.pem")))
+	assert.Error(t, client.SetClientTLS("", "", notPEM))
+	assert.Error(t, client.SetClientTLS(filepath.Join(dir, "missing.pem"), filepath.Join(dir, "missing.key"), ""))
+	assert.Error(t, client.SetClientTLS(notPEM, notPEM, ""))
+	assert.Nil(t, client.clientTLS)
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/netprobe"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// ErrNetworkQualityUnsupported is returned when xhub does not implement network quality reports
+var ErrNetworkQualityUnsupported = errors.New("xhub does not support network quality reports")
+
+// SendNetworkQualityReport reports the outcome of a network probe run to xhub. It returns
+// ErrNetworkQualityUnsupported if xhub does not implement the RPC.
+func (r *ReportClient) SendNetworkQualityReport(uuid string, result netprobe.Report) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.NetworkQualityReport{
+		Uuid:       uuid,
+		Trigger:    result.Trigger,
+		CommandId:  result.CommandID,
+		StartedAt:  result.StartedAt.Unix(),
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	for _, target := range result.Targets {
+		req.Probes = append(req.Probes, &pb.LatencyProbe{
+			Target:      target.Target,
+			Sent:        int32(target.Sent),
+			Received:    int32(target.Received),
+			LossPercent: target.LossPercent,
+			MinMs:       target.MinMs,
+			AvgMs:       target.AvgMs,
+			MaxMs:       target.MaxMs,
+			JitterMs:    target.JitterMs,
+			Error:       target.Error,
+		})
+	}
+	if result.Throughput != nil {
+		req.Throughput = &pb.ThroughputProbe{
+			Url:           result.Throughput.URL,
+			Bytes:         result.Throughput.Bytes,
+			DurationMs:    result.Throughput.Duration.Milliseconds(),
+			BitsPerSecond: result.Throughput.BitsPerSecond,
+			Error:         result.Throughput.Error,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.reportTimeout())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.SendNetworkQualityReport(ctx, req, r.callOptions()...)
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return ErrNetworkQualityUnsupported
+		}
+		return r.withConnectionHint(fmt.Errorf("gRPC network quality report failed: %w", err))
+	}
+	if !resp.Success {
+		return rejectedf("network quality report rejected: %s", resp.Message)
+	}
+	r.markSuccess("网络质量上报")
+	return nil
+}
@@ -0,0 +1,202 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"xhub-agent/internal/privacy"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// replayKey marks the context of a replayed request
+type replayKey struct{}
+
+// offlineQueue holds the reports that failed while xhub was unreachable (offline_queue_max_mb)
+type offlineQueue struct {
+	queue *Queue
+	mutex sync.Mutex // Serializes flushes
+	full  bool       // Dropping over the size limit was logged for the current outage
+}
+
+// SetOfflineQueue queues the reports that fail while xhub is unreachable and sends them,
+// in order, before the next report once it is reachable again (nil disables queueing)
+func (r *ReportClient) SetOfflineQueue(queue *Queue) {
+	r.offline.queue = queue
+}
+
+// QueuedReports returns the number of reports waiting in the offline queue
+func (r *ReportClient) QueuedReports() int {
+	if r.offline.queue == nil {
+		return 0
+	}
+	return r.offline.queue.Len()
+}
+
+// QueueStats returns the state of the offline queue, nil when queueing is disabled
+func (r *ReportClient) QueueStats() *QueueStats {
+	if r.offline.queue == nil {
+		return nil
+	}
+	stats := r.offline.queue.Stats()
+	return &stats
+}
+
+// isOutage reports whether err means the request did not reach xhub
+func isOutage(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// queueOffline is a unary interceptor sending the queued reports before each report and
+// queueing the reports that fail to reach xhub. It runs after mapEmails, so queued requests
+// carry mapped emails and replays skip the mapping.
+func (r *ReportClient) queueOffline(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	msg, ok := req.(proto.Message)
+	if r.offline.queue == nil || ctx.Value(replayKey{}) != nil || !ok {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if method == pb.ReportService_SendShutdownNotice_FullMethodName {
+		// Sent after the shutdown drain flushed the queue, reporting what is left in it
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	err := r.flushQueue(context.Background(), cc)
+	if err == nil {
+		err = invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if isOutage(err) {
+		r.enqueue(method, msg)
+	}
+	return err
+}
+
+// FlushQueue sends the queued reports now, within ctx. It returns the error of the first
+// report that does not reach xhub.
+func (r *ReportClient) FlushQueue(ctx context.Context) error {
+	if r.offline.queue == nil {
+		return nil
+	}
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+	return r.flushQueue(ctx, r.conn)
+}
+
+// flushQueue sends the queued reports in order, each within ctx and the request timeout. It
+// stops at the first one that does not reach xhub and returns its error; reports rejected by
+// xhub are dropped.
+func (r *ReportClient) flushQueue(ctx context.Context, cc *grpc.ClientConn) error {
+	r.offline.mutex.Lock()
+	defer r.offline.mutex.Unlock()
+
+	sent := 0
+	for {
+		queued := r.offline.queue.Peek()
+		if queued == nil {
+			break
+		}
+		err := r.replay(ctx, cc, queued)
+		if isOutage(err) {
+			if sent > 0 {
+				r.logger.Infof("📤 Sent %d queued reports, %d still queued", sent, r.offline.queue.Len())
+			}
+			return err
+		}
+		if err != nil {
+			r.logger.Warnf("⚠️  Dropping queued %s from %s: %v", path.Base(queued.Method), queued.QueuedAt.Format(time.RFC3339), err)
+		} else {
+			sent++
+		}
+		r.offline.queue.Remove(queued)
+	}
+
+	if sent > 0 {
+		r.logger.Infof("📤 Sent %d queued reports", sent)
+	}
+	r.offline.full = false
+	return nil
+}
+
+// replay sends a queued report with its queue time in the x-agent-queued-at metadata
+func (r *ReportClient) replay(ctx context.Context, cc *grpc.ClientConn, queued *QueuedReport) error {
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, replayKey{}, true), r.methodTimeout(queued.Method))
+	defer cancel()
+	md := r.outgoingMetadata()
+	md.Set("x-agent-queued-at", strconv.FormatInt(queued.QueuedAt.Unix(), 10))
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp := &pb.ReportResponse{}
+	if err := cc.Invoke(ctx, queued.Method, queued.Request, resp, r.callOptions()...); err != nil {
+		return err
+	}
+	if !resp.Success {
+		return rejectedf("rejected by xhub: %s", resp.Message)
+	}
+	return nil
+}
+
+// enqueue queues a report that did not reach xhub. Status reports are queued without their
+// error counts (they stay pending for the next live report), as are status batches; combined
+// reports are queued as their parts.
+func (r *ReportClient) enqueue(method string, req proto.Message) {
+	now := time.Now()
+	push := func(method string, req proto.Message) {
+		if err := r.offline.queue.Push(method, req, now); err != nil {
+			r.logger.Warnf("⚠️  Failed to queue %s: %v", path.Base(method), err)
+		}
+	}
+
+	switch typed := req.(type) {
+	case *pb.ReportRequest:
+		push(method, &pb.ReportRequest{Uuid: typed.Uuid, Data: typed.Data, Agent: typed.Agent})
+	case *pb.StatusBatchRequest:
+		push(method, &pb.StatusBatchRequest{Uuid: typed.Uuid, Samples: typed.Samples, Agent: typed.Agent})
+	case *pb.CombinedReportRequest:
+		push(pb.ReportService_SendReport_FullMethodName, &pb.ReportRequest{Uuid: typed.Uuid, Data: typed.Data, Agent: typed.Agent})
+		if typed.Subscriptions != nil {
+			push(pb.ReportService_SendSubscriptionReport_FullMethodName, typed.Subscriptions)
+		}
+		if typed.OnlineUsers != nil {
+			push(pb.ReportService_SendOnlineUsersReport_FullMethodName, typed.OnlineUsers)
+		}
+	default:
+		if _, ok := queuedMethods[method]; !ok {
+			return
+		}
+		push(method, req)
+	}
+
+	r.logger.Debugf("📥 xhub unreachable, queued %s (%d queued)", path.Base(method), r.offline.queue.Len())
+	if dropped := r.offline.queue.Dropped(); dropped > 0 && !r.offline.full {
+		r.offline.full = true
+		r.logger.Warnf("⚠️  Offline report queue is full, dropping the oldest queued reports")
+	}
+}
+
+// enqueueChunks queues the unsent chunks of a split subscription report after a chunk did not
+// reach xhub, so that the queue holds the rest of the batch. They did not go through mapEmails.
+func (r *ReportClient) enqueueChunks(chunks []*pb.SubscriptionReportRequest) {
+	if r.offline.queue == nil {
+		return
+	}
+	for _, chunk := range chunks {
+		if r.emails != nil {
+			privacy.MapMessageEmails(chunk, r.emails)
+		}
+		r.enqueue(pb.ReportService_SendSubscriptionReport_FullMethodName, chunk)
+	}
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// CertificatePin is the SHA-256 fingerprint of a DER certificate (grpc_cert_pin_sha256)
+type CertificatePin [sha256.Size]byte
+
+// ParseCertificatePins parses comma-separated hex SHA-256 certificate fingerprints, with or
+// without colons (openssl x509 -noout -fingerprint -sha256). Empty means no pinning.
+func ParseCertificatePins(value string) ([]CertificatePin, error) {
+	var pins []CertificatePin
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		digest, err := hex.DecodeString(strings.ReplaceAll(entry, ":", ""))
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("invalid grpc_cert_pin_sha256 entry %q (expected 64 hex digits)", entry)
+		}
+		pins = append(pins, CertificatePin(digest))
+	}
+	return pins, nil
+}
+
+// SetCertificatePins accepts xhub only if its certificate has one of the fingerprints. With a
+// CA file (grpc_ca) the pin may also name a CA of the verified chain, to pin a whole issuer.
+// Without one the pin replaces the verification against the system roots, so that a
+// self-signed xhub certificate can be pinned, and only the server certificate is matched.
+// It forces TLS and applies to both transports.
+func (r *ReportClient) SetCertificatePins(pins []CertificatePin) {
+	r.Close()
+	if r.clientTLS == nil {
+		r.clientTLS = &clientTLS{}
+	}
+	r.clientTLS.pins = pins
+	r.useTLS = true
+}
+
+// CertificatePins returns the number of pinned certificate fingerprints
+func (r *ReportClient) CertificatePins() int {
+	if r.clientTLS == nil {
+		return 0
+	}
+	return len(r.clientTLS.pins)
+}
+
+// verifyPins is the VerifyPeerCertificate check of pinned connections. Unverified extra
+// certificates xhub presents prove nothing, anyone can append a public certificate: without
+// grpc_ca only the server certificate is matched, with it only the verified chains are.
+func (c *clientTLS) verifyPins(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	var candidates [][]byte
+	if c.roots == nil {
+		candidates = rawCerts[:min(len(rawCerts), 1)]
+	}
+	for _, chain := range verifiedChains {
+		for _, cert := range chain {
+			candidates = append(candidates, cert.Raw)
+		}
+	}
+	for _, raw := range candidates {
+		digest := sha256.Sum256(raw)
+		for _, pin := range c.pins {
+			if subtle.ConstantTimeCompare(digest[:], pin[:]) == 1 {
+				return nil
+			}
+		}
+	}
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("xhub presented no certificate")
+	}
+	leaf := sha256.Sum256(rawCerts[0])
+	return fmt.Errorf("xhub certificate %s matches no grpc_cert_pin_sha256", hex.EncodeToString(leaf[:]))
+}
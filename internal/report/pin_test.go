@@ -0,0 +1,154 @@
+package report
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+func TestParseCertificatePins(t *testing.T) {
+	digest := sha256.Sum256([]byte("certificate"))
+	lower := hex.EncodeToString(digest[:])
+	var colons []string
+	for i := 0; i < len(lower); i += 2 {
+		colons = append(colons, strings.ToUpper(lower[i:i+2]))
+	}
+
+	pins, err := ParseCertificatePins(lower + ", " + strings.Join(colons, ":"))
+	require.NoError(t, err)
+	assert.Equal(t, []CertificatePin{digest, digest}, pins)
+
+	pins, err = ParseCertificatePins("")
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+
+	_, err = ParseCertificatePins("abcd")
+	assert.ErrorContains(t, err, `invalid grpc_cert_pin_sha256 entry "abcd"`)
+}
+
+// startTLSServer serves a report server over TLS with a certificate issued by ca and returns
+// its address and certificate
+func startTLSServer(t *testing.T, ca *testCA) (string, *x509.Certificate) {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "xhub", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth)
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{serverCert}})))
+	pb.RegisterReportServiceServer(s, &mockReportServer{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String(), serverCert.Leaf
+}
+
+// startChainServer serves a report server presenting the leaf certificate issued by ca,
+// followed by the extra certificates, and returns its address
+func startChainServer(t *testing.T, ca *testCA, extra ...*x509.Certificate) string {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "xhub", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth)
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	for _, cert := range extra {
+		serverCert.Certificate = append(serverCert.Certificate, cert.Raw)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{serverCert}})))
+	pb.RegisterReportServiceServer(s, &mockReportServer{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func TestReportClient_CertificatePin(t *testing.T) {
+	ca := newTestCA(t)
+	addr, serverCert := startTLSServer(t, ca)
+	pinned := CertificatePin(sha256.Sum256(serverCert.Raw))
+	other := CertificatePin(sha256.Sum256([]byte("another certificate")))
+
+	// Without a pin the private CA is not trusted
+	client := NewReportClient(addr, "test-key", createTestLogger(t))
+	client.SetTLS(true)
+	defer client.Close()
+	require.Error(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+
+	// The pinned certificate is accepted instead of the system roots
+	client.SetCertificatePins([]CertificatePin{other, pinned})
+	assert.Equal(t, 2, client.CertificatePins())
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+
+	// Any other certificate is rejected
+	client.SetCertificatePins([]CertificatePin{other})
+	err := client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matches no grpc_cert_pin_sha256")
+}
+
+func TestReportClient_CertificatePinWithCA(t *testing.T) {
+	ca := newTestCA(t)
+	addr, _ := startTLSServer(t, ca)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+
+	// With grpc_ca the chain is verified and the pin may name the CA
+	client := NewReportClient(addr, "test-key", createTestLogger(t))
+	defer client.Close()
+	client.SetCertificatePins([]CertificatePin{sha256.Sum256(ca.cert.Raw)})
+	require.NoError(t, client.SetClientTLS("", "", caFile))
+	assert.Equal(t, 1, client.CertificatePins(), "the pins survive SetClientTLS")
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+
+	// The CA does not make up for a wrong pin
+	client.SetCertificatePins([]CertificatePin{sha256.Sum256([]byte("another certificate"))})
+	require.Error(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+}
+
+func TestReportClient_CertificatePinAppendedCertificate(t *testing.T) {
+	_, serverCert := startTLSServer(t, newTestCA(t))
+	pinned := CertificatePin(sha256.Sum256(serverCert.Raw))
+
+	// An attacker leaf followed by the public pinned certificate is not xhub
+	addr := startChainServer(t, newTestCA(t), serverCert)
+	client := NewReportClient(addr, "test-key", createTestLogger(t))
+	defer client.Close()
+	client.SetCertificatePins([]CertificatePin{pinned})
+	err := client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matches no grpc_cert_pin_sha256")
+}
+
+func TestReportClient_CertificatePinWithCAAppendedCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	_, serverCert := startTLSServer(t, ca)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+
+	// Another leaf of the CA passes the chain verification, but the appended pinned
+	// certificate is not part of its chain
+	addr := startChainServer(t, ca, serverCert)
+	client := NewReportClient(addr, "test-key", createTestLogger(t))
+	defer client.Close()
+	client.SetCertificatePins([]CertificatePin{sha256.Sum256(serverCert.Raw)})
+	require.NoError(t, client.SetClientTLS("", "", caFile))
+	err := client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matches no grpc_cert_pin_sha256")
+}
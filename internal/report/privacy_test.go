@@ -0,0 +1,133 @@
+package report
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/privacy"
+	"xhub-agent/internal/state"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// plantedEmail must never reach the server in the hashed and pseudonym modes
+const plantedEmail = "planted.user@example.com"
+
+// recordingReportServer implements every RPC and keeps the raw requests
+type recordingReportServer struct {
+	pb.UnimplementedReportServiceServer
+	mutex    sync.Mutex
+	requests []proto.Message
+}
+
+func (s *recordingReportServer) record(req proto.Message) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *recordingReportServer) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	return s.record(req)
+}
+
+func (s *recordingReportServer) SendSubscriptionReport(ctx context.Context, req *pb.SubscriptionReportRequest) (*pb.ReportResponse, error) {
+	return s.record(req)
+}
+
+func (s *recordingReportServer) SendOnlineUsersReport(ctx context.Context, req *pb.OnlineUsersReportRequest) (*pb.ReportResponse, error) {
+	return s.record(req)
+}
+
+// setupRecordingServer starts a gRPC server backed by recorder
+func setupRecordingServer(t *testing.T, recorder *recordingReportServer) (string, func()) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, recorder)
+	go s.Serve(lis)
+	return lis.Addr().String(), s.Stop
+}
+
+func TestReportClient_EmailPrivacy_NoLeak(t *testing.T) {
+	hasher, err := privacy.NewHasher("test-key")
+	require.NoError(t, err)
+
+	mappers := map[string]privacy.EmailMapper{
+		"hashed":    hasher,
+		"pseudonym": privacy.NewPseudonymizer(state.NewMemoryStore(), createTestLogger(t)),
+	}
+	for name, mapper := range mappers {
+		t.Run(name, func(t *testing.T) {
+			recorder := &recordingReportServer{}
+			addr, cleanup := setupRecordingServer(t, recorder)
+			defer cleanup()
+
+			client := NewReportClient(addr, "test-key", createTestLogger(t))
+			defer client.Close()
+			client.SetEmailMapper(mapper)
+
+			subs := []SubscriptionData{
+				{SubID: "sub-1", Email: plantedEmail, NodeConfig: "bm9kZQ=="},
+				{SubID: "sub-2", Email: "other@example.com", NodeConfig: "bm9kZQ=="},
+			}
+			require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 1}))
+			require.NoError(t, client.SendSubscriptionReport("test-uuid", subs))
+			require.NoError(t, client.SendOnlineUsers("test-uuid", &CombinedOnlineUsers{
+				Emails: []string{plantedEmail, "other@example.com"},
+				Users:  []monitor.OnlineUser{{Email: plantedEmail, IPs: []monitor.ClientIP{{IP: "203.0.113.7"}}}},
+			}))
+
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			require.Len(t, recorder.requests, 3)
+			for _, req := range recorder.requests {
+				raw, err := proto.Marshal(req)
+				require.NoError(t, err)
+				assert.NotContains(t, string(raw), plantedEmail)
+				assert.NotContains(t, protojson.Format(req), plantedEmail)
+			}
+
+			// The identifiers stay stable across RPC types so xhub can correlate them
+			subReq := recorder.requests[1].(*pb.SubscriptionReportRequest)
+			onlineReq := recorder.requests[2].(*pb.OnlineUsersReportRequest)
+			assert.Equal(t, mapper.MapEmail(plantedEmail), subReq.Subscriptions[0].Email)
+			assert.Equal(t, subReq.Subscriptions[0].Email, onlineReq.OnlineEmails[0])
+			assert.NotEqual(t, onlineReq.OnlineEmails[0], onlineReq.OnlineEmails[1])
+			assert.Equal(t, onlineReq.OnlineEmails[0], onlineReq.Users[0].Email, "connection details use the same identifier")
+		})
+	}
+}
+
+func TestReportClient_EmailPrivacy_CapturedFailures(t *testing.T) {
+	hasher, err := privacy.NewHasher("test-key")
+	require.NoError(t, err)
+
+	// The mock server does not implement the subscription and online users RPCs
+	addr, cleanup := setupGRPCTestServer(t, &mockReportServer{})
+	defer cleanup()
+
+	client := NewReportClient(addr, "test-key", createTestLogger(t))
+	defer client.Close()
+	client.SetEmailMapper(hasher)
+
+	require.Error(t, client.SendSubscriptionReport("test-uuid", []SubscriptionData{{SubID: "sub-1", Email: plantedEmail}}))
+	require.Error(t, client.SendOnlineUsersReport("test-uuid", []string{plantedEmail}))
+
+	failures := client.LastFailures()
+	require.Len(t, failures, 2)
+	for _, failure := range failures {
+		assert.False(t, strings.Contains(failure.Request, plantedEmail), failure.Method)
+		assert.Contains(t, failure.Request, hasher.MapEmail(plantedEmail), failure.Method)
+	}
+}
@@ -0,0 +1,30 @@
+package report
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+// CheckConnection establishes the gRPC connection to xhub, including the TLS handshake, and
+// waits until it is ready or ctx is done. No RPC is sent, so the API key is not checked.
+func (r *ReportClient) CheckConnection(ctx context.Context) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	r.conn.Connect()
+	for {
+		state := r.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Shutdown:
+			return fmt.Errorf("gRPC connection to %s is shut down", r.serverAddr)
+		}
+		if !r.conn.WaitForStateChange(ctx, state) {
+			return r.withConnectionHint(fmt.Errorf("gRPC connection to %s not ready (%s): %w", r.serverAddr, state, ctx.Err()))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+
+	"xhub-agent/internal/provision"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// ProvisioningStream receives the client changes xhub pushes to the agent (provisioning_channel)
+type ProvisioningStream struct {
+	stream pb.ReportService_SubscribeProvisioningClient
+}
+
+// OpenProvisioningStream subscribes to the provisioning requests of this agent. The stream
+// ends when ctx is done or the connection breaks; an xhub without the RPC answers
+// codes.Unimplemented on the first Recv.
+func (r *ReportClient) OpenProvisioningStream(ctx context.Context, uuid string) (*ProvisioningStream, error) {
+	if err := r.Connect(); err != nil {
+		return nil, r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+	stream, err := r.client.SubscribeProvisioning(ctx, &pb.ProvisioningSubscription{Uuid: uuid}, r.callOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	return &ProvisioningStream{stream: stream}, nil
+}
+
+// Recv waits for the next provisioning request
+func (s *ProvisioningStream) Recv() (provision.Request, error) {
+	req, err := s.stream.Recv()
+	if err != nil {
+		return provision.Request{}, err
+	}
+	return provision.Request{
+		Key:       req.IdempotencyKey,
+		Action:    req.Action,
+		InboundID: int(req.InboundId),
+		ClientID:  req.ClientId,
+		Client:    req.ClientJson,
+	}, nil
+}
+
+// SendProvisioningResult reports the outcome of a provisioning request to xhub
+func (r *ReportClient) SendProvisioningResult(uuid string, result provision.Result) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.ProvisioningResult{
+		Uuid:           uuid,
+		IdempotencyKey: result.Key,
+		Action:         result.Action,
+		Success:        result.Err == "",
+		Error:          result.Err,
+		Replayed:       result.Replayed,
+		DurationMs:     result.Duration.Milliseconds(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.reportTimeout())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.SendProvisioningResult(ctx, req, r.callOptions()...)
+	if err != nil {
+		if r.shouldLogError(fmt.Sprintf("provisioning_result_%s", r.serverAddr)) {
+			r.logger.Errorf("❌ gRPC provisioning result request failed: %v", err)
+		}
+		return r.withConnectionHint(fmt.Errorf("gRPC provisioning result request failed: %w", err))
+	}
+	if !resp.Success {
+		return rejectedf("provisioning result rejected: %s", resp.Message)
+	}
+	r.markSuccess("开通结果上报")
+	return nil
+}
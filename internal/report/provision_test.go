@@ -0,0 +1,70 @@
+package report
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/provision"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// provisioningServer streams its requests to each subscriber and records the results
+type provisioningServer struct {
+	pb.UnimplementedReportServiceServer
+	requests []*pb.ProvisioningRequest
+
+	mutex   sync.Mutex
+	results []*pb.ProvisioningResult
+}
+
+func (s *provisioningServer) SubscribeProvisioning(req *pb.ProvisioningSubscription, stream pb.ReportService_SubscribeProvisioningServer) error {
+	for _, request := range s.requests {
+		if err := stream.Send(request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *provisioningServer) SendProvisioningResult(ctx context.Context, req *pb.ProvisioningResult) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.results = append(s.results, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestReportClient_ProvisioningStream(t *testing.T) {
+	server := &provisioningServer{requests: []*pb.ProvisioningRequest{
+		{IdempotencyKey: "k1", Action: provision.AddClient, InboundId: 3, ClientJson: []byte(`{"email": "alice"}`)},
+		{IdempotencyKey: "k2", Action: provision.RemoveClient, InboundId: 3, ClientId: "alice-uuid"},
+	}}
+	client := newStreamClient(t, server)
+
+	stream, err := client.OpenProvisioningStream(context.Background(), "test-uuid")
+	require.NoError(t, err)
+	req, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, provision.Request{Key: "k1", Action: provision.AddClient, InboundID: 3, Client: []byte(`{"email": "alice"}`)}, req)
+	req, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "alice-uuid", req.ClientID)
+	_, err = stream.Recv()
+	assert.Error(t, err, "the stream ends with the server handler")
+
+	require.NoError(t, client.SendProvisioningResult("test-uuid", provision.Result{Key: "k1", Action: provision.AddClient, Duration: 250 * time.Millisecond}))
+	require.NoError(t, client.SendProvisioningResult("test-uuid", provision.Result{Key: "k1", Action: provision.AddClient, Replayed: true}))
+	require.NoError(t, client.SendProvisioningResult("test-uuid", provision.Result{Key: "k2", Action: provision.RemoveClient, Err: "client not found"}))
+
+	require.Len(t, server.results, 3)
+	assert.Equal(t, "test-uuid", server.results[0].Uuid)
+	assert.True(t, server.results[0].Success)
+	assert.Equal(t, int64(250), server.results[0].DurationMs)
+	assert.True(t, server.results[1].Replayed)
+	assert.False(t, server.results[2].Success)
+	assert.Equal(t, "client not found", server.results[2].Error)
+}
@@ -0,0 +1,189 @@
+package report
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyDirect is the grpc_proxy value connecting without proxy, even if one is set in the
+// environment
+const ProxyDirect = "direct"
+
+// Proxy selects how the gRPC connection reaches xhub (grpc_proxy). The zero value uses the
+// proxy of the environment (HTTPS_PROXY, NO_PROXY).
+type Proxy struct {
+	URL    *url.URL // Proxy tunneling the connection, nil for the environment or Direct
+	Direct bool     // Ignore the environment and connect directly
+}
+
+// ParseProxy parses a grpc_proxy value: empty uses the environment, "direct" disables
+// proxying, otherwise an http:// (HTTP CONNECT) or socks5:// URL, optionally with
+// user:password@ for proxy authentication
+func ParseProxy(value string) (Proxy, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "":
+		return Proxy{}, nil
+	case ProxyDirect:
+		return Proxy{Direct: true}, nil
+	}
+	proxyURL, err := url.Parse(value)
+	if err != nil {
+		return Proxy{}, fmt.Errorf("invalid grpc_proxy: %w", err)
+	}
+	if err := checkProxyURL(proxyURL); err != nil {
+		return Proxy{}, fmt.Errorf("invalid grpc_proxy %q: %w", proxyURL.Redacted(), err)
+	}
+	return Proxy{URL: proxyURL}, nil
+}
+
+// checkProxyURL checks that the proxy scheme is supported and the host is set
+func checkProxyURL(proxyURL *url.URL) error {
+	switch proxyURL.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q (expected http, socks5 or socks5h)", proxyURL.Scheme)
+	}
+	if proxyURL.Hostname() == "" {
+		return fmt.Errorf("missing proxy host")
+	}
+	return nil
+}
+
+// SetProxy sets the proxy the connection to the server goes through. It takes effect on the
+// next connection; unix targets are never proxied.
+func (r *ReportClient) SetProxy(p Proxy) {
+	r.proxy = p
+}
+
+// proxyURL returns the proxy to tunnel the connection to the server through, nil to
+// connect directly
+func (r *ReportClient) proxyURL() (*url.URL, error) {
+	if r.proxy.Direct || hasResolverScheme(r.serverAddr) {
+		return nil, nil
+	}
+	if r.proxy.URL != nil {
+		return r.proxy.URL, nil
+	}
+	proxyURL, err := proxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: r.serverAddr}})
+	if err != nil || proxyURL == nil {
+		return nil, err
+	}
+	if err := checkProxyURL(proxyURL); err != nil {
+		return nil, fmt.Errorf("HTTPS_PROXY %q: %w", proxyURL.Redacted(), err)
+	}
+	return proxyURL, nil
+}
+
+// proxyDialer is the gRPC ContextDialer tunneling connections through an HTTP CONNECT or
+// SOCKS5 proxy. The target host is resolved by the proxy.
+type proxyDialer struct {
+	proxy *url.URL
+	dial  func(ctx context.Context, addr string) (net.Conn, error) // Connects to the proxy
+}
+
+// newProxyDialer creates a dialer tunneling through proxyURL, reaching the proxy with dial
+func newProxyDialer(proxyURL *url.URL, dial func(ctx context.Context, addr string) (net.Conn, error)) *proxyDialer {
+	return &proxyDialer{proxy: proxyURL, dial: dial}
+}
+
+// DialContext connects to addr ("host:port") through the proxy
+func (d *proxyDialer) DialContext(ctx context.Context, addr string) (net.Conn, error) {
+	if d.proxy.Scheme == "http" {
+		return d.dialHTTP(ctx, addr)
+	}
+
+	var auth *proxy.Auth
+	if user := d.proxy.User; user != nil {
+		password, _ := user.Password()
+		auth = &proxy.Auth{User: user.Username(), Password: password}
+	}
+	socks, err := proxy.SOCKS5("tcp", d.proxyAddr("1080"), auth, forwardDialer(d.dial))
+	if err != nil {
+		return nil, err
+	}
+	conn, err := socks.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("SOCKS5 proxy %s: %w", d.proxy.Host, err)
+	}
+	return conn, nil
+}
+
+// dialHTTP opens a tunnel to addr with an HTTP CONNECT request
+func (d *proxyDialer) dialHTTP(ctx context.Context, addr string) (net.Conn, error) {
+	conn, err := d.dial(ctx, d.proxyAddr("80"))
+	if err != nil {
+		return nil, fmt.Errorf("HTTP proxy %s: %w", d.proxy.Host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Host: addr}, Host: addr, Header: make(http.Header)}
+	if user := d.proxy.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("HTTP proxy %s: %w", d.proxy.Host, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("HTTP proxy %s: %w", d.proxy.Host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("HTTP proxy %s refused the tunnel: %s", d.proxy.Host, resp.Status)
+	}
+
+	// Bytes the server sent right after the tunnel opened are already buffered
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// proxyAddr returns the proxy host:port, with defaultPort when the URL has none
+func (d *proxyDialer) proxyAddr(defaultPort string) string {
+	if port := d.proxy.Port(); port != "" {
+		return d.proxy.Host
+	}
+	return net.JoinHostPort(d.proxy.Hostname(), defaultPort)
+}
+
+// forwardDialer adapts the connection to the proxy to golang.org/x/net/proxy
+type forwardDialer func(ctx context.Context, addr string) (net.Conn, error)
+
+func (f forwardDialer) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), addr)
+}
+
+func (f forwardDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, addr)
+}
+
+// bufferedConn reads through the reader that consumed the proxy response
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
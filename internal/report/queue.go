@@ -0,0 +1,249 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// queueFilePrefix and queueFileSuffix frame the queue file names:
+// q-<sequence>-<queued at, unix seconds>-<method>.pb
+const (
+	queueFilePrefix = "q-"
+	queueFileSuffix = ".pb"
+)
+
+// queuedMethods are the RPCs that can be queued, with their request types
+var queuedMethods = map[string]func() proto.Message{
+	pb.ReportService_SendReport_FullMethodName:             func() proto.Message { return &pb.ReportRequest{} },
+	pb.ReportService_SendSubscriptionReport_FullMethodName: func() proto.Message { return &pb.SubscriptionReportRequest{} },
+	pb.ReportService_SendOnlineUsersReport_FullMethodName:  func() proto.Message { return &pb.OnlineUsersReportRequest{} },
+	pb.ReportService_SendStatusBatch_FullMethodName:        func() proto.Message { return &pb.StatusBatchRequest{} },
+}
+
+// QueuedReport is a report request waiting in the offline queue
+type QueuedReport struct {
+	Method   string // Full gRPC method name
+	QueuedAt time.Time
+	Request  proto.Message
+
+	name string // File name under the queue directory
+	size int64
+}
+
+// QueueStats describes the requests in the offline queue
+type QueueStats struct {
+	Queued   int            `json:"queued"`
+	Bytes    int64          `json:"bytes"`
+	Dropped  uint64         `json:"dropped"`             // Dropped over the size limit since the queue was last empty
+	OldestAt time.Time      `json:"oldest_at,omitzero"`  // Queue time of the oldest request
+	ByMethod map[string]int `json:"by_method,omitempty"` // Queued requests per RPC name, e.g. "SendReport"
+}
+
+// Queue is a bounded on-disk queue of report requests that could not be delivered while
+// xhub was unreachable. Every request is one file, written atomically, so the queue survives
+// restarts and a crash loses at most the request being written.
+type Queue struct {
+	dir      string
+	maxBytes int64
+
+	mutex   sync.Mutex
+	entries []*QueuedReport // Oldest first, Request is loaded on demand
+	size    int64
+	next    uint64 // Sequence number of the next entry
+	dropped uint64 // Entries dropped over the size limit since the queue was last empty
+}
+
+// OpenQueue opens (or creates) the queue in dir, keeping at most maxBytes of requests.
+// Leftover temporary and unrecognized files are removed.
+func OpenQueue(dir string, maxBytes int64) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create report queue directory: %w", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report queue directory: %w", err)
+	}
+
+	q := &Queue{dir: dir, maxBytes: maxBytes}
+	for _, file := range files {
+		entry, seq, ok := parseQueueFile(file.Name())
+		info, err := file.Info()
+		if !ok || err != nil || !info.Mode().IsRegular() {
+			os.Remove(filepath.Join(dir, file.Name()))
+			continue
+		}
+		entry.size = info.Size()
+		q.entries = append(q.entries, entry)
+		q.size += entry.size
+		if seq >= q.next {
+			q.next = seq + 1
+		}
+	}
+	sort.Slice(q.entries, func(i, j int) bool { return q.entries[i].name < q.entries[j].name })
+	q.trim()
+	return q, nil
+}
+
+// parseQueueFile parses a queue file name, returning the entry and its sequence number
+func parseQueueFile(name string) (*QueuedReport, uint64, bool) {
+	if !strings.HasPrefix(name, queueFilePrefix) || !strings.HasSuffix(name, queueFileSuffix) {
+		return nil, 0, false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(name, queueFilePrefix), queueFileSuffix), "-", 3)
+	if len(parts) != 3 {
+		return nil, 0, false
+	}
+	seq, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, 0, false
+	}
+	queuedAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, 0, false
+	}
+	method := "/reportpb.ReportService/" + parts[2]
+	if _, ok := queuedMethods[method]; !ok {
+		return nil, 0, false
+	}
+	return &QueuedReport{Method: method, QueuedAt: time.Unix(queuedAt, 0), name: name}, seq, true
+}
+
+// Push appends req to the queue. A subscription report replaces the queued ones (each is a
+// full snapshot, the following chunks of a split one join its first chunk), and the oldest
+// requests are dropped beyond the size limit.
+func (q *Queue) Push(method string, req proto.Message, queuedAt time.Time) error {
+	if _, ok := queuedMethods[method]; !ok {
+		return fmt.Errorf("method %s cannot be queued", method)
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode queued request: %w", err)
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	name := fmt.Sprintf("%s%020d-%d-%s%s", queueFilePrefix, q.next, queuedAt.Unix(), filepath.Base(method), queueFileSuffix)
+	tmp := filepath.Join(q.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write queued request: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(q.dir, name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write queued request: %w", err)
+	}
+	q.next++
+
+	if subscriptions, ok := req.(*pb.SubscriptionReportRequest); ok && subscriptions.ChunkIndex == 0 {
+		q.removeWhere(func(entry *QueuedReport) bool { return entry.Method == method })
+	}
+	q.entries = append(q.entries, &QueuedReport{Method: method, QueuedAt: queuedAt, name: name, size: int64(len(data))})
+	q.size += int64(len(data))
+	q.trim()
+	return nil
+}
+
+// trim drops the oldest entries while the queue is over its size limit (keeping the newest)
+func (q *Queue) trim() {
+	for q.size > q.maxBytes && len(q.entries) > 1 {
+		q.removeLocked(q.entries[0])
+		q.dropped++
+	}
+}
+
+// removeWhere removes the entries matching match
+func (q *Queue) removeWhere(match func(*QueuedReport) bool) {
+	for _, entry := range append([]*QueuedReport(nil), q.entries...) {
+		if match(entry) {
+			q.removeLocked(entry)
+		}
+	}
+}
+
+// Peek returns the oldest queued request with its decoded payload, nil when the queue is
+// empty. Undecodable entries are removed and skipped.
+func (q *Queue) Peek() *QueuedReport {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.entries) > 0 {
+		entry := q.entries[0]
+		data, err := os.ReadFile(filepath.Join(q.dir, entry.name))
+		if err == nil {
+			req := queuedMethods[entry.Method]()
+			if err = proto.Unmarshal(data, req); err == nil {
+				return &QueuedReport{Method: entry.Method, QueuedAt: entry.QueuedAt, Request: req, name: entry.name, size: entry.size}
+			}
+		}
+		q.removeLocked(entry)
+	}
+	return nil
+}
+
+// Remove removes a delivered (or rejected) request returned by Peek
+func (q *Queue) Remove(report *QueuedReport) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, entry := range q.entries {
+		if entry.name == report.name {
+			q.removeLocked(entry)
+			break
+		}
+	}
+	if len(q.entries) == 0 {
+		q.dropped = 0
+	}
+}
+
+// removeLocked deletes entry from disk and from the queue
+func (q *Queue) removeLocked(entry *QueuedReport) {
+	os.Remove(filepath.Join(q.dir, entry.name))
+	for i, queued := range q.entries {
+		if queued == entry {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			q.size -= entry.size
+			return
+		}
+	}
+}
+
+// Len returns the number of queued requests
+func (q *Queue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.entries)
+}
+
+// Stats returns the number, size and age of the queued requests
+func (q *Queue) Stats() QueueStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	stats := QueueStats{Queued: len(q.entries), Bytes: q.size, Dropped: q.dropped}
+	if len(q.entries) > 0 {
+		stats.OldestAt = q.entries[0].QueuedAt
+		stats.ByMethod = make(map[string]int)
+	}
+	for _, entry := range q.entries {
+		stats.ByMethod[path.Base(entry.Method)]++
+	}
+	return stats
+}
+
+// Dropped returns the requests dropped over the size limit since the queue was last empty
+func (q *Queue) Dropped() uint64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.dropped
+}
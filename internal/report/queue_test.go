@@ -0,0 +1,190 @@
+package report
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/privacy"
+	pb "xhub-agent/proto/reportpb"
+)
+
+func onlineRequest(emails ...string) *pb.OnlineUsersReportRequest {
+	return &pb.OnlineUsersReportRequest{Uuid: "test-uuid", OnlineEmails: emails}
+}
+
+func TestQueue_OrderAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := OpenQueue(dir, 1<<20)
+	require.NoError(t, err)
+
+	queuedAt := time.Unix(1700000000, 0)
+	require.NoError(t, queue.Push(pb.ReportService_SendReport_FullMethodName, &pb.ReportRequest{Uuid: "first"}, queuedAt))
+	require.NoError(t, queue.Push(pb.ReportService_SendOnlineUsersReport_FullMethodName, onlineRequest("a"), queuedAt))
+	require.NoError(t, queue.Push(pb.ReportService_SendReport_FullMethodName, &pb.ReportRequest{Uuid: "second"}, queuedAt))
+	assert.Error(t, queue.Push("/reportpb.ReportService/SendCombinedReport", &pb.CombinedReportRequest{}, queuedAt))
+
+	// Leftovers of an interrupted write and unknown files are cleaned up on open
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".q-00000000000000000099-1-SendReport.pb.tmp"), []byte("x"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unrelated"), []byte("x"), 0600))
+
+	reopened, err := OpenQueue(dir, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, 3, reopened.Len())
+	require.NoError(t, reopened.Push(pb.ReportService_SendReport_FullMethodName, &pb.ReportRequest{Uuid: "third"}, queuedAt))
+
+	var uuids []string
+	for queued := reopened.Peek(); queued != nil; queued = reopened.Peek() {
+		assert.Equal(t, queuedAt, queued.QueuedAt)
+		switch req := queued.Request.(type) {
+		case *pb.ReportRequest:
+			uuids = append(uuids, req.Uuid)
+		case *pb.OnlineUsersReportRequest:
+			uuids = append(uuids, "online")
+		}
+		reopened.Remove(queued)
+	}
+	assert.Equal(t, []string{"first", "online", "second", "third"}, uuids)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestQueue_SizeLimitAndSubscriptionSnapshots(t *testing.T) {
+	// Room for the last two online users reports
+	limit := proto.Size(onlineRequest("second@example.com")) + proto.Size(onlineRequest("third@example.com"))
+	queue, err := OpenQueue(t.TempDir(), int64(limit))
+	require.NoError(t, err)
+	now := time.Now()
+
+	// A newer subscription snapshot replaces the queued one
+	subs := func(id string) *pb.SubscriptionReportRequest {
+		return &pb.SubscriptionReportRequest{Uuid: "u", Subscriptions: []*pb.SubscriptionData{{SubId: id}}}
+	}
+	require.NoError(t, queue.Push(pb.ReportService_SendSubscriptionReport_FullMethodName, subs("old"), now))
+	require.NoError(t, queue.Push(pb.ReportService_SendSubscriptionReport_FullMethodName, subs("new"), now))
+	require.Equal(t, 1, queue.Len())
+	assert.Equal(t, "new", queue.Peek().Request.(*pb.SubscriptionReportRequest).Subscriptions[0].SubId)
+	assert.Zero(t, queue.Dropped())
+
+	// Beyond the size limit the oldest reports are dropped
+	for _, email := range []string{"first@example.com", "second@example.com", "third@example.com"} {
+		require.NoError(t, queue.Push(pb.ReportService_SendOnlineUsersReport_FullMethodName, onlineRequest(email), now))
+	}
+	assert.Equal(t, 2, queue.Len())
+	assert.Equal(t, uint64(2), queue.Dropped())
+	assert.Equal(t, []string{"second@example.com"}, queue.Peek().Request.(*pb.OnlineUsersReportRequest).OnlineEmails)
+}
+
+// outageServer answers Unavailable while down and records the delivered requests in order
+type outageServer struct {
+	pb.UnimplementedReportServiceServer
+	down atomic.Bool
+
+	mutex     sync.Mutex
+	delivered []proto.Message
+	queuedAt  []string // x-agent-queued-at per delivered request
+}
+
+func (s *outageServer) deliver(ctx context.Context, req proto.Message) (*pb.ReportResponse, error) {
+	if s.down.Load() {
+		return nil, status.Error(codes.Unavailable, "xhub is down")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.delivered = append(s.delivered, req)
+	s.queuedAt = append(s.queuedAt, append(md.Get("x-agent-queued-at"), "")[0])
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *outageServer) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	return s.deliver(ctx, req)
+}
+
+func (s *outageServer) SendSubscriptionReport(ctx context.Context, req *pb.SubscriptionReportRequest) (*pb.ReportResponse, error) {
+	return s.deliver(ctx, req)
+}
+
+func (s *outageServer) SendOnlineUsersReport(ctx context.Context, req *pb.OnlineUsersReportRequest) (*pb.ReportResponse, error) {
+	return s.deliver(ctx, req)
+}
+
+func TestReportClient_OfflineQueue(t *testing.T) {
+	server := &outageServer{}
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	queue, err := OpenQueue(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	hasher, err := privacy.NewHasher("queue-key")
+	require.NoError(t, err)
+	counters := errstats.NewCounters()
+
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	client.SetOfflineQueue(queue)
+	client.SetEmailMapper(hasher)
+	client.SetErrorCounters(counters)
+	defer client.Close()
+
+	// During the outage every report is queued
+	server.down.Store(true)
+	counters.RecordCategory(errstats.PanelTimeout)
+	assert.Error(t, client.SendReport("test-uuid", combinedTestData()))
+	assert.Error(t, client.SendSubscriptionReport("test-uuid", []SubscriptionData{{SubID: "sub1", Email: "user1@example.com"}}))
+	assert.Error(t, client.SendOnlineUsersReport("test-uuid", []string{"user1@example.com"}))
+	assert.Equal(t, 3, client.QueuedReports())
+
+	// Back online: the queued reports are sent in order before the live one
+	server.down.Store(false)
+	require.NoError(t, client.SendOnlineUsersReport("test-uuid", []string{"user2@example.com"}))
+	assert.Zero(t, client.QueuedReports())
+
+	require.Len(t, server.delivered, 4)
+	statusReq := server.delivered[0].(*pb.ReportRequest)
+	assert.Empty(t, statusReq.ErrorCounts, "error counts stay pending for the live report")
+	assert.Equal(t, "sub1", server.delivered[1].(*pb.SubscriptionReportRequest).Subscriptions[0].SubId)
+	assert.Equal(t, []string{hasher.MapEmail("user1@example.com")}, server.delivered[2].(*pb.OnlineUsersReportRequest).OnlineEmails,
+		"queued emails are mapped exactly once")
+	assert.Equal(t, []string{hasher.MapEmail("user2@example.com")}, server.delivered[3].(*pb.OnlineUsersReportRequest).OnlineEmails)
+
+	for _, queuedAt := range server.queuedAt[:3] {
+		assert.NotEmpty(t, queuedAt)
+	}
+	assert.Empty(t, server.queuedAt[3], "live reports carry no queue time")
+	assert.Equal(t, uint64(1), counters.Pending()[errstats.PanelTimeout])
+}
+
+func TestReportClient_OfflineQueueSkipsRejections(t *testing.T) {
+	mock := &mockReportServer{shouldError: codes.Unauthenticated}
+	addr, cleanup := setupGRPCTestServer(t, mock)
+	defer cleanup()
+
+	queue, err := OpenQueue(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	client := NewReportClient(addr, "test-key", createTestLogger(t))
+	client.SetOfflineQueue(queue)
+	defer client.Close()
+
+	assert.Error(t, client.SendReport("test-uuid", combinedTestData()))
+	assert.Zero(t, client.QueuedReports(), "requests xhub rejected are not queued")
+}
@@ -3,9 +3,12 @@ package report
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
@@ -15,12 +18,52 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/faultinject"
+	"xhub-agent/internal/metrics"
 	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/privacy"
+	"xhub-agent/internal/sysinfo"
 	"xhub-agent/pkg/logger"
 	pb "xhub-agent/proto/reportpb"
 )
 
+// ErrReportRejected is matched by the errors of requests xhub answered with success false
+var ErrReportRejected = errors.New("rejected by xhub")
+
+// rejectedError is a request xhub answered with success false
+type rejectedError struct {
+	message string
+}
+
+func (e *rejectedError) Error() string { return e.message }
+func (e *rejectedError) Is(target error) bool {
+	return target == ErrReportRejected
+}
+
+// rejectedf returns the error of a request xhub answered with success false, matching
+// ErrReportRejected and counted as errstats.ReportRejected
+func rejectedf(format string, args ...interface{}) error {
+	return errstats.Wrap(errstats.ReportRejected, &rejectedError{message: fmt.Sprintf(format, args...)})
+}
+
+// RPCError is a gRPC status error with an agent-friendly message
+type RPCError struct {
+	Code    codes.Code
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// GRPCStatus exposes the original status code (used by status.FromError and error categorization)
+func (e *RPCError) GRPCStatus() *status.Status {
+	return status.New(e.Code, e.Message)
+}
+
 // ReportClient report data client
 type ReportClient struct {
 	serverAddr      string
@@ -35,6 +78,63 @@ type ReportClient struct {
 	lastErrorState string // track last error state to avoid duplicate logs
 	hasLoggedError bool   // track if error has been logged for current failure
 	wasSuccessful  bool   // track if last operation was successful
+	// Agent process info sent as metadata
+	agentStartTime    time.Time // agent process start time
+	agentRestartCount int64     // persisted restart counter
+	configFingerprint string    // hash of the effective config, changes on reload
+	// Hint appended to RPC errors until the first RPC succeeds (e.g. legacy config migration)
+	connectionHint string
+	rpcSucceeded   bool
+	// Testing-only failure injection (fail_inject), nil when off
+	faults *faultinject.Injector
+	// Per-category error counters sent with every status report
+	errorCounters *errstats.Counters
+	// Block RPCs until the connection is ready (within the request timeout)
+	waitForReady bool
+	// Address family dialing (grpc_dial_strategy), nil keeps gRPC's default dialer
+	dialer *familyDialer
+	// IPv4 is dialed first under the auto strategy (prefer_ipv6: false)
+	preferIPv4 bool
+	// HTTP CONNECT or SOCKS5 proxy (grpc_proxy), zero uses the environment
+	proxy Proxy
+	// Last failed request/response pair per RPC type
+	failures *failureCapture
+	// Replaces user emails in every outgoing request (email_reporting), nil sends them as-is
+	emails privacy.EmailMapper
+	// Data flows sent (report_status, report_subscriptions, report_online_users)
+	types ReportTypes
+	// Combined report RPC negotiation (report_combined)
+	combined combinedState
+	// Delta encoding of status reports (report_delta)
+	delta deltaState
+	// Batched status reports (report_batch_size)
+	batch batchState
+	// Reports queued while xhub is unreachable (offline_queue_max_mb)
+	offline offlineQueue
+	// Long-lived status report stream (report_stream)
+	stream reportStream
+	// Retries of failed RPCs (report_retry_*), zero value never retries
+	retry RetryPolicy
+	// Mutual TLS client certificate and CA (grpc_client_cert, grpc_ca), nil when not set
+	clientTLS *clientTLS
+	// Report RPC outcomes (metrics_listen), nil when off
+	metrics *metrics.Registry
+	// Gzip compression of unary RPCs (report_compression)
+	compression compressionState
+	// Outbound bytes per minute (report_bandwidth_kb_per_min), nil when unlimited
+	bandwidth *bandwidthLimiter
+	// Subscription reports above this encoded size are split (subscription_chunk_kb), 0 never
+	chunkBytes int
+	// Transport of unary RPCs and HTTPS failover (transport, xhub_https_url)
+	transport transportState
+	// RPC and connect timeouts (report_timeout, subscription_timeout, connect_timeout)
+	timeouts Timeouts
+	// Keepalive pings and reconnect backoff (grpc_keepalive_*, grpc_reconnect_max_backoff)
+	keepalive KeepalivePolicy
+	// Dropped connections re-established by watchConnection
+	reconnects atomic.Uint64
+	// Agent and host metadata attached to status reports, nil until set
+	hostInfo atomic.Pointer[pb.AgentInfo]
 }
 
 // NewReportClient creates a new report client
@@ -52,6 +152,12 @@ func NewReportClient(serverAddr, apiKey string, log *logger.Logger) *ReportClien
 		log.Warnf("   Example: 'server.com:9090' instead of 'server.com:9090/api'")
 	}
 
+	if isUnbracketedIPv6(serverAddr) {
+		log.Warnf("⚠️  gRPC server address is an IPv6 literal without brackets: %s", serverAddr)
+		log.Warnf("   The port cannot be told apart from the address, use '[host]:port'")
+		log.Warnf("   Example: '[2001:db8::1]:443' instead of '2001:db8::1:443'")
+	}
+
 	// Auto-detect TLS usage based on common patterns
 	useTLS := shouldUseTLS(serverAddr)
 
@@ -61,13 +167,15 @@ func NewReportClient(serverAddr, apiKey string, log *logger.Logger) *ReportClien
 		logger:        log,
 		useTLS:        useTLS,
 		wasSuccessful: true, // assume success initially
+		failures:      newFailureCapture(),
+		types:         AllReportTypes(),
 	}
 }
 
 // shouldUseTLS determines if TLS should be used based on server address patterns
 func shouldUseTLS(serverAddr string) bool {
 	// Only allow insecure connections for local development
-	if strings.Contains(serverAddr, "localhost") || strings.Contains(serverAddr, "127.0.0.1") {
+	if isLocalServer(serverAddr) {
 		return false // Local development - use insecure
 	}
 
@@ -77,7 +185,26 @@ func shouldUseTLS(serverAddr string) bool {
 
 // isLocalServer checks if the server is a local development server
 func isLocalServer(serverAddr string) bool {
-	return strings.Contains(serverAddr, "localhost") || strings.Contains(serverAddr, "127.0.0.1")
+	return strings.Contains(serverAddr, "localhost") || strings.Contains(serverAddr, "127.0.0.1") ||
+		serverHost(serverAddr) == "::1"
+}
+
+// serverHost returns the host of a host:port server address, without the brackets of an
+// IPv6 literal ("[2001:db8::1]:443" gives "2001:db8::1")
+func serverHost(serverAddr string) string {
+	if host, _, err := net.SplitHostPort(serverAddr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(serverAddr, "["), "]")
+}
+
+// isUnbracketedIPv6 reports whether serverAddr holds an IPv6 literal without the brackets
+// needed to separate it from the port
+func isUnbracketedIPv6(serverAddr string) bool {
+	if hasResolverScheme(serverAddr) || strings.HasPrefix(serverAddr, "[") {
+		return false
+	}
+	return strings.Count(serverAddr, ":") > 1
 }
 
 // SetTLS explicitly enables or disables TLS for the connection
@@ -100,6 +227,162 @@ func (r *ReportClient) SetTLS(useTLS bool) {
 	}
 }
 
+// SetTarget points the client at another xhub server or API key. The connection is closed
+// and re-established by the next RPC; capabilities are negotiated again with the new server.
+// It must not be called while RPCs are in flight.
+func (r *ReportClient) SetTarget(serverAddr, apiKey string) {
+	r.Close()
+	r.serverAddr = serverAddr
+	r.apiKey = apiKey
+	r.useTLS = shouldUseTLS(serverAddr) || r.clientTLS != nil
+	r.combined.supported.Store(false)
+	r.combined.unimplemented.Store(false)
+	r.combined.subscriptions = ""
+	r.delta.supported.Store(false)
+	r.resetDelta()
+	r.stream.unsupported.Store(false)
+	r.stream.pressureMutex.Lock()
+	r.stream.minInterval = 0
+	r.stream.pressureMutex.Unlock()
+	r.logger.Infof("📡 gRPC target changed to %s", serverAddr)
+}
+
+// ServerAddr returns the xhub server address the client reports to
+func (r *ReportClient) ServerAddr() string {
+	return r.serverAddr
+}
+
+// SetAgentInfo sets the agent start time and restart counter sent with every report
+func (r *ReportClient) SetAgentInfo(startTime time.Time, restartCount int64) {
+	r.agentStartTime = startTime
+	r.agentRestartCount = restartCount
+}
+
+// SetHostInfo sets the agent and host metadata attached to every status report. It may be
+// called while reports are sent (the geo lookup completes after startup).
+func (r *ReportClient) SetHostInfo(info sysinfo.Info) {
+	r.hostInfo.Store(ConvertHostInfo(info))
+}
+
+// SetConfigFingerprint sets the config fingerprint sent with every report
+func (r *ReportClient) SetConfigFingerprint(fingerprint string) {
+	r.configFingerprint = fingerprint
+}
+
+// SetConnectionHint sets a hint appended to RPC errors until the first RPC succeeds
+func (r *ReportClient) SetConnectionHint(hint string) {
+	r.connectionHint = hint
+}
+
+// SetWaitForReady makes RPCs wait for the connection to become ready instead of
+// failing fast while the server is briefly unavailable (bounded by the request timeout)
+func (r *ReportClient) SetWaitForReady(enabled bool) {
+	r.waitForReady = enabled
+}
+
+// SetDialStrategy sets the address family strategy used to dial the server, or the proxy
+// when there is one. It takes effect on the next connection and is ignored for unix targets.
+func (r *ReportClient) SetDialStrategy(strategy DialStrategy) {
+	r.dialer = newFamilyDialer(strategy, r.logger)
+	r.dialer.preferIPv4 = r.preferIPv4
+}
+
+// SetPreferIPv6 selects the family dialed first under the auto dial strategy (prefer_ipv6),
+// IPv4 being raced after the fallback delay when prefer is true and the reverse otherwise
+func (r *ReportClient) SetPreferIPv6(prefer bool) {
+	r.preferIPv4 = !prefer
+	if r.dialer != nil {
+		r.dialer.preferIPv4 = r.preferIPv4
+	}
+}
+
+// AddressFamily returns the family (ipv4/ipv6) of the active connection, "" if unknown
+func (r *ReportClient) AddressFamily() string {
+	if r.dialer == nil {
+		return ""
+	}
+	return r.dialer.Family()
+}
+
+// callOptions returns the call options applied to every RPC
+func (r *ReportClient) callOptions() []grpc.CallOption {
+	if r.waitForReady {
+		return []grpc.CallOption{grpc.WaitForReady(true)}
+	}
+	return nil
+}
+
+// SetErrorCounters sets the error counters attached to status reports.
+// Counts are acknowledged (subtracted) only after xhub accepted the report.
+func (r *ReportClient) SetErrorCounters(counters *errstats.Counters) {
+	r.errorCounters = counters
+}
+
+// SetFaultInjector enables testing-only failure injection on report RPCs
+func (r *ReportClient) SetFaultInjector(injector *faultinject.Injector) {
+	r.faults = injector
+}
+
+// SetEmailMapper replaces user emails in all outgoing requests (nil reports them as-is)
+func (r *ReportClient) SetEmailMapper(mapper privacy.EmailMapper) {
+	r.emails = mapper
+}
+
+// mapEmails is a unary interceptor rewriting the emails of every request. It runs first in
+// the chain so that neither the wire, captured failures nor queued reports carry plain emails.
+// Replayed reports were mapped before they were queued.
+func (r *ReportClient) mapEmails(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if msg, ok := req.(proto.Message); ok && r.emails != nil && ctx.Value(replayKey{}) == nil {
+		privacy.MapMessageEmails(msg, r.emails)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// injectFaults is a unary interceptor failing RPCs at the configured fail_inject rate.
+// Injected failures surface as Unavailable so they go through the normal error handling.
+func (r *ReportClient) injectFaults(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := r.faults.Fail(faultinject.PointReport); err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// withConnectionHint appends the connection hint to err if no RPC has succeeded yet
+func (r *ReportClient) withConnectionHint(err error) error {
+	if r.connectionHint == "" || r.rpcSucceeded {
+		return err
+	}
+	return fmt.Errorf("%w (hint: %s)", err, r.connectionHint)
+}
+
+// outgoingMetadata builds the gRPC metadata attached to every request
+func (r *ReportClient) outgoingMetadata() metadata.MD {
+	md := metadata.MD{}
+	if r.apiKey != "" {
+		md.Set("authorization", "Bearer "+r.apiKey)
+	}
+	if !r.agentStartTime.IsZero() {
+		md.Set("x-agent-start-time", strconv.FormatInt(r.agentStartTime.Unix(), 10))
+		md.Set("x-agent-restart-count", strconv.FormatInt(r.agentRestartCount, 10))
+	}
+	if r.configFingerprint != "" {
+		md.Set("x-agent-config-fingerprint", r.configFingerprint)
+	}
+	var capabilities []string
+	if r.combined.enabled {
+		capabilities = append(capabilities, CombinedCapability)
+	}
+	if r.delta.enabled {
+		capabilities = append(capabilities, DeltaCapability)
+	}
+	if len(capabilities) > 0 {
+		md.Set("x-agent-capabilities", strings.Join(capabilities, ","))
+	}
+	return md
+}
+
 // IsTLSEnabled returns whether TLS is currently enabled
 func (r *ReportClient) IsTLSEnabled() bool {
 	return r.useTLS
@@ -108,7 +391,7 @@ func (r *ReportClient) IsTLSEnabled() bool {
 // GetSecurityInfo returns security information about the connection
 func (r *ReportClient) GetSecurityInfo() map[string]interface{} {
 	isLocal := isLocalServer(r.serverAddr)
-	return map[string]interface{}{
+	info := map[string]interface{}{
 		"server_address": r.serverAddr,
 		"tls_enabled":    r.useTLS,
 		"is_local":       isLocal,
@@ -125,6 +408,18 @@ func (r *ReportClient) GetSecurityInfo() map[string]interface{} {
 			}
 		}(),
 	}
+	if r.dialer != nil {
+		info["dial_strategy"] = string(r.dialer.strategy)
+		info["address_family"] = r.dialer.Family()
+	}
+	if proxyURL, err := r.proxyURL(); err == nil && proxyURL != nil {
+		info["proxy"] = proxyURL.Redacted()
+	}
+	if r.transport.https != nil {
+		info["transport"] = string(r.ActiveTransport())
+		info["https_url"] = r.transport.https.base.Redacted()
+	}
+	return info
 }
 
 // extractHostname extracts hostname from server address for TLS ServerName
@@ -141,6 +436,7 @@ func (r *ReportClient) extractHostname() string {
 
 // Connect establishes gRPC connection
 func (r *ReportClient) Connect() error {
+	r.refreshClientCertificate()
 	if r.conn != nil {
 		r.logger.Debugf("gRPC connection already exists, reusing connection to: %s", r.serverAddr)
 		return nil // Already connected
@@ -151,7 +447,7 @@ func (r *ReportClient) Connect() error {
 		r.logger.Infof("🔗 Attempting to establish gRPC connection...")
 		r.logger.Debugf("📡 gRPC Server Address: %s", r.serverAddr)
 		r.logger.Debugf("🔑 API Key: %s", r.apiKey)
-		r.logger.Debugf("⏱️  Connection Timeout: 10 seconds")
+		r.logger.Debugf("⏱️  Connection Timeout: %v", r.connectTimeout())
 		if r.useTLS {
 			r.logger.Debugf("🔒 Transport: Secure (TLS enabled)")
 		} else {
@@ -163,7 +459,10 @@ func (r *ReportClient) Connect() error {
 
 	// Choose credentials based on TLS setting
 	var creds credentials.TransportCredentials
-	if r.useTLS {
+	if r.clientTLS != nil {
+		// Mutual TLS and/or a custom CA
+		creds = credentials.NewTLS(r.clientTLS.tlsConfig(r.extractHostname()))
+	} else if r.useTLS {
 		// Use TLS with system root CAs
 		creds = credentials.NewTLS(&tls.Config{
 			ServerName: r.extractHostname(),
@@ -173,7 +472,39 @@ func (r *ReportClient) Connect() error {
 		creds = insecure.NewCredentials()
 	}
 
-	conn, err := grpc.NewClient(r.serverAddr, grpc.WithTransportCredentials(creds))
+	target := r.serverAddr
+	opts := append(r.connectionOptions(),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(r.recordMetrics, r.mapEmails, r.queueOffline, r.retryRPCs, r.encodeDeltas, r.captureFailures, r.injectFaults, r.throttleBandwidth, r.streamReports, r.negotiateCapabilities, r.routeTransport, r.compressPayloads),
+	)
+	if r.bandwidth != nil {
+		opts = append(opts, grpc.WithStatsHandler(bandwidthStats{limiter: r.bandwidth}))
+	}
+	proxyURL, err := r.proxyURL()
+	if err != nil {
+		r.isConnected = false
+		return fmt.Errorf("invalid proxy: %w", err)
+	}
+	switch {
+	case proxyURL != nil:
+		// The proxy resolves the host, so bypass gRPC's DNS resolver and its own proxy support
+		proxyDial := func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: r.connectTimeout(), KeepAlive: 30 * time.Second}).DialContext(ctx, "tcp", addr)
+		}
+		if r.dialer != nil {
+			proxyDial = r.dialer.DialContext // The dial strategy applies to the proxy
+		}
+		target = "passthrough:///" + r.serverAddr
+		opts = append(opts, grpc.WithContextDialer(newProxyDialer(proxyURL, proxyDial).DialContext))
+		r.logger.Debugf("🌐 gRPC connection through proxy %s", proxyURL.Redacted())
+	case r.dialer != nil && usesCustomDialer(r.serverAddr):
+		// The dialer resolves the host itself, so bypass gRPC's DNS resolver
+		target = "passthrough:///" + r.serverAddr
+		opts = append(opts, grpc.WithContextDialer(r.dialer.DialContext))
+		r.logger.Debugf("🌐 gRPC dial strategy: %s", r.dialer.strategy)
+	}
+
+	conn, err := grpc.NewClient(target, opts...)
 	if err != nil {
 		r.isConnected = false
 
@@ -202,6 +533,7 @@ func (r *ReportClient) Connect() error {
 
 	r.conn = conn
 	r.client = pb.NewReportServiceClient(conn)
+	go r.watchConnection(conn, r.serverAddr)
 
 	// Only log success if not recently connected or first time
 	if !r.isConnected || time.Since(r.lastConnectTime) > 5*time.Minute {
@@ -219,6 +551,10 @@ func (r *ReportClient) Connect() error {
 
 // Close closes the gRPC connection
 func (r *ReportClient) Close() error {
+	r.closeStream()
+	if r.transport.https != nil {
+		r.transport.https.close()
+	}
 	if r.conn != nil {
 		r.logger.Debug("Closing gRPC connection")
 		err := r.conn.Close()
@@ -267,13 +603,21 @@ func (r *ReportClient) markSuccess(operationType string) {
 
 // SendReport sends monitoring data to xhub via gRPC
 func (r *ReportClient) SendReport(uuid string, data *monitor.ServerStatusData) error {
+	return r.SendReportContext(context.Background(), uuid, data)
+}
+
+// SendReportContext is SendReport within ctx and the request timeout
+func (r *ReportClient) SendReportContext(ctx context.Context, uuid string, data *monitor.ServerStatusData) error {
+	if err := checkEnabled(r.types.Status, "status"); err != nil {
+		return err
+	}
 	r.logger.Debugf("📊 Starting gRPC report transmission...")
 	r.logger.Debugf("🆔 Agent UUID: %s", uuid)
 	r.logger.Debugf("📡 Target Server: %s", r.serverAddr)
 
 	// Ensure connection is established
 	if err := r.Connect(); err != nil {
-		return fmt.Errorf("failed to establish gRPC connection: %w", err)
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
 	}
 
 	// Convert monitor data to protobuf format
@@ -285,34 +629,34 @@ func (r *ReportClient) SendReport(uuid string, data *monitor.ServerStatusData) e
 	}
 	r.logger.Debugf("✅ Data conversion successful")
 
-	// Create request
+	// Create request with the error counts pending since the last acknowledged report
+	pendingErrors := r.errorCounters.Pending()
 	req := &pb.ReportRequest{
-		Uuid: uuid,
-		Data: pbData,
+		Uuid:        uuid,
+		Data:        pbData,
+		ErrorCounts: ConvertErrorCounts(pendingErrors),
+		Agent:       r.hostInfo.Load(),
 	}
 	r.logger.Debugf("📦 Created gRPC request with UUID: %s", uuid)
 
 	// Create context with timeout and metadata for authentication
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, r.reportTimeout())
 	defer cancel()
 
-	// Add API key to metadata for authentication
-	md := metadata.New(map[string]string{
-		"authorization": "Bearer " + r.apiKey,
-	})
-	ctx = metadata.NewOutgoingContext(ctx, md)
+	// Add API key and agent info to metadata
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
 
 	// Debug: Log detailed request information
 	r.logger.Debugf("🚀 Sending gRPC request...")
 	r.logger.Debugf("   🎯 Server: %s", r.serverAddr)
 	r.logger.Debugf("   🆔 UUID: %s", uuid)
 	r.logger.Debugf("   🔑 Auth: Bearer %s", r.apiKey)
-	r.logger.Debugf("   ⏱️  Timeout: 30 seconds")
+	r.logger.Debugf("   ⏱️  Timeout: %v", r.reportTimeout())
 	r.logger.Debugf("   📊 Data: CPU=%.1f%%, Memory=%d/%d bytes",
 		pbData.Cpu, pbData.Memory.Current, pbData.Memory.Total)
 
 	// Send gRPC request
-	resp, err := r.client.SendReport(ctx, req)
+	resp, err := r.client.SendReport(ctx, req, r.callOptions()...)
 	if err != nil {
 		// Create error key for deduplication
 		var errorKey string
@@ -365,7 +709,7 @@ func (r *ReportClient) SendReport(uuid string, data *monitor.ServerStatusData) e
 				}
 			}
 
-			return fmt.Errorf("%s", errorMsg)
+			return r.withConnectionHint(&RPCError{Code: st.Code(), Message: errorMsg})
 		}
 
 		// Handle non-gRPC errors
@@ -376,7 +720,7 @@ func (r *ReportClient) SendReport(uuid string, data *monitor.ServerStatusData) e
 			r.logger.Errorf("   UUID: %s", uuid)
 			r.logger.Errorf("   Raw error: %v", err)
 		}
-		return fmt.Errorf("gRPC request failed: %w", err)
+		return r.withConnectionHint(fmt.Errorf("gRPC request failed: %w", err))
 	}
 
 	// Debug: Log response details
@@ -390,10 +734,14 @@ func (r *ReportClient) SendReport(uuid string, data *monitor.ServerStatusData) e
 		if r.shouldLogError(errorKey) {
 			r.logger.Errorf("❌ Server rejected the report: %s", resp.Message)
 		}
-		return fmt.Errorf("report failed: %s", resp.Message)
+		return rejectedf("report failed: %s", resp.Message)
 	}
 
+	// Error counts were delivered, start the next window
+	r.errorCounters.Ack(pendingErrors)
+
 	// Mark success and log recovery if needed
+	r.rpcSucceeded = true
 	r.markSuccess("监控数据上报")
 	r.logger.Debugf("🎉 Data successfully reported via gRPC!")
 	return nil
@@ -401,6 +749,9 @@ func (r *ReportClient) SendReport(uuid string, data *monitor.ServerStatusData) e
 
 // SendSubscriptionReport sends subscription data to xhub via gRPC
 func (r *ReportClient) SendSubscriptionReport(uuid string, subscriptions []SubscriptionData) error {
+	if err := checkEnabled(r.types.Subscriptions, "subscription"); err != nil {
+		return err
+	}
 	r.logger.Debugf("📊 Starting gRPC subscription report transmission...")
 	r.logger.Debugf("🆔 Agent UUID: %s", uuid)
 	r.logger.Debugf("📡 Target Server: %s", r.serverAddr)
@@ -408,55 +759,62 @@ func (r *ReportClient) SendSubscriptionReport(uuid string, subscriptions []Subsc
 
 	// Ensure connection is established
 	if err := r.Connect(); err != nil {
-		return fmt.Errorf("failed to establish gRPC connection: %w", err)
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
 	}
 
 	// Convert subscription data to protobuf format
 	r.logger.Debugf("🔄 Converting subscription data to protobuf format...")
-	var pbSubscriptions []*pb.SubscriptionData
-	for _, sub := range subscriptions {
-		pbHeaders := &pb.SubscriptionHeaders{
-			ProfileTitle:          sub.Headers.ProfileTitle,
-			ProfileUpdateInterval: sub.Headers.ProfileUpdateInterval,
-			SubscriptionUserinfo:  sub.Headers.SubscriptionUserinfo,
-		}
-
-		pbSub := &pb.SubscriptionData{
-			SubId:      sub.SubID,
-			Email:      sub.Email,
-			NodeConfig: sub.NodeConfig,
-			Headers:    pbHeaders,
-		}
-		pbSubscriptions = append(pbSubscriptions, pbSub)
-	}
+	pbSubscriptions := convertSubscriptions(subscriptions)
 
-	// Create request
-	req := &pb.SubscriptionReportRequest{
+	// Create request, split when it exceeds the chunk size
+	chunks := splitSubscriptions(&pb.SubscriptionReportRequest{
 		Uuid:          uuid,
 		Subscriptions: pbSubscriptions,
-	}
+	}, r.chunkBytes)
 	r.logger.Debugf("📦 Created gRPC subscription request with UUID: %s", uuid)
+	if len(chunks) > 1 {
+		r.logger.Debugf("✂️  Subscription report exceeds %d bytes, sending it in %d chunks", r.chunkBytes, len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if err := r.sendSubscriptionChunk(uuid, chunk); err != nil {
+			// The failed chunk was queued if xhub is unreachable, queue the rest of the batch
+			if isOutage(err) {
+				r.enqueueChunks(chunks[i+1:])
+			}
+			return err
+		}
+	}
+
+	// Mark success and log recovery if needed
+	r.rpcSucceeded = true
+	r.markSuccess("订阅数据上报")
+	r.logger.Debugf("🎉 Subscription data successfully reported via gRPC!")
+	return nil
+}
 
+// sendSubscriptionChunk sends one subscription report request (a whole report or a chunk)
+func (r *ReportClient) sendSubscriptionChunk(uuid string, req *pb.SubscriptionReportRequest) error {
 	// Create context with timeout and metadata for authentication
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), r.subscriptionTimeout())
 	defer cancel()
 
-	// Add API key to metadata for authentication
-	md := metadata.New(map[string]string{
-		"authorization": "Bearer " + r.apiKey,
-	})
-	ctx = metadata.NewOutgoingContext(ctx, md)
+	// Add API key and agent info to metadata
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
 
 	// Debug: Log detailed request information
 	r.logger.Debugf("🚀 Sending gRPC subscription request...")
 	r.logger.Debugf("   🎯 Server: %s", r.serverAddr)
 	r.logger.Debugf("   🆔 UUID: %s", uuid)
 	r.logger.Debugf("   🔑 Auth: Bearer %s", r.apiKey)
-	r.logger.Debugf("   ⏱️  Timeout: 30 seconds")
-	r.logger.Debugf("   📋 Subscriptions: %d items", len(pbSubscriptions))
+	r.logger.Debugf("   ⏱️  Timeout: %v", r.subscriptionTimeout())
+	r.logger.Debugf("   📋 Subscriptions: %d items", len(req.Subscriptions))
+	if req.ChunkCount > 0 {
+		r.logger.Debugf("   ✂️  Chunk: %d/%d of batch %s", req.ChunkIndex+1, req.ChunkCount, req.BatchId)
+	}
 
 	// Send gRPC request
-	resp, err := r.client.SendSubscriptionReport(ctx, req)
+	resp, err := r.client.SendSubscriptionReport(ctx, req, r.callOptions()...)
 	if err != nil {
 		// Create error key for deduplication
 		var errorKey string
@@ -509,7 +867,7 @@ func (r *ReportClient) SendSubscriptionReport(uuid string, subscriptions []Subsc
 				}
 			}
 
-			return fmt.Errorf("%s", errorMsg)
+			return r.withConnectionHint(&RPCError{Code: st.Code(), Message: errorMsg})
 		}
 
 		// Handle non-gRPC errors
@@ -520,7 +878,7 @@ func (r *ReportClient) SendSubscriptionReport(uuid string, subscriptions []Subsc
 			r.logger.Errorf("   UUID: %s", uuid)
 			r.logger.Errorf("   Raw error: %v", err)
 		}
-		return fmt.Errorf("gRPC subscription request failed: %w", err)
+		return r.withConnectionHint(fmt.Errorf("gRPC subscription request failed: %w", err))
 	}
 
 	// Debug: Log response details
@@ -534,17 +892,59 @@ func (r *ReportClient) SendSubscriptionReport(uuid string, subscriptions []Subsc
 		if r.shouldLogError(errorKey) {
 			r.logger.Errorf("❌ Server rejected the subscription report: %s", resp.Message)
 		}
-		return fmt.Errorf("subscription report failed: %s", resp.Message)
+		return rejectedf("subscription report failed: %s", resp.Message)
 	}
-
-	// Mark success and log recovery if needed
-	r.markSuccess("订阅数据上报")
-	r.logger.Debugf("🎉 Subscription data successfully reported via gRPC!")
 	return nil
 }
 
+// convertSubscriptions converts subscription data to protobuf format
+func convertSubscriptions(subscriptions []SubscriptionData) []*pb.SubscriptionData {
+	var pbSubscriptions []*pb.SubscriptionData
+	for _, sub := range subscriptions {
+		pbHeaders := &pb.SubscriptionHeaders{
+			ProfileTitle:          sub.Headers.ProfileTitle,
+			ProfileUpdateInterval: sub.Headers.ProfileUpdateInterval,
+			SubscriptionUserinfo:  sub.Headers.SubscriptionUserinfo,
+		}
+
+		pbSub := &pb.SubscriptionData{
+			SubId:           sub.SubID,
+			Email:           sub.Email,
+			NodeConfig:      sub.NodeConfig,
+			JsonConfig:      sub.JSONConfig,
+			WireguardConfig: sub.WireGuard,
+			Headers:         pbHeaders,
+
+			ContentStaleSuspect: sub.StaleSuspect,
+			StaleReason:         sub.StaleReason,
+		}
+		pbSubscriptions = append(pbSubscriptions, pbSub)
+	}
+	return pbSubscriptions
+}
+
 // SendOnlineUsersReport sends online users data to xhub via gRPC
 func (r *ReportClient) SendOnlineUsersReport(uuid string, onlineEmails []string) error {
+	return r.sendOnlineUsersReport(uuid, onlineEmails, nil, 0)
+}
+
+// SendStaleOnlineUsersReport sends a previously fetched online users list, with its age
+// in the x-agent-online-users-age metadata (seconds)
+func (r *ReportClient) SendStaleOnlineUsersReport(uuid string, onlineEmails []string, age time.Duration) error {
+	return r.sendOnlineUsersReport(uuid, onlineEmails, nil, age)
+}
+
+// SendOnlineUsers sends a collected online users list with its connection details, marked
+// as reused when its age is set
+func (r *ReportClient) SendOnlineUsers(uuid string, online *CombinedOnlineUsers) error {
+	return r.sendOnlineUsersReport(uuid, online.Emails, online.Users, online.Age)
+}
+
+// sendOnlineUsersReport sends the online users list; age > 0 marks it as reused
+func (r *ReportClient) sendOnlineUsersReport(uuid string, onlineEmails []string, users []monitor.OnlineUser, age time.Duration) error {
+	if err := checkEnabled(r.types.OnlineUsers, "online users"); err != nil {
+		return err
+	}
 	r.logger.Debugf("📊 Starting gRPC online users report transmission...")
 	r.logger.Debugf("🆔 Agent UUID: %s", uuid)
 	r.logger.Debugf("📡 Target Server: %s", r.serverAddr)
@@ -552,24 +952,26 @@ func (r *ReportClient) SendOnlineUsersReport(uuid string, onlineEmails []string)
 
 	// Ensure connection is established
 	if err := r.Connect(); err != nil {
-		return fmt.Errorf("failed to establish gRPC connection: %w", err)
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
 	}
 
 	// Create request
 	req := &pb.OnlineUsersReportRequest{
 		Uuid:         uuid,
 		OnlineEmails: onlineEmails,
+		Users:        convertOnlineUsers(users),
 	}
 	r.logger.Debugf("📦 Created gRPC online users request with UUID: %s", uuid)
 
 	// Create context with timeout and metadata for authentication
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), r.reportTimeout())
 	defer cancel()
 
-	// Add API key to metadata for authentication
-	md := metadata.New(map[string]string{
-		"authorization": "Bearer " + r.apiKey,
-	})
+	// Add API key and agent info to metadata
+	md := r.outgoingMetadata()
+	if age > 0 {
+		md.Set("x-agent-online-users-age", strconv.FormatInt(int64(age/time.Second), 10))
+	}
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
 	// Debug: Log detailed request information
@@ -577,7 +979,7 @@ func (r *ReportClient) SendOnlineUsersReport(uuid string, onlineEmails []string)
 	r.logger.Debugf("   🎯 Server: %s", r.serverAddr)
 	r.logger.Debugf("   🆔 UUID: %s", uuid)
 	r.logger.Debugf("   🔑 Auth: Bearer %s", r.apiKey)
-	r.logger.Debugf("   ⏱️  Timeout: 30 seconds")
+	r.logger.Debugf("   ⏱️  Timeout: %v", r.reportTimeout())
 	if len(onlineEmails) > 0 {
 		r.logger.Debugf("   👥 Online Users: %v", onlineEmails)
 	} else {
@@ -585,7 +987,7 @@ func (r *ReportClient) SendOnlineUsersReport(uuid string, onlineEmails []string)
 	}
 
 	// Send gRPC request
-	resp, err := r.client.SendOnlineUsersReport(ctx, req)
+	resp, err := r.client.SendOnlineUsersReport(ctx, req, r.callOptions()...)
 	if err != nil {
 		// Create error key for deduplication
 		var errorKey string
@@ -638,7 +1040,7 @@ func (r *ReportClient) SendOnlineUsersReport(uuid string, onlineEmails []string)
 				}
 			}
 
-			return fmt.Errorf("%s", errorMsg)
+			return r.withConnectionHint(&RPCError{Code: st.Code(), Message: errorMsg})
 		}
 
 		// Handle non-gRPC errors
@@ -649,7 +1051,7 @@ func (r *ReportClient) SendOnlineUsersReport(uuid string, onlineEmails []string)
 			r.logger.Errorf("   UUID: %s", uuid)
 			r.logger.Errorf("   Raw error: %v", err)
 		}
-		return fmt.Errorf("gRPC online users request failed: %w", err)
+		return r.withConnectionHint(fmt.Errorf("gRPC online users request failed: %w", err))
 	}
 
 	// Debug: Log response details
@@ -663,10 +1065,11 @@ func (r *ReportClient) SendOnlineUsersReport(uuid string, onlineEmails []string)
 		if r.shouldLogError(errorKey) {
 			r.logger.Errorf("❌ Server rejected the online users report: %s", resp.Message)
 		}
-		return fmt.Errorf("online users report failed: %s", resp.Message)
+		return rejectedf("online users report failed: %s", resp.Message)
 	}
 
 	// Mark success and log recovery if needed
+	r.rpcSucceeded = true
 	r.markSuccess("在线用户上报")
 	r.logger.Debugf("🎉 Online users data successfully reported via gRPC!")
 	return nil
@@ -677,7 +1080,12 @@ type SubscriptionData struct {
 	SubID      string              `json:"subId"`
 	Email      string              `json:"email"`
 	NodeConfig string              `json:"nodeConfig"` // base64编码的节点配置
+	JSONConfig string              `json:"jsonConfig"` // JSON (sing-box) 订阅，未采集时为空
+	WireGuard  string              `json:"wireguard"`  // WireGuard 客户端配置模板，私钥由 xhub 填写
 	Headers    SubscriptionHeaders `json:"headers"`    // HTTP响应头
+
+	StaleSuspect bool   `json:"staleSuspect"` // 内容与面板客户端列表不一致
+	StaleReason  string `json:"staleReason"`  // 不一致原因
 }
 
 // SubscriptionHeaders HTTP响应头信息
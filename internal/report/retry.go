@@ -0,0 +1,93 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRetryCodes are the gRPC codes retried when report_retry_codes is not set
+var DefaultRetryCodes = []codes.Code{codes.Unavailable, codes.DeadlineExceeded}
+
+// RetryPolicy is the retry policy of report RPCs (report_retry_*)
+type RetryPolicy struct {
+	Attempts   int           // Attempts per RPC, 1 disables retries
+	Backoff    time.Duration // Delay before the first retry, doubled per retry and jittered
+	MaxBackoff time.Duration // Delay cap, 0 for none
+	Codes      []codes.Code  // Retried status codes
+}
+
+// ParseRetryCodes parses gRPC code names such as "unavailable", "DEADLINE_EXCEEDED" or
+// "ResourceExhausted". OK cannot be retried.
+func ParseRetryCodes(names []string) ([]codes.Code, error) {
+	var parsed []codes.Code
+	for _, name := range names {
+		normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "_", ""))
+		found := false
+		for code := codes.Canceled; code <= codes.Unauthenticated; code++ {
+			if strings.ToLower(code.String()) == normalized {
+				if !slices.Contains(parsed, code) {
+					parsed = append(parsed, code)
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid gRPC status code %q in report_retry_codes", name)
+		}
+	}
+	return parsed, nil
+}
+
+// SetRetryPolicy retries failed report RPCs with the policy's codes within their timeout
+func (r *ReportClient) SetRetryPolicy(policy RetryPolicy) {
+	r.retry = policy
+}
+
+// retryDelay returns the jittered delay before retry number retry (1 for the first):
+// the exponential backoff, capped, of which a random half is waited
+func (p RetryPolicy) retryDelay(retry int) time.Duration {
+	backoff := p.Backoff << (retry - 1)
+	if backoff <= 0 || (p.MaxBackoff > 0 && backoff > p.MaxBackoff) {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// retryRPCs is a unary interceptor retrying failed RPCs per the retry policy. It runs inside
+// the email mapping (requests are mapped once) and the offline queue (only the last failure
+// is queued); every attempt goes through failure capture and fault injection.
+func (r *ReportClient) retryRPCs(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	for attempt := 1; attempt < r.retry.Attempts && err != nil; attempt++ {
+		if !slices.Contains(r.retry.Codes, status.Code(err)) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := r.retry.retryDelay(attempt)
+		r.logger.Debugf("🔁 %s failed (attempt %d/%d), retrying in %v: %v",
+			path.Base(method), attempt, r.retry.Attempts, delay.Round(time.Millisecond), err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package report
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+func TestParseRetryCodes(t *testing.T) {
+	parsed, err := ParseRetryCodes([]string{"unavailable", "DEADLINE_EXCEEDED", "ResourceExhausted", " aborted ", "unavailable"})
+	require.NoError(t, err)
+	assert.Equal(t, []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted}, parsed)
+
+	for _, invalid := range []string{"ok", "not_a_code", ""} {
+		_, err := ParseRetryCodes([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	for retry, backoff := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 300 * time.Millisecond, 40: 300 * time.Millisecond} {
+		for i := 0; i < 20; i++ {
+			delay := policy.retryDelay(retry)
+			assert.GreaterOrEqual(t, delay, backoff/2, "retry %d", retry)
+			assert.LessOrEqual(t, delay, backoff, "retry %d", retry)
+		}
+	}
+	assert.Zero(t, RetryPolicy{}.retryDelay(1))
+}
+
+// flakyServer answers the given code to the first failures online users reports
+type flakyServer struct {
+	pb.UnimplementedReportServiceServer
+	code     codes.Code
+	failures int
+
+	mutex    sync.Mutex
+	attempts int
+}
+
+func (s *flakyServer) SendOnlineUsersReport(ctx context.Context, req *pb.OnlineUsersReportRequest) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attempts++
+	if s.attempts <= s.failures {
+		return nil, status.Error(s.code, "flaky")
+	}
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestReportClient_Retry(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Codes: DefaultRetryCodes}
+
+	tests := []struct {
+		name     string
+		server   *flakyServer
+		policy   RetryPolicy
+		wantErr  codes.Code
+		attempts int
+	}{
+		{"recovers within the attempts", &flakyServer{code: codes.Unavailable, failures: 2}, policy, codes.OK, 3},
+		{"attempts exhausted", &flakyServer{code: codes.Unavailable, failures: 5}, policy, codes.Unavailable, 3},
+		{"code not retried", &flakyServer{code: codes.InvalidArgument, failures: 1}, policy, codes.InvalidArgument, 1},
+		{"retries disabled", &flakyServer{code: codes.Unavailable, failures: 1}, RetryPolicy{}, codes.Unavailable, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStreamClient(t, tt.server)
+			client.SetStreaming(false)
+			client.SetRetryPolicy(tt.policy)
+
+			err := client.SendOnlineUsersReport("test-uuid", []string{"user1"})
+			assert.Equal(t, tt.wantErr, status.Code(err))
+			assert.Equal(t, tt.attempts, tt.server.attempts)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// SendShutdownNotice tells xhub the agent is going offline on purpose. ctx bounds the
+// request and its retries, so an unreachable xhub does not hold up the shutdown.
+func (r *ReportClient) SendShutdownNotice(ctx context.Context, uuid, reason string, uptime time.Duration) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.ShutdownNotice{
+		Uuid:          uuid,
+		Reason:        reason,
+		UptimeSeconds: int64(uptime / time.Second),
+		QueuedReports: int32(r.QueuedReports()),
+	}
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+	resp, err := r.client.SendShutdownNotice(ctx, req, r.callOptions()...)
+	if err != nil {
+		return fmt.Errorf("gRPC shutdown notice failed: %w", err)
+	}
+	if !resp.Success {
+		return rejectedf("shutdown notice rejected: %s", resp.Message)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// shutdownServer is an outageServer that also records shutdown notices (even while down)
+type shutdownServer struct {
+	outageServer
+	notices []*pb.ShutdownNotice
+}
+
+func (s *shutdownServer) SendShutdownNotice(ctx context.Context, req *pb.ShutdownNotice) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.notices = append(s.notices, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func newShutdownClient(t *testing.T, server *shutdownServer) *ReportClient {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	queue, err := OpenQueue(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	client.SetOfflineQueue(queue)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestReportClient_FlushQueueAndShutdownNotice(t *testing.T) {
+	server := &shutdownServer{}
+	client := newShutdownClient(t, server)
+
+	server.down.Store(true)
+	assert.Error(t, client.SendReport("test-uuid", combinedTestData()))
+	require.Equal(t, 1, client.QueuedReports())
+
+	server.down.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, client.FlushQueue(ctx))
+	assert.Zero(t, client.QueuedReports())
+	assert.Len(t, server.delivered, 1)
+
+	require.NoError(t, client.SendShutdownNotice(ctx, "test-uuid", "signal terminated", 90*time.Second))
+	require.Len(t, server.notices, 1)
+	notice := server.notices[0]
+	assert.Equal(t, "test-uuid", notice.Uuid)
+	assert.Equal(t, "signal terminated", notice.Reason)
+	assert.Equal(t, int64(90), notice.UptimeSeconds)
+	assert.Zero(t, notice.QueuedReports)
+}
+
+func TestReportClient_ShutdownNoticeCountsUndelivered(t *testing.T) {
+	server := &shutdownServer{}
+	client := newShutdownClient(t, server)
+
+	server.down.Store(true)
+	assert.Error(t, client.SendReport("test-uuid", combinedTestData()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Error(t, client.FlushQueue(ctx), "xhub is still down")
+	require.NoError(t, client.SendShutdownNotice(ctx, "test-uuid", "config reload", time.Second))
+	assert.Equal(t, int32(1), server.notices[0].QueuedReports)
+}
+
+func TestReportClient_FlushQueueWithoutQueue(t *testing.T) {
+	client := NewReportClient("localhost:1", "test-key", createTestLogger(t))
+	defer client.Close()
+	assert.NoError(t, client.FlushQueue(context.Background()))
+}
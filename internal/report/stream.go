@@ -0,0 +1,182 @@
+package report
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// streamAck is an acknowledgement or the error that ended the stream
+type streamAck struct {
+	ack *pb.StreamReportAck
+	err error
+}
+
+// reportStream is the long-lived status report stream (report_stream)
+type reportStream struct {
+	enabled     bool
+	unsupported atomic.Bool // xhub answered Unimplemented, unary SendReport is used until restart
+
+	mutex    sync.Mutex // One report in flight on the stream
+	stream   pb.ReportService_StreamReportsClient
+	cancel   context.CancelFunc
+	acks     chan streamAck
+	sequence uint64
+
+	// Backpressure requested by xhub in its acknowledgements
+	pressureMutex sync.Mutex
+	minInterval   time.Duration
+	lastAck       time.Time
+}
+
+// SetStreaming sends status reports over one long-lived stream instead of one RPC each.
+// It falls back to unary reports if xhub does not implement the stream.
+func (r *ReportClient) SetStreaming(enabled bool) {
+	r.stream.enabled = enabled
+}
+
+// Backpressure returns how long xhub asked the agent to wait before the next status report,
+// 0 when it may report now
+func (r *ReportClient) Backpressure() time.Duration {
+	s := &r.stream
+	s.pressureMutex.Lock()
+	defer s.pressureMutex.Unlock()
+	if s.minInterval <= 0 {
+		return 0
+	}
+	return max(0, time.Until(s.lastAck.Add(s.minInterval)))
+}
+
+// streamReports is the last unary interceptor: with report_stream it carries SendReport over
+// the report stream, so the other interceptors (email mapping, offline queue, failure
+// capture, fault injection) apply to streamed reports too
+func (r *ReportClient) streamReports(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	report, isReport := req.(*pb.ReportRequest)
+	resp, isResponse := reply.(*pb.ReportResponse)
+	if !r.stream.enabled || r.stream.unsupported.Load() || method != pb.ReportService_SendReport_FullMethodName || !isReport || !isResponse {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if r.transport.usesHTTPS() {
+		// The stream needs gRPC
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	ack, err := r.sendOnStream(ctx, cc, report)
+	if status.Code(err) == codes.Unimplemented {
+		r.stream.unsupported.Store(true)
+		r.logger.Infof("📶 xhub does not support the report stream, using one RPC per report")
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if isOutage(err) && r.transport.https != nil && ctx.Err() == nil {
+		// Unary RPCs can fail over to HTTPS
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	resp.Success, resp.Message = ack.Success, ack.Message
+	r.stream.pressureMutex.Lock()
+	r.stream.minInterval = time.Duration(ack.MinIntervalMs) * time.Millisecond
+	r.stream.lastAck = time.Now()
+	r.stream.pressureMutex.Unlock()
+	return nil
+}
+
+// sendOnStream sends report on the stream, opening it if needed, and waits for its
+// acknowledgement until ctx is done. A broken stream is closed and reopened by the next report.
+func (r *ReportClient) sendOnStream(ctx context.Context, cc *grpc.ClientConn, report *pb.ReportRequest) (*pb.StreamReportAck, error) {
+	s := &r.stream
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stream == nil {
+		if err := r.openStream(cc); err != nil {
+			return nil, err
+		}
+	}
+
+	s.sequence++
+	sequence := s.sequence
+	// A failed send is followed by the stream error on the receive side
+	s.stream.Send(&pb.StreamReportRequest{Sequence: sequence, Report: report})
+
+	for {
+		select {
+		case received := <-s.acks:
+			if received.err != nil {
+				s.closeLocked()
+				return nil, received.err
+			}
+			if received.ack.Sequence == sequence {
+				return received.ack, nil
+			}
+		case <-ctx.Done():
+			s.closeLocked()
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// openStream opens the report stream with the agent metadata and starts receiving acks
+func (r *ReportClient) openStream(cc *grpc.ClientConn) error {
+	s := &r.stream
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(context.Background(), r.outgoingMetadata()))
+	stream, err := pb.NewReportServiceClient(cc).StreamReports(ctx, r.callOptions()...)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	acks := make(chan streamAck, 1)
+	go func() {
+		// The response header carries the capabilities, like the unary responses
+		if header, err := stream.Header(); err == nil {
+			r.setCapabilities(parseCapabilities(header))
+		}
+		for {
+			ack, err := stream.Recv()
+			if err == io.EOF {
+				err = status.Error(codes.Unavailable, "report stream closed by xhub")
+			}
+			select {
+			case acks <- streamAck{ack: ack, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	s.stream, s.cancel, s.acks, s.sequence = stream, cancel, acks, 0
+	r.logger.Debugf("📶 Opened report stream to %s", r.serverAddr)
+	return nil
+}
+
+// closeStream closes the report stream, if open
+func (r *ReportClient) closeStream() {
+	r.stream.mutex.Lock()
+	defer r.stream.mutex.Unlock()
+	r.stream.closeLocked()
+}
+
+// closeLocked cancels the stream and its receiver
+func (s *reportStream) closeLocked() {
+	if s.stream == nil {
+		return
+	}
+	s.cancel()
+	s.stream, s.cancel, s.acks = nil, nil, nil
+}
@@ -0,0 +1,153 @@
+package report
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// streamServer acknowledges streamed reports and counts streams and unary reports.
+// With closeAfter > 0 it ends each stream after that many reports.
+type streamServer struct {
+	pb.UnimplementedReportServiceServer
+	minIntervalMs int64
+	closeAfter    int
+
+	mutex    sync.Mutex
+	streams  int
+	unary    int
+	reports  []*pb.ReportRequest
+	sequence []uint64
+	apiKeys  []string // authorization metadata per stream
+}
+
+func (s *streamServer) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unary++
+	s.reports = append(s.reports, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *streamServer) StreamReports(stream pb.ReportService_StreamReportsServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	s.mutex.Lock()
+	s.streams++
+	s.apiKeys = append(s.apiKeys, md.Get("authorization")...)
+	s.mutex.Unlock()
+
+	for received := 0; s.closeAfter == 0 || received < s.closeAfter; received++ {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.mutex.Lock()
+		s.reports = append(s.reports, req.Report)
+		s.sequence = append(s.sequence, req.Sequence)
+		s.mutex.Unlock()
+		if err := stream.Send(&pb.StreamReportAck{Sequence: req.Sequence, Success: true, MinIntervalMs: s.minIntervalMs}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newStreamClient(t *testing.T, server pb.ReportServiceServer) *ReportClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	client.SetStreaming(true)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestReportClient_Stream(t *testing.T) {
+	server := &streamServer{}
+	client := newStreamClient(t, server)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+	}
+
+	assert.Equal(t, 1, server.streams, "one stream carries all reports")
+	assert.Zero(t, server.unary)
+	assert.Equal(t, []uint64{1, 2, 3}, server.sequence)
+	assert.Equal(t, []string{"Bearer test-key"}, server.apiKeys)
+	assert.Equal(t, int32(2), server.reports[0].Data.CpuCores)
+	assert.Zero(t, client.Backpressure())
+}
+
+func TestReportClient_StreamBackpressure(t *testing.T) {
+	server := &streamServer{minIntervalMs: 60000}
+	client := newStreamClient(t, server)
+
+	require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+	wait := client.Backpressure()
+	assert.Greater(t, wait, 59*time.Second)
+	assert.LessOrEqual(t, wait, time.Minute)
+
+	// An acknowledgement without a minimum interval lifts it
+	server.minIntervalMs = 0
+	require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+	assert.Zero(t, client.Backpressure())
+}
+
+func TestReportClient_StreamReopensAfterClose(t *testing.T) {
+	server := &streamServer{closeAfter: 1}
+	client := newStreamClient(t, server)
+
+	require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+	// The server ended the stream: the next report fails and a new stream carries the one after
+	err := client.SendReport("test-uuid", combinedTestData())
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+
+	assert.Equal(t, 2, server.streams)
+	assert.Equal(t, []uint64{1, 1}, server.sequence)
+}
+
+// unaryOnlyServer implements only the unary status report
+type unaryOnlyServer struct {
+	pb.UnimplementedReportServiceServer
+	mutex sync.Mutex
+	unary int
+}
+
+func (s *unaryOnlyServer) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unary++
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestReportClient_StreamFallsBackToUnary(t *testing.T) {
+	server := &unaryOnlyServer{}
+	client := newStreamClient(t, server)
+
+	require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+	require.NoError(t, client.SendReport("test-uuid", combinedTestData()))
+	assert.Equal(t, 2, server.unary)
+	assert.True(t, client.stream.unsupported.Load())
+}
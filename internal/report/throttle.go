@@ -0,0 +1,228 @@
+package report
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// Compression selects the compression of report payloads (report_compression)
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+)
+
+// ParseCompression parses a report_compression value (empty means gzip)
+func ParseCompression(value string) (Compression, error) {
+	switch compression := Compression(strings.ToLower(strings.TrimSpace(value))); compression {
+	case "":
+		return CompressionGzip, nil
+	case CompressionNone, CompressionGzip:
+		return compression, nil
+	default:
+		return "", fmt.Errorf("unknown report_compression %q (known: gzip, none)", value)
+	}
+}
+
+// compressionState tracks the compression of unary RPCs
+type compressionState struct {
+	enabled  bool
+	minBytes int // Smaller requests are sent uncompressed, gzip saving little on them
+	// xhub has no gzip decompressor; requests are sent uncompressed until the agent restarts
+	unsupported atomic.Bool
+}
+
+// SetCompression sets the compression of unary RPC payloads. Requests are sent uncompressed
+// from the first one xhub cannot decompress on.
+func (r *ReportClient) SetCompression(compression Compression) {
+	r.compression.enabled = compression == CompressionGzip
+}
+
+// SetCompressionThreshold sends requests smaller than minBytes (encoded) uncompressed, 0
+// compresses every request
+func (r *ReportClient) SetCompressionThreshold(minBytes int) {
+	r.compression.minBytes = minBytes
+}
+
+// isCompressionUnsupported reports whether err is xhub rejecting the request encoding
+func isCompressionUnsupported(err error) bool {
+	st, ok := status.FromError(err)
+	return ok && st.Code() == codes.Unimplemented && strings.Contains(st.Message(), "grpc-encoding")
+}
+
+// compressPayloads is a unary interceptor gzipping requests of at least the compression
+// threshold. It runs last in the chain so that a request xhub cannot decompress is resent
+// uncompressed before anything sees the failure.
+func (r *ReportClient) compressPayloads(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if !r.compression.enabled || r.compression.unsupported.Load() {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if msg, ok := req.(proto.Message); ok && proto.Size(msg) < r.compression.minBytes {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.UseCompressor(gzip.Name))...)
+	if !isCompressionUnsupported(err) {
+		return err
+	}
+	if r.compression.unsupported.CompareAndSwap(false, true) {
+		r.logger.Warnf("⚠️  xhub does not accept gzip compressed requests, sending them uncompressed until restart")
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// bandwidthLimiter is a token bucket of outbound bytes refilled at perMinute bytes per minute,
+// holding at most a minute worth. Payloads are charged after they are sent, with their size
+// on the wire, so a payload larger than the bucket goes out once the bucket is full and the
+// following ones wait until the debt is paid back.
+type bandwidthLimiter struct {
+	perMinute float64
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newBandwidthLimiter creates a limiter of perMinute bytes per minute, starting full
+func newBandwidthLimiter(perMinute int64) *bandwidthLimiter {
+	return &bandwidthLimiter{perMinute: float64(perMinute), tokens: float64(perMinute), last: time.Now(), now: time.Now}
+}
+
+// refill adds the tokens accumulated since the last call (mutex held)
+func (l *bandwidthLimiter) refill() {
+	now := l.now()
+	l.tokens = min(l.perMinute, l.tokens+now.Sub(l.last).Minutes()*l.perMinute)
+	l.last = now
+}
+
+// delay returns how long to wait before the next payload can be sent
+func (l *bandwidthLimiter) delay() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.refill()
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.perMinute * float64(time.Minute))
+}
+
+// consume charges n sent bytes
+func (l *bandwidthLimiter) consume(n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.refill()
+	l.tokens -= float64(n)
+}
+
+// SetBandwidthLimit caps the outbound bytes per minute of all RPCs and streams, 0 for no
+// limit. It takes effect on the next connection.
+func (r *ReportClient) SetBandwidthLimit(bytesPerMinute int64) {
+	r.bandwidth = nil
+	if bytesPerMinute > 0 {
+		r.bandwidth = newBandwidthLimiter(bytesPerMinute)
+	}
+}
+
+// throttleBandwidth is a unary interceptor delaying RPCs while the bandwidth limit is used up.
+// An RPC that cannot be sent within its deadline fails with ResourceExhausted. Stream
+// messages are charged but not delayed.
+func (r *ReportClient) throttleBandwidth(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if r.bandwidth == nil {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if delay := r.bandwidth.delay(); delay > 0 {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return status.Errorf(codes.ResourceExhausted, "outbound bandwidth limit of %d KiB/min reached, next request possible in %v",
+				int64(r.bandwidth.perMinute)>>10, delay.Round(time.Second))
+		}
+		r.logger.Debugf("🐢 Bandwidth limit reached, delaying %s by %v", path.Base(method), delay.Round(time.Millisecond))
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// bandwidthStats is the stats handler charging every outgoing payload to the bandwidth limit
+type bandwidthStats struct {
+	limiter *bandwidthLimiter
+}
+
+func (s bandwidthStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (s bandwidthStats) HandleRPC(_ context.Context, rs stats.RPCStats) {
+	if payload, ok := rs.(*stats.OutPayload); ok && payload.Client {
+		s.limiter.consume(payload.WireLength)
+	}
+}
+
+func (s bandwidthStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (s bandwidthStats) HandleConn(context.Context, stats.ConnStats) {}
+
+// SetSubscriptionChunkSize splits subscription reports larger than maxBytes (encoded) into
+// chunks, 0 never splits them
+func (r *ReportClient) SetSubscriptionChunkSize(maxBytes int) {
+	r.chunkBytes = maxBytes
+}
+
+// splitSubscriptions splits req into chunks of at most maxBytes, sharing a batch ID. A
+// subscription larger than maxBytes gets a chunk of its own. req is returned as-is when it
+// fits or maxBytes is 0.
+func splitSubscriptions(req *pb.SubscriptionReportRequest, maxBytes int) []*pb.SubscriptionReportRequest {
+	if maxBytes <= 0 || proto.Size(req) <= maxBytes {
+		return []*pb.SubscriptionReportRequest{req}
+	}
+
+	batch := make([]byte, 8)
+	rand.Read(batch)
+	batchID := hex.EncodeToString(batch)
+	// Headroom for the chunk fields, whatever the chunk count
+	overhead := proto.Size(&pb.SubscriptionReportRequest{Uuid: req.Uuid, BatchId: batchID, ChunkIndex: 1 << 30, ChunkCount: 1 << 30})
+
+	var chunks []*pb.SubscriptionReportRequest
+	var current *pb.SubscriptionReportRequest
+	size := 0
+	for _, subscription := range req.Subscriptions {
+		entry := protowire.SizeTag(2) + protowire.SizeBytes(proto.Size(subscription))
+		if current == nil || (len(current.Subscriptions) > 0 && size+entry > maxBytes) {
+			current = &pb.SubscriptionReportRequest{Uuid: req.Uuid, BatchId: batchID}
+			chunks = append(chunks, current)
+			size = overhead
+		}
+		current.Subscriptions = append(current.Subscriptions, subscription)
+		size += entry
+	}
+	for i, chunk := range chunks {
+		chunk.ChunkIndex, chunk.ChunkCount = int32(i), int32(len(chunks))
+	}
+	return chunks
+}
@@ -0,0 +1,283 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+func TestParseCompression(t *testing.T) {
+	compression, err := ParseCompression("")
+	require.NoError(t, err)
+	assert.Equal(t, CompressionGzip, compression)
+	compression, err = ParseCompression(" None ")
+	require.NoError(t, err)
+	assert.Equal(t, CompressionNone, compression)
+	_, err = ParseCompression("zstd")
+	assert.ErrorContains(t, err, `unknown report_compression "zstd"`)
+}
+
+// testSubscriptions returns count subscriptions with a node config of size bytes each
+func testSubscriptions(count, size int) []SubscriptionData {
+	subscriptions := make([]SubscriptionData, count)
+	for i := range subscriptions {
+		subscriptions[i] = SubscriptionData{SubID: fmt.Sprintf("sub-%d", i), Email: fmt.Sprintf("user%d@example.com", i), NodeConfig: strings.Repeat("A", size)}
+	}
+	return subscriptions
+}
+
+func TestSplitSubscriptions(t *testing.T) {
+	req := &pb.SubscriptionReportRequest{Uuid: "u", Subscriptions: convertSubscriptions(testSubscriptions(10, 1000))}
+	assert.Equal(t, []*pb.SubscriptionReportRequest{req}, splitSubscriptions(req, 0), "0 never splits")
+	assert.Equal(t, []*pb.SubscriptionReportRequest{req}, splitSubscriptions(req, proto.Size(req)), "fits")
+
+	chunks := splitSubscriptions(req, 3500)
+	require.Len(t, chunks, 4)
+	var ids []string
+	for i, chunk := range chunks {
+		assert.LessOrEqual(t, proto.Size(chunk), 3500)
+		assert.Equal(t, "u", chunk.Uuid)
+		assert.Equal(t, chunks[0].BatchId, chunk.BatchId)
+		assert.Equal(t, int32(i), chunk.ChunkIndex)
+		assert.Equal(t, int32(4), chunk.ChunkCount)
+		for _, subscription := range chunk.Subscriptions {
+			ids = append(ids, subscription.SubId)
+		}
+	}
+	assert.NotEmpty(t, chunks[0].BatchId)
+	assert.Equal(t, "sub-0", ids[0])
+	assert.Equal(t, "sub-9", ids[9])
+	assert.Len(t, ids, 10, "every subscription is sent once, in order")
+
+	// A subscription over the limit gets a chunk of its own
+	chunks = splitSubscriptions(req, 500)
+	assert.Len(t, chunks, 10)
+}
+
+func TestBandwidthLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newBandwidthLimiter(60 << 10) // 1 KiB per second
+	limiter.now = func() time.Time { return now }
+	limiter.last = now
+
+	assert.Zero(t, limiter.delay(), "starts full")
+	limiter.consume(70 << 10)
+	assert.Equal(t, 10*time.Second, limiter.delay(), "the debt is paid back at the refill rate")
+
+	now = now.Add(4 * time.Second)
+	assert.Equal(t, 6*time.Second, limiter.delay())
+	now = now.Add(time.Hour)
+	assert.Zero(t, limiter.delay())
+	limiter.consume(60 << 10)
+	assert.Zero(t, limiter.delay(), "the bucket holds a minute worth")
+	limiter.consume(1 << 10)
+	assert.Equal(t, time.Second, limiter.delay())
+}
+
+// encodingKey carries the request encoding recorded by encodingStats
+type encodingKey struct{}
+
+// encodingStats records the grpc-encoding of incoming requests
+type encodingStats struct{}
+
+func (encodingStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, encodingKey{}, new(string))
+}
+
+func (encodingStats) HandleRPC(ctx context.Context, rs stats.RPCStats) {
+	if header, ok := rs.(*stats.InHeader); ok {
+		*ctx.Value(encodingKey{}).(*string) = header.Compression
+	}
+}
+
+func (encodingStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (encodingStats) HandleConn(context.Context, stats.ConnStats)                       {}
+
+// budgetServer records the subscription chunks and request encodings it receives. Without
+// gzip it answers compressed requests like a server lacking the decompressor.
+type budgetServer struct {
+	pb.UnimplementedReportServiceServer
+	noGzip bool
+
+	mutex     sync.Mutex
+	chunks    []*pb.SubscriptionReportRequest
+	encodings []string
+}
+
+func (s *budgetServer) record(ctx context.Context) error {
+	encoding := *ctx.Value(encodingKey{}).(*string)
+	if s.noGzip && encoding == "gzip" {
+		return status.Errorf(codes.Unimplemented, "grpc: Decompressor is not installed for grpc-encoding %q", encoding)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.encodings = append(s.encodings, encoding)
+	return nil
+}
+
+func (s *budgetServer) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	if err := s.record(ctx); err != nil {
+		return nil, err
+	}
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *budgetServer) SendSubscriptionReport(ctx context.Context, req *pb.SubscriptionReportRequest) (*pb.ReportResponse, error) {
+	if err := s.record(ctx); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.chunks = append(s.chunks, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func newBudgetClient(t *testing.T, server *budgetServer) *ReportClient {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.StatsHandler(encodingStats{}))
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestReportClient_Compression(t *testing.T) {
+	server := &budgetServer{}
+	client := newBudgetClient(t, server)
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+	client.SetCompression(CompressionGzip)
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Equal(t, []string{"", "gzip"}, server.encodings)
+}
+
+func TestReportClient_CompressionThreshold(t *testing.T) {
+	server := &budgetServer{}
+	client := newBudgetClient(t, server)
+	client.SetCompression(CompressionGzip)
+	client.SetCompressionThreshold(1 << 10)
+
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+	require.NoError(t, client.SendSubscriptionReport("test-uuid", testSubscriptions(4, 1000)))
+	assert.Equal(t, []string{"", "gzip"}, server.encodings, "only the large subscription report is compressed")
+}
+
+func TestReportClient_CompressionUnsupported(t *testing.T) {
+	server := &budgetServer{noGzip: true}
+	client := newBudgetClient(t, server)
+	client.SetCompression(CompressionGzip)
+
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}), "resent uncompressed")
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Equal(t, []string{"", ""}, server.encodings)
+	assert.True(t, client.compression.unsupported.Load())
+}
+
+func TestReportClient_SubscriptionChunks(t *testing.T) {
+	server := &budgetServer{}
+	client := newBudgetClient(t, server)
+	client.SetSubscriptionChunkSize(5 << 10) // Four subscriptions per chunk
+
+	require.NoError(t, client.SendSubscriptionReport("test-uuid", testSubscriptions(20, 1000)))
+	require.Len(t, server.chunks, 5)
+	total := 0
+	for i, chunk := range server.chunks {
+		assert.Equal(t, int32(i), chunk.ChunkIndex)
+		assert.Equal(t, int32(5), chunk.ChunkCount)
+		assert.Equal(t, server.chunks[0].BatchId, chunk.BatchId)
+		total += len(chunk.Subscriptions)
+	}
+	assert.Equal(t, 20, total)
+
+	// Small reports are not split
+	require.NoError(t, client.SendSubscriptionReport("test-uuid", testSubscriptions(2, 1000)))
+	require.Len(t, server.chunks, 6)
+	assert.Zero(t, server.chunks[5].ChunkCount)
+	assert.Empty(t, server.chunks[5].BatchId)
+}
+
+func TestReportClient_SubscriptionChunksQueued(t *testing.T) {
+	server := &outageServer{}
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, server)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	queue, err := OpenQueue(t.TempDir(), 16<<20)
+	require.NoError(t, err)
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	defer client.Close()
+	client.SetOfflineQueue(queue)
+	client.SetSubscriptionChunkSize(5 << 10) // Four subscriptions per chunk
+
+	// The whole batch is queued when xhub is down, chunks do not replace each other
+	server.down.Store(true)
+	require.Error(t, client.SendSubscriptionReport("test-uuid", testSubscriptions(20, 1000)))
+	assert.Equal(t, 5, queue.Len())
+
+	server.down.Store(false)
+	require.NoError(t, client.FlushQueue(context.Background()))
+	require.Len(t, server.delivered, 5)
+	for i, delivered := range server.delivered {
+		assert.Equal(t, int32(i), delivered.(*pb.SubscriptionReportRequest).ChunkIndex)
+	}
+
+	// A new batch replaces the queued one
+	server.down.Store(true)
+	require.Error(t, client.SendSubscriptionReport("test-uuid", testSubscriptions(20, 1000)))
+	require.Error(t, client.SendSubscriptionReport("test-uuid", testSubscriptions(10, 1000)))
+	assert.Equal(t, 3, queue.Len())
+}
+
+func TestReportClient_BandwidthLimit(t *testing.T) {
+	server := &budgetServer{}
+	client := newBudgetClient(t, server)
+	client.SetBandwidthLimit(60 << 10)
+
+	// Sent payloads are charged to the limit
+	require.NoError(t, client.SendSubscriptionReport("test-uuid", testSubscriptions(10, 1000)))
+	client.bandwidth.mutex.Lock()
+	tokens := client.bandwidth.tokens
+	client.bandwidth.mutex.Unlock()
+	assert.Less(t, tokens, float64(50<<10))
+
+	// A short debt is waited out
+	client.bandwidth.consume(int(tokens) + 100)
+	start := time.Now()
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Greater(t, time.Since(start), 50*time.Millisecond)
+
+	// A debt longer than the request timeout fails the request
+	client.bandwidth.consume(60 << 10)
+	err := client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Len(t, server.encodings, 2)
+}
+
+func TestConvertSubscriptions_JSONConfig(t *testing.T) {
+	subscriptions := []SubscriptionData{{SubID: "sub1", NodeConfig: "dmxlc3M6Ly9ub2Rl", JSONConfig: `{"outbounds":[]}`}}
+	converted := convertSubscriptions(subscriptions)
+	require.Len(t, converted, 1)
+	assert.Equal(t, `{"outbounds":[]}`, converted[0].JsonConfig)
+}
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"time"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// Default timeouts of the RPCs to xhub
+const (
+	DefaultReportTimeout       = 30 * time.Second
+	DefaultSubscriptionTimeout = 30 * time.Second
+	DefaultConnectTimeout      = 10 * time.Second
+)
+
+// Timeouts bounds the RPCs to xhub. Zero fields select the defaults.
+type Timeouts struct {
+	Report       time.Duration // Each unary RPC except the subscription reports (report_timeout)
+	Subscription time.Duration // Each subscription report request or chunk (subscription_timeout)
+	Connect      time.Duration // Establishing a connection, including TLS and proxy (connect_timeout)
+}
+
+// SetTimeouts sets the RPC timeouts. The connect timeout applies to new connections.
+func (r *ReportClient) SetTimeouts(timeouts Timeouts) {
+	r.timeouts = timeouts
+}
+
+// reportTimeout returns the timeout of a unary RPC other than a subscription report
+func (r *ReportClient) reportTimeout() time.Duration {
+	if r.timeouts.Report > 0 {
+		return r.timeouts.Report
+	}
+	return DefaultReportTimeout
+}
+
+// subscriptionTimeout returns the timeout of a subscription report request
+func (r *ReportClient) subscriptionTimeout() time.Duration {
+	if r.timeouts.Subscription > 0 {
+		return r.timeouts.Subscription
+	}
+	return DefaultSubscriptionTimeout
+}
+
+// connectTimeout returns the time allowed to establish a connection
+func (r *ReportClient) connectTimeout() time.Duration {
+	if r.timeouts.Connect > 0 {
+		return r.timeouts.Connect
+	}
+	return DefaultConnectTimeout
+}
+
+// methodTimeout returns the timeout of a unary RPC by its full method name
+func (r *ReportClient) methodTimeout(method string) time.Duration {
+	if method == pb.ReportService_SendSubscriptionReport_FullMethodName {
+		return r.subscriptionTimeout()
+	}
+	return r.reportTimeout()
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// slowServer answers the reports after delay
+type slowServer struct {
+	pb.UnimplementedReportServiceServer
+	delay time.Duration
+}
+
+func (s *slowServer) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *slowServer) SendSubscriptionReport(ctx context.Context, req *pb.SubscriptionReportRequest) (*pb.ReportResponse, error) {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestReportClient_Timeouts(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterReportServiceServer(s, &slowServer{delay: 300 * time.Millisecond})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	client := NewReportClient(lis.Addr().String(), "test-key", createTestLogger(t))
+	t.Cleanup(func() { client.Close() })
+	client.SetTimeouts(Timeouts{Report: 100 * time.Millisecond, Subscription: 5 * time.Second})
+
+	err = client.SendReport("test-uuid", combinedTestData())
+	require.Error(t, err, "the report outlives report_timeout")
+	assert.Contains(t, err.Error(), "deadline exceeded")
+
+	assert.NoError(t, client.SendSubscriptionReport("test-uuid", testSubscriptions(1, 10)), "subscriptions use subscription_timeout")
+}
+
+func TestReportClient_DefaultTimeouts(t *testing.T) {
+	client := NewReportClient("localhost:9090", "test-key", createTestLogger(t))
+	assert.Equal(t, DefaultReportTimeout, client.reportTimeout())
+	assert.Equal(t, DefaultSubscriptionTimeout, client.subscriptionTimeout())
+	assert.Equal(t, DefaultConnectTimeout, client.connectTimeout())
+
+	client.SetTimeouts(Timeouts{Report: time.Second, Subscription: 2 * time.Second})
+	assert.Equal(t, 2*time.Second, client.methodTimeout(pb.ReportService_SendSubscriptionReport_FullMethodName))
+	assert.Equal(t, time.Second, client.methodTimeout(pb.ReportService_SendReport_FullMethodName))
+	assert.Equal(t, DefaultConnectTimeout, client.connectTimeout())
+}
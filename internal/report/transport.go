@@ -0,0 +1,164 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// TransportKind selects how report RPCs reach xhub (transport)
+type TransportKind string
+
+const (
+	TransportGRPC  TransportKind = "grpc"  // gRPC over HTTP/2
+	TransportHTTPS TransportKind = "https" // JSON payloads posted to the REST endpoints of xhub_https_url
+)
+
+// FailoverPeriod is how long RPCs stay on the other transport after the configured one was
+// unreachable, before the configured one is tried again
+const FailoverPeriod = 10 * time.Minute
+
+// ParseTransport parses a transport value (empty means grpc)
+func ParseTransport(value string) (TransportKind, error) {
+	switch kind := TransportKind(strings.ToLower(strings.TrimSpace(value))); kind {
+	case "":
+		return TransportGRPC, nil
+	case TransportGRPC, TransportHTTPS:
+		return kind, nil
+	default:
+		return "", fmt.Errorf("unknown transport %q (known: grpc, https)", value)
+	}
+}
+
+// Transport carries unary report RPCs to xhub. Requests and replies are the protobuf
+// messages of ReportService; method is the full gRPC method name.
+type Transport interface {
+	Name() string
+	Invoke(ctx context.Context, method string, req, reply interface{}, opts ...grpc.CallOption) error
+}
+
+// grpcTransport sends an RPC on the gRPC connection, as the last step of its interceptor chain
+type grpcTransport struct {
+	cc      *grpc.ClientConn
+	invoker grpc.UnaryInvoker
+}
+
+func (t grpcTransport) Name() string {
+	return "gRPC"
+}
+
+func (t grpcTransport) Invoke(ctx context.Context, method string, req, reply interface{}, opts ...grpc.CallOption) error {
+	return t.invoker(ctx, method, req, reply, t.cc, opts...)
+}
+
+// transportState is the transport selection (transport, xhub_https_url)
+type transportState struct {
+	primary TransportKind
+	https   *httpsTransport // nil without xhub_https_url, no failover then
+
+	mutex         sync.Mutex
+	fallbackUntil time.Time // RPCs use the other transport until then
+}
+
+// SetTransport selects the transport of unary RPCs. With an HTTPS base URL, an RPC whose
+// transport is unreachable is sent on the other one, which is then used for FailoverPeriod.
+// Streams (report_stream, commands) need gRPC.
+func (r *ReportClient) SetTransport(kind TransportKind, httpsURL string) error {
+	r.transport = transportState{primary: kind}
+	if httpsURL == "" {
+		if kind == TransportHTTPS {
+			return fmt.Errorf("the https transport needs xhub_https_url")
+		}
+		return nil
+	}
+	base, err := url.Parse(strings.TrimSpace(httpsURL))
+	if err != nil {
+		return fmt.Errorf("invalid xhub_https_url: %w", err)
+	}
+	switch {
+	case base.Host == "":
+		return fmt.Errorf("invalid xhub_https_url %q: missing host", httpsURL)
+	case base.Scheme == "https":
+	case base.Scheme == "http" && isLocalServer(base.Host):
+		// Plain HTTP is only allowed for local development, like disabling gRPC TLS
+	default:
+		return fmt.Errorf("invalid xhub_https_url %q: expected an https:// URL", httpsURL)
+	}
+	r.transport.https = &httpsTransport{base: base}
+	return nil
+}
+
+// ActiveTransport returns the transport the next RPC is sent on
+func (r *ReportClient) ActiveTransport() TransportKind {
+	if r.transport.usesHTTPS() {
+		return TransportHTTPS
+	}
+	return TransportGRPC
+}
+
+// failedOver reports whether RPCs currently go to the other transport
+func (t *transportState) failedOver() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return time.Now().Before(t.fallbackUntil)
+}
+
+// setFailedOver switches RPCs to the other transport for FailoverPeriod, or back
+func (t *transportState) setFailedOver(failedOver bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.fallbackUntil = time.Time{}
+	if failedOver {
+		t.fallbackUntil = time.Now().Add(FailoverPeriod)
+	}
+}
+
+// usesHTTPS reports whether the next RPC is sent on the HTTPS transport
+func (t *transportState) usesHTTPS() bool {
+	if t.https == nil {
+		return false
+	}
+	return (t.primary == TransportHTTPS) != t.failedOver()
+}
+
+// routeTransport is a unary interceptor sending RPCs on the active transport, failing over
+// to the other one when it is unreachable. It runs after the stream and capability handling
+// so that both transports share the rest of the chain (queueing, retries, metrics).
+func (r *ReportClient) routeTransport(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if r.transport.https == nil {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	var primary, fallback Transport = grpcTransport{cc: cc, invoker: invoker}, r.httpsTransport()
+	if r.transport.primary == TransportHTTPS {
+		primary, fallback = fallback, primary
+	}
+	failedOver := r.transport.failedOver()
+	first, second := primary, fallback
+	if failedOver {
+		first, second = fallback, primary
+	}
+
+	err := first.Invoke(ctx, method, req, reply, opts...)
+	if !isOutage(err) || ctx.Err() != nil {
+		return err
+	}
+	secondErr := second.Invoke(ctx, method, req, reply, opts...)
+	if isOutage(secondErr) {
+		return err
+	}
+	if failedOver {
+		r.transport.setFailedOver(false)
+		r.logger.Infof("✅ %s transport reachable again, leaving %s", second.Name(), first.Name())
+	} else {
+		r.transport.setFailedOver(true)
+		r.logger.Warnf("⚠️  %s transport unreachable (%v), using %s for %v", first.Name(), err, second.Name(), FailoverPeriod)
+	}
+	return secondErr
+}
@@ -0,0 +1,182 @@
+package report
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"xhub-agent/internal/monitor"
+	pb "xhub-agent/proto/reportpb"
+)
+
+func TestParseTransport(t *testing.T) {
+	kind, err := ParseTransport("")
+	require.NoError(t, err)
+	assert.Equal(t, TransportGRPC, kind)
+	kind, err = ParseTransport(" HTTPS ")
+	require.NoError(t, err)
+	assert.Equal(t, TransportHTTPS, kind)
+	_, err = ParseTransport("websocket")
+	assert.ErrorContains(t, err, `unknown transport "websocket"`)
+}
+
+func TestReportClient_SetTransport(t *testing.T) {
+	client := NewReportClient("xhub.example.com:9090", "test-key", createTestLogger(t))
+	assert.ErrorContains(t, client.SetTransport(TransportHTTPS, ""), "needs xhub_https_url")
+	assert.ErrorContains(t, client.SetTransport(TransportGRPC, "http://xhub.example.com"), "expected an https:// URL")
+	assert.ErrorContains(t, client.SetTransport(TransportGRPC, "https://"), "missing host")
+	require.NoError(t, client.SetTransport(TransportGRPC, "http://127.0.0.1:8080/api"), "plain HTTP for local development")
+	require.NoError(t, client.SetTransport(TransportHTTPS, "https://xhub.example.com/api"))
+	assert.Equal(t, TransportHTTPS, client.ActiveTransport())
+	assert.Equal(t, "https", client.GetSecurityInfo()["transport"])
+}
+
+func TestHTTPStatusError(t *testing.T) {
+	err := httpStatusError(http.StatusNotFound, []byte(`{"code": 5, "message": "unknown agent"}`))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "unknown agent", status.Convert(err).Message())
+
+	for statusCode, code := range map[int]codes.Code{
+		http.StatusBadRequest:          codes.InvalidArgument,
+		http.StatusUnauthorized:        codes.Unauthenticated,
+		http.StatusForbidden:           codes.PermissionDenied,
+		http.StatusTooManyRequests:     codes.ResourceExhausted,
+		http.StatusNotImplemented:      codes.Unimplemented,
+		http.StatusServiceUnavailable:  codes.Unavailable,
+		http.StatusInternalServerError: codes.Internal,
+		http.StatusTeapot:              codes.Unknown,
+	} {
+		assert.Equal(t, code, status.Code(httpStatusError(statusCode, []byte("<html>"))), statusCode)
+	}
+}
+
+// restServer is an xhub HTTPS/JSON endpoint recording the status reports it receives
+type restServer struct {
+	down bool // Answer 503
+
+	mutex   sync.Mutex
+	reports []*pb.ReportRequest
+	auth    []string
+}
+
+func (s *restServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if req.Method != http.MethodPost || req.URL.Path != "/api"+pb.ReportService_SendReport_FullMethodName {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	report := &pb.ReportRequest{}
+	if err := protojson.Unmarshal(body, report); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.mutex.Lock()
+	s.reports = append(s.reports, report)
+	s.auth = append(s.auth, req.Header.Get("Authorization"))
+	s.mutex.Unlock()
+
+	w.Header().Set("X-Xhub-Capabilities", CombinedCapability)
+	w.Header().Set("Content-Type", "application/json")
+	out, _ := protojson.Marshal(&pb.ReportResponse{Success: true, Message: "ok"})
+	w.Write(out)
+}
+
+func (s *restServer) received() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.reports)
+}
+
+// unreachableAddr returns a local address nothing listens on
+func unreachableAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+	return addr
+}
+
+func TestReportClient_HTTPSTransport(t *testing.T) {
+	rest := &restServer{}
+	server := httptest.NewServer(rest)
+	defer server.Close()
+
+	client := NewReportClient(unreachableAddr(t), "test-key", createTestLogger(t))
+	defer client.Close()
+	client.SetCombinedReports(true)
+	require.NoError(t, client.SetTransport(TransportHTTPS, server.URL+"/api"))
+
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 42.5}))
+	require.Equal(t, 1, rest.received())
+	assert.Equal(t, "test-uuid", rest.reports[0].Uuid)
+	assert.Equal(t, 42.5, rest.reports[0].Data.Cpu)
+	assert.Equal(t, "Bearer test-key", rest.auth[0])
+	assert.True(t, client.CombinedReportsAvailable(), "capabilities are read from the HTTP headers")
+}
+
+func TestReportClient_TransportFailover(t *testing.T) {
+	rest := &restServer{}
+	server := httptest.NewServer(rest)
+	defer server.Close()
+
+	// gRPC is unreachable: the report goes to HTTPS, which is then used directly
+	client := NewReportClient(unreachableAddr(t), "test-key", createTestLogger(t))
+	defer client.Close()
+	require.NoError(t, client.SetTransport(TransportGRPC, server.URL+"/api"))
+	assert.Equal(t, TransportGRPC, client.ActiveTransport())
+
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Equal(t, 1, rest.received())
+	assert.Equal(t, TransportHTTPS, client.ActiveTransport())
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Equal(t, 2, rest.received())
+
+	// After the failover period gRPC is tried again first
+	client.transport.fallbackUntil = time.Now().Add(-time.Second)
+	assert.Equal(t, TransportGRPC, client.ActiveTransport())
+
+	// Both unreachable: the error of the active transport is returned
+	rest.down = true
+	err := client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, TransportGRPC, client.ActiveTransport())
+}
+
+func TestReportClient_TransportFailoverToGRPC(t *testing.T) {
+	mock := &mockReportServer{}
+	addr, cleanup := setupGRPCTestServer(t, mock)
+	defer cleanup()
+	rest := &restServer{down: true}
+	server := httptest.NewServer(rest)
+	defer server.Close()
+
+	client := NewReportClient(addr, "test-key", createTestLogger(t))
+	defer client.Close()
+	require.NoError(t, client.SetTransport(TransportHTTPS, server.URL+"/api"))
+
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Len(t, mock.receivedRequests, 1)
+	assert.Equal(t, TransportGRPC, client.ActiveTransport())
+
+	// Back on HTTPS once it works again
+	rest.down = false
+	client.transport.fallbackUntil = time.Now().Add(-time.Second)
+	require.NoError(t, client.SendReport("test-uuid", &monitor.ServerStatusData{CPU: 10.0}))
+	assert.Equal(t, 1, rest.received())
+	assert.Len(t, mock.receivedRequests, 1)
+}
@@ -0,0 +1,55 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrReportDisabled is returned for a report whose type is turned off in the config
+// (report_status, report_subscriptions, report_online_users); nothing is sent
+var ErrReportDisabled = errors.New("report type disabled")
+
+// ReportTypes selects the data flows sent to xhub
+type ReportTypes struct {
+	Status        bool // Status reports, also the host fallback and the status of combined reports
+	Subscriptions bool // Subscription reports
+	OnlineUsers   bool // Online users reports
+}
+
+// AllReportTypes enables every data flow (default)
+func AllReportTypes() ReportTypes {
+	return ReportTypes{Status: true, Subscriptions: true, OnlineUsers: true}
+}
+
+// Disabled returns the names of the report types turned off
+func (t ReportTypes) Disabled() []string {
+	var disabled []string
+	if !t.Status {
+		disabled = append(disabled, "status")
+	}
+	if !t.Subscriptions {
+		disabled = append(disabled, "subscriptions")
+	}
+	if !t.OnlineUsers {
+		disabled = append(disabled, "online users")
+	}
+	return disabled
+}
+
+// SetReportTypes selects the report types sent; reports of the others return ErrReportDisabled
+func (r *ReportClient) SetReportTypes(types ReportTypes) {
+	r.types = types
+}
+
+// ReportTypes returns the report types sent
+func (r *ReportClient) ReportTypes() ReportTypes {
+	return r.types
+}
+
+// checkEnabled returns ErrReportDisabled unless the report type is enabled
+func checkEnabled(enabled bool, name string) error {
+	if enabled {
+		return nil
+	}
+	return fmt.Errorf("%s report: %w", name, ErrReportDisabled)
+}
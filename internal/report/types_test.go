@@ -0,0 +1,27 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+)
+
+func TestReportClient_ReportTypes(t *testing.T) {
+	client := NewReportClient("localhost:1", "test-key", createTestLogger(t))
+	assert.Equal(t, AllReportTypes(), client.ReportTypes())
+	assert.Empty(t, client.ReportTypes().Disabled())
+
+	client.SetReportTypes(ReportTypes{Subscriptions: true})
+	assert.Equal(t, []string{"status", "online users"}, client.ReportTypes().Disabled())
+
+	// Disabled reports fail before any connection attempt
+	err := client.SendReport("test-uuid", &monitor.ServerStatusData{})
+	require.ErrorIs(t, err, ErrReportDisabled)
+	assert.EqualError(t, err, "status report: report type disabled")
+	assert.ErrorIs(t, client.SendOnlineUsersReport("test-uuid", []string{"alice@example.com"}), ErrReportDisabled)
+	assert.ErrorIs(t, client.SendCombinedReport("test-uuid", &monitor.ServerStatusData{}, nil, nil), ErrReportDisabled)
+	assert.False(t, client.isConnected)
+}
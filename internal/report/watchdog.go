@@ -0,0 +1,56 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/sanitize"
+	"xhub-agent/internal/watchdog"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// ErrPanelRestartUnsupported is returned when xhub does not implement panel restart reports
+var ErrPanelRestartUnsupported = errors.New("xhub does not support panel restart reports")
+
+// SendPanelRestartReport reports a restart of the 3x-ui service by the panel watchdog to
+// xhub. It returns ErrPanelRestartUnsupported if xhub does not implement the RPC.
+func (r *ReportClient) SendPanelRestartReport(uuid string, restart watchdog.Restart, maxRestarts int) error {
+	if err := r.Connect(); err != nil {
+		return r.withConnectionHint(fmt.Errorf("failed to establish gRPC connection: %w", err))
+	}
+
+	req := &pb.PanelRestartReport{
+		Uuid:        uuid,
+		Service:     restart.Service,
+		Failures:    sanitize.Int32(restart.Failures),
+		LastError:   sanitize.String(restart.LastError),
+		Attempt:     sanitize.Int32(restart.Attempt),
+		MaxRestarts: sanitize.Int32(maxRestarts),
+		Success:     restart.Error == "",
+		Error:       sanitize.String(restart.Error),
+		StartedAt:   restart.StartedAt.Unix(),
+		DurationMs:  restart.Duration.Milliseconds(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.reportTimeout())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, r.outgoingMetadata())
+
+	resp, err := r.client.SendPanelRestartReport(ctx, req, r.callOptions()...)
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return ErrPanelRestartUnsupported
+		}
+		return r.withConnectionHint(fmt.Errorf("gRPC panel restart report failed: %w", err))
+	}
+	if !resp.Success {
+		return rejectedf("panel restart report rejected: %s", resp.Message)
+	}
+	r.markSuccess("面板重启上报")
+	return nil
+}
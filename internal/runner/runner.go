@@ -0,0 +1,26 @@
+package runner
+
+import (
+	"context"
+	"os/exec"
+)
+
+// MaxOutput is the tail of a command output kept in errors and results
+const MaxOutput = 4 << 10
+
+// Func runs a command and returns its combined output. The watchdogs and the certificate
+// renewal hold one so that tests can replace the commands they run.
+type Func func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// Run runs name and returns its combined output
+func Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// Tail returns the last max bytes of output
+func Tail(output string, max int) string {
+	if len(output) > max {
+		return output[len(output)-max:]
+	}
+	return output
+}
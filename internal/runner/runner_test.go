@@ -0,0 +1,28 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	out, err := Run(context.Background(), "sh", "-c", "echo out; echo err >&2")
+	require.NoError(t, err)
+	assert.Equal(t, "out\nerr\n", string(out))
+
+	_, err = Run(context.Background(), "sh", "-c", "exit 3")
+	assert.Error(t, err)
+}
+
+func TestTail(t *testing.T) {
+	assert.Equal(t, "short", Tail("short", MaxOutput))
+
+	long := strings.Repeat("x", 2*MaxOutput) + "end"
+	out := Tail(long, MaxOutput)
+	assert.Len(t, out, MaxOutput)
+	assert.True(t, strings.HasSuffix(out, "end"))
+}
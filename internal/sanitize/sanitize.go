@@ -0,0 +1,121 @@
+// Package sanitize holds the limits applied to untrusted panel and config input.
+//
+// Guarantees for data passed onward (logs, proto conversion, subscription reports):
+//   - panel responses larger than MaxResponseSize are rejected before decoding
+//   - JSON nested deeper than MaxJSONDepth is rejected before decoding
+//   - every string taken from panel output is valid UTF-8 (invalid bytes become U+FFFD)
+//   - integers narrowed for the proto (int32 fields) are clamped instead of wrapping
+package sanitize
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxResponseSize caps a single 3x-ui response body (8 MiB)
+	MaxResponseSize = 8 << 20
+	// MaxJSONDepth caps object/array nesting of panel JSON
+	MaxJSONDepth = 64
+	// MaxHeaderLength caps subscription response header values passed onward
+	MaxHeaderLength = 4096
+)
+
+// ReadLimited reads r fully, failing if it exceeds limit bytes
+func ReadLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("response exceeds %d bytes", limit)
+	}
+	return data, nil
+}
+
+// CheckJSON rejects input over MaxResponseSize or nested deeper than MaxJSONDepth.
+// It does not validate the JSON itself, the decoder does that afterwards.
+func CheckJSON(data []byte) error {
+	if len(data) > MaxResponseSize {
+		return fmt.Errorf("JSON exceeds %d bytes", MaxResponseSize)
+	}
+
+	depth := 0
+	inString := false
+	escaped := false
+	for _, b := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch b {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > MaxJSONDepth {
+				return fmt.Errorf("JSON nested deeper than %d levels", MaxJSONDepth)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
+
+// String returns s as valid UTF-8, replacing invalid bytes with U+FFFD
+func String(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	return strings.ToValidUTF8(s, "\uFFFD")
+}
+
+// Strings returns a copy of ss with every element sanitized
+func Strings(ss []string) []string {
+	if ss == nil {
+		return nil
+	}
+	result := make([]string, len(ss))
+	for i, s := range ss {
+		result[i] = String(s)
+	}
+	return result
+}
+
+// Header sanitizes a header value and caps its length
+func Header(s string) string {
+	s = String(s)
+	if len(s) <= MaxHeaderLength {
+		return s
+	}
+	// Cut on a rune boundary
+	cut := MaxHeaderLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// Int32 converts v to int32, clamping out-of-range values
+func Int32(v int) int32 {
+	switch {
+	case v > math.MaxInt32:
+		return math.MaxInt32
+	case v < math.MinInt32:
+		return math.MinInt32
+	default:
+		return int32(v)
+	}
+}
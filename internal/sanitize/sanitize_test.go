@@ -0,0 +1,56 @@
+package sanitize
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLimited(t *testing.T) {
+	data, err := ReadLimited(strings.NewReader("hello"), 5)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	_, err = ReadLimited(strings.NewReader("hello!"), 5)
+	assert.Error(t, err)
+}
+
+func TestCheckJSON(t *testing.T) {
+	assert.NoError(t, CheckJSON([]byte(`{"obj":{"a":[1,2,{"b":"{{{{["}]}}`)))
+
+	deep := strings.Repeat("[", MaxJSONDepth+1) + strings.Repeat("]", MaxJSONDepth+1)
+	assert.Error(t, CheckJSON([]byte(deep)))
+
+	// Brackets inside strings (including escaped quotes) don't count
+	inString := `{"s":"` + strings.Repeat(`\"[`, 200) + `"}`
+	assert.NoError(t, CheckJSON([]byte(inString)))
+
+	assert.Error(t, CheckJSON(bytes.Repeat([]byte(" "), MaxResponseSize+1)))
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "user@example.com", String("user@example.com"))
+	assert.Equal(t, "bad�byte", String("bad\xffbyte"))
+	assert.True(t, utf8.ValidString(String("\xc3\x28\xa0\xa1")))
+	assert.Equal(t, []string{"ok", "�"}, Strings([]string{"ok", "\xff"}))
+}
+
+func TestHeader(t *testing.T) {
+	assert.Equal(t, "upload=1", Header("upload=1"))
+
+	long := strings.Repeat("é", MaxHeaderLength) // 2 bytes per rune
+	header := Header(long)
+	assert.LessOrEqual(t, len(header), MaxHeaderLength)
+	assert.True(t, utf8.ValidString(header))
+}
+
+func TestInt32(t *testing.T) {
+	assert.Equal(t, int32(42), Int32(42))
+	assert.Equal(t, int32(math.MaxInt32), Int32(math.MaxInt32+1))
+	assert.Equal(t, int32(math.MinInt32), Int32(math.MinInt32-1))
+}
@@ -0,0 +1,57 @@
+package selftest
+
+// Fixture is the panel data fed to the pipeline instead of the real 3x-ui responses
+type Fixture struct {
+	UUID         string              // Agent UUID of the requests
+	Status       string              // /server/status response body
+	Inbounds     string              // /panel/inbound/list response body
+	Content      map[string][]string // Node URIs served per SubID
+	Userinfo     map[string]string   // subscription-userinfo header per SubID
+	OnlineUsers  string              // /panel/inbound/onlines response body
+	Fail2banBans map[string]int      // Agent-side map section
+}
+
+// Fixture client identities and emails
+const (
+	fixtureAliceID   = "5c2a2f7e-7d1b-4c8a-9f3e-0b6d4e1a2c3f"
+	fixtureBobPass   = "selftest-trojan-password"
+	fixtureAlice     = "selftest-alice@example.invalid"
+	fixtureBob       = "selftest-bob@example.invalid"
+	fixtureExpiryMs  = "1893456000000" // 2030-01-01
+	fixtureExpireSec = "1893456000"
+)
+
+// DefaultFixture returns healthy fixture data: two inbounds with one client each
+func DefaultFixture() Fixture {
+	return Fixture{
+		UUID: "selftest",
+		Status: `{"success":true,"msg":"","obj":{
+			"cpu":12.5,"cpuCores":2,"logicalPro":2,"cpuSpeedMhz":2400,
+			"mem":{"current":536870912,"total":2147483648},
+			"swap":{"current":0,"total":0},
+			"disk":{"current":10737418240,"total":42949672960},
+			"uptime":86400,"loads":[0.1,0.2,0.3],"tcpCount":120,"udpCount":4,
+			"netIO":{"up":1024,"down":2048},"netTraffic":{"sent":1048576,"recv":2097152},
+			"publicIP":{"ipv4":"203.0.113.10","ipv6":"2001:db8::10"},
+			"xray":{"state":"running","errorMsg":"","version":"25.8.3"},
+			"appStats":{"threads":40,"mem":52428800,"uptime":3600}}}`,
+		Inbounds: `{"success":true,"msg":"","obj":[
+			{"id":1,"remark":"vless","enable":true,"port":443,"protocol":"vless",
+			 "settings":"{\"clients\":[{\"id\":\"` + fixtureAliceID + `\",\"email\":\"` + fixtureAlice + `\",\"subId\":\"selftest-a\",\"enable\":true,\"totalGB\":10737418240,\"expiryTime\":` + fixtureExpiryMs + `}]}"},
+			{"id":2,"remark":"trojan","enable":true,"port":8443,"protocol":"trojan",
+			 "settings":"{\"clients\":[{\"password\":\"` + fixtureBobPass + `\",\"email\":\"` + fixtureBob + `\",\"subId\":\"selftest-b\",\"enable\":true}]}"}]}`,
+		Content: map[string][]string{
+			"selftest-a": {"vless://" + fixtureAliceID + "@selftest.example.invalid:443?security=reality#alice"},
+			"selftest-b": {"trojan://" + fixtureBobPass + "@selftest.example.invalid:8443#bob"},
+		},
+		Userinfo: map[string]string{
+			"selftest-a": "upload=0; download=1024; total=10737418240; expire=" + fixtureExpireSec,
+			"selftest-b": "upload=0; download=0; total=0; expire=0",
+		},
+		OnlineUsers:  `{"success":true,"msg":"","obj":["` + fixtureAlice + `","` + fixtureBob + `"]}`,
+		Fail2banBans: map[string]int{"sshd": 3, "nginx-http-auth": 1, "recidive": 0},
+	}
+}
+
+// fixtureEmails are the emails of DefaultFixture, which must not reach xhub unmapped
+var fixtureEmails = []string{fixtureAlice, fixtureBob}
@@ -0,0 +1,147 @@
+package selftest
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// MaxMessageSize is the largest request xhub accepts (the gRPC default receive limit)
+const MaxMessageSize = 4 * 1024 * 1024
+
+// loopback is an in-process ReportService that validates the structural invariants of every
+// request instead of storing it. It always answers success so that the client takes the
+// same path as against xhub.
+type loopback struct {
+	pb.UnimplementedReportServiceServer
+
+	forbidden []string // Strings that must not appear in any request (unmapped fixture emails)
+
+	mutex      sync.Mutex
+	requests   map[string][][]byte // Deterministic encoding per RPC, in arrival order
+	violations []string
+}
+
+// newLoopback creates a loopback rejecting requests that contain any of forbidden
+func newLoopback(forbidden []string) *loopback {
+	return &loopback{forbidden: forbidden, requests: make(map[string][][]byte)}
+}
+
+func (l *loopback) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	var problems []string
+	if req.Data == nil {
+		problems = append(problems, "data is missing")
+	} else {
+		problems = append(problems, checkStatus(req.Data)...)
+	}
+	for i := 1; i < len(req.ErrorCounts); i++ {
+		if req.ErrorCounts[i-1].Category >= req.ErrorCounts[i].Category {
+			problems = append(problems, "error_counts are not ordered by category")
+			break
+		}
+	}
+	return l.record("SendReport", req, req.Uuid, problems)
+}
+
+func (l *loopback) SendSubscriptionReport(ctx context.Context, req *pb.SubscriptionReportRequest) (*pb.ReportResponse, error) {
+	var problems []string
+	if len(req.Subscriptions) == 0 {
+		problems = append(problems, "no subscriptions")
+	}
+	for i, sub := range req.Subscriptions {
+		switch {
+		case sub.SubId == "":
+			problems = append(problems, fmt.Sprintf("subscriptions[%d].sub_id is empty", i))
+		case sub.Headers == nil:
+			problems = append(problems, fmt.Sprintf("subscriptions[%d].headers are missing", i))
+		}
+		if _, err := base64.StdEncoding.DecodeString(sub.NodeConfig); err != nil || sub.NodeConfig == "" {
+			problems = append(problems, fmt.Sprintf("subscriptions[%d].node_config is not base64", i))
+		}
+		if sub.ContentStaleSuspect {
+			problems = append(problems, fmt.Sprintf("subscriptions[%d] flagged stale: %s", i, sub.StaleReason))
+		}
+		if i > 0 && req.Subscriptions[i-1].SubId >= sub.SubId {
+			problems = append(problems, "subscriptions are not ordered by sub_id")
+		}
+	}
+	return l.record("SendSubscriptionReport", req, req.Uuid, problems)
+}
+
+func (l *loopback) SendOnlineUsersReport(ctx context.Context, req *pb.OnlineUsersReportRequest) (*pb.ReportResponse, error) {
+	var problems []string
+	for i, email := range req.OnlineEmails {
+		if email == "" {
+			problems = append(problems, fmt.Sprintf("online_emails[%d] is empty", i))
+		}
+		if slices.Contains(req.OnlineEmails[:i], email) && email != "" {
+			problems = append(problems, fmt.Sprintf("online_emails[%d] is a duplicate", i))
+		}
+	}
+	return l.record("SendOnlineUsersReport", req, req.Uuid, problems)
+}
+
+// checkStatus validates the required fields and ordering of a status
+func checkStatus(data *pb.ServerStatusData) []string {
+	var problems []string
+	if data.CpuCores <= 0 {
+		problems = append(problems, "data.cpu_cores is not positive")
+	}
+	if data.Memory == nil || data.Memory.Total <= 0 {
+		problems = append(problems, "data.memory.total is not positive")
+	}
+	if data.Xray == nil || data.Xray.State == "" {
+		problems = append(problems, "data.xray.state is empty")
+	}
+	if data.PublicIp == nil || (data.PublicIp.Ipv4 == "" && data.PublicIp.Ipv6 == "") {
+		problems = append(problems, "data.public_ip is empty")
+	}
+	if len(data.InboundProtocols) == 0 {
+		problems = append(problems, "data.inbound_protocols is empty")
+	}
+	if !slices.IsSorted(data.InboundProtocols) || len(slices.Compact(slices.Clone(data.InboundProtocols))) != len(data.InboundProtocols) {
+		problems = append(problems, "data.inbound_protocols is not sorted and unique")
+	}
+	return problems
+}
+
+// record checks the invariants shared by every RPC and stores the request
+func (l *loopback) record(method string, req proto.Message, uuid string, problems []string) (*pb.ReportResponse, error) {
+	if uuid == "" {
+		problems = append(problems, "uuid is empty")
+	}
+	if size := proto.Size(req); size > MaxMessageSize {
+		problems = append(problems, fmt.Sprintf("request is %d bytes, over the %d byte limit", size, MaxMessageSize))
+	}
+	encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("request does not marshal: %v", err))
+	}
+	for _, forbidden := range l.forbidden {
+		if strings.Contains(string(encoded), forbidden) {
+			problems = append(problems, fmt.Sprintf("request contains the unmapped email %s", forbidden))
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.requests[method] = append(l.requests[method], encoded)
+	for _, problem := range problems {
+		l.violations = append(l.violations, method+": "+problem)
+	}
+	return &pb.ReportResponse{Success: true, Message: "selftest"}, nil
+}
+
+// result returns the recorded violations and requests
+func (l *loopback) result() ([]string, map[string][][]byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return slices.Clone(l.violations), l.requests
+}
@@ -0,0 +1,290 @@
+package selftest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/privacy"
+	"xhub-agent/internal/report"
+	"xhub-agent/internal/subscription"
+	"xhub-agent/pkg/logger"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// Collector registry name and default interval
+const (
+	CollectorName   = "selftest"
+	DefaultInterval = 24 * time.Hour
+)
+
+// maxFailures caps the failures kept per run (and reported in the status)
+const maxFailures = 10
+
+// Result is the outcome of one self-test run
+type Result struct {
+	Time     time.Time
+	Duration time.Duration
+	Passed   bool
+	Failures []string // Invariant violations and pipeline errors (capped)
+}
+
+// Stats self-test counters since agent start
+type Stats struct {
+	Runs       uint64
+	FailedRuns uint64
+	Last       Result // Zero before the first run
+}
+
+// Runner runs the collection-conversion-serialization pipeline against fixture data and an
+// in-process loopback ReportService, so that regressions show up without real traffic
+type Runner struct {
+	mapping       monitor.FieldMapping // Panel field mapping of the real monitor client
+	mapper        privacy.EmailMapper  // Email mapping of the real report client (nil for plain)
+	logger        *logger.Logger
+	errorCounters *errstats.Counters
+	fixture       Fixture
+	now           func() time.Time // injectable for tests
+
+	mutex   sync.Mutex // Serializes runs
+	stats   Stats
+	failing bool // The last run failed (error logged once per failure streak)
+}
+
+// NewRunner creates a runner using the same field and email mapping as the real reports.
+// mapper must not have side effects (e.g. a pseudonymizer over the persisted state).
+func NewRunner(mapping monitor.FieldMapping, mapper privacy.EmailMapper, logger *logger.Logger) *Runner {
+	return &Runner{
+		mapping: mapping,
+		mapper:  mapper,
+		logger:  logger,
+		fixture: DefaultFixture(),
+		now:     time.Now,
+	}
+}
+
+// SetErrorCounters records failed runs in the selftest error category
+func (r *Runner) SetErrorCounters(counters *errstats.Counters) {
+	r.errorCounters = counters
+}
+
+// SetFixtureForTesting replaces the fixture data (for testing only)
+func (r *Runner) SetFixtureForTesting(fixture Fixture) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.fixture = fixture
+}
+
+// SetClockForTesting replaces the time source (for testing only)
+func (r *Runner) SetClockForTesting(now func() time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.now = now
+}
+
+// Stats returns the counters and the last result
+func (r *Runner) Stats() Stats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stats := r.stats
+	stats.Last.Failures = slices.Clone(stats.Last.Failures)
+	return stats
+}
+
+// Status returns the telemetry section of the last run, nil before the first run
+func (r *Runner) Status() *monitor.SelfTestStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.stats.Runs == 0 {
+		return nil
+	}
+	return &monitor.SelfTestStatus{
+		LastRun:    r.stats.Last.Time.Unix(),
+		Passed:     r.stats.Last.Passed,
+		Failures:   slices.Clone(r.stats.Last.Failures),
+		Runs:       r.stats.Runs,
+		FailedRuns: r.stats.FailedRuns,
+	}
+}
+
+// Run runs the pipeline twice against the loopback and checks the invariants of every
+// request, plus that both runs produced identical requests (deterministic ordering)
+func (r *Runner) Run() Result {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	started := r.now()
+	failures := r.run()
+	if len(failures) > maxFailures {
+		failures = append(failures[:maxFailures], fmt.Sprintf("and %d more", len(failures)-maxFailures))
+	}
+	result := Result{
+		Time:     started,
+		Duration: r.now().Sub(started),
+		Passed:   len(failures) == 0,
+		Failures: failures,
+	}
+	r.record(result)
+	return result
+}
+
+// run runs the pipeline and returns the failures
+func (r *Runner) run() []string {
+	var forbidden []string
+	if r.mapper != nil {
+		forbidden = fixtureEmails
+	}
+	loop := newLoopback(forbidden)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return []string{fmt.Sprintf("loopback listener: %v", err)}
+	}
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(4 * MaxMessageSize)) // Oversize requests are reported, not refused
+	pb.RegisterReportServiceServer(server, loop)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	client := report.NewReportClient(listener.Addr().String(), "selftest", r.logger)
+	defer client.Close()
+	if r.mapper != nil {
+		client.SetEmailMapper(r.mapper)
+	}
+
+	var failures []string
+	for i := 0; i < 2; i++ {
+		failures = appendUnique(failures, r.pipeline(client)...)
+	}
+
+	violations, requests := loop.result()
+	failures = appendUnique(failures, violations...)
+	return append(failures, compareRuns(requests)...)
+}
+
+// compareRuns reports the RPCs whose requests differ between the two identical runs
+func compareRuns(requests map[string][][]byte) []string {
+	methods := make([]string, 0, len(requests))
+	for method := range requests {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	var failures []string
+	for _, method := range methods {
+		if encoded := requests[method]; len(encoded) == 2 && !bytes.Equal(encoded[0], encoded[1]) {
+			failures = append(failures, method+": request differs between identical runs (nondeterministic ordering)")
+		}
+	}
+	return failures
+}
+
+// pipeline decodes the fixture like the panel responses, converts it like a report cycle
+// and sends the three reports through client
+func (r *Runner) pipeline(client *report.ReportClient) []string {
+	fixture := r.fixture
+	var failures []string
+
+	status, err := monitor.DecodeServerStatus([]byte(fixture.Status), r.mapping)
+	if err != nil {
+		return []string{fmt.Sprintf("decode status: %v", err)}
+	}
+	inbounds, err := subscription.DecodeInboundList([]byte(fixture.Inbounds))
+	if err != nil {
+		return []string{fmt.Sprintf("decode inbound list: %v", err)}
+	}
+	data := status.Data
+	data.InboundProtocols = subscription.ExtractProtocols(inbounds)
+	if len(fixture.Fail2banBans) > 0 {
+		data.Fail2banBans = make(map[string]int, len(fixture.Fail2banBans))
+		for jail, count := range fixture.Fail2banBans {
+			data.Fail2banBans[jail] = count
+		}
+	}
+	if err := client.SendReport(fixture.UUID, data); err != nil {
+		failures = append(failures, fmt.Sprintf("SendReport: %v", err))
+	}
+
+	subscriptions, err := subscription.NewSubscriptionClient(nil, "", r.logger).ExtractUniqueSubIDs(inbounds)
+	if err != nil {
+		return append(failures, fmt.Sprintf("extract SubIDs: %v", err))
+	}
+	index := subscription.BuildClientIndex(inbounds)
+	var reportSubs []report.SubscriptionData
+	for _, sub := range subscriptions {
+		sub.NodeConfig = base64.StdEncoding.EncodeToString([]byte(strings.Join(fixture.Content[sub.SubID], "\n")))
+		sub.Headers.SubscriptionUserinfo = subscription.NormalizeUserinfo(fixture.Userinfo[sub.SubID])
+		if reason := index.CheckStale(sub); reason != "" {
+			sub.StaleSuspect, sub.StaleReason = true, reason
+		}
+		reportSubs = append(reportSubs, report.SubscriptionData{
+			SubID:      sub.SubID,
+			Email:      sub.Email,
+			NodeConfig: sub.NodeConfig,
+			Headers: report.SubscriptionHeaders{
+				ProfileTitle:          sub.Headers.ProfileTitle,
+				ProfileUpdateInterval: sub.Headers.ProfileUpdateInterval,
+				SubscriptionUserinfo:  sub.Headers.SubscriptionUserinfo,
+			},
+			StaleSuspect: sub.StaleSuspect,
+			StaleReason:  sub.StaleReason,
+		})
+	}
+	if err := client.SendSubscriptionReport(fixture.UUID, reportSubs); err != nil {
+		failures = append(failures, fmt.Sprintf("SendSubscriptionReport: %v", err))
+	}
+
+	online, err := monitor.DecodeOnlineUsers([]byte(fixture.OnlineUsers))
+	if err != nil {
+		return append(failures, fmt.Sprintf("decode online users: %v", err))
+	}
+	if err := client.SendOnlineUsersReport(fixture.UUID, online.Data); err != nil {
+		failures = append(failures, fmt.Sprintf("SendOnlineUsersReport: %v", err))
+	}
+	return failures
+}
+
+// record updates the counters and reports a failed run: an error log at the start of a
+// failure streak, a note on recovery, and the selftest error category on every failure
+func (r *Runner) record(result Result) {
+	r.stats.Runs++
+	r.stats.Last = result
+
+	if result.Passed {
+		if r.failing {
+			r.logger.Infof("✅ Self-test passes again")
+		} else {
+			r.logger.Debugf("🧪 Self-test passed in %v", result.Duration)
+		}
+		r.failing = false
+		return
+	}
+
+	r.stats.FailedRuns++
+	r.errorCounters.RecordCategory(errstats.SelfTest)
+	if !r.failing {
+		r.logger.Errorf("❌ Self-test failed: %s", strings.Join(result.Failures, "; "))
+	} else {
+		r.logger.Debugf("🧪 Self-test still failing: %s", strings.Join(result.Failures, "; "))
+	}
+	r.failing = true
+}
+
+// appendUnique appends the values not already in list
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
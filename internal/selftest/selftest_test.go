@@ -0,0 +1,229 @@
+package selftest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/privacy"
+	"xhub-agent/pkg/logger"
+	pb "xhub-agent/proto/reportpb"
+)
+
+func newTestRunner(t *testing.T, mapper privacy.EmailMapper) (*Runner, *errstats.Counters, string) {
+	t.Helper()
+	logPath := filepath.Join(t.TempDir(), "test.log")
+	log, err := logger.NewLogger(logPath, "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+
+	counters := errstats.NewCounters()
+	runner := NewRunner(nil, mapper, log)
+	runner.SetErrorCounters(counters)
+	return runner, counters, logPath
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+// identityMapper leaves emails untouched, like a broken email_reporting setup
+type identityMapper struct{}
+
+func (identityMapper) MapEmail(email string) string { return email }
+
+func TestRunner_HealthyFixturePasses(t *testing.T) {
+	hasher, err := privacy.NewHasher("selftest-key")
+	require.NoError(t, err)
+
+	for name, mapper := range map[string]privacy.EmailMapper{"plain": nil, "hashed": hasher} {
+		t.Run(name, func(t *testing.T) {
+			runner, counters, _ := newTestRunner(t, mapper)
+			assert.Nil(t, runner.Status(), "no status before the first run")
+
+			result := runner.Run()
+			assert.True(t, result.Passed, "failures: %v", result.Failures)
+			assert.Empty(t, result.Failures)
+
+			stats := runner.Stats()
+			assert.Equal(t, uint64(1), stats.Runs)
+			assert.Equal(t, uint64(0), stats.FailedRuns)
+			require.NotNil(t, runner.Status())
+			assert.True(t, runner.Status().Passed)
+			assert.Empty(t, counters.Pending())
+		})
+	}
+}
+
+func TestRunner_DetectsViolations(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapper  privacy.EmailMapper
+		corrupt func(f *Fixture)
+		failure string
+	}{
+		{
+			name:    "missing required field",
+			corrupt: func(f *Fixture) { f.Status = strings.Replace(f.Status, `"state":"running"`, `"state":""`, 1) },
+			failure: "SendReport: data.xray.state is empty",
+		},
+		{
+			name:    "empty uuid",
+			corrupt: func(f *Fixture) { f.UUID = "" },
+			failure: "uuid is empty",
+		},
+		{
+			name: "oversize request",
+			corrupt: func(f *Fixture) {
+				f.Content["selftest-a"] = append(f.Content["selftest-a"], "#"+strings.Repeat("x", MaxMessageSize))
+			},
+			failure: "SendSubscriptionReport: request is",
+		},
+		{
+			name:    "duplicate online users",
+			corrupt: func(f *Fixture) { f.OnlineUsers = `{"success":true,"obj":["a@example.invalid","a@example.invalid"]}` },
+			failure: "online_emails[1] is a duplicate",
+		},
+		{
+			name:    "undecodable panel response",
+			corrupt: func(f *Fixture) { f.Status = `{"success":true,"obj":` },
+			failure: "decode status",
+		},
+		{
+			name: "stale content",
+			corrupt: func(f *Fixture) {
+				f.Content["selftest-b"] = []string{"trojan://removed-password@selftest.example.invalid:8443"}
+			},
+			failure: "flagged stale",
+		},
+		{
+			name:    "unmapped emails",
+			mapper:  identityMapper{},
+			corrupt: func(f *Fixture) {},
+			failure: "request contains the unmapped email " + fixtureAlice,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, counters, logPath := newTestRunner(t, tt.mapper)
+			fixture := DefaultFixture()
+			tt.corrupt(&fixture)
+			runner.SetFixtureForTesting(fixture)
+
+			// Result
+			result := runner.Run()
+			assert.False(t, result.Passed)
+			assert.True(t, containsFailure(result.Failures, tt.failure), "failures: %v", result.Failures)
+			assert.LessOrEqual(t, len(result.Failures), maxFailures+1)
+
+			// Stats
+			stats := runner.Stats()
+			assert.Equal(t, uint64(1), stats.FailedRuns)
+			assert.Equal(t, result.Failures, stats.Last.Failures)
+
+			// Telemetry section
+			status := runner.Status()
+			require.NotNil(t, status)
+			assert.False(t, status.Passed)
+			assert.Equal(t, result.Failures, status.Failures)
+			assert.Equal(t, uint64(1), status.FailedRuns)
+
+			// Error counters
+			assert.Equal(t, uint64(1), counters.Pending()[errstats.SelfTest])
+
+			// Error log
+			assert.Contains(t, readLog(t, logPath), "[ERROR] ❌ Self-test failed")
+		})
+	}
+}
+
+func containsFailure(failures []string, substring string) bool {
+	for _, failure := range failures {
+		if strings.Contains(failure, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCompareRuns_NondeterministicOrdering(t *testing.T) {
+	loop := newLoopback(nil)
+	ctx := context.Background()
+
+	// The online list flips between runs, as if built from map iteration
+	_, err := loop.SendOnlineUsersReport(ctx, &pb.OnlineUsersReportRequest{Uuid: "selftest", OnlineEmails: []string{fixtureAlice, fixtureBob}})
+	require.NoError(t, err)
+	_, err = loop.SendOnlineUsersReport(ctx, &pb.OnlineUsersReportRequest{Uuid: "selftest", OnlineEmails: []string{fixtureBob, fixtureAlice}})
+	require.NoError(t, err)
+	_, err = loop.SendReport(ctx, &pb.ReportRequest{Uuid: "selftest"})
+	require.NoError(t, err)
+	_, err = loop.SendReport(ctx, &pb.ReportRequest{Uuid: "selftest"})
+	require.NoError(t, err)
+
+	_, requests := loop.result()
+	assert.Equal(t, []string{"SendOnlineUsersReport: request differs between identical runs (nondeterministic ordering)"},
+		compareRuns(requests))
+}
+
+func TestRunner_ErrorLoggedOncePerStreak(t *testing.T) {
+	runner, counters, logPath := newTestRunner(t, nil)
+	broken := DefaultFixture()
+	broken.UUID = ""
+
+	runner.SetFixtureForTesting(broken)
+	runner.Run()
+	runner.Run()
+	assert.Equal(t, 1, strings.Count(readLog(t, logPath), "Self-test failed"))
+	assert.Equal(t, uint64(2), counters.Pending()[errstats.SelfTest])
+
+	runner.SetFixtureForTesting(DefaultFixture())
+	assert.True(t, runner.Run().Passed)
+	assert.Contains(t, readLog(t, logPath), "Self-test passes again")
+
+	runner.SetFixtureForTesting(broken)
+	runner.Run()
+	assert.Equal(t, 2, strings.Count(readLog(t, logPath), "Self-test failed"), "a new streak is logged again")
+
+	stats := runner.Stats()
+	assert.Equal(t, uint64(4), stats.Runs)
+	assert.Equal(t, uint64(3), stats.FailedRuns)
+}
+
+func TestRunner_SchedulingGate(t *testing.T) {
+	runner, _, _ := newTestRunner(t, nil)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	registry := collector.NewRegistry(nil)
+	registry.SetClockForTesting(func() time.Time { return now })
+	registry.Register(collector.Collector{
+		Name:     CollectorName,
+		Interval: DefaultInterval,
+		Collect: func() collector.Apply {
+			runner.Run()
+			return nil
+		},
+	})
+
+	for _, step := range []time.Duration{0, time.Hour, 22 * time.Hour} {
+		now = now.Add(step)
+		registry.Apply(&monitor.ServerStatusData{})
+	}
+	assert.Equal(t, uint64(1), runner.Stats().Runs, "runs once per interval")
+
+	now = now.Add(time.Hour)
+	registry.Apply(&monitor.ServerStatusData{})
+	assert.Equal(t, uint64(2), runner.Stats().Runs)
+}
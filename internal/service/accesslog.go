@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"xhub-agent/internal/report"
+)
+
+// runAccessSummaries sends a summary of the Xray access log every access_summary_interval
+// until the agent stops
+func (a *AgentService) runAccessSummaries(interval time.Duration) {
+	defer a.wg.Done()
+	defer a.crashes.Recover("access summary")
+
+	unsupportedLogged := false
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		err := a.sendAccessSummary()
+		switch {
+		case errors.Is(err, report.ErrAccessSummaryUnsupported):
+			if !unsupportedLogged {
+				a.logger.Warnf("⚠️  xhub does not support access summaries, they are not sent")
+				unsupportedLogged = true
+			}
+		case err != nil:
+			a.logger.Debugf("🧮 Access summary failed: %v", err)
+		}
+	}
+}
+
+// sendAccessSummary summarizes the access log lines written since the last summary and sends
+// the summary to xhub
+func (a *AgentService) sendAccessSummary() error {
+	summary, err := a.accessSummarizer.Summarize()
+	if err != nil {
+		return err
+	}
+	if summary.SkippedBytes > 0 {
+		a.logger.Warnf("⚠️  Xray access log grew too fast, %d bytes were not summarized", summary.SkippedBytes)
+	}
+	a.logger.Debugf("🧮 Access summary: %d connections of %d users", summary.Connections, len(summary.Users))
+
+	a.withReportClient(func() { err = a.reportClient.SendAccessSummary(a.config.UUID, summary) })
+	if err != nil {
+		if !errors.Is(err, report.ErrAccessSummaryUnsupported) {
+			a.recordError(err)
+		}
+		return fmt.Errorf("access summary report failed: %w", err)
+	}
+	return nil
+}
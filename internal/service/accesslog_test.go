@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "xhub-agent/proto/reportpb"
+)
+
+// accessSummaryXHub records the access summaries it receives
+type accessSummaryXHub struct {
+	commandXHub
+
+	mutex     sync.Mutex
+	summaries []*pb.AccessSummary
+}
+
+func (s *accessSummaryXHub) SendAccessSummary(ctx context.Context, req *pb.AccessSummary) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.summaries = append(s.summaries, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestAgentService_SendAccessSummary(t *testing.T) {
+	accessLog := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(accessLog, nil, 0644))
+	xhub := &accessSummaryXHub{}
+	agent := newCommandTestAgent(t, xhub, fmt.Sprintf("access_summary: true\nxray_access_log: %q\n", accessLog))
+	require.NotNil(t, agent.accessSummarizer)
+
+	require.NoError(t, os.WriteFile(accessLog, []byte(
+		"2024/05/01 12:00:00 from 192.0.2.1:40000 accepted tcp:example.com:443 [in >> direct] email: alice@example.com\n"+
+			"2024/05/01 12:00:01 from 192.0.2.2:40000 accepted tcp:example.com:443 [in >> direct] email: alice@example.com\n"), 0644))
+	require.NoError(t, agent.sendAccessSummary())
+
+	require.Len(t, xhub.summaries, 1)
+	summary := xhub.summaries[0]
+	assert.Equal(t, agent.config.UUID, summary.Uuid)
+	assert.Equal(t, int64(2), summary.Connections)
+	require.Len(t, summary.Users, 1)
+	assert.Equal(t, "alice@example.com", summary.Users[0].Email)
+	assert.Equal(t, int32(2), summary.Users[0].SourceIps)
+	assert.Equal(t, []*pb.DomainAccess{{Domain: "example.com", Connections: 2}}, summary.Users[0].TopDomains)
+}
+
+func TestAgentService_AccessSummaryUnsupported(t *testing.T) {
+	accessLog := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(accessLog, nil, 0644))
+	agent := newCommandTestAgent(t, &commandXHub{}, fmt.Sprintf("access_summary: true\nxray_access_log: %q\n", accessLog))
+
+	err := agent.sendAccessSummary()
+	assert.ErrorContains(t, err, "xhub does not support access summaries")
+
+	assert.Nil(t, newReloadTestAgent(t).accessSummarizer, "access_summary is off by default")
+}
@@ -4,16 +4,48 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"xhub-agent/internal/accesslog"
 	"xhub-agent/internal/auth"
+	"xhub-agent/internal/backup"
+	"xhub-agent/internal/certfile"
+	"xhub-agent/internal/certrenew"
+	"xhub-agent/internal/clocksync"
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/command"
 	"xhub-agent/internal/config"
+	"xhub-agent/internal/crash"
+	"xhub-agent/internal/datadir"
+	"xhub-agent/internal/diskio"
+	"xhub-agent/internal/dnscheck"
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/fail2ban"
+	"xhub-agent/internal/faultinject"
+	"xhub-agent/internal/history"
 	"xhub-agent/internal/hysteria2"
+	"xhub-agent/internal/inbound"
+	"xhub-agent/internal/metrics"
 	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/netprobe"
+	"xhub-agent/internal/nodeprovider"
+	"xhub-agent/internal/portcheck"
+	"xhub-agent/internal/pressure"
+	"xhub-agent/internal/privacy"
+	"xhub-agent/internal/provision"
 	"xhub-agent/internal/report"
+	"xhub-agent/internal/selftest"
+	"xhub-agent/internal/state"
 	"xhub-agent/internal/subscription"
+	"xhub-agent/internal/sysinfo"
+	"xhub-agent/internal/watchdog"
 	"xhub-agent/pkg/logger"
 )
 
@@ -24,13 +56,59 @@ type AgentService struct {
 	authClient         *auth.XUIAuth
 	monitorClient      *monitor.MonitorClient
 	reportClient       *report.ReportClient
+	hostInfo           sysinfo.Info         // Agent and host metadata attached to status reports
+	heartbeatClient    *report.ReportClient // Dedicated heartbeat connection (nil when heartbeat_interval is 0)
+	heartbeatMutex     sync.Mutex           // Serializes heartbeats and reloads of heartbeatClient
+	health             agentHealth          // Outcome of the latest report cycles, sent with heartbeats
 	subscriptionClient *subscription.SubscriptionClient
-	hysteria2Client    *hysteria2.Client // Hysteria2 configuration client
+	hysteria2Client    *hysteria2.Client              // Hysteria2 configuration client
+	nodeProviders      []nodeprovider.Provider        // Enabled protocols outside 3x-ui (Hysteria2, TUIC, ...)
+	portDetector       *portcheck.Detector            // Inbound port listener detection (nil when disabled)
+	hostCollector      *monitor.HostCollector         // Host metrics while the panel is unavailable (nil when disabled)
+	backups            *backup.Tracker                // Inbound configuration backups (nil when disabled)
+	pendingBackup      *backup.Snapshot               // Snapshot to back up this cycle (guarded by cycleMutex)
+	commands           *command.Executor              // Commands issued by xhub (nil when command_channel is off)
+	netProber          *netprobe.Prober               // Network quality probes (nil when nothing to probe is configured)
+	accessSummarizer   *accesslog.Summarizer          // Xray access log summaries (nil when access_summary is off)
+	certRenewer        *certrenew.Renewer             // ACME certificate renewal (nil when cert_renew_client is unset)
+	certRenewMutex     sync.Mutex                     // One certificate renewal at a time
+	panelWatchdog      *watchdog.Watchdog             // 3x-ui service restarts (nil when panel_watchdog is off)
+	xraySupervisor     *watchdog.XraySupervisor       // Xray restarts on xray_supervision and restart_xray
+	xrayRestartMutex   sync.Mutex                     // Guards pendingXrayRestart, also set by commands
+	pendingXrayRestart *monitor.XrayRestart           // Outcome of the last Xray restart, for the next status report
+	provisioner        *provision.Provisioner         // Client changes pushed by xhub (nil when provisioning_channel is off)
+	collectors         *collector.Registry            // Agent-side collectors, each run at its own interval
+	inboundCertFiles   []subscription.InboundCertFile // Certificate files of the last inbound list
+	userDetailsErr     string                         // Last online user details error, logged once
+	publicIPs          []string                       // Public IPs of the last status (DNS check default)
+	domainChecker      *subscription.DomainChecker    // resolvedDomain DNS check (nil when off or no domain)
+	domainCheckMode    subscription.DomainCheckMode
+	dataDir            *datadir.DataDir    // Writable data directory
+	stateStore         *state.Store        // Persisted agent state (restart counter)
+	crashes            *crash.Recorder     // Panic recovery and crash dumps of the agent goroutines
+	startTime          time.Time           // Agent process start time
+	errorCounters      *errstats.Counters  // Per-category error counts reported to xhub
+	triggers           *triggerCoordinator // Serializes scheduled and forced report cycles
+	sender             *sendSpacer         // Spaces subscription and online users sends across the interval
+	onlineUsers        *onlineUsersCache   // Last online users list, reused when a fetch fails
+	schedule           *reportSchedule     // Cycles in which subscriptions and online users are collected
+	selfTest           *selftest.Runner    // Pipeline self-test against fixture data
+	cycleMutex         sync.Mutex          // Held by report cycles and live config reloads
+	configMutex        sync.RWMutex        // Guards config replaced by Reload, for readers outside the cycles
+	ticker             *cycleTicker        // Status interval ticker of the work loop (guarded by cycleMutex)
+	metrics            *metrics.Registry   // Agent health metrics (nil when metrics_listen is unset)
+	metricsEndpoint    *metricsEndpoint    // Serves metrics on metrics_listen (nil when unset)
+	localAPI           *localAPI           // Status introspection API on local_api (nil when unset)
+	lastReport         lastReport          // Last status sent to xhub, shown by the local API
+	batch              statusBatch         // Status samples waiting for the next batch (report_batch_size)
+	history            *history.Buffer     // Collected status of the last history_hours (nil when off)
+	alerts             *alerting           // Local alert hooks (nil when no alert_* threshold is set)
 
 	ctx               context.Context
 	cancel            context.CancelFunc
 	wg                sync.WaitGroup
 	running           bool
+	shutdownReason    string // Reported to xhub on shutdown (guarded by runningMux)
 	runningMux        sync.RWMutex
 	firstSubReport    bool       // 标记是否第一次获取订阅数据
 	firstSubReportMux sync.Mutex // 保护firstSubReport的并发访问
@@ -38,33 +116,226 @@ type AgentService struct {
 
 // NewAgentService creates a new Agent service
 func NewAgentService(configPath, logFile string) (*AgentService, error) {
+	return newAgentService(configPath, logFile, datadir.FSProber{})
+}
+
+// newAgentService creates a new Agent service using the given data directory prober
+func newAgentService(configPath, logFile string, prober datadir.Prober) (*AgentService, error) {
 	// Load configuration
-	cfg, err := config.LoadFromFile(configPath)
+	cfg, err := config.LoadFromEnv(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	// Prepare data directory, degrade to stdout-only logging if it is not writable
+	dataDir := datadir.Prepare(cfg.DataDir, logFile, prober)
+	if !dataDir.Writable() && cfg.RequireWritableDataDir {
+		return nil, fmt.Errorf("data directory is required to be writable: %w", dataDir.Err())
+	}
+
 	// Create logger
-	log, err := logger.NewLogger(logFile, cfg.LogLevel)
+	// Containers log to stdout only, collected by the runtime
+	var log *logger.Logger
+	if dataDir.Writable() && !cfg.Container {
+		log, err = logger.NewLogger(dataDir.Path(datadir.ArtifactLog), cfg.LogLevel)
+	} else {
+		log, err = logger.NewStdoutLogger(cfg.LogLevel)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create logger: %w", err)
 	}
+	if err := log.SetFormat(cfg.LogFormat); err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	// Tag every log line with the agent identity for centralized log aggregation
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	log.SetGlobalFields(map[string]string{"uuid": cfg.UUID, "host": hostname})
+
+	if cfg.Container {
+		log.Infof("📦 Container mode: logging to stdout, 3x-ui panel at %s (%s network)", cfg.XUIBaseURL, cfg.ContainerNetwork)
+	}
+	if !dataDir.Writable() {
+		log.Warnf("⚠️  %v", dataDir.Err())
+		log.Warnf("⚠️  Logging to stdout only, log file %s disabled", dataDir.Path(datadir.ArtifactLog))
+		for _, artifact := range dataDir.DisabledArtifacts(dataArtifacts(cfg)) {
+			log.Warnf("⚠️  Feature disabled (data directory not writable): %s (%s)", artifact, dataDir.Path(artifact))
+		}
+	}
+
+	// Explain a gRPC endpoint derived from a legacy reportUrl
+	if migration := cfg.LegacyMigration(); migration != nil {
+		for _, line := range migration.Warning() {
+			log.Warnf("⚠️  %s", line)
+		}
+	}
+
+	// Record this start in the state file (restart counter for crash-loop detection)
+	startTime := time.Now()
+	stateStore := state.NewMemoryStore()
+	if dataDir.Enabled(datadir.ArtifactState) {
+		store, err := state.Open(dataDir.Path(datadir.ArtifactState))
+		if err != nil {
+			log.Warnf("⚠️  %v", err)
+		}
+		stateStore = store
+	}
+	agentState, err := stateStore.RecordStart(startTime)
+	if err != nil {
+		log.Warnf("⚠️  Failed to save agent state: %v", err)
+	}
+	log.Infof("🔁 Agent start #%d", agentState.RestartCount)
+
+	// A panic of an agent goroutine is logged with its stack and dumped for xhub
+	crashDir := ""
+	if dataDir.Enabled(datadir.ArtifactCrash) {
+		crashDir = dataDir.Path(datadir.ArtifactCrash)
+	}
+	crashes := crash.NewRecorder(crashDir, log, startTime, agentState.RestartCount)
 
 	// Create authentication client
-	authClient := auth.NewXUIAuth(cfg.GetFullXUIURL(), cfg.XUIUser, cfg.XUIPass)
+	authClient, err := newXUIAuth(cfg, log)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create monitoring client
-	monitorClient := monitor.NewMonitorClient(authClient, log)
+	monitorClient, err := newMonitorClient(cfg, authClient, log)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create subscription client
-	subscriptionClient := subscription.NewSubscriptionClient(authClient, cfg.ResolvedDomain, log)
+	subscriptionClient := subscription.NewSubscriptionClient(authClient, cfg.ResolvedDomain, log.With("component", "subscription"))
+	subscriptionClient.SetRetryPolicy(cfg.SubscriptionRetryAttempts, time.Duration(cfg.SubscriptionRetryBackoffMs)*time.Millisecond)
+	subscriptionClient.SetFetchPolicy(cfg.SubscriptionFetchConcurrency, time.Duration(cfg.SubscriptionFetchTimeout)*time.Second)
+	subscriptionClient.SetDNSTTL(time.Duration(cfg.SubscriptionDNSTTL) * time.Second)
+	subscriptionClient.SetJSONEnabled(cfg.SubscriptionJSON)
+	subscriptionClient.SetHostRewrite(cfg.ResolvedDomainRewrite)
+
+	// Check that resolvedDomain actually resolves before reporting node configs pointing at it
+	domainCheckMode, err := subscription.ParseDomainCheckMode(cfg.ResolvedDomainCheck)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resolved domain check: %w", err)
+	}
+	var domainChecker *subscription.DomainChecker
+	if domainCheckMode != subscription.DomainCheckOff && cfg.ResolvedDomain != "" {
+		domainChecker = subscription.NewDomainChecker(cfg.ResolvedDomain, subscription.DefaultDomainCheckInterval)
+	}
 
 	// Create report client using gRPC server and port
-	grpcAddr := fmt.Sprintf("%s:%d", cfg.GRPCServer, cfg.GRPCPort)
-	reportClient := report.NewReportClient(grpcAddr, cfg.XHubAPIKey, log)
+	reportClient, err := newXHubClient(cfg, log.With("component", "report"), startTime, agentState.RestartCount)
+	if err != nil {
+		return nil, err
+	}
+	if expiry := reportClient.ClientCertificateExpiry(); !expiry.IsZero() {
+		log.Infof("🔐 Mutual TLS enabled, client certificate expires %s", expiry.Format(time.RFC3339))
+	}
+	if pins := reportClient.CertificatePins(); pins > 0 {
+		log.Infof("📌 xhub certificate pinned (%d fingerprints)", pins)
+	}
+	if cfg.XHubHTTPSURL != "" {
+		log.Infof("🚚 Report transport: %s, failing over to the other one when unreachable", reportClient.ActiveTransport())
+	}
+	reportClient.SetWaitForReady(cfg.GRPCWaitForReady)
+	reportClient.SetReportTypes(report.ReportTypes{
+		Status:        cfg.StatusReportEnabled(),
+		Subscriptions: cfg.SubscriptionReportEnabled(),
+		OnlineUsers:   cfg.OnlineUsersReportEnabled(),
+	})
+	if disabled := reportClient.ReportTypes().Disabled(); len(disabled) > 0 {
+		log.Infof("🔕 Reports turned off: %s", strings.Join(disabled, ", "))
+	}
+	reportClient.SetCombinedReports(cfg.ReportCombined)
+	reportClient.SetStreaming(cfg.ReportStream)
+	reportClient.SetDeltaReports(cfg.ReportDelta, time.Duration(cfg.ReportDeltaFull)*time.Second)
+	reportClient.SetSubscriptionChunkSize(cfg.SubscriptionChunkBytes())
+	reportClient.SetBandwidthLimit(int64(cfg.ReportBandwidthKBPerMin) << 10)
+	hostInfo := sysinfo.NewCollector("/").Collect()
+	reportClient.SetHostInfo(hostInfo)
+	retryCodes := report.DefaultRetryCodes
+	if len(cfg.ReportRetryCodes) > 0 {
+		if retryCodes, err = report.ParseRetryCodes(cfg.ReportRetryCodes); err != nil {
+			return nil, err
+		}
+	}
+	reportClient.SetRetryPolicy(report.RetryPolicy{
+		Attempts:   cfg.ReportRetryAttempts,
+		Backoff:    time.Duration(cfg.ReportRetryBackoffMs) * time.Millisecond,
+		MaxBackoff: time.Duration(cfg.ReportRetryMaxBackoffMs) * time.Millisecond,
+		Codes:      retryCodes,
+	})
+	if cfg.PersistFailedPayloads && dataDir.Enabled(datadir.ArtifactFailed) {
+		if err := reportClient.SetFailurePersistence(dataDir.Path(datadir.ArtifactFailed), 0); err != nil {
+			log.Warnf("⚠️  %v", err)
+		}
+	}
+	if maxBytes := cfg.OfflineQueueMaxBytes(); maxBytes > 0 && dataDir.Enabled(datadir.ArtifactQueue) {
+		queue, err := report.OpenQueue(dataDir.Path(datadir.ArtifactQueue), maxBytes)
+		if err != nil {
+			log.Warnf("⚠️  Offline report queue disabled: %v", err)
+		} else {
+			reportClient.SetOfflineQueue(queue)
+			if queued := queue.Len(); queued > 0 {
+				log.Infof("📥 %d reports queued from a previous run will be sent once xhub is reachable", queued)
+			}
+		}
+	}
+
+	// Heartbeats get their own connection, the report client is held by the report cycles
+	var heartbeatClient *report.ReportClient
+	if cfg.HeartbeatPeriod() > 0 {
+		if heartbeatClient, err = newXHubClient(cfg, log.With("component", "heartbeat"), startTime, agentState.RestartCount); err != nil {
+			return nil, err
+		}
+	}
+
+	// Replace user emails in every outgoing payload (email_reporting)
+	emailMode, err := privacy.ParseEmailMode(cfg.EmailReporting)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email reporting: %w", err)
+	}
+	var selfTestMapper privacy.EmailMapper // Same mapping without persisting fixture pseudonyms
+	switch emailMode {
+	case privacy.EmailHashed:
+		hasher, err := privacy.NewHasher(cfg.EmailHashKey)
+		if err != nil {
+			return nil, err
+		}
+		reportClient.SetEmailMapper(hasher)
+		selfTestMapper = hasher
+		log.Info("🙈 User emails are reported as keyed hashes")
+	case privacy.EmailPseudonym:
+		reportClient.SetEmailMapper(privacy.NewPseudonymizer(stateStore, log))
+		selfTestMapper = privacy.NewPseudonymizer(state.NewMemoryStore(), log)
+		if !dataDir.Enabled(datadir.ArtifactState) {
+			log.Warn("⚠️  Email pseudonyms cannot be persisted (data directory not writable), labels change on restart")
+		}
+		log.Info("🙈 User emails are reported as pseudonyms")
+	}
+
+	// Error counters shared by the clients and reported with every status report
+	errorCounters := errstats.NewCounters()
+	reportClient.SetErrorCounters(errorCounters)
+	subscriptionClient.SetErrorCounters(errorCounters)
+
+	// Testing-only failure injection
+	faults, err := faultinject.Parse(cfg.FailInject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fail_inject: %w", err)
+	}
+	if faults != nil {
+		log.Warnf("🧪 Failure injection enabled (%s), do not use in production", faults)
+		authClient.SetFaultInjector(faults)
+		reportClient.SetFaultInjector(faults)
+	}
 
 	// Create Hysteria2 client
-	hy2Client := hysteria2.NewClient(log)
+	hy2Client := hysteria2.NewClient(log.With("component", "hysteria2"))
 	hy2Client.Configure(
 		cfg.Hysteria2Enabled,
 		cfg.Hysteria2ConfigPath,
@@ -74,25 +345,434 @@ func NewAgentService(configPath, logFile string) (*AgentService, error) {
 		cfg.Hysteria2PortHopping,
 		cfg.Hysteria2PortHoppingRange,
 	)
+	hy2Client.SetUsersFile(cfg.Hysteria2UsersFile)
 
 	if cfg.Hysteria2Enabled {
 		log.Infof("🚀 Hysteria2 support enabled, config: %s", cfg.Hysteria2ConfigPath)
 	}
+	if cfg.TUICEnabled {
+		log.Infof("🚀 TUIC support enabled, config: %s", cfg.TUICConfigPath)
+	}
+	if cfg.ShadowTLSEnabled {
+		log.Infof("🚀 ShadowTLS support enabled, config: %s", cfg.ShadowTLSConfigPath)
+	}
+
+	// Host metrics reported while 3x-ui is unavailable
+	var hostCollector *monitor.HostCollector
+	if cfg.HostStatusFallbackEnabled() {
+		hostCollector = monitor.NewHostCollector("/proc", "/")
+	}
+
+	// Inbound configuration backups to xhub
+	var backups *backup.Tracker
+	if cfg.InboundBackup {
+		backups = backup.NewTracker()
+		log.Info("💾 Inbound configuration backup to xhub enabled")
+	}
+
+	// Commands pushed by xhub
+	var commandAllowlist []string
+	if cfg.CommandChannel {
+		allowlist, err := command.ParseAllowlist(cfg.CommandAllowlist)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command_allowlist: %w", err)
+		}
+		commandAllowlist = allowlist
+	}
+
+	// Create port frontend detector if enabled
+	var portDetector *portcheck.Detector
+	if cfg.DetectPortFrontends {
+		portDetector = portcheck.NewDetector("/proc", log.With("component", "portcheck"))
+		log.Info("🔌 Port frontend detection enabled")
+	}
+
+	// Register agent-side collectors (intervals overridable via collector_intervals)
+	intervals := make(map[string]time.Duration, len(cfg.CollectorIntervals))
+	for name, seconds := range cfg.CollectorIntervals {
+		intervals[name] = time.Duration(seconds) * time.Second
+	}
+	collectors := collector.NewRegistry(intervals)
+	if cfg.CollectFail2ban {
+		fail2banCollector := fail2ban.NewCollector(cfg.Fail2banSocket, log.With("component", "fail2ban"))
+		collectors.Register(collector.Collector{
+			Name:     fail2ban.CollectorName,
+			Interval: fail2ban.DefaultInterval,
+			Collect: func() collector.Apply {
+				bans := fail2banCollector.Collect()
+				return func(data *monitor.ServerStatusData) { data.Fail2banBans = bans }
+			},
+		})
+		log.Info("🚫 fail2ban ban statistics enabled")
+	}
+	if cfg.CollectDiskIO {
+		diskCollector := diskio.NewCollector(cfg.DiskIODevice, log.With("component", "diskio"))
+		smartCollector := diskio.NewSMARTCollector(diskCollector, log.With("component", "smart"))
+		collectors.Register(collector.Collector{
+			Name:     diskio.CollectorName,
+			Interval: diskio.DefaultInterval,
+			Collect: func() collector.Apply {
+				stats := diskCollector.Collect()
+				return func(data *monitor.ServerStatusData) { data.DiskIO = stats }
+			},
+		})
+		collectors.Register(collector.Collector{
+			Name:     diskio.SMARTCollectorName,
+			Interval: diskio.DefaultSMARTInterval,
+			Collect: func() collector.Apply {
+				smart := smartCollector.Collect()
+				return func(data *monitor.ServerStatusData) { data.DiskSMART = smart }
+			},
+		})
+		log.Infof("💽 Disk IO statistics enabled (disk: %q)", diskCollector.Device())
+	}
+	if cfg.CollectPressure {
+		pressureCollector := pressure.NewCollector("/proc", "/sys", log.With("component", "pressure"))
+		collectors.Register(collector.Collector{
+			Name:     pressure.CollectorName,
+			Interval: pressure.DefaultInterval,
+			Collect: func() collector.Apply {
+				readings := pressureCollector.Collect()
+				return func(data *monitor.ServerStatusData) { data.Pressure = readings }
+			},
+		})
+		log.Info("🌡️  Pressure stall and thermal zone reporting enabled")
+	}
+	if cfg.CollectClock {
+		clockCollector := clocksync.NewCollector(cfg.ClockNTPServer, log.With("component", "clock"))
+		collectors.Register(collector.Collector{
+			Name:     clocksync.CollectorName,
+			Interval: clocksync.DefaultInterval,
+			Collect: func() collector.Apply {
+				clock := clockCollector.Collect()
+				return func(data *monitor.ServerStatusData) { data.Clock = clock }
+			},
+		})
+		log.Info("🕰️  Clock offset reporting enabled")
+	}
+	// Protocols served outside 3x-ui, their status collected like the collectors above
+	nodeProviders := registerNodeProviders(reportedNodeProviders(cfg, newNodeProviders(cfg, hy2Client, log)), collectors)
+	if cfg.CollectWireGuard {
+		log.Info("🔐 WireGuard peer statistics enabled")
+	}
+	if cfg.WireGuardPeersFile != "" {
+		log.Infof("🔐 WireGuard client config stubs enabled, peers: %s", cfg.WireGuardPeersFile)
+	}
+
+	// Prometheus endpoint with the agent health metrics (metrics_listen)
+	var metricsRegistry *metrics.Registry
+	var endpoint *metricsEndpoint
+	if cfg.MetricsListen != "" {
+		metricsRegistry = metrics.NewRegistry()
+		if endpoint, err = listenMetrics(cfg.MetricsListen, metricsRegistry); err != nil {
+			return nil, err
+		}
+		authClient.SetMetrics(metricsRegistry)
+		reportClient.SetMetrics(metricsRegistry)
+	}
+
+	// Status introspection API for operators and the install script (local_api)
+	var api *localAPI
+	if cfg.LocalAPI != "" {
+		if api, err = listenLocalAPI(cfg.LocalAPI); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create context
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &AgentService{
+	agent := &AgentService{
 		config:             cfg,
 		logger:             log,
 		authClient:         authClient,
 		monitorClient:      monitorClient,
 		reportClient:       reportClient,
+		hostInfo:           hostInfo,
+		heartbeatClient:    heartbeatClient,
 		subscriptionClient: subscriptionClient,
 		hysteria2Client:    hy2Client,
+		nodeProviders:      nodeProviders,
+		portDetector:       portDetector,
+		hostCollector:      hostCollector,
+		backups:            backups,
+		collectors:         collectors,
+		domainChecker:      domainChecker,
+		domainCheckMode:    domainCheckMode,
+		dataDir:            dataDir,
+		stateStore:         stateStore,
+		crashes:            crashes,
+		startTime:          startTime,
+		errorCounters:      errorCounters,
+		metrics:            metricsRegistry,
+		metricsEndpoint:    endpoint,
+		localAPI:           api,
 		ctx:                ctx,
 		cancel:             cancel,
-	}, nil
+	}
+	// Forced triggers of one source are limited to one per status interval
+	statusInterval := cfg.StatusPeriod()
+	agent.triggers = newTriggerCoordinator(agent.executeOnce, statusInterval)
+	metricsRegistry.CounterFunc("xhub_agent_skipped_ticks_total", "Scheduled cycles skipped because the previous cycle was still running.",
+		func() float64 { return float64(agent.triggers.skippedTicks()) })
+	agent.schedule = newReportSchedule(statusInterval, cfg.SubscriptionPeriod(), cfg.OnlineUsersPeriod())
+	agent.onlineUsers = newOnlineUsersCache(time.Duration(cfg.OnlineUsersMaxStaleness) * time.Second)
+	agent.sender = newSendSpacer(sendSpacing(time.Duration(cfg.SendSpacingMs)*time.Millisecond, statusInterval, deferredSendsPerCycle))
+	agent.sender.crashes = crashes
+	agent.history = openHistory(cfg, dataDir, log)
+	agent.alerts = newAlerting(cfg, log)
+
+	// Inbound-derived data, collected before the certificate expiry reading its certificate files
+	collectors.Register(agent.inboundsCollector())
+
+	// Certificate expiry reads the files referenced by the Hysteria2 and inbound configs
+	if cfg.CollectCertExpiry {
+		certCollector := certfile.NewCollector(agent.certSources, log.With("component", "certfile"))
+		collectors.Register(collector.Collector{
+			Name:     certfile.CollectorName,
+			Interval: certfile.DefaultInterval,
+			Collect: func() collector.Apply {
+				expiry := certCollector.Collect()
+				return func(data *monitor.ServerStatusData) { data.CertExpiry = expiry }
+			},
+		})
+		log.Info("📜 Certificate expiry reporting enabled")
+	}
+	// DNS self-check of the domains users connect to
+	if cfg.DNSCheck {
+		domains := cfg.DNSCheckDomains
+		if len(domains) == 0 {
+			domains = dnscheck.DefaultDomains(cfg.ResolvedDomain, cfg.Hysteria2ServerAddr)
+		}
+		var external dnscheck.Resolver
+		switch cfg.DNSCheckResolver {
+		case "none":
+		case "":
+			external = dnscheck.NewExternalResolver(dnscheck.DefaultExternalResolver)
+		default:
+			external = dnscheck.NewExternalResolver(cfg.DNSCheckResolver)
+		}
+		if len(domains) == 0 {
+			log.Warn("⚠️  dns_check enabled but no domain to check (set dns_check_domains)")
+		} else {
+			checker := dnscheck.NewChecker(domains, net.DefaultResolver, external, log.With("component", "dnscheck"))
+			collectors.Register(collector.Collector{
+				Name:     dnscheck.CollectorName,
+				Interval: dnscheck.DefaultInterval,
+				Collect: func() collector.Apply {
+					checks := checker.Check(agent.dnsCheckPublicIPs())
+					return func(data *monitor.ServerStatusData) { data.DNSChecks = checks }
+				},
+			})
+			log.Infof("🌐 DNS self-check enabled for %v", domains)
+		}
+	}
+	// Pipeline self-test, scheduled (selftest_interval) and on demand (RunSelfTest)
+	agent.selfTest = selftest.NewRunner(monitorClient.FieldMapping(), selfTestMapper, log.With("component", "selftest"))
+	agent.selfTest.SetErrorCounters(errorCounters)
+	if cfg.NetworkProbeEnabled() {
+		agent.netProber = netprobe.NewProber(netprobe.Config{
+			Targets:            cfg.NetworkProbeTargets,
+			Count:              cfg.NetworkProbeCount,
+			ThroughputURL:      cfg.NetworkProbeThroughputURL,
+			ThroughputMaxBytes: int64(cfg.NetworkProbeThroughputMB) << 20,
+		})
+	}
+	if cfg.AccessSummary {
+		accessLog := cfg.XrayAccessLog
+		if accessLog == "" {
+			accessLog = monitor.DefaultAccessLog
+		}
+		agent.accessSummarizer = accesslog.NewSummarizer(accessLog, cfg.AccessSummaryTopDomains, log.With("component", "accesslog"))
+		log.Infof("🧮 Access log summaries enabled (%s, every %ds)", accessLog, cfg.AccessSummaryInterval)
+	}
+	if cfg.CertRenewClient != "" {
+		agent.certRenewer, err = certrenew.NewRenewer(cfg.CertRenewClient)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("🔐 Certificate renewal through %s enabled (%d days before expiry)", cfg.CertRenewClient, cfg.CertRenewBeforeDays)
+	}
+	if cfg.PanelWatchdog {
+		agent.panelWatchdog = watchdog.New(watchdog.Config{
+			Service:     cfg.PanelWatchdogService,
+			Failures:    cfg.PanelWatchdogFailures,
+			Cooldown:    time.Duration(cfg.PanelWatchdogCooldown) * time.Second,
+			MaxRestarts: cfg.PanelWatchdogMaxRestarts,
+		})
+		log.Infof("🐕 Panel watchdog enabled, restarting %s after %d failed status requests", cfg.PanelWatchdogService, cfg.PanelWatchdogFailures)
+	}
+	agent.xraySupervisor = watchdog.NewXraySupervisor(cfg.XraySupervisionCycles, cfg.XrayRestartService)
+	if cfg.XraySupervision {
+		log.Infof("🔁 Xray supervision enabled, restarting Xray after %d cycles not running", cfg.XraySupervisionCycles)
+	}
+	if cfg.CommandChannel {
+		agent.commands = agent.newCommandExecutor(commandAllowlist)
+		log.Infof("📡 Command channel enabled, allowed commands: %s", strings.Join(agent.commands.Supported(), ", "))
+	}
+	if cfg.ProvisioningChannel {
+		agent.provisioner = provision.NewProvisioner(inbound.NewClient(authClient), agent.ensureAuthenticated)
+		log.Info("👥 Provisioning channel enabled, xhub may add, update and remove 3x-ui clients")
+	}
+	if period := cfg.SelfTestPeriod(); period > 0 {
+		collectors.Register(collector.Collector{
+			Name:     selftest.CollectorName,
+			Interval: period,
+			Collect: func() collector.Apply {
+				agent.selfTest.Run()
+				return nil // The status is attached every cycle, including on-demand results
+			},
+		})
+	}
+	for _, name := range collectors.UnknownOverrides() {
+		log.Warnf("⚠️  collector_intervals: unknown or disabled collector %q", name)
+	}
+
+	return agent, nil
+}
+
+// dataArtifacts returns the artifacts cfg keeps in the data directory, besides the log
+func dataArtifacts(cfg *config.Config) []datadir.Artifact {
+	artifacts := []datadir.Artifact{datadir.ArtifactState, datadir.ArtifactCrash}
+	if cfg.HistoryHours > 0 && cfg.HistoryPersist {
+		artifacts = append(artifacts, datadir.ArtifactHistory)
+	}
+	if cfg.PersistFailedPayloads {
+		artifacts = append(artifacts, datadir.ArtifactFailed)
+	}
+	if cfg.OfflineQueueMaxBytes() > 0 {
+		artifacts = append(artifacts, datadir.ArtifactQueue)
+	}
+	return artifacts
+}
+
+// newXUIAuth creates the 3x-ui authentication client of cfg
+func newXUIAuth(cfg *config.Config, log *logger.Logger) (*auth.XUIAuth, error) {
+	authClient := auth.NewXUIAuth(cfg.GetFullXUIURL(), cfg.XUIUser, cfg.XUIPass)
+	authClient.SetSessionTTL(time.Duration(cfg.XUISessionTTL) * time.Second)
+	csrfMode, err := auth.ParseCSRFMode(cfg.XUICSRFMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSRF mode: %w", err)
+	}
+	if err := authClient.SetCSRF(auth.CSRFConfig{
+		Mode:      csrfMode,
+		Path:      cfg.XUICSRFPath,
+		Cookie:    cfg.XUICSRFCookie,
+		Pattern:   cfg.XUICSRFPattern,
+		Header:    cfg.XUICSRFHeader,
+		FormField: cfg.XUICSRFFormField,
+	}); err != nil {
+		return nil, fmt.Errorf("invalid CSRF configuration: %w", err)
+	}
+	if csrfMode != auth.CSRFOff {
+		log.Infof("🛡️  3x-ui CSRF token handling enabled (mode %s)", csrfMode)
+	}
+	authClient.SetLoginSecret(cfg.XUILoginSecret)
+	if cfg.XUITOTPSecret != "" {
+		totpSecret, err := auth.ParseTOTPSecret(cfg.XUITOTPSecret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xui_totp_secret: %w", err)
+		}
+		authClient.SetTOTPSecret(totpSecret)
+		log.Infof("🔐 3x-ui two-factor login enabled")
+	}
+	return authClient, nil
+}
+
+// detectPanelVersion detects the 3x-ui release once, for the layout of its status responses
+func (a *AgentService) detectPanelVersion() {
+	if _, err := a.monitorClient.DetectPanelVersion(); err != nil {
+		a.logger.Debugf("🧩 3x-ui version detection failed: %v", err)
+	}
+}
+
+// newMonitorClient creates the 3x-ui monitoring client of cfg
+func newMonitorClient(cfg *config.Config, authClient *auth.XUIAuth, log *logger.Logger) (*monitor.MonitorClient, error) {
+	monitorClient := monitor.NewMonitorClient(authClient, log.With("component", "monitor"))
+	fieldMapping, err := monitor.ResolveFieldMapping(cfg.XUIPanelProfile, cfg.XUIFieldMapping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid panel profile: %w", err)
+	}
+	monitorClient.SetFieldMapping(fieldMapping)
+	if cfg.XUIPanelVersion != "" {
+		version, err := monitor.ParsePanelVersion(cfg.XUIPanelVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xui_panel_version: %w", err)
+		}
+		monitorClient.SetPanelVersion(version)
+		log.Infof("🧩 3x-ui version pinned to %s (status layout %s)", version, monitor.SchemaFor(version).Name)
+	}
+	userDetails, err := monitor.ParseUserDetailsSource(cfg.OnlineUserDetails)
+	if err != nil {
+		return nil, fmt.Errorf("invalid online user details: %w", err)
+	}
+	monitorClient.SetUserDetails(userDetails, cfg.XrayAccessLog)
+	if monitorClient.UserDetailsEnabled() {
+		log.Infof("🕵️  Reporting the source IPs of online users (%s)", userDetails)
+	}
+	if len(fieldMapping) > 0 {
+		log.Infof("🧩 Using 3x-ui panel profile %q (%d field mappings)", cfg.XUIPanelProfile, len(fieldMapping))
+	}
+	return monitorClient, nil
+}
+
+// newXHubClient creates a client of the xhub gRPC server with the connection settings of cfg
+// (target, TLS and pinning, dial strategy, proxy, compression, transport)
+func newXHubClient(cfg *config.Config, log *logger.Logger, startTime time.Time, restartCount int64) (*report.ReportClient, error) {
+	client := report.NewReportClient(cfg.GRPCAddress(), cfg.XHubAPIKey, log)
+	client.SetAgentInfo(startTime, restartCount)
+	client.SetConfigFingerprint(cfg.Fingerprint())
+	client.SetTimeouts(report.Timeouts{
+		Report:       time.Duration(cfg.ReportTimeout) * time.Second,
+		Subscription: time.Duration(cfg.SubscriptionTimeout) * time.Second,
+		Connect:      time.Duration(cfg.ConnectTimeout) * time.Second,
+	})
+	client.SetKeepalive(report.KeepalivePolicy{
+		Time:                time.Duration(cfg.GRPCKeepaliveTime) * time.Second,
+		Timeout:             time.Duration(cfg.GRPCKeepaliveTimeout) * time.Second,
+		PermitWithoutStream: cfg.GRPCKeepaliveWithoutStreamEnabled(),
+		MaxBackoff:          time.Duration(cfg.GRPCReconnectMaxBackoff) * time.Second,
+	})
+	dialStrategy, err := report.ParseDialStrategy(cfg.GRPCDialStrategy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dial strategy: %w", err)
+	}
+	client.SetPreferIPv6(cfg.PreferIPv6Enabled())
+	client.SetDialStrategy(dialStrategy)
+	proxy, err := report.ParseProxy(cfg.GRPCProxy)
+	if err != nil {
+		return nil, err
+	}
+	client.SetProxy(proxy)
+	compression, err := report.ParseCompression(cfg.ReportCompression)
+	if err != nil {
+		return nil, err
+	}
+	client.SetCompression(compression)
+	client.SetCompressionThreshold(cfg.ReportCompressionMinBytes())
+	transport, err := report.ParseTransport(cfg.Transport)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.SetTransport(transport, cfg.XHubHTTPSURL); err != nil {
+		return nil, err
+	}
+	if cfg.GRPCClientCert != "" || cfg.CAFile() != "" {
+		if err := client.SetClientTLS(cfg.GRPCClientCert, cfg.GRPCClientKey, cfg.CAFile()); err != nil {
+			return nil, err
+		}
+	}
+	pins, err := report.ParseCertificatePins(cfg.GRPCCertPinSHA256)
+	if err != nil {
+		return nil, err
+	}
+	if len(pins) > 0 {
+		client.SetCertificatePins(pins)
+	}
+	if migration := cfg.LegacyMigration(); migration != nil {
+		client.SetConnectionHint(migration.ConnectionHint())
+	}
+	return client, nil
 }
 
 // Start starts the Agent service
@@ -107,22 +787,82 @@ func (a *AgentService) Start() {
 
 	a.logger.Info("🚀 Starting xhub-agent service")
 	a.logger.Infof("🆔 Agent UUID: %s", a.config.UUID)
-	a.logger.Infof("⏱️  Poll interval: %d seconds", a.config.PollInterval)
+	a.logger.Infof("⏱️  Report intervals: status %v, subscriptions %v, online users %v",
+		a.config.StatusPeriod(), a.config.SubscriptionPeriod(), a.config.OnlineUsersPeriod())
+	if a.config.PollJitter > 0 {
+		phase := time.Duration(uuidPhase(a.config.UUID) * float64(a.config.StatusPeriod()))
+		a.logger.Infof("🎲 Cycle jitter ±%d%%, phase offset %v", a.config.PollJitter, phase.Round(time.Millisecond))
+	}
+	if a.config.ReportBatchSize > 1 {
+		a.logger.Infof("📦 Status batches of %d samples, sent every %v", a.config.ReportBatchSize,
+			time.Duration(a.config.ReportBatchSize)*a.config.StatusPeriod())
+	}
+	a.logger.Infof("🧬 Config fingerprint: %s", a.config.Fingerprint())
+	a.logger.Infof("🖥️  Host: %s (%s/%s, kernel %s, virtualization %s)", a.hostInfo.Hostname,
+		a.hostInfo.OS, a.hostInfo.Arch, a.hostInfo.Kernel, a.hostInfo.Virtualization)
 
 	// Debug: Log detailed configuration
 	a.logger.Debugf("📋 Configuration Details:")
 	a.logger.Debugf("   🌐 3x-ui URL: %s", a.config.GetFullXUIURL())
 	a.logger.Debugf("   👤 3x-ui User: %s", a.config.XUIUser)
-	a.logger.Debugf("   📡 gRPC Server: %s:%d", a.config.GRPCServer, a.config.GRPCPort)
+	a.logger.Debugf("   📡 gRPC Server: %s", a.config.GRPCAddress())
 	a.logger.Debugf("   🔑 API Key: %s", a.config.XHubAPIKey)
 	a.logger.Debugf("   📊 Log Level: %s", a.config.LogLevel)
+	a.logger.Debugf("   📤 Send spacing: %v", a.sender.spacing)
+
+	// Startup check of the resolved domain (repeated before each subscription report)
+	a.checkResolvedDomain()
+
+	if a.metricsEndpoint != nil {
+		go a.serveMetrics()
+	}
+	if a.localAPI != nil {
+		go a.serveLocalAPI()
+	}
+	if a.dataDir != nil && a.dataDir.Enabled(datadir.ArtifactCrash) {
+		a.wg.Add(1)
+		go a.reportCrashes()
+	}
+	if a.commands != nil {
+		a.wg.Add(1)
+		go a.runCommandChannel()
+	}
+	if a.provisioner != nil {
+		a.wg.Add(1)
+		go a.runProvisioningChannel()
+	}
+	if a.heartbeatClient != nil {
+		a.wg.Add(1)
+		go a.runHeartbeats(a.config.HeartbeatPeriod())
+	}
+	if a.config.GeoLookupURL != "" {
+		a.wg.Add(1)
+		go a.lookupGeo()
+	}
+	if a.netProber != nil && a.config.NetworkProbeInterval > 0 {
+		a.wg.Add(1)
+		go a.runNetworkProbes(time.Duration(a.config.NetworkProbeInterval) * time.Second)
+	}
+	if a.accessSummarizer != nil {
+		a.wg.Add(1)
+		go a.runAccessSummaries(time.Duration(a.config.AccessSummaryInterval) * time.Second)
+	}
+	if a.certRenewer != nil {
+		a.wg.Add(1)
+		go a.runCertRenewals()
+	}
+	if a.alerts != nil && len(a.alerts.hooks) > 0 {
+		a.wg.Add(1)
+		go a.runAlertHooks()
+	}
 
 	// Start main work loop
 	a.wg.Add(1)
 	go a.workLoop()
 
-	// Wait for all goroutines to complete
+	// Wait for all goroutines to complete, then drain before the connection is closed
 	a.wg.Wait()
+	a.drain()
 	a.logger.Info("🛑 xhub-agent service stopped")
 }
 
@@ -144,12 +884,23 @@ func (a *AgentService) Stop() {
 func (a *AgentService) Close() {
 	a.Stop()
 
+	if a.metricsEndpoint != nil {
+		a.metricsEndpoint.close()
+	}
+	if a.localAPI != nil {
+		a.localAPI.close()
+	}
+	a.history.Close()
+
 	// Close gRPC connection
 	if a.reportClient != nil {
 		if err := a.reportClient.Close(); err != nil {
 			a.logger.Errorf("Failed to close gRPC connection: %v", err)
 		}
 	}
+	if a.heartbeatClient != nil {
+		a.heartbeatClient.Close()
+	}
 
 	if a.logger != nil {
 		a.logger.Close()
@@ -163,38 +914,135 @@ func (a *AgentService) IsRunning() bool {
 	return a.running
 }
 
+// ShutdownTimeout returns how long a clean shutdown may take before the process is forced to exit
+func (a *AgentService) ShutdownTimeout() time.Duration {
+	return time.Duration(a.config.ShutdownTimeout) * time.Second
+}
+
+// Config returns the effective configuration of the service. Goroutines other than the
+// report cycles read it here, Reload may replace it.
+func (a *AgentService) Config() *config.Config {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	return a.config
+}
+
+// Logger returns the service logger
+func (a *AgentService) Logger() *logger.Logger {
+	return a.logger
+}
+
 // workLoop main work loop
 func (a *AgentService) workLoop() {
 	defer a.wg.Done()
+	defer a.crashes.Recover("work loop")
 
-	// Create ticker
-	ticker := time.NewTicker(time.Duration(a.config.PollInterval) * time.Second)
+	// Create ticker, reset by live config reloads
+	a.cycleMutex.Lock()
+	ticker := newCycleTicker(a.config.StatusPeriod(), float64(a.config.PollJitter)/100, a.config.UUID, a.triggers.skipTick)
+	a.ticker = ticker
+	a.cycleMutex.Unlock()
 	defer ticker.Stop()
 
-	// Execute immediately once
-	a.executeOnce()
+	// Execute immediately once, then periodically. Ticks and forced triggers share
+	// one funnel so cycles never overlap; ticks due while a cycle runs are skipped.
+	if _, err := a.triggers.Trigger(TriggerStartup); err != nil {
+		a.logger.Warnf("⚠️  Failed to trigger startup cycle: %v", err)
+	}
+	a.triggers.loop(a.ctx, ticker.C)
 
-	// Execute periodically
-	for {
-		select {
-		case <-a.ctx.Done():
-			return
-		case <-ticker.C:
-			a.executeOnce()
-		}
+	// Deliver sends still spaced out from the last cycle
+	a.sender.Flush()
+}
+
+// TriggerReport requests an out-of-band report cycle from source, collecting every report
+// type. Triggers pending at the same time are served by a single cycle; the returned channel
+// receives its result.
+func (a *AgentService) TriggerReport(source TriggerSource) (<-chan error, error) {
+	a.schedule.Force()
+	done, err := a.triggers.Trigger(source)
+	if err != nil {
+		a.logger.Debugf("🚫 Report trigger from %s rejected: %v", source, err)
+		return nil, err
 	}
+	a.logger.Debugf("⚡ Report cycle requested by %s", source)
+	return done, nil
+}
+
+// RunSelfTest runs the pipeline self-test now, outside the selftest_interval schedule.
+// The result is also reported with the next status.
+func (a *AgentService) RunSelfTest() selftest.Result {
+	a.logger.Info("🧪 Running self-test on demand")
+	result := a.selfTest.Run()
+	if result.Passed {
+		a.logger.Infof("✅ Self-test passed in %v", result.Duration.Round(time.Millisecond))
+	}
+	return result
+}
+
+// SelfTestStats returns the self-test counters and last result
+func (a *AgentService) SelfTestStats() selftest.Stats {
+	return a.selfTest.Stats()
 }
 
-// executeOnce executes one complete monitoring and reporting cycle
-func (a *AgentService) executeOnce() {
+// executeOnce executes one complete monitoring and reporting cycle.
+// The returned error reflects the status report; subscription and online users
+// reports are best-effort.
+func (a *AgentService) executeOnce() (err error) {
+	a.cycleMutex.Lock()
+	defer a.cycleMutex.Unlock()
+	timing := newCycleTiming()
+	defer a.finishCycle(timing)
+	defer func() { a.health.recordCycle(err) }()
+	defer func() {
+		if r := recover(); r != nil {
+			a.errorCounters.RecordCategory(errstats.InternalPanic)
+			a.logger.Errorf("❌ Recovered panic in monitoring cycle: %v", r)
+			err = fmt.Errorf("panic in monitoring cycle: %v", r)
+		}
+	}()
+
+	// Sends spaced out from the previous cycle must not run into this one
+	timing.begin(stageFlush)
+	a.sender.Flush()
+
+	// xhub asked for a longer interval on the report stream
+	if wait := a.reportClient.Backpressure(); wait > 0 {
+		a.logger.Debugf("⏸️  Skipping cycle, xhub asked to wait %v before the next report", wait.Round(time.Millisecond))
+		return nil
+	}
+
 	a.logger.Debug("🔄 Starting monitoring and reporting cycle")
-	a.logger.Debugf("   🎯 Target gRPC server: %s:%d", a.config.GRPCServer, a.config.GRPCPort)
+	a.logger.Debugf("   🎯 Target gRPC server: %s", a.config.GRPCAddress())
 	a.logger.Debugf("   🆔 Agent UUID: %s", a.config.UUID)
 
+	// Allow one CSRF token refresh per cycle
+	a.authClient.StartCycle()
+
 	// Check authentication status, re-login if needed
+	timing.begin(stageAuth)
 	if err := a.ensureAuthenticated(); err != nil {
-		a.logger.Errorf("❌ Authentication failed: %v", err)
-		return
+		if errors.Is(err, auth.ErrPanelUnreachable) {
+			a.logger.Errorf("❌ 3x-ui unreachable, cannot log in: %v", err)
+		} else {
+			a.logger.Errorf("❌ Authentication failed: %v", err)
+		}
+		a.recordError(err)
+		a.health.setPanelReachable(false)
+		if panelDown(err) {
+			a.observePanel(err)
+		}
+		timing.begin(stageReport)
+		return a.reportHostStatus(err)
+	}
+
+	timing.begin(stageStatus)
+	a.detectPanelVersion()
+
+	// report_status: false, the cycle only sends the other report types
+	if !a.config.StatusReportEnabled() {
+		a.sender.Schedule(a.dueSends()...)
+		return nil
 	}
 
 	// Get server status
@@ -202,101 +1050,315 @@ func (a *AgentService) executeOnce() {
 	status, err := a.monitorClient.GetServerStatus()
 	if err != nil {
 		a.logger.Errorf("❌ Failed to get server status: %v", err)
+		a.recordError(err)
+		a.health.setPanelReachable(false)
+		a.observePanel(err)
 
-		// If it's an authentication error, clear auth status for re-login in next cycle
-		if isAuthError(err) {
+		// If the session was rejected, clear it for re-login in next cycle
+		if a.expireRejectedSession(err) {
 			a.logger.Warn("🔑 Detected authentication error, will re-login in next cycle")
 		}
-		return
+		timing.begin(stageReport)
+		return a.reportHostStatus(err)
 	}
 
 	a.logger.Debug("✅ Successfully retrieved server status from 3x-ui")
+	a.health.setPanelReachable(true)
+	a.observePanel(nil)
+
+	// Attach agent-side collector values (cached between their runs), including the
+	// inbound-derived data (protocols, port listeners)
+	timing.begin(stageCollect)
+	a.publicIPs = []string{status.Data.PublicIP.IPv4, status.Data.PublicIP.IPv6}
+	a.collectors.Apply(status.Data)
+	status.Data.SelfTest = a.selfTest.Status()
+	status.Data.XrayRestart = a.takeXrayRestart()
+	a.superviseXray(status.Data)
+	a.checkAlerts(status.Data)
 
 	// Print data to be reported
-	if statusJSON, err := json.MarshalIndent(status.Data, "", "  "); err == nil {
-		a.logger.Debugf("📋 Data to be reported via gRPC: %s", string(statusJSON))
+	a.logStatusDump(status.Data)
+	a.recordHistory(status.Data, reportSourcePanel)
+
+	// Collect the status into batches of report_batch_size samples, sent in one RPC
+	timing.begin(stageReport)
+	if a.config.ReportBatchSize > 1 && a.reportClient.BatchReportsAvailable() {
+		return a.reportBatched(status.Data)
+	}
+
+	// Once xhub advertised support, send the whole cycle in one RPC (report_combined)
+	if a.reportClient.CombinedReportsAvailable() {
+		return a.reportCombined(status.Data)
 	}
 
 	// Report data to xhub
 	a.logger.Debug("📡 Sending data to xhub via gRPC...")
-	if err := a.reportClient.SendReport(a.config.UUID, status.Data); err != nil {
+	err = a.reportClient.SendReport(a.config.UUID, status.Data)
+	a.lastReport.record(status.Data, reportSourcePanel, err)
+	if err != nil {
 		// Error details are already logged in report.go with deduplication
-		return
+		a.recordError(err)
+		return err
 	}
 
 	a.logger.Debug("✅ Successfully reported data to xhub via gRPC")
 
-	// Report subscription data to xhub (includes current active subscriptions)
-	a.reportSubscriptionData()
+	// Report subscription data (includes current active subscriptions) and online users
+	// data to xhub when due, spaced across the rest of the interval instead of back-to-back
+	a.sender.Schedule(a.dueSends()...)
+	return nil
+}
 
-	// Report online users data to xhub
-	a.reportOnlineUsersData()
+// dueSends returns the subscription and online users reports due this cycle, followed by
+// the backup sends
+func (a *AgentService) dueSends() []func() {
+	due := a.nextReports()
+	var sends []func()
+	if due.subscriptions {
+		sends = append(sends, a.recovered(a.timed(stageSubscriptions, a.reportSubscriptionData)))
+	}
+	if due.onlineUsers {
+		sends = append(sends, a.recovered(a.timed(stageOnlineUsers, a.reportOnlineUsersData)))
+	}
+	return append(sends, a.backupSends()...)
 }
 
-// reportSubscriptionData gets and reports subscription data
-func (a *AgentService) reportSubscriptionData() {
-	a.logger.Debug("🔄 Starting subscription data collection and reporting")
+// nextReports returns the report types due this cycle, leaving out the ones turned off
+// (report_subscriptions, report_online_users)
+func (a *AgentService) nextReports() reportsDue {
+	due := a.schedule.Next()
+	due.subscriptions = due.subscriptions && a.config.SubscriptionReportEnabled()
+	due.onlineUsers = due.onlineUsers && a.config.OnlineUsersReportEnabled()
+	return due
+}
 
-	// Get all subscription data
-	subscriptions, err := a.subscriptionClient.GetAllSubscriptionData()
+// reportHostStatus reports host metrics read from /proc while 3x-ui is unavailable, with Xray
+// marked unreachable, so xhub still sees the node's health. It returns panelErr: the cycle
+// failed to reach the panel even if the fallback report was delivered.
+func (a *AgentService) reportHostStatus(panelErr error) error {
+	if a.hostCollector == nil || !a.config.StatusReportEnabled() {
+		return panelErr
+	}
+
+	data := a.hostCollector.Collect(fmt.Sprintf("3x-ui unavailable (%s)", errstats.Categorize(panelErr)))
+	if len(a.publicIPs) == 2 {
+		data.PublicIP = monitor.PublicIPInfo{IPv4: a.publicIPs[0], IPv6: a.publicIPs[1]} // Last known
+	}
+	a.collectors.Apply(data)
+	data.SelfTest = a.selfTest.Status()
+	a.checkAlerts(data)
+	a.logStatusDump(data)
+	a.recordHistory(data, reportSourceHost)
+
+	a.logger.Debug("🩺 Sending host metrics to xhub while 3x-ui is unavailable...")
+	err := a.reportClient.SendReport(a.config.UUID, data)
+	a.lastReport.record(data, reportSourceHost, err)
 	if err != nil {
-		a.logger.Errorf("❌ Failed to get subscription data: %v", err)
-		return
+		a.recordError(err)
 	}
+	return panelErr
+}
 
-	a.logger.Debugf("📋 Raw subscription data count: %d", len(subscriptions))
+// reportCombined sends the status, and the online users and subscriptions when due, in one
+// RPC. If xhub turns out not to implement it, the collected payloads are sent as separate
+// RPCs instead.
+func (a *AgentService) reportCombined(data *monitor.ServerStatusData) error {
+	due := a.nextReports()
+	var online *report.CombinedOnlineUsers
+	if due.onlineUsers {
+		online = a.collectOnlineUsers()
+	}
+	var subscriptions []report.SubscriptionData
+	if due.subscriptions {
+		subscriptions = a.collectSubscriptionData()
+	}
 
-	if len(subscriptions) == 0 {
-		a.logger.Debug("📋 No subscription data found, skipping subscription report")
+	a.logger.Debug("📡 Sending combined report to xhub via gRPC...")
+	err := a.reportClient.SendCombinedReport(a.config.UUID, data, online, subscriptions)
+	if errors.Is(err, report.ErrCombinedUnsupported) {
+		err := a.reportClient.SendReport(a.config.UUID, data)
+		a.lastReport.record(data, reportSourcePanel, err)
+		if err != nil {
+			a.recordError(err)
+			return err
+		}
+		var sends []func()
+		if len(subscriptions) > 0 {
+			sends = append(sends, a.recovered(a.timed(stageSubscriptions, func() { a.sendSubscriptionData(subscriptions) })))
+		}
+		if online != nil {
+			sends = append(sends, a.recovered(a.timed(stageOnlineUsers, func() { a.sendOnlineUsers(online) })))
+		}
+		a.sender.Schedule(append(sends, a.backupSends()...)...)
+		return nil
+	}
+	a.lastReport.record(data, reportSourcePanel, err)
+	if err != nil {
+		// Error details are already logged in report.go with deduplication
+		a.recordError(err)
+		return err
+	}
+
+	a.logger.Debug("✅ Successfully sent combined report to xhub via gRPC")
+	a.sender.Schedule(a.backupSends()...)
+	return nil
+}
+
+// recovered wraps a deferred send so that a panic is logged and counted instead of
+// crashing the agent (it no longer runs under executeOnce's recover)
+func (a *AgentService) recovered(send func()) func() {
+	return func() {
+		defer func() {
+			if r := recover(); r != nil {
+				a.errorCounters.RecordCategory(errstats.InternalPanic)
+				a.logger.Errorf("❌ Recovered panic in deferred report send: %v", r)
+			}
+		}()
+		send()
+	}
+}
+
+// logStatusDump logs the status to be reported at debug level, indented or compact
+// depending on log_status_compact, unless log_status_dump is false
+func (a *AgentService) logStatusDump(data *monitor.ServerStatusData) {
+	if !a.config.StatusDumpEnabled() {
 		return
 	}
 
-	a.logger.Debugf("📋 Found %d unique subscriptions to report", len(subscriptions))
+	var statusJSON []byte
+	var err error
+	if a.config.LogStatusCompact {
+		statusJSON, err = json.Marshal(data)
+	} else {
+		statusJSON, err = json.MarshalIndent(data, "", "  ")
+	}
+	if err == nil {
+		a.logger.Debugf("📋 Data to be reported via gRPC: %s", string(statusJSON))
+	}
+}
 
-	// Get Hysteria2 node config if enabled
-	var hy2NodeRaw string
-	if a.hysteria2Client.IsEnabled() {
-		rawURI, err := a.hysteria2Client.GetNodeConfigRaw()
-		if err != nil {
-			a.logger.Warnf("⚠️ Failed to get Hysteria2 node config: %v", err)
-		} else {
-			hy2NodeRaw = rawURI
-			a.logger.Debugf("🚀 Hysteria2 node URI: %s", hy2NodeRaw)
-		}
+// Inbound list collector: its collector_intervals name and default interval
+const (
+	inboundsCollectorName = "inbounds"
+	inboundsInterval      = time.Minute
+)
+
+// inboundsCollector returns the collector of the inbound-derived data. The inbound list holds
+// the settings of every client, so it is fetched at its own interval and not every cycle.
+func (a *AgentService) inboundsCollector() collector.Collector {
+	return collector.Collector{Name: inboundsCollectorName, Interval: inboundsInterval, Collect: a.collectInbounds}
+}
+
+// collectInbounds gets the inbound list, snapshots it for inbound_backup and returns how to
+// attach the inbound protocols and, if enabled, the listener of every enabled inbound port.
+// The listeners are detected every cycle, on the ports of the cached list.
+func (a *AgentService) collectInbounds() collector.Apply {
+	body, err := a.subscriptionClient.GetInboundListBody()
+	var inbounds []*subscription.InboundInfo
+	if err == nil {
+		a.snapshotInbounds(body)
+		inbounds, err = subscription.DecodeInboundList(body)
+	}
+	if err != nil {
+		a.logger.Warnf("⚠️ Failed to get inbound list: %v", err)
+		a.recordError(err)
+		return nil
 	}
 
-	// Convert to report format
-	var reportSubs []report.SubscriptionData
-	for _, sub := range subscriptions {
-		nodeConfig := sub.NodeConfig
+	protocols := subscription.ExtractProtocols(inbounds)
+	a.logger.Debugf("🧾 Inbound protocols: %v", protocols)
+	a.inboundCertFiles = subscription.ExtractCertFiles(inbounds)
 
-		// Inject Hysteria2 node if available
-		// The NodeConfig is base64 encoded, we need to decode, append, and re-encode
-		if hy2NodeRaw != "" && nodeConfig != "" {
-			decoded, err := base64.StdEncoding.DecodeString(nodeConfig)
-			if err == nil {
-				// Append Hysteria2 URI to the decoded content
-				combined := string(decoded)
-				if !endsWithNewline(combined) {
-					combined += "\n"
-				}
-				combined += hy2NodeRaw
-				nodeConfig = base64.StdEncoding.EncodeToString([]byte(combined))
+	var ports []int
+	for _, inbound := range inbounds {
+		if inbound.Enable {
+			ports = append(ports, inbound.Port)
+		}
+	}
+
+	return func(data *monitor.ServerStatusData) {
+		// Merged, the protocols served outside 3x-ui may be attached already
+		for _, protocol := range protocols {
+			if !slices.Contains(data.InboundProtocols, protocol) {
+				data.InboundProtocols = append(data.InboundProtocols, protocol)
 			}
 		}
+		slices.Sort(data.InboundProtocols)
 
-		reportSub := report.SubscriptionData{
-			SubID:      sub.SubID,
-			Email:      sub.Email,
-			NodeConfig: nodeConfig,
-			Headers: report.SubscriptionHeaders{ // Convert headers to report package type
-				ProfileTitle:          sub.Headers.ProfileTitle,
-				ProfileUpdateInterval: sub.Headers.ProfileUpdateInterval,
-				SubscriptionUserinfo:  sub.Headers.SubscriptionUserinfo,
-			},
+		if a.portDetector == nil {
+			return
 		}
-		reportSubs = append(reportSubs, reportSub)
+		data.PortListeners = a.portDetector.Detect(ports)
+		a.logger.Debugf("🔌 Port listeners: %+v", data.PortListeners)
+	}
+}
+
+// certSources returns the certificate files referenced by the Hysteria2 config and the
+// inbounds of the last inbound list
+func (a *AgentService) certSources() []certfile.Source {
+	var sources []certfile.Source
+	if path := a.hysteria2Client.CertFile(); path != "" {
+		sources = append(sources, certfile.Source{Path: path, Origin: "hysteria2"})
+	}
+	for _, file := range a.inboundCertFiles {
+		sources = append(sources, certfile.Source{Path: file.CertFile, Origin: fmt.Sprintf("inbound:%d", file.Port)})
+	}
+	return sources
+}
+
+// dnsCheckPublicIPs returns the public IPs a checked domain must point to: the configured
+// ones, or those of the last status
+func (a *AgentService) dnsCheckPublicIPs() []string {
+	if len(a.config.DNSCheckPublicIPs) > 0 {
+		return a.config.DNSCheckPublicIPs
+	}
+	return a.publicIPs
+}
+
+// reportSubscriptionData gets and reports subscription data
+func (a *AgentService) reportSubscriptionData() {
+	reportSubs := a.collectSubscriptionData()
+	if len(reportSubs) == 0 {
+		return
 	}
+	a.sendSubscriptionData(reportSubs)
+}
+
+// sendSubscriptionData reports collected subscription data in its own RPC
+func (a *AgentService) sendSubscriptionData(reportSubs []report.SubscriptionData) {
+	a.logger.Debug("📡 Sending subscription data to xhub via gRPC...")
+	if err := a.reportClient.SendSubscriptionReport(a.config.UUID, reportSubs); err != nil {
+		// Error details are already logged in report.go with deduplication
+		a.recordError(err)
+		return
+	}
+
+	a.logger.Debug("✅ Successfully reported subscription data to xhub via gRPC")
+}
+
+// collectSubscriptionData gets the subscription data to report, nil if there is nothing to
+// report this cycle (skipped, failed or no subscriptions)
+func (a *AgentService) collectSubscriptionData() []report.SubscriptionData {
+	a.logger.Debug("🔄 Starting subscription data collection and reporting")
+
+	if !a.checkResolvedDomain() {
+		a.logger.Debugf("⏭️  Skipping subscription report: resolved domain %s does not resolve", a.domainChecker.Domain())
+		return nil
+	}
+
+	reportSubs, err := a.subscriptionReport()
+	if err != nil {
+		a.logger.Errorf("❌ Failed to get subscription data: %v", err)
+		a.recordError(err)
+		return nil
+	}
+
+	if len(reportSubs) == 0 {
+		a.logger.Debug("📋 No subscription data found, skipping subscription report")
+		return nil
+	}
+
+	a.logger.Debugf("📋 Found %d unique subscriptions to report", len(reportSubs))
 
 	// Check if this is the first time reporting subscription data
 	a.firstSubReportMux.Lock()
@@ -340,32 +1402,162 @@ func (a *AgentService) reportSubscriptionData() {
 			}
 		}
 	}
+	return reportSubs
+}
 
-	// Report data to xhub
-	a.logger.Debug("📡 Sending subscription data to xhub via gRPC...")
-	if err := a.reportClient.SendSubscriptionReport(a.config.UUID, reportSubs); err != nil {
-		// Error details are already logged in report.go with deduplication
-		return
+// subscriptionReport gets the subscriptions of the panel in report format, with the nodes
+// running outside 3x-ui (Hysteria2, TUIC, ShadowTLS) appended to their node configs
+func (a *AgentService) subscriptionReport() ([]report.SubscriptionData, error) {
+	// Get all subscription data
+	subscriptions, err := a.subscriptionClient.GetAllSubscriptionData()
+	if err != nil {
+		return nil, err
 	}
 
-	a.logger.Debug("✅ Successfully reported subscription data to xhub via gRPC")
+	a.logger.Debugf("📋 Raw subscription data count: %d", len(subscriptions))
+	if len(subscriptions) == 0 {
+		return nil, nil
+	}
+
+	// Get the links of the protocols outside 3x-ui, shared or per subscriber
+	links := make([]nodeprovider.ShareLinks, len(a.nodeProviders))
+	for i, provider := range a.nodeProviders {
+		providerLinks, err := provider.BuildShareLinks()
+		switch {
+		case err != nil:
+			a.logger.Warnf("⚠️ Failed to get %s node config: %v", provider.Name(), err)
+		case providerLinks.Shared != "":
+			a.logger.Debugf("🚀 %s node URI: %s", provider.Name(), providerLinks.Shared)
+		case !providerLinks.Empty():
+			a.logger.Debugf("🚀 %s node configs for %d users", provider.Name(), len(providerLinks.PerUser))
+		}
+		links[i] = providerLinks
+	}
+
+	// Convert to report format
+	var reportSubs []report.SubscriptionData
+	without := make([]int, len(a.nodeProviders))
+	for _, sub := range subscriptions {
+		nodeConfig := sub.NodeConfig
+		var extraURIs []string
+		var wireguardConfig string
+		for i := range links {
+			uri := links[i].URIFor(sub.Email)
+			switch {
+			case uri == "" && len(links[i].PerUser) > 0:
+				without[i]++
+			case uri == "":
+			case links[i].WireGuardConfig:
+				wireguardConfig = uri
+			default:
+				extraURIs = append(extraURIs, uri)
+			}
+		}
+
+		// Inject the extra nodes if available
+		// The NodeConfig is base64 encoded, we need to decode, append, and re-encode
+		if len(extraURIs) > 0 && nodeConfig != "" {
+			decoded, err := base64.StdEncoding.DecodeString(nodeConfig)
+			if err == nil {
+				// Append the URIs to the decoded content
+				combined := string(decoded)
+				if !endsWithNewline(combined) {
+					combined += "\n"
+				}
+				combined += strings.Join(extraURIs, "\n")
+				nodeConfig = base64.StdEncoding.EncodeToString([]byte(combined))
+			}
+		}
+
+		reportSub := report.SubscriptionData{
+			SubID:      sub.SubID,
+			Email:      sub.Email,
+			NodeConfig: nodeConfig,
+			JSONConfig: sub.JSONConfig,
+			WireGuard:  wireguardConfig,
+			Headers: report.SubscriptionHeaders{ // Convert headers to report package type
+				ProfileTitle:          sub.Headers.ProfileTitle,
+				ProfileUpdateInterval: sub.Headers.ProfileUpdateInterval,
+				SubscriptionUserinfo:  sub.Headers.SubscriptionUserinfo,
+			},
+			StaleSuspect: sub.StaleSuspect,
+			StaleReason:  sub.StaleReason,
+		}
+		reportSubs = append(reportSubs, reportSub)
+	}
+	for i, count := range without {
+		if count > 0 {
+			a.logger.Debugf("🚀 %d subscriptions without %s credentials, their %s node is omitted", count, a.nodeProviders[i].Name(), a.nodeProviders[i].Name())
+		}
+	}
+	return reportSubs, nil
+}
+
+// checkResolvedDomain checks that resolvedDomain resolves, logging state changes once.
+// It returns false if subscription reporting must be skipped (strict mode).
+func (a *AgentService) checkResolvedDomain() bool {
+	if a.domainChecker == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(a.ctx, 5*time.Second)
+	defer cancel()
+
+	changed, err := a.domainChecker.Check(ctx)
+	if err == nil {
+		if changed {
+			a.logger.Debugf("✅ Resolved domain %s resolves", a.domainChecker.Domain())
+		}
+		return true
+	}
+
+	if changed {
+		if a.domainCheckMode == subscription.DomainCheckStrict {
+			a.logger.Errorf("❌ %v, subscription reporting paused until it resolves", err)
+		} else {
+			a.logger.Warnf("⚠️  %v, reported node configs may point at an unresolvable host", err)
+		}
+	}
+	return a.domainCheckMode != subscription.DomainCheckStrict
 }
 
 // reportOnlineUsersData gets and reports online users data
 func (a *AgentService) reportOnlineUsersData() {
+	if online := a.collectOnlineUsers(); online != nil {
+		a.sendOnlineUsers(online)
+	}
+}
+
+// sendOnlineUsers reports a collected online users list in its own RPC
+func (a *AgentService) sendOnlineUsers(online *report.CombinedOnlineUsers) {
+	a.logger.Debug("📡 Sending online users data to xhub via gRPC...")
+	if err := a.reportClient.SendOnlineUsers(a.config.UUID, online); err != nil {
+		// Error details are already logged in report.go with deduplication
+		a.recordError(err)
+		return
+	}
+
+	a.logger.Debug("✅ Successfully reported online users data to xhub via gRPC")
+}
+
+// collectOnlineUsers gets the online users list to report: the live list, the last one
+// within online_users_max_staleness after a failed fetch, or nil if there is none
+func (a *AgentService) collectOnlineUsers() *report.CombinedOnlineUsers {
 	a.logger.Debug("🔄 Starting online users data collection and reporting")
 
 	// Get online users data
 	onlineResp, err := a.monitorClient.GetOnlineUsers()
 	if err != nil {
 		a.logger.Errorf("❌ Failed to get online users data: %v", err)
+		a.recordError(err)
 
-		// If it's an authentication error, clear auth status for re-login in next cycle
-		if isAuthError(err) {
+		// If the session was rejected, clear it for re-login in next cycle
+		if a.expireRejectedSession(err) {
 			a.logger.Warn("🔑 Detected authentication error in online users check, will re-login in next cycle")
 		}
-		return
+		return a.staleOnlineUsers()
 	}
+	a.onlineUsers.Store(onlineResp.Data)
 
 	a.logger.Debugf("📋 Found %d online users", len(onlineResp.Data))
 
@@ -375,19 +1567,77 @@ func (a *AgentService) reportOnlineUsersData() {
 	} else {
 		a.logger.Debug("👥 No users currently online")
 	}
+	return &report.CombinedOnlineUsers{Emails: onlineResp.Data, Users: a.collectOnlineUserDetails(onlineResp.Data)}
+}
 
-	// Report data to xhub
-	a.logger.Debug("📡 Sending online users data to xhub via gRPC...")
-	if err := a.reportClient.SendOnlineUsersReport(a.config.UUID, onlineResp.Data); err != nil {
-		// Error details are already logged in report.go with deduplication
-		return
+// collectOnlineUserDetails gets the source IPs of the online users (online_user_details).
+// A failure is logged once per distinct error and the list is reported without details.
+func (a *AgentService) collectOnlineUserDetails(emails []string) []monitor.OnlineUser {
+	if !a.monitorClient.UserDetailsEnabled() || len(emails) == 0 {
+		return nil
+	}
+	users, err := a.monitorClient.GetOnlineUserDetails(emails)
+	if err != nil {
+		a.recordError(err)
+		if err.Error() != a.userDetailsErr {
+			a.logger.Warnf("⚠️  Failed to get online user details, reporting emails only: %v", err)
+			a.userDetailsErr = err.Error()
+		}
+		return nil
+	}
+	a.userDetailsErr = ""
+	a.logger.Debugf("🕵️  Collected the source IPs of %d online users", len(users))
+	return users
+}
+
+// staleOnlineUsers returns the last online users list after a failed fetch while it is
+// within online_users_max_staleness, and an empty list once it is older
+func (a *AgentService) staleOnlineUsers() *report.CombinedOnlineUsers {
+	emails, age, ok, expiredNow := a.onlineUsers.Fallback()
+	if !ok {
+		return nil
 	}
+	if expiredNow {
+		a.logger.Warnf("⚠️  Online users list is %v old (limit %v), reporting no online users until 3x-ui answers",
+			age.Round(time.Second), a.onlineUsers.maxStaleness)
+	} else if len(emails) > 0 {
+		a.logger.Debugf("♻️  Reusing online users list from %v ago (%d users)", age.Round(time.Second), len(emails))
+	}
+	return &report.CombinedOnlineUsers{Emails: emails, Age: age}
+}
 
-	a.logger.Debug("✅ Successfully reported online users data to xhub via gRPC")
+// withReportClient runs fn outside the report cycles: the report client is shared with them,
+// so fn waits for the running cycle and runs once its spaced sends are flushed
+func (a *AgentService) withReportClient(fn func()) {
+	a.cycleMutex.Lock()
+	defer a.cycleMutex.Unlock()
+	a.sender.Flush()
+	fn()
 }
 
-// ensureAuthenticated ensures authentication, attempts login if not authenticated
+// recordError counts err in its error category
+func (a *AgentService) recordError(err error) {
+	if category := a.errorCounters.Record(err); category == errstats.Unknown {
+		a.logger.Debugf("📈 Uncategorized error counted as unknown: %v", err)
+	}
+}
+
+// ensureAuthenticated ensures authentication, attempts login if not authenticated. Once the
+// assumed session lifetime elapsed, the panel is asked whether the session is still valid
+// before logging in again.
 func (a *AgentService) ensureAuthenticated() error {
+	if a.authClient.IsAuthenticated() && a.authClient.IsSessionExpired() {
+		err := a.authClient.ValidateSession()
+		if err == nil {
+			a.logger.Debug("🔑 3x-ui session still valid, keeping it")
+			return nil
+		}
+		if errors.Is(err, auth.ErrPanelUnreachable) {
+			return err
+		}
+		a.logger.Debugf("🔑 3x-ui session no longer valid: %v", err)
+	}
+
 	// Check if re-authentication is needed
 	if !a.authClient.IsAuthenticated() || a.authClient.IsSessionExpired() {
 		a.logger.Info("Logging into 3x-ui...")
@@ -402,37 +1652,14 @@ func (a *AgentService) ensureAuthenticated() error {
 	return nil
 }
 
-// isAuthError checks if the error is authentication-related
-func isAuthError(err error) bool {
-	if err == nil {
+// expireRejectedSession drops the session after the panel rejected it (auth.ErrAuthExpired),
+// so that the next cycle logs in again instead of reusing it until session_ttl elapses
+func (a *AgentService) expireRejectedSession(err error) bool {
+	if !errors.Is(err, auth.ErrAuthExpired) {
 		return false
 	}
-
-	errorMsg := err.Error()
-	return contains(errorMsg, "unauthenticated") ||
-		contains(errorMsg, "session") ||
-		contains(errorMsg, "authentication") ||
-		contains(errorMsg, "unauthorized")
-}
-
-// contains checks if string contains substring (case insensitive)
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) &&
-		(s == substr ||
-			len(s) > len(substr) &&
-				(s[:len(substr)] == substr ||
-					s[len(s)-len(substr):] == substr ||
-					containsSubstring(s, substr)))
-}
-
-// containsSubstring checks if it contains substring
-func containsSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
+	a.authClient.Expire(a.authClient.GetSessionToken())
+	return true
 }
 
 // endsWithNewline checks if a string ends with a newline character
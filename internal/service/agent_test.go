@@ -1,7 +1,9 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -9,11 +11,20 @@ import (
 	"path/filepath"
 	"strconv"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/datadir"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/selftest"
+	"xhub-agent/internal/subscription"
+	"xhub-agent/pkg/logger"
 )
 
 func TestAgentService_NewAgentService(t *testing.T) {
@@ -223,3 +234,216 @@ log_level: debug
 	require.NoError(t, err)
 	assert.Contains(t, string(logContent), "username or password incorrect")
 }
+
+// readOnlyProber simulates a read-only data directory
+type readOnlyProber struct{}
+
+func (readOnlyProber) Probe(dir string) error {
+	return &datadir.ProbeError{Dir: dir, Reason: datadir.ReasonReadOnly, Err: syscall.EROFS}
+}
+
+func writeDataDirTestConfig(t *testing.T, extra string) (string, string) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yml")
+	configContent := `uuid: test-uuid-123
+xui_user: admin
+xui_pass: password123
+xhub_api_key: abcd1234apikey
+grpcServer: localhost
+grpcPort: 9090
+rootPath: /test
+port: 54321
+` + extra
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+	return configPath, filepath.Join(tmpDir, "logs", "agent.log")
+}
+
+func TestAgentService_ReadOnlyDataDir_Degrades(t *testing.T) {
+	configPath, logFile := writeDataDirTestConfig(t, "")
+
+	agent, err := newAgentService(configPath, logFile, readOnlyProber{})
+	require.NoError(t, err)
+	defer agent.Close()
+
+	assert.False(t, agent.dataDir.Writable())
+	// Only the features the config turns on are reported disabled
+	assert.Equal(t, []datadir.Artifact{datadir.ArtifactState, datadir.ArtifactCrash, datadir.ArtifactQueue},
+		agent.dataDir.DisabledArtifacts(dataArtifacts(agent.config)))
+
+	// Logger is stdout-only, so no log file must be created
+	_, err = os.Stat(logFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAgentService_ReadOnlyDataDir_Strict(t *testing.T) {
+	configPath, logFile := writeDataDirTestConfig(t, "require_writable_data_dir: true\n")
+
+	_, err := newAgentService(configPath, logFile, readOnlyProber{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read-only filesystem")
+}
+
+func TestAgentService_WritableDataDir(t *testing.T) {
+	configPath, logFile := writeDataDirTestConfig(t, "")
+
+	agent, err := NewAgentService(configPath, logFile)
+	require.NoError(t, err)
+	defer agent.Close()
+
+	assert.True(t, agent.dataDir.Writable())
+	assert.Equal(t, filepath.Dir(logFile), agent.dataDir.Root())
+
+	// Log directory is created and log file is written as before
+	_, err = os.Stat(logFile)
+	assert.NoError(t, err)
+}
+
+// staticResolver resolves only the listed hosts
+type staticResolver map[string]string
+
+func (r staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip, ok := r[host]; ok {
+		return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestAgentService_CheckResolvedDomain(t *testing.T) {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "agent.log"), "debug")
+	require.NoError(t, err)
+	defer log.Close()
+
+	resolver := staticResolver{"sub.example.com": "192.0.2.10"}
+	newAgent := func(domain string, mode subscription.DomainCheckMode) *AgentService {
+		checker := subscription.NewDomainChecker(domain, time.Minute)
+		checker.SetResolver(resolver)
+		return &AgentService{logger: log, ctx: context.Background(), domainChecker: checker, domainCheckMode: mode}
+	}
+
+	assert.True(t, newAgent("sub.example.com", subscription.DomainCheckStrict).checkResolvedDomain())
+	assert.True(t, newAgent("gone.example.com", subscription.DomainCheckWarn).checkResolvedDomain(),
+		"warn mode keeps reporting")
+	assert.False(t, newAgent("gone.example.com", subscription.DomainCheckStrict).checkResolvedDomain(),
+		"strict mode skips subscription reporting")
+
+	// No checker (off or no resolvedDomain) never blocks
+	assert.True(t, (&AgentService{logger: log}).checkResolvedDomain())
+}
+
+func TestAgentService_LogStatusDump(t *testing.T) {
+	data := &monitor.ServerStatusData{CPU: 12.5, InboundProtocols: []string{"vless"}}
+	disabled := false
+
+	tests := []struct {
+		name   string
+		config config.Config
+		check  func(t *testing.T, dump string)
+	}{
+		{"indented by default", config.Config{}, func(t *testing.T, dump string) {
+			assert.Contains(t, dump, "Data to be reported")
+			assert.Contains(t, dump, "\n  \"cpu\": 12.5")
+		}},
+		{"compact", config.Config{LogStatusCompact: true}, func(t *testing.T, dump string) {
+			assert.Contains(t, dump, `Data to be reported via gRPC: {"cpu":12.5,`)
+			assert.NotContains(t, dump, "\n  \"cpu\"")
+		}},
+		{"disabled", config.Config{LogStatusDump: &disabled}, func(t *testing.T, dump string) {
+			assert.NotContains(t, dump, "Data to be reported")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "agent.log")
+			log, err := logger.NewLogger(logPath, "debug")
+			require.NoError(t, err)
+			defer log.Close()
+
+			agent := &AgentService{logger: log, config: &tt.config}
+			agent.logStatusDump(data)
+
+			content, err := os.ReadFile(logPath)
+			require.NoError(t, err)
+			tt.check(t, string(content))
+		})
+	}
+}
+
+func TestAgentService_SelfTestSchedule(t *testing.T) {
+	configPath, logFile := writeDataDirTestConfig(t, "")
+	agent, err := NewAgentService(configPath, logFile)
+	require.NoError(t, err)
+	defer agent.Close()
+
+	interval, ok := agent.collectors.Interval(selftest.CollectorName)
+	assert.True(t, ok, "scheduled daily by default")
+	assert.Equal(t, selftest.DefaultInterval, interval)
+
+	configPath, logFile = writeDataDirTestConfig(t, "selftest_interval: 0\n")
+	disabled, err := NewAgentService(configPath, logFile)
+	require.NoError(t, err)
+	defer disabled.Close()
+
+	_, ok = disabled.collectors.Interval(selftest.CollectorName)
+	assert.False(t, ok, "selftest_interval: 0 disables the schedule")
+}
+
+func TestAgentService_RunSelfTestOnDemand(t *testing.T) {
+	configPath, logFile := writeDataDirTestConfig(t, "selftest_interval: 0\nemail_reporting: pseudonym\n")
+	agent, err := NewAgentService(configPath, logFile)
+	require.NoError(t, err)
+	defer agent.Close()
+
+	result := agent.RunSelfTest()
+	assert.True(t, result.Passed, "failures: %v", result.Failures)
+	assert.Equal(t, uint64(1), agent.SelfTestStats().Runs)
+
+	// Fixture emails never get pseudonyms in the persisted state
+	assert.Empty(t, agent.stateStore.Get().EmailPseudonyms)
+}
+
+func TestAgentService_EnsureAuthenticatedValidatesSession(t *testing.T) {
+	var logins, checks atomic.Int32
+	var sessionValid atomic.Bool
+	sessionValid.Store(true)
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			logins.Add(1)
+			http.SetCookie(w, &http.Cookie{Name: "3x-ui", Value: "session"})
+			w.Write([]byte(`{"success":true}`))
+		case "/panel/setting/defaultSettings":
+			checks.Add(1)
+			if !sessionValid.Load() {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"success":true,"obj":{}}`))
+		}
+	}))
+	defer panel.Close()
+
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	defer log.Close()
+	authClient := auth.NewXUIAuth(panel.URL, "admin", "password")
+	authClient.SetSessionTTL(time.Nanosecond)
+	agent := &AgentService{logger: log, authClient: authClient}
+
+	require.NoError(t, agent.ensureAuthenticated())
+	assert.Equal(t, int32(1), logins.Load())
+	assert.Zero(t, checks.Load(), "no session to check yet")
+
+	// The assumed lifetime elapsed but the panel still accepts the session
+	time.Sleep(time.Millisecond)
+	require.NoError(t, agent.ensureAuthenticated())
+	assert.Equal(t, int32(1), logins.Load())
+	assert.Equal(t, int32(1), checks.Load())
+
+	// The panel dropped the session
+	sessionValid.Store(false)
+	time.Sleep(time.Millisecond)
+	require.NoError(t, agent.ensureAuthenticated())
+	assert.Equal(t, int32(2), logins.Load())
+	assert.Equal(t, int32(2), checks.Load())
+}
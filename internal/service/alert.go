@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+
+	"xhub-agent/internal/alert"
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// alertQueueSize is the number of alert events waiting for the hooks before new ones are dropped
+const alertQueueSize = 32
+
+// alerting checks the statuses against the alert_* thresholds and hands the alerts that fire
+// or resolve to the local hooks, independently of xhub
+type alerting struct {
+	evaluator *alert.Evaluator // Used by the report cycles only (cycleMutex)
+	hooks     []alert.Hook
+	events    chan alert.Event
+}
+
+// newAlerting creates the alerting of the alert_* options, nil when no threshold is set
+func newAlerting(cfg *config.Config, log *logger.Logger) *alerting {
+	thresholds := alert.Thresholds{
+		CPU:            cfg.AlertCPU,
+		Memory:         cfg.AlertMemory,
+		Disk:           cfg.AlertDisk,
+		XrayDown:       cfg.AlertXrayDown,
+		CertExpiryDays: cfg.AlertCertExpiryDays,
+		Cycles:         cfg.AlertCycles,
+	}
+	if !thresholds.Enabled() {
+		return nil
+	}
+
+	a := &alerting{evaluator: alert.NewEvaluator(thresholds), events: make(chan alert.Event, alertQueueSize)}
+	if cfg.AlertExec != "" {
+		a.hooks = append(a.hooks, alert.NewExecHook(cfg.AlertExec))
+	}
+	if cfg.AlertWebhook != "" {
+		a.hooks = append(a.hooks, alert.NewWebhookHook(cfg.AlertWebhook))
+	}
+	if cfg.AlertTelegramToken != "" {
+		a.hooks = append(a.hooks, alert.NewTelegramHook(cfg.AlertTelegramToken, cfg.AlertTelegramChat))
+	}
+
+	if len(a.hooks) == 0 {
+		log.Warnf("⚠️  Alert thresholds set without alert_exec, alert_webhook or alert_telegram_token, alerts are only logged")
+	} else {
+		names := make([]string, len(a.hooks))
+		for i, hook := range a.hooks {
+			names[i] = hook.Name()
+		}
+		log.Infof("🚨 Local alerts enabled (hooks: %v)", names)
+	}
+	if cfg.AlertCertExpiryDays > 0 && !cfg.CollectCertExpiry {
+		log.Warnf("⚠️  alert_cert_expiry_days needs collect_cert_expiry, certificate alerts will not fire")
+	}
+	return a
+}
+
+// checkAlerts evaluates the status of the cycle and queues the alerts that fired or resolved
+// for the hooks. Called by the report cycles.
+func (a *AgentService) checkAlerts(data *monitor.ServerStatusData) {
+	if a.alerts == nil {
+		return
+	}
+	uuid := a.Config().UUID
+	for _, event := range a.alerts.evaluator.Evaluate(data) {
+		event.UUID, event.Host = uuid, a.hostInfo.Hostname
+		if event.State == alert.StateFiring {
+			a.logger.Warnf("🚨 Alert %s: %s", event.Alert, event.Message)
+		} else {
+			a.logger.Infof("✅ Alert %s resolved: %s", event.Alert, event.Message)
+		}
+		if len(a.alerts.hooks) == 0 {
+			continue
+		}
+		select {
+		case a.alerts.events <- event:
+		default:
+			a.logger.Warnf("⚠️  Alert hooks are falling behind, dropped alert %s (%s)", event.Alert, event.State)
+		}
+	}
+}
+
+// runAlertHooks delivers the queued alerts to every hook, one event at a time
+func (a *AgentService) runAlertHooks() {
+	defer a.wg.Done()
+	defer a.crashes.Recover("alert hooks")
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case event := <-a.alerts.events:
+			for _, hook := range a.alerts.hooks {
+				ctx, cancel := context.WithTimeout(a.ctx, alert.HookTimeout)
+				err := hook.Send(ctx, event)
+				cancel()
+				if err != nil && a.ctx.Err() == nil {
+					a.logger.Warnf("⚠️  Alert hook %s failed for %s: %v", hook.Name(), event.Alert, err)
+				}
+			}
+		}
+	}
+}
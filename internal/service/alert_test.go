@@ -0,0 +1,64 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/alert"
+	"xhub-agent/internal/monitor"
+)
+
+func TestAgentService_AlertHooks(t *testing.T) {
+	received := make(chan alert.Event, 4)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event alert.Event
+		if json.NewDecoder(r.Body).Decode(&event) == nil {
+			received <- event
+		}
+	}))
+	defer webhook.Close()
+
+	agent := newCommandTestAgent(t, &commandXHub{}, "alert_cpu: 80\nalert_xray_down: true\nalert_webhook: "+webhook.URL+"\n")
+	require.NotNil(t, agent.alerts)
+	agent.wg.Add(1)
+	go agent.runAlertHooks()
+	defer func() {
+		agent.cancel()
+		agent.wg.Wait()
+	}()
+
+	next := func() alert.Event {
+		select {
+		case event := <-received:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no alert delivered")
+			return alert.Event{}
+		}
+	}
+
+	agent.checkAlerts(&monitor.ServerStatusData{CPU: 95, Xray: monitor.XrayInfo{State: "running"}})
+	event := next()
+	assert.Equal(t, alert.AlertCPU, event.Alert)
+	assert.Equal(t, alert.StateFiring, event.State)
+	assert.Equal(t, agent.config.UUID, event.UUID)
+	assert.Equal(t, agent.hostInfo.Hostname, event.Host)
+
+	agent.checkAlerts(&monitor.ServerStatusData{CPU: 10, Xray: monitor.XrayInfo{State: "stop"}})
+	event = next()
+	assert.Equal(t, []string{alert.AlertCPU, alert.StateResolved}, []string{event.Alert, event.State})
+	event = next()
+	assert.Equal(t, []string{alert.AlertXrayDown, alert.StateFiring}, []string{event.Alert, event.State})
+}
+
+func TestAgentService_AlertsDisabled(t *testing.T) {
+	agent := newReloadTestAgent(t)
+	assert.Nil(t, agent.alerts)
+	assert.NotPanics(t, func() { agent.checkAlerts(&monitor.ServerStatusData{CPU: 100}) })
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"xhub-agent/internal/backup"
+	"xhub-agent/internal/report"
+)
+
+const (
+	backupRetryInterval    = time.Minute // Wait after a failed backup send
+	backupUnsupportedRetry = time.Hour   // Wait while xhub does not implement backups
+)
+
+// snapshotInbounds takes a snapshot of the inbound list body and keeps it for this cycle's
+// sends if the configuration changed since the last backup (inbound_backup)
+func (a *AgentService) snapshotInbounds(body []byte) {
+	a.pendingBackup = nil // Not sent with a failed status report, taken again if still due
+	if a.backups == nil {
+		return
+	}
+	snapshot, err := backup.NewSnapshot(body, time.Now())
+	if err != nil {
+		a.logger.Warnf("⚠️  Failed to snapshot the inbound configuration: %v", err)
+		return
+	}
+	if a.backups.Due(snapshot, snapshot.CapturedAt) {
+		a.logger.Debugf("💾 Inbound configuration changed (sha256 %s), backing it up", snapshot.SHA256[:12])
+		a.pendingBackup = snapshot
+	}
+}
+
+// backupSends returns the send of the snapshot taken this cycle, if any
+func (a *AgentService) backupSends() []func() {
+	snapshot := a.pendingBackup
+	a.pendingBackup = nil
+	if snapshot == nil {
+		return nil
+	}
+	return []func(){a.recovered(func() { a.sendBackup(snapshot) })}
+}
+
+// sendBackup sends an inbound configuration snapshot to xhub
+func (a *AgentService) sendBackup(snapshot *backup.Snapshot) {
+	a.logger.Debug("📡 Sending inbound configuration backup to xhub via gRPC...")
+	err := a.reportClient.SendBackupReport(a.config.UUID, snapshot)
+	switch {
+	case errors.Is(err, report.ErrBackupUnsupported):
+		a.logger.Warnf("⚠️  xhub does not support inbound configuration backups, retrying in %s", backupUnsupportedRetry)
+		a.backups.Failed(time.Now(), backupUnsupportedRetry)
+	case err != nil:
+		// Error details are already logged in report.go with deduplication
+		a.recordError(err)
+		a.backups.Failed(time.Now(), backupRetryInterval)
+	default:
+		a.backups.Delivered(snapshot)
+		a.logger.Infof("💾 Inbound configuration backed up to xhub (%d inbounds, sha256 %s)", snapshot.Count, snapshot.SHA256[:12])
+	}
+}
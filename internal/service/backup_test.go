@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/backup"
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/hysteria2"
+	"xhub-agent/internal/subscription"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// backupXHub records backup reports; with unsupported set it answers Unimplemented
+type backupXHub struct {
+	pb.UnimplementedReportServiceServer
+	unsupported atomic.Bool
+	mutex       sync.Mutex
+	backups     []*pb.BackupReportRequest
+}
+
+func (s *backupXHub) SendBackupReport(ctx context.Context, req *pb.BackupReportRequest) (*pb.ReportResponse, error) {
+	if s.unsupported.Load() {
+		return s.UnimplementedReportServiceServer.SendBackupReport(ctx, req)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.backups = append(s.backups, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *backupXHub) count() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.backups)
+}
+
+// newBackupAgent wires an agent with inbound_backup to a panel whose inbound port and traffic
+// are set by the returned values, and to xhub
+func newBackupAgent(t *testing.T, xhub *backupXHub) (*AgentService, *atomic.Int64, *atomic.Int64) {
+	var port, traffic atomic.Int64
+	port.Store(443)
+	mux := http.NewServeMux()
+	panel := httptest.NewServer(mux)
+	t.Cleanup(panel.Close)
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "3x-ui", Value: "session"})
+		w.Write([]byte(`{"success":true}`))
+	})
+	mux.HandleFunc("/panel/inbound/list", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"success":true,"obj":[{"id":1,"enable":true,"protocol":"vless","port":%d,"up":%d,"settings":"{}"}]}`,
+			port.Load(), traffic.Load())
+	})
+
+	reportClient, log := startTestXHub(t, xhub)
+	authClient := auth.NewXUIAuth(panel.URL, "admin", "password")
+	require.NoError(t, authClient.Login())
+
+	return &AgentService{
+		config:             &config.Config{UUID: "test-uuid", InboundBackup: true},
+		logger:             log,
+		subscriptionClient: subscription.NewSubscriptionClient(authClient, "", log),
+		hysteria2Client:    hysteria2.NewClient(log),
+		reportClient:       reportClient,
+		errorCounters:      errstats.NewCounters(),
+		sender:             newSendSpacer(0),
+		backups:            backup.NewTracker(),
+	}, &port, &traffic
+}
+
+// backupCycle collects the inbound list and runs the backup send of the cycle
+func backupCycle(agent *AgentService) {
+	agent.collectInbounds()
+	agent.sender.Schedule(agent.backupSends()...)
+}
+
+func TestAgentService_InboundBackup(t *testing.T) {
+	xhub := &backupXHub{}
+	agent, port, traffic := newBackupAgent(t, xhub)
+
+	backupCycle(agent)
+	require.Equal(t, 1, xhub.count(), "the first snapshot is backed up")
+	assert.Contains(t, string(xhub.backups[0].InboundsJson), `"port":443`)
+
+	// Traffic alone is not a configuration change
+	traffic.Store(4096)
+	backupCycle(agent)
+	assert.Equal(t, 1, xhub.count())
+
+	port.Store(8443)
+	backupCycle(agent)
+	require.Equal(t, 2, xhub.count())
+	assert.Contains(t, string(xhub.backups[1].InboundsJson), `"port":8443`)
+}
+
+func TestAgentService_InboundBackupUnsupported(t *testing.T) {
+	xhub := &backupXHub{}
+	xhub.unsupported.Store(true)
+	agent, _, _ := newBackupAgent(t, xhub)
+
+	// Not retried every cycle while xhub lacks the RPC
+	backupCycle(agent)
+	xhub.unsupported.Store(false)
+	backupCycle(agent)
+	assert.Equal(t, 0, xhub.count())
+
+	snapshot, err := backup.NewSnapshot([]byte(`{"success":true,"obj":[]}`), time.Now())
+	require.NoError(t, err)
+	assert.False(t, agent.backups.Due(snapshot, time.Now().Add(backupRetryInterval)))
+	assert.True(t, agent.backups.Due(snapshot, time.Now().Add(backupUnsupportedRetry)))
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/report"
+)
+
+// statusBatch holds the status samples collected since the last batch, guarded by cycleMutex
+type statusBatch struct {
+	samples []report.StatusSample
+}
+
+// reportBatched adds the status to the batch (report_batch_size) and sends the batch once it
+// holds report_batch_size samples, or right away in a cycle requested through TriggerReport.
+// The subscriptions and online users keep their own schedule as separate RPCs.
+func (a *AgentService) reportBatched(data *monitor.ServerStatusData) error {
+	a.batch.samples = append(a.batch.samples, report.StatusSample{CollectedAt: time.Now(), Data: data})
+	if len(a.batch.samples) < a.config.ReportBatchSize && !a.schedule.Forced() {
+		a.logger.Debugf("📥 Status sample %d/%d collected for the next batch", len(a.batch.samples), a.config.ReportBatchSize)
+		a.sender.Schedule(a.dueSends()...)
+		return nil
+	}
+
+	if err := a.sendBatch(context.Background()); err != nil {
+		return err
+	}
+	a.logger.Debug("✅ Successfully reported status batch to xhub via gRPC")
+	a.sender.Schedule(a.dueSends()...)
+	return nil
+}
+
+// sendBatch sends the collected status samples. If xhub does not implement batches, the
+// latest sample is sent as a status report instead, and the following cycles report every
+// sample on its own. Both are bounded by ctx. Samples that do not reach xhub are not kept
+// (the offline queue does).
+func (a *AgentService) sendBatch(ctx context.Context) error {
+	samples := a.batch.samples
+	a.batch.samples = nil
+	if len(samples) == 0 {
+		return nil
+	}
+
+	latest := samples[len(samples)-1].Data
+	a.logger.Debugf("📡 Sending batch of %d status samples to xhub via gRPC...", len(samples))
+	err := a.reportClient.SendStatusBatch(ctx, a.config.UUID, samples)
+	if errors.Is(err, report.ErrBatchUnsupported) {
+		err = a.reportClient.SendReportContext(ctx, a.config.UUID, latest)
+	}
+	a.lastReport.record(latest, reportSourcePanel, err)
+	if err != nil {
+		// Error details are already logged in report.go with deduplication
+		a.recordError(err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/report"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// batchXHub records status batches on top of combinedXHub; with batches false
+// SendStatusBatch answers Unimplemented
+type batchXHub struct {
+	*combinedXHub
+	batches bool
+
+	mutex    sync.Mutex
+	received []*pb.StatusBatchRequest
+}
+
+func (s *batchXHub) SendStatusBatch(ctx context.Context, req *pb.StatusBatchRequest) (*pb.ReportResponse, error) {
+	if !s.batches {
+		return s.UnimplementedReportServiceServer.SendStatusBatch(ctx, req)
+	}
+	s.record(ctx, "SendStatusBatch")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.received = append(s.received, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+// newBatchAgent wires an agent with report_batch_size 3 to xhub
+func newBatchAgent(t *testing.T, xhub *batchXHub) *AgentService {
+	agent := newCombinedAgent(t, xhub)
+	agent.config = &config.Config{UUID: "test-uuid", ReportBatchSize: 3, DrainTimeout: new(int)}
+	return agent
+}
+
+func TestAgentService_ReportBatched(t *testing.T) {
+	xhub := &batchXHub{combinedXHub: &combinedXHub{calls: map[string]int{}}, batches: true}
+	agent := newBatchAgent(t, xhub)
+
+	// Samples are sent once the batch is full
+	for range 2 {
+		require.NoError(t, agent.reportBatched(combinedStatus()))
+	}
+	assert.Zero(t, xhub.counts()["SendStatusBatch"])
+	require.NoError(t, agent.reportBatched(combinedStatus()))
+	require.Len(t, xhub.received, 1)
+	assert.Len(t, xhub.received[0].Samples, 3)
+	assert.LessOrEqual(t, xhub.received[0].Samples[0].CollectedAtMs, xhub.received[0].Samples[2].CollectedAtMs, "oldest first")
+	assert.Zero(t, xhub.counts()["SendReport"])
+
+	// A forced cycle sends the batch right away
+	agent.schedule.Force()
+	require.NoError(t, agent.reportBatched(combinedStatus()))
+	require.Len(t, xhub.received, 2)
+	assert.Len(t, xhub.received[1].Samples, 1)
+}
+
+func TestAgentService_ReportBatched_Unsupported(t *testing.T) {
+	xhub := &batchXHub{combinedXHub: &combinedXHub{calls: map[string]int{}}}
+	agent := newBatchAgent(t, xhub)
+
+	for range 3 {
+		require.NoError(t, agent.reportBatched(combinedStatus()))
+	}
+	assert.Equal(t, 1, xhub.counts()["SendReport"], "the latest sample is sent as a status report")
+	assert.False(t, agent.reportClient.BatchReportsAvailable())
+	assert.NotNil(t, agent.lastReport.status)
+}
+
+func TestAgentService_DrainSendsUnfinishedBatch(t *testing.T) {
+	xhub := &batchXHub{combinedXHub: &combinedXHub{calls: map[string]int{}}, batches: true}
+	agent := newBatchAgent(t, xhub)
+	*agent.config.DrainTimeout = 1
+
+	require.NoError(t, agent.reportBatched(combinedStatus()))
+	agent.drain()
+	require.Len(t, xhub.received, 1)
+	assert.Len(t, xhub.received[0].Samples, 1)
+	assert.Empty(t, agent.batch.samples)
+}
+
+// stuckBatchXHub never answers status batches
+type stuckBatchXHub struct {
+	pb.UnimplementedReportServiceServer
+}
+
+func (s *stuckBatchXHub) SendStatusBatch(ctx context.Context, req *pb.StatusBatchRequest) (*pb.ReportResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestAgentService_DrainBoundsUnfinishedBatch(t *testing.T) {
+	reportClient, log := startTestXHub(t, &stuckBatchXHub{})
+	drainTimeout := 1
+	agent := &AgentService{
+		config:       &config.Config{UUID: "test-uuid", ReportBatchSize: 3, DrainTimeout: &drainTimeout},
+		logger:       log,
+		reportClient: reportClient,
+		startTime:    time.Now(),
+	}
+	agent.batch.samples = []report.StatusSample{{CollectedAt: time.Now(), Data: combinedStatus()}}
+
+	start := time.Now()
+	agent.drain()
+	assert.Less(t, time.Since(start), 3*time.Second, "a stuck xhub must not hold the shutdown past drain_timeout")
+	assert.Empty(t, agent.batch.samples)
+}
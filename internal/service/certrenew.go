@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/certfile"
+	"xhub-agent/internal/certrenew"
+	"xhub-agent/internal/report"
+)
+
+const (
+	certRenewCheckInterval  = time.Hour        // Between certificate expiry checks
+	certRenewRetry          = 12 * time.Hour   // Before a due certificate is renewed again, ACME rate limits apply
+	certRenewCommandTimeout = 10 * time.Minute // cert_renew runs the ACME client, then restarts services
+)
+
+// runCertRenewals checks the certificates referenced by the Hysteria2 and inbound configs
+// every hour and renews them when one expires within cert_renew_before_days
+func (a *AgentService) runCertRenewals() {
+	defer a.wg.Done()
+	defer a.crashes.Recover("certificate renewal")
+
+	var lastAttempt time.Time
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(certRenewCheckInterval):
+		}
+
+		due := a.dueCertificates()
+		if len(due) == 0 || (!lastAttempt.IsZero() && time.Since(lastAttempt) < certRenewRetry) {
+			continue
+		}
+		lastAttempt = time.Now()
+		a.logger.Infof("🔐 Certificates expiring soon, renewing: %s", strings.Join(due, ", "))
+		if _, err := a.renewCertificates(a.ctx, certrenew.TriggerExpiry, "", "", false); err != nil && a.ctx.Err() == nil {
+			a.logger.Warnf("⚠️  Certificate renewal failed: %v", err)
+		}
+	}
+}
+
+// dueCertificates returns the certificate files expiring within cert_renew_before_days
+func (a *AgentService) dueCertificates() []string {
+	renewBefore := time.Duration(a.Config().CertRenewBeforeDays) * 24 * time.Hour
+	var due []string
+	for path, cert := range a.certSnapshot() {
+		if time.Until(cert.NotAfter) < renewBefore {
+			due = append(due, path)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+// certSnapshot returns the expiry of the readable certificate files, by path
+func (a *AgentService) certSnapshot() map[string]certrenew.Certificate {
+	a.cycleMutex.Lock() // The inbound certificate files are updated by the report cycles
+	sources := a.certSources()
+	a.cycleMutex.Unlock()
+
+	snapshot := make(map[string]certrenew.Certificate, len(sources))
+	for _, source := range sources {
+		if _, seen := snapshot[source.Path]; seen || source.Path == "" {
+			continue
+		}
+		if cert, err := certfile.ReadCertificate(source.Path); err == nil {
+			snapshot[source.Path] = certrenew.Certificate{Path: source.Path, Origin: source.Origin, NotAfter: cert.NotAfter}
+		}
+	}
+	return snapshot
+}
+
+// renewCertificates runs the ACME client, restarts Xray and Hysteria2 when their
+// certificates changed, and reports the outcome to xhub. The returned error is the renewal
+// or restart failure, else the report failure.
+func (a *AgentService) renewCertificates(ctx context.Context, trigger, commandID, domain string, force bool) (certrenew.Result, error) {
+	a.certRenewMutex.Lock()
+	defer a.certRenewMutex.Unlock()
+
+	result := certrenew.Result{Trigger: trigger, CommandID: commandID, Client: a.certRenewer.Client(), Domain: domain, StartedAt: time.Now()}
+	before := a.certSnapshot()
+	output, err := a.certRenewer.Renew(ctx, domain, force)
+	result.Output = output
+	var errs []string
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// Certificates changed on disk even when the client failed for another domain
+	for path, cert := range a.certSnapshot() {
+		if previous, ok := before[path]; ok && previous.NotAfter.Equal(cert.NotAfter) {
+			continue
+		}
+		cert.PreviousNotAfter = before[path].NotAfter
+		result.Certificates = append(result.Certificates, cert)
+	}
+	sort.Slice(result.Certificates, func(i, j int) bool { return result.Certificates[i].Path < result.Certificates[j].Path })
+
+	reloadXray, reloadHysteria2 := false, false
+	for _, cert := range result.Certificates {
+		reloadXray = reloadXray || strings.HasPrefix(cert.Origin, "inbound:")
+		reloadHysteria2 = reloadHysteria2 || cert.Origin == "hysteria2"
+	}
+	if reloadXray {
+		if err := a.restartXrayForCertificates(); err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			result.Reloaded = append(result.Reloaded, "xray")
+		}
+	}
+	if reloadHysteria2 {
+		unit := a.Config().Hysteria2Service
+		if unit == "" {
+			unit = certrenew.DefaultHysteria2Service
+		}
+		if err := a.certRenewer.RestartService(context.WithoutCancel(ctx), unit); err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			result.Reloaded = append(result.Reloaded, "hysteria2")
+		}
+	}
+	result.Error = strings.Join(errs, "; ")
+	result.Duration = time.Since(result.StartedAt)
+
+	if result.Error != "" {
+		a.logger.Warnf("⚠️  Certificate renewal (%s): %s", trigger, result.Summary())
+	} else {
+		a.logger.Infof("🔐 Certificate renewal (%s): %s", trigger, result.Summary())
+	}
+
+	var reportErr error
+	a.withReportClient(func() { reportErr = a.reportClient.SendCertRenewalReport(a.config.UUID, result) })
+	if reportErr != nil && !errors.Is(reportErr, report.ErrCertRenewalUnsupported) {
+		a.recordError(reportErr)
+	}
+
+	if result.Error != "" {
+		return result, errors.New(result.Error)
+	}
+	if reportErr != nil {
+		return result, fmt.Errorf("certificate renewal report failed: %w", reportErr)
+	}
+	return result, nil
+}
+
+// restartXrayForCertificates restarts Xray through 3x-ui, which loads the renewed inbound
+// certificates on start
+func (a *AgentService) restartXrayForCertificates() error {
+	if err := a.ensureAuthenticated(); err != nil {
+		return fmt.Errorf("Xray restart failed: %w", err)
+	}
+	if err := a.monitorClient.RestartXray(); err != nil {
+		return fmt.Errorf("Xray restart failed: %w", err)
+	}
+	return nil
+}
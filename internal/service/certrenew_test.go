@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/certrenew"
+	"xhub-agent/internal/command"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// certRenewalXHub records the certificate renewal reports it receives
+type certRenewalXHub struct {
+	commandXHub
+
+	mutex   sync.Mutex
+	reports []*pb.CertRenewalReport
+}
+
+func (s *certRenewalXHub) SendCertRenewalReport(ctx context.Context, req *pb.CertRenewalReport) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reports = append(s.reports, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+// writeTestCert writes a self-signed certificate expiring at notAfter to path
+func writeTestCert(t *testing.T, path string, notAfter time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(notAfter.Unix()),
+		Subject:      pkix.Name{CommonName: "node.example.com"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+}
+
+// newCertRenewTestAgent creates an agent renewing the certificate of its Hysteria2 config
+// through certbot
+func newCertRenewTestAgent(t *testing.T, xhub pb.ReportServiceServer, certPath string) *AgentService {
+	dir := t.TempDir()
+	hysteria2Config := filepath.Join(dir, "hysteria.yaml")
+	require.NoError(t, os.WriteFile(hysteria2Config, []byte(fmt.Sprintf("listen: :443\ntls:\n  cert: %s\n  key: %s.key\n", certPath, certPath)), 0644))
+	return newCommandTestAgent(t, xhub, fmt.Sprintf(
+		"command_allowlist: [cert_renew]\ncert_renew_client: certbot\nhysteria2_enabled: true\nhysteria2_config_path: %q\nhysteria2_service: hy2\n", hysteria2Config))
+}
+
+func TestAgentService_CommandCertRenew(t *testing.T) {
+	certPath := filepath.Join(t.TempDir(), "cert.pem")
+	expiring := time.Now().Add(5 * 24 * time.Hour).Truncate(time.Second)
+	renewed := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second)
+	writeTestCert(t, certPath, expiring)
+
+	xhub := &certRenewalXHub{}
+	agent := newCertRenewTestAgent(t, xhub, certPath)
+	assert.Equal(t, []string{certPath}, agent.dueCertificates())
+
+	var calls [][]string
+	agent.certRenewer.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		if name == "certbot" {
+			writeTestCert(t, certPath, renewed)
+		}
+		return []byte("Congratulations, all renewals succeeded"), nil
+	})
+
+	result := agent.commands.Execute(context.Background(), command.Command{ID: "cr1", Name: command.CertRenew, Args: map[string]string{"domain": "node.example.com"}})
+	require.Empty(t, result.Err)
+	assert.Contains(t, result.Output, certPath+" (hysteria2) renewed until "+renewed.Format(time.DateOnly))
+	assert.Contains(t, result.Output, "reloaded hysteria2")
+	assert.Equal(t, [][]string{
+		{"certbot", "renew", "--non-interactive", "--cert-name", "node.example.com"},
+		{"systemctl", "restart", "hy2"},
+	}, calls)
+	assert.Empty(t, agent.dueCertificates())
+
+	require.Len(t, xhub.reports, 1)
+	report := xhub.reports[0]
+	assert.Equal(t, certrenew.TriggerCommand, report.Trigger)
+	assert.Equal(t, "cr1", report.CommandId)
+	assert.Equal(t, "certbot", report.Client)
+	assert.True(t, report.Success)
+	assert.Equal(t, []string{"hysteria2"}, report.Reloaded)
+	require.Len(t, report.Certificates, 1)
+	assert.Equal(t, expiring.Unix(), report.Certificates[0].PreviousNotAfter)
+	assert.Equal(t, renewed.Unix(), report.Certificates[0].NotAfter)
+	assert.Contains(t, report.Output, "all renewals succeeded")
+}
+
+func TestAgentService_CertRenewFailure(t *testing.T) {
+	certPath := filepath.Join(t.TempDir(), "cert.pem")
+	writeTestCert(t, certPath, time.Now().Add(24*time.Hour))
+
+	xhub := &certRenewalXHub{}
+	agent := newCertRenewTestAgent(t, xhub, certPath)
+	agent.certRenewer.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("Challenge failed for domain node.example.com"), fmt.Errorf("exit status 1")
+	})
+
+	_, err := agent.renewCertificates(context.Background(), certrenew.TriggerExpiry, "", "", false)
+	assert.EqualError(t, err, "certbot failed: exit status 1")
+	require.Len(t, xhub.reports, 1)
+	assert.False(t, xhub.reports[0].Success)
+	assert.Equal(t, certrenew.TriggerExpiry, xhub.reports[0].Trigger)
+	assert.Empty(t, xhub.reports[0].Certificates)
+	assert.Empty(t, xhub.reports[0].Reloaded, "nothing changed, nothing reloaded")
+}
+
+func TestAgentService_CommandCertRenew_Arguments(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "command_allowlist: [cert_renew]\n")
+	assert.Nil(t, agent.certRenewer)
+	result := agent.commands.Execute(context.Background(), command.Command{Name: command.CertRenew})
+	assert.Equal(t, "no cert_renew_client configured", result.Err)
+
+	agent = newCertRenewTestAgent(t, &commandXHub{}, filepath.Join(t.TempDir(), "cert.pem"))
+	result = agent.commands.Execute(context.Background(), command.Command{Name: command.CertRenew, Args: map[string]string{"domain": "-x"}})
+	assert.Equal(t, `invalid domain "-x"`, result.Err)
+	result = agent.commands.Execute(context.Background(), command.Command{Name: command.CertRenew, Args: map[string]string{"force": "maybe"}})
+	assert.Equal(t, "force must be true or false", result.Err)
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/hysteria2"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/report"
+	"xhub-agent/internal/selftest"
+	"xhub-agent/internal/subscription"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// combinedXHub advertises the combined report capability and counts the RPCs per method.
+// With implemented false SendCombinedReport answers Unimplemented.
+type combinedXHub struct {
+	pb.UnimplementedReportServiceServer
+	implemented bool
+
+	mutex    sync.Mutex
+	calls    map[string]int
+	combined []*pb.CombinedReportRequest
+}
+
+func (s *combinedXHub) record(ctx context.Context, method string) {
+	grpc.SetHeader(ctx, metadata.Pairs("x-xhub-capabilities", report.CombinedCapability))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls[method]++
+}
+
+func (s *combinedXHub) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	s.record(ctx, "SendReport")
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *combinedXHub) SendSubscriptionReport(ctx context.Context, req *pb.SubscriptionReportRequest) (*pb.ReportResponse, error) {
+	s.record(ctx, "SendSubscriptionReport")
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *combinedXHub) SendOnlineUsersReport(ctx context.Context, req *pb.OnlineUsersReportRequest) (*pb.ReportResponse, error) {
+	s.record(ctx, "SendOnlineUsersReport")
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *combinedXHub) SendCombinedReport(ctx context.Context, req *pb.CombinedReportRequest) (*pb.ReportResponse, error) {
+	if !s.implemented {
+		return s.UnimplementedReportServiceServer.SendCombinedReport(ctx, req)
+	}
+	s.record(ctx, "SendCombinedReport")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.combined = append(s.combined, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *combinedXHub) counts() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	counts := make(map[string]int, len(s.calls))
+	for method, count := range s.calls {
+		counts[method] = count
+	}
+	return counts
+}
+
+// newTestPanel starts a fake 3x-ui panel serving a status, one subscription and, with online,
+// two online users
+func newTestPanel(t *testing.T, online bool) *httptest.Server {
+	mux := http.NewServeMux()
+	panel := httptest.NewServer(mux)
+	t.Cleanup(panel.Close)
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "3x-ui", Value: "session"})
+		w.Write([]byte(`{"success":true}`))
+	})
+	mux.HandleFunc("/server/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":{"cpu":4.5,"cpuCores":2,"mem":{"current":1,"total":2},"xray":{"state":"running"}}}`))
+	})
+	if online {
+		mux.HandleFunc("/panel/inbound/onlines", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"success":true,"obj":["alice@example.com","bob@example.com"]}`))
+		})
+	}
+	mux.HandleFunc("/panel/setting/defaultSettings", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":{"subEnable":true,"subURI":"` + panel.URL + `/sub/"}}`))
+	})
+	mux.HandleFunc("/panel/inbound/list", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":[{"id":1,"enable":true,"protocol":"vless","port":443,"settings":"{\"clients\":[{\"email\":\"alice@example.com\",\"subId\":\"sub1\",\"enable\":true}]}"}]}`))
+	})
+	mux.HandleFunc("/sub/sub1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString([]byte("vless://node"))))
+	})
+	return panel
+}
+
+// newPanelAgent wires an agent to the test panel, with two online users when online is set,
+// and to xhub
+func newPanelAgent(t *testing.T, xhub pb.ReportServiceServer, online bool) *AgentService {
+	panel := newTestPanel(t, online)
+	reportClient, log := startTestXHub(t, xhub)
+	authClient := auth.NewXUIAuth(panel.URL, "admin", "password")
+
+	agent := &AgentService{
+		config:             &config.Config{UUID: "test-uuid"},
+		logger:             log,
+		authClient:         authClient,
+		monitorClient:      monitor.NewMonitorClient(authClient, log),
+		subscriptionClient: subscription.NewSubscriptionClient(authClient, "", log),
+		hysteria2Client:    hysteria2.NewClient(log),
+		reportClient:       reportClient,
+		collectors:         collector.NewRegistry(nil),
+		selfTest:           selftest.NewRunner(nil, nil, log),
+		errorCounters:      errstats.NewCounters(),
+		onlineUsers:        newOnlineUsersCache(0),
+		schedule:           newReportSchedule(0, 0, 0),
+		sender:             newSendSpacer(0),
+	}
+	agent.collectors.Register(agent.inboundsCollector())
+	return agent
+}
+
+// newCombinedAgent wires an agent with report_combined to the test panel, with online users,
+// and to xhub
+func newCombinedAgent(t *testing.T, xhub pb.ReportServiceServer) *AgentService {
+	agent := newPanelAgent(t, xhub, true)
+	agent.config.ReportCombined = true
+	agent.reportClient.SetCombinedReports(true)
+	require.NoError(t, agent.authClient.Login())
+	return agent
+}
+
+func combinedStatus() *monitor.ServerStatusData {
+	return &monitor.ServerStatusData{CPUCores: 2, Memory: monitor.MemoryInfo{Current: 1, Total: 2}}
+}
+
+func TestAgentService_CombinedReport(t *testing.T) {
+	xhub := &combinedXHub{implemented: true, calls: make(map[string]int)}
+	agent := newCombinedAgent(t, xhub)
+
+	// The first status report negotiates the capability
+	require.NoError(t, agent.reportClient.SendReport("test-uuid", combinedStatus()))
+	require.True(t, agent.reportClient.CombinedReportsAvailable())
+
+	require.NoError(t, agent.reportCombined(combinedStatus()))
+	assert.Equal(t, map[string]int{"SendReport": 1, "SendCombinedReport": 1}, xhub.counts(),
+		"the cycle is a single RPC")
+
+	require.Len(t, xhub.combined, 1)
+	req := xhub.combined[0]
+	assert.Equal(t, int32(2), req.Data.CpuCores)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, req.OnlineUsers.OnlineEmails)
+	require.NotNil(t, req.Subscriptions)
+	require.Len(t, req.Subscriptions.Subscriptions, 1)
+	assert.Equal(t, "sub1", req.Subscriptions.Subscriptions[0].SubId)
+}
+
+func TestAgentService_CombinedReportFallback(t *testing.T) {
+	xhub := &combinedXHub{calls: make(map[string]int)}
+	agent := newCombinedAgent(t, xhub)
+
+	require.NoError(t, agent.reportClient.SendReport("test-uuid", combinedStatus()))
+	require.True(t, agent.reportClient.CombinedReportsAvailable())
+
+	// xhub advertised the capability without implementing it: the cycle goes out as separate RPCs
+	require.NoError(t, agent.reportCombined(combinedStatus()))
+	assert.Equal(t, map[string]int{"SendReport": 2, "SendSubscriptionReport": 1, "SendOnlineUsersReport": 1}, xhub.counts())
+	assert.False(t, agent.reportClient.CombinedReportsAvailable())
+}
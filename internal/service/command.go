@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/certrenew"
+	"xhub-agent/internal/command"
+	"xhub-agent/internal/datadir"
+	"xhub-agent/internal/inbound"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/netprobe"
+	"xhub-agent/internal/report"
+	"xhub-agent/internal/xraycore"
+)
+
+const (
+	commandTimeout          = 2 * time.Minute // Longest a command may run
+	commandRetryMin         = 5 * time.Second // First wait before reopening a broken command stream
+	commandRetryMax         = 5 * time.Minute // Longest wait before reopening it
+	commandUnsupportedRetry = time.Hour       // Wait while xhub does not implement the command stream
+
+	defaultFetchLogLines = 100
+	maxFetchLogLines     = 1000
+	maxPollInterval      = 3600 // Seconds, upper bound of adjust_poll_interval
+
+	// xray_install may install twice (the requested version and the rollback), each download
+	// taking up to 3 minutes plus the health check
+	xrayInstallCommandTimeout = 10 * time.Minute
+)
+
+// newCommandExecutor creates the executor of the commands xhub may issue (command_channel),
+// limited to allowlist
+func (a *AgentService) newCommandExecutor(allowlist []string) *command.Executor {
+	executor := command.NewExecutor(allowlist, commandTimeout)
+	executor.Register(command.RestartXray, func(ctx context.Context, args map[string]string) (string, error) {
+		if err := a.restartXray(ctx, monitor.XrayRestartCommand, 0); err != nil {
+			return "", err
+		}
+		return "Xray restarted", nil
+	})
+	executor.Register(command.ResyncSubscriptions, func(ctx context.Context, args map[string]string) (string, error) {
+		a.cycleMutex.Lock()
+		a.reportClient.ResendSubscriptions()
+		a.cycleMutex.Unlock()
+		done, err := a.TriggerReport(TriggerServer)
+		if err != nil {
+			return "", err
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				return "", err
+			}
+			return "report cycle with subscriptions completed", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	})
+	executor.Register(command.AdjustPollInterval, func(ctx context.Context, args map[string]string) (string, error) {
+		seconds, err := strconv.Atoi(args["seconds"])
+		if err != nil || seconds < 1 || seconds > maxPollInterval {
+			return "", fmt.Errorf("seconds must be an integer between 1 and %d", maxPollInterval)
+		}
+		next := *a.Config()
+		next.StatusInterval = seconds
+		if !a.Reload(&next) {
+			return "", fmt.Errorf("status interval could not be changed")
+		}
+		return fmt.Sprintf("status interval set to %d seconds until the next config reload", seconds), nil
+	})
+	executor.Register(command.FetchLogs, func(ctx context.Context, args map[string]string) (string, error) {
+		lines := defaultFetchLogLines
+		if value, ok := args["lines"]; ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxFetchLogLines {
+				return "", fmt.Errorf("lines must be an integer between 1 and %d", maxFetchLogLines)
+			}
+			lines = parsed
+		}
+		if a.dataDir == nil || !a.dataDir.Enabled(datadir.ArtifactLog) {
+			return "", fmt.Errorf("the agent logs to stdout only, no log file to read")
+		}
+		a.logger.Sync()
+		return command.TailFile(a.dataDir.Path(datadir.ArtifactLog), lines)
+	})
+	executor.Register(command.SetLogLevel, func(ctx context.Context, args map[string]string) (string, error) {
+		level, err := a.SetLogLevel(args["level"], "requested by xhub")
+		if err != nil {
+			return "", fmt.Errorf("level must be debug, info, warn or error")
+		}
+		return fmt.Sprintf("log level set to %s until the next config reload", level), nil
+	})
+	executor.Register(command.NetworkProbe, func(ctx context.Context, args map[string]string) (string, error) {
+		if a.netProber == nil {
+			return "", fmt.Errorf("no network_probe_targets or network_probe_throughput_url configured")
+		}
+		throughput := true
+		if value, ok := args["throughput"]; ok {
+			var err error
+			if throughput, err = strconv.ParseBool(value); err != nil {
+				return "", fmt.Errorf("throughput must be true or false")
+			}
+		}
+		return a.runNetworkProbe(ctx, netprobe.TriggerCommand, command.ID(ctx), throughput)
+	})
+	a.registerInboundCommands(executor)
+	a.registerQuotaCommands(executor)
+	a.registerXrayCommands(executor)
+	executor.Register(command.CertRenew, func(ctx context.Context, args map[string]string) (string, error) {
+		if a.certRenewer == nil {
+			return "", fmt.Errorf("no cert_renew_client configured")
+		}
+		domain := args["domain"]
+		if err := certrenew.CheckDomain(domain); err != nil {
+			return "", err
+		}
+		force := false
+		if value, ok := args["force"]; ok {
+			var err error
+			if force, err = strconv.ParseBool(value); err != nil {
+				return "", fmt.Errorf("force must be true or false")
+			}
+		}
+		result, err := a.renewCertificates(ctx, certrenew.TriggerCommand, command.ID(ctx), domain, force)
+		return result.Summary(), err
+	})
+	executor.SetTimeout(command.CertRenew, certRenewCommandTimeout)
+	return executor
+}
+
+// xrayPanel authenticates before each 3x-ui call, as the panel may restart along with Xray
+// while a version switch is checked
+type xrayPanel struct {
+	agent *AgentService
+}
+
+func (p xrayPanel) XrayVersions() ([]string, error) {
+	if err := p.agent.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+	return p.agent.monitorClient.XrayVersions()
+}
+
+func (p xrayPanel) InstallXray(version string) error {
+	if err := p.agent.ensureAuthenticated(); err != nil {
+		return err
+	}
+	return p.agent.monitorClient.InstallXray(version)
+}
+
+func (p xrayPanel) XrayStatus() (monitor.XrayInfo, error) {
+	if err := p.agent.ensureAuthenticated(); err != nil {
+		return monitor.XrayInfo{}, err
+	}
+	return p.agent.monitorClient.XrayStatus()
+}
+
+// registerXrayCommands registers the commands listing and switching the Xray core version.
+// Their output is JSON.
+func (a *AgentService) registerXrayCommands(executor *command.Executor) {
+	manager := xraycore.NewManager(xrayPanel{agent: a})
+	executor.Register(command.XrayVersions, func(ctx context.Context, args map[string]string) (string, error) {
+		versions, err := manager.Versions()
+		if err != nil {
+			return "", err
+		}
+		out, err := json.Marshal(versions)
+		return string(out), err
+	})
+	executor.Register(command.XrayInstall, func(ctx context.Context, args map[string]string) (string, error) {
+		if _, err := xraycore.NormalizeVersion(args["version"]); err != nil {
+			return "", err
+		}
+		result, err := manager.Switch(ctx, args["version"])
+		switch {
+		case result.Unchanged:
+			a.logger.Infof("⬆️  Xray %s is already running", result.Running)
+		case result.RolledBack:
+			a.logger.Warnf("⚠️  Xray %s rolled back to %s: %v", result.Requested, result.Running, err)
+		case err == nil:
+			a.logger.Infof("⬆️  Xray switched from %s to %s on request of xhub", result.Previous, result.Running)
+		}
+		if result.Requested == "" {
+			return "", err
+		}
+		out, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			return "", marshalErr
+		}
+		return string(out), err
+	})
+	executor.SetTimeout(command.XrayInstall, xrayInstallCommandTimeout)
+}
+
+// registerInboundCommands registers the commands provisioning 3x-ui inbounds from xhub
+func (a *AgentService) registerInboundCommands(executor *command.Executor) {
+	inbounds := inbound.NewClient(a.authClient)
+	executor.Register(command.InboundCreate, func(ctx context.Context, args map[string]string) (string, error) {
+		in, err := inbound.Parse(args["inbound"])
+		if err != nil {
+			return "", err
+		}
+		if err := a.ensureAuthenticated(); err != nil {
+			return "", err
+		}
+		id, err := inbounds.Create(in)
+		if err != nil {
+			return "", err
+		}
+		a.logger.Infof("📥 Inbound %d (%s, port %d) created on request of xhub", id, in.Protocol(), in.Port())
+		return fmt.Sprintf("inbound %d created", id), nil
+	})
+	executor.Register(command.InboundUpdate, func(ctx context.Context, args map[string]string) (string, error) {
+		id, err := inboundID(args)
+		if err != nil {
+			return "", err
+		}
+		changes, err := inbound.Parse(args["inbound"])
+		if err != nil {
+			return "", err
+		}
+		if err := a.ensureAuthenticated(); err != nil {
+			return "", err
+		}
+		if err := inbounds.Update(id, changes); err != nil {
+			return "", err
+		}
+		a.logger.Infof("📝 Inbound %d updated on request of xhub", id)
+		return fmt.Sprintf("inbound %d updated", id), nil
+	})
+	executor.Register(command.InboundDelete, func(ctx context.Context, args map[string]string) (string, error) {
+		id, err := inboundID(args)
+		if err != nil {
+			return "", err
+		}
+		if err := a.ensureAuthenticated(); err != nil {
+			return "", err
+		}
+		if err := inbounds.Delete(id); err != nil {
+			return "", err
+		}
+		a.logger.Infof("🗑️  Inbound %d deleted on request of xhub", id)
+		return fmt.Sprintf("inbound %d deleted", id), nil
+	})
+	executor.Register(command.InboundEnable, func(ctx context.Context, args map[string]string) (string, error) {
+		id, err := inboundID(args)
+		if err != nil {
+			return "", err
+		}
+		enable := true
+		if value, ok := args["enable"]; ok {
+			if enable, err = strconv.ParseBool(value); err != nil {
+				return "", fmt.Errorf("enable must be true or false")
+			}
+		}
+		if err := a.ensureAuthenticated(); err != nil {
+			return "", err
+		}
+		if err := inbounds.SetEnabled(id, enable); err != nil {
+			return "", err
+		}
+		state := "enabled"
+		if !enable {
+			state = "disabled"
+		}
+		a.logger.Infof("🔌 Inbound %d %s on request of xhub", id, state)
+		return fmt.Sprintf("inbound %d %s", id, state), nil
+	})
+}
+
+// registerQuotaCommands registers the traffic reset and quota enforcement commands. Their
+// output is the audit trail of the affected clients as JSON.
+func (a *AgentService) registerQuotaCommands(executor *command.Executor) {
+	inbounds := inbound.NewClient(a.authClient)
+	executor.Register(command.ResetTraffic, func(ctx context.Context, args map[string]string) (string, error) {
+		id, err := strconv.Atoi(args["inbound_id"])
+		if err != nil || id < 1 {
+			return "", fmt.Errorf("inbound_id must be a positive inbound id")
+		}
+		dryRun, err := dryRunArg(args)
+		if err != nil {
+			return "", err
+		}
+		if err := a.ensureAuthenticated(); err != nil {
+			return "", err
+		}
+		entries, err := inbounds.ResetTraffic(id, args["email"], dryRun)
+		a.logAudit(entries)
+		return auditTrail(entries, err)
+	})
+	executor.Register(command.EnforceQuota, func(ctx context.Context, args map[string]string) (string, error) {
+		var emails []string
+		for _, email := range strings.Split(args["emails"], ",") {
+			if email = strings.TrimSpace(email); email != "" {
+				emails = append(emails, email)
+			}
+		}
+		dryRun, err := dryRunArg(args)
+		if err != nil {
+			return "", err
+		}
+		if err := a.ensureAuthenticated(); err != nil {
+			return "", err
+		}
+		entries, err := inbounds.EnforceQuota(emails, dryRun)
+		a.logAudit(entries)
+		return auditTrail(entries, err)
+	})
+}
+
+// logAudit logs the audit entries of a traffic reset or quota enforcement
+func (a *AgentService) logAudit(entries []inbound.AuditEntry) {
+	for _, entry := range entries {
+		switch {
+		case entry.Error != "":
+			a.logger.Warnf("⚠️  %s for %s of inbound %d failed: %s", entry.Action, entry.Email, entry.InboundID, entry.Error)
+		case entry.DryRun:
+			a.logger.Infof("🧾 Dry run: would %s for %s of inbound %d", entry.Action, entry.Email, entry.InboundID)
+		default:
+			a.logger.Infof("🧾 %s for %s of inbound %d on request of xhub", entry.Action, entry.Email, entry.InboundID)
+		}
+	}
+}
+
+// auditTrail encodes the audit entries as the command output. The entries are returned
+// together with err, so that xhub learns which clients were changed before a failure.
+func auditTrail(entries []inbound.AuditEntry, err error) (string, error) {
+	if entries == nil {
+		entries = []inbound.AuditEntry{}
+	}
+	out, marshalErr := json.Marshal(entries)
+	if marshalErr != nil {
+		return "", marshalErr
+	}
+	return string(out), err
+}
+
+// dryRunArg parses the dry_run argument, false when absent
+func dryRunArg(args map[string]string) (bool, error) {
+	value, ok := args["dry_run"]
+	if !ok {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("dry_run must be true or false")
+	}
+	return dryRun, nil
+}
+
+// inboundID parses the id argument of the inbound commands
+func inboundID(args map[string]string) (int, error) {
+	id, err := strconv.Atoi(args["id"])
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("id must be a positive inbound id")
+	}
+	return id, nil
+}
+
+// runCommandChannel keeps the command stream from xhub open until the agent stops, running
+// each command and reporting its result
+func (a *AgentService) runCommandChannel() {
+	defer a.wg.Done()
+	defer a.crashes.Recover("command channel")
+	a.keepStreamOpen("command", a.receiveCommands)
+}
+
+// keepStreamOpen runs receive, which serves a stream from xhub until it ends, again and again
+// until the agent stops. A broken stream is reopened with backoff.
+func (a *AgentService) keepStreamOpen(channel string, receive func() error) {
+	backoff := commandRetryMin
+	unsupportedLogged := false
+	for {
+		opened := time.Now()
+		err := receive()
+		if a.ctx.Err() != nil {
+			return
+		}
+		if time.Since(opened) > commandRetryMax {
+			backoff = commandRetryMin // A long-lived stream broke, not a failing one
+		}
+
+		wait := backoff
+		if status.Code(err) == codes.Unimplemented {
+			if !unsupportedLogged {
+				a.logger.Warnf("⚠️  xhub does not support the %s channel, retrying every %s", channel, commandUnsupportedRetry)
+				unsupportedLogged = true
+			}
+			wait = commandUnsupportedRetry
+		} else {
+			a.logger.Debugf("📭 The %s stream closed (%v), reopening in %s", channel, err, wait)
+			backoff = min(backoff*2, commandRetryMax)
+		}
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// receiveCommands opens the command stream and runs the received commands one at a time
+// until the stream ends
+func (a *AgentService) receiveCommands() error {
+	var stream *report.CommandStream
+	var err error
+	a.withReportClient(func() {
+		stream, err = a.reportClient.OpenCommandStream(a.ctx, a.config.UUID, a.commands.Supported())
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		cmd, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		a.logger.Infof("📨 Command %s (%s) received from xhub", cmd.Name, cmd.ID)
+
+		result := a.commands.Execute(a.ctx, cmd)
+		if result.Err != "" {
+			a.logger.Warnf("⚠️  Command %s (%s) failed: %s", cmd.Name, cmd.ID, result.Err)
+		}
+
+		a.withReportClient(func() { err = a.reportClient.SendCommandResult(a.config.UUID, result) })
+		if err != nil {
+			a.recordError(err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/command"
+	"xhub-agent/pkg/logger"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// commandXHub streams its commands to the agent and records the results
+type commandXHub struct {
+	pb.UnimplementedReportServiceServer
+	commands []*pb.Command
+
+	mutex   sync.Mutex
+	results []*pb.CommandResult
+}
+
+func (s *commandXHub) SubscribeCommands(req *pb.CommandSubscription, stream pb.ReportService_SubscribeCommandsServer) error {
+	for _, cmd := range s.commands {
+		if err := stream.Send(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *commandXHub) SendCommandResult(ctx context.Context, req *pb.CommandResult) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.results = append(s.results, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+// newCommandTestAgent creates an agent with command_channel on, reporting to xhub
+func newCommandTestAgent(t *testing.T, xhub pb.ReportServiceServer, extra string) *AgentService {
+	t.Helper()
+	addr := serveTestXHub(t, xhub)
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yml")
+	content := strings.NewReplacer(
+		"grpcServer: xhub.example.com", "grpcServer: localhost",
+		"grpcPort: 9090", fmt.Sprintf("grpcPort: %d", addr.Port),
+	).Replace(reloadTestConfig) + "command_channel: true\n" + extra
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+	agent, err := NewAgentService(configPath, filepath.Join(tmpDir, "agent.log"))
+	require.NoError(t, err)
+	t.Cleanup(agent.Close)
+	return agent
+}
+
+func TestAgentService_CommandAllowlist(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "")
+	assert.Equal(t, []string{command.FetchLogs, command.ResyncSubscriptions}, agent.commands.Supported())
+
+	agent = newCommandTestAgent(t, &commandXHub{}, "command_allowlist: [adjust_poll_interval, restart_xray]\n")
+	assert.Equal(t, []string{command.AdjustPollInterval, command.RestartXray}, agent.commands.Supported())
+
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte(reloadTestConfig+"command_channel: true\ncommand_allowlist: [shell]\n"), 0644))
+	_, err := NewAgentService(configPath, filepath.Join(t.TempDir(), "agent.log"))
+	assert.ErrorContains(t, err, `invalid command_allowlist: unknown command "shell"`)
+
+	assert.Nil(t, newReloadTestAgent(t).commands, "command_channel is off by default")
+}
+
+func TestAgentService_CommandAdjustPollInterval(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "command_allowlist: [adjust_poll_interval]\n")
+
+	result := agent.commands.Execute(context.Background(), command.Command{Name: command.AdjustPollInterval, Args: map[string]string{"seconds": "30"}})
+	require.Empty(t, result.Err)
+	assert.Equal(t, 30*time.Second, agent.Config().StatusPeriod())
+
+	result = agent.commands.Execute(context.Background(), command.Command{Name: command.AdjustPollInterval, Args: map[string]string{"seconds": "0"}})
+	assert.Contains(t, result.Err, "seconds must be an integer between 1 and 3600")
+	assert.Equal(t, 30*time.Second, agent.Config().StatusPeriod())
+}
+
+func TestAgentService_CommandInboundArguments(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "command_allowlist: [inbound_create, inbound_update, inbound_delete, inbound_enable]\n")
+	assert.Equal(t, []string{command.InboundCreate, command.InboundDelete, command.InboundEnable, command.InboundUpdate}, agent.commands.Supported())
+
+	// Arguments are checked before the panel is contacted
+	for name, args := range map[string]map[string]string{
+		command.InboundCreate: {"inbound": "[]"},
+		command.InboundUpdate: {"id": "x", "inbound": "{}"},
+		command.InboundDelete: {"id": "0"},
+		command.InboundEnable: {"id": "3", "enable": "maybe"},
+	} {
+		result := agent.commands.Execute(context.Background(), command.Command{Name: name, Args: args})
+		assert.Regexp(t, "invalid inbound|id must be a positive inbound id|enable must be true or false", result.Err, name)
+	}
+}
+
+func TestAgentService_CommandQuotaArguments(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "command_allowlist: [reset_traffic, enforce_quota]\n")
+
+	result := agent.commands.Execute(context.Background(), command.Command{Name: command.ResetTraffic, Args: map[string]string{"inbound_id": "-1"}})
+	assert.Equal(t, "inbound_id must be a positive inbound id", result.Err)
+	result = agent.commands.Execute(context.Background(), command.Command{Name: command.EnforceQuota, Args: map[string]string{"dry_run": "perhaps"}})
+	assert.Equal(t, "dry_run must be true or false", result.Err)
+
+	out, err := auditTrail(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", out, "an empty audit trail is still a JSON array")
+}
+
+func TestAgentService_CommandXrayInstallArguments(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "command_allowlist: [xray_versions, xray_install]\n")
+	assert.Equal(t, []string{command.XrayInstall, command.XrayVersions}, agent.commands.Supported())
+
+	// The version is checked before the panel is contacted
+	for _, version := range []string{"", "latest", "v1.8.24;reboot"} {
+		result := agent.commands.Execute(context.Background(), command.Command{Name: command.XrayInstall, Args: map[string]string{"version": version}})
+		assert.Contains(t, result.Err, "invalid Xray version", version)
+	}
+}
+
+func TestAgentService_CommandChannel(t *testing.T) {
+	xhub := &commandXHub{commands: []*pb.Command{
+		{Id: "c1", Name: command.FetchLogs, Args: map[string]string{"lines": "10"}},
+		{Id: "c2", Name: command.RestartXray},
+	}}
+	agent := newCommandTestAgent(t, xhub, "")
+	agent.logger.Infof("last line before fetch")
+
+	require.Error(t, agent.receiveCommands(), "the stream ends after the commands")
+
+	require.Len(t, xhub.results, 2)
+	assert.Equal(t, "c1", xhub.results[0].Id)
+	assert.True(t, xhub.results[0].Success, xhub.results[0].Error)
+	assert.Contains(t, xhub.results[0].Output, "last line before fetch")
+	assert.Equal(t, "c2", xhub.results[1].Id)
+	assert.False(t, xhub.results[1].Success)
+	assert.Contains(t, xhub.results[1].Error, "not in the agent's command_allowlist")
+}
+
+func TestAgentService_CommandSetLogLevel(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "command_allowlist: [set_log_level]\n")
+
+	result := agent.commands.Execute(context.Background(), command.Command{Name: command.SetLogLevel, Args: map[string]string{"level": "debug"}})
+	require.Empty(t, result.Err)
+	assert.Equal(t, "log level set to debug until the next config reload", result.Output)
+	assert.Equal(t, logger.DEBUG, agent.logger.Level())
+
+	result = agent.commands.Execute(context.Background(), command.Command{Name: command.SetLogLevel, Args: map[string]string{"level": "trace"}})
+	assert.Equal(t, "level must be debug, info, warn or error", result.Err)
+	assert.Equal(t, logger.DEBUG, agent.logger.Level())
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"os"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"xhub-agent/internal/crash"
+	"xhub-agent/internal/datadir"
+)
+
+// crashReportRetry is the wait before crash dumps xhub could not receive are sent again
+const crashReportRetry = 5 * time.Minute
+
+// reportCrashes logs the crash dumps left by previous runs and sends them to xhub, removing
+// each one once delivered. Undelivered dumps are retried until the agent stops; they are kept
+// for the next start if xhub does not support crash reports.
+func (a *AgentService) reportCrashes() {
+	defer a.wg.Done()
+	defer a.crashes.Recover("crash reporter")
+
+	dir := a.dataDir.Path(datadir.ArtifactCrash)
+	pending, err := crash.List(dir)
+	if err != nil {
+		a.logger.Warnf("⚠️  Failed to read crash dumps: %v", err)
+		return
+	}
+	for _, p := range pending {
+		a.logger.Warnf("💥 A previous run (start #%d, %s) crashed in %s at %s: %s",
+			p.Dump.RestartCount, p.Dump.Version, p.Dump.Component, p.Dump.Time.Format(time.RFC3339), p.Dump.Panic)
+	}
+
+	for len(pending) > 0 {
+		var failed []crash.Pending
+		for _, p := range pending {
+			a.cycleMutex.Lock()
+			err := a.reportClient.SendCrashReport(a.config.UUID, p.Dump)
+			a.cycleMutex.Unlock()
+			switch {
+			case err == nil:
+				os.Remove(p.Path)
+				a.logger.Infof("📮 Crash of %s reported to xhub", p.Dump.Time.Format(time.RFC3339))
+			case status.Code(err) == codes.Unimplemented:
+				a.logger.Warnf("⚠️  xhub does not support crash reports, crash dumps kept in %s", dir)
+				return
+			default:
+				a.logger.Debugf("📮 Crash report failed, retrying in %s: %v", crashReportRetry, err)
+				failed = append(failed, p)
+			}
+		}
+		pending = failed
+		if len(pending) == 0 {
+			return
+		}
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(crashReportRetry):
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/crash"
+	"xhub-agent/internal/datadir"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// crashXHub records the crash reports it receives
+type crashXHub struct {
+	pb.UnimplementedReportServiceServer
+
+	mutex   sync.Mutex
+	reports []*pb.CrashReport
+}
+
+func (s *crashXHub) SendCrashReport(ctx context.Context, req *pb.CrashReport) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reports = append(s.reports, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestAgentService_ReportCrashes(t *testing.T) {
+	xhub := &crashXHub{}
+	agent := newCommandTestAgent(t, xhub, "")
+	dir := agent.dataDir.Path(datadir.ArtifactCrash)
+	crashedAt := time.Unix(1700000000, 0)
+	_, err := crash.Write(dir, crash.Dump{Time: crashedAt, Component: "work loop", Panic: "boom", Stack: "goroutine 1", Version: "v1.2.3", UptimeSeconds: 42, RestartCount: 3})
+	require.NoError(t, err)
+
+	agent.wg.Add(1)
+	agent.reportCrashes()
+
+	require.Len(t, xhub.reports, 1)
+	report := xhub.reports[0]
+	assert.Equal(t, agent.config.UUID, report.Uuid)
+	assert.Equal(t, crashedAt.Unix(), report.CrashedAt)
+	assert.Equal(t, "work loop", report.Component)
+	assert.Equal(t, "boom", report.Panic)
+	assert.Equal(t, "goroutine 1", report.Stack)
+	assert.Equal(t, "v1.2.3", report.AgentVersion)
+	assert.Equal(t, int64(42), report.UptimeSeconds)
+	assert.Equal(t, int64(3), report.RestartCount)
+
+	pending, err := crash.List(dir)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "delivered dumps are removed")
+}
+
+func TestAgentService_ReportCrashesUnsupported(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "")
+	dir := agent.dataDir.Path(datadir.ArtifactCrash)
+	_, err := crash.Write(dir, crash.Dump{Time: time.Now(), Component: "heartbeat", Panic: "boom"})
+	require.NoError(t, err)
+
+	agent.wg.Add(1)
+	agent.reportCrashes()
+
+	pending, err := crash.List(dir)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1, "dumps are kept while xhub does not support crash reports")
+}
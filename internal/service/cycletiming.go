@@ -0,0 +1,112 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Stages of a report cycle, timed by cycleTiming and exported as
+// xhub_agent_cycle_stage_duration_seconds{stage=...}
+const (
+	stageFlush         = "flush"         // Deferred sends of the previous cycle still pending
+	stageAuth          = "auth"          // Session check and re-login
+	stageStatus        = "status"        // Panel version and server status from 3x-ui
+	stageCollect       = "collect"       // Inbounds, collectors and self-test attached to the status
+	stageReport        = "report"        // Status (or combined, or host metrics) report to xhub
+	stageSubscriptions = "subscriptions" // Deferred subscription report
+	stageOnlineUsers   = "online_users"  // Deferred online users report
+)
+
+// stageDuration is the time spent in one stage of a cycle
+type stageDuration struct {
+	stage    string
+	duration time.Duration
+}
+
+// cycleTiming records the stages of one report cycle. begin ends the running stage and
+// starts the next one, so the stages cover the cycle back to back.
+type cycleTiming struct {
+	now    func() time.Time
+	start  time.Time
+	stage  string    // Running stage, "" before the first one
+	since  time.Time // Start of the running stage
+	stages []stageDuration
+}
+
+// newCycleTiming starts timing a cycle
+func newCycleTiming() *cycleTiming {
+	t := &cycleTiming{now: time.Now}
+	t.start = t.now()
+	return t
+}
+
+// begin ends the running stage and starts stage
+func (t *cycleTiming) begin(stage string) {
+	now := t.now()
+	t.end(now)
+	t.stage = stage
+	t.since = now
+}
+
+// end ends the running stage at now
+func (t *cycleTiming) end(now time.Time) {
+	if t.stage != "" {
+		t.stages = append(t.stages, stageDuration{stage: t.stage, duration: now.Sub(t.since)})
+		t.stage = ""
+	}
+}
+
+// finish ends the running stage and returns the duration of the cycle
+func (t *cycleTiming) finish() time.Duration {
+	now := t.now()
+	t.end(now)
+	return now.Sub(t.start)
+}
+
+// fields returns the stage durations as logger fields, e.g. "auth_ms", 12
+func (t *cycleTiming) fields() []interface{} {
+	fields := make([]interface{}, 0, 2*len(t.stages))
+	for _, s := range t.stages {
+		fields = append(fields, s.stage+"_ms", s.duration.Milliseconds())
+	}
+	return fields
+}
+
+// String returns the stage durations, e.g. "auth=12ms status=40ms"
+func (t *cycleTiming) String() string {
+	parts := make([]string, len(t.stages))
+	for i, s := range t.stages {
+		parts[i] = fmt.Sprintf("%s=%v", s.stage, s.duration.Round(time.Millisecond))
+	}
+	return strings.Join(parts, " ")
+}
+
+// finishCycle records the stages and duration of a cycle, and warns when the cycle took
+// longer than the status interval: the tick that came due meanwhile is merged into this
+// cycle instead of starting another one right away
+func (a *AgentService) finishCycle(timing *cycleTiming) {
+	total := timing.finish()
+	a.metrics.ObserveCycle(total)
+	for _, s := range timing.stages {
+		a.metrics.ObserveCycleStage(s.stage, s.duration)
+	}
+
+	interval := a.config.StatusPeriod()
+	if interval > 0 && total > interval {
+		a.logger.With(timing.fields()...).With("cycle_ms", total.Milliseconds(), "interval_ms", interval.Milliseconds()).
+			Warnf("🐢 Cycle took %v, longer than the %v status interval (%s), skipping the tick missed meanwhile",
+				total.Round(time.Millisecond), interval, timing)
+		return
+	}
+	a.logger.Debugf("⏱️  Cycle took %v (%s)", total.Round(time.Millisecond), timing)
+}
+
+// timed wraps a deferred send so that its duration is recorded as stage
+func (a *AgentService) timed(stage string, send func()) func() {
+	return func() {
+		start := time.Now()
+		defer func() { a.metrics.ObserveCycleStage(stage, time.Since(start)) }()
+		send()
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/metrics"
+	"xhub-agent/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTiming returns a cycle timing whose clock advances by step on every reading
+func fakeTiming(step time.Duration) *cycleTiming {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	t := &cycleTiming{now: func() time.Time {
+		now = now.Add(step)
+		return now
+	}}
+	t.start = t.now()
+	return t
+}
+
+func TestCycleTiming(t *testing.T) {
+	timing := fakeTiming(20 * time.Millisecond)
+	timing.begin(stageAuth)
+	timing.begin(stageStatus)
+	timing.begin(stageReport)
+
+	assert.Equal(t, 80*time.Millisecond, timing.finish(), "the cycle runs from start to finish")
+	assert.Equal(t, []stageDuration{
+		{stage: stageAuth, duration: 20 * time.Millisecond},
+		{stage: stageStatus, duration: 20 * time.Millisecond},
+		{stage: stageReport, duration: 20 * time.Millisecond},
+	}, timing.stages)
+	assert.Equal(t, "auth=20ms status=20ms report=20ms", timing.String())
+	assert.Equal(t, []interface{}{"auth_ms", int64(20), "status_ms", int64(20), "report_ms", int64(20)}, timing.fields())
+}
+
+func TestAgentService_FinishCycle(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "test.log")
+	log, err := logger.NewLogger(logPath, "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+
+	registry := metrics.NewRegistry()
+	agent := &AgentService{config: &config.Config{StatusInterval: 1}, logger: log, metrics: registry}
+
+	// Within the interval
+	timing := fakeTiming(100 * time.Millisecond)
+	timing.begin(stageAuth)
+	agent.finishCycle(timing)
+
+	// Slower than the interval
+	timing = fakeTiming(time.Second)
+	timing.begin(stageAuth)
+	timing.begin(stageReport)
+	agent.finishCycle(timing)
+
+	content, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "Cycle took 200ms (auth=100ms)")
+	assert.NotContains(t, lines[0], "WARN")
+	assert.Contains(t, lines[1], "WARN")
+	assert.Contains(t, lines[1], "Cycle took 3s, longer than the 1s status interval (auth=1s report=1s)")
+	assert.Contains(t, lines[1], "auth_ms=1000")
+	assert.Contains(t, lines[1], "interval_ms=1000")
+
+	var out strings.Builder
+	registry.Write(&out)
+	assert.Contains(t, out.String(), `xhub_agent_cycle_stage_duration_seconds_count{stage="auth"} 2`+"\n")
+	assert.Contains(t, out.String(), `xhub_agent_cycle_stage_duration_seconds_count{stage="report"} 1`+"\n")
+	assert.Contains(t, out.String(), "xhub_agent_cycle_duration_seconds_count 2\n")
+}
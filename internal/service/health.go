@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/hysteria2"
+	"xhub-agent/pkg/logger"
+)
+
+// Health check names
+const (
+	HealthXUILogin     = "xui_login"
+	HealthServerStatus = "server_status"
+	HealthGRPC         = "grpc"
+	HealthHysteria2    = "hysteria2"
+	HealthTUIC         = "tuic"      // Only run when tuic_enabled
+	HealthShadowTLS    = "shadowtls" // Only run when shadowtls_enabled
+	HealthWireGuard    = "wireguard" // Only run with collect_wireguard or wireguard_peers_file
+)
+
+// Health check outcomes, the overall status being critical when any check failed and
+// unknown when the checks could not run
+const (
+	HealthOK       = "ok"
+	HealthFailed   = "failed"
+	HealthSkipped  = "skipped"
+	HealthCritical = "critical"
+	HealthUnknown  = "unknown"
+)
+
+// HealthCheck is the outcome of one check of xhub-agent health
+type HealthCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // ok, failed or skipped
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// HealthReport is the result of xhub-agent health
+type HealthReport struct {
+	Status string        `json:"status"` // ok, critical or unknown
+	Checks []HealthCheck `json:"checks"`
+	Error  string        `json:"error,omitempty"` // Why the checks could not run
+}
+
+// Healthy reports whether no check failed
+func (r *HealthReport) Healthy() bool {
+	return r.Status == HealthOK
+}
+
+// add runs check and records its outcome under name
+func (r *HealthReport) add(name string, check func() error) error {
+	start := time.Now()
+	err := check()
+	result := HealthCheck{Name: name, Status: HealthOK, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = HealthFailed
+		result.Detail = err.Error()
+		r.Status = HealthCritical
+	}
+	r.Checks = append(r.Checks, result)
+	return err
+}
+
+// skip records a check that was not run
+func (r *HealthReport) skip(name, reason string) {
+	r.Checks = append(r.Checks, HealthCheck{Name: name, Status: HealthSkipped, Detail: reason})
+}
+
+// CheckHealth checks the 3x-ui login, the /server/status endpoint, the gRPC connection to
+// xhub and, when enabled, the node providers (Hysteria2, TUIC, ShadowTLS, WireGuard). Only
+// the clients it needs are created: unlike a start of the agent, a health check leaves the
+// state file and xhub untouched.
+func CheckHealth(cfg *config.Config, log *logger.Logger) *HealthReport {
+	report := &HealthReport{Status: HealthOK}
+
+	authClient, err := newXUIAuth(cfg, log)
+	if err == nil {
+		err = report.add(HealthXUILogin, authClient.Login)
+	} else {
+		report.add(HealthXUILogin, func() error { return err })
+	}
+	if err != nil {
+		report.skip(HealthServerStatus, "3x-ui login failed")
+	} else if monitorClient, err := newMonitorClient(cfg, authClient, log); err != nil {
+		report.add(HealthServerStatus, func() error { return err })
+	} else {
+		report.add(HealthServerStatus, func() error {
+			_, err := monitorClient.GetServerStatus()
+			return err
+		})
+	}
+
+	report.add(HealthGRPC, func() error {
+		client, err := newXHubClient(cfg, log, time.Now(), 0)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ConnectTimeout)*time.Second)
+		defer cancel()
+		return client.CheckConnection(ctx)
+	})
+
+	hy2Client := hysteria2.NewClient(log)
+	hy2Client.Configure(cfg.Hysteria2Enabled, cfg.Hysteria2ConfigPath, cfg.Hysteria2NodeName, cfg.Hysteria2ServerAddr,
+		cfg.Hysteria2Insecure, cfg.Hysteria2PortHopping, cfg.Hysteria2PortHoppingRange)
+	hy2Client.SetUsersFile(cfg.Hysteria2UsersFile)
+	if !cfg.Hysteria2Enabled {
+		report.skip(HealthHysteria2, "hysteria2_enabled is false")
+	}
+	// Node providers are named after their protocol, like the health checks
+	for _, provider := range newNodeProviders(cfg, hy2Client, log) {
+		if provider.Enabled() {
+			report.add(provider.Name(), func() error {
+				_, err := provider.BuildShareLinks()
+				return err
+			})
+		}
+	}
+	return report
+}
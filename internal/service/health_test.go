@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/pkg/logger"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// newHealthConfig returns a config pointing at a fake panel answering logins and statuses
+// (HTTP 401 without login) and at a gRPC server
+func newHealthConfig(t *testing.T, login bool) *config.Config {
+	panel := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case !login:
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/test/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "test-session"})
+			w.Write([]byte(`{"success":true}`))
+		case r.URL.Path == "/test/server/status":
+			w.Write([]byte(`{"success":true,"obj":{"cpu":1,"cpuCores":1,"mem":{"current":1,"total":2}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(panel.Close)
+	panelURL, err := url.Parse(panel.URL)
+	require.NoError(t, err)
+
+	xhub := serveTestXHub(t, &pb.UnimplementedReportServiceServer{})
+
+	return loadReloadTestConfig(t,
+		"port: 54321", "port: "+panelURL.Port(),
+		"grpcServer: xhub.example.com", "grpcServer: localhost",
+		"grpcPort: 9090", fmt.Sprintf("grpcPort: %d", xhub.Port),
+	)
+}
+
+func newHealthLogger(t *testing.T) *logger.Logger {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "error")
+	require.NoError(t, err)
+	t.Cleanup(log.Close)
+	return log
+}
+
+// checkStatuses returns the status of every check by name
+func checkStatuses(report *HealthReport) map[string]string {
+	statuses := map[string]string{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestCheckHealth(t *testing.T) {
+	report := CheckHealth(newHealthConfig(t, true), newHealthLogger(t))
+	assert.True(t, report.Healthy(), "%+v", report.Checks)
+	assert.Equal(t, map[string]string{
+		HealthXUILogin:     HealthOK,
+		HealthServerStatus: HealthOK,
+		HealthGRPC:         HealthOK,
+		HealthHysteria2:    HealthSkipped,
+	}, checkStatuses(report))
+}
+
+func TestCheckHealth_Failures(t *testing.T) {
+	cfg := newHealthConfig(t, false)
+	cfg.Hysteria2Enabled = true
+	cfg.Hysteria2ConfigPath = filepath.Join(t.TempDir(), "missing.yaml")
+	// Nothing listens on the port of a closed listener
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	cfg.GRPCPort = lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+	cfg.ConnectTimeout = 1
+
+	report := CheckHealth(cfg, newHealthLogger(t))
+	assert.False(t, report.Healthy())
+	assert.Equal(t, HealthCritical, report.Status)
+	assert.Equal(t, map[string]string{
+		HealthXUILogin:     HealthFailed,
+		HealthServerStatus: HealthSkipped,
+		HealthGRPC:         HealthFailed,
+		HealthHysteria2:    HealthFailed,
+	}, checkStatuses(report))
+	assert.Contains(t, report.Checks[3].Detail, "missing.yaml")
+}
+
+func TestCheckHealth_NodeSources(t *testing.T) {
+	dir := t.TempDir()
+	cfg := newHealthConfig(t, true)
+	cfg.TUICEnabled = true
+	cfg.TUICConfigPath = filepath.Join(dir, "tuic.json")
+	cfg.TUICServerAddr = "node.example.com"
+	require.NoError(t, os.WriteFile(cfg.TUICConfigPath, []byte(`{"server":"[::]:443","users":{"uuid":"pw"}}`), 0644))
+	cfg.ShadowTLSEnabled = true
+	cfg.ShadowTLSConfigPath = filepath.Join(dir, "missing.json")
+
+	report := CheckHealth(cfg, newHealthLogger(t))
+	assert.Equal(t, map[string]string{
+		HealthXUILogin:     HealthOK,
+		HealthServerStatus: HealthOK,
+		HealthGRPC:         HealthOK,
+		HealthHysteria2:    HealthSkipped,
+		HealthTUIC:         HealthOK,
+		HealthShadowTLS:    HealthFailed,
+	}, checkStatuses(report))
+	assert.Equal(t, HealthCritical, report.Status)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"xhub-agent/internal/report"
+	"xhub-agent/internal/version"
+)
+
+// heartbeatUnsupportedRetry is the wait between heartbeats while xhub does not implement them
+const heartbeatUnsupportedRetry = time.Hour
+
+// agentHealth is the outcome of the latest report cycles, carried by the heartbeat
+type agentHealth struct {
+	mutex          sync.Mutex
+	panelReachable bool
+	lastError      string
+	lastErrorAt    time.Time
+	lastSuccessAt  time.Time
+}
+
+// setPanelReachable records whether the current cycle reached the 3x-ui panel
+func (h *agentHealth) setPanelReachable(reachable bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.panelReachable = reachable
+}
+
+// recordCycle records the outcome of a report cycle
+func (h *agentHealth) recordCycle(err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err != nil {
+		h.lastError = err.Error()
+		h.lastErrorAt = time.Now()
+		return
+	}
+	h.lastSuccessAt = time.Now()
+}
+
+// heartbeat returns the heartbeat of an agent running for uptime
+func (h *agentHealth) heartbeat(uptime time.Duration) report.Heartbeat {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return report.Heartbeat{
+		Version:        version.Version,
+		Uptime:         uptime,
+		PanelReachable: h.panelReachable,
+		LastError:      h.lastError,
+		LastErrorAt:    h.lastErrorAt,
+		LastSuccessAt:  h.lastSuccessAt,
+	}
+}
+
+// runHeartbeats sends a heartbeat every heartbeat_interval until the agent stops. Heartbeats
+// use their own connection, so a report cycle stuck on the panel or on xhub does not hold
+// them up.
+func (a *AgentService) runHeartbeats(interval time.Duration) {
+	defer a.wg.Done()
+	defer a.crashes.Recover("heartbeat")
+
+	uuid := a.Config().UUID
+	unsupportedLogged := false
+	for {
+		wait := interval
+		if err := a.sendHeartbeat(uuid); errors.Is(err, report.ErrHeartbeatUnsupported) {
+			if !unsupportedLogged {
+				a.logger.Warnf("⚠️  xhub does not support heartbeats, retrying every %s", heartbeatUnsupportedRetry)
+				unsupportedLogged = true
+			}
+			wait = heartbeatUnsupportedRetry
+		} else if err != nil {
+			a.logger.Debugf("💓 Heartbeat failed: %v", err)
+		}
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// sendHeartbeat sends one heartbeat on the heartbeat connection
+func (a *AgentService) sendHeartbeat(uuid string) error {
+	a.heartbeatMutex.Lock()
+	defer a.heartbeatMutex.Unlock()
+	return a.heartbeatClient.SendHeartbeat(uuid, a.health.heartbeat(time.Since(a.startTime)))
+}
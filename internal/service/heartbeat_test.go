@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/version"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// heartbeatXHub records heartbeats
+type heartbeatXHub struct {
+	pb.UnimplementedReportServiceServer
+	mutex      sync.Mutex
+	heartbeats []*pb.HeartbeatRequest
+}
+
+func (s *heartbeatXHub) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.heartbeats = append(s.heartbeats, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *heartbeatXHub) received() []*pb.HeartbeatRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*pb.HeartbeatRequest(nil), s.heartbeats...)
+}
+
+func newHeartbeatAgent(t *testing.T, xhub *heartbeatXHub) *AgentService {
+	heartbeatClient, log := startTestXHub(t, xhub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &AgentService{
+		config:          &config.Config{UUID: "test-uuid"},
+		logger:          log,
+		heartbeatClient: heartbeatClient,
+		startTime:       time.Now().Add(-time.Minute),
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+func TestAgentService_HeartbeatsWhilePanelIsDown(t *testing.T) {
+	xhub := &heartbeatXHub{}
+	agent := newHeartbeatAgent(t, xhub)
+	agent.health.setPanelReachable(false)
+	agent.health.recordCycle(errors.New("3x-ui login failed"))
+
+	// A report cycle stuck on the panel does not hold up heartbeats
+	agent.cycleMutex.Lock()
+	defer agent.cycleMutex.Unlock()
+
+	agent.wg.Add(1)
+	go agent.runHeartbeats(10 * time.Millisecond)
+	require.Eventually(t, func() bool { return len(xhub.received()) >= 2 }, 5*time.Second, 10*time.Millisecond)
+	agent.cancel()
+	agent.wg.Wait()
+
+	hb := xhub.received()[0]
+	assert.Equal(t, "test-uuid", hb.Uuid)
+	assert.Equal(t, version.Version, hb.AgentVersion)
+	assert.GreaterOrEqual(t, hb.UptimeSeconds, int64(60))
+	assert.False(t, hb.PanelReachable)
+	assert.Equal(t, "3x-ui login failed", hb.LastError)
+	assert.NotZero(t, hb.LastErrorAt)
+	assert.Zero(t, hb.LastSuccessAt)
+}
+
+func TestAgentHealth_RecordCycle(t *testing.T) {
+	var health agentHealth
+	health.recordCycle(errors.New("xhub unavailable"))
+	health.setPanelReachable(true)
+	health.recordCycle(nil)
+
+	hb := health.heartbeat(time.Minute)
+	assert.True(t, hb.PanelReachable)
+	assert.Equal(t, "xhub unavailable", hb.LastError, "the last error is kept after a success")
+	assert.False(t, hb.LastSuccessAt.Before(hb.LastErrorAt))
+	assert.Equal(t, time.Minute, hb.Uptime)
+}
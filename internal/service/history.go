@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/datadir"
+	"xhub-agent/internal/history"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// openHistory creates the status history of history_hours, persisted under the data
+// directory with history_persist. It returns nil when the history is off.
+func openHistory(cfg *config.Config, dataDir *datadir.DataDir, log *logger.Logger) *history.Buffer {
+	if cfg.HistoryHours <= 0 {
+		return nil
+	}
+	retention := time.Duration(cfg.HistoryHours) * time.Hour
+	capacity := int(retention/cfg.StatusPeriod()) + 1
+
+	if !cfg.HistoryPersist {
+		log.Infof("🗂️  Keeping %dh of status history (up to %d samples)", cfg.HistoryHours, capacity)
+		return history.New(retention, capacity)
+	}
+	if !dataDir.Enabled(datadir.ArtifactHistory) {
+		log.Warnf("⚠️  Status history kept in memory only, the data directory is not writable")
+		return history.New(retention, capacity)
+	}
+	buffer, err := history.Open(filepath.Join(dataDir.Path(datadir.ArtifactHistory), history.FileName), retention, capacity)
+	if err != nil {
+		log.Warnf("⚠️  Status history kept in memory only: %v", err)
+		return buffer
+	}
+	log.Infof("🗂️  Keeping %dh of status history (up to %d samples, %d loaded from %s)",
+		cfg.HistoryHours, capacity, buffer.Len(), dataDir.Path(datadir.ArtifactHistory))
+	return buffer
+}
+
+// recordHistory adds the status collected by the cycle to the history
+func (a *AgentService) recordHistory(data *monitor.ServerStatusData, source string) {
+	if err := a.history.Add(history.Sample{Time: time.Now(), Source: source, Status: data}); err != nil {
+		a.logger.Debugf("🗂️  Failed to persist status history: %v", err)
+	}
+}
+
+// handleHistory answers the status history since the "since" parameter (a duration such as
+// 1h or an RFC 3339 time, default all of it) as JSON, or as CSV with format=csv
+func (a *AgentService) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if a.history == nil {
+		writeAPIError(w, http.StatusNotFound, "status history is disabled (history_hours)")
+		return
+	}
+	since, err := history.ParseSince(r.URL.Query().Get("since"), time.Now())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	samples := a.history.Since(since)
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		writeAPIJSON(w, http.StatusOK, samples)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		history.WriteCSV(w, samples)
+	default:
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("unknown format %q (expected json or csv)", format))
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/history"
+	"xhub-agent/internal/monitor"
+)
+
+func TestAgentService_History(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte(reloadTestConfig+"history_hours: 1\nhistory_persist: true\n"), 0644))
+	agent, err := NewAgentService(configPath, filepath.Join(tmpDir, "agent.log"))
+	require.NoError(t, err)
+	t.Cleanup(agent.Close)
+
+	agent.recordHistory(&monitor.ServerStatusData{CPU: 12.5}, reportSourcePanel)
+	agent.recordHistory(&monitor.ServerStatusData{CPU: 50}, reportSourceHost)
+
+	code, body := getLocalAPI(t, agent, http.MethodGet, "/history?since=1h", "")
+	require.Equal(t, http.StatusOK, code)
+	var samples []history.Sample
+	require.NoError(t, json.Unmarshal([]byte(body), &samples))
+	require.Len(t, samples, 2)
+	assert.Equal(t, 12.5, samples[0].Status.CPU)
+	assert.Equal(t, reportSourceHost, samples[1].Source)
+
+	code, body = getLocalAPI(t, agent, http.MethodGet, "/history?format=csv", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, ",host,50,")
+
+	code, _ = getLocalAPI(t, agent, http.MethodGet, "/history?since=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Persisted in the data directory
+	persisted, err := history.ReadFile(filepath.Join(tmpDir, "history", history.FileName), time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, persisted, 2)
+}
+
+func TestAgentService_HistoryDisabled(t *testing.T) {
+	agent := newReloadTestAgent(t)
+	agent.recordHistory(&monitor.ServerStatusData{CPU: 12.5}, reportSourcePanel)
+
+	code, body := getLocalAPI(t, agent, http.MethodGet, "/history", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Contains(t, body, "history_hours")
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+
+	"xhub-agent/internal/sysinfo"
+)
+
+// lookupGeo looks up the location and AS of the public IP once at startup (geo_lookup_url)
+// and adds it to the host metadata of the status reports
+func (a *AgentService) lookupGeo() {
+	defer a.wg.Done()
+	defer a.crashes.Recover("geo_lookup")
+
+	ctx, cancel := context.WithTimeout(a.ctx, sysinfo.DefaultGeoTimeout)
+	defer cancel()
+	geo, err := sysinfo.NewCollector("/").LookupGeo(ctx, a.Config().GeoLookupURL)
+	if err != nil {
+		a.logger.Warnf("⚠️  Host metadata reported without geo information: %v", err)
+		return
+	}
+
+	info := a.hostInfo
+	info.Geo = geo
+	a.reportClient.SetHostInfo(info)
+	a.logger.Infof("🌍 Public IP %s: %s, AS%d %s", geo.IP, geo.Country, geo.ASN, geo.ASOrg)
+}
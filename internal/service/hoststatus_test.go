@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/selftest"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// statusXHub records the status reports it receives
+type statusXHub struct {
+	pb.UnimplementedReportServiceServer
+	mutex   sync.Mutex
+	reports []*pb.ReportRequest
+}
+
+func (s *statusXHub) SendReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reports = append(s.reports, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+// newPanelDownAgent wires an agent to a panel answering every request with HTTP 502
+func newPanelDownAgent(t *testing.T, xhub *statusXHub, fallback bool) *AgentService {
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(panel.Close)
+
+	reportClient, log := startTestXHub(t, xhub)
+	authClient := auth.NewXUIAuth(panel.URL, "admin", "password")
+
+	agent := &AgentService{
+		config:        &config.Config{UUID: "test-uuid"},
+		logger:        log,
+		authClient:    authClient,
+		monitorClient: monitor.NewMonitorClient(authClient, log),
+		reportClient:  reportClient,
+		collectors:    collector.NewRegistry(nil),
+		selfTest:      selftest.NewRunner(nil, nil, log),
+		errorCounters: errstats.NewCounters(),
+		sender:        newSendSpacer(0),
+		publicIPs:     []string{"203.0.113.7", ""},
+	}
+	if fallback {
+		agent.hostCollector = monitor.NewHostCollector("/proc", "/")
+	}
+	return agent
+}
+
+func TestAgentService_HostStatusFallback(t *testing.T) {
+	xhub := &statusXHub{}
+	agent := newPanelDownAgent(t, xhub, true)
+
+	// The cycle still fails (the panel is down) but xhub gets the host health
+	assert.Error(t, agent.executeOnce())
+	require.Len(t, xhub.reports, 1)
+	data := xhub.reports[0].Data
+	assert.Equal(t, monitor.XrayStateUnreachable, data.Xray.State)
+	assert.Contains(t, data.Xray.ErrorMsg, "3x-ui unavailable")
+	assert.Equal(t, "203.0.113.7", data.PublicIp.Ipv4, "the last known public IP is kept")
+	assert.Positive(t, data.Memory.Total)
+	assert.Positive(t, data.Uptime)
+}
+
+func TestAgentService_HostStatusFallbackDisabled(t *testing.T) {
+	xhub := &statusXHub{}
+	agent := newPanelDownAgent(t, xhub, false)
+
+	assert.Error(t, agent.executeOnce())
+	assert.Empty(t, xhub.reports)
+}
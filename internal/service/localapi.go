@@ -0,0 +1,259 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/report"
+	"xhub-agent/internal/version"
+)
+
+// Sources of the last status report
+const (
+	reportSourcePanel = "panel" // Status read from 3x-ui
+	reportSourceHost  = "host"  // Host metrics read from /proc while 3x-ui was unavailable
+)
+
+// localAPI is the status introspection API served on local_api
+type localAPI struct {
+	listener net.Listener
+	server   *http.Server
+	socket   string // Unix socket file removed on close, empty for TCP
+}
+
+// listenLocalAPI binds local_api (a loopback host:port or unix:<path>), so that a busy
+// address fails the agent construction. A stale socket file left by a crash is replaced.
+func listenLocalAPI(address string) (*localAPI, error) {
+	network, socket := "tcp", ""
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		network, address, socket = "unix", path, path
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale local API socket: %w", err)
+		}
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the local API: %w", err)
+	}
+	if socket != "" {
+		// The API shows the configuration: only the agent user and its group may connect
+		if err := os.Chmod(socket, 0660); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to restrict the local API socket: %w", err)
+		}
+	}
+	return &localAPI{
+		listener: listener,
+		server:   &http.Server{ReadHeaderTimeout: 5 * time.Second},
+		socket:   socket,
+	}, nil
+}
+
+// serveLocalAPI serves the local API until it is closed
+func (a *AgentService) serveLocalAPI() {
+	defer a.crashes.Recover("local API")
+	a.localAPI.server.Handler = a.localAPIHandler()
+	a.logger.Infof("🔎 Serving the local API on %s", a.localAPI.listener.Addr())
+	if err := a.localAPI.server.Serve(a.localAPI.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		a.logger.Errorf("❌ Local API stopped: %v", err)
+	}
+}
+
+// close stops serving and releases the address or socket
+func (e *localAPI) close() {
+	e.server.Close()
+	e.listener.Close()
+	if e.socket != "" {
+		os.Remove(e.socket)
+	}
+}
+
+// localAPIHandler routes the local API endpoints, all answering JSON but /config (YAML)
+func (a *AgentService) localAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", a.handleStatus)
+	mux.HandleFunc("GET /last-report", a.handleLastReport)
+	mux.HandleFunc("GET /config", a.handleConfig)
+	mux.HandleFunc("GET /queue", a.handleQueue)
+	mux.HandleFunc("GET /last-failures", a.handleLastFailures)
+	mux.HandleFunc("GET /history", a.handleHistory)
+	mux.HandleFunc("GET /loglevel", a.handleGetLogLevel)
+	mux.HandleFunc("PUT /loglevel", a.handleSetLogLevel)
+	mux.HandleFunc("POST /loglevel", a.handleSetLogLevel)
+	return mux
+}
+
+// lastReport is the last status sent to xhub and the outcome of the send
+type lastReport struct {
+	mutex  sync.Mutex
+	status *monitor.ServerStatusData
+	source string // reportSourcePanel or reportSourceHost
+	sentAt time.Time
+	err    string
+}
+
+// record records a status report and its error (nil when xhub accepted it)
+func (l *lastReport) record(status *monitor.ServerStatusData, source string, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.status, l.source, l.sentAt, l.err = status, source, time.Now(), ""
+	if err != nil {
+		l.err = err.Error()
+	}
+}
+
+// apiStatus is the /status response
+type apiStatus struct {
+	Version           string        `json:"version"`
+	UUID              string        `json:"uuid"`
+	Hostname          string        `json:"hostname"`
+	StartedAt         time.Time     `json:"started_at"`
+	UptimeSeconds     int64         `json:"uptime_seconds"`
+	Running           bool          `json:"running"`
+	PanelReachable    bool          `json:"panel_reachable"`
+	LastSuccessAt     time.Time     `json:"last_success_at,omitzero"`
+	LastError         string        `json:"last_error,omitempty"`
+	LastErrorAt       time.Time     `json:"last_error_at,omitzero"`
+	LogLevel          string        `json:"log_level"`
+	ConfigFingerprint string        `json:"config_fingerprint"`
+	XHub              apiXHubStatus `json:"xhub"`
+}
+
+// apiXHubStatus is the connection to xhub in the /status response
+type apiXHubStatus struct {
+	Server          string `json:"server"`
+	Transport       string `json:"transport"`
+	TLS             bool   `json:"tls"`
+	AddressFamily   string `json:"address_family,omitempty"`
+	CombinedReports bool   `json:"combined_reports"` // xhub negotiated report_combined
+	DeltaReports    bool   `json:"delta_reports"`    // xhub negotiated report_delta
+	BatchedReports  bool   `json:"batched_reports"`  // report_batch_size in use, xhub implements batches
+	QueuedReports   int    `json:"queued_reports"`
+}
+
+// handleStatus answers the agent identity, the outcome of the latest cycles and the xhub
+// connection
+func (a *AgentService) handleStatus(w http.ResponseWriter, r *http.Request) {
+	cfg := a.Config()
+	uptime := time.Since(a.startTime)
+	health := a.health.heartbeat(uptime)
+	writeAPIJSON(w, http.StatusOK, apiStatus{
+		Version:           version.Version,
+		UUID:              cfg.UUID,
+		Hostname:          a.hostInfo.Hostname,
+		StartedAt:         a.startTime,
+		UptimeSeconds:     int64(uptime / time.Second),
+		Running:           a.IsRunning(),
+		PanelReachable:    health.PanelReachable,
+		LastSuccessAt:     health.LastSuccessAt,
+		LastError:         health.LastError,
+		LastErrorAt:       health.LastErrorAt,
+		LogLevel:          a.LogLevel(),
+		ConfigFingerprint: cfg.Fingerprint(),
+		XHub: apiXHubStatus{
+			Server:          a.reportClient.ServerAddr(),
+			Transport:       string(a.reportClient.ActiveTransport()),
+			TLS:             a.reportClient.IsTLSEnabled(),
+			AddressFamily:   a.reportClient.AddressFamily(),
+			CombinedReports: a.reportClient.CombinedReportsAvailable(),
+			DeltaReports:    a.reportClient.DeltaReportsAvailable(),
+			BatchedReports:  cfg.ReportBatchSize > 1 && a.reportClient.BatchReportsAvailable(),
+			QueuedReports:   a.reportClient.QueuedReports(),
+		},
+	})
+}
+
+// handleLastReport answers the last status sent to xhub, 404 before the first report
+func (a *AgentService) handleLastReport(w http.ResponseWriter, r *http.Request) {
+	a.lastReport.mutex.Lock()
+	defer a.lastReport.mutex.Unlock()
+	if a.lastReport.status == nil {
+		writeAPIError(w, http.StatusNotFound, "no status report sent yet")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, struct {
+		SentAt time.Time                 `json:"sent_at"`
+		Source string                    `json:"source"`
+		Error  string                    `json:"error,omitempty"`
+		Status *monitor.ServerStatusData `json:"status"`
+	}{a.lastReport.sentAt, a.lastReport.source, a.lastReport.err, a.lastReport.status})
+}
+
+// handleConfig answers the effective configuration with secrets redacted, as shown by
+// "xhub-agent config show"
+func (a *AgentService) handleConfig(w http.ResponseWriter, r *http.Request) {
+	rendered, err := a.Config().EffectiveYAML()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(rendered)
+}
+
+// handleLastFailures answers the last failed or rejected RPC of every type, with the masked
+// request, as persisted by persist_failed_payloads
+func (a *AgentService) handleLastFailures(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, a.reportClient.LastFailures())
+}
+
+// handleQueue answers the state of the offline report queue
+func (a *AgentService) handleQueue(w http.ResponseWriter, r *http.Request) {
+	stats := a.reportClient.QueueStats()
+	writeAPIJSON(w, http.StatusOK, struct {
+		Enabled bool `json:"enabled"`
+		*report.QueueStats
+	}{stats != nil, stats})
+}
+
+// apiLogLevel is the /loglevel request and response
+type apiLogLevel struct {
+	Level string `json:"level"`
+}
+
+// handleGetLogLevel answers the current log level
+func (a *AgentService) handleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, apiLogLevel{Level: a.LogLevel()})
+}
+
+// handleSetLogLevel changes the log level until the agent restarts or reloads a changed
+// log_level. The level is read from the level query parameter or a {"level": ...} body.
+func (a *AgentService) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	requested := apiLogLevel{Level: r.URL.Query().Get("level")}
+	if requested.Level == "" {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&requested); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "expected ?level= or a {\"level\": ...} body")
+			return
+		}
+	}
+	level, err := a.SetLogLevel(requested.Level, "local API")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, apiLogLevel{Level: level})
+}
+
+// writeAPIJSON writes an indented JSON response
+func writeAPIJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// writeAPIError writes a {"error": ...} response
+func writeAPIError(w http.ResponseWriter, code int, message string) {
+	writeAPIJSON(w, code, struct {
+		Error string `json:"error"`
+	}{message})
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/report"
+	"xhub-agent/pkg/logger"
+)
+
+// getLocalAPI sends a request to the local API handler and returns the status and body
+func getLocalAPI(t *testing.T, agent *AgentService, method, target, body string) (int, string) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	agent.localAPIHandler().ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestAgentService_LocalAPI(t *testing.T) {
+	agent := newReloadTestAgent(t)
+
+	code, body := getLocalAPI(t, agent, http.MethodGet, "/status", "")
+	require.Equal(t, http.StatusOK, code)
+	var status apiStatus
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	assert.Equal(t, "test-uuid-123", status.UUID)
+	assert.Equal(t, "xhub.example.com:443", status.XHub.Server)
+	assert.Equal(t, "info", status.LogLevel)
+	assert.NotContains(t, body, "last_error", "unset times and errors are left out")
+
+	code, _ = getLocalAPI(t, agent, http.MethodGet, "/last-report", "")
+	assert.Equal(t, http.StatusNotFound, code, "no report sent yet")
+	agent.lastReport.record(&monitor.ServerStatusData{CPU: 12.5}, reportSourceHost, errors.New("xhub unreachable"))
+	code, body = getLocalAPI(t, agent, http.MethodGet, "/last-report", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"source": "host"`)
+	assert.Contains(t, body, `"error": "xhub unreachable"`)
+	assert.Contains(t, body, `"cpu": 12.5`)
+
+	code, body = getLocalAPI(t, agent, http.MethodGet, "/config", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "uuid: test-uuid-123")
+	assert.NotContains(t, body, "abcd1234apikey", "secrets are redacted")
+
+	code, body = getLocalAPI(t, agent, http.MethodGet, "/queue", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"enabled": true, "queued": 0, "bytes": 0, "dropped": 0}`, body)
+}
+
+func TestAgentService_LocalAPILastFailures(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "")
+
+	code, body := getLocalAPI(t, agent, http.MethodGet, "/last-failures", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[]`, body)
+
+	// The test xhub implements no report RPC
+	require.Error(t, agent.reportClient.SendReport(agent.config.UUID, &monitor.ServerStatusData{CPU: 12.5}))
+	code, body = getLocalAPI(t, agent, http.MethodGet, "/last-failures", "")
+	assert.Equal(t, http.StatusOK, code)
+	var failures []report.FailedRPC
+	require.NoError(t, json.Unmarshal([]byte(body), &failures))
+	require.Len(t, failures, 1)
+	assert.Equal(t, "SendReport", failures[0].Method)
+	assert.Equal(t, "Unimplemented", failures[0].Code)
+	assert.Contains(t, failures[0].Request, `"cpu":12.5`)
+}
+
+func TestAgentService_LocalAPILogLevel(t *testing.T) {
+	agent := newReloadTestAgent(t)
+
+	code, body := getLocalAPI(t, agent, http.MethodPut, "/loglevel?level=debug", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"level": "debug"}`, body)
+	assert.Equal(t, logger.DEBUG, agent.logger.Level())
+
+	code, _ = getLocalAPI(t, agent, http.MethodPost, "/loglevel", `{"level": "warn"}`)
+	assert.Equal(t, http.StatusOK, code)
+	code, body = getLocalAPI(t, agent, http.MethodGet, "/loglevel", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"level": "warn"}`, body)
+
+	code, _ = getLocalAPI(t, agent, http.MethodPut, "/loglevel?level=verbose", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, logger.WARN, agent.logger.Level(), "an unknown level is rejected")
+
+	code, _ = getLocalAPI(t, agent, http.MethodDelete, "/loglevel", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestAgentService_LocalAPIUnixSocket(t *testing.T) {
+	tmpDir := t.TempDir()
+	socket := filepath.Join(tmpDir, "api.sock")
+	require.NoError(t, os.WriteFile(socket, nil, 0644), "a stale socket file is replaced")
+	configPath := filepath.Join(tmpDir, "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte(reloadTestConfig+"local_api: unix:"+socket+"\n"), 0644))
+	agent, err := NewAgentService(configPath, filepath.Join(tmpDir, "agent.log"))
+	require.NoError(t, err)
+	require.NotNil(t, agent.localAPI)
+
+	go agent.serveLocalAPI()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://agent/status")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `"uuid": "test-uuid-123"`)
+
+	// Close removes the socket
+	agent.Close()
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err))
+}
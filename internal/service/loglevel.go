@@ -0,0 +1,38 @@
+package service
+
+import (
+	"strings"
+
+	"xhub-agent/pkg/logger"
+)
+
+// LogLevel returns the current log level name (debug, info, warn or error)
+func (a *AgentService) LogLevel() string {
+	return strings.ToLower(a.logger.Level().String())
+}
+
+// SetLogLevel changes the log level until the agent restarts or reloads a config changing
+// log_level, and returns the new level. source names who asked for it in the log line.
+func (a *AgentService) SetLogLevel(level, source string) (string, error) {
+	if err := a.logger.SetLevel(level); err != nil {
+		return "", err
+	}
+	level = a.LogLevel()
+	a.logger.Infof("🔧 Log level changed to %s (%s)", level, source)
+	return level, nil
+}
+
+// ToggleDebugLogging switches to debug logging, or back to the configured log_level when
+// debug logging is on (to info when log_level is debug), and returns the new level
+func (a *AgentService) ToggleDebugLogging(source string) string {
+	level := "debug"
+	if a.logger.Level() == logger.DEBUG {
+		level = a.Config().LogLevel
+		if strings.EqualFold(level, "debug") {
+			level = "info"
+		}
+	}
+	// Both levels are valid: the logger was created with log_level
+	level, _ = a.SetLogLevel(level, source)
+	return level
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentService_ToggleDebugLogging(t *testing.T) {
+	agent := newReloadTestAgent(t)
+	require.Equal(t, "info", agent.LogLevel())
+
+	assert.Equal(t, "debug", agent.ToggleDebugLogging("SIGUSR1"))
+	assert.Equal(t, "info", agent.ToggleDebugLogging("SIGUSR1"), "back to log_level")
+
+	_, err := agent.SetLogLevel("warn", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "debug", agent.ToggleDebugLogging("SIGUSR1"))
+
+	agent.config.LogLevel = "debug"
+	assert.Equal(t, "info", agent.ToggleDebugLogging("SIGUSR1"), "info when log_level is debug")
+}
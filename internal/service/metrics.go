@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"xhub-agent/internal/metrics"
+)
+
+// metricsEndpoint is the Prometheus endpoint served on metrics_listen
+type metricsEndpoint struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// listenMetrics binds metrics_listen, so that a busy address fails the agent construction
+func listenMetrics(address string, registry *metrics.Registry) (*metricsEndpoint, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	return &metricsEndpoint{
+		listener: listener,
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second},
+	}, nil
+}
+
+// serveMetrics serves /metrics until the endpoint is closed
+func (a *AgentService) serveMetrics() {
+	defer a.crashes.Recover("metrics endpoint")
+	a.logger.Infof("📈 Serving metrics on http://%s/metrics", a.metricsEndpoint.listener.Addr())
+	if err := a.metricsEndpoint.server.Serve(a.metricsEndpoint.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		a.logger.Errorf("❌ Metrics endpoint stopped: %v", err)
+	}
+}
+
+// close stops serving and releases the address
+func (e *metricsEndpoint) close() {
+	e.server.Close()
+	e.listener.Close()
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentService_MetricsEndpoint(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte(reloadTestConfig+"metrics_listen: 127.0.0.1:0\n"), 0644))
+	agent, err := NewAgentService(configPath, filepath.Join(tmpDir, "agent.log"))
+	require.NoError(t, err)
+	require.NotNil(t, agent.metricsEndpoint)
+
+	go agent.serveMetrics()
+	agent.metrics.ObserveCycle(0)
+	resp, err := http.Get("http://" + agent.metricsEndpoint.listener.Addr().String() + "/metrics")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "xhub_agent_cycle_duration_seconds_count 1\n")
+	assert.Contains(t, string(body), "xhub_agent_report_queue_depth 0\n")
+	assert.Contains(t, string(body), "xhub_agent_skipped_ticks_total 0\n")
+
+	// Close releases the address
+	address := agent.metricsEndpoint.listener.Addr().String()
+	agent.Close()
+	_, err = http.Get("http://" + address + "/metrics")
+	assert.Error(t, err)
+}
+
+func TestAgentService_MetricsDisabled(t *testing.T) {
+	agent := newReloadTestAgent(t)
+	assert.Nil(t, agent.metrics)
+	assert.Nil(t, agent.metricsEndpoint)
+}
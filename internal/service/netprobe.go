@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"xhub-agent/internal/netprobe"
+	"xhub-agent/internal/report"
+)
+
+// runNetworkProbes runs the network quality probes every network_probe_interval until the
+// agent stops. The first run waits one interval, so restarts do not probe in bursts.
+func (a *AgentService) runNetworkProbes(interval time.Duration) {
+	defer a.wg.Done()
+	defer a.crashes.Recover("network probe")
+
+	unsupportedLogged := false
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		_, err := a.runNetworkProbe(a.ctx, netprobe.TriggerSchedule, "", true)
+		switch {
+		case a.ctx.Err() != nil:
+			return
+		case errors.Is(err, report.ErrNetworkQualityUnsupported):
+			if !unsupportedLogged {
+				a.logger.Warnf("⚠️  xhub does not support network quality reports, results are only logged")
+				unsupportedLogged = true
+			}
+		case err != nil:
+			a.logger.Debugf("📶 Network quality report failed: %v", err)
+		}
+	}
+}
+
+// runNetworkProbe runs the network quality probes and reports the results to xhub. It returns
+// the summary of the results, also when the report fails.
+func (a *AgentService) runNetworkProbe(ctx context.Context, trigger, commandID string, throughput bool) (string, error) {
+	result := a.netProber.Run(ctx, trigger, throughput)
+	result.CommandID = commandID
+	summary := result.Summary()
+	a.logger.Infof("📶 Network probe (%s): %s", trigger, summary)
+
+	var err error
+	a.withReportClient(func() { err = a.reportClient.SendNetworkQualityReport(a.config.UUID, result) })
+	if err != nil {
+		if !errors.Is(err, report.ErrNetworkQualityUnsupported) {
+			a.recordError(err)
+		}
+		return summary, fmt.Errorf("network quality report failed: %w", err)
+	}
+	return summary, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/command"
+	"xhub-agent/internal/netprobe"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// networkQualityXHub records the network quality reports and command results it receives
+type networkQualityXHub struct {
+	commandXHub
+
+	mutex   sync.Mutex
+	reports []*pb.NetworkQualityReport
+}
+
+func (s *networkQualityXHub) SendNetworkQualityReport(ctx context.Context, req *pb.NetworkQualityReport) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reports = append(s.reports, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+// listenAndClose accepts and closes connections until the test ends, returning its address
+func listenAndClose(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return lis.Addr().String()
+}
+
+func TestAgentService_CommandNetworkProbe(t *testing.T) {
+	target := listenAndClose(t)
+	xhub := &networkQualityXHub{}
+	agent := newCommandTestAgent(t, xhub, fmt.Sprintf(
+		"command_allowlist: [network_probe]\nnetwork_probe_targets: [%q]\nnetwork_probe_count: 2\n", target))
+
+	result := agent.commands.Execute(context.Background(), command.Command{ID: "np1", Name: command.NetworkProbe})
+	require.Empty(t, result.Err)
+	assert.Contains(t, result.Output, target+": avg ")
+	assert.Contains(t, result.Output, "loss 0%")
+
+	require.Len(t, xhub.reports, 1)
+	report := xhub.reports[0]
+	assert.Equal(t, netprobe.TriggerCommand, report.Trigger)
+	assert.Equal(t, "np1", report.CommandId)
+	require.Len(t, report.Probes, 1)
+	assert.Equal(t, target, report.Probes[0].Target)
+	assert.Equal(t, int32(2), report.Probes[0].Received)
+	assert.Nil(t, report.Throughput)
+
+	result = agent.commands.Execute(context.Background(), command.Command{ID: "np2", Name: command.NetworkProbe, Args: map[string]string{"throughput": "maybe"}})
+	assert.Equal(t, "throughput must be true or false", result.Err)
+}
+
+func TestAgentService_CommandNetworkProbe_Unconfigured(t *testing.T) {
+	agent := newCommandTestAgent(t, &networkQualityXHub{}, "command_allowlist: [network_probe]\n")
+	assert.Nil(t, agent.netProber)
+
+	result := agent.commands.Execute(context.Background(), command.Command{ID: "np1", Name: command.NetworkProbe})
+	assert.Contains(t, result.Err, "no network_probe_targets or network_probe_throughput_url configured")
+}
+
+func TestAgentService_NetworkProbeUnsupported(t *testing.T) {
+	target := listenAndClose(t)
+	agent := newCommandTestAgent(t, &commandXHub{}, fmt.Sprintf("network_probe_targets: [%q]\nnetwork_probe_count: 1\n", target))
+
+	summary, err := agent.runNetworkProbe(context.Background(), netprobe.TriggerSchedule, "", true)
+	assert.Contains(t, summary, target+": avg ")
+	assert.ErrorContains(t, err, "xhub does not support network quality reports")
+}
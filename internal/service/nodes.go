@@ -0,0 +1,69 @@
+package service
+
+import (
+	"slices"
+
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/hysteria2"
+	"xhub-agent/internal/nodeprovider"
+	"xhub-agent/internal/shadowtls"
+	"xhub-agent/internal/tuic"
+	"xhub-agent/internal/wireguard"
+	"xhub-agent/pkg/logger"
+)
+
+// newNodeProviders returns the providers of every protocol served outside 3x-ui, enabled or
+// not, in subscription order. Supporting a new protocol only takes adding its provider here.
+func newNodeProviders(cfg *config.Config, hy2Client *hysteria2.Client, log *logger.Logger) []nodeprovider.Provider {
+	return []nodeprovider.Provider{
+		hy2Client,
+		tuic.NewClient(tuic.Options{
+			Enabled:    cfg.TUICEnabled,
+			ConfigPath: cfg.TUICConfigPath,
+			NodeName:   cfg.TUICNodeName,
+			ServerAddr: cfg.TUICServerAddr,
+			Insecure:   cfg.TUICInsecure,
+			UsersFile:  cfg.TUICUsersFile,
+		}),
+		shadowtls.NewClient(shadowtls.Options{
+			Enabled:    cfg.ShadowTLSEnabled,
+			ConfigPath: cfg.ShadowTLSConfigPath,
+			NodeName:   cfg.ShadowTLSNodeName,
+			ServerAddr: cfg.ShadowTLSServerAddr,
+		}),
+		wireguard.NewProvider(cfg.CollectWireGuard, wireguard.StubOptions{
+			Interface: cfg.WireGuardInterface,
+			Endpoint:  cfg.WireGuardEndpoint,
+			DNS:       cfg.WireGuardDNS,
+			PeersFile: cfg.WireGuardPeersFile,
+		}, log.With("component", "wireguard")),
+	}
+}
+
+// registerNodeProviders registers the status of the enabled providers as collectors named
+// after their protocol and returns the enabled providers
+func registerNodeProviders(providers []nodeprovider.Provider, collectors *collector.Registry) []nodeprovider.Provider {
+	var enabled []nodeprovider.Provider
+	for _, provider := range providers {
+		if !provider.Enabled() {
+			continue
+		}
+		collectors.Register(collector.Collector{
+			Name:    provider.Name(),
+			Collect: provider.CollectStatus,
+		})
+		enabled = append(enabled, provider)
+	}
+	return enabled
+}
+
+// reportedNodeProviders leaves out the providers whose reports are turned off (report_hysteria2)
+func reportedNodeProviders(cfg *config.Config, providers []nodeprovider.Provider) []nodeprovider.Provider {
+	if cfg.Hysteria2ReportEnabled() {
+		return providers
+	}
+	return slices.DeleteFunc(providers, func(provider nodeprovider.Provider) bool {
+		return provider.Name() == hysteria2.ProtocolName
+	})
+}
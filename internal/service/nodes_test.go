@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/nodeprovider"
+	"xhub-agent/internal/sharelink"
+)
+
+// fakeNodeProvider is a node provider with canned links
+type fakeNodeProvider struct {
+	name    string
+	enabled bool
+	links   nodeprovider.ShareLinks
+	err     error
+}
+
+func (p *fakeNodeProvider) Name() string  { return p.name }
+func (p *fakeNodeProvider) Enabled() bool { return p.enabled }
+
+func (p *fakeNodeProvider) CollectStatus() collector.Apply {
+	return nodeprovider.ProtocolStatus(p.name)
+}
+
+func (p *fakeNodeProvider) BuildShareLinks() (nodeprovider.ShareLinks, error) {
+	return p.links, p.err
+}
+
+func TestAgentService_NodeProviders(t *testing.T) {
+	agent := newPanelAgent(t, &combinedXHub{calls: map[string]int{}}, true)
+	agent.nodeProviders = registerNodeProviders([]nodeprovider.Provider{
+		&fakeNodeProvider{name: "tuic", enabled: true, links: nodeprovider.ShareLinks{Nodes: sharelink.Nodes{Shared: "tuic://shared"}}},
+		&fakeNodeProvider{name: "disabled", links: nodeprovider.ShareLinks{Nodes: sharelink.Nodes{Shared: "disabled://"}}},
+		&fakeNodeProvider{name: "broken", enabled: true, err: errors.New("no config")},
+		&fakeNodeProvider{name: "wireguard", enabled: true, links: nodeprovider.ShareLinks{
+			Nodes:           sharelink.Nodes{PerUser: map[string]string{"alice@example.com": "[Interface]\n"}},
+			WireGuardConfig: true,
+		}},
+	}, agent.collectors)
+	require.Len(t, agent.nodeProviders, 3, "disabled providers are dropped")
+	_, registered := agent.collectors.Interval("disabled")
+	assert.False(t, registered)
+
+	result := agent.RunOnce(false)
+	assert.Equal(t, []string{"broken", "tuic", "vless", "wireguard"}, result.Status.InboundProtocols)
+	require.Len(t, result.Subscriptions, 1)
+	nodeConfig, err := base64.StdEncoding.DecodeString(result.Subscriptions[0].NodeConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "vless://node\ntuic://shared", string(nodeConfig), "a failing provider adds no link")
+	assert.Equal(t, "[Interface]\n", result.Subscriptions[0].WireGuard)
+}
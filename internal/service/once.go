@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/report"
+)
+
+// Stages of a one-shot cycle, named in its failures
+const (
+	StageAuth              = "auth"
+	StageStatus            = "status"
+	StageSubscriptions     = "subscriptions"
+	StageOnlineUsers       = "online_users"
+	StageSendStatus        = "send_status"
+	StageSendSubscriptions = "send_subscriptions"
+	StageSendOnlineUsers   = "send_online_users"
+)
+
+// StageFailure is a failed stage of a one-shot cycle
+type StageFailure struct {
+	Stage string `json:"stage"`
+	Error string `json:"error"`
+}
+
+// OnceResult holds the payloads collected by a one-shot cycle (-once, -dry-run)
+type OnceResult struct {
+	Sent          bool                      `json:"sent"` // Reported to xhub (-once), false for a dry run
+	Status        *monitor.ServerStatusData `json:"status"`
+	Subscriptions []report.SubscriptionData `json:"subscriptions"`
+	OnlineUsers   []string                  `json:"online_users"`
+	OnlineDetails []monitor.OnlineUser      `json:"online_user_details,omitempty"`
+	Failures      []StageFailure            `json:"failures,omitempty"`
+}
+
+// Passed reports whether every stage of the cycle succeeded
+func (r *OnceResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// fail records a failed stage
+func (r *OnceResult) fail(stage string, err error) {
+	r.Failures = append(r.Failures, StageFailure{Stage: stage, Error: err.Error()})
+}
+
+// RunOnce runs a single collection cycle outside the work loop, collecting the status, the
+// subscriptions and the online users whatever their intervals, unless their report type is
+// turned off. With send the payloads are
+// reported to xhub, otherwise (dry run) nothing leaves the host. Unlike the work loop, a
+// failed stage is returned rather than only logged.
+func (a *AgentService) RunOnce(send bool) *OnceResult {
+	a.cycleMutex.Lock()
+	defer a.cycleMutex.Unlock()
+
+	result := &OnceResult{}
+	a.authClient.StartCycle()
+	if err := a.ensureAuthenticated(); err != nil {
+		result.fail(StageAuth, err)
+		return result
+	}
+
+	a.detectPanelVersion()
+	if a.config.StatusReportEnabled() {
+		status, err := a.monitorClient.GetServerStatus()
+		if err != nil {
+			result.fail(StageStatus, err)
+		} else {
+			a.collectors.Apply(status.Data)
+			status.Data.SelfTest = a.selfTest.Status()
+			result.Status = status.Data
+		}
+	}
+
+	if !a.config.SubscriptionReportEnabled() {
+		// report_subscriptions: false
+	} else if !a.checkResolvedDomain() {
+		result.fail(StageSubscriptions, fmt.Errorf("resolved domain %s does not resolve", a.domainChecker.Domain()))
+	} else if subscriptions, err := a.subscriptionReport(); err != nil {
+		result.fail(StageSubscriptions, err)
+	} else {
+		result.Subscriptions = subscriptions
+	}
+
+	if a.config.OnlineUsersReportEnabled() {
+		online, err := a.monitorClient.GetOnlineUsers()
+		if err != nil {
+			result.fail(StageOnlineUsers, err)
+		} else {
+			result.OnlineUsers = online.Data
+			result.OnlineDetails = a.collectOnlineUserDetails(online.Data)
+		}
+	}
+
+	if !send {
+		return result
+	}
+	if result.Status != nil {
+		if err := a.reportClient.SendReport(a.config.UUID, result.Status); err != nil {
+			result.fail(StageSendStatus, err)
+		}
+	}
+	if len(result.Subscriptions) > 0 {
+		if err := a.reportClient.SendSubscriptionReport(a.config.UUID, result.Subscriptions); err != nil {
+			result.fail(StageSendSubscriptions, err)
+		}
+	}
+	if result.OnlineUsers != nil {
+		users := &report.CombinedOnlineUsers{Emails: result.OnlineUsers, Users: result.OnlineDetails}
+		if err := a.reportClient.SendOnlineUsers(a.config.UUID, users); err != nil {
+			result.fail(StageSendOnlineUsers, err)
+		}
+	}
+	result.Sent = true
+	return result
+}
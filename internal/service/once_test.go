@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/subscription"
+)
+
+func TestAgentService_InboundsCollectedAtInterval(t *testing.T) {
+	agent := newPanelAgent(t, &combinedXHub{calls: map[string]int{}}, false)
+	now := time.Now()
+	agent.collectors.SetClockForTesting(func() time.Time { return now })
+	require.NoError(t, agent.ensureAuthenticated())
+
+	data := &monitor.ServerStatusData{}
+	agent.collectors.Apply(data)
+	assert.Equal(t, []string{"vless"}, data.InboundProtocols)
+
+	// Within the interval the cached list is reported without asking the panel
+	unreachable := auth.NewXUIAuth("http://127.0.0.1:1", "admin", "password")
+	agent.subscriptionClient = subscription.NewSubscriptionClient(unreachable, "", agent.logger)
+	data = &monitor.ServerStatusData{}
+	agent.collectors.Apply(data)
+	assert.Equal(t, []string{"vless"}, data.InboundProtocols)
+	assert.Empty(t, agent.errorCounters.Pending())
+
+	now = now.Add(inboundsInterval)
+	data = &monitor.ServerStatusData{}
+	agent.collectors.Apply(data)
+	assert.Empty(t, data.InboundProtocols)
+	assert.NotEmpty(t, agent.errorCounters.Pending())
+}
+
+func TestAgentService_RunOnceDryRun(t *testing.T) {
+	xhub := &combinedXHub{calls: map[string]int{}}
+	agent := newPanelAgent(t, xhub, true)
+
+	result := agent.RunOnce(false)
+	assert.True(t, result.Passed(), "%+v", result.Failures)
+	assert.False(t, result.Sent)
+	require.NotNil(t, result.Status)
+	assert.Equal(t, 4.5, result.Status.CPU)
+	assert.Equal(t, []string{"vless"}, result.Status.InboundProtocols)
+	require.Len(t, result.Subscriptions, 1)
+	assert.Equal(t, "sub1", result.Subscriptions[0].SubID)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, result.OnlineUsers)
+	assert.Empty(t, xhub.counts(), "a dry run reports nothing")
+}
+
+func TestAgentService_RunOnceSends(t *testing.T) {
+	xhub := &combinedXHub{calls: map[string]int{}}
+	agent := newPanelAgent(t, xhub, true)
+
+	result := agent.RunOnce(true)
+	assert.True(t, result.Passed(), "%+v", result.Failures)
+	assert.True(t, result.Sent)
+	assert.Equal(t, map[string]int{"SendReport": 1, "SendSubscriptionReport": 1, "SendOnlineUsersReport": 1}, xhub.counts())
+}
+
+func TestAgentService_RunOnceFailedStage(t *testing.T) {
+	xhub := &combinedXHub{calls: map[string]int{}}
+	agent := newPanelAgent(t, xhub, false)
+
+	result := agent.RunOnce(true)
+	assert.False(t, result.Passed())
+	require.Len(t, result.Failures, 1)
+	assert.Equal(t, StageOnlineUsers, result.Failures[0].Stage)
+	assert.Nil(t, result.OnlineUsers)
+	assert.NotNil(t, result.Status, "the other stages still run")
+	assert.Equal(t, map[string]int{"SendReport": 1, "SendSubscriptionReport": 1}, xhub.counts())
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"time"
+)
+
+// onlineUsersCache keeps the last successfully fetched online users list so that a transient
+// GetOnlineUsers failure can be bridged for up to maxStaleness
+type onlineUsersCache struct {
+	maxStaleness time.Duration // 0 disables reuse: a failed fetch skips the send
+	clock        sendClock
+
+	emails    []string
+	fetchedAt time.Time
+	fetched   bool // At least one fetch succeeded
+	expired   bool // The expiry has been reported since the last successful fetch
+}
+
+// newOnlineUsersCache creates a cache reusing lists for up to maxStaleness
+func newOnlineUsersCache(maxStaleness time.Duration) *onlineUsersCache {
+	return &onlineUsersCache{maxStaleness: maxStaleness, clock: realClock{}}
+}
+
+// Store records a successfully fetched list
+func (c *onlineUsersCache) Store(emails []string) {
+	c.emails = append([]string(nil), emails...)
+	c.fetchedAt = c.clock.Now()
+	c.fetched = true
+	c.expired = false
+}
+
+// Fallback returns what to report after a failed fetch: the last list while it is at most
+// maxStaleness old, an empty list once it is older. ok is false when nothing should be sent
+// (reuse disabled or no list fetched yet); expiredNow is true the first time the bound is exceeded.
+func (c *onlineUsersCache) Fallback() (emails []string, age time.Duration, ok, expiredNow bool) {
+	if c.maxStaleness <= 0 || !c.fetched {
+		return nil, 0, false, false
+	}
+
+	age = c.clock.Now().Sub(c.fetchedAt)
+	if age <= c.maxStaleness {
+		return append([]string(nil), c.emails...), age, true, false
+	}
+
+	expiredNow = !c.expired
+	c.expired = true
+	return []string{}, age, true, expiredNow
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/report"
+	"xhub-agent/pkg/logger"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// onlineUsersServer records the online users reports and their age metadata
+type onlineUsersServer struct {
+	pb.UnimplementedReportServiceServer
+	mutex   sync.Mutex
+	reports [][]string
+	ages    []string // x-agent-online-users-age, "" when absent
+	users   [][]*pb.OnlineUser
+}
+
+func (s *onlineUsersServer) SendOnlineUsersReport(ctx context.Context, req *pb.OnlineUsersReportRequest) (*pb.ReportResponse, error) {
+	age := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-agent-online-users-age")) > 0 {
+		age = md.Get("x-agent-online-users-age")[0]
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reports = append(s.reports, append([]string{}, req.OnlineEmails...))
+	s.ages = append(s.ages, age)
+	s.users = append(s.users, req.Users)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func (s *onlineUsersServer) last() ([]string, string, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.reports) == 0 {
+		return nil, "", 0
+	}
+	return s.reports[len(s.reports)-1], s.ages[len(s.ages)-1], len(s.reports)
+}
+
+// serveTestXHub serves xhub over gRPC on a local port until the test ends
+func serveTestXHub(t *testing.T, xhub pb.ReportServiceServer) *net.TCPAddr {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	pb.RegisterReportServiceServer(server, xhub)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().(*net.TCPAddr)
+}
+
+// startTestXHub serves xhub and returns a report client connected to it, logging to a debug
+// log in the test directory
+func startTestXHub(t *testing.T, xhub pb.ReportServiceServer) (*report.ReportClient, *logger.Logger) {
+	t.Helper()
+	addr := serveTestXHub(t, xhub)
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+	reportClient := report.NewReportClient(addr.String(), "test-key", log)
+	t.Cleanup(func() { reportClient.Close() })
+	return reportClient, log
+}
+
+// newOnlineUsersAgent wires an agent to a fake panel whose onlines endpoint fails while
+// panelDown is set, and to a recording xhub server
+func newOnlineUsersAgent(t *testing.T, maxStaleness time.Duration, panelDown *atomic.Bool) (*AgentService, *onlineUsersServer, *fakeClock) {
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "3x-ui", Value: "session"})
+			w.Write([]byte(`{"success":true}`))
+		case "/panel/inbound/onlines":
+			if panelDown.Load() {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.Write([]byte(`{"success":true,"obj":["alice@example.com","bob@example.com"]}`))
+		case "/panel/inbound/clientIps/alice@example.com":
+			w.Write([]byte(`{"success":true,"obj":"[\"203.0.113.7 (2024-05-01 12:00:00)\",\"198.51.100.2\"]"}`))
+		case "/panel/inbound/clientIps/bob@example.com":
+			w.Write([]byte(`{"success":true,"obj":"No IP Record"}`))
+		}
+	}))
+	t.Cleanup(panel.Close)
+
+	recorder := &onlineUsersServer{}
+	reportClient, log := startTestXHub(t, recorder)
+	authClient := auth.NewXUIAuth(panel.URL, "admin", "password")
+	require.NoError(t, authClient.Login())
+
+	clock := newFakeClock()
+	agent := &AgentService{
+		config:        &config.Config{UUID: "test-uuid"},
+		logger:        log,
+		monitorClient: monitor.NewMonitorClient(authClient, log),
+		reportClient:  reportClient,
+		errorCounters: errstats.NewCounters(),
+		onlineUsers:   newOnlineUsersCache(maxStaleness),
+	}
+	agent.onlineUsers.clock = clock
+	return agent, recorder, clock
+}
+
+func TestAgentService_OnlineUsersStaleness(t *testing.T) {
+	var panelDown atomic.Bool
+	agent, recorder, clock := newOnlineUsersAgent(t, 2*time.Minute, &panelDown)
+
+	agent.reportOnlineUsersData()
+	emails, age, count := recorder.last()
+	require.Equal(t, 1, count)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, emails)
+	assert.Empty(t, age, "a fresh list carries no age")
+
+	// Within the bound the last list is reused, with its age
+	panelDown.Store(true)
+	clock.Advance(90 * time.Second)
+	agent.reportOnlineUsersData()
+	emails, age, count = recorder.last()
+	require.Equal(t, 2, count)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, emails)
+	assert.Equal(t, "90", age)
+
+	// Past the bound the stale list is no longer sent
+	clock.Advance(time.Minute)
+	agent.reportOnlineUsersData()
+	agent.reportOnlineUsersData()
+	emails, age, count = recorder.last()
+	require.Equal(t, 4, count)
+	assert.Empty(t, emails)
+	assert.Equal(t, "150", age)
+
+	// Recovery reports the live list again
+	panelDown.Store(false)
+	agent.reportOnlineUsersData()
+	emails, age, _ = recorder.last()
+	assert.Len(t, emails, 2)
+	assert.Empty(t, age)
+}
+
+func TestAgentService_OnlineUsersStalenessDisabled(t *testing.T) {
+	var panelDown atomic.Bool
+	agent, recorder, _ := newOnlineUsersAgent(t, 0, &panelDown)
+
+	agent.reportOnlineUsersData()
+	panelDown.Store(true)
+	agent.reportOnlineUsersData()
+
+	_, _, count := recorder.last()
+	assert.Equal(t, 1, count, "a failed fetch skips the send when reuse is disabled")
+}
+
+func TestOnlineUsersCache_NothingFetchedYet(t *testing.T) {
+	cache := newOnlineUsersCache(time.Minute)
+	_, _, ok, _ := cache.Fallback()
+	assert.False(t, ok, "no list is invented before the first successful fetch")
+}
+
+func TestAgentService_OnlineUserDetails(t *testing.T) {
+	var panelDown atomic.Bool
+	agent, recorder, _ := newOnlineUsersAgent(t, 0, &panelDown)
+	agent.monitorClient.SetUserDetails(monitor.UserDetailsPanel, "")
+
+	agent.reportOnlineUsersData()
+	require.Len(t, recorder.users, 1)
+	users := recorder.users[0]
+	require.Len(t, users, 2)
+	assert.Equal(t, "alice@example.com", users[0].Email)
+	require.Len(t, users[0].Ips, 2)
+	assert.Equal(t, "203.0.113.7", users[0].Ips[0].Ip, "most recently seen first")
+	assert.NotZero(t, users[0].Ips[0].LastSeen)
+	assert.Equal(t, "198.51.100.2", users[0].Ips[1].Ip)
+	assert.Zero(t, users[0].Ips[1].LastSeen)
+	assert.Equal(t, "bob@example.com", users[1].Email)
+	assert.Empty(t, users[1].Ips)
+}
+
+func TestAgentService_OnlineUserDetailsUnavailable(t *testing.T) {
+	var panelDown atomic.Bool
+	agent, recorder, _ := newOnlineUsersAgent(t, 0, &panelDown)
+	agent.monitorClient.SetUserDetails(monitor.UserDetailsAccessLog, filepath.Join(t.TempDir(), "missing.log"))
+
+	agent.reportOnlineUsersData()
+	emails, _, count := recorder.last()
+	require.Equal(t, 1, count, "the list is still reported")
+	assert.Len(t, emails, 2)
+	assert.Empty(t, recorder.users[0])
+	assert.Contains(t, agent.userDetailsErr, "failed to open Xray access log")
+}
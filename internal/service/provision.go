@@ -0,0 +1,44 @@
+package service
+
+import "xhub-agent/internal/report"
+
+// runProvisioningChannel keeps the provisioning stream from xhub open until the agent stops,
+// applying each client change and reporting its result
+func (a *AgentService) runProvisioningChannel() {
+	defer a.wg.Done()
+	defer a.crashes.Recover("provisioning channel")
+	a.keepStreamOpen("provisioning", a.receiveProvisioning)
+}
+
+// receiveProvisioning opens the provisioning stream and applies the received requests one at
+// a time until the stream ends
+func (a *AgentService) receiveProvisioning() error {
+	var stream *report.ProvisioningStream
+	var err error
+	a.withReportClient(func() { stream, err = a.reportClient.OpenProvisioningStream(a.ctx, a.config.UUID) })
+	if err != nil {
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		result := a.provisioner.Apply(req)
+		switch {
+		case result.Err != "":
+			a.logger.Warnf("⚠️  Provisioning %s (%s) on inbound %d failed: %s", req.Action, req.Key, req.InboundID, result.Err)
+		case result.Replayed:
+			a.logger.Infof("🔂 Provisioning %s (%s) already applied, result resent", req.Action, req.Key)
+		default:
+			a.logger.Infof("👤 Provisioning %s (%s) applied on inbound %d", req.Action, req.Key, req.InboundID)
+		}
+
+		a.withReportClient(func() { err = a.reportClient.SendProvisioningResult(a.config.UUID, result) })
+		if err != nil {
+			a.recordError(err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/provision"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// provisioningXHub streams its provisioning requests to the agent and records the results
+type provisioningXHub struct {
+	pb.UnimplementedReportServiceServer
+	requests []*pb.ProvisioningRequest
+
+	mutex   sync.Mutex
+	results []*pb.ProvisioningResult
+}
+
+func (s *provisioningXHub) SubscribeProvisioning(req *pb.ProvisioningSubscription, stream pb.ReportService_SubscribeProvisioningServer) error {
+	for _, request := range s.requests {
+		if err := stream.Send(request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *provisioningXHub) SendProvisioningResult(ctx context.Context, req *pb.ProvisioningResult) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.results = append(s.results, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestAgentService_ProvisioningChannel(t *testing.T) {
+	xhub := &provisioningXHub{requests: []*pb.ProvisioningRequest{
+		{Action: provision.AddClient, InboundId: 1, ClientJson: []byte(`{"email": "alice"}`)},
+		{IdempotencyKey: "k2", Action: provision.RemoveClient, InboundId: 1, ClientId: "alice"},
+	}}
+	agent := newCommandTestAgent(t, xhub, "provisioning_channel: true\n")
+	require.NotNil(t, agent.provisioner)
+
+	require.Error(t, agent.receiveProvisioning(), "the stream ends after the requests")
+
+	require.Len(t, xhub.results, 2)
+	assert.False(t, xhub.results[0].Success)
+	assert.Equal(t, "missing idempotency key", xhub.results[0].Error)
+	assert.Equal(t, "k2", xhub.results[1].IdempotencyKey)
+	assert.Equal(t, provision.RemoveClient, xhub.results[1].Action)
+	assert.False(t, xhub.results[1].Success)
+	assert.Contains(t, xhub.results[1].Error, "login failed", "the test panel is unreachable")
+
+	agent = newCommandTestAgent(t, xhub, "")
+	assert.Nil(t, agent.provisioner, "off by default")
+}
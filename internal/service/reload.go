@@ -0,0 +1,82 @@
+package service
+
+import (
+	"time"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/pkg/logger"
+)
+
+// liveReloadFields are the config fields Reload applies to the running agent; a change to any
+// other field needs a new agent
+var liveReloadFields = map[string]bool{
+	"poll_interval":         true,
+	"status_interval":       true,
+	"subscription_interval": true,
+	"online_users_interval": true,
+	"log_level":             true,
+	"log_format":            true,
+	"grpcServer":            true,
+	"grpcPort":              true,
+	"xhub_api_key":          true,
+}
+
+// Reload applies next to the running agent between report cycles when every changed field
+// can be changed live, and reports whether it did. Only the clients whose settings changed
+// are recreated; false leaves the agent untouched.
+func (a *AgentService) Reload(next *config.Config) bool {
+	changes := config.Diff(a.config, next)
+	for _, change := range changes {
+		if !liveReloadFields[change.Field] {
+			return false
+		}
+	}
+
+	// Wait for the running cycle and its spaced sends, the next one uses the new config
+	a.cycleMutex.Lock()
+	defer a.cycleMutex.Unlock()
+	a.sender.Flush()
+
+	current := a.config
+	// Both are checked before either is applied, so a bad format does not leave a new level
+	if next.LogFormat != current.LogFormat {
+		if _, err := logger.ParseFormat(next.LogFormat); err != nil {
+			return false
+		}
+	}
+	if next.LogLevel != current.LogLevel {
+		if err := a.logger.SetLevel(next.LogLevel); err != nil {
+			return false
+		}
+	}
+	if next.LogFormat != current.LogFormat {
+		a.logger.SetFormat(next.LogFormat)
+	}
+	retarget := next.GRPCServer != current.GRPCServer || next.GRPCPort != current.GRPCPort || next.XHubAPIKey != current.XHubAPIKey
+	if retarget {
+		a.reportClient.SetTarget(next.GRPCAddress(), next.XHubAPIKey)
+	}
+	statusInterval := next.StatusPeriod()
+	if statusInterval != current.StatusPeriod() {
+		if a.ticker != nil {
+			a.ticker.Reset(statusInterval)
+		}
+		a.triggers.setMinInterval(statusInterval)
+		a.sender.spacing = sendSpacing(time.Duration(next.SendSpacingMs)*time.Millisecond, statusInterval, deferredSendsPerCycle)
+		a.logger.Infof("⏱️  Status interval changed to %v", statusInterval)
+	}
+	a.schedule.SetIntervals(statusInterval, next.SubscriptionPeriod(), next.OnlineUsersPeriod())
+	a.reportClient.SetConfigFingerprint(next.Fingerprint())
+	if a.heartbeatClient != nil {
+		a.heartbeatMutex.Lock()
+		if retarget {
+			a.heartbeatClient.SetTarget(next.GRPCAddress(), next.XHubAPIKey)
+		}
+		a.heartbeatClient.SetConfigFingerprint(next.Fingerprint())
+		a.heartbeatMutex.Unlock()
+	}
+	a.configMutex.Lock()
+	a.config = next
+	a.configMutex.Unlock()
+	return true
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/pkg/logger"
+)
+
+const reloadTestConfig = `uuid: test-uuid-123
+xui_user: admin
+xui_pass: password123
+xhub_api_key: abcd1234apikey
+grpcServer: xhub.example.com
+grpcPort: 9090
+rootPath: /test
+port: 54321
+xui_base_url: 127.0.0.1
+poll_interval: 5
+log_level: info
+`
+
+// loadReloadTestConfig loads reloadTestConfig with the given replacements applied
+func loadReloadTestConfig(t *testing.T, replacements ...string) *config.Config {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte(strings.NewReplacer(replacements...).Replace(reloadTestConfig)), 0644))
+	cfg, err := config.LoadFromFile(configPath)
+	require.NoError(t, err)
+	return cfg
+}
+
+func newReloadTestAgent(t *testing.T) *AgentService {
+	t.Helper()
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte(reloadTestConfig), 0644))
+	agent, err := NewAgentService(configPath, filepath.Join(tmpDir, "agent.log"))
+	require.NoError(t, err)
+	t.Cleanup(agent.Close)
+	return agent
+}
+
+func TestAgentService_ReloadLive(t *testing.T) {
+	agent := newReloadTestAgent(t)
+	reportClient := agent.reportClient
+	next := loadReloadTestConfig(t,
+		"poll_interval: 5", "poll_interval: 10",
+		"log_level: info", "log_level: debug",
+		"grpcServer: xhub.example.com", "grpcServer: localhost",
+		"xhub_api_key: abcd1234apikey", "xhub_api_key: rotated-key")
+
+	require.True(t, agent.Reload(next))
+	assert.Same(t, next, agent.Config())
+	assert.Same(t, reportClient, agent.reportClient, "the report client is kept and re-targeted")
+	assert.Equal(t, "localhost:9090", reportClient.ServerAddr())
+	assert.Equal(t, logger.DEBUG, agent.logger.Level())
+	assert.Equal(t, 10*time.Second, agent.triggers.minInterval)
+	assert.Equal(t, sendSpacing(0, 10*time.Second, deferredSendsPerCycle), agent.sender.spacing)
+}
+
+func TestAgentService_ReloadLogLevelOnly(t *testing.T) {
+	agent := newReloadTestAgent(t)
+
+	require.True(t, agent.Reload(loadReloadTestConfig(t, "log_level: info", "log_level: warn")))
+	assert.Equal(t, logger.WARN, agent.logger.Level())
+	assert.Equal(t, logger.TEXT, agent.logger.Format())
+	assert.Equal(t, fmt.Sprintf("xhub.example.com:%d", agent.config.GRPCPort), agent.reportClient.ServerAddr(),
+		"unchanged target keeps the client as is")
+	assert.Equal(t, 5*time.Second, agent.triggers.minInterval)
+}
+
+func TestAgentService_ReloadLogFormat(t *testing.T) {
+	agent := newReloadTestAgent(t)
+
+	require.True(t, agent.Reload(loadReloadTestConfig(t, "log_level: info", "log_level: info\nlog_format: json")))
+	assert.Equal(t, logger.JSON, agent.logger.Format())
+	assert.False(t, agent.Reload(loadReloadTestConfig(t, "log_level: info", "log_level: info\nlog_format: xml")))
+
+	// A bad format leaves the level as it was
+	assert.False(t, agent.Reload(loadReloadTestConfig(t, "log_level: info", "log_level: debug\nlog_format: xml")))
+	assert.Equal(t, logger.INFO, agent.logger.Level())
+	assert.Equal(t, logger.JSON, agent.logger.Format())
+}
+
+func TestAgentService_ReloadReportIntervals(t *testing.T) {
+	agent := newReloadTestAgent(t)
+
+	require.True(t, agent.Reload(loadReloadTestConfig(t,
+		"poll_interval: 5", "poll_interval: 5\nstatus_interval: 3\nsubscription_interval: 60\nonline_users_interval: 9")))
+	assert.Equal(t, 3*time.Second, agent.triggers.minInterval, "status_interval paces the cycles")
+	assert.Equal(t, 3*time.Second, agent.schedule.status)
+	assert.Equal(t, time.Minute, agent.schedule.subscriptions)
+	assert.Equal(t, 9*time.Second, agent.schedule.onlineUsers)
+}
+
+func TestAgentService_ReloadNeedsRestart(t *testing.T) {
+	agent := newReloadTestAgent(t)
+	current := agent.Config()
+
+	// resolvedDomain is not applied live: nothing changes, not even the live fields
+	next := loadReloadTestConfig(t,
+		"log_level: info", "log_level: debug\nresolvedDomain: node.example.com")
+	assert.False(t, agent.Reload(next))
+	assert.Same(t, current, agent.Config())
+	assert.Equal(t, logger.INFO, agent.logger.Level())
+}
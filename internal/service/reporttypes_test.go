@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/config"
+	"xhub-agent/internal/hysteria2"
+	"xhub-agent/internal/nodeprovider"
+)
+
+func TestAgentService_ReportTypesOff(t *testing.T) {
+	xhub := &combinedXHub{calls: map[string]int{}}
+	agent := newPanelAgent(t, xhub, true)
+	off := false
+	agent.config.ReportStatus = &off
+	agent.config.ReportOnlineUsers = &off
+
+	// Disabled types are neither collected nor sent
+	result := agent.RunOnce(true)
+	assert.Empty(t, result.Failures)
+	assert.Nil(t, result.Status)
+	assert.Nil(t, result.OnlineUsers)
+	require.Len(t, result.Subscriptions, 1)
+	assert.Equal(t, map[string]int{"SendSubscriptionReport": 1}, xhub.counts())
+
+	agent.schedule = newReportSchedule(0, 0, 0)
+	due := agent.nextReports()
+	assert.True(t, due.subscriptions)
+	assert.False(t, due.onlineUsers, "due by its interval but turned off")
+}
+
+func TestReportedNodeProviders(t *testing.T) {
+	providers := []nodeprovider.Provider{
+		&fakeNodeProvider{name: hysteria2.ProtocolName, enabled: true},
+		&fakeNodeProvider{name: "tuic", enabled: true},
+	}
+	assert.Len(t, reportedNodeProviders(&config.Config{}, providers), 2)
+
+	off := false
+	reported := reportedNodeProviders(&config.Config{ReportHysteria2: &off}, providers)
+	require.Len(t, reported, 1)
+	assert.Equal(t, "tuic", reported[0].Name())
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// reportsDue are the report types collected in addition to the status in one cycle
+type reportsDue struct {
+	subscriptions bool
+	onlineUsers   bool
+}
+
+// reportSchedule decides in which cycles the subscriptions and online users are collected.
+// Cycles run every status_interval and always report the status; subscriptions and online
+// users are due once their own interval elapsed since they were last collected, and in
+// every cycle requested through TriggerReport.
+type reportSchedule struct {
+	status        time.Duration // Cycle interval
+	subscriptions time.Duration // subscription_interval
+	onlineUsers   time.Duration // online_users_interval
+	clock         sendClock
+
+	lastSubscriptions time.Time
+	lastOnlineUsers   time.Time
+	forced            bool // The next cycle collects everything
+	mutex             sync.Mutex
+}
+
+// newReportSchedule creates a schedule for which every report type is due in the first cycle
+func newReportSchedule(status, subscriptions, onlineUsers time.Duration) *reportSchedule {
+	return &reportSchedule{status: status, subscriptions: subscriptions, onlineUsers: onlineUsers, clock: realClock{}}
+}
+
+// SetIntervals replaces the intervals (live config reload)
+func (s *reportSchedule) SetIntervals(status, subscriptions, onlineUsers time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status, s.subscriptions, s.onlineUsers = status, subscriptions, onlineUsers
+}
+
+// Force makes every report type due in the next cycle
+func (s *reportSchedule) Force() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.forced = true
+}
+
+// Forced reports whether the next cycle was requested through TriggerReport
+func (s *reportSchedule) Forced() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.forced
+}
+
+// Next returns the report types due in the cycle running now and records them as collected
+func (s *reportSchedule) Next() reportsDue {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.clock.Now()
+	due := reportsDue{
+		subscriptions: s.forced || s.elapsed(s.lastSubscriptions, s.subscriptions, now),
+		onlineUsers:   s.forced || s.elapsed(s.lastOnlineUsers, s.onlineUsers, now),
+	}
+	s.forced = false
+	if due.subscriptions {
+		s.lastSubscriptions = now
+	}
+	if due.onlineUsers {
+		s.lastOnlineUsers = now
+	}
+	return due
+}
+
+// elapsed reports whether interval has passed since last. Cycles start on ticks that jitter,
+// so half a cycle of slack keeps an interval from slipping to the following cycle.
+func (s *reportSchedule) elapsed(last time.Time, interval time.Duration, now time.Time) bool {
+	return last.IsZero() || now.Sub(last) >= interval-s.status/2
+}
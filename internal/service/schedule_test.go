@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSchedule(status, subscriptions, onlineUsers time.Duration) (*reportSchedule, *fakeClock) {
+	clock := newFakeClock()
+	schedule := newReportSchedule(status, subscriptions, onlineUsers)
+	schedule.clock = clock
+	return schedule, clock
+}
+
+func TestReportSchedule_DefaultEveryCycle(t *testing.T) {
+	schedule, clock := newTestSchedule(2*time.Second, 2*time.Second, 2*time.Second)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, reportsDue{subscriptions: true, onlineUsers: true}, schedule.Next())
+		clock.Advance(2*time.Second - 10*time.Millisecond) // A tick slightly early
+	}
+}
+
+func TestReportSchedule_OwnIntervals(t *testing.T) {
+	schedule, clock := newTestSchedule(2*time.Second, time.Minute, 10*time.Second)
+
+	assert.Equal(t, reportsDue{subscriptions: true, onlineUsers: true}, schedule.Next(), "everything is due in the first cycle")
+
+	var subscriptions, onlineUsers int
+	for i := 0; i < 30; i++ {
+		clock.Advance(2 * time.Second)
+		due := schedule.Next()
+		if due.subscriptions {
+			subscriptions++
+		}
+		if due.onlineUsers {
+			onlineUsers++
+		}
+	}
+	assert.Equal(t, 1, subscriptions, "once per minute")
+	assert.Equal(t, 6, onlineUsers, "every 10 seconds")
+}
+
+func TestReportSchedule_Force(t *testing.T) {
+	schedule, clock := newTestSchedule(2*time.Second, time.Minute, time.Minute)
+	schedule.Next()
+
+	clock.Advance(time.Second)
+	schedule.Force()
+	assert.Equal(t, reportsDue{subscriptions: true, onlineUsers: true}, schedule.Next())
+	clock.Advance(time.Second)
+	assert.Equal(t, reportsDue{}, schedule.Next(), "forcing covers a single cycle")
+}
+
+func TestReportSchedule_SetIntervals(t *testing.T) {
+	schedule, clock := newTestSchedule(2*time.Second, time.Minute, time.Minute)
+	schedule.Next()
+
+	schedule.SetIntervals(2*time.Second, 2*time.Second, time.Minute)
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, reportsDue{subscriptions: true}, schedule.Next())
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"xhub-agent/internal/crash"
+)
+
+// deferredSendsPerCycle is the number of report sends spaced after the status report
+// (subscriptions, online users)
+const deferredSendsPerCycle = 2
+
+// sendClock is the time source of the send spacer (fake in tests)
+type sendClock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// sendSpacing returns the effective spacing between deferred sends. configured < 0 disables
+// spacing, 0 selects interval/4; the result is clamped so that all sends of a cycle fall
+// inside the poll interval.
+func sendSpacing(configured, interval time.Duration, sends int) time.Duration {
+	if configured < 0 || interval <= 0 {
+		return 0
+	}
+	spacing := configured
+	if spacing == 0 {
+		spacing = interval / 4
+	}
+	if limit := interval / time.Duration(sends+1); spacing > limit {
+		spacing = limit
+	}
+	return spacing
+}
+
+// sendSpacer spreads the deferred sends of a cycle across the poll interval so that report
+// types do not hit xhub back-to-back. Sends of one batch run in order on one goroutine;
+// a batch still pending when the next one is scheduled (or on Flush) runs immediately.
+type sendSpacer struct {
+	spacing time.Duration
+	clock   sendClock
+
+	crashes *crash.Recorder // Panic recovery of the send goroutine, nil in tests
+
+	flush chan struct{} // Closed to run the pending batch without further waiting
+	done  chan struct{} // Closed when the pending batch has completed
+	mutex sync.Mutex
+}
+
+// newSendSpacer creates a spacer waiting spacing before each deferred send (0 sends inline)
+func newSendSpacer(spacing time.Duration) *sendSpacer {
+	return &sendSpacer{spacing: spacing, clock: realClock{}}
+}
+
+// Schedule flushes the previous batch, then runs sends spaced apart in the background
+func (s *sendSpacer) Schedule(sends ...func()) {
+	s.Flush()
+	if s.spacing <= 0 {
+		for _, send := range sends {
+			send()
+		}
+		return
+	}
+
+	flush, done := make(chan struct{}), make(chan struct{})
+	s.mutex.Lock()
+	s.flush, s.done = flush, done
+	s.mutex.Unlock()
+
+	go func() {
+		defer close(done)
+		defer s.crashes.Recover("report sender")
+		for _, send := range sends {
+			select {
+			case <-s.clock.After(s.spacing):
+			case <-flush:
+			}
+			send()
+		}
+	}()
+}
+
+// Flush runs the sends still pending without waiting and returns once they completed
+func (s *sendSpacer) Flush() {
+	s.mutex.Lock()
+	flush, done := s.flush, s.done
+	s.flush, s.done = nil, nil
+	s.mutex.Unlock()
+
+	if done == nil {
+		return
+	}
+	close(flush)
+	<-done
+}
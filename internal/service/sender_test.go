@@ -0,0 +1,204 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced sendClock
+type fakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward and fires the waiters that became due
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	var remaining []fakeWaiter
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			remaining = append(remaining, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = remaining
+}
+
+func (c *fakeClock) waiting() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.waiters)
+}
+
+// sendRecorder records the name and clock instant of every send
+type sendRecorder struct {
+	clock *fakeClock
+	mutex sync.Mutex
+	names []string
+	at    []time.Time
+}
+
+func (r *sendRecorder) send(name string) func() {
+	return func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.names = append(r.names, name)
+		r.at = append(r.at, r.clock.Now())
+	}
+}
+
+func (r *sendRecorder) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.names)
+}
+
+func newTestSpacer(clock *fakeClock, spacing time.Duration) *sendSpacer {
+	s := newSendSpacer(spacing)
+	s.clock = clock
+	return s
+}
+
+func TestSendSpacing(t *testing.T) {
+	interval := 2 * time.Second
+
+	assert.Equal(t, 500*time.Millisecond, sendSpacing(0, interval, 2), "default is interval/4")
+	assert.Equal(t, 200*time.Millisecond, sendSpacing(200*time.Millisecond, interval, 2))
+	assert.Equal(t, interval/3, sendSpacing(5*time.Second, interval, 2), "clamped into the interval")
+	assert.Zero(t, sendSpacing(-1, interval, 2), "negative disables spacing")
+	assert.Zero(t, sendSpacing(0, 0, 2))
+}
+
+func TestSendSpacer_SpacesSendsAcrossCycles(t *testing.T) {
+	clock := newFakeClock()
+	interval := 2 * time.Second
+	spacing := sendSpacing(0, interval, 2)
+	spacer := newTestSpacer(clock, spacing)
+	rec := &sendRecorder{clock: clock}
+
+	for cycle := 0; cycle < 3; cycle++ {
+		cycleStart := clock.Now()
+		rec.send("status")()
+		spacer.Schedule(rec.send("subscription"), rec.send("online"))
+
+		for step := 1; step <= 2; step++ {
+			require.Eventually(t, func() bool { return clock.waiting() == 1 }, time.Second, time.Millisecond)
+			clock.Advance(spacing)
+			require.Eventually(t, func() bool { return rec.count() == cycle*3+1+step }, time.Second, time.Millisecond)
+		}
+		clock.Advance(interval - 2*spacing)
+
+		base := cycle * 3
+		assert.Equal(t, []string{"status", "subscription", "online"}, rec.names[base:base+3])
+		assert.Equal(t, cycleStart, rec.at[base], "status is sent immediately")
+		assert.Equal(t, cycleStart.Add(spacing), rec.at[base+1])
+		assert.Equal(t, cycleStart.Add(2*spacing), rec.at[base+2])
+		assert.True(t, rec.at[base+2].Before(cycleStart.Add(interval)))
+	}
+}
+
+func TestSendSpacer_FlushBeforeNextCycle(t *testing.T) {
+	clock := newFakeClock()
+	// Spacing longer than the time until the next cycle
+	spacer := newTestSpacer(clock, time.Hour)
+	rec := &sendRecorder{clock: clock}
+
+	cycleStart := clock.Now()
+	spacer.Schedule(rec.send("subscription-1"), rec.send("online-1"))
+	require.Eventually(t, func() bool { return clock.waiting() == 1 }, time.Second, time.Millisecond)
+	assert.Zero(t, rec.count())
+
+	// The next cycle begins: the pending sends run right away, in order, before its own
+	clock.Advance(time.Second)
+	spacer.Flush()
+	require.Equal(t, 2, rec.count())
+	rec.send("status-2")()
+	spacer.Schedule(rec.send("subscription-2"), rec.send("online-2"))
+	spacer.Flush()
+
+	assert.Equal(t, []string{"subscription-1", "online-1", "status-2", "subscription-2", "online-2"}, rec.names)
+	for _, at := range rec.at {
+		assert.Equal(t, cycleStart.Add(time.Second), at, "no send is delayed past the next cycle start")
+	}
+
+	// Scheduling without an explicit flush also runs the previous batch first
+	spacer.Schedule(rec.send("subscription-3"))
+	spacer.Schedule(rec.send("subscription-4"))
+	spacer.Flush()
+	assert.Equal(t, []string{"subscription-3", "subscription-4"}, rec.names[5:])
+}
+
+func TestSendSpacer_Disabled(t *testing.T) {
+	clock := newFakeClock()
+	spacer := newTestSpacer(clock, 0)
+	rec := &sendRecorder{clock: clock}
+
+	spacer.Schedule(rec.send("subscription"), rec.send("online"))
+	assert.Equal(t, []string{"subscription", "online"}, rec.names, "sends run inline")
+	assert.Zero(t, clock.waiting())
+}
+
+func TestSendSpacer_LargePayloadDoesNotDelayStatus(t *testing.T) {
+	spacer := newSendSpacer(time.Millisecond)
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	var order []string
+	var mutex sync.Mutex
+	record := func(name string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		order = append(order, name)
+	}
+
+	// A large subscription payload keeps its send busy; the status send is not behind it
+	payload := make([]byte, 4<<20)
+	record("status")
+	start := time.Now()
+	spacer.Schedule(func() {
+		close(inFlight)
+		<-release
+		record(fmt.Sprintf("subscription (%d bytes)", len(payload)))
+	})
+	assert.Less(t, time.Since(start), 100*time.Millisecond, "scheduling must not wait for the deferred send")
+
+	<-inFlight
+	mutex.Lock()
+	assert.Equal(t, []string{"status"}, order, "status was sent before the slow send started")
+	mutex.Unlock()
+
+	close(release)
+	spacer.Flush()
+	assert.Len(t, order, 2)
+}
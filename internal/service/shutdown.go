@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// SetShutdownReason sets the reason reported to xhub when the agent stops (e.g. the signal)
+func (a *AgentService) SetShutdownReason(reason string) {
+	a.runningMux.Lock()
+	defer a.runningMux.Unlock()
+	a.shutdownReason = reason
+}
+
+// drain delivers the unfinished status batch and the queued reports, and tells xhub the agent
+// is going offline, all within drain_timeout. It runs once the work loop and the command
+// channel have stopped, so the report client is no longer shared.
+func (a *AgentService) drain() {
+	timeout := a.config.DrainPeriod()
+	if timeout <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if pending := len(a.batch.samples); pending > 0 {
+		a.logger.Infof("📦 Sending the %d status samples of the unfinished batch before shutdown...", pending)
+		if err := a.sendBatch(ctx); err != nil {
+			a.logger.Warnf("⚠️  Unfinished status batch not delivered: %v", err)
+		}
+	}
+
+	if queued := a.reportClient.QueuedReports(); queued > 0 {
+		a.logger.Infof("📤 Delivering %d queued reports before shutdown...", queued)
+		if err := a.reportClient.FlushQueue(ctx); err != nil {
+			a.logger.Warnf("⚠️  %d queued reports left undelivered: %v", a.reportClient.QueuedReports(), err)
+		}
+	}
+
+	a.runningMux.RLock()
+	reason := a.shutdownReason
+	a.runningMux.RUnlock()
+	if reason == "" {
+		reason = "stopped"
+	}
+	if err := a.reportClient.SendShutdownNotice(ctx, a.config.UUID, reason, time.Since(a.startTime)); err != nil {
+		a.logger.Debugf("📴 Shutdown notice not delivered: %v", err)
+		return
+	}
+	a.logger.Infof("📴 Told xhub the agent is going offline (%s)", reason)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/config"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// shutdownXHub records shutdown notices
+type shutdownXHub struct {
+	pb.UnimplementedReportServiceServer
+	mutex   sync.Mutex
+	notices []*pb.ShutdownNotice
+}
+
+func (s *shutdownXHub) SendShutdownNotice(ctx context.Context, req *pb.ShutdownNotice) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.notices = append(s.notices, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func newShutdownAgent(t *testing.T, xhub *shutdownXHub, drainTimeout *int) *AgentService {
+	reportClient, log := startTestXHub(t, xhub)
+	return &AgentService{
+		config:       &config.Config{UUID: "test-uuid", ShutdownTimeout: 10, DrainTimeout: drainTimeout},
+		logger:       log,
+		reportClient: reportClient,
+		startTime:    time.Now().Add(-time.Minute),
+	}
+}
+
+func TestAgentService_DrainSendsShutdownNotice(t *testing.T) {
+	xhub := &shutdownXHub{}
+	agent := newShutdownAgent(t, xhub, nil)
+
+	agent.SetShutdownReason("signal terminated")
+	agent.drain()
+
+	require.Len(t, xhub.notices, 1)
+	assert.Equal(t, "test-uuid", xhub.notices[0].Uuid)
+	assert.Equal(t, "signal terminated", xhub.notices[0].Reason)
+	assert.Equal(t, int64(60), xhub.notices[0].UptimeSeconds)
+}
+
+func TestAgentService_DrainDisabled(t *testing.T) {
+	xhub := &shutdownXHub{}
+	disabled := 0
+	agent := newShutdownAgent(t, xhub, &disabled)
+
+	agent.drain()
+	assert.Empty(t, xhub.notices)
+}
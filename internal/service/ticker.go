@@ -0,0 +1,119 @@
+package service
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// cycleTicker delivers the scheduled ticks of the work loop every status interval. With a
+// jitter (poll_jitter) each tick moves by up to that fraction of the interval, and the ticks
+// start after a phase offset derived from the agent UUID: a fleet of agents with the same
+// interval spreads its reports across the interval instead of reaching xhub together. Ticks
+// stay on the grid anchored at the phase, so the jitter does not accumulate. Like a
+// time.Ticker, ticks the work loop is too busy to receive are dropped (and passed to dropped).
+type cycleTicker struct {
+	C <-chan time.Time
+
+	ticks   chan time.Time
+	jitter  float64          // Fraction of the interval, 0 ticks like a time.Ticker
+	phase   float64          // Fraction of the interval from the UUID, [0, 1)
+	random  func() float64   // [0, 1), injectable for tests
+	now     func() time.Time // injectable for tests
+	dropped func()           // Called for every dropped tick (nil to ignore them)
+
+	mutex    sync.Mutex
+	interval time.Duration
+	anchor   time.Time // Tick n is due at anchor + n*interval, moved by the jitter
+	n        int64
+	timer    *time.Timer
+	stopped  bool
+}
+
+// newCycleTicker starts a ticker of interval with jitter (a fraction of the interval, 0
+// disables the jitter and the phase offset) and the phase offset of uuid. dropped, if not
+// nil, is called for every tick dropped because the previous one was not received yet.
+func newCycleTicker(interval time.Duration, jitter float64, uuid string, dropped func()) *cycleTicker {
+	t := &cycleTicker{
+		ticks:   make(chan time.Time, 1),
+		jitter:  jitter,
+		random:  rand.Float64,
+		now:     time.Now,
+		dropped: dropped,
+	}
+	t.C = t.ticks
+	if jitter > 0 {
+		t.phase = uuidPhase(uuid)
+	}
+	t.Reset(interval)
+	return t
+}
+
+// uuidPhase maps uuid to a stable fraction of the interval in [0, 1)
+func uuidPhase(uuid string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(uuid))
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
+
+// Reset restarts the ticks with a new interval, the first one an interval (plus the phase
+// offset) from now
+func (t *cycleTicker) Reset(interval time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.interval = interval
+	t.anchor = t.now().Add(time.Duration(t.phase * float64(interval)))
+	t.n = 1
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.stopped = false
+	t.timer = time.AfterFunc(t.delay(), t.fire)
+}
+
+// Stop stops the ticks
+func (t *cycleTicker) Stop() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// fire delivers a tick and schedules the next one, skipping the ticks already past
+func (t *cycleTicker) fire() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stopped {
+		return
+	}
+	now := t.now()
+	select {
+	case t.ticks <- now:
+	default: // The work loop is still busy with the previous tick
+		if t.dropped != nil {
+			t.dropped()
+		}
+	}
+	t.n++
+	for t.due(t.n).Before(now) {
+		t.n++
+	}
+	t.timer = time.AfterFunc(t.delay(), t.fire)
+}
+
+// due returns when tick n is due, before the jitter
+func (t *cycleTicker) due(n int64) time.Time {
+	return t.anchor.Add(time.Duration(n) * t.interval)
+}
+
+// delay returns the time until the next tick, jitter included
+func (t *cycleTicker) delay() time.Duration {
+	at := t.due(t.n)
+	if t.jitter > 0 {
+		at = at.Add(time.Duration((t.random()*2 - 1) * t.jitter * float64(t.interval)))
+	}
+	return max(at.Sub(t.now()), 0)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDPhase(t *testing.T) {
+	phase := uuidPhase("test-uuid-123")
+	assert.Equal(t, phase, uuidPhase("test-uuid-123"), "stable per UUID")
+	assert.GreaterOrEqual(t, phase, 0.0)
+	assert.Less(t, phase, 1.0)
+	assert.NotEqual(t, phase, uuidPhase("test-uuid-124"))
+}
+
+func TestCycleTicker_Delay(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	ticker := &cycleTicker{ticks: make(chan time.Time, 1), jitter: 0.2, phase: 0.25, now: func() time.Time { return now }}
+	ticker.random = func() float64 { return 1 } // Latest
+	ticker.Reset(10 * time.Second)
+	defer ticker.Stop()
+
+	// Phase 2.5s, one interval, +20%
+	assert.Equal(t, now.Add(2500*time.Millisecond), ticker.anchor)
+	assert.Equal(t, 14500*time.Millisecond, ticker.delay())
+
+	// Ticks stay on the grid whatever the jitter of the previous ones
+	ticker.random = func() float64 { return 0 } // Earliest
+	now = now.Add(14500 * time.Millisecond)
+	ticker.mutex.Lock()
+	ticker.n++
+	assert.Equal(t, 6*time.Second, ticker.delay(), "second tick due at 22.5s, 2s early, now 14.5s")
+	ticker.mutex.Unlock()
+}
+
+func TestCycleTicker_Ticks(t *testing.T) {
+	ticker := newCycleTicker(20*time.Millisecond, 0.5, "test-uuid-123", nil)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for range 3 {
+		select {
+		case <-ticker.C:
+		case <-time.After(time.Second):
+			require.Fail(t, "no tick")
+		}
+	}
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// Without jitter it ticks every interval from now
+	plain := newCycleTicker(time.Hour, 0, "test-uuid-123", nil)
+	defer plain.Stop()
+	assert.Zero(t, plain.phase)
+	assert.InDelta(t, float64(time.Hour), float64(plain.delay()), float64(time.Second))
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// TriggerSource identifies what requested a report cycle
+type TriggerSource string
+
+const (
+	TriggerScheduled TriggerSource = "scheduled" // Poll interval ticker
+	TriggerStartup   TriggerSource = "startup"   // First cycle after Start
+	TriggerControl   TriggerSource = "control"   // Local control requests (report-now)
+	TriggerServer    TriggerSource = "server"    // Commands pushed by xhub
+	TriggerSignal    TriggerSource = "signal"    // OS signals
+)
+
+// ErrTriggerRateLimited is returned when a source triggers faster than its rate limit allows
+var ErrTriggerRateLimited = errors.New("report trigger rate limited")
+
+// ErrTriggerStopped is delivered to requesters still pending when the coordinator stops
+var ErrTriggerStopped = errors.New("report trigger coordinator stopped")
+
+// triggerRequest a pending trigger waiting for the cycle that serves it
+type triggerRequest struct {
+	source TriggerSource
+	done   chan error // Buffered, receives the result of the serving cycle (nil for ticks)
+}
+
+// triggerCoordinator funnels scheduled ticks and forced triggers into a single cycle runner.
+// Cycles never overlap; every trigger pending when a cycle starts is served by that cycle
+// (coalescing), so each request causes at most one cycle. Ticks that come due while a cycle
+// runs are skipped and counted rather than queued behind it.
+type triggerCoordinator struct {
+	run         func() error  // One report cycle
+	minInterval time.Duration // Minimum interval between accepted triggers of one forced source
+	now         func() time.Time
+
+	pending  []triggerRequest
+	lastSeen map[TriggerSource]time.Time
+	wake     chan struct{} // Signaled (capacity 1) when pending becomes non-empty
+	stopped  bool
+	mutex    sync.Mutex
+
+	cycles  uint64 // Number of cycles run (for tests and debugging)
+	skipped uint64 // Number of ticks skipped because a cycle was running
+}
+
+// newTriggerCoordinator creates a coordinator running cycles with run
+func newTriggerCoordinator(run func() error, minInterval time.Duration) *triggerCoordinator {
+	return &triggerCoordinator{
+		run:         run,
+		minInterval: minInterval,
+		now:         time.Now,
+		lastSeen:    make(map[TriggerSource]time.Time),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Trigger requests a cycle from source. The returned channel receives the result of the
+// cycle that serves the request, or ErrTriggerStopped if the coordinator stops first.
+func (c *triggerCoordinator) Trigger(source TriggerSource) (<-chan error, error) {
+	c.mutex.Lock()
+	if c.stopped {
+		c.mutex.Unlock()
+		return nil, ErrTriggerStopped
+	}
+	if source != TriggerScheduled && c.minInterval > 0 {
+		now := c.now()
+		if last, ok := c.lastSeen[source]; ok && now.Sub(last) < c.minInterval {
+			c.mutex.Unlock()
+			return nil, ErrTriggerRateLimited
+		}
+		c.lastSeen[source] = now
+	}
+
+	req := triggerRequest{source: source, done: make(chan error, 1)}
+	c.pending = append(c.pending, req)
+	c.mutex.Unlock()
+
+	select {
+	case c.wake <- struct{}{}:
+	default: // A wake-up is already pending
+	}
+	return req.done, nil
+}
+
+// loop runs cycles for ticks and triggers until ctx is done
+func (c *triggerCoordinator) loop(ctx context.Context, ticks <-chan time.Time) {
+	for {
+		scheduled := false
+		select {
+		case <-ctx.Done():
+			c.mutex.Lock()
+			c.stopped = true
+			c.mutex.Unlock()
+			c.finish(c.takePending(), ErrTriggerStopped)
+			return
+		case <-ticks:
+			scheduled = true
+		case <-c.wake:
+		}
+
+		// Merge a tick and triggers that are both due into one cycle
+		select {
+		case <-ticks:
+			scheduled = true
+		default:
+		}
+		select {
+		case <-c.wake:
+		default:
+		}
+
+		batch := c.takePending()
+		if len(batch) == 0 && !scheduled {
+			continue
+		}
+
+		c.mutex.Lock()
+		c.cycles++
+		c.mutex.Unlock()
+
+		err := c.run()
+
+		// A tick that came due while the cycle ran past the interval is merged into it
+		// rather than starting the next cycle right away (slow cycles do not pile up)
+		select {
+		case <-ticks:
+			c.skipTick()
+		default:
+		}
+		c.finish(batch, err)
+	}
+}
+
+// setMinInterval changes the minimum interval between triggers of one forced source
+func (c *triggerCoordinator) setMinInterval(minInterval time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.minInterval = minInterval
+}
+
+// takePending removes and returns all pending requests
+func (c *triggerCoordinator) takePending() []triggerRequest {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	batch := c.pending
+	c.pending = nil
+	return batch
+}
+
+// finish delivers the cycle result to every request of the batch
+func (c *triggerCoordinator) finish(batch []triggerRequest, err error) {
+	for _, req := range batch {
+		req.done <- err
+	}
+}
+
+// skipTick counts a tick skipped because a cycle was running
+func (c *triggerCoordinator) skipTick() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.skipped++
+}
+
+// skippedTicks returns the number of ticks skipped so far
+func (c *triggerCoordinator) skippedTicks() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.skipped
+}
+
+// cycleCount returns the number of cycles run so far
+func (c *triggerCoordinator) cycleCount() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.cycles
+}
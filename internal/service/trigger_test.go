@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingCycle is a cycle runner that blocks until released and detects overlapping runs
+type blockingCycle struct {
+	started chan struct{}
+	release chan struct{}
+	active  int32
+	overlap int32
+	runs    int32
+	err     error
+}
+
+func newBlockingCycle() *blockingCycle {
+	return &blockingCycle{started: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (b *blockingCycle) run() error {
+	if atomic.AddInt32(&b.active, 1) > 1 {
+		atomic.StoreInt32(&b.overlap, 1)
+	}
+	atomic.AddInt32(&b.runs, 1)
+	b.started <- struct{}{}
+	<-b.release
+	atomic.AddInt32(&b.active, -1)
+	return b.err
+}
+
+func waitResult(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for trigger completion")
+		return nil
+	}
+}
+
+func waitStarted(t *testing.T, b *blockingCycle) {
+	t.Helper()
+	select {
+	case <-b.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for cycle start")
+	}
+}
+
+func TestTriggerCoordinator_CoalescesSimultaneousTriggers(t *testing.T) {
+	cycle := newBlockingCycle()
+	close(cycle.release)
+	c := newTriggerCoordinator(cycle.run, 0)
+
+	// Triggers queued before the loop runs are served by one cycle
+	var dones []<-chan error
+	for _, source := range []TriggerSource{TriggerControl, TriggerServer, TriggerSignal} {
+		done, err := c.Trigger(source)
+		require.NoError(t, err)
+		dones = append(dones, done)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.loop(ctx, nil)
+
+	for _, done := range dones {
+		assert.NoError(t, waitResult(t, done))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cycle.runs))
+	assert.Equal(t, uint64(1), c.cycleCount())
+}
+
+func TestTriggerCoordinator_CompletionNotifications(t *testing.T) {
+	cycle := newBlockingCycle()
+	cycle.err = errors.New("panel unreachable")
+	c := newTriggerCoordinator(cycle.run, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.loop(ctx, nil)
+
+	first, err := c.Trigger(TriggerControl)
+	require.NoError(t, err)
+	waitStarted(t, cycle)
+
+	// Triggers arriving while a cycle runs wait for the next one
+	second, err := c.Trigger(TriggerServer)
+	require.NoError(t, err)
+	third, err := c.Trigger(TriggerSignal)
+	require.NoError(t, err)
+
+	cycle.release <- struct{}{}
+	assert.EqualError(t, waitResult(t, first), "panel unreachable")
+
+	waitStarted(t, cycle)
+	cycle.err = nil
+	close(cycle.release)
+	assert.NoError(t, waitResult(t, second))
+	assert.NoError(t, waitResult(t, third))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&cycle.runs))
+}
+
+func TestTriggerCoordinator_TickMergesWithPendingTriggers(t *testing.T) {
+	cycle := newBlockingCycle()
+	c := newTriggerCoordinator(cycle.run, 0)
+	ticks := make(chan time.Time, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.loop(ctx, ticks)
+
+	ticks <- time.Now()
+	waitStarted(t, cycle)
+
+	// A tick and a forced trigger both due after the running cycle share one cycle
+	ticks <- time.Now()
+	done, err := c.Trigger(TriggerControl)
+	require.NoError(t, err)
+
+	cycle.release <- struct{}{}
+	waitStarted(t, cycle)
+	cycle.release <- struct{}{}
+	assert.NoError(t, waitResult(t, done))
+
+	// No third cycle follows
+	select {
+	case <-cycle.started:
+		t.Fatal("unexpected extra cycle")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&cycle.runs))
+}
+
+func TestTriggerCoordinator_SlowCycleSkipsMissedTick(t *testing.T) {
+	cycle := newBlockingCycle()
+	c := newTriggerCoordinator(cycle.run, 0)
+	ticks := make(chan time.Time, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.loop(ctx, ticks)
+
+	ticks <- time.Now()
+	waitStarted(t, cycle)
+
+	// The cycle runs past the next tick, which is merged into it
+	ticks <- time.Now()
+	cycle.release <- struct{}{}
+
+	select {
+	case <-cycle.started:
+		t.Fatal("missed tick started another cycle")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cycle.runs))
+	assert.Equal(t, uint64(1), c.skippedTicks())
+
+	// The following tick runs as usual
+	ticks <- time.Now()
+	waitStarted(t, cycle)
+	cycle.release <- struct{}{}
+}
+
+func TestTriggerCoordinator_RateLimit(t *testing.T) {
+	c := newTriggerCoordinator(func() error { return nil }, 10*time.Second)
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+
+	_, err := c.Trigger(TriggerServer)
+	require.NoError(t, err)
+
+	_, err = c.Trigger(TriggerServer)
+	assert.ErrorIs(t, err, ErrTriggerRateLimited)
+
+	// Limits are per source, scheduled triggers are never limited
+	_, err = c.Trigger(TriggerControl)
+	assert.NoError(t, err)
+	_, err = c.Trigger(TriggerScheduled)
+	assert.NoError(t, err)
+	_, err = c.Trigger(TriggerScheduled)
+	assert.NoError(t, err)
+
+	now = now.Add(10 * time.Second)
+	_, err = c.Trigger(TriggerServer)
+	assert.NoError(t, err)
+}
+
+func TestTriggerCoordinator_StopFailsPending(t *testing.T) {
+	c := newTriggerCoordinator(func() error { return nil }, 0)
+	done, err := c.Trigger(TriggerControl)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.loop(ctx, nil)
+
+	// The loop may serve or fail the pending trigger, but never leaves it hanging
+	result := waitResult(t, done)
+	if result != nil {
+		assert.ErrorIs(t, result, ErrTriggerStopped)
+	}
+
+	_, err = c.Trigger(TriggerControl)
+	assert.ErrorIs(t, err, ErrTriggerStopped)
+}
+
+func TestTriggerCoordinator_ConcurrentSources(t *testing.T) {
+	cycle := newBlockingCycle()
+	close(cycle.release)
+	c := newTriggerCoordinator(cycle.run, 0)
+	ticks := make(chan time.Time)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	loopDone := make(chan struct{})
+	go func() {
+		c.loop(ctx, ticks)
+		close(loopDone)
+	}()
+	go func() {
+		for i := 0; i < 20; i++ {
+			select {
+			case ticks <- time.Now():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var served int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			done, err := c.Trigger(TriggerSource(fmt.Sprintf("source-%d", i%5)))
+			if !assert.NoError(t, err) {
+				return
+			}
+			if waitResult(t, done) == nil {
+				atomic.AddInt32(&served, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cancel()
+	<-loopDone
+	assert.Equal(t, int32(50), atomic.LoadInt32(&served))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&cycle.overlap), "cycles must never overlap")
+	assert.LessOrEqual(t, c.cycleCount(), uint64(70))
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/report"
+)
+
+// panelDown reports whether a failed login means the panel itself is down, rather than
+// refusing the credentials
+func panelDown(err error) bool {
+	var statusErr *auth.StatusError
+	return errors.Is(err, auth.ErrPanelUnreachable) || (errors.As(err, &statusErr) && statusErr.StatusCode >= http.StatusInternalServerError)
+}
+
+// observePanel feeds the outcome of a status request to the panel watchdog and restarts the
+// 3x-ui service when a restart is due. Rejected sessions say nothing about the panel health.
+// Called by the report cycles, with cycleMutex held.
+func (a *AgentService) observePanel(err error) {
+	if a.panelWatchdog == nil {
+		return
+	}
+	if err == nil {
+		a.panelWatchdog.Success()
+		return
+	}
+	if errors.Is(err, auth.ErrAuthExpired) {
+		return
+	}
+
+	config := a.panelWatchdog.Config()
+	due, exhausted := a.panelWatchdog.Failure()
+	if exhausted {
+		a.logger.Errorf("🐕 3x-ui still failing after %d watchdog restarts, no more restarts until it answers again", config.MaxRestarts)
+	}
+	if !due {
+		return
+	}
+
+	a.logger.Warnf("🐕 3x-ui status failed %d times in a row, restarting %s", config.Failures, config.Service)
+	restart := a.panelWatchdog.Restart(a.ctx, err)
+	if restart.Error != "" {
+		a.logger.Errorf("❌ Panel watchdog restart failed: %s", restart.Error)
+	} else {
+		a.logger.Infof("🐕 Restarted %s (restart %d of %d)", restart.Service, restart.Attempt, config.MaxRestarts)
+	}
+	if reportErr := a.reportClient.SendPanelRestartReport(a.config.UUID, restart, config.MaxRestarts); reportErr != nil && !errors.Is(reportErr, report.ErrPanelRestartUnsupported) {
+		a.logger.Warnf("⚠️  Panel restart report failed: %v", reportErr)
+		a.recordError(reportErr)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+	pb "xhub-agent/proto/reportpb"
+)
+
+// panelRestartXHub records the panel restart reports it receives
+type panelRestartXHub struct {
+	commandXHub
+
+	mutex   sync.Mutex
+	reports []*pb.PanelRestartReport
+}
+
+func (s *panelRestartXHub) SendPanelRestartReport(ctx context.Context, req *pb.PanelRestartReport) (*pb.ReportResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reports = append(s.reports, req)
+	return &pb.ReportResponse{Success: true}, nil
+}
+
+func TestAgentService_PanelWatchdog(t *testing.T) {
+	xhub := &panelRestartXHub{}
+	agent := newCommandTestAgent(t, xhub, "panel_watchdog: true\npanel_watchdog_failures: 2\n")
+	require.NotNil(t, agent.panelWatchdog)
+
+	var calls [][]string
+	agent.panelWatchdog.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return nil, nil
+	})
+
+	// Rejected sessions do not count, an answering panel resets the count
+	agent.observePanel(auth.Unreachable(errors.New("connection refused")))
+	agent.observePanel(auth.ErrAuthExpired)
+	agent.observePanel(nil)
+	agent.observePanel(auth.Unreachable(errors.New("connection refused")))
+	assert.Empty(t, calls)
+
+	agent.observePanel(&auth.StatusError{StatusCode: 502})
+	assert.Equal(t, [][]string{{"systemctl", "restart", "x-ui"}}, calls)
+
+	xhub.mutex.Lock()
+	defer xhub.mutex.Unlock()
+	require.Len(t, xhub.reports, 1)
+	report := xhub.reports[0]
+	assert.Equal(t, "test-uuid-123", report.Uuid)
+	assert.Equal(t, "x-ui", report.Service)
+	assert.Equal(t, int32(2), report.Failures)
+	assert.Equal(t, "request failed, HTTP status code: 502", report.LastError)
+	assert.Equal(t, int32(1), report.Attempt)
+	assert.Equal(t, int32(3), report.MaxRestarts)
+	assert.True(t, report.Success)
+}
+
+func TestPanelDown(t *testing.T) {
+	assert.True(t, panelDown(auth.Unreachable(errors.New("connection refused"))))
+	assert.True(t, panelDown(&auth.StatusError{StatusCode: 503}))
+	assert.False(t, panelDown(&auth.StatusError{StatusCode: 404}))
+	assert.False(t, panelDown(&auth.LoginError{Message: "wrong password"}))
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"xhub-agent/internal/monitor"
+)
+
+// superviseXray restarts Xray once the status reported it not running for
+// xray_supervision_cycles cycles in a row. Called by the report cycles.
+func (a *AgentService) superviseXray(data *monitor.ServerStatusData) {
+	if !a.config.XraySupervision {
+		return
+	}
+	down, due := a.xraySupervisor.Observe(data.Xray.State == "running")
+	if down == 0 {
+		return
+	}
+	if !due {
+		a.logger.Debugf("🔁 Xray not running (%s) for %d cycle(s)", data.Xray.State, down)
+		return
+	}
+	a.logger.Warnf("🔁 Xray not running (%s) for %d cycles, restarting it", data.Xray.State, down)
+	a.restartXray(a.ctx, monitor.XrayRestartSupervision, down)
+}
+
+// restartXray restarts Xray through 3x-ui, falling back to xray_restart_service, and keeps
+// the outcome for the next status report. down is the number of cycles Xray was not running.
+func (a *AgentService) restartXray(ctx context.Context, trigger string, down int) error {
+	restart := monitor.XrayRestart{Trigger: trigger, Method: monitor.XrayRestartPanel, At: time.Now().Unix(), DownCycles: down}
+	err := a.ensureAuthenticated()
+	if err == nil {
+		err = a.monitorClient.RestartXray()
+	}
+	if err != nil && a.xraySupervisor.Service() != "" {
+		a.logger.Warnf("⚠️  Xray restart through 3x-ui failed (%v), restarting %s", err, a.xraySupervisor.Service())
+		restart.Method = monitor.XrayRestartSystemd
+		err = a.xraySupervisor.RestartService(ctx)
+	}
+	restart.Success = err == nil
+	if err != nil {
+		restart.Error = err.Error()
+		a.logger.Errorf("❌ Xray restart (%s) failed: %v", trigger, err)
+	} else {
+		a.logger.Infof("🔁 Xray restarted (%s, via %s)", trigger, restart.Method)
+	}
+
+	a.xrayRestartMutex.Lock()
+	a.pendingXrayRestart = &restart
+	a.xrayRestartMutex.Unlock()
+	return err
+}
+
+// takeXrayRestart returns the outcome of the last Xray restart not reported yet, nil when none
+func (a *AgentService) takeXrayRestart() *monitor.XrayRestart {
+	a.xrayRestartMutex.Lock()
+	defer a.xrayRestartMutex.Unlock()
+	restart := a.pendingXrayRestart
+	a.pendingXrayRestart = nil
+	return restart
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+)
+
+func TestAgentService_SuperviseXray(t *testing.T) {
+	agent := newCommandTestAgent(t, &commandXHub{}, "xray_supervision: true\nxray_supervision_cycles: 2\nxray_restart_service: x-ui\n")
+
+	var calls [][]string
+	agent.xraySupervisor.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return nil, nil
+	})
+
+	stopped := &monitor.ServerStatusData{Xray: monitor.XrayInfo{State: "stop"}}
+	agent.superviseXray(stopped)
+	assert.Nil(t, agent.takeXrayRestart())
+
+	// The test panel is unreachable, so the restart falls back to systemd
+	agent.superviseXray(stopped)
+	assert.Equal(t, [][]string{{"systemctl", "restart", "x-ui"}}, calls)
+
+	restart := agent.takeXrayRestart()
+	require.NotNil(t, restart)
+	assert.Equal(t, monitor.XrayRestartSupervision, restart.Trigger)
+	assert.Equal(t, monitor.XrayRestartSystemd, restart.Method)
+	assert.Equal(t, 2, restart.DownCycles)
+	assert.True(t, restart.Success)
+	assert.Nil(t, agent.takeXrayRestart(), "reported once")
+
+	// A running Xray is left alone
+	agent.superviseXray(&monitor.ServerStatusData{Xray: monitor.XrayInfo{State: "running"}})
+	agent.superviseXray(&monitor.ServerStatusData{Xray: monitor.XrayInfo{State: "running"}})
+	assert.Len(t, calls, 1)
+}
+
+func TestAgentService_SuperviseXrayDisabled(t *testing.T) {
+	agent := newReloadTestAgent(t)
+	stopped := &monitor.ServerStatusData{Xray: monitor.XrayInfo{State: "stop"}}
+	for i := 0; i < 5; i++ {
+		agent.superviseXray(stopped)
+	}
+	assert.Nil(t, agent.takeXrayRestart())
+}
@@ -0,0 +1,234 @@
+package shadowtls
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/nodeprovider"
+	"xhub-agent/internal/sanitize"
+	"xhub-agent/internal/sharelink"
+)
+
+// Defaults of a sing-box installation serving ShadowTLS
+const (
+	DefaultConfigPath = "/etc/sing-box/config.json"
+	DefaultNodeName   = "ShadowTLS"
+)
+
+// maxConfigSize caps the sing-box config read (1 MiB)
+const maxConfigSize = 1 << 20
+
+// User is a named credential of a sing-box inbound
+type User struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// Inbound is the part of a sing-box inbound needed for share links
+type Inbound struct {
+	Type       string `json:"type"` // "shadowtls" or "shadowsocks"
+	Tag        string `json:"tag"`
+	ListenPort int    `json:"listen_port"`
+
+	// shadowtls
+	Version   int    `json:"version"`  // 2 or 3
+	Password  string `json:"password"` // shadowtls v2, or the shadowsocks (server) password
+	Users     []User `json:"users"`    // shadowtls v3, or shadowsocks 2022 multi-user
+	Handshake struct {
+		Server string `json:"server"` // TLS server whose handshake is borrowed
+	} `json:"handshake"`
+	Detour string `json:"detour"` // Tag of the shadowsocks inbound behind shadowtls
+
+	// shadowsocks
+	Method string `json:"method"`
+}
+
+// Config is the part of a sing-box config needed for share links
+type Config struct {
+	Inbounds []Inbound `json:"inbounds"`
+}
+
+// ProtocolName is the protocol of ShadowTLS nodes, as reported in the inbound protocols
+const ProtocolName = "shadowtls"
+
+// Options configure the ShadowTLS node
+type Options struct {
+	Enabled    bool   // shadowtls_enabled
+	ConfigPath string // sing-box config, default DefaultConfigPath
+	NodeName   string // Node display name, default DefaultNodeName
+	ServerAddr string // External server address (domain or IP)
+}
+
+// Client builds the share links of a ShadowTLS inbound of sing-box
+type Client struct {
+	options Options
+}
+
+// NewClient creates a ShadowTLS client
+func NewClient(options Options) *Client {
+	if options.ConfigPath == "" {
+		options.ConfigPath = DefaultConfigPath
+	}
+	if options.NodeName == "" {
+		options.NodeName = DefaultNodeName
+	}
+	return &Client{options: options}
+}
+
+// Name returns the protocol of the ShadowTLS node provider
+func (c *Client) Name() string {
+	return ProtocolName
+}
+
+// Enabled returns whether ShadowTLS support is enabled
+func (c *Client) Enabled() bool {
+	return c.options.Enabled
+}
+
+// CollectStatus adds shadowtls to the inbound protocols: it runs outside 3x-ui, so it never
+// shows up in the inbound list
+func (c *Client) CollectStatus() collector.Apply {
+	return nodeprovider.ProtocolStatus(ProtocolName)
+}
+
+// BuildShareLinks builds the share links of the ShadowTLS node
+func (c *Client) BuildShareLinks() (nodeprovider.ShareLinks, error) {
+	nodes, err := c.Nodes()
+	return nodeprovider.ShareLinks{Nodes: nodes}, err
+}
+
+// ParseConfig reads and parses the sing-box config file
+func (c *Client) ParseConfig() (*Config, error) {
+	f, err := os.Open(c.options.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shadowtls config file %s: %w", c.options.ConfigPath, err)
+	}
+	defer f.Close()
+	data, err := sanitize.ReadLimited(f, maxConfigSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shadowtls config file %s: %w", c.options.ConfigPath, err)
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig decodes a sing-box JSON config
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse shadowtls config: %w", err)
+	}
+	return &config, nil
+}
+
+// inbounds returns the ShadowTLS inbound of config and the shadowsocks inbound behind it
+func (config *Config) inbounds() (shadowTLS, shadowsocks *Inbound, err error) {
+	for i := range config.Inbounds {
+		if config.Inbounds[i].Type == "shadowtls" {
+			shadowTLS = &config.Inbounds[i]
+			break
+		}
+	}
+	if shadowTLS == nil {
+		return nil, nil, fmt.Errorf("no shadowtls inbound in sing-box config")
+	}
+	if shadowTLS.Version != 2 && shadowTLS.Version != 3 {
+		return nil, nil, fmt.Errorf("unsupported shadowtls version %d (supported: 2, 3)", shadowTLS.Version)
+	}
+	for i := range config.Inbounds {
+		if config.Inbounds[i].Type == "shadowsocks" && config.Inbounds[i].Tag == shadowTLS.Detour {
+			shadowsocks = &config.Inbounds[i]
+		}
+	}
+	if shadowsocks == nil {
+		return nil, nil, fmt.Errorf("shadowtls detour %q is not a shadowsocks inbound", sanitize.String(shadowTLS.Detour))
+	}
+	return shadowTLS, shadowsocks, nil
+}
+
+// BuildURI constructs the ss:// share link with the shadow-tls parameter (Shadowrocket
+// format) of a ShadowTLS password and a shadowsocks password
+func (c *Client) BuildURI(shadowTLS, shadowsocks *Inbound, shadowTLSPassword, shadowsocksPassword string) (string, error) {
+	if shadowTLS.ListenPort < 1 || shadowTLS.ListenPort > 65535 {
+		return "", fmt.Errorf("invalid shadowtls listen port %d", shadowTLS.ListenPort)
+	}
+	if c.options.ServerAddr == "" {
+		return "", fmt.Errorf("server address is not configured")
+	}
+
+	plugin, err := json.Marshal(map[string]string{
+		"version":  strconv.Itoa(shadowTLS.Version),
+		"host":     shadowTLS.Handshake.Server,
+		"password": shadowTLSPassword,
+	})
+	if err != nil {
+		return "", err
+	}
+	userinfo := base64.RawURLEncoding.EncodeToString([]byte(shadowsocks.Method + ":" + shadowsocksPassword))
+	params := url.Values{}
+	params.Set("shadow-tls", base64.StdEncoding.EncodeToString(plugin))
+
+	return fmt.Sprintf("ss://%s@%s?%s#%s", userinfo,
+		net.JoinHostPort(strings.Trim(c.options.ServerAddr, "[]"), strconv.Itoa(shadowTLS.ListenPort)),
+		params.Encode(), url.PathEscape(c.options.NodeName)), nil
+}
+
+// Nodes builds the share links of the ShadowTLS node: one shared link for ShadowTLS v2 or a
+// single v3 user, else one link per v3 user name. With a shadowsocks 2022 multi-user inbound
+// each user needs a shadowsocks user of the same name.
+func (c *Client) Nodes() (sharelink.Nodes, error) {
+	config, err := c.ParseConfig()
+	if err != nil {
+		return sharelink.Nodes{}, err
+	}
+	shadowTLS, shadowsocks, err := config.inbounds()
+	if err != nil {
+		return sharelink.Nodes{}, err
+	}
+
+	users := shadowTLS.Users
+	if shadowTLS.Version == 2 {
+		users = []User{{Password: shadowTLS.Password}}
+	}
+	if len(users) == 0 {
+		return sharelink.Nodes{}, fmt.Errorf("shadowtls v3 inbound without users")
+	}
+
+	nodes := sharelink.Nodes{PerUser: make(map[string]string, len(users))}
+	shared := len(users) == 1 && len(shadowsocks.Users) == 0
+	for _, user := range users {
+		password, ok := shadowsocksPassword(shadowsocks, user.Name)
+		if !ok {
+			continue // No shadowsocks credentials, no link
+		}
+		uri, err := c.BuildURI(shadowTLS, shadowsocks, user.Password, password)
+		if err != nil {
+			return sharelink.Nodes{}, err
+		}
+		if shared {
+			return sharelink.Nodes{Shared: uri}, nil
+		}
+		nodes.PerUser[user.Name] = uri
+	}
+	return nodes, nil
+}
+
+// shadowsocksPassword returns the client password of the named user of a shadowsocks inbound:
+// the server password, or with 2022 multi-user the server and user keys joined by ":"
+func shadowsocksPassword(shadowsocks *Inbound, name string) (string, bool) {
+	if len(shadowsocks.Users) == 0 {
+		return shadowsocks.Password, true
+	}
+	for _, user := range shadowsocks.Users {
+		if user.Name == name {
+			return shadowsocks.Password + ":" + user.Password, true
+		}
+	}
+	return "", false
+}
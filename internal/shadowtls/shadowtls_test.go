@@ -0,0 +1,109 @@
+package shadowtls
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigV3 = `{
+  "inbounds": [
+    {"type": "vless", "tag": "vless-in", "listen_port": 8443},
+    {
+      "type": "shadowtls", "tag": "st-in", "listen_port": 443, "version": 3,
+      "users": [{"name": "alice@example.com", "password": "st-alice"}, {"name": "bob@example.com", "password": "st-bob"}],
+      "handshake": {"server": "www.bing.com", "server_port": 443},
+      "detour": "ss-in"
+    },
+    {
+      "type": "shadowsocks", "tag": "ss-in", "listen": "127.0.0.1",
+      "method": "2022-blake3-aes-128-gcm", "password": "server-key",
+      "users": [{"name": "alice@example.com", "password": "alice-key"}]
+    }
+  ]
+}`
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// decodeURI returns the shadowsocks userinfo and the shadow-tls parameter of a link
+func decodeURI(t *testing.T, uri string) (string, map[string]string) {
+	t.Helper()
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "ss", u.Scheme)
+	userinfo, err := base64.RawURLEncoding.DecodeString(u.User.Username())
+	require.NoError(t, err)
+	plugin, err := base64.StdEncoding.DecodeString(u.Query().Get("shadow-tls"))
+	require.NoError(t, err)
+	var params map[string]string
+	require.NoError(t, json.Unmarshal(plugin, &params))
+	return string(userinfo), params
+}
+
+func TestNodes_V3PerUser(t *testing.T) {
+	client := NewClient(Options{ConfigPath: writeFile(t, testConfigV3), ServerAddr: "node.example.com"})
+	nodes, err := client.Nodes()
+	require.NoError(t, err)
+	assert.Empty(t, nodes.Shared)
+	require.Len(t, nodes.PerUser, 1, "bob has no shadowsocks user")
+
+	uri := nodes.URIFor("alice@example.com")
+	assert.True(t, strings.HasPrefix(uri, "ss://"))
+	assert.Contains(t, uri, "@node.example.com:443?")
+	assert.True(t, strings.HasSuffix(uri, "#ShadowTLS"))
+	userinfo, params := decodeURI(t, uri)
+	assert.Equal(t, "2022-blake3-aes-128-gcm:server-key:alice-key", userinfo)
+	assert.Equal(t, map[string]string{"version": "3", "host": "www.bing.com", "password": "st-alice"}, params)
+	assert.Empty(t, nodes.URIFor("bob@example.com"))
+}
+
+func TestNodes_Shared(t *testing.T) {
+	v2 := `{"inbounds":[
+	  {"type":"shadowtls","listen_port":8443,"version":2,"password":"st-pass","handshake":{"server":"example.org"},"detour":"ss"},
+	  {"type":"shadowsocks","tag":"ss","method":"aes-256-gcm","password":"ss-pass"}]}`
+	client := NewClient(Options{ConfigPath: writeFile(t, v2), NodeName: "ST 节点", ServerAddr: "2001:db8::1"})
+	nodes, err := client.Nodes()
+	require.NoError(t, err)
+	assert.Contains(t, nodes.Shared, "@[2001:db8::1]:8443?")
+	assert.True(t, strings.HasSuffix(nodes.Shared, "#ST%20%E8%8A%82%E7%82%B9"))
+	userinfo, params := decodeURI(t, nodes.Shared)
+	assert.Equal(t, "aes-256-gcm:ss-pass", userinfo)
+	assert.Equal(t, map[string]string{"version": "2", "host": "example.org", "password": "st-pass"}, params)
+	assert.Equal(t, nodes.Shared, nodes.URIFor("anyone@example.com"))
+}
+
+func TestNodes_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"no shadowtls", `{"inbounds":[{"type":"shadowsocks","tag":"ss"}]}`, "no shadowtls inbound in sing-box config"},
+		{"version", `{"inbounds":[{"type":"shadowtls","version":1}]}`, "unsupported shadowtls version 1 (supported: 2, 3)"},
+		{"detour", `{"inbounds":[{"type":"shadowtls","version":3,"detour":"vless"},{"type":"vless","tag":"vless"}]}`, `shadowtls detour "vless" is not a shadowsocks inbound`},
+		{"no users", `{"inbounds":[{"type":"shadowtls","version":3,"detour":"ss"},{"type":"shadowsocks","tag":"ss"}]}`, "shadowtls v3 inbound without users"},
+		{"port", `{"inbounds":[{"type":"shadowtls","version":2,"detour":"ss"},{"type":"shadowsocks","tag":"ss"}]}`, "invalid shadowtls listen port 0"},
+		{"json", `{`, "failed to parse shadowtls config: unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(Options{ConfigPath: writeFile(t, tt.config), ServerAddr: "node.example.com"}).Nodes()
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	_, err := NewClient(Options{ConfigPath: writeFile(t, testConfigV3)}).Nodes()
+	assert.EqualError(t, err, "server address is not configured")
+}
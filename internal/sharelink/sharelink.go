@@ -0,0 +1,46 @@
+package sharelink
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Nodes are the share links of a node running outside 3x-ui, shared or per user
+type Nodes struct {
+	Shared  string            // Link of every subscriber, "" with per-user credentials
+	PerUser map[string]string // Link by user name
+}
+
+// URIFor returns the link of the subscriber with email: the shared link, or the link of the
+// user named email (exact match first, then ignoring case). It returns "" when the
+// subscriber has no credentials.
+func (n Nodes) URIFor(email string) string {
+	if n.Shared != "" {
+		return n.Shared
+	}
+	if uri, ok := n.PerUser[email]; ok {
+		return uri
+	}
+	var match string
+	found := false
+	for user := range n.PerUser {
+		if strings.EqualFold(user, email) && (!found || user < match) {
+			match, found = user, true
+		}
+	}
+	if !found {
+		return ""
+	}
+	return n.PerUser[match]
+}
+
+// EscapeAuth escapes a credential for the userinfo of a share link, including the "@" of
+// user names that are emails
+func EscapeAuth(auth string) string {
+	return strings.ReplaceAll(url.PathEscape(auth), "@", "%40")
+}
+
+// EscapeUser escapes the user part of a user:password userinfo
+func EscapeUser(user string) string {
+	return strings.ReplaceAll(EscapeAuth(user), ":", "%3A")
+}
@@ -0,0 +1,29 @@
+package sharelink
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodes_URIFor(t *testing.T) {
+	shared := Nodes{Shared: "tuic://shared"}
+	assert.Equal(t, "tuic://shared", shared.URIFor("alice@example.com"))
+
+	perUser := Nodes{PerUser: map[string]string{
+		"alice@example.com": "tuic://alice",
+		"Bob@Example.com":   "tuic://bob-upper",
+		"bob@example.com.":  "tuic://other",
+		"BOB@example.com":   "tuic://bob-caps",
+	}}
+	assert.Equal(t, "tuic://alice", perUser.URIFor("alice@example.com"))
+	assert.Equal(t, "tuic://bob-caps", perUser.URIFor("bob@example.com"), "smallest case-insensitive match")
+	assert.Equal(t, "", perUser.URIFor("carol@example.com"))
+	assert.Equal(t, "", Nodes{}.URIFor("alice@example.com"))
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, "alice%40example.com", EscapeAuth("alice@example.com"))
+	assert.Equal(t, "a:b%2Fc", EscapeAuth("a:b/c"))
+	assert.Equal(t, "a%3Ab%2Fc", EscapeUser("a:b/c"))
+}
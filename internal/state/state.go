@@ -0,0 +1,116 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State is the small persisted agent state kept across restarts
+type State struct {
+	RestartCount int64     `json:"restart_count"` // Number of agent starts recorded
+	LastStart    time.Time `json:"last_start"`    // Start time of the current process
+
+	EmailPseudonyms map[string]string `json:"email_pseudonyms,omitempty"` // email -> label (email_reporting: pseudonym)
+}
+
+// Store loads and saves State to a JSON file
+type Store struct {
+	path  string
+	state State
+	mutex sync.Mutex
+}
+
+// Open loads the state file at path.
+// A missing file starts fresh silently; a corrupt file starts fresh and returns
+// the parse error alongside the usable store so the caller can log it.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		s.state = State{}
+		return s, fmt.Errorf("corrupt state file %s, starting fresh: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// NewMemoryStore returns a store that is never persisted (used when the data directory is read-only)
+func NewMemoryStore() *Store {
+	return &Store{}
+}
+
+// RecordStart increments the restart counter, stores the start time and saves the file
+func (s *Store) RecordStart(now time.Time) (State, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.state.RestartCount++
+	s.state.LastStart = now
+
+	return s.state, s.saveLocked()
+}
+
+// Pseudonym returns the stable label of email, assigning and saving the next free one
+// (user-0001, user-0002, ...) on first use. The label is valid even if saving fails.
+func (s *Store) Pseudonym(email string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if label, ok := s.state.EmailPseudonyms[email]; ok {
+		return label, nil
+	}
+	if s.state.EmailPseudonyms == nil {
+		s.state.EmailPseudonyms = make(map[string]string)
+	}
+	label := fmt.Sprintf("user-%04d", len(s.state.EmailPseudonyms)+1)
+	s.state.EmailPseudonyms[email] = label
+	return label, s.saveLocked()
+}
+
+// Get returns a copy of the current state
+func (s *Store) Get() State {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.state
+}
+
+// saveLocked writes the state atomically (temp file + rename)
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_RestartCounterAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	for i := int64(1); i <= 3; i++ {
+		// Each iteration simulates a fresh process reading the same state file
+		store, err := Open(path)
+		require.NoError(t, err)
+
+		now := time.Unix(1700000000+i, 0)
+		st, err := store.RecordStart(now)
+		require.NoError(t, err)
+		assert.Equal(t, i, st.RestartCount)
+		assert.True(t, now.Equal(st.LastStart))
+	}
+}
+
+func TestStore_CorruptFileStartsFresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0644))
+
+	store, err := Open(path)
+	assert.Error(t, err, "corrupt file should be reported")
+	require.NotNil(t, store)
+
+	st, err := store.RecordStart(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), st.RestartCount)
+
+	// The rewritten file is valid again
+	store, err = Open(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), store.Get().RestartCount)
+}
+
+func TestStore_MemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	st, err := store.RecordStart(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), st.RestartCount)
+}
+
+func TestStore_PseudonymsSurviveRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := Open(path)
+	require.NoError(t, err)
+	first, err := store.Pseudonym("alice@example.com")
+	require.NoError(t, err)
+	second, err := store.Pseudonym("bob@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "user-0001", first)
+	assert.Equal(t, "user-0002", second)
+
+	again, err := store.Pseudonym("alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	// A fresh process reads the same labels and continues the sequence
+	store, err = Open(path)
+	require.NoError(t, err)
+	_, err = store.RecordStart(time.Now())
+	require.NoError(t, err)
+	label, err := store.Pseudonym("bob@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "user-0002", label)
+	label, err = store.Pseudonym("carol@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "user-0003", label)
+}
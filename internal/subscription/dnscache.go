@@ -0,0 +1,174 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Default TTLs of the subscription DNS cache
+const (
+	DefaultDNSTTL         = 60 * time.Second
+	DefaultNegativeDNSTTL = 5 * time.Second
+)
+
+// Resolver looks up host addresses (satisfied by *net.Resolver)
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// dnsEntry cached lookup result
+type dnsEntry struct {
+	addrs   []net.IPAddr
+	err     error // non-nil for negative entries
+	expires time.Time
+}
+
+// dnsStats DNS cache counters
+type dnsStats struct {
+	hits, misses, negative uint64
+}
+
+// dnsCache is a small TTL'd resolver cache used by the subscription fetch dialer
+type dnsCache struct {
+	resolver    Resolver
+	dialer      *net.Dialer
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time // injectable for tests
+
+	entries  map[string]dnsEntry
+	inflight map[string]chan struct{} // Closed when the pending lookup of a host finishes
+	stats    dnsStats
+	mutex    sync.Mutex
+}
+
+// newDNSCache creates a DNS cache using the default resolver
+func newDNSCache() *dnsCache {
+	return &dnsCache{
+		resolver:    net.DefaultResolver,
+		dialer:      &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		ttl:         DefaultDNSTTL,
+		negativeTTL: DefaultNegativeDNSTTL,
+		now:         time.Now,
+		entries:     make(map[string]dnsEntry),
+		inflight:    make(map[string]chan struct{}),
+	}
+}
+
+// lookup resolves host, answering from the cache while the entry is fresh.
+// Literal IPs bypass the cache; cancelled lookups are never cached.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+
+	for {
+		c.mutex.Lock()
+		if entry, ok := c.entries[host]; ok && c.now().Before(entry.expires) {
+			if entry.err != nil {
+				c.stats.negative++
+			} else {
+				c.stats.hits++
+			}
+			c.mutex.Unlock()
+			return entry.addrs, entry.err
+		}
+
+		// Another caller is resolving this host: wait for it and re-check the cache
+		if done, ok := c.inflight[host]; ok {
+			c.mutex.Unlock()
+			select {
+			case <-done:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		done := make(chan struct{})
+		c.inflight[host] = done
+		c.stats.misses++
+		c.mutex.Unlock()
+
+		addrs, err := c.resolve(ctx, host)
+
+		c.mutex.Lock()
+		delete(c.inflight, host)
+		close(done)
+		c.mutex.Unlock()
+		return addrs, err
+	}
+}
+
+// resolve queries the resolver and caches the answer (NXDOMAIN with the negative TTL)
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := c.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			c.store(host, dnsEntry{err: err, expires: c.now().Add(c.negativeTTL)})
+		}
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	c.store(host, dnsEntry{addrs: addrs, expires: c.now().Add(c.ttl)})
+	return addrs, nil
+}
+
+// store saves a cache entry
+func (c *dnsCache) store(host string, entry dnsEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[host] = entry
+}
+
+// prefetch resolves host in the background so the first fetch of a cycle finds it cached
+func (c *dnsCache) prefetch(ctx context.Context, host string) {
+	if host == "" || net.ParseIP(host) != nil {
+		return
+	}
+	go c.lookup(ctx, host)
+}
+
+// dialContext dials addr using cached lookups, trying each resolved address in turn
+func (c *dnsCache) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range addrs {
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// snapshot returns a copy of the counters
+func (c *dnsCache) snapshot() dnsStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
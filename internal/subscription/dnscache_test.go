@@ -0,0 +1,211 @@
+package subscription
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/pkg/logger"
+)
+
+// fakeResolver counts lookups and answers from a static table
+type fakeResolver struct {
+	mutex   sync.Mutex
+	hosts   map[string]string
+	lookups map[string]int
+	block   bool // Block until the context is cancelled
+}
+
+func newFakeResolver(hosts map[string]string) *fakeResolver {
+	return &fakeResolver{hosts: hosts, lookups: make(map[string]int)}
+}
+
+func (r *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mutex.Lock()
+	r.lookups[host]++
+	block := r.block
+	ip, ok := r.hosts[host]
+	r.mutex.Unlock()
+
+	if block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+}
+
+func (r *fakeResolver) count(host string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.lookups[host]
+}
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestDNSCache(resolver Resolver, clock *fakeClock) *dnsCache {
+	cache := newDNSCache()
+	cache.resolver = resolver
+	cache.now = clock.Now
+	return cache
+}
+
+func TestDNSCache_MultiSubIDCycle_OneLookupPerHost(t *testing.T) {
+	testLogger, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	defer testLogger.Close()
+
+	content := base64.StdEncoding.EncodeToString([]byte("vless://node"))
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	subBase := fmt.Sprintf("http://sub.example.test:%s/sub/", serverURL.Port())
+
+	mux.HandleFunc("/panel/setting/defaultSettings", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":{"subEnable":true,"subURI":"` + subBase + `"}}`))
+	})
+	mux.HandleFunc("/panel/inbound/list", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":[{"id":1,"enable":true,"settings":"{\"clients\":[` +
+			`{\"email\":\"u1\",\"subId\":\"s1\",\"enable\":true},` +
+			`{\"email\":\"u2\",\"subId\":\"s2\",\"enable\":true},` +
+			`{\"email\":\"u3\",\"subId\":\"s3\",\"enable\":true}]}"}]}`))
+	})
+	mux.HandleFunc("/sub/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	})
+
+	authClient := auth.NewXUIAuth(server.URL, "admin", "password123")
+	authClient.SetSessionForTesting("test-session-token")
+
+	client := NewSubscriptionClient(authClient, "", testLogger)
+	resolver := newFakeResolver(map[string]string{"sub.example.test": "127.0.0.1"})
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	client.dns.resolver = resolver
+	client.dns.now = clock.Now
+
+	// Two cycles within the TTL window share one lookup
+	for cycle := 0; cycle < 2; cycle++ {
+		subscriptions, err := client.GetAllSubscriptionData()
+		require.NoError(t, err)
+		assert.Len(t, subscriptions, 3)
+	}
+	assert.Equal(t, 1, resolver.count("sub.example.test"))
+
+	// The panel itself is a literal IP and never goes through the resolver
+	assert.Equal(t, 0, resolver.count("127.0.0.1"))
+
+	stats := client.Stats()
+	assert.Equal(t, uint64(1), stats.DNSMisses)
+	assert.Equal(t, uint64(0), stats.DNSNegative)
+}
+
+func TestDNSCache_TTLExpiry(t *testing.T) {
+	resolver := newFakeResolver(map[string]string{"sub.example.test": "192.0.2.1"})
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	cache := newTestDNSCache(resolver, clock)
+	cache.ttl = 60 * time.Second
+
+	ctx := context.Background()
+	_, err := cache.lookup(ctx, "sub.example.test")
+	require.NoError(t, err)
+
+	clock.Advance(59 * time.Second)
+	_, err = cache.lookup(ctx, "sub.example.test")
+	require.NoError(t, err)
+	assert.Equal(t, 1, resolver.count("sub.example.test"))
+
+	clock.Advance(2 * time.Second)
+	addrs, err := cache.lookup(ctx, "sub.example.test")
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", addrs[0].IP.String())
+	assert.Equal(t, 2, resolver.count("sub.example.test"))
+}
+
+func TestDNSCache_NegativeCaching(t *testing.T) {
+	resolver := newFakeResolver(nil)
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	cache := newTestDNSCache(resolver, clock)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := cache.lookup(ctx, "missing.example.test")
+		require.Error(t, err)
+	}
+	assert.Equal(t, 1, resolver.count("missing.example.test"))
+	assert.Equal(t, uint64(2), cache.snapshot().negative)
+
+	// Negative entries expire after the short negative TTL
+	clock.Advance(DefaultNegativeDNSTTL + time.Second)
+	_, err := cache.lookup(ctx, "missing.example.test")
+	require.Error(t, err)
+	assert.Equal(t, 2, resolver.count("missing.example.test"))
+}
+
+func TestDNSCache_LiteralIPBypass(t *testing.T) {
+	resolver := newFakeResolver(nil)
+	cache := newTestDNSCache(resolver, &fakeClock{now: time.Unix(1700000000, 0)})
+
+	for _, ip := range []string{"127.0.0.1", "::1", "203.0.113.7"} {
+		addrs, err := cache.lookup(context.Background(), ip)
+		require.NoError(t, err)
+		require.Len(t, addrs, 1)
+		assert.True(t, addrs[0].IP.Equal(net.ParseIP(ip)))
+		assert.Equal(t, 0, resolver.count(ip))
+	}
+	assert.Equal(t, dnsStats{}, cache.snapshot())
+}
+
+func TestDNSCache_Cancellation(t *testing.T) {
+	resolver := newFakeResolver(map[string]string{"slow.example.test": "192.0.2.1"})
+	resolver.block = true
+	cache := newTestDNSCache(resolver, &fakeClock{now: time.Unix(1700000000, 0)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := cache.lookup(ctx, "slow.example.test")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Cancelled lookups are not cached
+	resolver.mutex.Lock()
+	resolver.block = false
+	resolver.mutex.Unlock()
+	addrs, err := cache.lookup(context.Background(), "slow.example.test")
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", addrs[0].IP.String())
+	assert.Equal(t, 2, resolver.count("slow.example.test"))
+}
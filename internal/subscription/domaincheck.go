@@ -0,0 +1,107 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDomainCheckInterval is how often the resolved domain is re-checked
+const DefaultDomainCheckInterval = 5 * time.Minute
+
+// DomainCheckMode selects what happens when the resolved domain does not resolve
+type DomainCheckMode string
+
+const (
+	DomainCheckOff    DomainCheckMode = "off"    // No check
+	DomainCheckWarn   DomainCheckMode = "warn"   // Log the condition, keep reporting
+	DomainCheckStrict DomainCheckMode = "strict" // Skip subscription reporting until it resolves
+)
+
+// ParseDomainCheckMode parses a resolved_domain_check value (empty means warn)
+func ParseDomainCheckMode(value string) (DomainCheckMode, error) {
+	switch mode := DomainCheckMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return DomainCheckWarn, nil
+	case DomainCheckOff, DomainCheckWarn, DomainCheckStrict:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown resolved_domain_check %q (known: off, warn, strict)", value)
+	}
+}
+
+// DomainChecker checks that the configured resolved domain resolves, caching the
+// result for the check interval
+type DomainChecker struct {
+	domain   string
+	resolver Resolver
+	interval time.Duration
+	now      func() time.Time // injectable for tests
+
+	checked bool
+	checkAt time.Time // Time of the last check
+	lastErr error
+	mutex   sync.Mutex
+}
+
+// NewDomainChecker creates a checker for domain using the system resolver
+func NewDomainChecker(domain string, interval time.Duration) *DomainChecker {
+	if interval <= 0 {
+		interval = DefaultDomainCheckInterval
+	}
+	return &DomainChecker{
+		domain:   domain,
+		resolver: net.DefaultResolver,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// SetResolver replaces the resolver used for the check
+func (c *DomainChecker) SetResolver(resolver Resolver) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.resolver = resolver
+}
+
+// Domain returns the checked domain
+func (c *DomainChecker) Domain() string {
+	return c.domain
+}
+
+// Check returns a nil error if the domain resolves. The domain is re-resolved at most once per
+// interval; changed reports whether the outcome differs from the previous check (always
+// true for the first one) so callers can log transitions once.
+func (c *DomainChecker) Check(ctx context.Context) (changed bool, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.checked && c.now().Sub(c.checkAt) < c.interval {
+		return false, c.lastErr
+	}
+
+	err = c.resolve(ctx)
+	changed = !c.checked || (err == nil) != (c.lastErr == nil)
+	c.checked = true
+	c.checkAt = c.now()
+	c.lastErr = err
+	return changed, err
+}
+
+// resolve looks the domain up; literal IPs always resolve
+func (c *DomainChecker) resolve(ctx context.Context) error {
+	if net.ParseIP(c.domain) != nil {
+		return nil
+	}
+	addrs, err := c.resolver.LookupIPAddr(ctx, c.domain)
+	if err != nil {
+		return fmt.Errorf("resolved domain %s does not resolve: %w", c.domain, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("resolved domain %s has no addresses", c.domain)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package subscription
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDomainChecker(domain string, resolver Resolver, clock *fakeClock) *DomainChecker {
+	checker := NewDomainChecker(domain, time.Minute)
+	checker.SetResolver(resolver)
+	checker.now = clock.Now
+	return checker
+}
+
+func TestParseDomainCheckMode(t *testing.T) {
+	mode, err := ParseDomainCheckMode("")
+	require.NoError(t, err)
+	assert.Equal(t, DomainCheckWarn, mode)
+
+	mode, err = ParseDomainCheckMode("Strict")
+	require.NoError(t, err)
+	assert.Equal(t, DomainCheckStrict, mode)
+
+	_, err = ParseDomainCheckMode("loopback")
+	assert.Error(t, err)
+}
+
+func TestDomainChecker_Resolvable(t *testing.T) {
+	resolver := newFakeResolver(map[string]string{"sub.example.com": "192.0.2.10"})
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	checker := newTestDomainChecker("sub.example.com", resolver, clock)
+
+	changed, err := checker.Check(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, changed, "first check always reports a change")
+
+	// Cached within the interval
+	changed, err = checker.Check(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 1, resolver.count("sub.example.com"))
+}
+
+func TestDomainChecker_Unresolvable(t *testing.T) {
+	resolver := newFakeResolver(map[string]string{})
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	checker := newTestDomainChecker("gone.example.com", resolver, clock)
+
+	changed, err := checker.Check(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "gone.example.com")
+	assert.True(t, changed)
+
+	// Still failing after the interval: no change to log again
+	clock.Advance(time.Minute)
+	changed, err = checker.Check(context.Background())
+	assert.Error(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 2, resolver.count("gone.example.com"))
+
+	// Recovery is reported as a change
+	resolver.mutex.Lock()
+	resolver.hosts["gone.example.com"] = "192.0.2.20"
+	resolver.mutex.Unlock()
+	clock.Advance(time.Minute)
+	changed, err = checker.Check(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, changed)
+}
+
+func TestDomainChecker_LiteralIP(t *testing.T) {
+	resolver := newFakeResolver(map[string]string{})
+	checker := newTestDomainChecker("192.0.2.30", resolver, &fakeClock{now: time.Unix(1700000000, 0)})
+
+	_, err := checker.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, resolver.count("192.0.2.30"))
+}
@@ -0,0 +1,118 @@
+package subscription
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/internal/errstats"
+)
+
+// fetchOutcome is the result of fetching the subscription of one SubID
+type fetchOutcome struct {
+	sub     SubscriptionData
+	fetched bool  // Content fetched and not empty, the SubID is reported
+	err     error // Why the content could not be fetched, nil for empty content
+}
+
+// fetchAll fetches the subscriptions of subscriptions with up to fetchConcurrency requests
+// in flight, returning one outcome per SubID in the same order. A session that cannot be
+// restored stops the dispatch of the remaining SubIDs and is returned.
+func (s *SubscriptionClient) fetchAll(run *phaseRun, subscriptions []SubscriptionData, subURI, jsonURI string) ([]fetchOutcome, error) {
+	outcomes := make([]fetchOutcome, len(subscriptions))
+	jobs := make(chan int)
+	abort := make(chan struct{})
+	var abortErr error
+	var abortOnce sync.Once
+
+	var wg sync.WaitGroup
+	for range min(s.fetchConcurrency, len(subscriptions)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				outcome, err := s.fetchSubscription(run, subscriptions[i], subURI, jsonURI)
+				if err != nil {
+					abortOnce.Do(func() {
+						abortErr = err
+						close(abort)
+					})
+					continue
+				}
+				outcomes[i] = outcome
+			}
+		}()
+	}
+
+dispatch:
+	for i := range subscriptions {
+		select {
+		case jobs <- i:
+		case <-abort:
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return outcomes, abortErr
+}
+
+// fetchSubscription fetches the base64 and, when jsonURI is set, JSON subscription of sub.
+// Only an unrestorable session is returned as an error; other failures are in the outcome.
+func (s *SubscriptionClient) fetchSubscription(run *phaseRun, sub SubscriptionData, subURI, jsonURI string) (fetchOutcome, error) {
+	fetched, err := withSessionRetry(s, run, func() (fetchedContent, error) {
+		content, headers, err := s.GetSubscriptionContent(subURI, sub.SubID)
+		return fetchedContent{content, headers}, err
+	})
+	if err != nil {
+		if errors.Is(err, auth.ErrAuthExpired) {
+			return fetchOutcome{}, fmt.Errorf("failed to get subscription content for SubID %s: %w", sub.SubID, err)
+		}
+		// Log error but continue processing other subscriptions
+		s.errorCounters.RecordCategory(errstats.SubscriptionFetch)
+		s.logger.Warnf("Failed to get subscription content for SubID %s: %v", sub.SubID, err)
+		return fetchOutcome{sub: sub, err: err}, nil
+	}
+
+	// If content is empty, subscription service may be down, log warning but continue
+	if fetched.content == "" {
+		s.logger.Warnf("Empty subscription content for SubID %s (subscription service may be down), skipping", sub.SubID)
+		return fetchOutcome{sub: sub}, nil
+	}
+
+	sub.NodeConfig = fetched.content
+	sub.Headers = fetched.headers
+	if jsonURI != "" {
+		sub.JSONConfig, err = withSessionRetry(s, run, func() (string, error) {
+			return s.GetSubscriptionJSON(jsonURI, sub.SubID)
+		})
+		if errors.Is(err, auth.ErrAuthExpired) {
+			return fetchOutcome{}, fmt.Errorf("failed to get JSON subscription for SubID %s: %w", sub.SubID, err)
+		}
+		if err != nil {
+			// The base64 subscription is still reported
+			s.errorCounters.RecordCategory(errstats.SubscriptionFetch)
+			s.logger.Warnf("Failed to get JSON subscription for SubID %s: %v", sub.SubID, err)
+		}
+	}
+	return fetchOutcome{sub: sub, fetched: true}, nil
+}
+
+// logFetchFailures records the failed SubIDs of a phase and logs them in a single summary,
+// the cause of each having been logged as it failed
+func (s *SubscriptionClient) logFetchFailures(outcomes []fetchOutcome) {
+	var failed []string
+	for _, outcome := range outcomes {
+		if outcome.err != nil {
+			failed = append(failed, outcome.sub.SubID)
+		}
+	}
+	s.phase.setFailures(len(failed))
+	if len(failed) > 0 {
+		s.logger.Warnf("⚠️  Failed to fetch %d of %d subscriptions (%s), reporting the others",
+			len(failed), len(outcomes), strings.Join(failed, ", "))
+	}
+}
@@ -0,0 +1,145 @@
+package subscription
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/pkg/logger"
+)
+
+// newFetchPanel returns a client of a panel with count SubIDs (sub00, sub01, ...), their
+// subscription content being served by handler
+func newFetchPanel(t *testing.T, count int, handler http.HandlerFunc) *SubscriptionClient {
+	testLogger, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { testLogger.Close() })
+
+	var clients []string
+	for i := range count {
+		clients = append(clients, fmt.Sprintf(`{\"email\":\"user%02d\",\"subId\":\"sub%02d\",\"enable\":true}`, i, i))
+	}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/panel/setting/defaultSettings", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":{"subEnable":true,"subURI":"` + server.URL + `/sub/"}}`))
+	})
+	mux.HandleFunc("/panel/inbound/list", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":[{"id":1,"enable":true,"settings":"{\"clients\":[` + strings.Join(clients, ",") + `]}"}]}`))
+	})
+	mux.HandleFunc("/sub/", handler)
+
+	authClient := auth.NewXUIAuth(server.URL, "admin", "password123")
+	authClient.SetSessionForTesting("test-session-token")
+	client := NewSubscriptionClient(authClient, "", testLogger)
+	client.SetRetryPolicy(1, time.Millisecond)
+	return client
+}
+
+func nodeContent(subID string) string {
+	return base64.StdEncoding.EncodeToString([]byte("vless://" + subID))
+}
+
+func TestGetAllSubscriptionData_BoundedConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	client := newFetchPanel(t, 12, func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			peak := atomic.LoadInt32(&maxInFlight)
+			if current <= peak || atomic.CompareAndSwapInt32(&maxInFlight, peak, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(nodeContent(strings.TrimPrefix(r.URL.Path, "/sub/"))))
+	})
+	client.SetFetchPolicy(3, time.Second)
+
+	subscriptions, err := client.GetAllSubscriptionData()
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 12)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&maxInFlight))
+
+	// The SubID order does not depend on which request finished first
+	for i, sub := range subscriptions {
+		assert.Equal(t, fmt.Sprintf("sub%02d", i), sub.SubID)
+		assert.Equal(t, nodeContent(sub.SubID), sub.NodeConfig)
+	}
+}
+
+func TestGetAllSubscriptionData_FetchTimeoutAndFailures(t *testing.T) {
+	client := newFetchPanel(t, 4, func(w http.ResponseWriter, r *http.Request) {
+		switch subID := strings.TrimPrefix(r.URL.Path, "/sub/"); subID {
+		case "sub01":
+			time.Sleep(time.Second) // Hung subscription service
+		case "sub02":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(nodeContent(subID)))
+		}
+	})
+	client.SetFetchPolicy(4, 100*time.Millisecond)
+
+	started := time.Now()
+	subscriptions, err := client.GetAllSubscriptionData()
+	require.NoError(t, err)
+	assert.Less(t, time.Since(started), 900*time.Millisecond, "the hung request is cut at the fetch timeout")
+
+	var subIDs []string
+	for _, sub := range subscriptions {
+		subIDs = append(subIDs, sub.SubID)
+	}
+	assert.Equal(t, []string{"sub00", "sub03"}, subIDs)
+	assert.Equal(t, 2, client.Stats().LastPhaseFailures)
+}
+
+func TestSetFetchPolicy_KeepsDefaults(t *testing.T) {
+	client := NewSubscriptionClient(nil, "", nil)
+	client.SetFetchPolicy(0, 0)
+	assert.Equal(t, DefaultFetchConcurrency, client.fetchConcurrency)
+	assert.Equal(t, DefaultFetchTimeout, client.fetchTimeout)
+}
+
+func TestWithSessionRetry_ConcurrentRejections(t *testing.T) {
+	panel := newFakePanel(t)
+	client, authClient := newPanelClient(t, panel)
+	staleToken := authClient.GetSessionToken()
+
+	// Every concurrent request is rejected with the stale session
+	run := &phaseRun{}
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = withSessionRetry(client, run, func() (string, error) {
+				if authClient.GetSessionToken() == staleToken {
+					return "", auth.ErrAuthExpired
+				}
+				return "ok", nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, panel.loginCount(), "a single re-login serves all of them")
+	assert.Equal(t, uint64(1), client.Stats().InPhaseRelogins)
+}
@@ -0,0 +1,122 @@
+package subscription
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"xhub-agent/pkg/logger"
+)
+
+func FuzzExtractUniqueSubIDs(f *testing.F) {
+	f.Add(`{"clients":[{"email":"user1@example.com","subId":"sub-id-1","enable":true},{"email":"user4@example.com","subId":"sub-id-3","enable":false}]}`)
+	f.Add(`{"clients":[{"email":"\xff","subId":"\xfe","enable":true}]}`)
+	f.Add(`{"clients":[{"email":"a","subId":"../../admin","enable":true}]}`)
+	f.Add(`{"clients":null}`)
+	f.Add(strings.Repeat(`{"clients":[`, 80))
+
+	testLogger, err := logger.NewLogger(filepath.Join(f.TempDir(), "fuzz.log"), "error")
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer testLogger.Close()
+	client := &SubscriptionClient{logger: testLogger}
+
+	f.Fuzz(func(t *testing.T, settings string) {
+		inbounds := []*InboundInfo{nil, {ID: 1, Enable: true, Settings: settings}}
+		subscriptions, err := client.ExtractUniqueSubIDs(inbounds)
+		if err != nil {
+			return
+		}
+		for _, sub := range subscriptions {
+			if !utf8.ValidString(sub.SubID) || !utf8.ValidString(sub.Email) {
+				t.Fatalf("invalid UTF-8 passed onward: %q %q", sub.SubID, sub.Email)
+			}
+		}
+	})
+}
+
+func FuzzDecodeInboundList(f *testing.F) {
+	f.Add([]byte(`{"success":true,"obj":[{"id":1,"enable":true,"port":443,"protocol":"vless","remark":"r","settings":"{}"}]}`))
+	f.Add([]byte(`{"success":true,"obj":[null,{"id":2}]}`))
+	f.Add([]byte(`{"success":true,"obj":[{"protocol":"\xff"}]}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		inbounds, err := DecodeInboundList(body)
+		if err != nil {
+			return
+		}
+		for _, inbound := range inbounds {
+			if inbound == nil {
+				t.Fatal("nil inbound passed onward")
+			}
+			if !utf8.ValidString(inbound.Protocol) || !utf8.ValidString(inbound.Remark) {
+				t.Fatalf("invalid UTF-8 passed onward: %q %q", inbound.Protocol, inbound.Remark)
+			}
+		}
+		ExtractProtocols(inbounds)
+	})
+}
+
+func FuzzNormalizeUserinfo(f *testing.F) {
+	f.Add("upload=0; download=1024; total=10737418240; expire=1735689600")
+	f.Add("upload=99999999999999999999; download=-1; total=abc")
+	f.Add("UPLOAD = 5 ;; expire=; foo=1; upload=6")
+	f.Add("\xff=\xfe")
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		normalized := NormalizeUserinfo(raw)
+		for i := 0; i < len(normalized); i++ {
+			if normalized[i] >= utf8.RuneSelf {
+				t.Fatalf("non-ASCII output: %q", normalized)
+			}
+		}
+		if again := NormalizeUserinfo(normalized); again != normalized {
+			t.Fatalf("not idempotent: %q -> %q", normalized, again)
+		}
+	})
+}
+
+func TestExtractUniqueSubIDs_Hardening(t *testing.T) {
+	testLogger, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testLogger.Close()
+	client := &SubscriptionClient{logger: testLogger}
+
+	// A null entry in the inbound list used to panic
+	inbounds, err := DecodeInboundList([]byte(`{"success":true,"obj":[null,{"id":1,"enable":true,` +
+		`"settings":"{\"clients\":[{\"email\":\"a\\u00ff\",\"subId\":\"s1\",\"enable\":true}]}"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptions, err := client.ExtractUniqueSubIDs(append(inbounds, nil))
+	if err != nil || len(subscriptions) != 1 {
+		t.Fatalf("unexpected result %v, %v", subscriptions, err)
+	}
+
+	// Invalid UTF-8 SubIDs are skipped
+	subscriptions, _ = client.ExtractUniqueSubIDs([]*InboundInfo{{Enable: true,
+		Settings: "{\"clients\":[{\"email\":\"e\",\"subId\":\"\xff\",\"enable\":true}]}"}})
+	if len(subscriptions) != 0 {
+		t.Fatalf("invalid SubID accepted: %v", subscriptions)
+	}
+}
+
+func TestNormalizeUserinfo(t *testing.T) {
+	tests := map[string]string{
+		"upload=0; download=1024; total=10737418240; expire=1735689600": "upload=0; download=1024; total=10737418240; expire=1735689600",
+		"upload=1;download=2":                             "upload=1; download=2",
+		"upload=99999999999999999999; download=5":         "download=5", // overflow dropped
+		"upload=-1; total=abc; foo=3; expire=7; expire=8": "expire=7",
+		"":            "",
+		"garbage\xff": "",
+	}
+	for raw, expected := range tests {
+		if got := NormalizeUserinfo(raw); got != expected {
+			t.Errorf("NormalizeUserinfo(%q) = %q, want %q", raw, got, expected)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package subscription
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// rewrittenParams are the URI query parameters naming the server host (TLS SNI, HTTP Host)
+var rewrittenParams = []string{"sni", "host", "peer"}
+
+// RewriteNodeHosts points the nodes of a base64 node list at domain, returning the re-encoded
+// list and the number of rewritten nodes. The address of vless, trojan, shadowsocks (SIP002)
+// and vmess nodes becomes domain; their SNI and Host fields are rewritten only when they
+// named the replaced address, so REALITY targets and CDN hosts are kept. Other lines are
+// left as they are.
+func RewriteNodeHosts(content, domain string) (string, int, error) {
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return "", 0, err
+	}
+
+	lines := strings.Split(string(decoded), "\n")
+	rewritten := 0
+	for i, line := range lines {
+		uri := strings.TrimRight(line, "\r")
+		var node string
+		var ok bool
+		switch uriScheme(uri) {
+		case "vless", "trojan", "ss":
+			node, ok = rewriteURIHost(uri, domain)
+		case "vmess":
+			node, ok = rewriteVmessHost(uri, domain)
+		}
+		if ok {
+			lines[i] = node + strings.TrimPrefix(line, uri)
+			rewritten++
+		}
+	}
+	if rewritten == 0 {
+		return content, 0, nil
+	}
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join(lines, "\n"))), rewritten, nil
+}
+
+// rewriteURIHost rewrites a scheme://userinfo@host:port[/path][?query][#name] node URI,
+// touching only the host and the query parameters naming it so that the rest is kept as-is
+func rewriteURIHost(uri, domain string) (string, bool) {
+	body, fragment, hasFragment := strings.Cut(uri, "#")
+	body, query, hasQuery := strings.Cut(body, "?")
+	at := strings.LastIndex(body, "@")
+	if at < 0 {
+		return "", false // Legacy shadowsocks URI with an encoded host
+	}
+	userinfo, hostPort := body[:at], body[at+1:]
+	hostPort, path, hasPath := strings.Cut(hostPort, "/")
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil || host == domain {
+		return "", false
+	}
+
+	rewritten := userinfo + "@" + net.JoinHostPort(domain, port)
+	if hasPath {
+		rewritten += "/" + path
+	}
+	if hasQuery {
+		params := strings.Split(query, "&")
+		for i, param := range params {
+			key, value, _ := strings.Cut(param, "=")
+			unescaped, err := url.QueryUnescape(value)
+			if err == nil && unescaped == host && isRewrittenParam(key) {
+				params[i] = key + "=" + url.QueryEscape(domain)
+			}
+		}
+		rewritten += "?" + strings.Join(params, "&")
+	}
+	if hasFragment {
+		rewritten += "#" + fragment
+	}
+	return rewritten, true
+}
+
+// rewriteVmessHost rewrites the add field of a vmess://base64(JSON) node, and its sni and
+// host fields when they named the same address
+func rewriteVmessHost(uri, domain string) (string, bool) {
+	payload := uri[len("vmess://"):]
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		data, err = base64.RawStdEncoding.DecodeString(payload)
+	}
+	if err != nil {
+		return "", false
+	}
+
+	var node map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Keep the port and alter id as they were
+	if decoder.Decode(&node) != nil {
+		return "", false
+	}
+	host, _ := node["add"].(string)
+	if host == "" || host == domain {
+		return "", false
+	}
+	node["add"] = domain
+	for _, key := range rewrittenParams {
+		if value, _ := node[key].(string); value == host {
+			node[key] = domain
+		}
+	}
+
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false) // Paths and hosts keep their & < >
+	if encoder.Encode(node) != nil {
+		return "", false
+	}
+	return uri[:len("vmess://")] + base64.StdEncoding.EncodeToString(bytes.TrimSpace(encoded.Bytes())), true
+}
+
+// isRewrittenParam reports whether a URI query parameter names the server host
+func isRewrittenParam(key string) bool {
+	for _, param := range rewrittenParams {
+		if strings.EqualFold(key, param) {
+			return true
+		}
+	}
+	return false
+}
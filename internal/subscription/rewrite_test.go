@@ -0,0 +1,109 @@
+package subscription
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rewriteDomain = "node.example.com"
+
+func encodeNodes(lines ...string) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join(lines, "\n")))
+}
+
+func decodeNodes(t *testing.T, content string) []string {
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	require.NoError(t, err)
+	return strings.Split(string(decoded), "\n")
+}
+
+func vmessNode(t *testing.T, fields map[string]any) string {
+	data, err := json.Marshal(fields)
+	require.NoError(t, err)
+	return "vmess://" + base64.StdEncoding.EncodeToString(data)
+}
+
+func vmessFields(t *testing.T, uri string) map[string]any {
+	require.True(t, strings.HasPrefix(uri, "vmess://"))
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, "vmess://"))
+	require.NoError(t, err)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(data, &fields))
+	return fields
+}
+
+func TestRewriteNodeHosts_URISchemes(t *testing.T) {
+	content := encodeNodes(
+		"vless://"+aliceUUID+"@203.0.113.7:443?type=tcp&security=tls&sni=203.0.113.7#alice",
+		"vless://"+aliceUUID+"@203.0.113.7:8443?type=tcp&security=reality&sni=www.microsoft.com&pbk=key#alice-reality",
+		"trojan://"+carolPass+"@203.0.113.7:443?security=tls&peer=203.0.113.7&type=ws&host=cdn.example.net&path=%2Fws#carol",
+		"ss://YWVzLTI1Ni1nY206c2VjcmV0@203.0.113.7:8388#ss",
+		"ss://YWVzLTI1Ni1nY206c2VjcmV0QDIwMy4wLjExMy43OjgzODg=#legacy",
+		"hysteria2://secret@203.0.113.7:443?sni=203.0.113.7#hy2",
+	)
+
+	rewritten, count, err := RewriteNodeHosts(content, rewriteDomain)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+	assert.Equal(t, []string{
+		"vless://" + aliceUUID + "@node.example.com:443?type=tcp&security=tls&sni=node.example.com#alice",
+		"vless://" + aliceUUID + "@node.example.com:8443?type=tcp&security=reality&sni=www.microsoft.com&pbk=key#alice-reality",
+		"trojan://" + carolPass + "@node.example.com:443?security=tls&peer=node.example.com&type=ws&host=cdn.example.net&path=%2Fws#carol",
+		"ss://YWVzLTI1Ni1nY206c2VjcmV0@node.example.com:8388#ss",
+		"ss://YWVzLTI1Ni1nY206c2VjcmV0QDIwMy4wLjExMy43OjgzODg=#legacy",
+		"hysteria2://secret@203.0.113.7:443?sni=203.0.113.7#hy2",
+	}, decodeNodes(t, rewritten))
+}
+
+func TestRewriteNodeHosts_IPv6(t *testing.T) {
+	content := encodeNodes("vless://" + aliceUUID + "@[2001:db8::7]:443?security=tls&sni=2001%3Adb8%3A%3A7#v6")
+
+	rewritten, count, err := RewriteNodeHosts(content, rewriteDomain)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"vless://" + aliceUUID + "@node.example.com:443?security=tls&sni=node.example.com#v6"},
+		decodeNodes(t, rewritten))
+}
+
+func TestRewriteNodeHosts_Vmess(t *testing.T) {
+	content := encodeNodes(
+		vmessNode(t, map[string]any{"v": "2", "ps": "a&b", "add": "203.0.113.7", "port": 443, "id": aliceUUID,
+			"net": "ws", "host": "203.0.113.7", "sni": "cdn.example.net", "path": "/ws?ed=2048"}),
+	)
+
+	rewritten, count, err := RewriteNodeHosts(content, rewriteDomain)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	lines := decodeNodes(t, rewritten)
+	require.Len(t, lines, 1)
+	fields := vmessFields(t, lines[0])
+	assert.Equal(t, rewriteDomain, fields["add"])
+	assert.Equal(t, rewriteDomain, fields["host"])
+	assert.Equal(t, "cdn.example.net", fields["sni"])
+	assert.Equal(t, "/ws?ed=2048", fields["path"])
+	assert.Equal(t, "a&b", fields["ps"])
+	assert.EqualValues(t, 443, fields["port"])
+}
+
+func TestRewriteNodeHosts_Unchanged(t *testing.T) {
+	content := encodeNodes(
+		"vless://"+aliceUUID+"@node.example.com:443?sni=node.example.com#alice",
+		vmessNode(t, map[string]any{"add": rewriteDomain, "port": 443, "id": bobUUID}),
+	)
+
+	rewritten, count, err := RewriteNodeHosts(content, rewriteDomain)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Equal(t, content, rewritten)
+}
+
+func TestRewriteNodeHosts_InvalidBase64(t *testing.T) {
+	_, _, err := RewriteNodeHosts("not base64!", rewriteDomain)
+	assert.Error(t, err)
+}
@@ -0,0 +1,132 @@
+package subscription
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"xhub-agent/internal/auth"
+)
+
+// phaseEstimateDecay weights the previous estimate against the latest phase duration.
+// A single slow phase therefore fades out after a few normal ones.
+const phaseEstimateDecay = 0.5
+
+// phaseTracker keeps the decaying expected phase duration and session counters
+type phaseTracker struct {
+	stats Stats
+	mutex sync.Mutex
+}
+
+// newPhaseTracker creates a phase tracker with no history
+func newPhaseTracker() *phaseTracker {
+	return &phaseTracker{}
+}
+
+// observe records a completed phase duration into the decaying estimate
+func (p *phaseTracker) observe(d time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.stats.LastPhaseDuration = d
+	if p.stats.ExpectedPhaseDuration == 0 {
+		p.stats.ExpectedPhaseDuration = d
+		return
+	}
+	p.stats.ExpectedPhaseDuration = time.Duration(phaseEstimateDecay*float64(p.stats.ExpectedPhaseDuration) +
+		(1-phaseEstimateDecay)*float64(d))
+}
+
+// expected returns the current expected phase duration
+func (p *phaseTracker) expected() time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stats.ExpectedPhaseDuration
+}
+
+// count increments one of the counters
+func (p *phaseTracker) count(counter *uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	*counter++
+}
+
+// add adds n to one of the stats counters
+func (p *phaseTracker) add(counter *uint64, n uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	*counter += n
+}
+
+// setFailures records the number of SubIDs that could not be fetched in the last phase
+func (p *phaseTracker) setFailures(n int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.stats.LastPhaseFailures = n
+}
+
+// snapshot returns a copy of the phase counters
+func (p *phaseTracker) snapshot() Stats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stats
+}
+
+// phaseRun is the per-phase session state, shared by the concurrent fetches of the phase
+type phaseRun struct {
+	mutex    sync.Mutex // Held during the re-login so concurrent 401s wait for its session
+	relogged bool       // The single in-phase re-login has been used
+}
+
+// refreshSessionBeforePhase logs in again if the session may expire before the phase ends
+func (s *SubscriptionClient) refreshSessionBeforePhase() {
+	expected := s.phase.expected()
+	if expected == 0 || !s.auth.IsAuthenticated() {
+		return
+	}
+
+	remaining := s.auth.SessionRemaining()
+	if remaining >= expected {
+		return
+	}
+
+	s.logger.Infof("🔑 Session expires in %v, shorter than the expected subscription phase (%v), refreshing", remaining, expected)
+	if err := s.auth.Relogin(s.auth.GetSessionToken()); err != nil {
+		// Not fatal: the in-phase re-login still covers an actual expiry
+		s.logger.Warnf("⚠️ Preemptive session refresh failed: %v", err)
+		return
+	}
+	s.phase.count(&s.phase.stats.PreemptiveRefreshes)
+}
+
+// withSessionRetry runs fn and, on the first 401 of the phase, re-logs in once and retries.
+// Requests rejected with the same stale session retry with the new one; later 401s in the
+// same phase, or a failing re-login, are returned to abort the phase.
+func withSessionRetry[T any](s *SubscriptionClient, run *phaseRun, fn func() (T, error)) (T, error) {
+	staleToken := s.auth.GetSessionToken()
+	result, err := fn()
+	if err == nil || !errors.Is(err, auth.ErrAuthExpired) {
+		return result, err
+	}
+
+	run.mutex.Lock()
+	if run.relogged {
+		renewed := s.auth.GetSessionToken() != staleToken
+		run.mutex.Unlock()
+		if !renewed {
+			return result, err
+		}
+		return fn()
+	}
+	run.relogged = true
+	s.logger.Warn("🔑 Session rejected during subscription phase, logging in again")
+	loginErr := s.auth.Relogin(staleToken)
+	run.mutex.Unlock()
+	if loginErr != nil {
+		var zero T
+		return zero, errors.Join(err, loginErr)
+	}
+	s.phase.count(&s.phase.stats.InPhaseRelogins)
+
+	return fn()
+}
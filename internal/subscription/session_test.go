@@ -0,0 +1,167 @@
+package subscription
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/auth"
+	"xhub-agent/pkg/logger"
+)
+
+// fakePanel is a 3x-ui panel whose session can be expired on demand
+type fakePanel struct {
+	mutex       sync.Mutex
+	server      *httptest.Server
+	validToken  string
+	logins      int
+	loginFails  bool
+	expireAfter string // Expire the session after serving this path once
+	content     string
+}
+
+func newFakePanel(t *testing.T) *fakePanel {
+	p := &fakePanel{content: base64.StdEncoding.EncodeToString([]byte("vless://node"))}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if p.loginFails {
+			w.Write([]byte(`{"success":false,"msg":"panel unavailable"}`))
+			return
+		}
+		p.logins++
+		p.validToken = fmt.Sprintf("tok-%d", p.logins)
+		http.SetCookie(w, &http.Cookie{Name: "3x-ui", Value: p.validToken})
+		w.Write([]byte(`{"success":true}`))
+	})
+	mux.HandleFunc("/panel/setting/defaultSettings", p.authenticated(func(w http.ResponseWriter) {
+		w.Write([]byte(`{"success":true,"obj":{"subEnable":true,"subURI":"` + p.server.URL + `/sub/"}}`))
+	}))
+	mux.HandleFunc("/panel/inbound/list", p.authenticated(func(w http.ResponseWriter) {
+		w.Write([]byte(`{"success":true,"obj":[{"id":1,"enable":true,"protocol":"vless","settings":"{\"clients\":[` +
+			`{\"email\":\"u1\",\"subId\":\"s1\",\"enable\":true},` +
+			`{\"email\":\"u2\",\"subId\":\"s2\",\"enable\":true}]}"}]}`))
+	}))
+	mux.HandleFunc("/sub/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(p.content))
+	})
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authenticated rejects requests without the current session cookie
+func (p *fakePanel) authenticated(handler func(w http.ResponseWriter)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p.mutex.Lock()
+		cookie, err := r.Cookie("3x-ui")
+		if err != nil || cookie.Value != p.validToken {
+			p.mutex.Unlock()
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if p.expireAfter == r.URL.Path {
+			p.expireAfter = ""
+			p.validToken = ""
+		}
+		p.mutex.Unlock()
+		handler(w)
+	}
+}
+
+func (p *fakePanel) loginCount() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.logins
+}
+
+func newPanelClient(t *testing.T, panel *fakePanel) (*SubscriptionClient, *auth.XUIAuth) {
+	testLogger, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	t.Cleanup(func() { testLogger.Close() })
+
+	authClient := auth.NewXUIAuth(panel.server.URL, "admin", "password123")
+	require.NoError(t, authClient.Login())
+
+	client := NewSubscriptionClient(authClient, "", testLogger)
+	client.SetRetryPolicy(1, time.Millisecond)
+	return client, authClient
+}
+
+func TestSubscriptionPhase_SessionExpiresMidPhase(t *testing.T) {
+	panel := newFakePanel(t)
+	client, _ := newPanelClient(t, panel)
+
+	// The session dies right after the settings call
+	panel.expireAfter = "/panel/setting/defaultSettings"
+
+	subscriptions, err := client.GetAllSubscriptionData()
+	require.NoError(t, err)
+	assert.Len(t, subscriptions, 2)
+
+	// Exactly one in-phase re-login on top of the initial login
+	assert.Equal(t, 2, panel.loginCount())
+	assert.Equal(t, uint64(1), client.Stats().InPhaseRelogins)
+}
+
+func TestSubscriptionPhase_ReloginFailsAborts(t *testing.T) {
+	panel := newFakePanel(t)
+	client, _ := newPanelClient(t, panel)
+
+	panel.expireAfter = "/panel/setting/defaultSettings"
+	panel.loginFails = true
+
+	subscriptions, err := client.GetAllSubscriptionData()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, auth.ErrAuthExpired)
+	assert.Nil(t, subscriptions)
+	assert.Equal(t, uint64(0), client.Stats().InPhaseRelogins)
+}
+
+func TestSubscriptionPhase_PreemptiveRefresh(t *testing.T) {
+	panel := newFakePanel(t)
+	client, authClient := newPanelClient(t, panel)
+
+	// A long expected phase and a session that is about to expire
+	client.phase.observe(10 * time.Second)
+	authClient.SetSessionTTL(2 * time.Second)
+
+	_, err := client.GetAllSubscriptionData()
+	require.NoError(t, err)
+	assert.Equal(t, 2, panel.loginCount())
+	assert.Equal(t, uint64(1), client.Stats().PreemptiveRefreshes)
+
+	// Enough lifetime left: no refresh
+	authClient.SetSessionTTL(time.Hour)
+	_, err = client.GetAllSubscriptionData()
+	require.NoError(t, err)
+	assert.Equal(t, 2, panel.loginCount())
+}
+
+func TestPhaseTracker_EstimateDecays(t *testing.T) {
+	tracker := newPhaseTracker()
+	tracker.observe(time.Second)
+	assert.Equal(t, time.Second, tracker.expected())
+
+	// One anomalous slow phase raises the estimate...
+	tracker.observe(60 * time.Second)
+	assert.Greater(t, tracker.expected(), 20*time.Second)
+
+	// ...but it fades out after a few normal phases
+	for i := 0; i < 6; i++ {
+		tracker.observe(time.Second)
+	}
+	assert.Less(t, tracker.expected(), 2*time.Second)
+	assert.Equal(t, time.Second, tracker.snapshot().LastPhaseDuration)
+}
@@ -0,0 +1,218 @@
+package subscription
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/sanitize"
+)
+
+// Tolerances of the subscription-userinfo cross-check
+const (
+	userinfoExpireTolerance = 10 * time.Minute // Rounding and clock differences of the sub service
+	userinfoTotalTolerance  = 0.01             // Relative difference of the traffic quota
+)
+
+// indexedClient a panel client as seen by the staleness check
+type indexedClient struct {
+	email      string
+	subID      string
+	enabled    bool  // Client and its inbound are enabled
+	totalBytes int64 // Traffic quota, 0 unlimited
+	expiryMs   int64 // Expiry (unix ms), 0 never, negative relative to first use
+}
+
+// ClientIndex indexes the panel clients by the identity embedded in node URIs
+// (vless/vmess UUID, trojan password) and by SubID
+type ClientIndex struct {
+	byIdentity map[string]indexedClient
+	bySubID    map[string][]indexedClient
+}
+
+// BuildClientIndex parses the client settings of every inbound once per phase
+func BuildClientIndex(inbounds []*InboundInfo) *ClientIndex {
+	index := &ClientIndex{byIdentity: make(map[string]indexedClient), bySubID: make(map[string][]indexedClient)}
+	for _, inbound := range inbounds {
+		if inbound == nil || sanitize.CheckJSON([]byte(inbound.Settings)) != nil {
+			continue
+		}
+		var settings ClientSettings
+		if err := json.Unmarshal([]byte(inbound.Settings), &settings); err != nil {
+			continue
+		}
+		for _, client := range settings.Clients {
+			c := indexedClient{
+				email:      client.Email,
+				subID:      client.SubID,
+				enabled:    client.Enable && inbound.Enable,
+				totalBytes: client.TotalGB,
+				expiryMs:   client.ExpiryTime,
+			}
+			for _, identity := range []string{client.ID, client.Password} {
+				if identity != "" {
+					index.byIdentity[identity] = c
+				}
+			}
+			if client.SubID != "" {
+				index.bySubID[client.SubID] = append(index.bySubID[client.SubID], c)
+			}
+		}
+	}
+	return index
+}
+
+// CheckStale cross-checks fetched subscription content against the panel. It returns the
+// reason the content looks served from a stale cache, or "" when it is consistent.
+// Node URIs whose identity cannot be extracted are skipped, never flagged.
+func (x *ClientIndex) CheckStale(sub SubscriptionData) string {
+	if decoded, err := base64.StdEncoding.DecodeString(sub.NodeConfig); err == nil {
+		for _, line := range strings.Split(string(decoded), "\n") {
+			identity, ok := uriIdentity(strings.TrimSpace(line))
+			if !ok {
+				continue
+			}
+			client, exists := x.byIdentity[identity]
+			if !exists {
+				return fmt.Sprintf("content references a client that no longer exists (%s)", uriScheme(line))
+			}
+			if !client.enabled {
+				return fmt.Sprintf("content references disabled client %s", client.email)
+			}
+		}
+	}
+
+	return x.checkUserinfo(sub)
+}
+
+// checkUserinfo compares the subscription-userinfo total and expire with the panel clients of
+// the SubID; a value matching any client (or the sum of the quotas) is consistent
+func (x *ClientIndex) checkUserinfo(sub SubscriptionData) string {
+	clients := x.bySubID[sub.SubID]
+	if len(clients) == 0 {
+		return ""
+	}
+
+	for _, field := range ParseUserinfo(sub.Headers.SubscriptionUserinfo) {
+		switch field.Key {
+		case "total":
+			var sum int64
+			matched := false
+			for _, c := range clients {
+				sum += c.totalBytes
+				matched = matched || withinRatio(field.Value, c.totalBytes, userinfoTotalTolerance)
+			}
+			if !matched && !withinRatio(field.Value, sum, userinfoTotalTolerance) {
+				return fmt.Sprintf("subscription-userinfo total=%d contradicts the panel quota", field.Value)
+			}
+		case "expire":
+			matched := false
+			for _, c := range clients {
+				if c.expiryMs < 0 {
+					matched = true // Starts counting on first use, not comparable
+					break
+				}
+				diff := time.Duration(field.Value-c.expiryMs/1000) * time.Second
+				matched = matched || (diff > -userinfoExpireTolerance && diff < userinfoExpireTolerance)
+			}
+			if !matched {
+				return fmt.Sprintf("subscription-userinfo expire=%d contradicts the panel expiry", field.Value)
+			}
+		}
+	}
+	return ""
+}
+
+// withinRatio reports whether a and b differ by at most ratio (relative to the larger)
+func withinRatio(a, b int64, ratio float64) bool {
+	if a == b {
+		return true
+	}
+	larger := max(a, b)
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) <= float64(larger)*ratio
+}
+
+// uriIdentity extracts the client identity of a vless, vmess or trojan URI
+func uriIdentity(uri string) (string, bool) {
+	switch uriScheme(uri) {
+	case "vless", "trojan":
+		u, err := url.Parse(uri)
+		if err != nil || u.User == nil || u.User.Username() == "" {
+			return "", false
+		}
+		return u.User.Username(), true
+	case "vmess":
+		payload := strings.TrimPrefix(uri, "vmess://")
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(payload)
+		}
+		if err != nil {
+			return "", false
+		}
+		var vmess struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(data, &vmess) != nil || vmess.ID == "" {
+			return "", false
+		}
+		return vmess.ID, true
+	}
+	return "", false
+}
+
+// uriScheme returns the lower-case scheme of a node URI
+func uriScheme(uri string) string {
+	scheme, _, ok := strings.Cut(strings.TrimSpace(uri), "://")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
+
+// staleSummary summarizes the stale suspects of a phase for the log
+func staleSummary(stale map[string]string) string {
+	subIDs := make([]string, 0, len(stale))
+	for subID := range stale {
+		subIDs = append(subIDs, subID)
+	}
+	sort.Strings(subIDs)
+
+	const shown = 3
+	parts := make([]string, 0, shown)
+	for i, subID := range subIDs {
+		if i == shown {
+			parts = append(parts, fmt.Sprintf("and %d more", len(subIDs)-shown))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", subID, stale[subID]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// logStaleSuspects logs a summarized warning when subscriptions look stale. The same set of
+// suspects is logged once, and a note is logged when it clears.
+func (s *SubscriptionClient) logStaleSuspects(stale map[string]string) {
+	summary := staleSummary(stale)
+	if summary == s.staleSummary {
+		return
+	}
+	previous := s.staleSummary
+	s.staleSummary = summary
+
+	if len(stale) == 0 {
+		if previous != "" {
+			s.logger.Infof("✅ Subscription content is consistent with the panel again")
+		}
+		return
+	}
+	s.logger.Warnf("⚠️  %d subscription(s) look stale (sub service cache?): %s", len(stale), summary)
+}
@@ -0,0 +1,177 @@
+package subscription
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/pkg/logger"
+)
+
+const (
+	aliceUUID = "0f8e7a3c-1b2d-4c5e-8f90-a1b2c3d4e5f6"
+	bobUUID   = "9a8b7c6d-5e4f-4321-8765-0fedcba98765"
+	removedID = "11111111-2222-4333-8444-555555555555"
+	carolPass = "carol-trojan-secret"
+)
+
+func staleFixtureInbounds() []*InboundInfo {
+	return []*InboundInfo{
+		{
+			ID: 1, Enable: true, Port: 443, Protocol: "vless",
+			Settings: `{"clients":[
+				{"id":"` + aliceUUID + `","email":"alice@example.com","subId":"sub-alice","enable":true,"totalGB":10737418240,"expiryTime":1767225600000},
+				{"id":"` + bobUUID + `","email":"bob@example.com","subId":"sub-bob","enable":false}
+			]}`,
+		},
+		{
+			ID: 2, Enable: true, Port: 8443, Protocol: "trojan",
+			Settings: `{"clients":[{"password":"` + carolPass + `","email":"carol@example.com","subId":"sub-carol","enable":true,"expiryTime":-604800000}]}`,
+		},
+	}
+}
+
+func nodeConfig(uris ...string) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join(uris, "\n")))
+}
+
+func vmessURI(id string) string {
+	return "vmess://" + base64.StdEncoding.EncodeToString([]byte(`{"v":"2","ps":"node","add":"node.example.com","port":"443","id":"`+id+`"}`))
+}
+
+func TestClientIndex_CheckStale(t *testing.T) {
+	index := BuildClientIndex(staleFixtureInbounds())
+
+	tests := []struct {
+		name   string
+		sub    SubscriptionData
+		reason string // Substring of the expected reason, "" for consistent content
+	}{
+		{
+			name: "consistent content",
+			sub: SubscriptionData{SubID: "sub-alice", NodeConfig: nodeConfig(
+				"vless://"+aliceUUID+"@node.example.com:443?security=reality#alice",
+				vmessURI(aliceUUID),
+			), Headers: SubscriptionHeaders{SubscriptionUserinfo: "upload=0; download=1024; total=10737418240; expire=1767225600"}},
+		},
+		{
+			name: "removed client still in content",
+			sub: SubscriptionData{SubID: "sub-alice", NodeConfig: nodeConfig(
+				"vless://"+aliceUUID+"@node.example.com:443#alice",
+				"vless://"+removedID+"@node.example.com:443#gone",
+			)},
+			reason: "no longer exists (vless)",
+		},
+		{
+			name:   "removed vmess client",
+			sub:    SubscriptionData{SubID: "sub-alice", NodeConfig: nodeConfig(vmessURI(removedID))},
+			reason: "no longer exists (vmess)",
+		},
+		{
+			name:   "disabled client",
+			sub:    SubscriptionData{SubID: "sub-bob", NodeConfig: nodeConfig("vless://" + bobUUID + "@node.example.com:443#bob")},
+			reason: "disabled client bob@example.com",
+		},
+		{
+			name:   "trojan password identity",
+			sub:    SubscriptionData{SubID: "sub-carol", NodeConfig: nodeConfig("trojan://" + carolPass + "@node.example.com:8443#carol")},
+			reason: "",
+		},
+		{
+			name: "userinfo expire contradicts the panel",
+			sub: SubscriptionData{SubID: "sub-alice", NodeConfig: nodeConfig("vless://" + aliceUUID + "@node.example.com:443"),
+				Headers: SubscriptionHeaders{SubscriptionUserinfo: "total=10737418240; expire=1735689600"}},
+			reason: "expire=1735689600",
+		},
+		{
+			name: "userinfo total contradicts the panel",
+			sub: SubscriptionData{SubID: "sub-alice", NodeConfig: nodeConfig("vless://" + aliceUUID + "@node.example.com:443"),
+				Headers: SubscriptionHeaders{SubscriptionUserinfo: "total=5368709120; expire=1767225600"}},
+			reason: "total=5368709120",
+		},
+		{
+			name: "userinfo within tolerance",
+			sub: SubscriptionData{SubID: "sub-alice",
+				Headers: SubscriptionHeaders{SubscriptionUserinfo: "total=10737418000; expire=1767225660"}},
+		},
+		{
+			name: "expiry relative to first use is not compared",
+			sub: SubscriptionData{SubID: "sub-carol",
+				Headers: SubscriptionHeaders{SubscriptionUserinfo: "total=0; expire=1700000000"}},
+		},
+		{
+			name: "unparsable URIs are skipped",
+			sub: SubscriptionData{SubID: "sub-alice", NodeConfig: nodeConfig(
+				"ss://YWVzLTI1Ni1nY206cGFzcw@node.example.com:8388#ss",
+				"hysteria2://whatever@node.example.com:443",
+				"vless://@node.example.com:443",
+				"vmess://not-base64!!",
+				"vmess://"+base64.StdEncoding.EncodeToString([]byte("{broken")),
+				"not a uri",
+				"",
+			)},
+		},
+		{
+			name: "content that is not base64 is skipped",
+			sub:  SubscriptionData{SubID: "sub-alice", NodeConfig: "%%% not base64 %%%"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := index.CheckStale(tt.sub)
+			if tt.reason == "" {
+				assert.Empty(t, reason)
+				return
+			}
+			assert.Contains(t, reason, tt.reason)
+		})
+	}
+}
+
+func TestClientIndex_DisabledInbound(t *testing.T) {
+	inbounds := staleFixtureInbounds()
+	inbounds[1].Enable = false
+	index := BuildClientIndex(inbounds)
+
+	reason := index.CheckStale(SubscriptionData{SubID: "sub-carol", NodeConfig: nodeConfig("trojan://" + carolPass + "@node.example.com:8443")})
+	assert.Contains(t, reason, "disabled client carol@example.com")
+}
+
+func TestSubscriptionClient_LogStaleSuspects(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "test.log")
+	testLogger, err := logger.NewLogger(logPath, "debug")
+	require.NoError(t, err)
+	defer testLogger.Close()
+	s := &SubscriptionClient{logger: testLogger}
+
+	readLog := func() string {
+		content, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	s.logStaleSuspects(map[string]string{})
+	assert.NotContains(t, readLog(), "stale", "nothing is logged while content is consistent")
+
+	stale := map[string]string{"sub-a": "reason a", "sub-b": "reason b", "sub-c": "reason c", "sub-d": "reason d"}
+	s.logStaleSuspects(stale)
+	s.logStaleSuspects(stale)
+	log := readLog()
+	assert.Equal(t, 1, strings.Count(log, "look stale"), "an unchanged set is logged once")
+	assert.Contains(t, log, "4 subscription(s) look stale")
+	assert.Contains(t, log, "sub-a: reason a; sub-b: reason b; sub-c: reason c; and 1 more")
+
+	delete(stale, "sub-d")
+	s.logStaleSuspects(stale)
+	assert.Equal(t, 2, strings.Count(readLog(), "look stale"), "a changed set is logged again")
+
+	s.logStaleSuspects(map[string]string{})
+	s.logStaleSuspects(map[string]string{})
+	assert.Equal(t, 1, strings.Count(readLog(), "consistent with the panel again"))
+}
@@ -1,16 +1,22 @@
 package subscription
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"xhub-agent/internal/auth"
+	"xhub-agent/internal/errstats"
+	"xhub-agent/internal/sanitize"
 	"xhub-agent/pkg/logger"
 )
 
@@ -20,8 +26,61 @@ type SubscriptionClient struct {
 	client         *http.Client
 	resolvedDomain string
 	logger         *logger.Logger
+
+	// Retry policy for the prerequisite settings/inbound list calls
+	retryAttempts int
+	retryBackoff  time.Duration
+
+	// Concurrent subscription content requests and the timeout of each
+	fetchConcurrency int
+	fetchTimeout     time.Duration
+
+	// Resolver cache for subscription URLs that use hostnames
+	dns *dnsCache
+
+	// Session management around the multi-request subscription phase
+	phase *phaseTracker
+
+	// Per-category error counters (nil when not reported)
+	errorCounters *errstats.Counters
+
+	// Last logged stale content summary, so an unchanged set is not logged every cycle
+	staleSummary string
+
+	// Also collect the JSON (sing-box) subscriptions of subJsonURI (subscription_json)
+	jsonEnabled bool
+	// The panel serves no JSON subscription (subJsonURI empty), logged once
+	jsonUnavailable bool
+
+	// Point the base64 node lists at resolvedDomain (resolved_domain_rewrite)
+	rewriteHosts bool
+}
+
+// Stats subscription client counters
+type Stats struct {
+	DNSHits     uint64 // Lookups answered from the cache
+	DNSMisses   uint64 // Lookups sent to the resolver
+	DNSNegative uint64 // Lookups answered from the negative (NXDOMAIN) cache
+
+	LastPhaseDuration     time.Duration // Duration of the last subscription phase
+	ExpectedPhaseDuration time.Duration // Decaying estimate used for preemptive session refresh
+	PreemptiveRefreshes   uint64        // Session refreshes done before a phase
+	InPhaseRelogins       uint64        // Re-logins done after a 401 during a phase
+	LastPhaseFailures     int           // SubIDs whose content could not be fetched in the last phase
 }
 
+// Default retry policy for GetDefaultSettings and GetInboundList
+const (
+	DefaultRetryAttempts = 3
+	DefaultRetryBackoff  = 500 * time.Millisecond
+)
+
+// Default fetch policy for the subscription content of the SubIDs
+const (
+	DefaultFetchConcurrency = 4
+	DefaultFetchTimeout     = 30 * time.Second
+)
+
 // DefaultSettingsResponse default settings response structure
 type DefaultSettingsResponse struct {
 	Success bool          `json:"success"`
@@ -48,7 +107,27 @@ type InboundInfo struct {
 	ID       int    `json:"id"`
 	Remark   string `json:"remark"`
 	Enable   bool   `json:"enable"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
 	Settings string `json:"settings"`
+
+	StreamSettings string `json:"streamSettings"` // Transport and TLS settings (JSON string)
+}
+
+// InboundCertFile a certificate file referenced by the TLS settings of an inbound
+type InboundCertFile struct {
+	Port     int
+	CertFile string
+}
+
+// streamTLSSettings the certificate part of an inbound's streamSettings
+type streamTLSSettings struct {
+	Security    string `json:"security"`
+	TLSSettings struct {
+		Certificates []struct {
+			CertificateFile string `json:"certificateFile"`
+		} `json:"certificates"`
+	} `json:"tlsSettings"`
 }
 
 // ClientSettings client settings
@@ -61,6 +140,11 @@ type ClientInfo struct {
 	Email  string `json:"email"`
 	SubID  string `json:"subId"`
 	Enable bool   `json:"enable"`
+
+	ID         string `json:"id"`         // UUID (vless, vmess)
+	Password   string `json:"password"`   // trojan, shadowsocks
+	TotalGB    int64  `json:"totalGB"`    // Traffic quota in bytes, 0 unlimited
+	ExpiryTime int64  `json:"expiryTime"` // Unix ms, 0 never, negative relative to first use
 }
 
 // SubscriptionData subscription data
@@ -68,7 +152,11 @@ type SubscriptionData struct {
 	SubID      string              `json:"subId"`
 	Email      string              `json:"email"`
 	NodeConfig string              `json:"nodeConfig"` // base64 encoded node configuration
+	JSONConfig string              `json:"jsonConfig"` // JSON (sing-box) subscription, "" when not collected
 	Headers    SubscriptionHeaders `json:"headers"`    // HTTP response headers
+
+	StaleSuspect bool   `json:"staleSuspect"` // Content contradicts the panel client list
+	StaleReason  string `json:"staleReason"`  // Why the content looks stale
 }
 
 // SubscriptionHeaders HTTP response headers information
@@ -80,6 +168,7 @@ type SubscriptionHeaders struct {
 
 // NewSubscriptionClient creates a new subscription client
 func NewSubscriptionClient(authClient *auth.XUIAuth, resolvedDomain string, logger *logger.Logger) *SubscriptionClient {
+	dns := newDNSCache()
 	return &SubscriptionClient{
 		auth:   authClient,
 		logger: logger,
@@ -87,47 +176,132 @@ func NewSubscriptionClient(authClient *auth.XUIAuth, resolvedDomain string, logg
 			Timeout: 30 * time.Second,
 			Transport: &http.Transport{
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				DialContext:     dns.dialContext,
 			},
 		},
-		resolvedDomain: resolvedDomain,
+		resolvedDomain:   resolvedDomain,
+		retryAttempts:    DefaultRetryAttempts,
+		retryBackoff:     DefaultRetryBackoff,
+		fetchConcurrency: DefaultFetchConcurrency,
+		fetchTimeout:     DefaultFetchTimeout,
+		dns:              dns,
+		phase:            newPhaseTracker(),
 	}
 }
 
-// GetDefaultSettings gets default settings
-func (s *SubscriptionClient) GetDefaultSettings() (*SettingsData, error) {
-	// Check authentication status
-	if !s.auth.IsAuthenticated() {
-		return nil, fmt.Errorf("not authenticated, please login first")
+// SetDNSTTL sets how long resolved subscription hosts are cached (0 keeps the default).
+// NXDOMAIN results are cached for the shorter of ttl and DefaultNegativeDNSTTL.
+func (s *SubscriptionClient) SetDNSTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
 	}
+	s.dns.mutex.Lock()
+	defer s.dns.mutex.Unlock()
+	s.dns.ttl = ttl
+	s.dns.negativeTTL = min(ttl, DefaultNegativeDNSTTL)
+}
 
-	// Create authenticated request
-	req, err := s.auth.GetAuthenticatedRequest("POST", "/panel/setting/defaultSettings", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// SetJSONEnabled enables the collection of the JSON (sing-box) subscription of every SubID
+// from the panel's subJsonURI, alongside the base64 one
+func (s *SubscriptionClient) SetJSONEnabled(enabled bool) {
+	s.jsonEnabled = enabled
+}
+
+// SetHostRewrite enables rewriting the node addresses of the base64 subscriptions to the
+// resolved domain (see RewriteNodeHosts). It has no effect without a resolved domain.
+func (s *SubscriptionClient) SetHostRewrite(enabled bool) {
+	s.rewriteHosts = enabled
+}
+
+// SetErrorCounters sets the counters that record skipped subscription fetches
+func (s *SubscriptionClient) SetErrorCounters(counters *errstats.Counters) {
+	s.errorCounters = counters
+}
+
+// Stats returns the subscription client counters
+func (s *SubscriptionClient) Stats() Stats {
+	dns := s.dns.snapshot()
+	stats := s.phase.snapshot()
+	stats.DNSHits = dns.hits
+	stats.DNSMisses = dns.misses
+	stats.DNSNegative = dns.negative
+	return stats
+}
+
+// SetRetryPolicy sets the attempts and initial backoff used for the prerequisite calls.
+// The backoff doubles after each failed attempt; attempts < 1 means a single attempt.
+func (s *SubscriptionClient) SetRetryPolicy(attempts int, backoff time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	s.retryAttempts = attempts
+	s.retryBackoff = backoff
+}
+
+// SetFetchPolicy sets how many subscription contents are fetched concurrently and the
+// timeout of each request. Values < 1 keep the defaults.
+func (s *SubscriptionClient) SetFetchPolicy(concurrency int, timeout time.Duration) {
+	if concurrency > 0 {
+		s.fetchConcurrency = concurrency
+	}
+	if timeout > 0 {
+		s.fetchTimeout = timeout
 	}
+}
+
+// withRetry runs fn up to retryAttempts times with exponential backoff
+func withRetry[T any](s *SubscriptionClient, name string, fn func() (T, error)) (T, error) {
+	var result T
+	var err error
+	backoff := s.retryBackoff
 
+	for attempt := 1; attempt <= s.retryAttempts; attempt++ {
+		result, err = fn()
+		if err == nil {
+			return result, nil
+		}
+		// Session errors are handled by the phase re-login, not by backoff
+		if attempt == s.retryAttempts || errors.Is(err, auth.ErrAuthExpired) {
+			break
+		}
+
+		s.logger.Warnf("⚠️  %s failed (attempt %d/%d), retrying in %v: %v", name, attempt, s.retryAttempts, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	return result, err
+}
+
+// GetDefaultSettings gets default settings
+func (s *SubscriptionClient) GetDefaultSettings() (*SettingsData, error) {
 	// Send request
-	resp, err := s.client.Do(req)
+	resp, err := s.auth.Post("/panel/setting/defaultSettings")
 	if err != nil {
 		return nil, fmt.Errorf("failed to request default settings: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check HTTP status code
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("not authenticated, session may have expired")
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed, HTTP status code: %d", resp.StatusCode)
+		return nil, &auth.StatusError{StatusCode: resp.StatusCode}
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	// Read response body (size capped)
+	body, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	return DecodeDefaultSettings(body)
+}
+
+// DecodeDefaultSettings decodes a /panel/setting/defaultSettings response body
+func DecodeDefaultSettings(body []byte) (*SettingsData, error) {
+	if err := sanitize.CheckJSON(body); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
 	// Parse response
 	var settingsResp DefaultSettingsResponse
 	if err := json.Unmarshal(body, &settingsResp); err != nil {
@@ -136,7 +310,11 @@ func (s *SubscriptionClient) GetDefaultSettings() (*SettingsData, error) {
 
 	// Check if API response is successful
 	if !settingsResp.Success {
-		return nil, fmt.Errorf("API error: %s", settingsResp.Message)
+		return nil, fmt.Errorf("API error: %s", sanitize.String(settingsResp.Message))
+	}
+
+	if settingsResp.Data == nil {
+		return nil, fmt.Errorf("failed to parse response: missing obj")
 	}
 
 	return settingsResp.Data, nil
@@ -144,38 +322,41 @@ func (s *SubscriptionClient) GetDefaultSettings() (*SettingsData, error) {
 
 // GetInboundList gets inbound list
 func (s *SubscriptionClient) GetInboundList() ([]*InboundInfo, error) {
-	// Check authentication status
-	if !s.auth.IsAuthenticated() {
-		return nil, fmt.Errorf("not authenticated, please login first")
-	}
-
-	// Create authenticated request
-	req, err := s.auth.GetAuthenticatedRequest("POST", "/panel/inbound/list", nil)
+	body, err := s.GetInboundListBody()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
+	return DecodeInboundList(body)
+}
 
+// GetInboundListBody gets the raw /panel/inbound/list response body
+func (s *SubscriptionClient) GetInboundListBody() ([]byte, error) {
 	// Send request
-	resp, err := s.client.Do(req)
+	resp, err := s.auth.Post("/panel/inbound/list")
 	if err != nil {
 		return nil, fmt.Errorf("failed to request inbound list: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check HTTP status code
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("not authenticated, session may have expired")
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed, HTTP status code: %d", resp.StatusCode)
+		return nil, &auth.StatusError{StatusCode: resp.StatusCode}
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	// Read response body (size capped)
+	body, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	return body, nil
+}
+
+// DecodeInboundList decodes a /panel/inbound/list response body.
+// Null entries are dropped and display strings are valid UTF-8.
+func DecodeInboundList(body []byte) ([]*InboundInfo, error) {
+	if err := sanitize.CheckJSON(body); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
 
 	// Parse response
 	var inboundResp InboundListResponse
@@ -185,10 +366,20 @@ func (s *SubscriptionClient) GetInboundList() ([]*InboundInfo, error) {
 
 	// Check if API response is successful
 	if !inboundResp.Success {
-		return nil, fmt.Errorf("API error: %s", inboundResp.Message)
+		return nil, fmt.Errorf("API error: %s", sanitize.String(inboundResp.Message))
 	}
 
-	return inboundResp.Data, nil
+	inbounds := make([]*InboundInfo, 0, len(inboundResp.Data))
+	for _, inbound := range inboundResp.Data {
+		if inbound == nil {
+			continue
+		}
+		inbound.Remark = sanitize.String(inbound.Remark)
+		inbound.Protocol = sanitize.String(inbound.Protocol)
+		inbounds = append(inbounds, inbound)
+	}
+
+	return inbounds, nil
 }
 
 // ExtractUniqueSubIDs extracts unique SubIDs from inbound list
@@ -196,12 +387,15 @@ func (s *SubscriptionClient) ExtractUniqueSubIDs(inbounds []*InboundInfo) ([]Sub
 	subIDMap := make(map[string]SubscriptionData) // Use map for deduplication
 
 	for _, inbound := range inbounds {
-		if !inbound.Enable {
+		if inbound == nil || !inbound.Enable {
 			continue // Skip disabled inbound
 		}
 
-		// Parse settings JSON
+		// Parse settings JSON (nested JSON string, same limits as a response body)
 		var settings ClientSettings
+		if err := sanitize.CheckJSON([]byte(inbound.Settings)); err != nil {
+			continue // Skip pathological settings
+		}
 		if err := json.Unmarshal([]byte(inbound.Settings), &settings); err != nil {
 			continue // Skip unparseable settings
 		}
@@ -216,9 +410,17 @@ func (s *SubscriptionClient) ExtractUniqueSubIDs(inbounds []*InboundInfo) ([]Sub
 
 			if client.SubID == "" {
 				// Debug: log skipped client without SubID
-				s.logger.Debugf("Skipping client without SubID: Email=%s", client.Email)
+				s.logger.Debugf("Skipping client without SubID: Email=%s", sanitize.String(client.Email))
+				continue
+			}
+
+			// SubIDs are used as URL path segments and reported as-is. encoding/json turns
+			// invalid UTF-8 into U+FFFD, so a replacement rune marks a corrupt SubID.
+			if !utf8.ValidString(client.SubID) || strings.ContainsRune(client.SubID, utf8.RuneError) {
+				s.logger.Debugf("Skipping client with invalid SubID: Email=%s", sanitize.String(client.Email))
 				continue
 			}
+			client.Email = sanitize.String(client.Email)
 
 			// Deduplication: only save first encountered subID
 			if _, exists := subIDMap[client.SubID]; !exists {
@@ -231,58 +433,77 @@ func (s *SubscriptionClient) ExtractUniqueSubIDs(inbounds []*InboundInfo) ([]Sub
 		}
 	}
 
-	// Convert to slice
+	// Convert to slice, sorted so that reports do not depend on map iteration order
 	var result []SubscriptionData
 	for _, data := range subIDMap {
 		result = append(result, data)
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].SubID < result[j].SubID })
 
 	return result, nil
 }
 
-// GetSubscriptionContent gets subscription content (base64 node configuration) and response headers
-func (s *SubscriptionClient) GetSubscriptionContent(baseSubURL, subID string) (string, SubscriptionHeaders, error) {
-	var headers SubscriptionHeaders
-
-	// Build subscription URL directly
-	subscriptionURL := baseSubURL
-	if !strings.HasSuffix(subscriptionURL, "/") {
-		subscriptionURL += "/"
+// ExtractProtocols returns the sorted, deduplicated protocols of the enabled inbounds
+func ExtractProtocols(inbounds []*InboundInfo) []string {
+	seen := make(map[string]bool)
+	var protocols []string
+	for _, inbound := range inbounds {
+		if inbound == nil || !inbound.Enable {
+			continue
+		}
+		protocol := strings.ToLower(strings.TrimSpace(inbound.Protocol))
+		if protocol == "" || seen[protocol] {
+			continue
+		}
+		seen[protocol] = true
+		protocols = append(protocols, protocol)
 	}
-	subscriptionURL += subID
+	sort.Strings(protocols)
+	return protocols
+}
 
-	// Create HTTP request
-	req, err := http.NewRequest("GET", subscriptionURL, nil)
-	if err != nil {
-		return "", headers, fmt.Errorf("failed to create subscription request: %w", err)
+// ExtractCertFiles returns the certificate files referenced by the TLS settings of the
+// enabled inbounds. Inline certificates and unparsable settings are skipped.
+func ExtractCertFiles(inbounds []*InboundInfo) []InboundCertFile {
+	var files []InboundCertFile
+	for _, inbound := range inbounds {
+		if inbound == nil || !inbound.Enable || inbound.StreamSettings == "" {
+			continue
+		}
+		var stream streamTLSSettings
+		if err := json.Unmarshal([]byte(inbound.StreamSettings), &stream); err != nil || stream.Security != "tls" {
+			continue
+		}
+		for _, cert := range stream.TLSSettings.Certificates {
+			if path := strings.TrimSpace(cert.CertificateFile); path != "" {
+				files = append(files, InboundCertFile{Port: inbound.Port, CertFile: path})
+			}
+		}
 	}
+	return files
+}
 
-	// Set User-Agent to simulate v2ray client
-	req.Header.Set("User-Agent", "v2rayN/6.23")
+// GetSubscriptionContent gets subscription content (base64 node configuration) and response headers
+func (s *SubscriptionClient) GetSubscriptionContent(baseSubURL, subID string) (string, SubscriptionHeaders, error) {
+	var headers SubscriptionHeaders
 
-	// Set X-Forwarded-For header if resolved domain is available
-	if s.resolvedDomain != "" {
-		req.Header.Set("X-Forwarded-Host", s.resolvedDomain)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.fetchTimeout)
+	defer cancel()
 
-	// Send request
-	resp, err := s.client.Do(req)
+	// Set User-Agent to simulate v2ray client
+	resp, err := s.requestSubscription(ctx, baseSubURL, subID, "v2rayN/6.23")
 	if err != nil {
-		return "", headers, fmt.Errorf("failed to request subscription: %w", err)
+		return "", headers, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", headers, fmt.Errorf("subscription request failed, HTTP status code: %d", resp.StatusCode)
-	}
-
 	// Collect response headers
-	headers.ProfileTitle = resp.Header.Get("profile-title")
-	headers.ProfileUpdateInterval = resp.Header.Get("profile-update-interval")
-	headers.SubscriptionUserinfo = resp.Header.Get("subscription-userinfo")
+	headers.ProfileTitle = sanitize.Header(resp.Header.Get("profile-title"))
+	headers.ProfileUpdateInterval = sanitize.Header(resp.Header.Get("profile-update-interval"))
+	headers.SubscriptionUserinfo = NormalizeUserinfo(resp.Header.Get("subscription-userinfo"))
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	// Read response body (size capped)
+	body, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
 	if err != nil {
 		return "", headers, fmt.Errorf("failed to read subscription response: %w", err)
 	}
@@ -303,10 +524,96 @@ func (s *SubscriptionClient) GetSubscriptionContent(baseSubURL, subID string) (s
 	return content, headers, nil
 }
 
-// GetAllSubscriptionData gets all subscription data
+// GetSubscriptionJSON gets the JSON (sing-box) subscription of subID from the subJsonURI
+// service, "" when it is empty
+func (s *SubscriptionClient) GetSubscriptionJSON(baseSubJSONURL, subID string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.fetchTimeout)
+	defer cancel()
+
+	resp, err := s.requestSubscription(ctx, baseSubJSONURL, subID, "sing-box 1.10.0")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to read JSON subscription response: %w", err)
+	}
+	content := strings.TrimSpace(string(body))
+	if content == "" {
+		return "", nil
+	}
+	if !json.Valid([]byte(content)) {
+		return "", fmt.Errorf("invalid JSON content in subscription response")
+	}
+	return content, nil
+}
+
+// requestSubscription requests the subscription of subID from the service at baseSubURL as
+// userAgent, returning the response of a successful (HTTP 200) request. ctx bounds the
+// request and the read of the response body.
+func (s *SubscriptionClient) requestSubscription(ctx context.Context, baseSubURL, subID, userAgent string) (*http.Response, error) {
+	// Build subscription URL directly
+	subscriptionURL := baseSubURL
+	if !strings.HasSuffix(subscriptionURL, "/") {
+		subscriptionURL += "/"
+	}
+	subscriptionURL += url.PathEscape(subID)
+
+	// Create HTTP request
+	req, err := http.NewRequestWithContext(ctx, "GET", subscriptionURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscription request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	// Set X-Forwarded-For header if resolved domain is available
+	if s.resolvedDomain != "" {
+		req.Header.Set("X-Forwarded-Host", s.resolvedDomain)
+	}
+
+	// Send request
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request subscription: %w", auth.Unreachable(err))
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, fmt.Errorf("subscription request failed: %w", auth.ErrAuthExpired)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("subscription request failed, HTTP status code: %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// GetAllSubscriptionData gets all subscription data.
+// The session is refreshed up front if it may expire during the phase, and the first
+// 401 inside the phase triggers a single re-login and retry of the failed request.
 func (s *SubscriptionClient) GetAllSubscriptionData() ([]SubscriptionData, error) {
-	// 1. Get default settings
-	settings, err := s.GetDefaultSettings()
+	s.refreshSessionBeforePhase()
+
+	started := time.Now()
+	result, err := s.runPhase(&phaseRun{})
+	if err == nil {
+		s.phase.observe(time.Since(started))
+	}
+	return result, err
+}
+
+// runPhase runs one subscription phase
+func (s *SubscriptionClient) runPhase(run *phaseRun) ([]SubscriptionData, error) {
+	// Panel requests log in again by themselves on a 401, count those as in-phase re-logins
+	relogins := s.auth.Relogins()
+	defer func() { s.phase.add(&s.phase.stats.InPhaseRelogins, s.auth.Relogins()-relogins) }()
+
+	// 1. Get default settings (retried, the panel may still be starting up)
+	settings, err := withSessionRetry(s, run, func() (*SettingsData, error) {
+		return withRetry(s, "Get default settings", s.GetDefaultSettings)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get default settings: %w", err)
 	}
@@ -315,8 +622,15 @@ func (s *SubscriptionClient) GetAllSubscriptionData() ([]SubscriptionData, error
 		return nil, fmt.Errorf("subscription is not enabled or SubURI is empty")
 	}
 
-	// 2. Get inbound list
-	inbounds, err := s.GetInboundList()
+	// Resolve the subscription host while the inbound list is fetched
+	if subURL, err := url.Parse(settings.SubURI); err == nil {
+		s.dns.prefetch(context.Background(), subURL.Hostname())
+	}
+
+	// 2. Get inbound list (retried)
+	inbounds, err := withSessionRetry(s, run, func() ([]*InboundInfo, error) {
+		return withRetry(s, "Get inbound list", s.GetInboundList)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get inbound list: %w", err)
 	}
@@ -327,26 +641,58 @@ func (s *SubscriptionClient) GetAllSubscriptionData() ([]SubscriptionData, error
 		return nil, fmt.Errorf("failed to extract SubIDs: %w", err)
 	}
 
-	// 4. Get subscription content for each SubID
+	// 4. Get subscription content for each SubID, fetchConcurrency at a time
+	outcomes, err := s.fetchAll(run, subscriptions, settings.SubURI, s.jsonURI(settings))
+	if err != nil {
+		// A session that cannot be restored aborts the phase instead of a partial report
+		return nil, err
+	}
+	s.logFetchFailures(outcomes)
+
+	index := BuildClientIndex(inbounds)
+	stale := make(map[string]string)
 	var result []SubscriptionData
-	for _, sub := range subscriptions {
-		content, headers, err := s.GetSubscriptionContent(settings.SubURI, sub.SubID)
-		if err != nil {
-			// Log error but continue processing other subscriptions
-			s.logger.Warnf("Failed to get subscription content for SubID %s: %v", sub.SubID, err)
+	for _, outcome := range outcomes {
+		if !outcome.fetched {
 			continue
 		}
-
-		// If content is empty, subscription service may be down, log warning but continue
-		if content == "" {
-			s.logger.Warnf("Empty subscription content for SubID %s (subscription service may be down), skipping", sub.SubID)
-			continue
+		sub := outcome.sub
+		if reason := index.CheckStale(sub); reason != "" {
+			sub.StaleSuspect, sub.StaleReason = true, reason
+			stale[sub.SubID] = reason
+		}
+		if s.rewriteHosts && s.resolvedDomain != "" {
+			if content, rewritten, err := RewriteNodeHosts(sub.NodeConfig, s.resolvedDomain); err == nil && rewritten > 0 {
+				sub.NodeConfig = content
+				s.logger.Debugf("🔀 Pointed %d nodes of SubID %s at %s", rewritten, sub.SubID, s.resolvedDomain)
+			}
 		}
-
-		sub.NodeConfig = content
-		sub.Headers = headers
 		result = append(result, sub)
 	}
+	s.logStaleSuspects(stale)
 
 	return result, nil
 }
+
+// jsonURI returns the base URL of the JSON subscriptions to collect, "" when disabled or
+// not served by the panel
+func (s *SubscriptionClient) jsonURI(settings *SettingsData) string {
+	if !s.jsonEnabled {
+		return ""
+	}
+	if settings.SubJsonURI == "" {
+		if !s.jsonUnavailable {
+			s.logger.Warnf("⚠️  subscription_json is enabled but the panel serves no JSON subscription (subJsonURI empty)")
+			s.jsonUnavailable = true
+		}
+		return ""
+	}
+	s.jsonUnavailable = false
+	return settings.SubJsonURI
+}
+
+// fetchedContent subscription content and headers of one SubID
+type fetchedContent struct {
+	content string
+	headers SubscriptionHeaders
+}
@@ -1,12 +1,19 @@
 package subscription
 
 import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"xhub-agent/internal/auth"
 	"xhub-agent/pkg/logger"
 )
 
@@ -111,3 +118,163 @@ func TestExtractUniqueSubIDs(t *testing.T) {
 		t.Error("sub-id-4 should not be in result (inbound disabled)")
 	}
 }
+
+func TestGetAllSubscriptionData_RetriesDefaultSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	testLogger, err := logger.NewLogger(filepath.Join(tmpDir, "test.log"), "debug")
+	require.NoError(t, err)
+	defer testLogger.Close()
+
+	content := base64.StdEncoding.EncodeToString([]byte("vless://node"))
+	var settingsCalls int32
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/panel/setting/defaultSettings", func(w http.ResponseWriter, r *http.Request) {
+		// Panel still starting up: fail twice, then succeed
+		if atomic.AddInt32(&settingsCalls, 1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"success":true,"obj":{"subEnable":true,"subURI":"` + server.URL + `/sub/"}}`))
+	})
+	mux.HandleFunc("/panel/inbound/list", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":[{"id":1,"enable":true,"settings":"{\"clients\":[{\"email\":\"user1\",\"subId\":\"sub1\",\"enable\":true}]}"}]}`))
+	})
+	mux.HandleFunc("/sub/sub1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	})
+
+	authClient := auth.NewXUIAuth(server.URL, "admin", "password123")
+	authClient.SetSessionForTesting("test-session-token")
+
+	client := NewSubscriptionClient(authClient, "", testLogger)
+	client.SetRetryPolicy(3, time.Millisecond)
+
+	subscriptions, err := client.GetAllSubscriptionData()
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&settingsCalls))
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, "sub1", subscriptions[0].SubID)
+	assert.Equal(t, content, subscriptions[0].NodeConfig)
+
+	// With a single attempt the same failure aborts the flow
+	atomic.StoreInt32(&settingsCalls, 0)
+	client.SetRetryPolicy(1, time.Millisecond)
+	_, err = client.GetAllSubscriptionData()
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&settingsCalls))
+}
+
+func TestExtractProtocols(t *testing.T) {
+	inbounds := []*InboundInfo{
+		{ID: 1, Enable: true, Protocol: "vless"},
+		{ID: 2, Enable: true, Protocol: "vmess"},
+		{ID: 3, Enable: true, Protocol: "vless"},
+		{ID: 4, Enable: true, Protocol: "Trojan"},
+		{ID: 5, Enable: false, Protocol: "wireguard"}, // disabled, not reported
+		{ID: 6, Enable: true, Protocol: "shadowsocks"},
+		{ID: 7, Enable: true, Protocol: ""},
+		nil,
+	}
+
+	assert.Equal(t, []string{"shadowsocks", "trojan", "vless", "vmess"}, ExtractProtocols(inbounds))
+	assert.Empty(t, ExtractProtocols(nil))
+}
+
+func TestExtractCertFiles(t *testing.T) {
+	tlsStream := `{"network":"tcp","security":"tls","tlsSettings":{"certificates":[{"certificateFile":"/etc/ssl/node.crt","keyFile":"/etc/ssl/node.key"}]}}`
+	inlineStream := `{"security":"tls","tlsSettings":{"certificates":[{"certificate":["-----BEGIN CERTIFICATE-----"]}]}}`
+	inbounds := []*InboundInfo{
+		{ID: 1, Enable: true, Port: 443, StreamSettings: tlsStream},
+		{ID: 2, Enable: true, Port: 8443, StreamSettings: `{"security":"reality"}`},
+		{ID: 3, Enable: false, Port: 9443, StreamSettings: tlsStream}, // disabled, not reported
+		{ID: 4, Enable: true, Port: 2053, StreamSettings: inlineStream},
+		{ID: 5, Enable: true, Port: 2083, StreamSettings: "not json"},
+		nil,
+	}
+
+	assert.Equal(t, []InboundCertFile{{Port: 443, CertFile: "/etc/ssl/node.crt"}}, ExtractCertFiles(inbounds))
+	assert.Empty(t, ExtractCertFiles(nil))
+}
+
+func TestGetInboundList_Protocol(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":[` +
+			`{"id":1,"enable":true,"port":443,"protocol":"vless","settings":"{}"},` +
+			`{"id":2,"enable":true,"port":8443,"protocol":"trojan","settings":"{}"}]}`))
+	}))
+	defer server.Close()
+
+	testLogger, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	defer testLogger.Close()
+
+	authClient := auth.NewXUIAuth(server.URL, "admin", "password123")
+	authClient.SetSessionForTesting("test-session-token")
+	client := NewSubscriptionClient(authClient, "", testLogger)
+
+	inbounds, err := client.GetInboundList()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"trojan", "vless"}, ExtractProtocols(inbounds))
+}
+
+func TestGetAllSubscriptionData_JSON(t *testing.T) {
+	testLogger, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	defer testLogger.Close()
+
+	content := base64.StdEncoding.EncodeToString([]byte("vless://node"))
+	singBox := `{"outbounds":[{"type":"vless","server":"node.example.com"}]}`
+	var jsonCalls int32
+	var userAgent atomic.Value
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/panel/setting/defaultSettings", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":{"subEnable":true,"subURI":"` + server.URL + `/sub/","subJsonURI":"` + server.URL + `/json/"}}`))
+	})
+	mux.HandleFunc("/panel/inbound/list", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"obj":[{"id":1,"enable":true,"settings":"{\"clients\":[{\"email\":\"user1\",\"subId\":\"sub1\",\"enable\":true},{\"email\":\"user2\",\"subId\":\"sub2\",\"enable\":true}]}"}]}`))
+	})
+	mux.HandleFunc("/sub/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	})
+	mux.HandleFunc("/json/sub1", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&jsonCalls, 1)
+		userAgent.Store(r.UserAgent())
+		w.Write([]byte(singBox))
+	})
+	mux.HandleFunc("/json/sub2", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&jsonCalls, 1)
+		w.Write([]byte(`{"outbounds":`)) // Truncated
+	})
+
+	authClient := auth.NewXUIAuth(server.URL, "admin", "password123")
+	authClient.SetSessionForTesting("test-session-token")
+	client := NewSubscriptionClient(authClient, "", testLogger)
+
+	// Off by default
+	subscriptions, err := client.GetAllSubscriptionData()
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+	assert.Empty(t, subscriptions[0].JSONConfig)
+	assert.Zero(t, atomic.LoadInt32(&jsonCalls))
+
+	client.SetJSONEnabled(true)
+	subscriptions, err = client.GetAllSubscriptionData()
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+	bySubID := map[string]SubscriptionData{}
+	for _, sub := range subscriptions {
+		bySubID[sub.SubID] = sub
+	}
+	assert.Equal(t, singBox, bySubID["sub1"].JSONConfig)
+	assert.Contains(t, userAgent.Load(), "sing-box")
+	assert.Empty(t, bySubID["sub2"].JSONConfig, "invalid JSON is dropped")
+	assert.Equal(t, content, bySubID["sub2"].NodeConfig, "the base64 subscription is still reported")
+}
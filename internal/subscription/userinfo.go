@@ -0,0 +1,54 @@
+package subscription
+
+import (
+	"strconv"
+	"strings"
+)
+
+// userinfoKeys are the subscription-userinfo fields passed on to xhub
+var userinfoKeys = map[string]bool{"upload": true, "download": true, "total": true, "expire": true}
+
+// UserinfoField is one parsed subscription-userinfo field
+type UserinfoField struct {
+	Key   string
+	Value int64
+}
+
+// ParseUserinfo parses a subscription-userinfo header such as
+// "upload=0; download=1024; total=10737418240; expire=1735689600".
+// Unknown keys and values that are not non-negative int64 are dropped; the first
+// occurrence of a key wins.
+func ParseUserinfo(raw string) []UserinfoField {
+	var fields []UserinfoField
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(raw, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !userinfoKeys[key] || seen[key] {
+			continue
+		}
+
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || n < 0 {
+			continue // Overflow, garbage or negative
+		}
+		seen[key] = true
+		fields = append(fields, UserinfoField{Key: key, Value: n})
+	}
+	return fields
+}
+
+// NormalizeUserinfo re-serializes the valid fields of a subscription-userinfo header.
+// The result is always ASCII; a header without valid fields becomes "".
+func NormalizeUserinfo(raw string) string {
+	fields := ParseUserinfo(raw)
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts = append(parts, f.Key+"="+strconv.FormatInt(f.Value, 10))
+	}
+	return strings.Join(parts, "; ")
+}
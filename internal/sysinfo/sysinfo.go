@@ -0,0 +1,216 @@
+package sysinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/sanitize"
+	"xhub-agent/internal/version"
+)
+
+// DefaultGeoTimeout bounds the geo lookup of the public IP
+const DefaultGeoTimeout = 5 * time.Second
+
+// Info is the agent and host metadata attached to the status reports
+type Info struct {
+	AgentVersion   string
+	GoVersion      string
+	OS             string
+	Arch           string
+	Kernel         string // Kernel release, "" if unknown
+	Hostname       string
+	Virtualization string // kvm, xen, vmware, ..., "none" on bare metal, "" if unknown
+	Geo            *Geo   // nil without a geo lookup
+}
+
+// Geo is the location and network of the public IP of the host
+type Geo struct {
+	IP      string
+	Country string // ISO 3166-1 alpha-2 code
+	Region  string
+	City    string
+	ASN     uint32 // 0 if unknown
+	ASOrg   string
+}
+
+// Collector gathers the host metadata from a root filesystem (normally "/")
+type Collector struct {
+	root   string
+	client *http.Client
+}
+
+// NewCollector creates a collector reading /proc and /sys under root
+func NewCollector(root string) *Collector {
+	if root == "" {
+		root = "/"
+	}
+	return &Collector{root: root, client: &http.Client{Timeout: DefaultGeoTimeout}}
+}
+
+// Collect returns the metadata of the agent and the host, without the geo lookup
+func (c *Collector) Collect() Info {
+	hostname, _ := os.Hostname()
+	return Info{
+		AgentVersion:   version.Version,
+		GoVersion:      runtime.Version(),
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		Kernel:         c.readFile("proc/sys/kernel/osrelease"),
+		Hostname:       hostname,
+		Virtualization: c.Virtualization(),
+	}
+}
+
+// dmiVendors maps DMI vendor or product names to the hypervisor they identify
+var dmiVendors = []struct{ match, virt string }{
+	{"kvm", "kvm"},
+	{"qemu", "kvm"},
+	{"amazon ec2", "kvm"},
+	{"google compute engine", "kvm"},
+	{"digitalocean", "kvm"},
+	{"hetzner", "kvm"},
+	{"openstack", "kvm"},
+	{"vmware", "vmware"},
+	{"virtualbox", "virtualbox"},
+	{"innotek", "virtualbox"},
+	{"xen", "xen"},
+	{"microsoft corporation virtual machine", "hyperv"},
+	{"parallels", "parallels"},
+	{"bochs", "bochs"},
+}
+
+// Virtualization returns the container or hypervisor the host runs in, "none" on bare metal
+// and "" when it cannot be told (no readable /proc/cpuinfo)
+func (c *Collector) Virtualization() string {
+	// Containers first: they report the DMI data of their host
+	switch {
+	case c.exists(".dockerenv"):
+		return "docker"
+	case c.exists("run/.containerenv"):
+		return "podman"
+	}
+	if container := c.readFile("run/systemd/container"); container != "" {
+		return container
+	}
+	if c.exists("proc/vz") && !c.exists("proc/bc") {
+		return "openvz"
+	}
+
+	dmi := strings.ToLower(c.readFile("sys/class/dmi/id/sys_vendor") + " " + c.readFile("sys/class/dmi/id/product_name"))
+	for _, vendor := range dmiVendors {
+		if strings.Contains(dmi, vendor.match) {
+			return vendor.virt
+		}
+	}
+	if c.exists("proc/xen") {
+		return "xen"
+	}
+
+	cpuinfo, err := os.ReadFile(filepath.Join(c.root, "proc/cpuinfo"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(cpuinfo), "\n") {
+		if key, flags, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) == "flags" {
+			if strings.Contains(" "+flags+" ", " hypervisor ") {
+				return "vm" // Virtualized, hypervisor not identified
+			}
+		}
+	}
+	return "none"
+}
+
+// geoResponse is the JSON of an ipinfo.io style (ip, country, org) or ip-api.com style
+// (query, countryCode, as) geo lookup service
+type geoResponse struct {
+	IP          string `json:"ip"`
+	Query       string `json:"query"`
+	Country     string `json:"country"`
+	CountryCode string `json:"countryCode"`
+	Region      string `json:"region"`
+	RegionName  string `json:"regionName"`
+	City        string `json:"city"`
+	Org         string `json:"org"`
+	AS          string `json:"as"`
+}
+
+// asPattern splits "AS13335 Cloudflare, Inc." into the number and the organization
+var asPattern = regexp.MustCompile(`^AS(\d+)\s*(.*)$`)
+
+// LookupGeo looks up the location and autonomous system of the public IP of the host with
+// the JSON service at geoURL, which sees the IP the request comes from
+func (c *Collector) LookupGeo(ctx context.Context, geoURL string) (*Geo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", geoURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geo lookup request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("geo lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geo lookup failed, HTTP status code: %d", resp.StatusCode)
+	}
+
+	body, err := sanitize.ReadLimited(resp.Body, sanitize.MaxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read geo lookup response: %w", err)
+	}
+	var decoded geoResponse
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, fmt.Errorf("invalid geo lookup response: %w", err)
+	}
+
+	geo := &Geo{
+		IP:      firstOf(decoded.IP, decoded.Query),
+		Country: firstOf(decoded.CountryCode, decoded.Country),
+		Region:  firstOf(decoded.RegionName, decoded.Region),
+		City:    decoded.City,
+	}
+	if match := asPattern.FindStringSubmatch(firstOf(decoded.AS, decoded.Org)); match != nil {
+		if asn, err := strconv.ParseUint(match[1], 10, 32); err == nil {
+			geo.ASN = uint32(asn)
+		}
+		geo.ASOrg = match[2]
+	}
+	if geo.IP == "" && geo.Country == "" && geo.ASN == 0 {
+		return nil, fmt.Errorf("geo lookup response has no IP, country or AS")
+	}
+	return geo, nil
+}
+
+// readFile returns the trimmed content of a file under root, "" if unreadable
+func (c *Collector) readFile(name string) string {
+	data, err := os.ReadFile(filepath.Join(c.root, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// exists reports whether a file exists under root
+func (c *Collector) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(c.root, name))
+	return err == nil
+}
+
+// firstOf returns the first non-empty value
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package sysinfo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/version"
+)
+
+// fakeRoot creates a root filesystem holding files (path relative to the root -> content)
+func fakeRoot(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return root
+}
+
+const bareMetalCPUInfo = "processor\t: 0\nflags\t\t: fpu vme de pse tsc msr pae\n"
+const guestCPUInfo = "processor\t: 0\nflags\t\t: fpu vme de pse tsc msr pae hypervisor lahf_lm\n"
+
+func TestCollector_Virtualization(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"docker", map[string]string{".dockerenv": "", "sys/class/dmi/id/sys_vendor": "QEMU"}, "docker"},
+		{"podman", map[string]string{"run/.containerenv": ""}, "podman"},
+		{"systemd container", map[string]string{"run/systemd/container": "lxc\n"}, "lxc"},
+		{"openvz", map[string]string{"proc/vz/version": "", "proc/cpuinfo": guestCPUInfo}, "openvz"},
+		{"openvz host", map[string]string{"proc/vz/version": "", "proc/bc/0": "", "proc/cpuinfo": bareMetalCPUInfo}, "none"},
+		{"kvm", map[string]string{"sys/class/dmi/id/sys_vendor": "QEMU\n", "sys/class/dmi/id/product_name": "Standard PC (i440FX + PIIX, 1996)"}, "kvm"},
+		{"ec2", map[string]string{"sys/class/dmi/id/sys_vendor": "Amazon EC2", "sys/class/dmi/id/product_name": "t3.micro"}, "kvm"},
+		{"vmware", map[string]string{"sys/class/dmi/id/sys_vendor": "VMware, Inc."}, "vmware"},
+		{"hyperv", map[string]string{"sys/class/dmi/id/sys_vendor": "Microsoft Corporation", "sys/class/dmi/id/product_name": "Virtual Machine"}, "hyperv"},
+		{"xen pv", map[string]string{"proc/xen/capabilities": ""}, "xen"},
+		{"unidentified hypervisor", map[string]string{"proc/cpuinfo": guestCPUInfo}, "vm"},
+		{"bare metal", map[string]string{"sys/class/dmi/id/sys_vendor": "Dell Inc.", "proc/cpuinfo": bareMetalCPUInfo}, "none"},
+		{"unknown", map[string]string{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewCollector(fakeRoot(t, tt.files)).Virtualization())
+		})
+	}
+}
+
+func TestCollector_Collect(t *testing.T) {
+	root := fakeRoot(t, map[string]string{
+		"proc/sys/kernel/osrelease":   "6.1.0-18-amd64\n",
+		"sys/class/dmi/id/sys_vendor": "QEMU",
+	})
+
+	info := NewCollector(root).Collect()
+	assert.Equal(t, version.Version, info.AgentVersion)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.Equal(t, runtime.GOOS, info.OS)
+	assert.Equal(t, runtime.GOARCH, info.Arch)
+	assert.Equal(t, "6.1.0-18-amd64", info.Kernel)
+	assert.NotEmpty(t, info.Hostname)
+	assert.Equal(t, "kvm", info.Virtualization)
+	assert.Nil(t, info.Geo, "the geo lookup is separate")
+}
+
+func TestCollector_LookupGeo(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Geo
+	}{
+		{
+			name: "ipinfo",
+			body: `{"ip":"203.0.113.7","city":"Frankfurt am Main","region":"Hesse","country":"DE","org":"AS24940 Hetzner Online GmbH"}`,
+			want: Geo{IP: "203.0.113.7", Country: "DE", Region: "Hesse", City: "Frankfurt am Main", ASN: 24940, ASOrg: "Hetzner Online GmbH"},
+		},
+		{
+			name: "ip-api",
+			body: `{"status":"success","query":"203.0.113.7","country":"Japan","countryCode":"JP","regionName":"Tokyo","city":"Tokyo","isp":"Vultr","as":"AS20473 The Constant Company, LLC"}`,
+			want: Geo{IP: "203.0.113.7", Country: "JP", Region: "Tokyo", City: "Tokyo", ASN: 20473, ASOrg: "The Constant Company, LLC"},
+		},
+		{
+			name: "no AS",
+			body: `{"ip":"203.0.113.7","country":"US"}`,
+			want: Geo{IP: "203.0.113.7", Country: "US"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			geo, err := NewCollector("").LookupGeo(context.Background(), server.URL)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *geo)
+		})
+	}
+}
+
+func TestCollector_LookupGeo_Errors(t *testing.T) {
+	var status int
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	collector := NewCollector("")
+
+	status, body = http.StatusTooManyRequests, `{"error":"rate limited"}`
+	_, err := collector.LookupGeo(context.Background(), server.URL)
+	assert.ErrorContains(t, err, "429")
+
+	status, body = http.StatusOK, `<html>`
+	_, err = collector.LookupGeo(context.Background(), server.URL)
+	assert.ErrorContains(t, err, "invalid geo lookup response")
+
+	status, body = http.StatusOK, `{"status":"fail","message":"reserved range"}`
+	_, err = collector.LookupGeo(context.Background(), server.URL)
+	assert.ErrorContains(t, err, "no IP, country or AS")
+}
@@ -0,0 +1,192 @@
+package tuic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/nodeprovider"
+	"xhub-agent/internal/sanitize"
+	"xhub-agent/internal/sharelink"
+)
+
+// Defaults of the official tuic-server installation
+const (
+	DefaultConfigPath = "/etc/tuic/config.json"
+	DefaultNodeName   = "TUIC"
+)
+
+// maxConfigSize caps the TUIC config and users file reads (1 MiB)
+const maxConfigSize = 1 << 20
+
+// Config is the part of a tuic-server (TUIC v5) JSON config needed for share links
+type Config struct {
+	Server            string            `json:"server"` // Listen address, e.g. "[::]:443"
+	Users             map[string]string `json:"users"`  // Password by user UUID
+	Certificate       string            `json:"certificate"`
+	CongestionControl string            `json:"congestion_control"` // cubic (default), new_reno or bbr
+	ALPN              []string          `json:"alpn"`
+}
+
+// ProtocolName is the protocol of TUIC nodes, as reported in the inbound protocols
+const ProtocolName = "tuic"
+
+// Options configure the TUIC node
+type Options struct {
+	Enabled    bool   // tuic_enabled
+	ConfigPath string // tuic-server config, default DefaultConfigPath
+	NodeName   string // Node display name, default DefaultNodeName
+	ServerAddr string // External server address (domain or IP)
+	Insecure   bool   // Skip TLS verification
+	UsersFile  string // User UUID per subscription email (YAML map), needed with several users
+}
+
+// Client builds the share links of a tuic-server
+type Client struct {
+	options Options
+}
+
+// NewClient creates a TUIC client
+func NewClient(options Options) *Client {
+	if options.ConfigPath == "" {
+		options.ConfigPath = DefaultConfigPath
+	}
+	if options.NodeName == "" {
+		options.NodeName = DefaultNodeName
+	}
+	return &Client{options: options}
+}
+
+// Name returns the protocol of the TUIC node provider
+func (c *Client) Name() string {
+	return ProtocolName
+}
+
+// Enabled returns whether TUIC support is enabled
+func (c *Client) Enabled() bool {
+	return c.options.Enabled
+}
+
+// CollectStatus adds tuic to the inbound protocols: it runs outside 3x-ui, so it never
+// shows up in the inbound list
+func (c *Client) CollectStatus() collector.Apply {
+	return nodeprovider.ProtocolStatus(ProtocolName)
+}
+
+// BuildShareLinks builds the share links of the TUIC node
+func (c *Client) BuildShareLinks() (nodeprovider.ShareLinks, error) {
+	nodes, err := c.Nodes()
+	return nodeprovider.ShareLinks{Nodes: nodes}, err
+}
+
+// ParseConfig reads and parses the tuic-server config file
+func (c *Client) ParseConfig() (*Config, error) {
+	data, err := readFile(c.options.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tuic config file %s: %w", c.options.ConfigPath, err)
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig decodes a tuic-server JSON config
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse tuic config: %w", err)
+	}
+	if len(config.Users) == 0 {
+		return nil, fmt.Errorf("tuic config without users")
+	}
+	return &config, nil
+}
+
+// BuildURI constructs the tuic:// share link of a user
+func (c *Client) BuildURI(config *Config, uuid, password string) (string, error) {
+	_, port, err := net.SplitHostPort(config.Server)
+	if n, convErr := strconv.Atoi(port); err != nil || convErr != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid server address in tuic config: %q", sanitize.String(config.Server))
+	}
+	if c.options.ServerAddr == "" {
+		return "", fmt.Errorf("server address is not configured")
+	}
+
+	params := url.Values{}
+	congestionControl := config.CongestionControl
+	if congestionControl == "" {
+		congestionControl = "cubic"
+	}
+	params.Set("congestion_control", congestionControl)
+	if len(config.ALPN) > 0 {
+		params.Set("alpn", strings.Join(config.ALPN, ","))
+	}
+	params.Set("udp_relay_mode", "native")
+	if net.ParseIP(strings.Trim(c.options.ServerAddr, "[]")) == nil {
+		params.Set("sni", c.options.ServerAddr)
+	}
+	if c.options.Insecure {
+		params.Set("allow_insecure", "1")
+	}
+
+	return fmt.Sprintf("tuic://%s:%s@%s/?%s#%s",
+		sharelink.EscapeUser(uuid), sharelink.EscapeAuth(password),
+		net.JoinHostPort(strings.Trim(c.options.ServerAddr, "[]"), port),
+		params.Encode(), url.PathEscape(c.options.NodeName)), nil
+}
+
+// Nodes builds the share links of the TUIC node: one shared link when the config has a single
+// user and no users file is set, else one link per subscription email of the users file
+func (c *Client) Nodes() (sharelink.Nodes, error) {
+	config, err := c.ParseConfig()
+	if err != nil {
+		return sharelink.Nodes{}, err
+	}
+
+	if c.options.UsersFile == "" {
+		if len(config.Users) > 1 {
+			return sharelink.Nodes{}, fmt.Errorf("tuic config with %d users requires tuic_users_file", len(config.Users))
+		}
+		for uuid, password := range config.Users {
+			uri, err := c.BuildURI(config, uuid, password)
+			return sharelink.Nodes{Shared: uri}, err
+		}
+	}
+
+	data, err := readFile(c.options.UsersFile)
+	if err != nil {
+		return sharelink.Nodes{}, fmt.Errorf("failed to read tuic users file %s: %w", c.options.UsersFile, err)
+	}
+	var users map[string]string // User UUID by email
+	if err := yaml.Unmarshal(data, &users); err != nil {
+		return sharelink.Nodes{}, fmt.Errorf("failed to parse tuic users file %s: %w", c.options.UsersFile, err)
+	}
+	nodes := sharelink.Nodes{PerUser: make(map[string]string, len(users))}
+	for email, uuid := range users {
+		password, ok := config.Users[uuid]
+		if !ok {
+			return sharelink.Nodes{}, fmt.Errorf("tuic users file maps %s to unknown user %s", sanitize.String(email), sanitize.String(uuid))
+		}
+		uri, err := c.BuildURI(config, uuid, password)
+		if err != nil {
+			return sharelink.Nodes{}, err
+		}
+		nodes.PerUser[email] = uri
+	}
+	return nodes, nil
+}
+
+// readFile reads at most maxConfigSize bytes of path
+func readFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return sanitize.ReadLimited(f, maxConfigSize)
+}
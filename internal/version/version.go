@@ -0,0 +1,6 @@
+// Package version holds the agent version, set at build time with
+// -ldflags "-X xhub-agent/internal/version.Version=v1.2.3"
+package version
+
+// Version is the agent version reported in the banner and the heartbeat
+var Version = "v1.0.0"
@@ -0,0 +1,133 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"xhub-agent/internal/runner"
+)
+
+// Watchdog defaults
+const (
+	DefaultService     = "x-ui"           // systemd unit of the 3x-ui installer
+	DefaultFailures    = 3                // Consecutive failed status requests before a restart
+	DefaultCooldown    = 10 * time.Minute // Between two restarts
+	DefaultMaxRestarts = 3                // Restarts until the panel answers again
+	restartTimeout     = 60 * time.Second // Longest a service restart may take
+)
+
+// Config selects when the watchdog restarts the panel
+type Config struct {
+	Service     string        // systemd unit, default DefaultService
+	Failures    int           // Consecutive failures before a restart, default DefaultFailures
+	Cooldown    time.Duration // Minimum time between restarts, default DefaultCooldown
+	MaxRestarts int           // Restarts without the panel recovering, default DefaultMaxRestarts
+}
+
+// Restart is a panel restart done by the watchdog
+type Restart struct {
+	Service   string
+	Failures  int    // Consecutive failures that triggered it
+	LastError string // Error of the last failed status request
+	Attempt   int    // 1 for the first restart since the panel last answered
+	StartedAt time.Time
+	Duration  time.Duration
+	Error     string // Why systemctl failed, empty on success
+}
+
+// Watchdog counts the consecutive failures of the 3x-ui status requests and restarts the
+// panel service when they reach the threshold. Restarts are spaced by the cooldown and stop
+// after MaxRestarts until the panel answers again.
+type Watchdog struct {
+	config Config
+	run    runner.Func // injectable for tests
+	now    func() time.Time
+
+	mutex       sync.Mutex
+	failures    int
+	restarts    int // Since the panel last answered
+	lastRestart time.Time
+	gaveUp      bool // MaxRestarts reached, logged once
+}
+
+// New creates a watchdog, applying the defaults to the zero fields of config
+func New(config Config) *Watchdog {
+	if config.Service == "" {
+		config.Service = DefaultService
+	}
+	if config.Failures <= 0 {
+		config.Failures = DefaultFailures
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = DefaultCooldown
+	}
+	if config.MaxRestarts <= 0 {
+		config.MaxRestarts = DefaultMaxRestarts
+	}
+	return &Watchdog{config: config, run: runner.Run, now: time.Now}
+}
+
+// SetRunnerForTesting replaces the command runner (for testing only)
+func (w *Watchdog) SetRunnerForTesting(run runner.Func) {
+	w.run = run
+}
+
+// SetClockForTesting replaces the clock (for testing only)
+func (w *Watchdog) SetClockForTesting(now func() time.Time) {
+	w.now = now
+}
+
+// Config returns the settings of the watchdog, defaults applied
+func (w *Watchdog) Config() Config {
+	return w.config
+}
+
+// Success records a status request the panel answered, resetting the failure and restart counts
+func (w *Watchdog) Success() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.failures, w.restarts, w.gaveUp = 0, 0, false
+}
+
+// Failure records a failed status request. It reports whether a restart is due and, when
+// it is, whether MaxRestarts was just reached (exhausted) instead.
+func (w *Watchdog) Failure() (due, exhausted bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.failures++
+	if w.failures < w.config.Failures {
+		return false, false
+	}
+	if w.restarts >= w.config.MaxRestarts {
+		exhausted, w.gaveUp = !w.gaveUp, true
+		return false, exhausted
+	}
+	if !w.lastRestart.IsZero() && w.now().Sub(w.lastRestart) < w.config.Cooldown {
+		return false, false
+	}
+	return true, false
+}
+
+// Restart restarts the panel service. lastErr is the failure that made it due.
+func (w *Watchdog) Restart(ctx context.Context, lastErr error) Restart {
+	w.mutex.Lock()
+	w.restarts++
+	w.lastRestart = w.now()
+	restart := Restart{Service: w.config.Service, Failures: w.failures, Attempt: w.restarts, StartedAt: w.lastRestart}
+	w.failures = 0 // Give the restarted panel a fresh threshold
+	w.mutex.Unlock()
+	if lastErr != nil {
+		restart.LastError = lastErr.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, restartTimeout)
+	defer cancel()
+	if out, err := w.run(ctx, "systemctl", "restart", w.config.Service); err != nil {
+		restart.Error = fmt.Sprintf("systemctl restart %s failed: %v (%s)", w.config.Service, err, strings.TrimSpace(runner.Tail(string(out), runner.MaxOutput)))
+	}
+	restart.Duration = w.now().Sub(restart.StartedAt)
+	return restart
+}
@@ -0,0 +1,77 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdog_Defaults(t *testing.T) {
+	w := New(Config{})
+	assert.Equal(t, Config{Service: DefaultService, Failures: DefaultFailures, Cooldown: DefaultCooldown, MaxRestarts: DefaultMaxRestarts}, w.Config())
+}
+
+func TestWatchdog_RestartAfterFailures(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := New(Config{Service: "x-ui", Failures: 3, Cooldown: 10 * time.Minute, MaxRestarts: 2})
+	w.SetClockForTesting(func() time.Time { return now })
+	var calls [][]string
+	w.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return nil, nil
+	})
+
+	due, _ := w.Failure()
+	assert.False(t, due)
+	due, _ = w.Failure()
+	assert.False(t, due)
+	due, _ = w.Failure()
+	require.True(t, due)
+
+	restart := w.Restart(context.Background(), errors.New("connection refused"))
+	assert.Equal(t, [][]string{{"systemctl", "restart", "x-ui"}}, calls)
+	assert.Equal(t, Restart{Service: "x-ui", Failures: 3, LastError: "connection refused", Attempt: 1, StartedAt: now}, restart)
+
+	// The restarted panel gets a fresh threshold, then waits for the cooldown
+	for i := 0; i < 3; i++ {
+		due, _ = w.Failure()
+		assert.False(t, due)
+	}
+	now = now.Add(10 * time.Minute)
+	due, _ = w.Failure()
+	require.True(t, due)
+	assert.Equal(t, 2, w.Restart(context.Background(), nil).Attempt)
+
+	// MaxRestarts reached: reported once, then nothing until the panel answers
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		due, _ = w.Failure()
+		assert.False(t, due)
+	}
+	due, exhausted := w.Failure()
+	assert.False(t, due)
+	assert.True(t, exhausted)
+	_, exhausted = w.Failure()
+	assert.False(t, exhausted)
+
+	w.Success()
+	for i := 0; i < 2; i++ {
+		w.Failure()
+	}
+	due, _ = w.Failure()
+	assert.True(t, due)
+}
+
+func TestWatchdog_RestartFailure(t *testing.T) {
+	w := New(Config{Service: "x-ui"})
+	w.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("Failed to restart x-ui.service: Unit x-ui.service not found.\n"), errors.New("exit status 5")
+	})
+
+	restart := w.Restart(context.Background(), nil)
+	assert.Equal(t, "systemctl restart x-ui failed: exit status 5 (Failed to restart x-ui.service: Unit x-ui.service not found.)", restart.Error)
+}
@@ -0,0 +1,75 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"xhub-agent/internal/runner"
+)
+
+// DefaultXrayCycles is the number of report cycles Xray may be not running before a restart
+const DefaultXrayCycles = 3
+
+// XraySupervisor counts the report cycles in which Xray is not running and tells when to
+// restart it. Restarts go through the panel; service, when set, is the systemd unit
+// restarted when the panel cannot do it (e.g. "x-ui", which starts Xray with it).
+type XraySupervisor struct {
+	cycles  int
+	service string
+	run     runner.Func // injectable for tests
+
+	mutex sync.Mutex
+	down  int // Consecutive cycles Xray was not running, since the last restart
+}
+
+// NewXraySupervisor creates a supervisor restarting Xray after cycles cycles (default
+// DefaultXrayCycles), falling back to a restart of service ("" for none)
+func NewXraySupervisor(cycles int, service string) *XraySupervisor {
+	if cycles <= 0 {
+		cycles = DefaultXrayCycles
+	}
+	return &XraySupervisor{cycles: cycles, service: service, run: runner.Run}
+}
+
+// SetRunnerForTesting replaces the command runner (for testing only)
+func (s *XraySupervisor) SetRunnerForTesting(run runner.Func) {
+	s.run = run
+}
+
+// Service returns the systemd unit restarted when the panel cannot restart Xray
+func (s *XraySupervisor) Service() string {
+	return s.service
+}
+
+// Observe records the Xray state of a report cycle. It returns the consecutive cycles Xray
+// was not running and whether a restart is due, which starts a new count.
+func (s *XraySupervisor) Observe(running bool) (down int, due bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if running {
+		s.down = 0
+		return 0, false
+	}
+	s.down++
+	down = s.down
+	if down < s.cycles {
+		return down, false
+	}
+	s.down = 0
+	return down, true
+}
+
+// RestartService restarts the fallback systemd unit
+func (s *XraySupervisor) RestartService(ctx context.Context) error {
+	if s.service == "" {
+		return fmt.Errorf("no xray_restart_service configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, restartTimeout)
+	defer cancel()
+	if out, err := s.run(ctx, "systemctl", "restart", s.service); err != nil {
+		return fmt.Errorf("systemctl restart %s failed: %w (%s)", s.service, err, strings.TrimSpace(runner.Tail(string(out), runner.MaxOutput)))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package watchdog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXraySupervisor_Observe(t *testing.T) {
+	s := NewXraySupervisor(2, "")
+
+	down, due := s.Observe(false)
+	assert.Equal(t, 1, down)
+	assert.False(t, due)
+	down, due = s.Observe(true)
+	assert.Equal(t, 0, down)
+	assert.False(t, due)
+
+	s.Observe(false)
+	down, due = s.Observe(false)
+	assert.Equal(t, 2, down)
+	assert.True(t, due)
+
+	// A restart starts a new count
+	down, due = s.Observe(false)
+	assert.Equal(t, 1, down)
+	assert.False(t, due)
+}
+
+func TestXraySupervisor_RestartService(t *testing.T) {
+	assert.EqualError(t, NewXraySupervisor(0, "").RestartService(context.Background()), "no xray_restart_service configured")
+
+	s := NewXraySupervisor(0, "x-ui")
+	var calls [][]string
+	s.SetRunnerForTesting(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return nil, nil
+	})
+	require.NoError(t, s.RestartService(context.Background()))
+	assert.Equal(t, [][]string{{"systemctl", "restart", "x-ui"}}, calls)
+}
@@ -0,0 +1,56 @@
+package wireguard
+
+import (
+	"xhub-agent/internal/collector"
+	"xhub-agent/internal/monitor"
+	"xhub-agent/internal/nodeprovider"
+	"xhub-agent/pkg/logger"
+)
+
+// Provider reports the WireGuard interfaces (collect_wireguard) and builds the client config
+// stubs of the hub-managed peers (wireguard_peers_file)
+type Provider struct {
+	collector *Collector
+	collect   bool        // Report the interfaces with the status
+	stubs     StubOptions // PeersFile "" without stubs
+}
+
+// NewProvider creates the WireGuard node provider
+func NewProvider(collect bool, stubs StubOptions, logger *logger.Logger) *Provider {
+	return &Provider{collector: NewCollector(logger), collect: collect, stubs: stubs}
+}
+
+// Collector returns the collector running wg
+func (p *Provider) Collector() *Collector {
+	return p.collector
+}
+
+// Name returns the protocol of the WireGuard node provider, also its collector name
+func (p *Provider) Name() string {
+	return CollectorName
+}
+
+// Enabled returns whether the interfaces are reported or client config stubs are built
+func (p *Provider) Enabled() bool {
+	return p.collect || p.stubs.PeersFile != ""
+}
+
+// CollectStatus collects the WireGuard interfaces when collect_wireguard is set. wireguard
+// is not added to the inbound protocols, which name 3x-ui wireguard inbounds.
+func (p *Provider) CollectStatus() collector.Apply {
+	if !p.collect {
+		return nil
+	}
+	interfaces := p.collector.Collect()
+	return func(data *monitor.ServerStatusData) { data.WireGuard = interfaces }
+}
+
+// BuildShareLinks builds the client config stubs per subscription email, none without a
+// peers file
+func (p *Provider) BuildShareLinks() (nodeprovider.ShareLinks, error) {
+	if p.stubs.PeersFile == "" {
+		return nodeprovider.ShareLinks{WireGuardConfig: true}, nil
+	}
+	configs, err := p.collector.ClientConfigs(p.stubs)
+	return nodeprovider.ShareLinks{Nodes: configs, WireGuardConfig: true}, err
+}
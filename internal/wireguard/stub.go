@@ -0,0 +1,131 @@
+package wireguard
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"xhub-agent/internal/sanitize"
+	"xhub-agent/internal/sharelink"
+)
+
+// Placeholders of the client secrets, which only xhub knows
+const (
+	PrivateKeyPlaceholder   = "<client private key>"
+	PresharedKeyPlaceholder = "<preshared key>"
+)
+
+// maxPeersFileSize caps the wireguard_peers_file read (1 MiB)
+const maxPeersFileSize = 1 << 20
+
+// StubOptions configure the client config stubs of hub-managed peers
+type StubOptions struct {
+	Interface string // Interface of the peers, "" when the host has a single one
+	Endpoint  string // External server address (domain or IP)
+	DNS       string // DNS servers of the clients, e.g. "1.1.1.1, 2606:4700:4700::1111"
+	PeersFile string // Peer public key per subscription email (YAML map)
+}
+
+// ClientConfigs builds the client config stub of every subscription email of the peers file
+// whose peer is configured on the interface. Stubs are keyed by email like per-user share links.
+func (c *Collector) ClientConfigs(options StubOptions) (sharelink.Nodes, error) {
+	peers, err := readPeersFile(options.PeersFile)
+	if err != nil {
+		return sharelink.Nodes{}, err
+	}
+	devices, err := c.Devices()
+	if err != nil {
+		return sharelink.Nodes{}, err
+	}
+	device, err := selectDevice(devices, options.Interface)
+	if err != nil {
+		return sharelink.Nodes{}, err
+	}
+
+	byKey := make(map[string]Peer, len(device.Peers))
+	for _, peer := range device.Peers {
+		byKey[peer.PublicKey] = peer
+	}
+	configs := sharelink.Nodes{PerUser: make(map[string]string, len(peers))}
+	for email, publicKey := range peers {
+		peer, ok := byKey[publicKey]
+		if !ok {
+			continue // Not provisioned yet, no stub
+		}
+		stub, err := BuildClientConfig(device, peer, options)
+		if err != nil {
+			return sharelink.Nodes{}, err
+		}
+		configs.PerUser[email] = stub
+	}
+	return configs, nil
+}
+
+// BuildClientConfig builds the wg-quick config of a peer, with placeholders for its secrets
+func BuildClientConfig(device Device, peer Peer, options StubOptions) (string, error) {
+	if options.Endpoint == "" {
+		return "", fmt.Errorf("wireguard endpoint is not configured")
+	}
+	if device.ListenPort < 1 || device.ListenPort > 65535 {
+		return "", fmt.Errorf("wireguard interface %s has no listen port", device.Name)
+	}
+
+	var b strings.Builder
+	b.WriteString("[Interface]\n")
+	b.WriteString("PrivateKey = " + PrivateKeyPlaceholder + "\n")
+	if len(peer.AllowedIPs) > 0 {
+		b.WriteString("Address = " + strings.Join(peer.AllowedIPs, ", ") + "\n")
+	}
+	if options.DNS != "" {
+		b.WriteString("DNS = " + options.DNS + "\n")
+	}
+	b.WriteString("\n[Peer]\n")
+	b.WriteString("PublicKey = " + device.PublicKey + "\n")
+	if peer.PresharedKey {
+		b.WriteString("PresharedKey = " + PresharedKeyPlaceholder + "\n")
+	}
+	b.WriteString("Endpoint = " + net.JoinHostPort(strings.Trim(options.Endpoint, "[]"), strconv.Itoa(device.ListenPort)) + "\n")
+	b.WriteString("AllowedIPs = 0.0.0.0/0, ::/0\n")
+	if peer.Keepalive > 0 {
+		b.WriteString("PersistentKeepalive = " + strconv.Itoa(peer.Keepalive) + "\n")
+	}
+	return b.String(), nil
+}
+
+// selectDevice returns the named interface, or the only one when name is empty
+func selectDevice(devices []Device, name string) (Device, error) {
+	if name == "" {
+		if len(devices) != 1 {
+			return Device{}, fmt.Errorf("%d wireguard interfaces, set wireguard_interface", len(devices))
+		}
+		return devices[0], nil
+	}
+	for _, device := range devices {
+		if device.Name == name {
+			return device, nil
+		}
+	}
+	return Device{}, fmt.Errorf("wireguard interface %s not found", name)
+}
+
+// readPeersFile reads the peer public key per subscription email
+func readPeersFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wireguard peers file %s: %w", path, err)
+	}
+	defer f.Close()
+	data, err := sanitize.ReadLimited(f, maxPeersFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wireguard peers file %s: %w", path, err)
+	}
+	var peers map[string]string
+	if err := yaml.Unmarshal(data, &peers); err != nil {
+		return nil, fmt.Errorf("failed to parse wireguard peers file %s: %w", path, err)
+	}
+	return peers, nil
+}
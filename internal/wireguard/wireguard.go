@@ -0,0 +1,236 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// CollectorName is the collector registry name of the WireGuard status, collected every cycle
+// (a single wg call) unless collector_intervals overrides it
+const CollectorName = "wireguard"
+
+// ActiveHandshake is the longest time since the latest handshake of an active peer: a peer
+// with traffic rekeys every 2 minutes, and a session is rejected after 3
+const ActiveHandshake = 3 * time.Minute
+
+// Collection limits
+const (
+	commandTimeout = 5 * time.Second // Bounds a single wg invocation
+	maxPeerStats   = 256             // Peers detailed per interface, counts cover all of them
+)
+
+// DumpSource returns the output of "wg show all dump"
+type DumpSource interface {
+	Dump(ctx context.Context) (string, error)
+}
+
+// wgSource queries the kernel through the wg tool
+type wgSource struct{}
+
+// Dump runs wg show all dump (needs CAP_NET_ADMIN)
+func (wgSource) Dump(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "wg", "show", "all", "dump").Output()
+	if err != nil {
+		return "", fmt.Errorf("wg show all dump: %w", err)
+	}
+	return string(out), nil
+}
+
+// Peer is a WireGuard peer of an interface
+type Peer struct {
+	PublicKey       string
+	PresharedKey    bool     // A preshared key is configured (its value is never kept)
+	AllowedIPs      []string // e.g. ["10.0.0.2/32"]
+	LatestHandshake int64    // Unix seconds, 0 when never
+	RxBytes         uint64
+	TxBytes         uint64
+	Keepalive       int // Persistent keepalive in seconds, 0 when off
+}
+
+// Device is a WireGuard interface and its peers; the private key is never kept
+type Device struct {
+	Name       string
+	PublicKey  string
+	ListenPort int
+	Peers      []Peer
+}
+
+// Collector reports the WireGuard interfaces of the host through wg
+type Collector struct {
+	source DumpSource
+	logger *logger.Logger
+	now    func() time.Time // injectable for tests
+
+	available func() bool // Whether wg is installed (injectable for tests)
+	warned    bool        // Unavailability has been logged
+}
+
+// NewCollector creates a collector running wg
+func NewCollector(logger *logger.Logger) *Collector {
+	return &Collector{
+		source:    wgSource{},
+		logger:    logger,
+		now:       time.Now,
+		available: installed,
+	}
+}
+
+// installed checks that the wg tool exists
+func installed() bool {
+	_, err := exec.LookPath("wg")
+	return err == nil
+}
+
+// SetSourceForTesting replaces wg and the clock (for testing only)
+func (c *Collector) SetSourceForTesting(source DumpSource, now func() time.Time) {
+	c.source = source
+	c.now = now
+	c.available = func() bool { return true }
+}
+
+// Devices returns the current WireGuard interfaces sorted by name
+func (c *Collector) Devices() ([]Device, error) {
+	if !c.available() {
+		return nil, fmt.Errorf("wg not installed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	dump, err := c.source.Dump(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDump(dump)
+}
+
+// Collect returns the interfaces with their peer counts, handshake freshness and transfer.
+// It returns nil when wg is not installed or fails.
+func (c *Collector) Collect() []monitor.WireGuardInterface {
+	devices, err := c.Devices()
+	if err != nil {
+		if !c.warned {
+			c.logger.Debugf("WireGuard not available, skipping interface statistics: %v", err)
+			c.warned = true
+		}
+		return nil
+	}
+	c.warned = false
+
+	now := c.now().Unix()
+	var result []monitor.WireGuardInterface
+	for _, device := range devices {
+		iface := monitor.WireGuardInterface{
+			Name:       device.Name,
+			PublicKey:  device.PublicKey,
+			ListenPort: device.ListenPort,
+			Peers:      len(device.Peers),
+		}
+		for i, peer := range device.Peers {
+			if peer.LatestHandshake > 0 && now-peer.LatestHandshake <= int64(ActiveHandshake/time.Second) {
+				iface.ActivePeers++
+			}
+			iface.RxBytes += peer.RxBytes
+			iface.TxBytes += peer.TxBytes
+			if i < maxPeerStats {
+				iface.PeerStats = append(iface.PeerStats, monitor.WireGuardPeer{
+					PublicKey:       peer.PublicKey,
+					LatestHandshake: peer.LatestHandshake,
+					RxBytes:         peer.RxBytes,
+					TxBytes:         peer.TxBytes,
+				})
+			}
+		}
+		result = append(result, iface)
+	}
+	return result
+}
+
+// ParseDump parses "wg show all dump": one tab-separated line per interface
+//
+//	wg0	<private-key>	<public-key>	51820	off
+//
+// followed by one line per peer
+//
+//	wg0	<public-key>	<preshared-key>	<endpoint>	<allowed-ips>	<latest-handshake>	<rx>	<tx>	<keepalive>
+//
+// Interfaces are sorted by name and peers by public key.
+func ParseDump(dump string) ([]Device, error) {
+	devices := map[string]*Device{}
+	for lineNo, line := range strings.Split(dump, "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		switch len(fields) {
+		case 5:
+			port, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid listen port %q", lineNo+1, fields[3])
+			}
+			device := deviceOf(devices, fields[0])
+			device.PublicKey = fields[2]
+			device.ListenPort = port
+		case 9:
+			peer, err := parsePeer(fields)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo+1, err)
+			}
+			device := deviceOf(devices, fields[0])
+			device.Peers = append(device.Peers, peer)
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %d fields", lineNo+1, len(fields))
+		}
+	}
+
+	result := make([]Device, 0, len(devices))
+	for _, device := range devices {
+		sort.Slice(device.Peers, func(i, j int) bool { return device.Peers[i].PublicKey < device.Peers[j].PublicKey })
+		result = append(result, *device)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// deviceOf returns the named device, adding it when new
+func deviceOf(devices map[string]*Device, name string) *Device {
+	device, ok := devices[name]
+	if !ok {
+		device = &Device{Name: name}
+		devices[name] = device
+	}
+	return device
+}
+
+// parsePeer parses the fields of a peer line
+func parsePeer(fields []string) (Peer, error) {
+	peer := Peer{
+		PublicKey:    fields[1],
+		PresharedKey: fields[2] != "(none)",
+	}
+	if fields[4] != "(none)" {
+		peer.AllowedIPs = strings.Split(fields[4], ",")
+	}
+	var err error
+	if peer.LatestHandshake, err = strconv.ParseInt(fields[5], 10, 64); err != nil {
+		return Peer{}, fmt.Errorf("invalid latest handshake %q", fields[5])
+	}
+	if peer.RxBytes, err = strconv.ParseUint(fields[6], 10, 64); err != nil {
+		return Peer{}, fmt.Errorf("invalid transfer rx %q", fields[6])
+	}
+	if peer.TxBytes, err = strconv.ParseUint(fields[7], 10, 64); err != nil {
+		return Peer{}, fmt.Errorf("invalid transfer tx %q", fields[7])
+	}
+	if fields[8] != "off" {
+		if peer.Keepalive, err = strconv.Atoi(fields[8]); err != nil {
+			return Peer{}, fmt.Errorf("invalid persistent keepalive %q", fields[8])
+		}
+	}
+	return peer, nil
+}
@@ -0,0 +1,177 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+	"xhub-agent/pkg/logger"
+)
+
+// now is the time of the test dump
+var now = time.Unix(1700000000, 0)
+
+const testDump = "wg0\tPRIVATE-KEY\tserver-pub\t51820\toff\n" +
+	"wg0\tpeer-b\t(none)\t198.51.100.7:40000\t10.0.0.3/32\t1699999900\t2048\t4096\toff\n" +
+	"wg0\tpeer-a\tPSK\t203.0.113.5:51000\t10.0.0.2/32,fd00::2/128\t1699999990\t100\t200\t25\n" +
+	"wg0\tpeer-c\t(none)\t(none)\t(none)\t0\t0\t0\toff\n" +
+	"wg1\tPRIVATE-KEY-2\tother-pub\t51821\t0xca6c\n"
+
+// stubSource returns a canned dump
+type stubSource struct {
+	dump string
+	err  error
+}
+
+func (s stubSource) Dump(ctx context.Context) (string, error) {
+	return s.dump, s.err
+}
+
+func newTestCollector(t *testing.T, source DumpSource) *Collector {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	t.Cleanup(log.Close)
+	c := NewCollector(log)
+	c.SetSourceForTesting(source, func() time.Time { return now })
+	return c
+}
+
+func TestParseDump(t *testing.T) {
+	devices, err := ParseDump(testDump)
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+
+	wg0 := devices[0]
+	assert.Equal(t, "wg0", wg0.Name)
+	assert.Equal(t, "server-pub", wg0.PublicKey)
+	assert.Equal(t, 51820, wg0.ListenPort)
+	require.Len(t, wg0.Peers, 3)
+	assert.Equal(t, Peer{
+		PublicKey:       "peer-a",
+		PresharedKey:    true,
+		AllowedIPs:      []string{"10.0.0.2/32", "fd00::2/128"},
+		LatestHandshake: 1699999990,
+		RxBytes:         100,
+		TxBytes:         200,
+		Keepalive:       25,
+	}, wg0.Peers[0], "peers sorted by public key")
+	assert.Equal(t, Peer{PublicKey: "peer-c"}, wg0.Peers[2])
+
+	assert.Equal(t, Device{Name: "wg1", PublicKey: "other-pub", ListenPort: 51821}, devices[1])
+
+	devices, err = ParseDump("")
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+
+	_, err = ParseDump("wg0\tpeer\t(none)\n")
+	assert.EqualError(t, err, "line 1: unexpected 3 fields")
+	_, err = ParseDump("wg0\tpeer\t(none)\t(none)\t(none)\tnever\t0\t0\toff\n")
+	assert.EqualError(t, err, `line 1: invalid latest handshake "never"`)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	c := newTestCollector(t, stubSource{dump: testDump})
+	assert.Equal(t, []monitor.WireGuardInterface{
+		{
+			Name:        "wg0",
+			PublicKey:   "server-pub",
+			ListenPort:  51820,
+			Peers:       3,
+			ActivePeers: 2,
+			RxBytes:     2148,
+			TxBytes:     4296,
+			PeerStats: []monitor.WireGuardPeer{
+				{PublicKey: "peer-a", LatestHandshake: 1699999990, RxBytes: 100, TxBytes: 200},
+				{PublicKey: "peer-b", LatestHandshake: 1699999900, RxBytes: 2048, TxBytes: 4096},
+				{PublicKey: "peer-c"},
+			},
+		},
+		{Name: "wg1", PublicKey: "other-pub", ListenPort: 51821},
+	}, c.Collect())
+
+	c = newTestCollector(t, stubSource{err: errors.New("exit status 1")})
+	assert.Nil(t, c.Collect())
+}
+
+func TestCollector_ClientConfigs(t *testing.T) {
+	peersFile := filepath.Join(t.TempDir(), "peers.yaml")
+	require.NoError(t, os.WriteFile(peersFile, []byte("alice@example.com: peer-a\nbob@example.com: peer-b\ncarol@example.com: unknown\n"), 0644))
+	c := newTestCollector(t, stubSource{dump: testDump})
+
+	options := StubOptions{Interface: "wg0", Endpoint: "vpn.example.com", DNS: "1.1.1.1", PeersFile: peersFile}
+	configs, err := c.ClientConfigs(options)
+	require.NoError(t, err)
+	assert.Len(t, configs.PerUser, 2, "carol has no configured peer")
+	assert.Equal(t, "[Interface]\n"+
+		"PrivateKey = <client private key>\n"+
+		"Address = 10.0.0.2/32, fd00::2/128\n"+
+		"DNS = 1.1.1.1\n"+
+		"\n[Peer]\n"+
+		"PublicKey = server-pub\n"+
+		"PresharedKey = <preshared key>\n"+
+		"Endpoint = vpn.example.com:51820\n"+
+		"AllowedIPs = 0.0.0.0/0, ::/0\n"+
+		"PersistentKeepalive = 25\n", configs.URIFor("Alice@example.com"))
+	assert.NotContains(t, configs.URIFor("bob@example.com"), "PresharedKey")
+	assert.Empty(t, configs.URIFor("carol@example.com"))
+
+	options.Interface = ""
+	_, err = c.ClientConfigs(options)
+	assert.EqualError(t, err, "2 wireguard interfaces, set wireguard_interface")
+	options.Interface = "wg9"
+	_, err = c.ClientConfigs(options)
+	assert.EqualError(t, err, "wireguard interface wg9 not found")
+	options.PeersFile = filepath.Join(t.TempDir(), "missing.yaml")
+	_, err = c.ClientConfigs(options)
+	assert.ErrorContains(t, err, "failed to read wireguard peers file")
+}
+
+func TestBuildClientConfig(t *testing.T) {
+	device := Device{Name: "wg0", PublicKey: "server-pub", ListenPort: 51820}
+	stub, err := BuildClientConfig(device, Peer{PublicKey: "peer"}, StubOptions{Endpoint: "2001:db8::1"})
+	require.NoError(t, err)
+	assert.Contains(t, stub, "Endpoint = [2001:db8::1]:51820\n")
+	assert.NotContains(t, stub, "Address")
+
+	_, err = BuildClientConfig(device, Peer{}, StubOptions{})
+	assert.EqualError(t, err, "wireguard endpoint is not configured")
+	_, err = BuildClientConfig(Device{Name: "wg0"}, Peer{}, StubOptions{Endpoint: "vpn.example.com"})
+	assert.EqualError(t, err, "wireguard interface wg0 has no listen port")
+}
+
+func TestProvider(t *testing.T) {
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug")
+	require.NoError(t, err)
+	t.Cleanup(log.Close)
+
+	provider := NewProvider(false, StubOptions{}, log)
+	assert.False(t, provider.Enabled())
+	assert.Nil(t, provider.CollectStatus())
+	links, err := provider.BuildShareLinks()
+	require.NoError(t, err)
+	assert.True(t, links.Empty())
+	assert.True(t, links.WireGuardConfig)
+
+	peersFile := filepath.Join(t.TempDir(), "peers.yaml")
+	require.NoError(t, os.WriteFile(peersFile, []byte("alice@example.com: peer-a\n"), 0644))
+	provider = NewProvider(true, StubOptions{Interface: "wg0", Endpoint: "vpn.example.com", PeersFile: peersFile}, log)
+	provider.Collector().SetSourceForTesting(stubSource{dump: testDump}, func() time.Time { return now })
+	assert.True(t, provider.Enabled())
+	assert.Equal(t, CollectorName, provider.Name())
+
+	data := &monitor.ServerStatusData{}
+	provider.CollectStatus()(data)
+	require.Len(t, data.WireGuard, 2)
+	assert.Empty(t, data.InboundProtocols, "3x-ui names its own wireguard inbounds")
+
+	links, err = provider.BuildShareLinks()
+	require.NoError(t, err)
+	assert.Contains(t, links.URIFor("alice@example.com"), "Endpoint = vpn.example.com:51820")
+}
@@ -0,0 +1,176 @@
+package xraycore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"xhub-agent/internal/monitor"
+)
+
+// Health check defaults: 3x-ui restarts Xray right after the install
+const (
+	DefaultHealthTimeout = time.Minute
+	healthPollInterval   = 3 * time.Second
+)
+
+// versionPattern matches Xray release tags, e.g. "v25.8.3" or "1.8.24"
+var versionPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+
+// Panel is the 3x-ui API the Xray core is managed through
+type Panel interface {
+	XrayVersions() ([]string, error)       // Releases offered for install
+	InstallXray(version string) error      // Install a release and restart Xray
+	XrayStatus() (monitor.XrayInfo, error) // State and version of the running Xray
+}
+
+// Versions are the installed and installable Xray versions
+type Versions struct {
+	Installed string   `json:"installed"` // Version of the running Xray, e.g. "25.8.3"
+	State     string   `json:"state"`     // Xray state, "running" when healthy
+	Available []string `json:"available"` // Releases 3x-ui offers, newest first
+}
+
+// SwitchResult is the outcome of a version switch
+type SwitchResult struct {
+	Previous   string `json:"previous"`        // Version running before the switch
+	Requested  string `json:"requested"`       // Version asked for
+	Running    string `json:"running"`         // Version running after the switch (or the rollback)
+	RolledBack bool   `json:"rolledBack"`      // The requested version failed its health check
+	Unchanged  bool   `json:"unchanged"`       // The requested version was already running
+	Error      string `json:"error,omitempty"` // Why the requested version was rolled back
+}
+
+// Manager lists, installs and rolls back Xray versions through the 3x-ui version switch API
+type Manager struct {
+	panel         Panel
+	healthTimeout time.Duration
+	pollInterval  time.Duration
+
+	mutex sync.Mutex // One switch at a time
+}
+
+// NewManager creates a manager switching Xray versions through panel
+func NewManager(panel Panel) *Manager {
+	return &Manager{panel: panel, healthTimeout: DefaultHealthTimeout, pollInterval: healthPollInterval}
+}
+
+// NormalizeVersion returns version as a release tag ("1.8.24" -> "v1.8.24")
+func NormalizeVersion(version string) (string, error) {
+	version = strings.TrimSpace(version)
+	if !versionPattern.MatchString(version) {
+		return "", fmt.Errorf("invalid Xray version %q (expected e.g. v25.8.3)", version)
+	}
+	return "v" + strings.TrimPrefix(version, "v"), nil
+}
+
+// sameVersion reports whether a status version ("25.8.3") and a release tag ("v25.8.3") match
+func sameVersion(a, b string) bool {
+	return a != "" && strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+// Versions returns the running Xray version and the releases 3x-ui offers
+func (m *Manager) Versions() (Versions, error) {
+	status, err := m.panel.XrayStatus()
+	if err != nil {
+		return Versions{}, err
+	}
+	available, err := m.panel.XrayVersions()
+	if err != nil {
+		return Versions{}, err
+	}
+	return Versions{Installed: status.Version, State: status.State, Available: available}, nil
+}
+
+// Switch installs version and waits for Xray to run it. When the new version does not come
+// up healthy within the health timeout, the previous version is installed again; the
+// returned error then describes both the failure and the rollback.
+func (m *Manager) Switch(ctx context.Context, version string) (SwitchResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	tag, err := NormalizeVersion(version)
+	if err != nil {
+		return SwitchResult{}, err
+	}
+	status, err := m.panel.XrayStatus()
+	if err != nil {
+		return SwitchResult{}, err
+	}
+	result := SwitchResult{Previous: status.Version, Requested: tag}
+	if sameVersion(status.Version, tag) && status.State == "running" {
+		result.Running, result.Unchanged = status.Version, true
+		return result, nil
+	}
+
+	available, err := m.panel.XrayVersions()
+	if err != nil {
+		return result, err
+	}
+	if !slices.ContainsFunc(available, func(v string) bool { return sameVersion(v, tag) }) {
+		return result, fmt.Errorf("Xray %s is not offered by 3x-ui (available: %s)", tag, strings.Join(available, ", "))
+	}
+
+	if err := m.panel.InstallXray(tag); err != nil {
+		return result, fmt.Errorf("failed to install Xray %s: %w", tag, err)
+	}
+	healthErr := m.waitHealthy(ctx, tag)
+	if healthErr == nil {
+		result.Running = tag
+		return result, nil
+	}
+	result.Error = healthErr.Error()
+	if status.Version == "" {
+		return result, fmt.Errorf("Xray %s failed its health check (%w), no previous version to roll back to", tag, healthErr)
+	}
+
+	// Roll back even when ctx is done: a broken Xray must not be left behind
+	previous := "v" + strings.TrimPrefix(status.Version, "v")
+	result.RolledBack = true
+	if err := m.panel.InstallXray(previous); err != nil {
+		return result, fmt.Errorf("Xray %s failed its health check (%w) and the rollback to %s failed: %v", tag, healthErr, previous, err)
+	}
+	if err := m.waitHealthy(context.WithoutCancel(ctx), previous); err != nil {
+		return result, fmt.Errorf("Xray %s failed its health check (%w) and %s is unhealthy after the rollback: %v", tag, healthErr, previous, err)
+	}
+	result.Running = status.Version
+	return result, fmt.Errorf("Xray %s failed its health check (%w), rolled back to %s", tag, healthErr, previous)
+}
+
+// waitHealthy polls the Xray status until it runs version, for at most the health timeout
+func (m *Manager) waitHealthy(ctx context.Context, version string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.healthTimeout)
+	defer cancel()
+
+	var lastErr error
+	for {
+		status, err := m.panel.XrayStatus()
+		switch {
+		case err != nil:
+			lastErr = err // The panel may restart along with Xray
+		case status.State != "running":
+			lastErr = fmt.Errorf("state %s", status.State)
+			if status.ErrorMsg != "" {
+				lastErr = fmt.Errorf("state %s: %s", status.State, status.ErrorMsg)
+			}
+		case !sameVersion(status.Version, version):
+			lastErr = fmt.Errorf("running version %s", status.Version)
+		default:
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("not healthy after %s, %v", m.healthTimeout, lastErr)
+			}
+			return fmt.Errorf("%v, %v", ctx.Err(), lastErr)
+		case <-time.After(m.pollInterval):
+		}
+	}
+}
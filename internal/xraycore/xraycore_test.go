@@ -0,0 +1,137 @@
+package xraycore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xhub-agent/internal/monitor"
+)
+
+// fakePanel runs the installed version unless it is listed in broken
+type fakePanel struct {
+	mutex     sync.Mutex
+	running   string
+	available []string
+	broken    map[string]bool
+	installs  []string
+	installFn func(version string) error
+}
+
+func (p *fakePanel) XrayVersions() ([]string, error) {
+	return p.available, nil
+}
+
+func (p *fakePanel) InstallXray(version string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.installs = append(p.installs, version)
+	if p.installFn != nil {
+		if err := p.installFn(version); err != nil {
+			return err
+		}
+	}
+	p.running = version[1:]
+	return nil
+}
+
+func (p *fakePanel) XrayStatus() (monitor.XrayInfo, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.broken["v"+p.running] {
+		return monitor.XrayInfo{State: "error", ErrorMsg: "failed to start", Version: p.running}, nil
+	}
+	return monitor.XrayInfo{State: "running", Version: p.running}, nil
+}
+
+func newTestManager(panel Panel) *Manager {
+	m := NewManager(panel)
+	m.healthTimeout = 50 * time.Millisecond
+	m.pollInterval = 5 * time.Millisecond
+	return m
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	for input, want := range map[string]string{"v25.8.3": "v25.8.3", "1.8.24": "v1.8.24", " v1.0.0 ": "v1.0.0"} {
+		got, err := NormalizeVersion(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got)
+	}
+	for _, input := range []string{"", "latest", "v1.8", "v1.8.24/../x", "v1.8.24-rc1"} {
+		_, err := NormalizeVersion(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestManager_Versions(t *testing.T) {
+	panel := &fakePanel{running: "25.7.26", available: []string{"v25.8.3", "v25.7.26"}}
+	versions, err := newTestManager(panel).Versions()
+	require.NoError(t, err)
+	assert.Equal(t, Versions{Installed: "25.7.26", State: "running", Available: []string{"v25.8.3", "v25.7.26"}}, versions)
+}
+
+func TestManager_Switch(t *testing.T) {
+	panel := &fakePanel{running: "25.7.26", available: []string{"v25.8.3", "v25.7.26"}}
+	result, err := newTestManager(panel).Switch(context.Background(), "25.8.3")
+	require.NoError(t, err)
+	assert.Equal(t, SwitchResult{Previous: "25.7.26", Requested: "v25.8.3", Running: "v25.8.3"}, result)
+	assert.Equal(t, []string{"v25.8.3"}, panel.installs)
+}
+
+func TestManager_Switch_Unchanged(t *testing.T) {
+	panel := &fakePanel{running: "25.8.3", available: []string{"v25.8.3"}}
+	result, err := newTestManager(panel).Switch(context.Background(), "v25.8.3")
+	require.NoError(t, err)
+	assert.True(t, result.Unchanged)
+	assert.Empty(t, panel.installs)
+}
+
+func TestManager_Switch_NotOffered(t *testing.T) {
+	panel := &fakePanel{running: "25.7.26", available: []string{"v25.8.3"}}
+	_, err := newTestManager(panel).Switch(context.Background(), "v1.8.24")
+	assert.ErrorContains(t, err, "not offered by 3x-ui (available: v25.8.3)")
+	assert.Empty(t, panel.installs)
+}
+
+func TestManager_Switch_RollBack(t *testing.T) {
+	panel := &fakePanel{running: "25.7.26", available: []string{"v25.8.3", "v25.7.26"}, broken: map[string]bool{"v25.8.3": true}}
+	result, err := newTestManager(panel).Switch(context.Background(), "v25.8.3")
+	assert.ErrorContains(t, err, "rolled back to v25.7.26")
+	assert.True(t, result.RolledBack)
+	assert.Equal(t, "25.7.26", result.Running)
+	assert.Contains(t, result.Error, "state error: failed to start")
+	assert.Equal(t, []string{"v25.8.3", "v25.7.26"}, panel.installs)
+}
+
+func TestManager_Switch_RollBackFails(t *testing.T) {
+	panel := &fakePanel{running: "25.7.26", available: []string{"v25.8.3"}, broken: map[string]bool{"v25.8.3": true}}
+	panel.installFn = func(version string) error {
+		if version == "v25.7.26" {
+			return errors.New("download failed")
+		}
+		return nil
+	}
+	result, err := newTestManager(panel).Switch(context.Background(), "v25.8.3")
+	assert.ErrorContains(t, err, "the rollback to v25.7.26 failed: download failed")
+	assert.True(t, result.RolledBack)
+	assert.Empty(t, result.Running)
+}
+
+func TestManager_Switch_RollBackAfterCancel(t *testing.T) {
+	panel := &fakePanel{running: "25.7.26", available: []string{"v25.8.3"}, broken: map[string]bool{"v25.8.3": true}}
+	ctx, cancel := context.WithCancel(context.Background())
+	panel.installFn = func(version string) error {
+		if version == "v25.8.3" {
+			cancel() // The command times out while the new version is checked
+		}
+		return nil
+	}
+	result, err := newTestManager(panel).Switch(ctx, "v25.8.3")
+	assert.ErrorContains(t, err, "rolled back to v25.7.26")
+	assert.Equal(t, "25.7.26", result.Running)
+}
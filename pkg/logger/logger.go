@@ -1,12 +1,16 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,13 +29,31 @@ const (
 	MaxLogFileSize = 10 * 1024 * 1024
 )
 
+// Format is the encoding of log records
+type Format int32
+
+const (
+	TEXT Format = iota // "[time] [LEVEL] [fields] message" lines
+	JSON               // One JSON object per line
+)
+
 // Logger represents a logger instance
 type Logger struct {
+	*output          // Destination and settings, shared with the loggers derived by With
+	fields  []string // Key/value pairs added by With
+}
+
+// output is the destination of a logger and of the loggers derived from it
+type output struct {
+	mutex    sync.Mutex // Serializes writes, file size accounting and truncation
 	file     *os.File
 	logger   *log.Logger
-	level    LogLevel
+	level    atomic.Int32 // LogLevel, changed by SetLevel while logging
+	format   atomic.Int32 // Format, changed by SetFormat while logging
 	logFile  string
 	fileSize int64
+	context  string   // Formatted global fields, prepended to every text line
+	global   []string // Global fields as sorted key/value pairs, for JSON records
 }
 
 // NewLogger creates a new logger instance
@@ -42,13 +64,7 @@ func NewLogger(logFile, level string) (*Logger, error) {
 		return nil, fmt.Errorf("invalid log level: %s", level)
 	}
 
-	// Create log directory
-	logDir := filepath.Dir(logFile)
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create log directory: %w", err)
-	}
-
-	// Get current file size
+	// Get current file size (the log directory is prepared by the caller)
 	fileInfo, err := os.Stat(logFile)
 	var currentSize int64 = 0
 	if err == nil {
@@ -75,13 +91,84 @@ func NewLogger(logFile, level string) (*Logger, error) {
 	multiWriter := io.MultiWriter(file, os.Stdout)
 	logger := log.New(multiWriter, "", 0) // No default prefix, we format ourselves
 
-	return &Logger{
+	l := &Logger{output: &output{
 		file:     file,
 		logger:   logger,
-		level:    logLevel,
 		logFile:  logFile,
 		fileSize: currentSize,
-	}, nil
+	}}
+	l.level.Store(int32(logLevel))
+	return l, nil
+}
+
+// NewStdoutLogger creates a logger that only writes to stdout (no log file)
+func NewStdoutLogger(level string) (*Logger, error) {
+	logLevel, err := parseLogLevel(level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %s", level)
+	}
+
+	l := &Logger{output: &output{logger: log.New(os.Stdout, "", 0)}}
+	l.level.Store(int32(logLevel))
+	return l, nil
+}
+
+// SetLevel changes the log level of the running logger
+func (l *Logger) SetLevel(level string) error {
+	logLevel, err := parseLogLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %s", level)
+	}
+	l.level.Store(int32(logLevel))
+	return nil
+}
+
+// Level returns the current log level
+func (l *Logger) Level() LogLevel {
+	return LogLevel(l.level.Load())
+}
+
+// SetFormat changes the record format of the running logger: text (default) or json
+func (l *Logger) SetFormat(format string) error {
+	parsed, err := ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	l.format.Store(int32(parsed))
+	return nil
+}
+
+// Format returns the current record format
+func (l *Logger) Format() Format {
+	return Format(l.format.Load())
+}
+
+// ParseFormat parses a log format name, empty meaning text
+func ParseFormat(format string) (Format, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return TEXT, nil
+	case "json":
+		return JSON, nil
+	default:
+		return TEXT, fmt.Errorf("invalid log format: %s (expected text or json)", format)
+	}
+}
+
+// With returns a logger writing to the same output with the key/value pairs added to every
+// record, e.g. log.With("component", "report"). Values are formatted with fmt.Sprint.
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	fields := make([]string, len(l.fields), len(l.fields)+len(keysAndValues)+1)
+	copy(fields, l.fields)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		value := "(missing)"
+		if i+1 < len(keysAndValues) {
+			value = fmt.Sprint(keysAndValues[i+1])
+		}
+		fields = append(fields, key, value)
+	}
+	return &Logger{output: l.output, fields: fields}
 }
 
 // parseLogLevel parses log level string
@@ -116,22 +203,102 @@ func (l LogLevel) String() string {
 	}
 }
 
+// SetGlobalFields sets fields (e.g. agent UUID, hostname) carried by every subsequent
+// log line as "[key=value ...]". Keys are sorted so the context is stable; values
+// containing spaces or quotes are quoted. Call it once at startup.
+func (l *Logger) SetGlobalFields(fields map[string]string) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	global := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		global = append(global, key, fields[key])
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.global = global
+	l.context = formatFields(global)
+}
+
+// formatFields formats key/value pairs as "[key=value ...] " for text lines, quoting values
+// containing spaces or quotes
+func formatFields(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		value := fields[i+1]
+		if value == "" || strings.ContainsAny(value, " \t\"=[]") {
+			value = strconv.Quote(value)
+		}
+		parts = append(parts, fields[i]+"="+value)
+	}
+	return "[" + strings.Join(parts, " ") + "] "
+}
+
+// render renders a record in the current format, without the trailing newline
+func (o *output) render(now time.Time, level LogLevel, fields []string, message string) string {
+	if Format(o.format.Load()) == JSON {
+		var b strings.Builder
+		b.WriteString(`{"time":`)
+		writeJSONString(&b, now.Format(time.RFC3339Nano))
+		b.WriteString(`,"level":`)
+		writeJSONString(&b, strings.ToLower(level.String()))
+		// Global fields first; a key repeated by With keeps its position with the latest value
+		var keys []string
+		values := make(map[string]string)
+		all := append(append([]string{}, o.global...), fields...)
+		for i := 0; i+1 < len(all); i += 2 {
+			key := all[i]
+			if key == "time" || key == "level" || key == "msg" {
+				continue
+			}
+			if _, ok := values[key]; !ok {
+				keys = append(keys, key)
+			}
+			values[key] = all[i+1]
+		}
+		for _, key := range keys {
+			b.WriteByte(',')
+			writeJSONString(&b, key)
+			b.WriteByte(':')
+			writeJSONString(&b, values[key])
+		}
+		b.WriteString(`,"msg":`)
+		writeJSONString(&b, message)
+		b.WriteByte('}')
+		return b.String()
+	}
+	return fmt.Sprintf("[%s] [%s] %s%s%s", now.Format("2006-01-02 15:04:05"), level.String(), o.context, formatFields(fields), message)
+}
+
+// writeJSONString writes s as a JSON string
+func writeJSONString(b *strings.Builder, s string) {
+	encoded, _ := json.Marshal(s)
+	b.Write(encoded)
+}
+
 // log writes a log message
 func (l *Logger) log(level LogLevel, message string) {
 	// Check log level
-	if level < l.level {
+	if level < l.Level() {
 		return
 	}
 
-	// Format timestamp
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 
 	// Build log message
-	logMessage := fmt.Sprintf("[%s] [%s] %s", timestamp, level.String(), message)
+	logMessage := l.render(time.Now(), level, l.fields, message)
 
 	// Check file size before writing
 	messageSize := int64(len(logMessage) + 1) // +1 for newline
-	if l.fileSize+messageSize > MaxLogFileSize {
+	if l.file != nil && l.fileSize+messageSize > MaxLogFileSize {
 		l.truncateLogFile()
 	}
 
@@ -141,7 +308,7 @@ func (l *Logger) log(level LogLevel, message string) {
 }
 
 // truncateLogFile truncates the log file when it becomes too large
-func (l *Logger) truncateLogFile() {
+func (l *output) truncateLogFile() {
 	if l.file != nil {
 		l.file.Close()
 	}
@@ -160,8 +327,7 @@ func (l *Logger) truncateLogFile() {
 	l.fileSize = 0
 
 	// Log truncation message
-	truncateMsg := fmt.Sprintf("[%s] [INFO] Log file truncated due to size limit (%d bytes)",
-		time.Now().Format("2006-01-02 15:04:05"), MaxLogFileSize)
+	truncateMsg := l.render(time.Now(), INFO, nil, fmt.Sprintf("Log file truncated due to size limit (%d bytes)", MaxLogFileSize))
 	l.logger.Println(truncateMsg)
 	l.fileSize = int64(len(truncateMsg) + 1)
 }
@@ -1,9 +1,12 @@
 package logger
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -137,6 +140,26 @@ func TestLogger_LevelFiltering(t *testing.T) {
 	assert.Contains(t, logContent, "This is an error message, should appear")
 }
 
+func TestLogger_SetLevel(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+	logger, err := NewLogger(logFile, "warn")
+	require.NoError(t, err)
+	defer logger.Close()
+
+	logger.Info("Info before the change, should not appear")
+	require.NoError(t, logger.SetLevel("debug"))
+	assert.Equal(t, DEBUG, logger.Level())
+	logger.Debug("Debug after the change, should appear")
+
+	assert.Error(t, logger.SetLevel("verbose"))
+	assert.Equal(t, DEBUG, logger.Level(), "an invalid level keeps the current one")
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "Info before the change")
+	assert.Contains(t, string(content), "Debug after the change")
+}
+
 func TestLogger_InvalidLogFile(t *testing.T) {
 	// Try to create log file in non-existent directory
 	invalidPath := "/non/existent/directory/test.log"
@@ -145,3 +168,120 @@ func TestLogger_InvalidLogFile(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, logger)
 }
+
+func TestLogger_NewStdoutLogger(t *testing.T) {
+	logger, err := NewStdoutLogger("debug")
+	require.NoError(t, err)
+	require.NotNil(t, logger)
+	assert.Nil(t, logger.file)
+
+	// Writing must not touch any file or panic
+	logger.Info("stdout only message")
+	logger.Close()
+
+	_, err = NewStdoutLogger("invalid")
+	assert.Error(t, err)
+}
+
+func TestLogger_GlobalFields(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xhub-agent-logger-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	logFile := filepath.Join(tmpDir, "test.log")
+
+	logger, err := NewLogger(logFile, "debug")
+	require.NoError(t, err)
+	defer logger.Close()
+
+	logger.SetGlobalFields(map[string]string{
+		"uuid": "agent-uuid-1",
+		"host": "node a",
+	})
+
+	logger.Debug("debug message")
+	logger.Infof("info %d", 1)
+	logger.Warn("warn message")
+	logger.Error("error message")
+	logger.Sync()
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 4)
+	for _, line := range lines {
+		// Keys are sorted, values with spaces are quoted
+		assert.Contains(t, line, `[host="node a" uuid=agent-uuid-1] `)
+	}
+	assert.Contains(t, lines[1], "[INFO] [host=\"node a\" uuid=agent-uuid-1] info 1")
+}
+
+func TestLogger_GlobalFields_Cleared(t *testing.T) {
+	logger, err := NewStdoutLogger("info")
+	require.NoError(t, err)
+
+	logger.SetGlobalFields(map[string]string{"uuid": "x"})
+	assert.Equal(t, "[uuid=x] ", logger.context)
+
+	logger.SetGlobalFields(nil)
+	assert.Empty(t, logger.context)
+}
+
+func TestLogger_JSONFormat(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+	logger, err := NewLogger(logFile, "info")
+	require.NoError(t, err)
+	defer logger.Close()
+	require.NoError(t, logger.SetFormat("json"))
+	logger.SetGlobalFields(map[string]string{"uuid": "agent-uuid-1", "host": "node a"})
+
+	report := logger.With("component", "report")
+	report.With("rpc", "SendReport", "attempt", 2).Warnf("⚠️  retry %q", "unavailable")
+	logger.Info("plain")
+	report.Debug("filtered by level")
+	logger.Sync()
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+
+	var record map[string]string
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "warn", record["level"])
+	assert.Equal(t, `⚠️  retry "unavailable"`, record["msg"])
+	assert.Equal(t, "report", record["component"])
+	assert.Equal(t, "SendReport", record["rpc"])
+	assert.Equal(t, "2", record["attempt"])
+	assert.Equal(t, "node a", record["host"])
+	_, err = time.Parse(time.RFC3339Nano, record["time"])
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(lines[0], `{"time":`), "stable key order")
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, `{"time":"`+record["time"]+`","level":"info","host":"node a","uuid":"agent-uuid-1","msg":"plain"}`, lines[1])
+}
+
+func TestLogger_WithTextFormat(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+	logger, err := NewLogger(logFile, "info")
+	require.NoError(t, err)
+	defer logger.Close()
+	logger.SetGlobalFields(map[string]string{"uuid": "x"})
+
+	// Derived loggers share the level and format of their parent
+	report := logger.With("component", "report")
+	require.NoError(t, logger.SetLevel("warn"))
+	report.Info("filtered")
+	report.With("odd").Warn("message")
+	logger.Sync()
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "[WARN] [uuid=x] [component=report odd=(missing)] message")
+	assert.NotContains(t, string(content), "filtered")
+
+	assert.Error(t, logger.SetFormat("xml"))
+	assert.Equal(t, TEXT, logger.Format())
+}
@@ -21,11 +21,97 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// ErrorCategory is the fixed agent error taxonomy
+type ErrorCategory int32
+
+const (
+	ErrorCategory_ERROR_CATEGORY_UNKNOWN            ErrorCategory = 0  // Error could not be mapped to a category
+	ErrorCategory_ERROR_CATEGORY_PANEL_TIMEOUT      ErrorCategory = 1  // 3x-ui request timed out
+	ErrorCategory_ERROR_CATEGORY_PANEL_AUTH         ErrorCategory = 2  // 3x-ui session rejected or login failed
+	ErrorCategory_ERROR_CATEGORY_PANEL_DECODE       ErrorCategory = 3  // 3x-ui response could not be decoded
+	ErrorCategory_ERROR_CATEGORY_PANEL_HTTP         ErrorCategory = 4  // 3x-ui returned a non-200 status
+	ErrorCategory_ERROR_CATEGORY_GRPC_UNAVAILABLE   ErrorCategory = 5  // xhub unreachable
+	ErrorCategory_ERROR_CATEGORY_GRPC_AUTH          ErrorCategory = 6  // xhub rejected the API key
+	ErrorCategory_ERROR_CATEGORY_GRPC_DEADLINE      ErrorCategory = 7  // xhub request timed out
+	ErrorCategory_ERROR_CATEGORY_GRPC_OTHER         ErrorCategory = 8  // Other gRPC status errors
+	ErrorCategory_ERROR_CATEGORY_SUBSCRIPTION_FETCH ErrorCategory = 9  // Subscription content could not be fetched
+	ErrorCategory_ERROR_CATEGORY_INTERNAL_PANIC     ErrorCategory = 10 // Recovered panic in the agent loop
+	ErrorCategory_ERROR_CATEGORY_SELFTEST           ErrorCategory = 11 // Scheduled or on-demand self-test failed
+	ErrorCategory_ERROR_CATEGORY_PANEL_UNREACHABLE  ErrorCategory = 12 // 3x-ui refused the connection or could not be resolved
+	ErrorCategory_ERROR_CATEGORY_REPORT_REJECTED    ErrorCategory = 13 // xhub answered a report with success false
+)
+
+// Enum value maps for ErrorCategory.
+var (
+	ErrorCategory_name = map[int32]string{
+		0:  "ERROR_CATEGORY_UNKNOWN",
+		1:  "ERROR_CATEGORY_PANEL_TIMEOUT",
+		2:  "ERROR_CATEGORY_PANEL_AUTH",
+		3:  "ERROR_CATEGORY_PANEL_DECODE",
+		4:  "ERROR_CATEGORY_PANEL_HTTP",
+		5:  "ERROR_CATEGORY_GRPC_UNAVAILABLE",
+		6:  "ERROR_CATEGORY_GRPC_AUTH",
+		7:  "ERROR_CATEGORY_GRPC_DEADLINE",
+		8:  "ERROR_CATEGORY_GRPC_OTHER",
+		9:  "ERROR_CATEGORY_SUBSCRIPTION_FETCH",
+		10: "ERROR_CATEGORY_INTERNAL_PANIC",
+		11: "ERROR_CATEGORY_SELFTEST",
+		12: "ERROR_CATEGORY_PANEL_UNREACHABLE",
+		13: "ERROR_CATEGORY_REPORT_REJECTED",
+	}
+	ErrorCategory_value = map[string]int32{
+		"ERROR_CATEGORY_UNKNOWN":            0,
+		"ERROR_CATEGORY_PANEL_TIMEOUT":      1,
+		"ERROR_CATEGORY_PANEL_AUTH":         2,
+		"ERROR_CATEGORY_PANEL_DECODE":       3,
+		"ERROR_CATEGORY_PANEL_HTTP":         4,
+		"ERROR_CATEGORY_GRPC_UNAVAILABLE":   5,
+		"ERROR_CATEGORY_GRPC_AUTH":          6,
+		"ERROR_CATEGORY_GRPC_DEADLINE":      7,
+		"ERROR_CATEGORY_GRPC_OTHER":         8,
+		"ERROR_CATEGORY_SUBSCRIPTION_FETCH": 9,
+		"ERROR_CATEGORY_INTERNAL_PANIC":     10,
+		"ERROR_CATEGORY_SELFTEST":           11,
+		"ERROR_CATEGORY_PANEL_UNREACHABLE":  12,
+		"ERROR_CATEGORY_REPORT_REJECTED":    13,
+	}
+)
+
+func (x ErrorCategory) Enum() *ErrorCategory {
+	p := new(ErrorCategory)
+	*p = x
+	return p
+}
+
+func (x ErrorCategory) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorCategory) Descriptor() protoreflect.EnumDescriptor {
+	return file_report_proto_enumTypes[0].Descriptor()
+}
+
+func (ErrorCategory) Type() protoreflect.EnumType {
+	return &file_report_proto_enumTypes[0]
+}
+
+func (x ErrorCategory) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorCategory.Descriptor instead.
+func (ErrorCategory) EnumDescriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{0}
+}
+
 // ReportRequest contains the data to be reported
 type ReportRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"` // Agent unique identifier
-	Data          *ServerStatusData      `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"` // Server status data
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                  // Agent unique identifier
+	Data          *ServerStatusData      `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`                                  // Server status data
+	ErrorCounts   []*ErrorCategoryCount  `protobuf:"bytes,3,rep,name=error_counts,json=errorCounts,proto3" json:"error_counts,omitempty"` // Errors per category since the last acknowledged report
+	Agent         *AgentInfo             `protobuf:"bytes,4,opt,name=agent,proto3" json:"agent,omitempty"`                                // Agent and host metadata, collected at startup
+	Delta         *StatusDelta           `protobuf:"bytes,5,opt,name=delta,proto3" json:"delta,omitempty"`                                // Set by report_delta: data is a full snapshot or only its changes
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -74,6 +160,330 @@ func (x *ReportRequest) GetData() *ServerStatusData {
 	return nil
 }
 
+func (x *ReportRequest) GetErrorCounts() []*ErrorCategoryCount {
+	if x != nil {
+		return x.ErrorCounts
+	}
+	return nil
+}
+
+func (x *ReportRequest) GetAgent() *AgentInfo {
+	if x != nil {
+		return x.Agent
+	}
+	return nil
+}
+
+func (x *ReportRequest) GetDelta() *StatusDelta {
+	if x != nil {
+		return x.Delta
+	}
+	return nil
+}
+
+// StatusDelta numbers the status reports of report_delta. A report with base_sequence 0 is
+// a full snapshot. Otherwise data holds only the fields changed since the report base_sequence,
+// which xhub acknowledged; xhub answers FAILED_PRECONDITION when it no longer has that report,
+// and the agent sends a full snapshot instead.
+type StatusDelta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`                                       // Increases by one per status report, starting at 1 on agent start
+	BaseSequence  uint64                 `protobuf:"varint,2,opt,name=base_sequence,json=baseSequence,proto3" json:"base_sequence,omitempty"`           // Report the changes apply to, 0 for a full snapshot
+	ChangedFields []uint32               `protobuf:"varint,3,rep,packed,name=changed_fields,json=changedFields,proto3" json:"changed_fields,omitempty"` // ServerStatusData field numbers that changed; a listed field absent from data was cleared
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusDelta) Reset() {
+	*x = StatusDelta{}
+	mi := &file_report_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusDelta) ProtoMessage() {}
+
+func (x *StatusDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusDelta.ProtoReflect.Descriptor instead.
+func (*StatusDelta) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StatusDelta) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *StatusDelta) GetBaseSequence() uint64 {
+	if x != nil {
+		return x.BaseSequence
+	}
+	return 0
+}
+
+func (x *StatusDelta) GetChangedFields() []uint32 {
+	if x != nil {
+		return x.ChangedFields
+	}
+	return nil
+}
+
+// AgentInfo identifies the agent and the host it runs on
+type AgentInfo struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AgentVersion   string                 `protobuf:"bytes,1,opt,name=agent_version,json=agentVersion,proto3" json:"agent_version,omitempty"` // Agent version, e.g. "v1.0.0"
+	GoVersion      string                 `protobuf:"bytes,2,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`          // Go toolchain the agent was built with, e.g. "go1.24.2"
+	Os             string                 `protobuf:"bytes,3,opt,name=os,proto3" json:"os,omitempty"`                                         // Operating system (GOOS)
+	Arch           string                 `protobuf:"bytes,4,opt,name=arch,proto3" json:"arch,omitempty"`                                     // CPU architecture (GOARCH)
+	Kernel         string                 `protobuf:"bytes,5,opt,name=kernel,proto3" json:"kernel,omitempty"`                                 // Kernel release, empty if unknown
+	Hostname       string                 `protobuf:"bytes,6,opt,name=hostname,proto3" json:"hostname,omitempty"`                             // Host name
+	Virtualization string                 `protobuf:"bytes,7,opt,name=virtualization,proto3" json:"virtualization,omitempty"`                 // kvm, xen, vmware, hyperv, openvz, lxc, docker, ...; "none" on bare metal, empty if unknown
+	Geo            *GeoInfo               `protobuf:"bytes,8,opt,name=geo,proto3" json:"geo,omitempty"`                                       // Location of the public IP, absent without a geo lookup
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AgentInfo) Reset() {
+	*x = AgentInfo{}
+	mi := &file_report_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentInfo) ProtoMessage() {}
+
+func (x *AgentInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentInfo.ProtoReflect.Descriptor instead.
+func (*AgentInfo) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AgentInfo) GetAgentVersion() string {
+	if x != nil {
+		return x.AgentVersion
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetArch() string {
+	if x != nil {
+		return x.Arch
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetKernel() string {
+	if x != nil {
+		return x.Kernel
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetVirtualization() string {
+	if x != nil {
+		return x.Virtualization
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetGeo() *GeoInfo {
+	if x != nil {
+		return x.Geo
+	}
+	return nil
+}
+
+// GeoInfo is the location and network of the public IP of the host
+type GeoInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`                    // Public IP the lookup saw
+	Country       string                 `protobuf:"bytes,2,opt,name=country,proto3" json:"country,omitempty"`          // ISO 3166-1 alpha-2 country code
+	Region        string                 `protobuf:"bytes,3,opt,name=region,proto3" json:"region,omitempty"`            // Region or state
+	City          string                 `protobuf:"bytes,4,opt,name=city,proto3" json:"city,omitempty"`                // City
+	Asn           uint32                 `protobuf:"varint,5,opt,name=asn,proto3" json:"asn,omitempty"`                 // Autonomous system number, 0 if unknown
+	AsOrg         string                 `protobuf:"bytes,6,opt,name=as_org,json=asOrg,proto3" json:"as_org,omitempty"` // Organization of the autonomous system
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GeoInfo) Reset() {
+	*x = GeoInfo{}
+	mi := &file_report_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GeoInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeoInfo) ProtoMessage() {}
+
+func (x *GeoInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeoInfo.ProtoReflect.Descriptor instead.
+func (*GeoInfo) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GeoInfo) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *GeoInfo) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *GeoInfo) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *GeoInfo) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *GeoInfo) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *GeoInfo) GetAsOrg() string {
+	if x != nil {
+		return x.AsOrg
+	}
+	return ""
+}
+
+// ErrorCategoryCount is the number of errors of one category
+type ErrorCategoryCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Category      ErrorCategory          `protobuf:"varint,1,opt,name=category,proto3,enum=reportpb.ErrorCategory" json:"category,omitempty"` // Error category
+	Count         uint64                 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`                                   // Errors since the last acknowledged report
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorCategoryCount) Reset() {
+	*x = ErrorCategoryCount{}
+	mi := &file_report_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorCategoryCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorCategoryCount) ProtoMessage() {}
+
+func (x *ErrorCategoryCount) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorCategoryCount.ProtoReflect.Descriptor instead.
+func (*ErrorCategoryCount) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ErrorCategoryCount) GetCategory() ErrorCategory {
+	if x != nil {
+		return x.Category
+	}
+	return ErrorCategory_ERROR_CATEGORY_UNKNOWN
+}
+
+func (x *ErrorCategoryCount) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
 // ReportResponse contains the response from the server
 type ReportResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -85,7 +495,7 @@ type ReportResponse struct {
 
 func (x *ReportResponse) Reset() {
 	*x = ReportResponse{}
-	mi := &file_report_proto_msgTypes[1]
+	mi := &file_report_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -97,7 +507,7 @@ func (x *ReportResponse) String() string {
 func (*ReportResponse) ProtoMessage() {}
 
 func (x *ReportResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[1]
+	mi := &file_report_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -110,61 +520,3290 @@ func (x *ReportResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportResponse.ProtoReflect.Descriptor instead.
 func (*ReportResponse) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{1}
+	return file_report_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ReportResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// ServerStatusData contains comprehensive server status information
+type ServerStatusData struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Cpu              float64                `protobuf:"fixed64,1,opt,name=cpu,proto3" json:"cpu,omitempty"`                                                                                                                 // CPU usage rate
+	CpuCores         int32                  `protobuf:"varint,2,opt,name=cpu_cores,json=cpuCores,proto3" json:"cpu_cores,omitempty"`                                                                                        // CPU core count
+	LogicalPro       int32                  `protobuf:"varint,3,opt,name=logical_pro,json=logicalPro,proto3" json:"logical_pro,omitempty"`                                                                                  // Logical processor count
+	CpuSpeedMhz      float64                `protobuf:"fixed64,4,opt,name=cpu_speed_mhz,json=cpuSpeedMhz,proto3" json:"cpu_speed_mhz,omitempty"`                                                                            // CPU frequency (MHz)
+	Memory           *MemoryInfo            `protobuf:"bytes,5,opt,name=memory,proto3" json:"memory,omitempty"`                                                                                                             // Memory information
+	Swap             *SwapInfo              `protobuf:"bytes,6,opt,name=swap,proto3" json:"swap,omitempty"`                                                                                                                 // Swap space information
+	Disk             *DiskInfo              `protobuf:"bytes,7,opt,name=disk,proto3" json:"disk,omitempty"`                                                                                                                 // Disk information
+	Uptime           int32                  `protobuf:"varint,8,opt,name=uptime,proto3" json:"uptime,omitempty"`                                                                                                            // Uptime (seconds)
+	Loads            []float64              `protobuf:"fixed64,9,rep,packed,name=loads,proto3" json:"loads,omitempty"`                                                                                                      // System load
+	TcpCount         int32                  `protobuf:"varint,10,opt,name=tcp_count,json=tcpCount,proto3" json:"tcp_count,omitempty"`                                                                                       // TCP connection count
+	UdpCount         int32                  `protobuf:"varint,11,opt,name=udp_count,json=udpCount,proto3" json:"udp_count,omitempty"`                                                                                       // UDP connection count
+	NetIo            *NetIOInfo             `protobuf:"bytes,12,opt,name=net_io,json=netIo,proto3" json:"net_io,omitempty"`                                                                                                 // Network IO
+	NetTraffic       *NetTraffic            `protobuf:"bytes,13,opt,name=net_traffic,json=netTraffic,proto3" json:"net_traffic,omitempty"`                                                                                  // Network traffic
+	PublicIp         *PublicIPInfo          `protobuf:"bytes,14,opt,name=public_ip,json=publicIp,proto3" json:"public_ip,omitempty"`                                                                                        // Public IP information
+	Xray             *XrayInfo              `protobuf:"bytes,15,opt,name=xray,proto3" json:"xray,omitempty"`                                                                                                                // Xray status
+	AppStats         *AppStats              `protobuf:"bytes,16,opt,name=app_stats,json=appStats,proto3" json:"app_stats,omitempty"`                                                                                        // Application status
+	PortListeners    []*PortListener        `protobuf:"bytes,17,rep,name=port_listeners,json=portListeners,proto3" json:"port_listeners,omitempty"`                                                                         // Listener process per inbound port (detect_port_frontends)
+	InboundProtocols []string               `protobuf:"bytes,18,rep,name=inbound_protocols,json=inboundProtocols,proto3" json:"inbound_protocols,omitempty"`                                                                // Deduplicated protocols of enabled inbounds (vless, vmess, ...)
+	Fail2BanBans     map[string]int32       `protobuf:"bytes,19,rep,name=fail2ban_bans,json=fail2banBans,proto3" json:"fail2ban_bans,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Currently banned IPs per fail2ban jail (collect_fail2ban)
+	CertExpiry       []*CertExpiry          `protobuf:"bytes,20,rep,name=cert_expiry,json=certExpiry,proto3" json:"cert_expiry,omitempty"`                                                                                  // Certificate files referenced by configs (collect_cert_expiry)
+	DnsChecks        []*DNSCheck            `protobuf:"bytes,21,rep,name=dns_checks,json=dnsChecks,proto3" json:"dns_checks,omitempty"`                                                                                     // DNS health of the domains users connect to (dns_check)
+	SelfTest         *SelfTestStatus        `protobuf:"bytes,22,opt,name=self_test,json=selfTest,proto3" json:"self_test,omitempty"`                                                                                        // Outcome of the last pipeline self-test (selftest_interval)
+	IpStack          string                 `protobuf:"bytes,23,opt,name=ip_stack,json=ipStack,proto3" json:"ip_stack,omitempty"`                                                                                           // ipv4-only, ipv6-only, dual-stack or unknown (from public_ip)
+	DiskIo           *DiskIOStats           `protobuf:"bytes,24,opt,name=disk_io,json=diskIo,proto3" json:"disk_io,omitempty"`                                                                                              // IO rates of the primary disk (collect_disk_io)
+	DiskSmart        *DiskSMART             `protobuf:"bytes,25,opt,name=disk_smart,json=diskSmart,proto3" json:"disk_smart,omitempty"`                                                                                     // SMART health of the primary disk (collect_disk_io, needs smartctl)
+	Pressure         *HostPressure          `protobuf:"bytes,26,opt,name=pressure,proto3" json:"pressure,omitempty"`                                                                                                        // Pressure stall and thermal zone readings (collect_pressure, Linux)
+	Wireguard        []*WireGuardInterface  `protobuf:"bytes,27,rep,name=wireguard,proto3" json:"wireguard,omitempty"`                                                                                                      // WireGuard interfaces and their peers (collect_wireguard, needs wg)
+	PanelVersion     string                 `protobuf:"bytes,28,opt,name=panel_version,json=panelVersion,proto3" json:"panel_version,omitempty"`                                                                            // 3x-ui release, detected from the panel page or xui_panel_version; empty when unknown
+	UnreportedFields []string               `protobuf:"bytes,29,rep,name=unreported_fields,json=unreportedFields,proto3" json:"unreported_fields,omitempty"`                                                                // 3x-ui status keys (e.g. "cpuCores") the panel did not report, left zero
+	XrayRestart      *XrayRestart           `protobuf:"bytes,30,opt,name=xray_restart,json=xrayRestart,proto3" json:"xray_restart,omitempty"`                                                                               // Xray restart by the agent since the previous report (xray_supervision, restart_xray)
+	Clock            *ClockStatus           `protobuf:"bytes,31,opt,name=clock,proto3" json:"clock,omitempty"`                                                                                                              // System clock offset against NTP (collect_clock)
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ServerStatusData) Reset() {
+	*x = ServerStatusData{}
+	mi := &file_report_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerStatusData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerStatusData) ProtoMessage() {}
+
+func (x *ServerStatusData) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerStatusData.ProtoReflect.Descriptor instead.
+func (*ServerStatusData) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ServerStatusData) GetCpu() float64 {
+	if x != nil {
+		return x.Cpu
+	}
+	return 0
+}
+
+func (x *ServerStatusData) GetCpuCores() int32 {
+	if x != nil {
+		return x.CpuCores
+	}
+	return 0
+}
+
+func (x *ServerStatusData) GetLogicalPro() int32 {
+	if x != nil {
+		return x.LogicalPro
+	}
+	return 0
+}
+
+func (x *ServerStatusData) GetCpuSpeedMhz() float64 {
+	if x != nil {
+		return x.CpuSpeedMhz
+	}
+	return 0
+}
+
+func (x *ServerStatusData) GetMemory() *MemoryInfo {
+	if x != nil {
+		return x.Memory
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetSwap() *SwapInfo {
+	if x != nil {
+		return x.Swap
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetDisk() *DiskInfo {
+	if x != nil {
+		return x.Disk
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetUptime() int32 {
+	if x != nil {
+		return x.Uptime
+	}
+	return 0
+}
+
+func (x *ServerStatusData) GetLoads() []float64 {
+	if x != nil {
+		return x.Loads
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetTcpCount() int32 {
+	if x != nil {
+		return x.TcpCount
+	}
+	return 0
+}
+
+func (x *ServerStatusData) GetUdpCount() int32 {
+	if x != nil {
+		return x.UdpCount
+	}
+	return 0
+}
+
+func (x *ServerStatusData) GetNetIo() *NetIOInfo {
+	if x != nil {
+		return x.NetIo
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetNetTraffic() *NetTraffic {
+	if x != nil {
+		return x.NetTraffic
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetPublicIp() *PublicIPInfo {
+	if x != nil {
+		return x.PublicIp
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetXray() *XrayInfo {
+	if x != nil {
+		return x.Xray
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetAppStats() *AppStats {
+	if x != nil {
+		return x.AppStats
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetPortListeners() []*PortListener {
+	if x != nil {
+		return x.PortListeners
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetInboundProtocols() []string {
+	if x != nil {
+		return x.InboundProtocols
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetFail2BanBans() map[string]int32 {
+	if x != nil {
+		return x.Fail2BanBans
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetCertExpiry() []*CertExpiry {
+	if x != nil {
+		return x.CertExpiry
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetDnsChecks() []*DNSCheck {
+	if x != nil {
+		return x.DnsChecks
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetSelfTest() *SelfTestStatus {
+	if x != nil {
+		return x.SelfTest
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetIpStack() string {
+	if x != nil {
+		return x.IpStack
+	}
+	return ""
+}
+
+func (x *ServerStatusData) GetDiskIo() *DiskIOStats {
+	if x != nil {
+		return x.DiskIo
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetDiskSmart() *DiskSMART {
+	if x != nil {
+		return x.DiskSmart
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetPressure() *HostPressure {
+	if x != nil {
+		return x.Pressure
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetWireguard() []*WireGuardInterface {
+	if x != nil {
+		return x.Wireguard
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetPanelVersion() string {
+	if x != nil {
+		return x.PanelVersion
+	}
+	return ""
+}
+
+func (x *ServerStatusData) GetUnreportedFields() []string {
+	if x != nil {
+		return x.UnreportedFields
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetXrayRestart() *XrayRestart {
+	if x != nil {
+		return x.XrayRestart
+	}
+	return nil
+}
+
+func (x *ServerStatusData) GetClock() *ClockStatus {
+	if x != nil {
+		return x.Clock
+	}
+	return nil
+}
+
+// ClockStatus is the offset of the system clock against NTP and the state of the NTP daemon
+type ClockStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OffsetMs      float64                `protobuf:"fixed64,1,opt,name=offset_ms,json=offsetMs,proto3" json:"offset_ms,omitempty"`            // System clock minus NTP time, positive when the clock is ahead
+	OffsetSource  string                 `protobuf:"bytes,2,opt,name=offset_source,json=offsetSource,proto3" json:"offset_source,omitempty"`  // "sntp" (query of clock_ntp_server) or "chrony", empty when not measured
+	RoundTripMs   float64                `protobuf:"fixed64,3,opt,name=round_trip_ms,json=roundTripMs,proto3" json:"round_trip_ms,omitempty"` // Round trip of the SNTP query
+	Server        string                 `protobuf:"bytes,4,opt,name=server,proto3" json:"server,omitempty"`                                  // NTP server the offset was measured against
+	Synchronized  bool                   `protobuf:"varint,5,opt,name=synchronized,proto3" json:"synchronized,omitempty"`                     // The NTP daemon reports the clock synchronized
+	NtpService    string                 `protobuf:"bytes,6,opt,name=ntp_service,json=ntpService,proto3" json:"ntp_service,omitempty"`        // "chronyd" or "systemd-timesyncd", empty when none runs
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`                                    // Why the offset could not be measured
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClockStatus) Reset() {
+	*x = ClockStatus{}
+	mi := &file_report_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClockStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClockStatus) ProtoMessage() {}
+
+func (x *ClockStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClockStatus.ProtoReflect.Descriptor instead.
+func (*ClockStatus) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ClockStatus) GetOffsetMs() float64 {
+	if x != nil {
+		return x.OffsetMs
+	}
+	return 0
+}
+
+func (x *ClockStatus) GetOffsetSource() string {
+	if x != nil {
+		return x.OffsetSource
+	}
+	return ""
+}
+
+func (x *ClockStatus) GetRoundTripMs() float64 {
+	if x != nil {
+		return x.RoundTripMs
+	}
+	return 0
+}
+
+func (x *ClockStatus) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *ClockStatus) GetSynchronized() bool {
+	if x != nil {
+		return x.Synchronized
+	}
+	return false
+}
+
+func (x *ClockStatus) GetNtpService() string {
+	if x != nil {
+		return x.NtpService
+	}
+	return ""
+}
+
+func (x *ClockStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// XrayRestart is the outcome of an Xray restart by the agent
+type XrayRestart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Trigger       string                 `protobuf:"bytes,1,opt,name=trigger,proto3" json:"trigger,omitempty"`                          // "supervision" or "command"
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`                            // "panel" (3x-ui API) or "systemd" (xray_restart_service), the last one tried
+	At            int64                  `protobuf:"varint,3,opt,name=at,proto3" json:"at,omitempty"`                                   // Unix time of the restart
+	DownCycles    int32                  `protobuf:"varint,4,opt,name=down_cycles,json=downCycles,proto3" json:"down_cycles,omitempty"` // Report cycles Xray was not running before a supervision restart
+	Success       bool                   `protobuf:"varint,5,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"` // Why the restart failed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XrayRestart) Reset() {
+	*x = XrayRestart{}
+	mi := &file_report_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XrayRestart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XrayRestart) ProtoMessage() {}
+
+func (x *XrayRestart) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XrayRestart.ProtoReflect.Descriptor instead.
+func (*XrayRestart) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *XrayRestart) GetTrigger() string {
+	if x != nil {
+		return x.Trigger
+	}
+	return ""
+}
+
+func (x *XrayRestart) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *XrayRestart) GetAt() int64 {
+	if x != nil {
+		return x.At
+	}
+	return 0
+}
+
+func (x *XrayRestart) GetDownCycles() int32 {
+	if x != nil {
+		return x.DownCycles
+	}
+	return 0
+}
+
+func (x *XrayRestart) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *XrayRestart) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// WireGuardInterface is a WireGuard interface of the host, as reported by "wg show all dump"
+type WireGuardInterface struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // e.g. "wg0"
+	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	ListenPort    int32                  `protobuf:"varint,3,opt,name=listen_port,json=listenPort,proto3" json:"listen_port,omitempty"`
+	Peers         int32                  `protobuf:"varint,4,opt,name=peers,proto3" json:"peers,omitempty"`                                // Configured peers
+	ActivePeers   int32                  `protobuf:"varint,5,opt,name=active_peers,json=activePeers,proto3" json:"active_peers,omitempty"` // Peers with a handshake in the last 3 minutes
+	RxBytes       uint64                 `protobuf:"varint,6,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`             // Received from all peers
+	TxBytes       uint64                 `protobuf:"varint,7,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`             // Sent to all peers
+	PeerStats     []*WireGuardPeer       `protobuf:"bytes,8,rep,name=peer_stats,json=peerStats,proto3" json:"peer_stats,omitempty"`        // First 256 peers by public key, peer endpoints are not reported
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WireGuardInterface) Reset() {
+	*x = WireGuardInterface{}
+	mi := &file_report_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WireGuardInterface) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WireGuardInterface) ProtoMessage() {}
+
+func (x *WireGuardInterface) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WireGuardInterface.ProtoReflect.Descriptor instead.
+func (*WireGuardInterface) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WireGuardInterface) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *WireGuardInterface) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *WireGuardInterface) GetListenPort() int32 {
+	if x != nil {
+		return x.ListenPort
+	}
+	return 0
+}
+
+func (x *WireGuardInterface) GetPeers() int32 {
+	if x != nil {
+		return x.Peers
+	}
+	return 0
+}
+
+func (x *WireGuardInterface) GetActivePeers() int32 {
+	if x != nil {
+		return x.ActivePeers
+	}
+	return 0
+}
+
+func (x *WireGuardInterface) GetRxBytes() uint64 {
+	if x != nil {
+		return x.RxBytes
+	}
+	return 0
+}
+
+func (x *WireGuardInterface) GetTxBytes() uint64 {
+	if x != nil {
+		return x.TxBytes
+	}
+	return 0
+}
+
+func (x *WireGuardInterface) GetPeerStats() []*WireGuardPeer {
+	if x != nil {
+		return x.PeerStats
+	}
+	return nil
+}
+
+// WireGuardPeer is the handshake freshness and transfer of a WireGuard peer
+type WireGuardPeer struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey       string                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	LatestHandshake int64                  `protobuf:"varint,2,opt,name=latest_handshake,json=latestHandshake,proto3" json:"latest_handshake,omitempty"` // Unix time, 0 when the peer never completed a handshake
+	RxBytes         uint64                 `protobuf:"varint,3,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
+	TxBytes         uint64                 `protobuf:"varint,4,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WireGuardPeer) Reset() {
+	*x = WireGuardPeer{}
+	mi := &file_report_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WireGuardPeer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WireGuardPeer) ProtoMessage() {}
+
+func (x *WireGuardPeer) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WireGuardPeer.ProtoReflect.Descriptor instead.
+func (*WireGuardPeer) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WireGuardPeer) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *WireGuardPeer) GetLatestHandshake() int64 {
+	if x != nil {
+		return x.LatestHandshake
+	}
+	return 0
+}
+
+func (x *WireGuardPeer) GetRxBytes() uint64 {
+	if x != nil {
+		return x.RxBytes
+	}
+	return 0
+}
+
+func (x *WireGuardPeer) GetTxBytes() uint64 {
+	if x != nil {
+		return x.TxBytes
+	}
+	return 0
+}
+
+// HostPressure is the pressure stall information (PSI) and thermal zone temperatures of the host
+type HostPressure struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cpu           *PressureStall         `protobuf:"bytes,1,opt,name=cpu,proto3" json:"cpu,omitempty"` // Unset when the kernel has no PSI (before 4.20 or psi=0)
+	Memory        *PressureStall         `protobuf:"bytes,2,opt,name=memory,proto3" json:"memory,omitempty"`
+	Io            *PressureStall         `protobuf:"bytes,3,opt,name=io,proto3" json:"io,omitempty"`
+	ThermalZones  []*ThermalZone         `protobuf:"bytes,4,rep,name=thermal_zones,json=thermalZones,proto3" json:"thermal_zones,omitempty"` // Empty on most virtual machines
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostPressure) Reset() {
+	*x = HostPressure{}
+	mi := &file_report_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostPressure) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostPressure) ProtoMessage() {}
+
+func (x *HostPressure) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostPressure.ProtoReflect.Descriptor instead.
+func (*HostPressure) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *HostPressure) GetCpu() *PressureStall {
+	if x != nil {
+		return x.Cpu
+	}
+	return nil
+}
+
+func (x *HostPressure) GetMemory() *PressureStall {
+	if x != nil {
+		return x.Memory
+	}
+	return nil
+}
+
+func (x *HostPressure) GetIo() *PressureStall {
+	if x != nil {
+		return x.Io
+	}
+	return nil
+}
+
+func (x *HostPressure) GetThermalZones() []*ThermalZone {
+	if x != nil {
+		return x.ThermalZones
+	}
+	return nil
+}
+
+// PressureStall is the share of time tasks stalled on a resource (/proc/pressure/<resource>)
+type PressureStall struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SomeAvg10     float64                `protobuf:"fixed64,1,opt,name=some_avg10,json=someAvg10,proto3" json:"some_avg10,omitempty"` // Percentage of time at least one task stalled, 10s average
+	SomeAvg60     float64                `protobuf:"fixed64,2,opt,name=some_avg60,json=someAvg60,proto3" json:"some_avg60,omitempty"`
+	SomeAvg300    float64                `protobuf:"fixed64,3,opt,name=some_avg300,json=someAvg300,proto3" json:"some_avg300,omitempty"`
+	FullAvg10     float64                `protobuf:"fixed64,4,opt,name=full_avg10,json=fullAvg10,proto3" json:"full_avg10,omitempty"` // Percentage of time all non-idle tasks stalled, 10s average
+	FullAvg60     float64                `protobuf:"fixed64,5,opt,name=full_avg60,json=fullAvg60,proto3" json:"full_avg60,omitempty"`
+	FullAvg300    float64                `protobuf:"fixed64,6,opt,name=full_avg300,json=fullAvg300,proto3" json:"full_avg300,omitempty"`
+	SomeTotalUs   uint64                 `protobuf:"varint,7,opt,name=some_total_us,json=someTotalUs,proto3" json:"some_total_us,omitempty"` // Total stall time since boot (microseconds)
+	FullTotalUs   uint64                 `protobuf:"varint,8,opt,name=full_total_us,json=fullTotalUs,proto3" json:"full_total_us,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PressureStall) Reset() {
+	*x = PressureStall{}
+	mi := &file_report_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PressureStall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PressureStall) ProtoMessage() {}
+
+func (x *PressureStall) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PressureStall.ProtoReflect.Descriptor instead.
+func (*PressureStall) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PressureStall) GetSomeAvg10() float64 {
+	if x != nil {
+		return x.SomeAvg10
+	}
+	return 0
+}
+
+func (x *PressureStall) GetSomeAvg60() float64 {
+	if x != nil {
+		return x.SomeAvg60
+	}
+	return 0
+}
+
+func (x *PressureStall) GetSomeAvg300() float64 {
+	if x != nil {
+		return x.SomeAvg300
+	}
+	return 0
+}
+
+func (x *PressureStall) GetFullAvg10() float64 {
+	if x != nil {
+		return x.FullAvg10
+	}
+	return 0
+}
+
+func (x *PressureStall) GetFullAvg60() float64 {
+	if x != nil {
+		return x.FullAvg60
+	}
+	return 0
+}
+
+func (x *PressureStall) GetFullAvg300() float64 {
+	if x != nil {
+		return x.FullAvg300
+	}
+	return 0
+}
+
+func (x *PressureStall) GetSomeTotalUs() uint64 {
+	if x != nil {
+		return x.SomeTotalUs
+	}
+	return 0
+}
+
+func (x *PressureStall) GetFullTotalUs() uint64 {
+	if x != nil {
+		return x.FullTotalUs
+	}
+	return 0
+}
+
+// ThermalZone is the temperature of a kernel thermal zone (/sys/class/thermal)
+type ThermalZone struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Zone               string                 `protobuf:"bytes,1,opt,name=zone,proto3" json:"zone,omitempty"` // e.g. "thermal_zone0"
+	Type               string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // Sensor, e.g. "x86_pkg_temp", "acpitz"
+	TemperatureCelsius float64                `protobuf:"fixed64,3,opt,name=temperature_celsius,json=temperatureCelsius,proto3" json:"temperature_celsius,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ThermalZone) Reset() {
+	*x = ThermalZone{}
+	mi := &file_report_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ThermalZone) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ThermalZone) ProtoMessage() {}
+
+func (x *ThermalZone) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ThermalZone.ProtoReflect.Descriptor instead.
+func (*ThermalZone) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ThermalZone) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *ThermalZone) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ThermalZone) GetTemperatureCelsius() float64 {
+	if x != nil {
+		return x.TemperatureCelsius
+	}
+	return 0
+}
+
+// DiskIOStats are the IO rates of a disk between two samples
+type DiskIOStats struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Device           string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"` // Block device, e.g. "vda"
+	ReadBytesPerSec  float64                `protobuf:"fixed64,2,opt,name=read_bytes_per_sec,json=readBytesPerSec,proto3" json:"read_bytes_per_sec,omitempty"`
+	WriteBytesPerSec float64                `protobuf:"fixed64,3,opt,name=write_bytes_per_sec,json=writeBytesPerSec,proto3" json:"write_bytes_per_sec,omitempty"`
+	ReadIops         float64                `protobuf:"fixed64,4,opt,name=read_iops,json=readIops,proto3" json:"read_iops,omitempty"`      // Completed reads per second
+	WriteIops        float64                `protobuf:"fixed64,5,opt,name=write_iops,json=writeIops,proto3" json:"write_iops,omitempty"`   // Completed writes per second
+	Utilization      float64                `protobuf:"fixed64,6,opt,name=utilization,proto3" json:"utilization,omitempty"`                // Percentage of the time the disk was busy (0-100)
+	AwaitMs          float64                `protobuf:"fixed64,7,opt,name=await_ms,json=awaitMs,proto3" json:"await_ms,omitempty"`         // Average time per completed IO, queueing included
+	IntervalMs       int64                  `protobuf:"varint,8,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"` // Duration of the sampled window
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *DiskIOStats) Reset() {
+	*x = DiskIOStats{}
+	mi := &file_report_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiskIOStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiskIOStats) ProtoMessage() {}
+
+func (x *DiskIOStats) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiskIOStats.ProtoReflect.Descriptor instead.
+func (*DiskIOStats) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DiskIOStats) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *DiskIOStats) GetReadBytesPerSec() float64 {
+	if x != nil {
+		return x.ReadBytesPerSec
+	}
+	return 0
+}
+
+func (x *DiskIOStats) GetWriteBytesPerSec() float64 {
+	if x != nil {
+		return x.WriteBytesPerSec
+	}
+	return 0
+}
+
+func (x *DiskIOStats) GetReadIops() float64 {
+	if x != nil {
+		return x.ReadIops
+	}
+	return 0
+}
+
+func (x *DiskIOStats) GetWriteIops() float64 {
+	if x != nil {
+		return x.WriteIops
+	}
+	return 0
+}
+
+func (x *DiskIOStats) GetUtilization() float64 {
+	if x != nil {
+		return x.Utilization
+	}
+	return 0
+}
+
+func (x *DiskIOStats) GetAwaitMs() float64 {
+	if x != nil {
+		return x.AwaitMs
+	}
+	return 0
+}
+
+func (x *DiskIOStats) GetIntervalMs() int64 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+// DiskSMART is the basic SMART health of a disk, as reported by smartctl
+type DiskSMART struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Device             string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`                                                    // Block device, e.g. "sda"
+	Available          bool                   `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"`                                             // smartctl returned SMART data (virtual disks have none)
+	Passed             bool                   `protobuf:"varint,3,opt,name=passed,proto3" json:"passed,omitempty"`                                                   // Overall health self-assessment
+	Model              string                 `protobuf:"bytes,4,opt,name=model,proto3" json:"model,omitempty"`                                                      // Model name
+	TemperatureCelsius int32                  `protobuf:"varint,5,opt,name=temperature_celsius,json=temperatureCelsius,proto3" json:"temperature_celsius,omitempty"` // Current temperature, 0 if unknown
+	PowerOnHours       int64                  `protobuf:"varint,6,opt,name=power_on_hours,json=powerOnHours,proto3" json:"power_on_hours,omitempty"`                 // 0 if unknown
+	PercentageUsed     int32                  `protobuf:"varint,7,opt,name=percentage_used,json=percentageUsed,proto3" json:"percentage_used,omitempty"`             // NVMe wear estimate, 0 if unknown or not NVMe
+	ReallocatedSectors int64                  `protobuf:"varint,8,opt,name=reallocated_sectors,json=reallocatedSectors,proto3" json:"reallocated_sectors,omitempty"` // ATA attribute 5 raw value, 0 if unknown or not ATA
+	Error              string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`                                                      // Why no SMART data is available
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *DiskSMART) Reset() {
+	*x = DiskSMART{}
+	mi := &file_report_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiskSMART) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiskSMART) ProtoMessage() {}
+
+func (x *DiskSMART) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiskSMART.ProtoReflect.Descriptor instead.
+func (*DiskSMART) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DiskSMART) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *DiskSMART) GetAvailable() bool {
+	if x != nil {
+		return x.Available
+	}
+	return false
+}
+
+func (x *DiskSMART) GetPassed() bool {
+	if x != nil {
+		return x.Passed
+	}
+	return false
+}
+
+func (x *DiskSMART) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *DiskSMART) GetTemperatureCelsius() int32 {
+	if x != nil {
+		return x.TemperatureCelsius
+	}
+	return 0
+}
+
+func (x *DiskSMART) GetPowerOnHours() int64 {
+	if x != nil {
+		return x.PowerOnHours
+	}
+	return 0
+}
+
+func (x *DiskSMART) GetPercentageUsed() int32 {
+	if x != nil {
+		return x.PercentageUsed
+	}
+	return 0
+}
+
+func (x *DiskSMART) GetReallocatedSectors() int64 {
+	if x != nil {
+		return x.ReallocatedSectors
+	}
+	return 0
+}
+
+func (x *DiskSMART) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// SelfTestStatus is the outcome of the last pipeline self-test
+type SelfTestStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LastRun       int64                  `protobuf:"varint,1,opt,name=last_run,json=lastRun,proto3" json:"last_run,omitempty"` // Unix seconds
+	Passed        bool                   `protobuf:"varint,2,opt,name=passed,proto3" json:"passed,omitempty"`
+	Failures      []string               `protobuf:"bytes,3,rep,name=failures,proto3" json:"failures,omitempty"`                        // Invariant violations of the last run (capped)
+	Runs          uint64                 `protobuf:"varint,4,opt,name=runs,proto3" json:"runs,omitempty"`                               // Runs since agent start
+	FailedRuns    uint64                 `protobuf:"varint,5,opt,name=failed_runs,json=failedRuns,proto3" json:"failed_runs,omitempty"` // Failed runs since agent start
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SelfTestStatus) Reset() {
+	*x = SelfTestStatus{}
+	mi := &file_report_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SelfTestStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SelfTestStatus) ProtoMessage() {}
+
+func (x *SelfTestStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SelfTestStatus.ProtoReflect.Descriptor instead.
+func (*SelfTestStatus) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SelfTestStatus) GetLastRun() int64 {
+	if x != nil {
+		return x.LastRun
+	}
+	return 0
+}
+
+func (x *SelfTestStatus) GetPassed() bool {
+	if x != nil {
+		return x.Passed
+	}
+	return false
+}
+
+func (x *SelfTestStatus) GetFailures() []string {
+	if x != nil {
+		return x.Failures
+	}
+	return nil
+}
+
+func (x *SelfTestStatus) GetRuns() uint64 {
+	if x != nil {
+		return x.Runs
+	}
+	return 0
+}
+
+func (x *SelfTestStatus) GetFailedRuns() uint64 {
+	if x != nil {
+		return x.FailedRuns
+	}
+	return 0
+}
+
+// DNSCheck is the DNS health of a domain users connect to
+type DNSCheck struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Domain               string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Resolves             bool                   `protobuf:"varint,2,opt,name=resolves,proto3" json:"resolves,omitempty"`                                                     // At least one resolver returned addresses
+	PointsHere           bool                   `protobuf:"varint,3,opt,name=points_here,json=pointsHere,proto3" json:"points_here,omitempty"`                               // An address users see is a public IP of this node
+	ResolvedIps          []string               `protobuf:"bytes,4,rep,name=resolved_ips,json=resolvedIps,proto3" json:"resolved_ips,omitempty"`                             // Addresses users see (external resolver when it answers)
+	ResolverDisagreement bool                   `protobuf:"varint,5,opt,name=resolver_disagreement,json=resolverDisagreement,proto3" json:"resolver_disagreement,omitempty"` // System and external resolvers differ (e.g. split horizon)
+	Error                string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`                                                            // Resolution failure
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *DNSCheck) Reset() {
+	*x = DNSCheck{}
+	mi := &file_report_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSCheck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSCheck) ProtoMessage() {}
+
+func (x *DNSCheck) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSCheck.ProtoReflect.Descriptor instead.
+func (*DNSCheck) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DNSCheck) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *DNSCheck) GetResolves() bool {
+	if x != nil {
+		return x.Resolves
+	}
+	return false
+}
+
+func (x *DNSCheck) GetPointsHere() bool {
+	if x != nil {
+		return x.PointsHere
+	}
+	return false
+}
+
+func (x *DNSCheck) GetResolvedIps() []string {
+	if x != nil {
+		return x.ResolvedIps
+	}
+	return nil
+}
+
+func (x *DNSCheck) GetResolverDisagreement() bool {
+	if x != nil {
+		return x.ResolverDisagreement
+	}
+	return false
+}
+
+func (x *DNSCheck) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// CertExpiry describes a certificate file referenced by the Hysteria2 or an inbound TLS config
+type CertExpiry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`                          // Certificate file
+	Origin        string                 `protobuf:"bytes,2,opt,name=origin,proto3" json:"origin,omitempty"`                      // Referencing config ("hysteria2", "inbound:<port>")
+	Subject       string                 `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`                    // Certificate common name
+	NotAfter      int64                  `protobuf:"varint,4,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"` // Expiry (unix seconds), 0 when unreadable
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`                        // Why the file could not be read or parsed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CertExpiry) Reset() {
+	*x = CertExpiry{}
+	mi := &file_report_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CertExpiry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CertExpiry) ProtoMessage() {}
+
+func (x *CertExpiry) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CertExpiry.ProtoReflect.Descriptor instead.
+func (*CertExpiry) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CertExpiry) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *CertExpiry) GetOrigin() string {
+	if x != nil {
+		return x.Origin
+	}
+	return ""
+}
+
+func (x *CertExpiry) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *CertExpiry) GetNotAfter() int64 {
+	if x != nil {
+		return x.NotAfter
+	}
+	return 0
+}
+
+func (x *CertExpiry) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// PortListener describes which local process listens on an inbound port
+type PortListener struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Port            int32                  `protobuf:"varint,1,opt,name=port,proto3" json:"port,omitempty"`                                             // Inbound port
+	ListenerProcess string                 `protobuf:"bytes,2,opt,name=listener_process,json=listenerProcess,proto3" json:"listener_process,omitempty"` // Process owning the listening socket
+	IsXray          bool                   `protobuf:"varint,3,opt,name=is_xray,json=isXray,proto3" json:"is_xray,omitempty"`                           // Listener is xray itself
+	IsFrontend      bool                   `protobuf:"varint,4,opt,name=is_frontend,json=isFrontend,proto3" json:"is_frontend,omitempty"`               // Listener is a non-xray frontend (SNI demux etc.)
+	Unknown         bool                   `protobuf:"varint,5,opt,name=unknown,proto3" json:"unknown,omitempty"`                                       // Owner could not be determined
+	ListenerLost    bool                   `protobuf:"varint,6,opt,name=listener_lost,json=listenerLost,proto3" json:"listener_lost,omitempty"`         // A previously-frontended port lost its listener
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PortListener) Reset() {
+	*x = PortListener{}
+	mi := &file_report_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortListener) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortListener) ProtoMessage() {}
+
+func (x *PortListener) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortListener.ProtoReflect.Descriptor instead.
+func (*PortListener) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *PortListener) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *PortListener) GetListenerProcess() string {
+	if x != nil {
+		return x.ListenerProcess
+	}
+	return ""
+}
+
+func (x *PortListener) GetIsXray() bool {
+	if x != nil {
+		return x.IsXray
+	}
+	return false
+}
+
+func (x *PortListener) GetIsFrontend() bool {
+	if x != nil {
+		return x.IsFrontend
+	}
+	return false
+}
+
+func (x *PortListener) GetUnknown() bool {
+	if x != nil {
+		return x.Unknown
+	}
+	return false
+}
+
+func (x *PortListener) GetListenerLost() bool {
+	if x != nil {
+		return x.ListenerLost
+	}
+	return false
+}
+
+// MemoryInfo contains memory usage information
+type MemoryInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Current       int64                  `protobuf:"varint,1,opt,name=current,proto3" json:"current,omitempty"` // Current memory usage
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`     // Total memory
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MemoryInfo) Reset() {
+	*x = MemoryInfo{}
+	mi := &file_report_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MemoryInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MemoryInfo) ProtoMessage() {}
+
+func (x *MemoryInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MemoryInfo.ProtoReflect.Descriptor instead.
+func (*MemoryInfo) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *MemoryInfo) GetCurrent() int64 {
+	if x != nil {
+		return x.Current
+	}
+	return 0
+}
+
+func (x *MemoryInfo) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// SwapInfo contains swap space information
+type SwapInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Current       int64                  `protobuf:"varint,1,opt,name=current,proto3" json:"current,omitempty"` // Current swap usage
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`     // Total swap space
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SwapInfo) Reset() {
+	*x = SwapInfo{}
+	mi := &file_report_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SwapInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwapInfo) ProtoMessage() {}
+
+func (x *SwapInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwapInfo.ProtoReflect.Descriptor instead.
+func (*SwapInfo) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SwapInfo) GetCurrent() int64 {
+	if x != nil {
+		return x.Current
+	}
+	return 0
+}
+
+func (x *SwapInfo) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// DiskInfo contains disk usage information
+type DiskInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Current       int64                  `protobuf:"varint,1,opt,name=current,proto3" json:"current,omitempty"` // Current disk usage
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`     // Total disk space
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiskInfo) Reset() {
+	*x = DiskInfo{}
+	mi := &file_report_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiskInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiskInfo) ProtoMessage() {}
+
+func (x *DiskInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiskInfo.ProtoReflect.Descriptor instead.
+func (*DiskInfo) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DiskInfo) GetCurrent() int64 {
+	if x != nil {
+		return x.Current
+	}
+	return 0
+}
+
+func (x *DiskInfo) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// NetIOInfo contains network IO information
+type NetIOInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Up            int64                  `protobuf:"varint,1,opt,name=up,proto3" json:"up,omitempty"`     // Upload traffic
+	Down          int64                  `protobuf:"varint,2,opt,name=down,proto3" json:"down,omitempty"` // Download traffic
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NetIOInfo) Reset() {
+	*x = NetIOInfo{}
+	mi := &file_report_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NetIOInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetIOInfo) ProtoMessage() {}
+
+func (x *NetIOInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetIOInfo.ProtoReflect.Descriptor instead.
+func (*NetIOInfo) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *NetIOInfo) GetUp() int64 {
+	if x != nil {
+		return x.Up
+	}
+	return 0
+}
+
+func (x *NetIOInfo) GetDown() int64 {
+	if x != nil {
+		return x.Down
+	}
+	return 0
+}
+
+// NetTraffic contains network traffic information
+type NetTraffic struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sent          int64                  `protobuf:"varint,1,opt,name=sent,proto3" json:"sent,omitempty"` // Sent traffic
+	Recv          int64                  `protobuf:"varint,2,opt,name=recv,proto3" json:"recv,omitempty"` // Received traffic
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NetTraffic) Reset() {
+	*x = NetTraffic{}
+	mi := &file_report_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NetTraffic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetTraffic) ProtoMessage() {}
+
+func (x *NetTraffic) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetTraffic.ProtoReflect.Descriptor instead.
+func (*NetTraffic) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *NetTraffic) GetSent() int64 {
+	if x != nil {
+		return x.Sent
+	}
+	return 0
+}
+
+func (x *NetTraffic) GetRecv() int64 {
+	if x != nil {
+		return x.Recv
+	}
+	return 0
+}
+
+// XrayInfo contains Xray status information
+type XrayInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         string                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`                       // Running status ("running" or other)
+	ErrorMsg      string                 `protobuf:"bytes,2,opt,name=error_msg,json=errorMsg,proto3" json:"error_msg,omitempty"` // Error message
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`                   // Version
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XrayInfo) Reset() {
+	*x = XrayInfo{}
+	mi := &file_report_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XrayInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XrayInfo) ProtoMessage() {}
+
+func (x *XrayInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XrayInfo.ProtoReflect.Descriptor instead.
+func (*XrayInfo) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *XrayInfo) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *XrayInfo) GetErrorMsg() string {
+	if x != nil {
+		return x.ErrorMsg
+	}
+	return ""
+}
+
+func (x *XrayInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+// PublicIPInfo contains public IP information
+type PublicIPInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ipv4          string                 `protobuf:"bytes,1,opt,name=ipv4,proto3" json:"ipv4,omitempty"` // IPv4 address
+	Ipv6          string                 `protobuf:"bytes,2,opt,name=ipv6,proto3" json:"ipv6,omitempty"` // IPv6 address
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublicIPInfo) Reset() {
+	*x = PublicIPInfo{}
+	mi := &file_report_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublicIPInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublicIPInfo) ProtoMessage() {}
+
+func (x *PublicIPInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublicIPInfo.ProtoReflect.Descriptor instead.
+func (*PublicIPInfo) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *PublicIPInfo) GetIpv4() string {
+	if x != nil {
+		return x.Ipv4
+	}
+	return ""
+}
+
+func (x *PublicIPInfo) GetIpv6() string {
+	if x != nil {
+		return x.Ipv6
+	}
+	return ""
+}
+
+// AppStats contains application status information
+type AppStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Threads       int32                  `protobuf:"varint,1,opt,name=threads,proto3" json:"threads,omitempty"` // Thread count
+	Memory        int64                  `protobuf:"varint,2,opt,name=memory,proto3" json:"memory,omitempty"`   // Application memory usage
+	Uptime        int32                  `protobuf:"varint,3,opt,name=uptime,proto3" json:"uptime,omitempty"`   // Application uptime
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppStats) Reset() {
+	*x = AppStats{}
+	mi := &file_report_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppStats) ProtoMessage() {}
+
+func (x *AppStats) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppStats.ProtoReflect.Descriptor instead.
+func (*AppStats) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *AppStats) GetThreads() int32 {
+	if x != nil {
+		return x.Threads
+	}
+	return 0
+}
+
+func (x *AppStats) GetMemory() int64 {
+	if x != nil {
+		return x.Memory
+	}
+	return 0
+}
+
+func (x *AppStats) GetUptime() int32 {
+	if x != nil {
+		return x.Uptime
+	}
+	return 0
+}
+
+// SubscriptionReportRequest contains subscription data to be reported
+type SubscriptionReportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                   // Agent unique identifier
+	Subscriptions []*SubscriptionData    `protobuf:"bytes,2,rep,name=subscriptions,proto3" json:"subscriptions,omitempty"` // Subscription data list
+	// Set when an oversized report is split: the chunks of a batch together are the snapshot
+	BatchId       string `protobuf:"bytes,3,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`           // Identifies the chunks of one split report
+	ChunkIndex    int32  `protobuf:"varint,4,opt,name=chunk_index,json=chunkIndex,proto3" json:"chunk_index,omitempty"` // Zero-based position of this chunk
+	ChunkCount    int32  `protobuf:"varint,5,opt,name=chunk_count,json=chunkCount,proto3" json:"chunk_count,omitempty"` // Number of chunks in the batch, 0 when not split
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscriptionReportRequest) Reset() {
+	*x = SubscriptionReportRequest{}
+	mi := &file_report_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscriptionReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscriptionReportRequest) ProtoMessage() {}
+
+func (x *SubscriptionReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscriptionReportRequest.ProtoReflect.Descriptor instead.
+func (*SubscriptionReportRequest) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *SubscriptionReportRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *SubscriptionReportRequest) GetSubscriptions() []*SubscriptionData {
+	if x != nil {
+		return x.Subscriptions
+	}
+	return nil
+}
+
+func (x *SubscriptionReportRequest) GetBatchId() string {
+	if x != nil {
+		return x.BatchId
+	}
+	return ""
+}
+
+func (x *SubscriptionReportRequest) GetChunkIndex() int32 {
+	if x != nil {
+		return x.ChunkIndex
+	}
+	return 0
+}
+
+func (x *SubscriptionReportRequest) GetChunkCount() int32 {
+	if x != nil {
+		return x.ChunkCount
+	}
+	return 0
+}
+
+// SubscriptionData contains individual subscription information
+type SubscriptionData struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	SubId               string                 `protobuf:"bytes,1,opt,name=sub_id,json=subId,proto3" json:"sub_id,omitempty"`                                              // Subscription ID
+	Email               string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`                                                           // Client email
+	NodeConfig          string                 `protobuf:"bytes,3,opt,name=node_config,json=nodeConfig,proto3" json:"node_config,omitempty"`                               // Base64 encoded node configuration
+	Headers             *SubscriptionHeaders   `protobuf:"bytes,4,opt,name=headers,proto3" json:"headers,omitempty"`                                                       // HTTP response headers
+	ContentStaleSuspect bool                   `protobuf:"varint,5,opt,name=content_stale_suspect,json=contentStaleSuspect,proto3" json:"content_stale_suspect,omitempty"` // Content contradicts the panel client list (stale sub service cache?)
+	StaleReason         string                 `protobuf:"bytes,6,opt,name=stale_reason,json=staleReason,proto3" json:"stale_reason,omitempty"`                            // Why the content looks stale
+	JsonConfig          string                 `protobuf:"bytes,7,opt,name=json_config,json=jsonConfig,proto3" json:"json_config,omitempty"`                               // JSON (sing-box) subscription from subJsonURI, empty when not collected
+	WireguardConfig     string                 `protobuf:"bytes,8,opt,name=wireguard_config,json=wireguardConfig,proto3" json:"wireguard_config,omitempty"`                // WireGuard client config stub (wireguard_peers_file), PrivateKey left for xhub to fill in
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *SubscriptionData) Reset() {
+	*x = SubscriptionData{}
+	mi := &file_report_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscriptionData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscriptionData) ProtoMessage() {}
+
+func (x *SubscriptionData) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscriptionData.ProtoReflect.Descriptor instead.
+func (*SubscriptionData) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *SubscriptionData) GetSubId() string {
+	if x != nil {
+		return x.SubId
+	}
+	return ""
+}
+
+func (x *SubscriptionData) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *SubscriptionData) GetNodeConfig() string {
+	if x != nil {
+		return x.NodeConfig
+	}
+	return ""
+}
+
+func (x *SubscriptionData) GetHeaders() *SubscriptionHeaders {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *SubscriptionData) GetContentStaleSuspect() bool {
+	if x != nil {
+		return x.ContentStaleSuspect
+	}
+	return false
+}
+
+func (x *SubscriptionData) GetStaleReason() string {
+	if x != nil {
+		return x.StaleReason
+	}
+	return ""
+}
+
+func (x *SubscriptionData) GetJsonConfig() string {
+	if x != nil {
+		return x.JsonConfig
+	}
+	return ""
+}
+
+func (x *SubscriptionData) GetWireguardConfig() string {
+	if x != nil {
+		return x.WireguardConfig
+	}
+	return ""
+}
+
+// SubscriptionHeaders contains HTTP response headers from subscription endpoint
+type SubscriptionHeaders struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	ProfileTitle          string                 `protobuf:"bytes,1,opt,name=profile_title,json=profileTitle,proto3" json:"profile_title,omitempty"`                              // profile-title header (base64 encoded)
+	ProfileUpdateInterval string                 `protobuf:"bytes,2,opt,name=profile_update_interval,json=profileUpdateInterval,proto3" json:"profile_update_interval,omitempty"` // profile-update-interval header
+	SubscriptionUserinfo  string                 `protobuf:"bytes,3,opt,name=subscription_userinfo,json=subscriptionUserinfo,proto3" json:"subscription_userinfo,omitempty"`      // subscription-userinfo header
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *SubscriptionHeaders) Reset() {
+	*x = SubscriptionHeaders{}
+	mi := &file_report_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscriptionHeaders) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscriptionHeaders) ProtoMessage() {}
+
+func (x *SubscriptionHeaders) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscriptionHeaders.ProtoReflect.Descriptor instead.
+func (*SubscriptionHeaders) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *SubscriptionHeaders) GetProfileTitle() string {
+	if x != nil {
+		return x.ProfileTitle
+	}
+	return ""
+}
+
+func (x *SubscriptionHeaders) GetProfileUpdateInterval() string {
+	if x != nil {
+		return x.ProfileUpdateInterval
+	}
+	return ""
+}
+
+func (x *SubscriptionHeaders) GetSubscriptionUserinfo() string {
+	if x != nil {
+		return x.SubscriptionUserinfo
+	}
+	return ""
+}
+
+// OnlineUsersReportRequest contains online users data to be reported
+type OnlineUsersReportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                     // Agent unique identifier
+	OnlineEmails  []string               `protobuf:"bytes,2,rep,name=online_emails,json=onlineEmails,proto3" json:"online_emails,omitempty"` // Online user emails list
+	Users         []*OnlineUser          `protobuf:"bytes,3,rep,name=users,proto3" json:"users,omitempty"`                                   // Connection details of the online users (online_user_details), empty when off
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OnlineUsersReportRequest) Reset() {
+	*x = OnlineUsersReportRequest{}
+	mi := &file_report_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OnlineUsersReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OnlineUsersReportRequest) ProtoMessage() {}
+
+func (x *OnlineUsersReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OnlineUsersReportRequest.ProtoReflect.Descriptor instead.
+func (*OnlineUsersReportRequest) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *OnlineUsersReportRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *OnlineUsersReportRequest) GetOnlineEmails() []string {
+	if x != nil {
+		return x.OnlineEmails
+	}
+	return nil
+}
+
+func (x *OnlineUsersReportRequest) GetUsers() []*OnlineUser {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+// OnlineUser is an online user with the source IPs it connected from
+type OnlineUser struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"` // Same value as in online_emails
+	Ips           []*ClientIP            `protobuf:"bytes,2,rep,name=ips,proto3" json:"ips,omitempty"`     // Most recently seen first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OnlineUser) Reset() {
+	*x = OnlineUser{}
+	mi := &file_report_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OnlineUser) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OnlineUser) ProtoMessage() {}
+
+func (x *OnlineUser) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OnlineUser.ProtoReflect.Descriptor instead.
+func (*OnlineUser) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *OnlineUser) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *OnlineUser) GetIps() []*ClientIP {
+	if x != nil {
+		return x.Ips
+	}
+	return nil
+}
+
+// ClientIP is a source IP of an online user
+type ClientIP struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	LastSeen      int64                  `protobuf:"varint,2,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"` // Unix time, 0 when unknown
+	Connections   int32                  `protobuf:"varint,3,opt,name=connections,proto3" json:"connections,omitempty"`           // Connections accepted in the access log window, 0 when unknown
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClientIP) Reset() {
+	*x = ClientIP{}
+	mi := &file_report_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientIP) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientIP) ProtoMessage() {}
+
+func (x *ClientIP) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientIP.ProtoReflect.Descriptor instead.
+func (*ClientIP) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ClientIP) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *ClientIP) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+func (x *ClientIP) GetConnections() int32 {
+	if x != nil {
+		return x.Connections
+	}
+	return 0
+}
+
+// StreamReportRequest is one status report on the report stream
+type StreamReportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"` // Increases by one per report on the stream, starting at 1
+	Report        *ReportRequest         `protobuf:"bytes,2,opt,name=report,proto3" json:"report,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamReportRequest) Reset() {
+	*x = StreamReportRequest{}
+	mi := &file_report_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamReportRequest) ProtoMessage() {}
+
+func (x *StreamReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamReportRequest.ProtoReflect.Descriptor instead.
+func (*StreamReportRequest) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *StreamReportRequest) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *StreamReportRequest) GetReport() *ReportRequest {
+	if x != nil {
+		return x.Report
+	}
+	return nil
+}
+
+// StreamReportAck acknowledges the report with the same sequence
+type StreamReportAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"` // Same meaning as ReportResponse.success
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	MinIntervalMs int64                  `protobuf:"varint,4,opt,name=min_interval_ms,json=minIntervalMs,proto3" json:"min_interval_ms,omitempty"` // Backpressure: minimum time between reports, 0 lifts it
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamReportAck) Reset() {
+	*x = StreamReportAck{}
+	mi := &file_report_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamReportAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamReportAck) ProtoMessage() {}
+
+func (x *StreamReportAck) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamReportAck.ProtoReflect.Descriptor instead.
+func (*StreamReportAck) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *StreamReportAck) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *StreamReportAck) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *StreamReportAck) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *StreamReportAck) GetMinIntervalMs() int64 {
+	if x != nil {
+		return x.MinIntervalMs
+	}
+	return 0
+}
+
+// CombinedReportRequest carries the payloads of one cycle in a single request (report_combined)
+type CombinedReportRequest struct {
+	state          protoimpl.MessageState     `protogen:"open.v1"`
+	Uuid           string                     `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                              // Agent unique identifier
+	Data           *ServerStatusData          `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`                                              // Server status data
+	ErrorCounts    []*ErrorCategoryCount      `protobuf:"bytes,3,rep,name=error_counts,json=errorCounts,proto3" json:"error_counts,omitempty"`             // Errors per category since the last acknowledged report
+	OnlineUsers    *OnlineUsersReportRequest  `protobuf:"bytes,4,opt,name=online_users,json=onlineUsers,proto3" json:"online_users,omitempty"`             // Absent when no online users list is available this cycle
+	OnlineUsersAge int64                      `protobuf:"varint,5,opt,name=online_users_age,json=onlineUsersAge,proto3" json:"online_users_age,omitempty"` // Seconds since a reused online users list was fetched, 0 when fresh
+	Subscriptions  *SubscriptionReportRequest `protobuf:"bytes,6,opt,name=subscriptions,proto3" json:"subscriptions,omitempty"`                            // Absent when subscriptions are not included this cycle
+	Agent          *AgentInfo                 `protobuf:"bytes,7,opt,name=agent,proto3" json:"agent,omitempty"`                                            // Agent and host metadata, collected at startup
+	Delta          *StatusDelta               `protobuf:"bytes,8,opt,name=delta,proto3" json:"delta,omitempty"`                                            // Set by report_delta, as in ReportRequest
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CombinedReportRequest) Reset() {
+	*x = CombinedReportRequest{}
+	mi := &file_report_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CombinedReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CombinedReportRequest) ProtoMessage() {}
+
+func (x *CombinedReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CombinedReportRequest.ProtoReflect.Descriptor instead.
+func (*CombinedReportRequest) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *CombinedReportRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *CombinedReportRequest) GetData() *ServerStatusData {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *CombinedReportRequest) GetErrorCounts() []*ErrorCategoryCount {
+	if x != nil {
+		return x.ErrorCounts
+	}
+	return nil
+}
+
+func (x *CombinedReportRequest) GetOnlineUsers() *OnlineUsersReportRequest {
+	if x != nil {
+		return x.OnlineUsers
+	}
+	return nil
+}
+
+func (x *CombinedReportRequest) GetOnlineUsersAge() int64 {
+	if x != nil {
+		return x.OnlineUsersAge
+	}
+	return 0
+}
+
+func (x *CombinedReportRequest) GetSubscriptions() *SubscriptionReportRequest {
+	if x != nil {
+		return x.Subscriptions
+	}
+	return nil
+}
+
+func (x *CombinedReportRequest) GetAgent() *AgentInfo {
+	if x != nil {
+		return x.Agent
+	}
+	return nil
+}
+
+func (x *CombinedReportRequest) GetDelta() *StatusDelta {
+	if x != nil {
+		return x.Delta
+	}
+	return nil
+}
+
+// BackupReportRequest carries a snapshot of the 3x-ui inbound configuration (inbound_backup)
+type BackupReportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                      // Agent unique identifier
+	InboundsJson  []byte                 `protobuf:"bytes,2,opt,name=inbounds_json,json=inboundsJson,proto3" json:"inbounds_json,omitempty"`  // JSON array of the /panel/inbound/list inbounds, traffic counters removed
+	Sha256        string                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"`                                  // Hex SHA-256 of inbounds_json, changes with the configuration
+	InboundCount  int32                  `protobuf:"varint,4,opt,name=inbound_count,json=inboundCount,proto3" json:"inbound_count,omitempty"` // Number of inbounds in the snapshot
+	CapturedAt    int64                  `protobuf:"varint,5,opt,name=captured_at,json=capturedAt,proto3" json:"captured_at,omitempty"`       // Unix time the snapshot was taken
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupReportRequest) Reset() {
+	*x = BackupReportRequest{}
+	mi := &file_report_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupReportRequest) ProtoMessage() {}
+
+func (x *BackupReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupReportRequest.ProtoReflect.Descriptor instead.
+func (*BackupReportRequest) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *BackupReportRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *BackupReportRequest) GetInboundsJson() []byte {
+	if x != nil {
+		return x.InboundsJson
+	}
+	return nil
+}
+
+func (x *BackupReportRequest) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *BackupReportRequest) GetInboundCount() int32 {
+	if x != nil {
+		return x.InboundCount
+	}
+	return 0
+}
+
+func (x *BackupReportRequest) GetCapturedAt() int64 {
+	if x != nil {
+		return x.CapturedAt
+	}
+	return 0
+}
+
+// CommandSubscription opens the command stream of an agent
+type CommandSubscription struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`         // Agent unique identifier
+	Commands      []string               `protobuf:"bytes,2,rep,name=commands,proto3" json:"commands,omitempty"` // Commands the agent accepts (its allowlist)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandSubscription) Reset() {
+	*x = CommandSubscription{}
+	mi := &file_report_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandSubscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandSubscription) ProtoMessage() {}
+
+func (x *CommandSubscription) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandSubscription.ProtoReflect.Descriptor instead.
+func (*CommandSubscription) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *CommandSubscription) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *CommandSubscription) GetCommands() []string {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+// Command is a command issued by xhub
+type Command struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                                                               // Unique per command, echoed in the result
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                                                                           // e.g. restart_xray, resync_subscriptions, adjust_poll_interval, fetch_logs
+	Args          map[string]string      `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Command arguments, e.g. seconds for adjust_poll_interval
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Command) Reset() {
+	*x = Command{}
+	mi := &file_report_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Command) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Command) ProtoMessage() {}
+
+func (x *Command) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Command.ProtoReflect.Descriptor instead.
+func (*Command) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *Command) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Command) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Command) GetArgs() map[string]string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+// ProvisioningSubscription opens the provisioning stream of an agent
+type ProvisioningSubscription struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"` // Agent unique identifier
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProvisioningSubscription) Reset() {
+	*x = ProvisioningSubscription{}
+	mi := &file_report_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProvisioningSubscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProvisioningSubscription) ProtoMessage() {}
+
+func (x *ProvisioningSubscription) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProvisioningSubscription.ProtoReflect.Descriptor instead.
+func (*ProvisioningSubscription) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ProvisioningSubscription) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+// ProvisioningRequest adds, updates or removes a client of a 3x-ui inbound
+type ProvisioningRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	IdempotencyKey string                 `protobuf:"bytes,1,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"` // Unique per change, echoed in the result; resent requests keep it
+	Action         string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`                                       // add_client, update_client or remove_client
+	InboundId      int32                  `protobuf:"varint,3,opt,name=inbound_id,json=inboundId,proto3" json:"inbound_id,omitempty"`               // Inbound of the client
+	ClientId       string                 `protobuf:"bytes,4,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`                   // update/remove: client id (UUID, trojan password or shadowsocks email)
+	ClientJson     []byte                 `protobuf:"bytes,5,opt,name=client_json,json=clientJson,proto3" json:"client_json,omitempty"`             // add/update: 3x-ui client object, e.g. {"id": "...", "email": "..."}
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ProvisioningRequest) Reset() {
+	*x = ProvisioningRequest{}
+	mi := &file_report_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProvisioningRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProvisioningRequest) ProtoMessage() {}
+
+func (x *ProvisioningRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProvisioningRequest.ProtoReflect.Descriptor instead.
+func (*ProvisioningRequest) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *ProvisioningRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *ProvisioningRequest) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *ProvisioningRequest) GetInboundId() int32 {
+	if x != nil {
+		return x.InboundId
+	}
+	return 0
+}
+
+func (x *ProvisioningRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *ProvisioningRequest) GetClientJson() []byte {
+	if x != nil {
+		return x.ClientJson
+	}
+	return nil
+}
+
+// ProvisioningResult is the outcome of a provisioning request
+type ProvisioningResult struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Uuid           string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                           // Agent unique identifier
+	IdempotencyKey string                 `protobuf:"bytes,2,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"` // Key of the request
+	Action         string                 `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`                                       // Action of the request
+	Success        bool                   `protobuf:"varint,4,opt,name=success,proto3" json:"success,omitempty"`
+	Error          string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`                              // Why the change failed or was refused
+	Replayed       bool                   `protobuf:"varint,6,opt,name=replayed,proto3" json:"replayed,omitempty"`                       // The key was already applied; this is the stored outcome
+	DurationMs     int64                  `protobuf:"varint,7,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"` // Time spent applying the change
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ProvisioningResult) Reset() {
+	*x = ProvisioningResult{}
+	mi := &file_report_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProvisioningResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProvisioningResult) ProtoMessage() {}
+
+func (x *ProvisioningResult) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProvisioningResult.ProtoReflect.Descriptor instead.
+func (*ProvisioningResult) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *ProvisioningResult) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *ProvisioningResult) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *ProvisioningResult) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *ProvisioningResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ProvisioningResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ProvisioningResult) GetReplayed() bool {
+	if x != nil {
+		return x.Replayed
+	}
+	return false
+}
+
+func (x *ProvisioningResult) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+// CommandResult is the outcome of a command
+type CommandResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"` // Agent unique identifier
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`     // Command id
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"` // Command name
+	Success       bool                   `protobuf:"varint,4,opt,name=success,proto3" json:"success,omitempty"`
+	Output        string                 `protobuf:"bytes,5,opt,name=output,proto3" json:"output,omitempty"`                            // Command output, capped at 64 KiB
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`                              // Why the command failed or was refused
+	DurationMs    int64                  `protobuf:"varint,7,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"` // Execution time
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandResult) Reset() {
+	*x = CommandResult{}
+	mi := &file_report_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandResult) ProtoMessage() {}
+
+func (x *CommandResult) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandResult.ProtoReflect.Descriptor instead.
+func (*CommandResult) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *CommandResult) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *CommandResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CommandResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CommandResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CommandResult) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+func (x *CommandResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CommandResult) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+// ShutdownNotice is the last message of an agent stopping cleanly
+type ShutdownNotice struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                         // Agent unique identifier
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`                                     // Why the agent stops, e.g. "signal terminated" or "config reload"
+	UptimeSeconds int64                  `protobuf:"varint,3,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"` // Time since the agent started
+	QueuedReports int32                  `protobuf:"varint,4,opt,name=queued_reports,json=queuedReports,proto3" json:"queued_reports,omitempty"` // Reports left undelivered in the offline queue
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShutdownNotice) Reset() {
+	*x = ShutdownNotice{}
+	mi := &file_report_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShutdownNotice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShutdownNotice) ProtoMessage() {}
+
+func (x *ShutdownNotice) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShutdownNotice.ProtoReflect.Descriptor instead.
+func (*ShutdownNotice) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *ShutdownNotice) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *ShutdownNotice) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ShutdownNotice) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *ShutdownNotice) GetQueuedReports() int32 {
+	if x != nil {
+		return x.QueuedReports
+	}
+	return 0
+}
+
+// CrashReport describes a panic that ended an agent run
+type CrashReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                         // Agent unique identifier
+	CrashedAt     int64                  `protobuf:"varint,2,opt,name=crashed_at,json=crashedAt,proto3" json:"crashed_at,omitempty"`             // Unix time of the panic
+	Component     string                 `protobuf:"bytes,3,opt,name=component,proto3" json:"component,omitempty"`                               // Goroutine that panicked, e.g. "work loop"
+	Panic         string                 `protobuf:"bytes,4,opt,name=panic,proto3" json:"panic,omitempty"`                                       // Panic value
+	Stack         string                 `protobuf:"bytes,5,opt,name=stack,proto3" json:"stack,omitempty"`                                       // Stack trace of the panicking goroutine, capped at 64 KiB
+	AgentVersion  string                 `protobuf:"bytes,6,opt,name=agent_version,json=agentVersion,proto3" json:"agent_version,omitempty"`     // Version of the crashed agent
+	UptimeSeconds int64                  `protobuf:"varint,7,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"` // Time the crashed run had been up
+	RestartCount  int64                  `protobuf:"varint,8,opt,name=restart_count,json=restartCount,proto3" json:"restart_count,omitempty"`    // Start number of the crashed run
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrashReport) Reset() {
+	*x = CrashReport{}
+	mi := &file_report_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrashReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrashReport) ProtoMessage() {}
+
+func (x *CrashReport) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrashReport.ProtoReflect.Descriptor instead.
+func (*CrashReport) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *CrashReport) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *CrashReport) GetCrashedAt() int64 {
+	if x != nil {
+		return x.CrashedAt
+	}
+	return 0
+}
+
+func (x *CrashReport) GetComponent() string {
+	if x != nil {
+		return x.Component
+	}
+	return ""
+}
+
+func (x *CrashReport) GetPanic() string {
+	if x != nil {
+		return x.Panic
+	}
+	return ""
+}
+
+func (x *CrashReport) GetStack() string {
+	if x != nil {
+		return x.Stack
+	}
+	return ""
+}
+
+func (x *CrashReport) GetAgentVersion() string {
+	if x != nil {
+		return x.AgentVersion
+	}
+	return ""
+}
+
+func (x *CrashReport) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *CrashReport) GetRestartCount() int64 {
+	if x != nil {
+		return x.RestartCount
+	}
+	return 0
+}
+
+// HeartbeatRequest carries the health of the agent itself, not of the server it monitors
+type HeartbeatRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Uuid           string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                            // Agent unique identifier
+	AgentVersion   string                 `protobuf:"bytes,2,opt,name=agent_version,json=agentVersion,proto3" json:"agent_version,omitempty"`        // Agent version, e.g. "v1.0.0"
+	UptimeSeconds  int64                  `protobuf:"varint,3,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`    // Time since the agent started
+	PanelReachable bool                   `protobuf:"varint,4,opt,name=panel_reachable,json=panelReachable,proto3" json:"panel_reachable,omitempty"` // Whether the last report cycle reached the 3x-ui panel
+	LastError      string                 `protobuf:"bytes,5,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`                 // Error of the last failed report cycle, empty if none yet
+	LastErrorAt    int64                  `protobuf:"varint,6,opt,name=last_error_at,json=lastErrorAt,proto3" json:"last_error_at,omitempty"`        // Unix time of last_error, 0 if none
+	LastSuccessAt  int64                  `protobuf:"varint,7,opt,name=last_success_at,json=lastSuccessAt,proto3" json:"last_success_at,omitempty"`  // Unix time of the last successful report cycle, 0 if none
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_report_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *HeartbeatRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetAgentVersion() string {
+	if x != nil {
+		return x.AgentVersion
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetPanelReachable() bool {
+	if x != nil {
+		return x.PanelReachable
+	}
+	return false
+}
+
+func (x *HeartbeatRequest) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
 }
 
-func (x *ReportResponse) GetSuccess() bool {
+func (x *HeartbeatRequest) GetLastErrorAt() int64 {
 	if x != nil {
-		return x.Success
+		return x.LastErrorAt
 	}
-	return false
+	return 0
 }
 
-func (x *ReportResponse) GetMessage() string {
+func (x *HeartbeatRequest) GetLastSuccessAt() int64 {
 	if x != nil {
-		return x.Message
+		return x.LastSuccessAt
 	}
-	return ""
+	return 0
 }
 
-// ServerStatusData contains comprehensive server status information
-type ServerStatusData struct {
+// NetworkQualityReport is the outcome of a network probe run
+type NetworkQualityReport struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Cpu           float64                `protobuf:"fixed64,1,opt,name=cpu,proto3" json:"cpu,omitempty"`                                      // CPU usage rate
-	CpuCores      int32                  `protobuf:"varint,2,opt,name=cpu_cores,json=cpuCores,proto3" json:"cpu_cores,omitempty"`             // CPU core count
-	LogicalPro    int32                  `protobuf:"varint,3,opt,name=logical_pro,json=logicalPro,proto3" json:"logical_pro,omitempty"`       // Logical processor count
-	CpuSpeedMhz   float64                `protobuf:"fixed64,4,opt,name=cpu_speed_mhz,json=cpuSpeedMhz,proto3" json:"cpu_speed_mhz,omitempty"` // CPU frequency (MHz)
-	Memory        *MemoryInfo            `protobuf:"bytes,5,opt,name=memory,proto3" json:"memory,omitempty"`                                  // Memory information
-	Swap          *SwapInfo              `protobuf:"bytes,6,opt,name=swap,proto3" json:"swap,omitempty"`                                      // Swap space information
-	Disk          *DiskInfo              `protobuf:"bytes,7,opt,name=disk,proto3" json:"disk,omitempty"`                                      // Disk information
-	Uptime        int32                  `protobuf:"varint,8,opt,name=uptime,proto3" json:"uptime,omitempty"`                                 // Uptime (seconds)
-	Loads         []float64              `protobuf:"fixed64,9,rep,packed,name=loads,proto3" json:"loads,omitempty"`                           // System load
-	TcpCount      int32                  `protobuf:"varint,10,opt,name=tcp_count,json=tcpCount,proto3" json:"tcp_count,omitempty"`            // TCP connection count
-	UdpCount      int32                  `protobuf:"varint,11,opt,name=udp_count,json=udpCount,proto3" json:"udp_count,omitempty"`            // UDP connection count
-	NetIo         *NetIOInfo             `protobuf:"bytes,12,opt,name=net_io,json=netIo,proto3" json:"net_io,omitempty"`                      // Network IO
-	NetTraffic    *NetTraffic            `protobuf:"bytes,13,opt,name=net_traffic,json=netTraffic,proto3" json:"net_traffic,omitempty"`       // Network traffic
-	PublicIp      *PublicIPInfo          `protobuf:"bytes,14,opt,name=public_ip,json=publicIp,proto3" json:"public_ip,omitempty"`             // Public IP information
-	Xray          *XrayInfo              `protobuf:"bytes,15,opt,name=xray,proto3" json:"xray,omitempty"`                                     // Xray status
-	AppStats      *AppStats              `protobuf:"bytes,16,opt,name=app_stats,json=appStats,proto3" json:"app_stats,omitempty"`             // Application status
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                // Agent unique identifier
+	Trigger       string                 `protobuf:"bytes,2,opt,name=trigger,proto3" json:"trigger,omitempty"`                          // "schedule" or "command"
+	CommandId     string                 `protobuf:"bytes,3,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`     // Command that requested the run, empty when scheduled
+	StartedAt     int64                  `protobuf:"varint,4,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`    // Unix time the run started
+	DurationMs    int64                  `protobuf:"varint,5,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"` // Duration of the whole run
+	Probes        []*LatencyProbe        `protobuf:"bytes,6,rep,name=probes,proto3" json:"probes,omitempty"`                            // One per network_probe_targets entry
+	Throughput    *ThroughputProbe       `protobuf:"bytes,7,opt,name=throughput,proto3" json:"throughput,omitempty"`                    // Absent when not measured
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ServerStatusData) Reset() {
-	*x = ServerStatusData{}
-	mi := &file_report_proto_msgTypes[2]
+func (x *NetworkQualityReport) Reset() {
+	*x = NetworkQualityReport{}
+	mi := &file_report_proto_msgTypes[47]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ServerStatusData) String() string {
+func (x *NetworkQualityReport) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ServerStatusData) ProtoMessage() {}
+func (*NetworkQualityReport) ProtoMessage() {}
 
-func (x *ServerStatusData) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[2]
+func (x *NetworkQualityReport) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[47]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -175,147 +3814,196 @@ func (x *ServerStatusData) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ServerStatusData.ProtoReflect.Descriptor instead.
-func (*ServerStatusData) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use NetworkQualityReport.ProtoReflect.Descriptor instead.
+func (*NetworkQualityReport) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{47}
 }
 
-func (x *ServerStatusData) GetCpu() float64 {
+func (x *NetworkQualityReport) GetUuid() string {
 	if x != nil {
-		return x.Cpu
+		return x.Uuid
 	}
-	return 0
+	return ""
 }
 
-func (x *ServerStatusData) GetCpuCores() int32 {
+func (x *NetworkQualityReport) GetTrigger() string {
 	if x != nil {
-		return x.CpuCores
+		return x.Trigger
 	}
-	return 0
+	return ""
 }
 
-func (x *ServerStatusData) GetLogicalPro() int32 {
+func (x *NetworkQualityReport) GetCommandId() string {
 	if x != nil {
-		return x.LogicalPro
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *NetworkQualityReport) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
 	}
 	return 0
 }
 
-func (x *ServerStatusData) GetCpuSpeedMhz() float64 {
+func (x *NetworkQualityReport) GetDurationMs() int64 {
 	if x != nil {
-		return x.CpuSpeedMhz
+		return x.DurationMs
 	}
 	return 0
 }
 
-func (x *ServerStatusData) GetMemory() *MemoryInfo {
+func (x *NetworkQualityReport) GetProbes() []*LatencyProbe {
 	if x != nil {
-		return x.Memory
+		return x.Probes
 	}
 	return nil
 }
 
-func (x *ServerStatusData) GetSwap() *SwapInfo {
+func (x *NetworkQualityReport) GetThroughput() *ThroughputProbe {
 	if x != nil {
-		return x.Swap
+		return x.Throughput
 	}
 	return nil
 }
 
-func (x *ServerStatusData) GetDisk() *DiskInfo {
+// LatencyProbe is the latency, jitter and loss of repeated TCP connects to a target
+type LatencyProbe struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        string                 `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`      // host:port
+	Sent          int32                  `protobuf:"varint,2,opt,name=sent,proto3" json:"sent,omitempty"`         // Connects attempted
+	Received      int32                  `protobuf:"varint,3,opt,name=received,proto3" json:"received,omitempty"` // Connects completed
+	LossPercent   float64                `protobuf:"fixed64,4,opt,name=loss_percent,json=lossPercent,proto3" json:"loss_percent,omitempty"`
+	MinMs         float64                `protobuf:"fixed64,5,opt,name=min_ms,json=minMs,proto3" json:"min_ms,omitempty"` // Connect times of the completed connects
+	AvgMs         float64                `protobuf:"fixed64,6,opt,name=avg_ms,json=avgMs,proto3" json:"avg_ms,omitempty"`
+	MaxMs         float64                `protobuf:"fixed64,7,opt,name=max_ms,json=maxMs,proto3" json:"max_ms,omitempty"`
+	JitterMs      float64                `protobuf:"fixed64,8,opt,name=jitter_ms,json=jitterMs,proto3" json:"jitter_ms,omitempty"` // Mean difference between consecutive connect times
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`                         // Last connect error, empty when none failed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LatencyProbe) Reset() {
+	*x = LatencyProbe{}
+	mi := &file_report_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LatencyProbe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LatencyProbe) ProtoMessage() {}
+
+func (x *LatencyProbe) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[48]
 	if x != nil {
-		return x.Disk
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-func (x *ServerStatusData) GetUptime() int32 {
+// Deprecated: Use LatencyProbe.ProtoReflect.Descriptor instead.
+func (*LatencyProbe) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *LatencyProbe) GetTarget() string {
 	if x != nil {
-		return x.Uptime
+		return x.Target
 	}
-	return 0
+	return ""
 }
 
-func (x *ServerStatusData) GetLoads() []float64 {
+func (x *LatencyProbe) GetSent() int32 {
 	if x != nil {
-		return x.Loads
+		return x.Sent
 	}
-	return nil
+	return 0
 }
 
-func (x *ServerStatusData) GetTcpCount() int32 {
+func (x *LatencyProbe) GetReceived() int32 {
 	if x != nil {
-		return x.TcpCount
+		return x.Received
 	}
 	return 0
 }
 
-func (x *ServerStatusData) GetUdpCount() int32 {
+func (x *LatencyProbe) GetLossPercent() float64 {
 	if x != nil {
-		return x.UdpCount
+		return x.LossPercent
 	}
 	return 0
 }
 
-func (x *ServerStatusData) GetNetIo() *NetIOInfo {
+func (x *LatencyProbe) GetMinMs() float64 {
 	if x != nil {
-		return x.NetIo
+		return x.MinMs
 	}
-	return nil
+	return 0
 }
 
-func (x *ServerStatusData) GetNetTraffic() *NetTraffic {
+func (x *LatencyProbe) GetAvgMs() float64 {
 	if x != nil {
-		return x.NetTraffic
+		return x.AvgMs
 	}
-	return nil
+	return 0
 }
 
-func (x *ServerStatusData) GetPublicIp() *PublicIPInfo {
+func (x *LatencyProbe) GetMaxMs() float64 {
 	if x != nil {
-		return x.PublicIp
+		return x.MaxMs
 	}
-	return nil
+	return 0
 }
 
-func (x *ServerStatusData) GetXray() *XrayInfo {
+func (x *LatencyProbe) GetJitterMs() float64 {
 	if x != nil {
-		return x.Xray
+		return x.JitterMs
 	}
-	return nil
+	return 0
 }
 
-func (x *ServerStatusData) GetAppStats() *AppStats {
+func (x *LatencyProbe) GetError() string {
 	if x != nil {
-		return x.AppStats
+		return x.Error
 	}
-	return nil
+	return ""
 }
 
-// MemoryInfo contains memory usage information
-type MemoryInfo struct {
+// ThroughputProbe is the outcome of the download throughput test
+type ThroughputProbe struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Current       int64                  `protobuf:"varint,1,opt,name=current,proto3" json:"current,omitempty"` // Current memory usage
-	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`     // Total memory
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Bytes         int64                  `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"` // Bytes downloaded (capped by network_probe_throughput_mb)
+	DurationMs    int64                  `protobuf:"varint,3,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	BitsPerSecond float64                `protobuf:"fixed64,4,opt,name=bits_per_second,json=bitsPerSecond,proto3" json:"bits_per_second,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"` // Why the download failed or ended early
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MemoryInfo) Reset() {
-	*x = MemoryInfo{}
-	mi := &file_report_proto_msgTypes[3]
+func (x *ThroughputProbe) Reset() {
+	*x = ThroughputProbe{}
+	mi := &file_report_proto_msgTypes[49]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MemoryInfo) String() string {
+func (x *ThroughputProbe) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MemoryInfo) ProtoMessage() {}
+func (*ThroughputProbe) ProtoMessage() {}
 
-func (x *MemoryInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[3]
+func (x *ThroughputProbe) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[49]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -326,49 +4014,75 @@ func (x *MemoryInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MemoryInfo.ProtoReflect.Descriptor instead.
-func (*MemoryInfo) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use ThroughputProbe.ProtoReflect.Descriptor instead.
+func (*ThroughputProbe) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{49}
 }
 
-func (x *MemoryInfo) GetCurrent() int64 {
+func (x *ThroughputProbe) GetUrl() string {
 	if x != nil {
-		return x.Current
+		return x.Url
+	}
+	return ""
+}
+
+func (x *ThroughputProbe) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
 	}
 	return 0
 }
 
-func (x *MemoryInfo) GetTotal() int64 {
+func (x *ThroughputProbe) GetDurationMs() int64 {
 	if x != nil {
-		return x.Total
+		return x.DurationMs
 	}
 	return 0
 }
 
-// SwapInfo contains swap space information
-type SwapInfo struct {
+func (x *ThroughputProbe) GetBitsPerSecond() float64 {
+	if x != nil {
+		return x.BitsPerSecond
+	}
+	return 0
+}
+
+func (x *ThroughputProbe) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// AccessSummary aggregates the connections accepted by Xray during an interval
+type AccessSummary struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Current       int64                  `protobuf:"varint,1,opt,name=current,proto3" json:"current,omitempty"` // Current swap usage
-	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`     // Total swap space
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                      // Agent unique identifier
+	StartedAt     int64                  `protobuf:"varint,2,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`          // Unix time the interval started
+	EndedAt       int64                  `protobuf:"varint,3,opt,name=ended_at,json=endedAt,proto3" json:"ended_at,omitempty"`                // Unix time the interval ended
+	Connections   int64                  `protobuf:"varint,4,opt,name=connections,proto3" json:"connections,omitempty"`                       // Accepted connections of all users
+	Users         []*UserAccess          `protobuf:"bytes,5,rep,name=users,proto3" json:"users,omitempty"`                                    // Most connections first
+	TopDomains    []*DomainAccess        `protobuf:"bytes,6,rep,name=top_domains,json=topDomains,proto3" json:"top_domains,omitempty"`        // Across all users, most connections first
+	SkippedBytes  int64                  `protobuf:"varint,7,opt,name=skipped_bytes,json=skippedBytes,proto3" json:"skipped_bytes,omitempty"` // Log lines not summarized because the interval was too busy
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SwapInfo) Reset() {
-	*x = SwapInfo{}
-	mi := &file_report_proto_msgTypes[4]
+func (x *AccessSummary) Reset() {
+	*x = AccessSummary{}
+	mi := &file_report_proto_msgTypes[50]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SwapInfo) String() string {
+func (x *AccessSummary) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SwapInfo) ProtoMessage() {}
+func (*AccessSummary) ProtoMessage() {}
 
-func (x *SwapInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[4]
+func (x *AccessSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[50]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -379,102 +4093,86 @@ func (x *SwapInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SwapInfo.ProtoReflect.Descriptor instead.
-func (*SwapInfo) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use AccessSummary.ProtoReflect.Descriptor instead.
+func (*AccessSummary) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{50}
 }
 
-func (x *SwapInfo) GetCurrent() int64 {
+func (x *AccessSummary) GetUuid() string {
 	if x != nil {
-		return x.Current
+		return x.Uuid
 	}
-	return 0
+	return ""
 }
 
-func (x *SwapInfo) GetTotal() int64 {
+func (x *AccessSummary) GetStartedAt() int64 {
 	if x != nil {
-		return x.Total
+		return x.StartedAt
 	}
 	return 0
 }
 
-// DiskInfo contains disk usage information
-type DiskInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Current       int64                  `protobuf:"varint,1,opt,name=current,proto3" json:"current,omitempty"` // Current disk usage
-	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`     // Total disk space
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *DiskInfo) Reset() {
-	*x = DiskInfo{}
-	mi := &file_report_proto_msgTypes[5]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *DiskInfo) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *AccessSummary) GetEndedAt() int64 {
+	if x != nil {
+		return x.EndedAt
+	}
+	return 0
 }
 
-func (*DiskInfo) ProtoMessage() {}
-
-func (x *DiskInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[5]
+func (x *AccessSummary) GetConnections() int64 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Connections
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use DiskInfo.ProtoReflect.Descriptor instead.
-func (*DiskInfo) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{5}
+func (x *AccessSummary) GetUsers() []*UserAccess {
+	if x != nil {
+		return x.Users
+	}
+	return nil
 }
 
-func (x *DiskInfo) GetCurrent() int64 {
+func (x *AccessSummary) GetTopDomains() []*DomainAccess {
 	if x != nil {
-		return x.Current
+		return x.TopDomains
 	}
-	return 0
+	return nil
 }
 
-func (x *DiskInfo) GetTotal() int64 {
+func (x *AccessSummary) GetSkippedBytes() int64 {
 	if x != nil {
-		return x.Total
+		return x.SkippedBytes
 	}
 	return 0
 }
 
-// NetIOInfo contains network IO information
-type NetIOInfo struct {
+// UserAccess is the activity of one user during the interval
+type UserAccess struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Up            int64                  `protobuf:"varint,1,opt,name=up,proto3" json:"up,omitempty"`     // Upload traffic
-	Down          int64                  `protobuf:"varint,2,opt,name=down,proto3" json:"down,omitempty"` // Download traffic
+	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Connections   int64                  `protobuf:"varint,2,opt,name=connections,proto3" json:"connections,omitempty"`
+	SourceIps     int32                  `protobuf:"varint,3,opt,name=source_ips,json=sourceIps,proto3" json:"source_ips,omitempty"` // Distinct source IPs
+	TopDomains    []*DomainAccess        `protobuf:"bytes,4,rep,name=top_domains,json=topDomains,proto3" json:"top_domains,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *NetIOInfo) Reset() {
-	*x = NetIOInfo{}
-	mi := &file_report_proto_msgTypes[6]
+func (x *UserAccess) Reset() {
+	*x = UserAccess{}
+	mi := &file_report_proto_msgTypes[51]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NetIOInfo) String() string {
+func (x *UserAccess) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NetIOInfo) ProtoMessage() {}
+func (*UserAccess) ProtoMessage() {}
 
-func (x *NetIOInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[6]
+func (x *UserAccess) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[51]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -485,49 +4183,63 @@ func (x *NetIOInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use NetIOInfo.ProtoReflect.Descriptor instead.
-func (*NetIOInfo) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use UserAccess.ProtoReflect.Descriptor instead.
+func (*UserAccess) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{51}
 }
 
-func (x *NetIOInfo) GetUp() int64 {
+func (x *UserAccess) GetEmail() string {
 	if x != nil {
-		return x.Up
+		return x.Email
+	}
+	return ""
+}
+
+func (x *UserAccess) GetConnections() int64 {
+	if x != nil {
+		return x.Connections
 	}
 	return 0
 }
 
-func (x *NetIOInfo) GetDown() int64 {
+func (x *UserAccess) GetSourceIps() int32 {
 	if x != nil {
-		return x.Down
+		return x.SourceIps
 	}
 	return 0
 }
 
-// NetTraffic contains network traffic information
-type NetTraffic struct {
+func (x *UserAccess) GetTopDomains() []*DomainAccess {
+	if x != nil {
+		return x.TopDomains
+	}
+	return nil
+}
+
+// DomainAccess is the number of connections to a destination domain or IP
+type DomainAccess struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Sent          int64                  `protobuf:"varint,1,opt,name=sent,proto3" json:"sent,omitempty"` // Sent traffic
-	Recv          int64                  `protobuf:"varint,2,opt,name=recv,proto3" json:"recv,omitempty"` // Received traffic
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Connections   int64                  `protobuf:"varint,2,opt,name=connections,proto3" json:"connections,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *NetTraffic) Reset() {
-	*x = NetTraffic{}
-	mi := &file_report_proto_msgTypes[7]
+func (x *DomainAccess) Reset() {
+	*x = DomainAccess{}
+	mi := &file_report_proto_msgTypes[52]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NetTraffic) String() string {
+func (x *DomainAccess) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NetTraffic) ProtoMessage() {}
+func (*DomainAccess) ProtoMessage() {}
 
-func (x *NetTraffic) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[7]
+func (x *DomainAccess) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[52]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -538,50 +4250,59 @@ func (x *NetTraffic) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use NetTraffic.ProtoReflect.Descriptor instead.
-func (*NetTraffic) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use DomainAccess.ProtoReflect.Descriptor instead.
+func (*DomainAccess) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{52}
 }
 
-func (x *NetTraffic) GetSent() int64 {
+func (x *DomainAccess) GetDomain() string {
 	if x != nil {
-		return x.Sent
+		return x.Domain
 	}
-	return 0
+	return ""
 }
 
-func (x *NetTraffic) GetRecv() int64 {
+func (x *DomainAccess) GetConnections() int64 {
 	if x != nil {
-		return x.Recv
+		return x.Connections
 	}
 	return 0
 }
 
-// XrayInfo contains Xray status information
-type XrayInfo struct {
+// CertRenewalReport is the outcome of a certificate renewal through certbot or acme.sh
+type CertRenewalReport struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	State         string                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`                       // Running status ("running" or other)
-	ErrorMsg      string                 `protobuf:"bytes,2,opt,name=error_msg,json=errorMsg,proto3" json:"error_msg,omitempty"` // Error message
-	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`                   // Version
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                            // Agent unique identifier
+	Trigger       string                 `protobuf:"bytes,2,opt,name=trigger,proto3" json:"trigger,omitempty"`                      // "expiry" or "command"
+	CommandId     string                 `protobuf:"bytes,3,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"` // Command that requested the renewal, empty on expiry
+	Client        string                 `protobuf:"bytes,4,opt,name=client,proto3" json:"client,omitempty"`                        // "certbot" or "acme.sh"
+	Domain        string                 `protobuf:"bytes,5,opt,name=domain,proto3" json:"domain,omitempty"`                        // Domain asked for, empty for every due certificate
+	Success       bool                   `protobuf:"varint,6,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`                            // Why the renewal or a reload failed
+	Certificates  []*RenewedCertificate  `protobuf:"bytes,8,rep,name=certificates,proto3" json:"certificates,omitempty"`              // Certificate files that changed
+	Reloaded      []string               `protobuf:"bytes,9,rep,name=reloaded,proto3" json:"reloaded,omitempty"`                      // Services reloaded, "xray" and "hysteria2"
+	StartedAt     int64                  `protobuf:"varint,10,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"` // Unix time the renewal started
+	DurationMs    int64                  `protobuf:"varint,11,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Output        string                 `protobuf:"bytes,12,opt,name=output,proto3" json:"output,omitempty"` // Tail of the client output
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *XrayInfo) Reset() {
-	*x = XrayInfo{}
-	mi := &file_report_proto_msgTypes[8]
+func (x *CertRenewalReport) Reset() {
+	*x = CertRenewalReport{}
+	mi := &file_report_proto_msgTypes[53]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *XrayInfo) String() string {
+func (x *CertRenewalReport) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*XrayInfo) ProtoMessage() {}
+func (*CertRenewalReport) ProtoMessage() {}
 
-func (x *XrayInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[8]
+func (x *CertRenewalReport) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[53]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -592,110 +4313,121 @@ func (x *XrayInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use XrayInfo.ProtoReflect.Descriptor instead.
-func (*XrayInfo) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use CertRenewalReport.ProtoReflect.Descriptor instead.
+func (*CertRenewalReport) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{53}
 }
 
-func (x *XrayInfo) GetState() string {
+func (x *CertRenewalReport) GetUuid() string {
 	if x != nil {
-		return x.State
+		return x.Uuid
 	}
 	return ""
 }
 
-func (x *XrayInfo) GetErrorMsg() string {
+func (x *CertRenewalReport) GetTrigger() string {
 	if x != nil {
-		return x.ErrorMsg
+		return x.Trigger
 	}
 	return ""
 }
 
-func (x *XrayInfo) GetVersion() string {
+func (x *CertRenewalReport) GetCommandId() string {
 	if x != nil {
-		return x.Version
+		return x.CommandId
 	}
 	return ""
 }
 
-// PublicIPInfo contains public IP information
-type PublicIPInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ipv4          string                 `protobuf:"bytes,1,opt,name=ipv4,proto3" json:"ipv4,omitempty"` // IPv4 address
-	Ipv6          string                 `protobuf:"bytes,2,opt,name=ipv6,proto3" json:"ipv6,omitempty"` // IPv6 address
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *CertRenewalReport) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
 }
 
-func (x *PublicIPInfo) Reset() {
-	*x = PublicIPInfo{}
-	mi := &file_report_proto_msgTypes[9]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *CertRenewalReport) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
 }
 
-func (x *PublicIPInfo) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *CertRenewalReport) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
 }
 
-func (*PublicIPInfo) ProtoMessage() {}
+func (x *CertRenewalReport) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
 
-func (x *PublicIPInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[9]
+func (x *CertRenewalReport) GetCertificates() []*RenewedCertificate {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Certificates
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use PublicIPInfo.ProtoReflect.Descriptor instead.
-func (*PublicIPInfo) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{9}
+func (x *CertRenewalReport) GetReloaded() []string {
+	if x != nil {
+		return x.Reloaded
+	}
+	return nil
 }
 
-func (x *PublicIPInfo) GetIpv4() string {
+func (x *CertRenewalReport) GetStartedAt() int64 {
 	if x != nil {
-		return x.Ipv4
+		return x.StartedAt
 	}
-	return ""
+	return 0
 }
 
-func (x *PublicIPInfo) GetIpv6() string {
+func (x *CertRenewalReport) GetDurationMs() int64 {
 	if x != nil {
-		return x.Ipv6
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *CertRenewalReport) GetOutput() string {
+	if x != nil {
+		return x.Output
 	}
 	return ""
 }
 
-// AppStats contains application status information
-type AppStats struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Threads       int32                  `protobuf:"varint,1,opt,name=threads,proto3" json:"threads,omitempty"` // Thread count
-	Memory        int64                  `protobuf:"varint,2,opt,name=memory,proto3" json:"memory,omitempty"`   // Application memory usage
-	Uptime        int32                  `protobuf:"varint,3,opt,name=uptime,proto3" json:"uptime,omitempty"`   // Application uptime
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+// RenewedCertificate is a certificate file replaced by a renewal
+type RenewedCertificate struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Path             string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Origin           string                 `protobuf:"bytes,2,opt,name=origin,proto3" json:"origin,omitempty"`                                                // Referencing config, e.g. "hysteria2" or "inbound:443"
+	PreviousNotAfter int64                  `protobuf:"varint,3,opt,name=previous_not_after,json=previousNotAfter,proto3" json:"previous_not_after,omitempty"` // Unix time, 0 when the file was unreadable before
+	NotAfter         int64                  `protobuf:"varint,4,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"`                           // Unix time
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
-func (x *AppStats) Reset() {
-	*x = AppStats{}
-	mi := &file_report_proto_msgTypes[10]
+func (x *RenewedCertificate) Reset() {
+	*x = RenewedCertificate{}
+	mi := &file_report_proto_msgTypes[54]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AppStats) String() string {
+func (x *RenewedCertificate) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AppStats) ProtoMessage() {}
+func (*RenewedCertificate) ProtoMessage() {}
 
-func (x *AppStats) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[10]
+func (x *RenewedCertificate) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[54]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -706,56 +4438,71 @@ func (x *AppStats) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AppStats.ProtoReflect.Descriptor instead.
-func (*AppStats) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use RenewedCertificate.ProtoReflect.Descriptor instead.
+func (*RenewedCertificate) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{54}
 }
 
-func (x *AppStats) GetThreads() int32 {
+func (x *RenewedCertificate) GetPath() string {
 	if x != nil {
-		return x.Threads
+		return x.Path
 	}
-	return 0
+	return ""
 }
 
-func (x *AppStats) GetMemory() int64 {
+func (x *RenewedCertificate) GetOrigin() string {
 	if x != nil {
-		return x.Memory
+		return x.Origin
+	}
+	return ""
+}
+
+func (x *RenewedCertificate) GetPreviousNotAfter() int64 {
+	if x != nil {
+		return x.PreviousNotAfter
 	}
 	return 0
 }
 
-func (x *AppStats) GetUptime() int32 {
+func (x *RenewedCertificate) GetNotAfter() int64 {
 	if x != nil {
-		return x.Uptime
+		return x.NotAfter
 	}
 	return 0
 }
 
-// SubscriptionReportRequest contains subscription data to be reported
-type SubscriptionReportRequest struct {
+// PanelRestartReport is a restart of the 3x-ui service by the panel watchdog
+type PanelRestartReport struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                   // Agent unique identifier
-	Subscriptions []*SubscriptionData    `protobuf:"bytes,2,rep,name=subscriptions,proto3" json:"subscriptions,omitempty"` // Subscription data list
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                   // Agent unique identifier
+	Service       string                 `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`                             // systemd unit restarted, e.g. "x-ui"
+	Failures      int32                  `protobuf:"varint,3,opt,name=failures,proto3" json:"failures,omitempty"`                          // Consecutive failed status requests that triggered it
+	LastError     string                 `protobuf:"bytes,4,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`        // Error of the last failed status request
+	Attempt       int32                  `protobuf:"varint,5,opt,name=attempt,proto3" json:"attempt,omitempty"`                            // 1 for the first restart since the panel last answered
+	MaxRestarts   int32                  `protobuf:"varint,6,opt,name=max_restarts,json=maxRestarts,proto3" json:"max_restarts,omitempty"` // Restarts allowed until the panel answers again
+	Success       bool                   `protobuf:"varint,7,opt,name=success,proto3" json:"success,omitempty"`                            // systemctl restart succeeded
+	Error         string                 `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`                                 // Why systemctl failed
+	StartedAt     int64                  `protobuf:"varint,9,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`       // Unix time the restart started
+	DurationMs    int64                  `protobuf:"varint,10,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SubscriptionReportRequest) Reset() {
-	*x = SubscriptionReportRequest{}
-	mi := &file_report_proto_msgTypes[11]
+func (x *PanelRestartReport) Reset() {
+	*x = PanelRestartReport{}
+	mi := &file_report_proto_msgTypes[55]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SubscriptionReportRequest) String() string {
+func (x *PanelRestartReport) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SubscriptionReportRequest) ProtoMessage() {}
+func (*PanelRestartReport) ProtoMessage() {}
 
-func (x *SubscriptionReportRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[11]
+func (x *PanelRestartReport) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[55]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -766,119 +4513,107 @@ func (x *SubscriptionReportRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SubscriptionReportRequest.ProtoReflect.Descriptor instead.
-func (*SubscriptionReportRequest) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use PanelRestartReport.ProtoReflect.Descriptor instead.
+func (*PanelRestartReport) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{55}
 }
 
-func (x *SubscriptionReportRequest) GetUuid() string {
+func (x *PanelRestartReport) GetUuid() string {
 	if x != nil {
 		return x.Uuid
 	}
 	return ""
 }
 
-func (x *SubscriptionReportRequest) GetSubscriptions() []*SubscriptionData {
+func (x *PanelRestartReport) GetService() string {
 	if x != nil {
-		return x.Subscriptions
+		return x.Service
 	}
-	return nil
-}
-
-// SubscriptionData contains individual subscription information
-type SubscriptionData struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SubId         string                 `protobuf:"bytes,1,opt,name=sub_id,json=subId,proto3" json:"sub_id,omitempty"`                // Subscription ID
-	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`                             // Client email
-	NodeConfig    string                 `protobuf:"bytes,3,opt,name=node_config,json=nodeConfig,proto3" json:"node_config,omitempty"` // Base64 encoded node configuration
-	Headers       *SubscriptionHeaders   `protobuf:"bytes,4,opt,name=headers,proto3" json:"headers,omitempty"`                         // HTTP response headers
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	return ""
 }
 
-func (x *SubscriptionData) Reset() {
-	*x = SubscriptionData{}
-	mi := &file_report_proto_msgTypes[12]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *PanelRestartReport) GetFailures() int32 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
 }
 
-func (x *SubscriptionData) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *PanelRestartReport) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
 }
 
-func (*SubscriptionData) ProtoMessage() {}
-
-func (x *SubscriptionData) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[12]
+func (x *PanelRestartReport) GetAttempt() int32 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Attempt
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use SubscriptionData.ProtoReflect.Descriptor instead.
-func (*SubscriptionData) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{12}
+func (x *PanelRestartReport) GetMaxRestarts() int32 {
+	if x != nil {
+		return x.MaxRestarts
+	}
+	return 0
 }
 
-func (x *SubscriptionData) GetSubId() string {
+func (x *PanelRestartReport) GetSuccess() bool {
 	if x != nil {
-		return x.SubId
+		return x.Success
 	}
-	return ""
+	return false
 }
 
-func (x *SubscriptionData) GetEmail() string {
+func (x *PanelRestartReport) GetError() string {
 	if x != nil {
-		return x.Email
+		return x.Error
 	}
 	return ""
 }
 
-func (x *SubscriptionData) GetNodeConfig() string {
+func (x *PanelRestartReport) GetStartedAt() int64 {
 	if x != nil {
-		return x.NodeConfig
+		return x.StartedAt
 	}
-	return ""
+	return 0
 }
 
-func (x *SubscriptionData) GetHeaders() *SubscriptionHeaders {
+func (x *PanelRestartReport) GetDurationMs() int64 {
 	if x != nil {
-		return x.Headers
+		return x.DurationMs
 	}
-	return nil
+	return 0
 }
 
-// SubscriptionHeaders contains HTTP response headers from subscription endpoint
-type SubscriptionHeaders struct {
-	state                 protoimpl.MessageState `protogen:"open.v1"`
-	ProfileTitle          string                 `protobuf:"bytes,1,opt,name=profile_title,json=profileTitle,proto3" json:"profile_title,omitempty"`                              // profile-title header (base64 encoded)
-	ProfileUpdateInterval string                 `protobuf:"bytes,2,opt,name=profile_update_interval,json=profileUpdateInterval,proto3" json:"profile_update_interval,omitempty"` // profile-update-interval header
-	SubscriptionUserinfo  string                 `protobuf:"bytes,3,opt,name=subscription_userinfo,json=subscriptionUserinfo,proto3" json:"subscription_userinfo,omitempty"`      // subscription-userinfo header
-	unknownFields         protoimpl.UnknownFields
-	sizeCache             protoimpl.SizeCache
+// StatusBatchRequest carries the status samples of several cycles (report_batch_size)
+type StatusBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                  // Agent unique identifier
+	Samples       []*StatusSample        `protobuf:"bytes,2,rep,name=samples,proto3" json:"samples,omitempty"`                            // Oldest first
+	ErrorCounts   []*ErrorCategoryCount  `protobuf:"bytes,3,rep,name=error_counts,json=errorCounts,proto3" json:"error_counts,omitempty"` // Errors per category since the last acknowledged report
+	Agent         *AgentInfo             `protobuf:"bytes,4,opt,name=agent,proto3" json:"agent,omitempty"`                                // Agent and host metadata, collected at startup
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SubscriptionHeaders) Reset() {
-	*x = SubscriptionHeaders{}
-	mi := &file_report_proto_msgTypes[13]
+func (x *StatusBatchRequest) Reset() {
+	*x = StatusBatchRequest{}
+	mi := &file_report_proto_msgTypes[56]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SubscriptionHeaders) String() string {
+func (x *StatusBatchRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SubscriptionHeaders) ProtoMessage() {}
+func (*StatusBatchRequest) ProtoMessage() {}
 
-func (x *SubscriptionHeaders) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[13]
+func (x *StatusBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[56]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -889,56 +4624,63 @@ func (x *SubscriptionHeaders) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SubscriptionHeaders.ProtoReflect.Descriptor instead.
-func (*SubscriptionHeaders) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use StatusBatchRequest.ProtoReflect.Descriptor instead.
+func (*StatusBatchRequest) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{56}
 }
 
-func (x *SubscriptionHeaders) GetProfileTitle() string {
+func (x *StatusBatchRequest) GetUuid() string {
 	if x != nil {
-		return x.ProfileTitle
+		return x.Uuid
 	}
 	return ""
 }
 
-func (x *SubscriptionHeaders) GetProfileUpdateInterval() string {
+func (x *StatusBatchRequest) GetSamples() []*StatusSample {
 	if x != nil {
-		return x.ProfileUpdateInterval
+		return x.Samples
 	}
-	return ""
+	return nil
 }
 
-func (x *SubscriptionHeaders) GetSubscriptionUserinfo() string {
+func (x *StatusBatchRequest) GetErrorCounts() []*ErrorCategoryCount {
 	if x != nil {
-		return x.SubscriptionUserinfo
+		return x.ErrorCounts
 	}
-	return ""
+	return nil
 }
 
-// OnlineUsersReportRequest contains online users data to be reported
-type OnlineUsersReportRequest struct {
+func (x *StatusBatchRequest) GetAgent() *AgentInfo {
+	if x != nil {
+		return x.Agent
+	}
+	return nil
+}
+
+// StatusSample is the server status collected by one cycle
+type StatusSample struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`                                     // Agent unique identifier
-	OnlineEmails  []string               `protobuf:"bytes,2,rep,name=online_emails,json=onlineEmails,proto3" json:"online_emails,omitempty"` // Online user emails list
+	CollectedAtMs int64                  `protobuf:"varint,1,opt,name=collected_at_ms,json=collectedAtMs,proto3" json:"collected_at_ms,omitempty"` // Unix time in milliseconds
+	Data          *ServerStatusData      `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *OnlineUsersReportRequest) Reset() {
-	*x = OnlineUsersReportRequest{}
-	mi := &file_report_proto_msgTypes[14]
+func (x *StatusSample) Reset() {
+	*x = StatusSample{}
+	mi := &file_report_proto_msgTypes[57]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *OnlineUsersReportRequest) String() string {
+func (x *StatusSample) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*OnlineUsersReportRequest) ProtoMessage() {}
+func (*StatusSample) ProtoMessage() {}
 
-func (x *OnlineUsersReportRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_report_proto_msgTypes[14]
+func (x *StatusSample) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[57]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -949,21 +4691,21 @@ func (x *OnlineUsersReportRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use OnlineUsersReportRequest.ProtoReflect.Descriptor instead.
-func (*OnlineUsersReportRequest) Descriptor() ([]byte, []int) {
-	return file_report_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use StatusSample.ProtoReflect.Descriptor instead.
+func (*StatusSample) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{57}
 }
 
-func (x *OnlineUsersReportRequest) GetUuid() string {
+func (x *StatusSample) GetCollectedAtMs() int64 {
 	if x != nil {
-		return x.Uuid
+		return x.CollectedAtMs
 	}
-	return ""
+	return 0
 }
 
-func (x *OnlineUsersReportRequest) GetOnlineEmails() []string {
+func (x *StatusSample) GetData() *ServerStatusData {
 	if x != nil {
-		return x.OnlineEmails
+		return x.Data
 	}
 	return nil
 }
@@ -972,13 +4714,40 @@ var File_report_proto protoreflect.FileDescriptor
 
 const file_report_proto_rawDesc = "" +
 	"\n" +
-	"\freport.proto\x12\breportpb\"S\n" +
+	"\freport.proto\x12\breportpb\"\xec\x01\n" +
 	"\rReportRequest\x12\x12\n" +
 	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12.\n" +
-	"\x04data\x18\x02 \x01(\v2\x1a.reportpb.ServerStatusDataR\x04data\"D\n" +
+	"\x04data\x18\x02 \x01(\v2\x1a.reportpb.ServerStatusDataR\x04data\x12?\n" +
+	"\ferror_counts\x18\x03 \x03(\v2\x1c.reportpb.ErrorCategoryCountR\verrorCounts\x12)\n" +
+	"\x05agent\x18\x04 \x01(\v2\x13.reportpb.AgentInfoR\x05agent\x12+\n" +
+	"\x05delta\x18\x05 \x01(\v2\x15.reportpb.StatusDeltaR\x05delta\"u\n" +
+	"\vStatusDelta\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x04R\bsequence\x12#\n" +
+	"\rbase_sequence\x18\x02 \x01(\x04R\fbaseSequence\x12%\n" +
+	"\x0echanged_fields\x18\x03 \x03(\rR\rchangedFields\"\xf4\x01\n" +
+	"\tAgentInfo\x12#\n" +
+	"\ragent_version\x18\x01 \x01(\tR\fagentVersion\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\x02 \x01(\tR\tgoVersion\x12\x0e\n" +
+	"\x02os\x18\x03 \x01(\tR\x02os\x12\x12\n" +
+	"\x04arch\x18\x04 \x01(\tR\x04arch\x12\x16\n" +
+	"\x06kernel\x18\x05 \x01(\tR\x06kernel\x12\x1a\n" +
+	"\bhostname\x18\x06 \x01(\tR\bhostname\x12&\n" +
+	"\x0evirtualization\x18\a \x01(\tR\x0evirtualization\x12#\n" +
+	"\x03geo\x18\b \x01(\v2\x11.reportpb.GeoInfoR\x03geo\"\x88\x01\n" +
+	"\aGeoInfo\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x18\n" +
+	"\acountry\x18\x02 \x01(\tR\acountry\x12\x16\n" +
+	"\x06region\x18\x03 \x01(\tR\x06region\x12\x12\n" +
+	"\x04city\x18\x04 \x01(\tR\x04city\x12\x10\n" +
+	"\x03asn\x18\x05 \x01(\rR\x03asn\x12\x15\n" +
+	"\x06as_org\x18\x06 \x01(\tR\x05asOrg\"_\n" +
+	"\x12ErrorCategoryCount\x123\n" +
+	"\bcategory\x18\x01 \x01(\x0e2\x17.reportpb.ErrorCategoryR\bcategory\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x04R\x05count\"D\n" +
 	"\x0eReportResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"\xdd\x04\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xa6\v\n" +
 	"\x10ServerStatusData\x12\x10\n" +
 	"\x03cpu\x18\x01 \x01(\x01R\x03cpu\x12\x1b\n" +
 	"\tcpu_cores\x18\x02 \x01(\x05R\bcpuCores\x12\x1f\n" +
@@ -998,7 +4767,138 @@ const file_report_proto_rawDesc = "" +
 	"netTraffic\x123\n" +
 	"\tpublic_ip\x18\x0e \x01(\v2\x16.reportpb.PublicIPInfoR\bpublicIp\x12&\n" +
 	"\x04xray\x18\x0f \x01(\v2\x12.reportpb.XrayInfoR\x04xray\x12/\n" +
-	"\tapp_stats\x18\x10 \x01(\v2\x12.reportpb.AppStatsR\bappStats\"<\n" +
+	"\tapp_stats\x18\x10 \x01(\v2\x12.reportpb.AppStatsR\bappStats\x12=\n" +
+	"\x0eport_listeners\x18\x11 \x03(\v2\x16.reportpb.PortListenerR\rportListeners\x12+\n" +
+	"\x11inbound_protocols\x18\x12 \x03(\tR\x10inboundProtocols\x12Q\n" +
+	"\rfail2ban_bans\x18\x13 \x03(\v2,.reportpb.ServerStatusData.Fail2banBansEntryR\ffail2banBans\x125\n" +
+	"\vcert_expiry\x18\x14 \x03(\v2\x14.reportpb.CertExpiryR\n" +
+	"certExpiry\x121\n" +
+	"\n" +
+	"dns_checks\x18\x15 \x03(\v2\x12.reportpb.DNSCheckR\tdnsChecks\x125\n" +
+	"\tself_test\x18\x16 \x01(\v2\x18.reportpb.SelfTestStatusR\bselfTest\x12\x19\n" +
+	"\bip_stack\x18\x17 \x01(\tR\aipStack\x12.\n" +
+	"\adisk_io\x18\x18 \x01(\v2\x15.reportpb.DiskIOStatsR\x06diskIo\x122\n" +
+	"\n" +
+	"disk_smart\x18\x19 \x01(\v2\x13.reportpb.DiskSMARTR\tdiskSmart\x122\n" +
+	"\bpressure\x18\x1a \x01(\v2\x16.reportpb.HostPressureR\bpressure\x12:\n" +
+	"\twireguard\x18\x1b \x03(\v2\x1c.reportpb.WireGuardInterfaceR\twireguard\x12#\n" +
+	"\rpanel_version\x18\x1c \x01(\tR\fpanelVersion\x12+\n" +
+	"\x11unreported_fields\x18\x1d \x03(\tR\x10unreportedFields\x128\n" +
+	"\fxray_restart\x18\x1e \x01(\v2\x15.reportpb.XrayRestartR\vxrayRestart\x12+\n" +
+	"\x05clock\x18\x1f \x01(\v2\x15.reportpb.ClockStatusR\x05clock\x1a?\n" +
+	"\x11Fail2banBansEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\xe6\x01\n" +
+	"\vClockStatus\x12\x1b\n" +
+	"\toffset_ms\x18\x01 \x01(\x01R\boffsetMs\x12#\n" +
+	"\roffset_source\x18\x02 \x01(\tR\foffsetSource\x12\"\n" +
+	"\rround_trip_ms\x18\x03 \x01(\x01R\vroundTripMs\x12\x16\n" +
+	"\x06server\x18\x04 \x01(\tR\x06server\x12\"\n" +
+	"\fsynchronized\x18\x05 \x01(\bR\fsynchronized\x12\x1f\n" +
+	"\vntp_service\x18\x06 \x01(\tR\n" +
+	"ntpService\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\"\xa0\x01\n" +
+	"\vXrayRestart\x12\x18\n" +
+	"\atrigger\x18\x01 \x01(\tR\atrigger\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x0e\n" +
+	"\x02at\x18\x03 \x01(\x03R\x02at\x12\x1f\n" +
+	"\vdown_cycles\x18\x04 \x01(\x05R\n" +
+	"downCycles\x12\x18\n" +
+	"\asuccess\x18\x05 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\"\x8f\x02\n" +
+	"\x12WireGuardInterface\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12\x1f\n" +
+	"\vlisten_port\x18\x03 \x01(\x05R\n" +
+	"listenPort\x12\x14\n" +
+	"\x05peers\x18\x04 \x01(\x05R\x05peers\x12!\n" +
+	"\factive_peers\x18\x05 \x01(\x05R\vactivePeers\x12\x19\n" +
+	"\brx_bytes\x18\x06 \x01(\x04R\arxBytes\x12\x19\n" +
+	"\btx_bytes\x18\a \x01(\x04R\atxBytes\x126\n" +
+	"\n" +
+	"peer_stats\x18\b \x03(\v2\x17.reportpb.WireGuardPeerR\tpeerStats\"\x8f\x01\n" +
+	"\rWireGuardPeer\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\tR\tpublicKey\x12)\n" +
+	"\x10latest_handshake\x18\x02 \x01(\x03R\x0flatestHandshake\x12\x19\n" +
+	"\brx_bytes\x18\x03 \x01(\x04R\arxBytes\x12\x19\n" +
+	"\btx_bytes\x18\x04 \x01(\x04R\atxBytes\"\xcf\x01\n" +
+	"\fHostPressure\x12)\n" +
+	"\x03cpu\x18\x01 \x01(\v2\x17.reportpb.PressureStallR\x03cpu\x12/\n" +
+	"\x06memory\x18\x02 \x01(\v2\x17.reportpb.PressureStallR\x06memory\x12'\n" +
+	"\x02io\x18\x03 \x01(\v2\x17.reportpb.PressureStallR\x02io\x12:\n" +
+	"\rthermal_zones\x18\x04 \x03(\v2\x15.reportpb.ThermalZoneR\fthermalZones\"\x95\x02\n" +
+	"\rPressureStall\x12\x1d\n" +
+	"\n" +
+	"some_avg10\x18\x01 \x01(\x01R\tsomeAvg10\x12\x1d\n" +
+	"\n" +
+	"some_avg60\x18\x02 \x01(\x01R\tsomeAvg60\x12\x1f\n" +
+	"\vsome_avg300\x18\x03 \x01(\x01R\n" +
+	"someAvg300\x12\x1d\n" +
+	"\n" +
+	"full_avg10\x18\x04 \x01(\x01R\tfullAvg10\x12\x1d\n" +
+	"\n" +
+	"full_avg60\x18\x05 \x01(\x01R\tfullAvg60\x12\x1f\n" +
+	"\vfull_avg300\x18\x06 \x01(\x01R\n" +
+	"fullAvg300\x12\"\n" +
+	"\rsome_total_us\x18\a \x01(\x04R\vsomeTotalUs\x12\"\n" +
+	"\rfull_total_us\x18\b \x01(\x04R\vfullTotalUs\"f\n" +
+	"\vThermalZone\x12\x12\n" +
+	"\x04zone\x18\x01 \x01(\tR\x04zone\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12/\n" +
+	"\x13temperature_celsius\x18\x03 \x01(\x01R\x12temperatureCelsius\"\x9b\x02\n" +
+	"\vDiskIOStats\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12+\n" +
+	"\x12read_bytes_per_sec\x18\x02 \x01(\x01R\x0freadBytesPerSec\x12-\n" +
+	"\x13write_bytes_per_sec\x18\x03 \x01(\x01R\x10writeBytesPerSec\x12\x1b\n" +
+	"\tread_iops\x18\x04 \x01(\x01R\breadIops\x12\x1d\n" +
+	"\n" +
+	"write_iops\x18\x05 \x01(\x01R\twriteIops\x12 \n" +
+	"\vutilization\x18\x06 \x01(\x01R\vutilization\x12\x19\n" +
+	"\bawait_ms\x18\a \x01(\x01R\aawaitMs\x12\x1f\n" +
+	"\vinterval_ms\x18\b \x01(\x03R\n" +
+	"intervalMs\"\xb6\x02\n" +
+	"\tDiskSMART\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12\x1c\n" +
+	"\tavailable\x18\x02 \x01(\bR\tavailable\x12\x16\n" +
+	"\x06passed\x18\x03 \x01(\bR\x06passed\x12\x14\n" +
+	"\x05model\x18\x04 \x01(\tR\x05model\x12/\n" +
+	"\x13temperature_celsius\x18\x05 \x01(\x05R\x12temperatureCelsius\x12$\n" +
+	"\x0epower_on_hours\x18\x06 \x01(\x03R\fpowerOnHours\x12'\n" +
+	"\x0fpercentage_used\x18\a \x01(\x05R\x0epercentageUsed\x12/\n" +
+	"\x13reallocated_sectors\x18\b \x01(\x03R\x12reallocatedSectors\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\"\x94\x01\n" +
+	"\x0eSelfTestStatus\x12\x19\n" +
+	"\blast_run\x18\x01 \x01(\x03R\alastRun\x12\x16\n" +
+	"\x06passed\x18\x02 \x01(\bR\x06passed\x12\x1a\n" +
+	"\bfailures\x18\x03 \x03(\tR\bfailures\x12\x12\n" +
+	"\x04runs\x18\x04 \x01(\x04R\x04runs\x12\x1f\n" +
+	"\vfailed_runs\x18\x05 \x01(\x04R\n" +
+	"failedRuns\"\xcd\x01\n" +
+	"\bDNSCheck\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12\x1a\n" +
+	"\bresolves\x18\x02 \x01(\bR\bresolves\x12\x1f\n" +
+	"\vpoints_here\x18\x03 \x01(\bR\n" +
+	"pointsHere\x12!\n" +
+	"\fresolved_ips\x18\x04 \x03(\tR\vresolvedIps\x123\n" +
+	"\x15resolver_disagreement\x18\x05 \x01(\bR\x14resolverDisagreement\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\"\x85\x01\n" +
+	"\n" +
+	"CertExpiry\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x16\n" +
+	"\x06origin\x18\x02 \x01(\tR\x06origin\x12\x18\n" +
+	"\asubject\x18\x03 \x01(\tR\asubject\x12\x1b\n" +
+	"\tnot_after\x18\x04 \x01(\x03R\bnotAfter\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"\xc6\x01\n" +
+	"\fPortListener\x12\x12\n" +
+	"\x04port\x18\x01 \x01(\x05R\x04port\x12)\n" +
+	"\x10listener_process\x18\x02 \x01(\tR\x0flistenerProcess\x12\x17\n" +
+	"\ais_xray\x18\x03 \x01(\bR\x06isXray\x12\x1f\n" +
+	"\vis_frontend\x18\x04 \x01(\bR\n" +
+	"isFrontend\x12\x18\n" +
+	"\aunknown\x18\x05 \x01(\bR\aunknown\x12#\n" +
+	"\rlistener_lost\x18\x06 \x01(\bR\flistenerLost\"<\n" +
 	"\n" +
 	"MemoryInfo\x12\x18\n" +
 	"\acurrent\x18\x01 \x01(\x03R\acurrent\x12\x14\n" +
@@ -1026,28 +4926,260 @@ const file_report_proto_rawDesc = "" +
 	"\bAppStats\x12\x18\n" +
 	"\athreads\x18\x01 \x01(\x05R\athreads\x12\x16\n" +
 	"\x06memory\x18\x02 \x01(\x03R\x06memory\x12\x16\n" +
-	"\x06uptime\x18\x03 \x01(\x05R\x06uptime\"q\n" +
+	"\x06uptime\x18\x03 \x01(\x05R\x06uptime\"\xce\x01\n" +
 	"\x19SubscriptionReportRequest\x12\x12\n" +
 	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12@\n" +
-	"\rsubscriptions\x18\x02 \x03(\v2\x1a.reportpb.SubscriptionDataR\rsubscriptions\"\x99\x01\n" +
+	"\rsubscriptions\x18\x02 \x03(\v2\x1a.reportpb.SubscriptionDataR\rsubscriptions\x12\x19\n" +
+	"\bbatch_id\x18\x03 \x01(\tR\abatchId\x12\x1f\n" +
+	"\vchunk_index\x18\x04 \x01(\x05R\n" +
+	"chunkIndex\x12\x1f\n" +
+	"\vchunk_count\x18\x05 \x01(\x05R\n" +
+	"chunkCount\"\xbc\x02\n" +
 	"\x10SubscriptionData\x12\x15\n" +
 	"\x06sub_id\x18\x01 \x01(\tR\x05subId\x12\x14\n" +
 	"\x05email\x18\x02 \x01(\tR\x05email\x12\x1f\n" +
 	"\vnode_config\x18\x03 \x01(\tR\n" +
 	"nodeConfig\x127\n" +
-	"\aheaders\x18\x04 \x01(\v2\x1d.reportpb.SubscriptionHeadersR\aheaders\"\xa7\x01\n" +
+	"\aheaders\x18\x04 \x01(\v2\x1d.reportpb.SubscriptionHeadersR\aheaders\x122\n" +
+	"\x15content_stale_suspect\x18\x05 \x01(\bR\x13contentStaleSuspect\x12!\n" +
+	"\fstale_reason\x18\x06 \x01(\tR\vstaleReason\x12\x1f\n" +
+	"\vjson_config\x18\a \x01(\tR\n" +
+	"jsonConfig\x12)\n" +
+	"\x10wireguard_config\x18\b \x01(\tR\x0fwireguardConfig\"\xa7\x01\n" +
 	"\x13SubscriptionHeaders\x12#\n" +
 	"\rprofile_title\x18\x01 \x01(\tR\fprofileTitle\x126\n" +
 	"\x17profile_update_interval\x18\x02 \x01(\tR\x15profileUpdateInterval\x123\n" +
-	"\x15subscription_userinfo\x18\x03 \x01(\tR\x14subscriptionUserinfo\"S\n" +
+	"\x15subscription_userinfo\x18\x03 \x01(\tR\x14subscriptionUserinfo\"\x7f\n" +
 	"\x18OnlineUsersReportRequest\x12\x12\n" +
 	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12#\n" +
-	"\ronline_emails\x18\x02 \x03(\tR\fonlineEmails2\x80\x02\n" +
+	"\ronline_emails\x18\x02 \x03(\tR\fonlineEmails\x12*\n" +
+	"\x05users\x18\x03 \x03(\v2\x14.reportpb.OnlineUserR\x05users\"H\n" +
+	"\n" +
+	"OnlineUser\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\x12$\n" +
+	"\x03ips\x18\x02 \x03(\v2\x12.reportpb.ClientIPR\x03ips\"Y\n" +
+	"\bClientIP\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x1b\n" +
+	"\tlast_seen\x18\x02 \x01(\x03R\blastSeen\x12 \n" +
+	"\vconnections\x18\x03 \x01(\x05R\vconnections\"b\n" +
+	"\x13StreamReportRequest\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x04R\bsequence\x12/\n" +
+	"\x06report\x18\x02 \x01(\v2\x17.reportpb.ReportRequestR\x06report\"\x89\x01\n" +
+	"\x0fStreamReportAck\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x04R\bsequence\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12&\n" +
+	"\x0fmin_interval_ms\x18\x04 \x01(\x03R\rminIntervalMs\"\xb0\x03\n" +
+	"\x15CombinedReportRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12.\n" +
+	"\x04data\x18\x02 \x01(\v2\x1a.reportpb.ServerStatusDataR\x04data\x12?\n" +
+	"\ferror_counts\x18\x03 \x03(\v2\x1c.reportpb.ErrorCategoryCountR\verrorCounts\x12E\n" +
+	"\fonline_users\x18\x04 \x01(\v2\".reportpb.OnlineUsersReportRequestR\vonlineUsers\x12(\n" +
+	"\x10online_users_age\x18\x05 \x01(\x03R\x0eonlineUsersAge\x12I\n" +
+	"\rsubscriptions\x18\x06 \x01(\v2#.reportpb.SubscriptionReportRequestR\rsubscriptions\x12)\n" +
+	"\x05agent\x18\a \x01(\v2\x13.reportpb.AgentInfoR\x05agent\x12+\n" +
+	"\x05delta\x18\b \x01(\v2\x15.reportpb.StatusDeltaR\x05delta\"\xac\x01\n" +
+	"\x13BackupReportRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12#\n" +
+	"\rinbounds_json\x18\x02 \x01(\fR\finboundsJson\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\tR\x06sha256\x12#\n" +
+	"\rinbound_count\x18\x04 \x01(\x05R\finboundCount\x12\x1f\n" +
+	"\vcaptured_at\x18\x05 \x01(\x03R\n" +
+	"capturedAt\"E\n" +
+	"\x13CommandSubscription\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x1a\n" +
+	"\bcommands\x18\x02 \x03(\tR\bcommands\"\x97\x01\n" +
+	"\aCommand\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12/\n" +
+	"\x04args\x18\x03 \x03(\v2\x1b.reportpb.Command.ArgsEntryR\x04args\x1a7\n" +
+	"\tArgsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\".\n" +
+	"\x18ProvisioningSubscription\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\"\xb3\x01\n" +
+	"\x13ProvisioningRequest\x12'\n" +
+	"\x0fidempotency_key\x18\x01 \x01(\tR\x0eidempotencyKey\x12\x16\n" +
+	"\x06action\x18\x02 \x01(\tR\x06action\x12\x1d\n" +
+	"\n" +
+	"inbound_id\x18\x03 \x01(\x05R\tinboundId\x12\x1b\n" +
+	"\tclient_id\x18\x04 \x01(\tR\bclientId\x12\x1f\n" +
+	"\vclient_json\x18\x05 \x01(\fR\n" +
+	"clientJson\"\xd6\x01\n" +
+	"\x12ProvisioningResult\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12'\n" +
+	"\x0fidempotency_key\x18\x02 \x01(\tR\x0eidempotencyKey\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x12\x18\n" +
+	"\asuccess\x18\x04 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x1a\n" +
+	"\breplayed\x18\x06 \x01(\bR\breplayed\x12\x1f\n" +
+	"\vduration_ms\x18\a \x01(\x03R\n" +
+	"durationMs\"\xb0\x01\n" +
+	"\rCommandResult\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x18\n" +
+	"\asuccess\x18\x04 \x01(\bR\asuccess\x12\x16\n" +
+	"\x06output\x18\x05 \x01(\tR\x06output\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12\x1f\n" +
+	"\vduration_ms\x18\a \x01(\x03R\n" +
+	"durationMs\"\x8a\x01\n" +
+	"\x0eShutdownNotice\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12%\n" +
+	"\x0euptime_seconds\x18\x03 \x01(\x03R\ruptimeSeconds\x12%\n" +
+	"\x0equeued_reports\x18\x04 \x01(\x05R\rqueuedReports\"\xfb\x01\n" +
+	"\vCrashReport\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x1d\n" +
+	"\n" +
+	"crashed_at\x18\x02 \x01(\x03R\tcrashedAt\x12\x1c\n" +
+	"\tcomponent\x18\x03 \x01(\tR\tcomponent\x12\x14\n" +
+	"\x05panic\x18\x04 \x01(\tR\x05panic\x12\x14\n" +
+	"\x05stack\x18\x05 \x01(\tR\x05stack\x12#\n" +
+	"\ragent_version\x18\x06 \x01(\tR\fagentVersion\x12%\n" +
+	"\x0euptime_seconds\x18\a \x01(\x03R\ruptimeSeconds\x12#\n" +
+	"\rrestart_count\x18\b \x01(\x03R\frestartCount\"\x86\x02\n" +
+	"\x10HeartbeatRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12#\n" +
+	"\ragent_version\x18\x02 \x01(\tR\fagentVersion\x12%\n" +
+	"\x0euptime_seconds\x18\x03 \x01(\x03R\ruptimeSeconds\x12'\n" +
+	"\x0fpanel_reachable\x18\x04 \x01(\bR\x0epanelReachable\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x05 \x01(\tR\tlastError\x12\"\n" +
+	"\rlast_error_at\x18\x06 \x01(\x03R\vlastErrorAt\x12&\n" +
+	"\x0flast_success_at\x18\a \x01(\x03R\rlastSuccessAt\"\x8e\x02\n" +
+	"\x14NetworkQualityReport\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x18\n" +
+	"\atrigger\x18\x02 \x01(\tR\atrigger\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x03 \x01(\tR\tcommandId\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x04 \x01(\x03R\tstartedAt\x12\x1f\n" +
+	"\vduration_ms\x18\x05 \x01(\x03R\n" +
+	"durationMs\x12.\n" +
+	"\x06probes\x18\x06 \x03(\v2\x16.reportpb.LatencyProbeR\x06probes\x129\n" +
+	"\n" +
+	"throughput\x18\a \x01(\v2\x19.reportpb.ThroughputProbeR\n" +
+	"throughput\"\xf1\x01\n" +
+	"\fLatencyProbe\x12\x16\n" +
+	"\x06target\x18\x01 \x01(\tR\x06target\x12\x12\n" +
+	"\x04sent\x18\x02 \x01(\x05R\x04sent\x12\x1a\n" +
+	"\breceived\x18\x03 \x01(\x05R\breceived\x12!\n" +
+	"\floss_percent\x18\x04 \x01(\x01R\vlossPercent\x12\x15\n" +
+	"\x06min_ms\x18\x05 \x01(\x01R\x05minMs\x12\x15\n" +
+	"\x06avg_ms\x18\x06 \x01(\x01R\x05avgMs\x12\x15\n" +
+	"\x06max_ms\x18\a \x01(\x01R\x05maxMs\x12\x1b\n" +
+	"\tjitter_ms\x18\b \x01(\x01R\bjitterMs\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\"\x98\x01\n" +
+	"\x0fThroughputProbe\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
+	"\x05bytes\x18\x02 \x01(\x03R\x05bytes\x12\x1f\n" +
+	"\vduration_ms\x18\x03 \x01(\x03R\n" +
+	"durationMs\x12&\n" +
+	"\x0fbits_per_second\x18\x04 \x01(\x01R\rbitsPerSecond\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"\x89\x02\n" +
+	"\rAccessSummary\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x02 \x01(\x03R\tstartedAt\x12\x19\n" +
+	"\bended_at\x18\x03 \x01(\x03R\aendedAt\x12 \n" +
+	"\vconnections\x18\x04 \x01(\x03R\vconnections\x12*\n" +
+	"\x05users\x18\x05 \x03(\v2\x14.reportpb.UserAccessR\x05users\x127\n" +
+	"\vtop_domains\x18\x06 \x03(\v2\x16.reportpb.DomainAccessR\n" +
+	"topDomains\x12#\n" +
+	"\rskipped_bytes\x18\a \x01(\x03R\fskippedBytes\"\x9c\x01\n" +
+	"\n" +
+	"UserAccess\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\x12 \n" +
+	"\vconnections\x18\x02 \x01(\x03R\vconnections\x12\x1d\n" +
+	"\n" +
+	"source_ips\x18\x03 \x01(\x05R\tsourceIps\x127\n" +
+	"\vtop_domains\x18\x04 \x03(\v2\x16.reportpb.DomainAccessR\n" +
+	"topDomains\"H\n" +
+	"\fDomainAccess\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12 \n" +
+	"\vconnections\x18\x02 \x01(\x03R\vconnections\"\xf6\x02\n" +
+	"\x11CertRenewalReport\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x18\n" +
+	"\atrigger\x18\x02 \x01(\tR\atrigger\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x03 \x01(\tR\tcommandId\x12\x16\n" +
+	"\x06client\x18\x04 \x01(\tR\x06client\x12\x16\n" +
+	"\x06domain\x18\x05 \x01(\tR\x06domain\x12\x18\n" +
+	"\asuccess\x18\x06 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12@\n" +
+	"\fcertificates\x18\b \x03(\v2\x1c.reportpb.RenewedCertificateR\fcertificates\x12\x1a\n" +
+	"\breloaded\x18\t \x03(\tR\breloaded\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\n" +
+	" \x01(\x03R\tstartedAt\x12\x1f\n" +
+	"\vduration_ms\x18\v \x01(\x03R\n" +
+	"durationMs\x12\x16\n" +
+	"\x06output\x18\f \x01(\tR\x06output\"\x8b\x01\n" +
+	"\x12RenewedCertificate\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x16\n" +
+	"\x06origin\x18\x02 \x01(\tR\x06origin\x12,\n" +
+	"\x12previous_not_after\x18\x03 \x01(\x03R\x10previousNotAfter\x12\x1b\n" +
+	"\tnot_after\x18\x04 \x01(\x03R\bnotAfter\"\xaa\x02\n" +
+	"\x12PanelRestartReport\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x18\n" +
+	"\aservice\x18\x02 \x01(\tR\aservice\x12\x1a\n" +
+	"\bfailures\x18\x03 \x01(\x05R\bfailures\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x04 \x01(\tR\tlastError\x12\x18\n" +
+	"\aattempt\x18\x05 \x01(\x05R\aattempt\x12!\n" +
+	"\fmax_restarts\x18\x06 \x01(\x05R\vmaxRestarts\x12\x18\n" +
+	"\asuccess\x18\a \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\t \x01(\x03R\tstartedAt\x12\x1f\n" +
+	"\vduration_ms\x18\n" +
+	" \x01(\x03R\n" +
+	"durationMs\"\xc6\x01\n" +
+	"\x12StatusBatchRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x120\n" +
+	"\asamples\x18\x02 \x03(\v2\x16.reportpb.StatusSampleR\asamples\x12?\n" +
+	"\ferror_counts\x18\x03 \x03(\v2\x1c.reportpb.ErrorCategoryCountR\verrorCounts\x12)\n" +
+	"\x05agent\x18\x04 \x01(\v2\x13.reportpb.AgentInfoR\x05agent\"f\n" +
+	"\fStatusSample\x12&\n" +
+	"\x0fcollected_at_ms\x18\x01 \x01(\x03R\rcollectedAtMs\x12.\n" +
+	"\x04data\x18\x02 \x01(\v2\x1a.reportpb.ServerStatusDataR\x04data*\xe1\x03\n" +
+	"\rErrorCategory\x12\x1a\n" +
+	"\x16ERROR_CATEGORY_UNKNOWN\x10\x00\x12 \n" +
+	"\x1cERROR_CATEGORY_PANEL_TIMEOUT\x10\x01\x12\x1d\n" +
+	"\x19ERROR_CATEGORY_PANEL_AUTH\x10\x02\x12\x1f\n" +
+	"\x1bERROR_CATEGORY_PANEL_DECODE\x10\x03\x12\x1d\n" +
+	"\x19ERROR_CATEGORY_PANEL_HTTP\x10\x04\x12#\n" +
+	"\x1fERROR_CATEGORY_GRPC_UNAVAILABLE\x10\x05\x12\x1c\n" +
+	"\x18ERROR_CATEGORY_GRPC_AUTH\x10\x06\x12 \n" +
+	"\x1cERROR_CATEGORY_GRPC_DEADLINE\x10\a\x12\x1d\n" +
+	"\x19ERROR_CATEGORY_GRPC_OTHER\x10\b\x12%\n" +
+	"!ERROR_CATEGORY_SUBSCRIPTION_FETCH\x10\t\x12!\n" +
+	"\x1dERROR_CATEGORY_INTERNAL_PANIC\x10\n" +
+	"\x12\x1b\n" +
+	"\x17ERROR_CATEGORY_SELFTEST\x10\v\x12$\n" +
+	" ERROR_CATEGORY_PANEL_UNREACHABLE\x10\f\x12\"\n" +
+	"\x1eERROR_CATEGORY_REPORT_REJECTED\x10\r2\x8a\v\n" +
 	"\rReportService\x12?\n" +
 	"\n" +
 	"SendReport\x12\x17.reportpb.ReportRequest\x1a\x18.reportpb.ReportResponse\x12W\n" +
 	"\x16SendSubscriptionReport\x12#.reportpb.SubscriptionReportRequest\x1a\x18.reportpb.ReportResponse\x12U\n" +
-	"\x15SendOnlineUsersReport\x12\".reportpb.OnlineUsersReportRequest\x1a\x18.reportpb.ReportResponseB\x1bZ\x19xhub-agent/proto/reportpbb\x06proto3"
+	"\x15SendOnlineUsersReport\x12\".reportpb.OnlineUsersReportRequest\x1a\x18.reportpb.ReportResponse\x12O\n" +
+	"\x12SendCombinedReport\x12\x1f.reportpb.CombinedReportRequest\x1a\x18.reportpb.ReportResponse\x12M\n" +
+	"\rStreamReports\x12\x1d.reportpb.StreamReportRequest\x1a\x19.reportpb.StreamReportAck(\x010\x01\x12K\n" +
+	"\x10SendBackupReport\x12\x1d.reportpb.BackupReportRequest\x1a\x18.reportpb.ReportResponse\x12G\n" +
+	"\x11SubscribeCommands\x12\x1d.reportpb.CommandSubscription\x1a\x11.reportpb.Command0\x01\x12F\n" +
+	"\x11SendCommandResult\x12\x17.reportpb.CommandResult\x1a\x18.reportpb.ReportResponse\x12\\\n" +
+	"\x15SubscribeProvisioning\x12\".reportpb.ProvisioningSubscription\x1a\x1d.reportpb.ProvisioningRequest0\x01\x12P\n" +
+	"\x16SendProvisioningResult\x12\x1c.reportpb.ProvisioningResult\x1a\x18.reportpb.ReportResponse\x12H\n" +
+	"\x12SendShutdownNotice\x12\x18.reportpb.ShutdownNotice\x1a\x18.reportpb.ReportResponse\x12B\n" +
+	"\x0fSendCrashReport\x12\x15.reportpb.CrashReport\x1a\x18.reportpb.ReportResponse\x12A\n" +
+	"\tHeartbeat\x12\x1a.reportpb.HeartbeatRequest\x1a\x18.reportpb.ReportResponse\x12T\n" +
+	"\x18SendNetworkQualityReport\x12\x1e.reportpb.NetworkQualityReport\x1a\x18.reportpb.ReportResponse\x12F\n" +
+	"\x11SendAccessSummary\x12\x17.reportpb.AccessSummary\x1a\x18.reportpb.ReportResponse\x12N\n" +
+	"\x15SendCertRenewalReport\x12\x1b.reportpb.CertRenewalReport\x1a\x18.reportpb.ReportResponse\x12P\n" +
+	"\x16SendPanelRestartReport\x12\x1c.reportpb.PanelRestartReport\x1a\x18.reportpb.ReportResponse\x12I\n" +
+	"\x0fSendStatusBatch\x12\x1c.reportpb.StatusBatchRequest\x1a\x18.reportpb.ReportResponseB\x1bZ\x19xhub-agent/proto/reportpbb\x06proto3"
 
 var (
 	file_report_proto_rawDescOnce sync.Once
@@ -1061,47 +5193,165 @@ func file_report_proto_rawDescGZIP() []byte {
 	return file_report_proto_rawDescData
 }
 
-var file_report_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_report_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_report_proto_msgTypes = make([]protoimpl.MessageInfo, 60)
 var file_report_proto_goTypes = []any{
-	(*ReportRequest)(nil),             // 0: reportpb.ReportRequest
-	(*ReportResponse)(nil),            // 1: reportpb.ReportResponse
-	(*ServerStatusData)(nil),          // 2: reportpb.ServerStatusData
-	(*MemoryInfo)(nil),                // 3: reportpb.MemoryInfo
-	(*SwapInfo)(nil),                  // 4: reportpb.SwapInfo
-	(*DiskInfo)(nil),                  // 5: reportpb.DiskInfo
-	(*NetIOInfo)(nil),                 // 6: reportpb.NetIOInfo
-	(*NetTraffic)(nil),                // 7: reportpb.NetTraffic
-	(*XrayInfo)(nil),                  // 8: reportpb.XrayInfo
-	(*PublicIPInfo)(nil),              // 9: reportpb.PublicIPInfo
-	(*AppStats)(nil),                  // 10: reportpb.AppStats
-	(*SubscriptionReportRequest)(nil), // 11: reportpb.SubscriptionReportRequest
-	(*SubscriptionData)(nil),          // 12: reportpb.SubscriptionData
-	(*SubscriptionHeaders)(nil),       // 13: reportpb.SubscriptionHeaders
-	(*OnlineUsersReportRequest)(nil),  // 14: reportpb.OnlineUsersReportRequest
+	(ErrorCategory)(0),                // 0: reportpb.ErrorCategory
+	(*ReportRequest)(nil),             // 1: reportpb.ReportRequest
+	(*StatusDelta)(nil),               // 2: reportpb.StatusDelta
+	(*AgentInfo)(nil),                 // 3: reportpb.AgentInfo
+	(*GeoInfo)(nil),                   // 4: reportpb.GeoInfo
+	(*ErrorCategoryCount)(nil),        // 5: reportpb.ErrorCategoryCount
+	(*ReportResponse)(nil),            // 6: reportpb.ReportResponse
+	(*ServerStatusData)(nil),          // 7: reportpb.ServerStatusData
+	(*ClockStatus)(nil),               // 8: reportpb.ClockStatus
+	(*XrayRestart)(nil),               // 9: reportpb.XrayRestart
+	(*WireGuardInterface)(nil),        // 10: reportpb.WireGuardInterface
+	(*WireGuardPeer)(nil),             // 11: reportpb.WireGuardPeer
+	(*HostPressure)(nil),              // 12: reportpb.HostPressure
+	(*PressureStall)(nil),             // 13: reportpb.PressureStall
+	(*ThermalZone)(nil),               // 14: reportpb.ThermalZone
+	(*DiskIOStats)(nil),               // 15: reportpb.DiskIOStats
+	(*DiskSMART)(nil),                 // 16: reportpb.DiskSMART
+	(*SelfTestStatus)(nil),            // 17: reportpb.SelfTestStatus
+	(*DNSCheck)(nil),                  // 18: reportpb.DNSCheck
+	(*CertExpiry)(nil),                // 19: reportpb.CertExpiry
+	(*PortListener)(nil),              // 20: reportpb.PortListener
+	(*MemoryInfo)(nil),                // 21: reportpb.MemoryInfo
+	(*SwapInfo)(nil),                  // 22: reportpb.SwapInfo
+	(*DiskInfo)(nil),                  // 23: reportpb.DiskInfo
+	(*NetIOInfo)(nil),                 // 24: reportpb.NetIOInfo
+	(*NetTraffic)(nil),                // 25: reportpb.NetTraffic
+	(*XrayInfo)(nil),                  // 26: reportpb.XrayInfo
+	(*PublicIPInfo)(nil),              // 27: reportpb.PublicIPInfo
+	(*AppStats)(nil),                  // 28: reportpb.AppStats
+	(*SubscriptionReportRequest)(nil), // 29: reportpb.SubscriptionReportRequest
+	(*SubscriptionData)(nil),          // 30: reportpb.SubscriptionData
+	(*SubscriptionHeaders)(nil),       // 31: reportpb.SubscriptionHeaders
+	(*OnlineUsersReportRequest)(nil),  // 32: reportpb.OnlineUsersReportRequest
+	(*OnlineUser)(nil),                // 33: reportpb.OnlineUser
+	(*ClientIP)(nil),                  // 34: reportpb.ClientIP
+	(*StreamReportRequest)(nil),       // 35: reportpb.StreamReportRequest
+	(*StreamReportAck)(nil),           // 36: reportpb.StreamReportAck
+	(*CombinedReportRequest)(nil),     // 37: reportpb.CombinedReportRequest
+	(*BackupReportRequest)(nil),       // 38: reportpb.BackupReportRequest
+	(*CommandSubscription)(nil),       // 39: reportpb.CommandSubscription
+	(*Command)(nil),                   // 40: reportpb.Command
+	(*ProvisioningSubscription)(nil),  // 41: reportpb.ProvisioningSubscription
+	(*ProvisioningRequest)(nil),       // 42: reportpb.ProvisioningRequest
+	(*ProvisioningResult)(nil),        // 43: reportpb.ProvisioningResult
+	(*CommandResult)(nil),             // 44: reportpb.CommandResult
+	(*ShutdownNotice)(nil),            // 45: reportpb.ShutdownNotice
+	(*CrashReport)(nil),               // 46: reportpb.CrashReport
+	(*HeartbeatRequest)(nil),          // 47: reportpb.HeartbeatRequest
+	(*NetworkQualityReport)(nil),      // 48: reportpb.NetworkQualityReport
+	(*LatencyProbe)(nil),              // 49: reportpb.LatencyProbe
+	(*ThroughputProbe)(nil),           // 50: reportpb.ThroughputProbe
+	(*AccessSummary)(nil),             // 51: reportpb.AccessSummary
+	(*UserAccess)(nil),                // 52: reportpb.UserAccess
+	(*DomainAccess)(nil),              // 53: reportpb.DomainAccess
+	(*CertRenewalReport)(nil),         // 54: reportpb.CertRenewalReport
+	(*RenewedCertificate)(nil),        // 55: reportpb.RenewedCertificate
+	(*PanelRestartReport)(nil),        // 56: reportpb.PanelRestartReport
+	(*StatusBatchRequest)(nil),        // 57: reportpb.StatusBatchRequest
+	(*StatusSample)(nil),              // 58: reportpb.StatusSample
+	nil,                               // 59: reportpb.ServerStatusData.Fail2banBansEntry
+	nil,                               // 60: reportpb.Command.ArgsEntry
 }
 var file_report_proto_depIdxs = []int32{
-	2,  // 0: reportpb.ReportRequest.data:type_name -> reportpb.ServerStatusData
-	3,  // 1: reportpb.ServerStatusData.memory:type_name -> reportpb.MemoryInfo
-	4,  // 2: reportpb.ServerStatusData.swap:type_name -> reportpb.SwapInfo
-	5,  // 3: reportpb.ServerStatusData.disk:type_name -> reportpb.DiskInfo
-	6,  // 4: reportpb.ServerStatusData.net_io:type_name -> reportpb.NetIOInfo
-	7,  // 5: reportpb.ServerStatusData.net_traffic:type_name -> reportpb.NetTraffic
-	9,  // 6: reportpb.ServerStatusData.public_ip:type_name -> reportpb.PublicIPInfo
-	8,  // 7: reportpb.ServerStatusData.xray:type_name -> reportpb.XrayInfo
-	10, // 8: reportpb.ServerStatusData.app_stats:type_name -> reportpb.AppStats
-	12, // 9: reportpb.SubscriptionReportRequest.subscriptions:type_name -> reportpb.SubscriptionData
-	13, // 10: reportpb.SubscriptionData.headers:type_name -> reportpb.SubscriptionHeaders
-	0,  // 11: reportpb.ReportService.SendReport:input_type -> reportpb.ReportRequest
-	11, // 12: reportpb.ReportService.SendSubscriptionReport:input_type -> reportpb.SubscriptionReportRequest
-	14, // 13: reportpb.ReportService.SendOnlineUsersReport:input_type -> reportpb.OnlineUsersReportRequest
-	1,  // 14: reportpb.ReportService.SendReport:output_type -> reportpb.ReportResponse
-	1,  // 15: reportpb.ReportService.SendSubscriptionReport:output_type -> reportpb.ReportResponse
-	1,  // 16: reportpb.ReportService.SendOnlineUsersReport:output_type -> reportpb.ReportResponse
-	14, // [14:17] is the sub-list for method output_type
-	11, // [11:14] is the sub-list for method input_type
-	11, // [11:11] is the sub-list for extension type_name
-	11, // [11:11] is the sub-list for extension extendee
-	0,  // [0:11] is the sub-list for field type_name
+	7,  // 0: reportpb.ReportRequest.data:type_name -> reportpb.ServerStatusData
+	5,  // 1: reportpb.ReportRequest.error_counts:type_name -> reportpb.ErrorCategoryCount
+	3,  // 2: reportpb.ReportRequest.agent:type_name -> reportpb.AgentInfo
+	2,  // 3: reportpb.ReportRequest.delta:type_name -> reportpb.StatusDelta
+	4,  // 4: reportpb.AgentInfo.geo:type_name -> reportpb.GeoInfo
+	0,  // 5: reportpb.ErrorCategoryCount.category:type_name -> reportpb.ErrorCategory
+	21, // 6: reportpb.ServerStatusData.memory:type_name -> reportpb.MemoryInfo
+	22, // 7: reportpb.ServerStatusData.swap:type_name -> reportpb.SwapInfo
+	23, // 8: reportpb.ServerStatusData.disk:type_name -> reportpb.DiskInfo
+	24, // 9: reportpb.ServerStatusData.net_io:type_name -> reportpb.NetIOInfo
+	25, // 10: reportpb.ServerStatusData.net_traffic:type_name -> reportpb.NetTraffic
+	27, // 11: reportpb.ServerStatusData.public_ip:type_name -> reportpb.PublicIPInfo
+	26, // 12: reportpb.ServerStatusData.xray:type_name -> reportpb.XrayInfo
+	28, // 13: reportpb.ServerStatusData.app_stats:type_name -> reportpb.AppStats
+	20, // 14: reportpb.ServerStatusData.port_listeners:type_name -> reportpb.PortListener
+	59, // 15: reportpb.ServerStatusData.fail2ban_bans:type_name -> reportpb.ServerStatusData.Fail2banBansEntry
+	19, // 16: reportpb.ServerStatusData.cert_expiry:type_name -> reportpb.CertExpiry
+	18, // 17: reportpb.ServerStatusData.dns_checks:type_name -> reportpb.DNSCheck
+	17, // 18: reportpb.ServerStatusData.self_test:type_name -> reportpb.SelfTestStatus
+	15, // 19: reportpb.ServerStatusData.disk_io:type_name -> reportpb.DiskIOStats
+	16, // 20: reportpb.ServerStatusData.disk_smart:type_name -> reportpb.DiskSMART
+	12, // 21: reportpb.ServerStatusData.pressure:type_name -> reportpb.HostPressure
+	10, // 22: reportpb.ServerStatusData.wireguard:type_name -> reportpb.WireGuardInterface
+	9,  // 23: reportpb.ServerStatusData.xray_restart:type_name -> reportpb.XrayRestart
+	8,  // 24: reportpb.ServerStatusData.clock:type_name -> reportpb.ClockStatus
+	11, // 25: reportpb.WireGuardInterface.peer_stats:type_name -> reportpb.WireGuardPeer
+	13, // 26: reportpb.HostPressure.cpu:type_name -> reportpb.PressureStall
+	13, // 27: reportpb.HostPressure.memory:type_name -> reportpb.PressureStall
+	13, // 28: reportpb.HostPressure.io:type_name -> reportpb.PressureStall
+	14, // 29: reportpb.HostPressure.thermal_zones:type_name -> reportpb.ThermalZone
+	30, // 30: reportpb.SubscriptionReportRequest.subscriptions:type_name -> reportpb.SubscriptionData
+	31, // 31: reportpb.SubscriptionData.headers:type_name -> reportpb.SubscriptionHeaders
+	33, // 32: reportpb.OnlineUsersReportRequest.users:type_name -> reportpb.OnlineUser
+	34, // 33: reportpb.OnlineUser.ips:type_name -> reportpb.ClientIP
+	1,  // 34: reportpb.StreamReportRequest.report:type_name -> reportpb.ReportRequest
+	7,  // 35: reportpb.CombinedReportRequest.data:type_name -> reportpb.ServerStatusData
+	5,  // 36: reportpb.CombinedReportRequest.error_counts:type_name -> reportpb.ErrorCategoryCount
+	32, // 37: reportpb.CombinedReportRequest.online_users:type_name -> reportpb.OnlineUsersReportRequest
+	29, // 38: reportpb.CombinedReportRequest.subscriptions:type_name -> reportpb.SubscriptionReportRequest
+	3,  // 39: reportpb.CombinedReportRequest.agent:type_name -> reportpb.AgentInfo
+	2,  // 40: reportpb.CombinedReportRequest.delta:type_name -> reportpb.StatusDelta
+	60, // 41: reportpb.Command.args:type_name -> reportpb.Command.ArgsEntry
+	49, // 42: reportpb.NetworkQualityReport.probes:type_name -> reportpb.LatencyProbe
+	50, // 43: reportpb.NetworkQualityReport.throughput:type_name -> reportpb.ThroughputProbe
+	52, // 44: reportpb.AccessSummary.users:type_name -> reportpb.UserAccess
+	53, // 45: reportpb.AccessSummary.top_domains:type_name -> reportpb.DomainAccess
+	53, // 46: reportpb.UserAccess.top_domains:type_name -> reportpb.DomainAccess
+	55, // 47: reportpb.CertRenewalReport.certificates:type_name -> reportpb.RenewedCertificate
+	58, // 48: reportpb.StatusBatchRequest.samples:type_name -> reportpb.StatusSample
+	5,  // 49: reportpb.StatusBatchRequest.error_counts:type_name -> reportpb.ErrorCategoryCount
+	3,  // 50: reportpb.StatusBatchRequest.agent:type_name -> reportpb.AgentInfo
+	7,  // 51: reportpb.StatusSample.data:type_name -> reportpb.ServerStatusData
+	1,  // 52: reportpb.ReportService.SendReport:input_type -> reportpb.ReportRequest
+	29, // 53: reportpb.ReportService.SendSubscriptionReport:input_type -> reportpb.SubscriptionReportRequest
+	32, // 54: reportpb.ReportService.SendOnlineUsersReport:input_type -> reportpb.OnlineUsersReportRequest
+	37, // 55: reportpb.ReportService.SendCombinedReport:input_type -> reportpb.CombinedReportRequest
+	35, // 56: reportpb.ReportService.StreamReports:input_type -> reportpb.StreamReportRequest
+	38, // 57: reportpb.ReportService.SendBackupReport:input_type -> reportpb.BackupReportRequest
+	39, // 58: reportpb.ReportService.SubscribeCommands:input_type -> reportpb.CommandSubscription
+	44, // 59: reportpb.ReportService.SendCommandResult:input_type -> reportpb.CommandResult
+	41, // 60: reportpb.ReportService.SubscribeProvisioning:input_type -> reportpb.ProvisioningSubscription
+	43, // 61: reportpb.ReportService.SendProvisioningResult:input_type -> reportpb.ProvisioningResult
+	45, // 62: reportpb.ReportService.SendShutdownNotice:input_type -> reportpb.ShutdownNotice
+	46, // 63: reportpb.ReportService.SendCrashReport:input_type -> reportpb.CrashReport
+	47, // 64: reportpb.ReportService.Heartbeat:input_type -> reportpb.HeartbeatRequest
+	48, // 65: reportpb.ReportService.SendNetworkQualityReport:input_type -> reportpb.NetworkQualityReport
+	51, // 66: reportpb.ReportService.SendAccessSummary:input_type -> reportpb.AccessSummary
+	54, // 67: reportpb.ReportService.SendCertRenewalReport:input_type -> reportpb.CertRenewalReport
+	56, // 68: reportpb.ReportService.SendPanelRestartReport:input_type -> reportpb.PanelRestartReport
+	57, // 69: reportpb.ReportService.SendStatusBatch:input_type -> reportpb.StatusBatchRequest
+	6,  // 70: reportpb.ReportService.SendReport:output_type -> reportpb.ReportResponse
+	6,  // 71: reportpb.ReportService.SendSubscriptionReport:output_type -> reportpb.ReportResponse
+	6,  // 72: reportpb.ReportService.SendOnlineUsersReport:output_type -> reportpb.ReportResponse
+	6,  // 73: reportpb.ReportService.SendCombinedReport:output_type -> reportpb.ReportResponse
+	36, // 74: reportpb.ReportService.StreamReports:output_type -> reportpb.StreamReportAck
+	6,  // 75: reportpb.ReportService.SendBackupReport:output_type -> reportpb.ReportResponse
+	40, // 76: reportpb.ReportService.SubscribeCommands:output_type -> reportpb.Command
+	6,  // 77: reportpb.ReportService.SendCommandResult:output_type -> reportpb.ReportResponse
+	42, // 78: reportpb.ReportService.SubscribeProvisioning:output_type -> reportpb.ProvisioningRequest
+	6,  // 79: reportpb.ReportService.SendProvisioningResult:output_type -> reportpb.ReportResponse
+	6,  // 80: reportpb.ReportService.SendShutdownNotice:output_type -> reportpb.ReportResponse
+	6,  // 81: reportpb.ReportService.SendCrashReport:output_type -> reportpb.ReportResponse
+	6,  // 82: reportpb.ReportService.Heartbeat:output_type -> reportpb.ReportResponse
+	6,  // 83: reportpb.ReportService.SendNetworkQualityReport:output_type -> reportpb.ReportResponse
+	6,  // 84: reportpb.ReportService.SendAccessSummary:output_type -> reportpb.ReportResponse
+	6,  // 85: reportpb.ReportService.SendCertRenewalReport:output_type -> reportpb.ReportResponse
+	6,  // 86: reportpb.ReportService.SendPanelRestartReport:output_type -> reportpb.ReportResponse
+	6,  // 87: reportpb.ReportService.SendStatusBatch:output_type -> reportpb.ReportResponse
+	70, // [70:88] is the sub-list for method output_type
+	52, // [52:70] is the sub-list for method input_type
+	52, // [52:52] is the sub-list for extension type_name
+	52, // [52:52] is the sub-list for extension extendee
+	0,  // [0:52] is the sub-list for field type_name
 }
 
 func init() { file_report_proto_init() }
@@ -1114,13 +5364,14 @@ func file_report_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_report_proto_rawDesc), len(file_report_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   15,
+			NumEnums:      1,
+			NumMessages:   60,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_report_proto_goTypes,
 		DependencyIndexes: file_report_proto_depIdxs,
+		EnumInfos:         file_report_proto_enumTypes,
 		MessageInfos:      file_report_proto_msgTypes,
 	}.Build()
 	File_report_proto = out.File
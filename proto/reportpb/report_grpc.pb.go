@@ -19,9 +19,24 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ReportService_SendReport_FullMethodName             = "/reportpb.ReportService/SendReport"
-	ReportService_SendSubscriptionReport_FullMethodName = "/reportpb.ReportService/SendSubscriptionReport"
-	ReportService_SendOnlineUsersReport_FullMethodName  = "/reportpb.ReportService/SendOnlineUsersReport"
+	ReportService_SendReport_FullMethodName               = "/reportpb.ReportService/SendReport"
+	ReportService_SendSubscriptionReport_FullMethodName   = "/reportpb.ReportService/SendSubscriptionReport"
+	ReportService_SendOnlineUsersReport_FullMethodName    = "/reportpb.ReportService/SendOnlineUsersReport"
+	ReportService_SendCombinedReport_FullMethodName       = "/reportpb.ReportService/SendCombinedReport"
+	ReportService_StreamReports_FullMethodName            = "/reportpb.ReportService/StreamReports"
+	ReportService_SendBackupReport_FullMethodName         = "/reportpb.ReportService/SendBackupReport"
+	ReportService_SubscribeCommands_FullMethodName        = "/reportpb.ReportService/SubscribeCommands"
+	ReportService_SendCommandResult_FullMethodName        = "/reportpb.ReportService/SendCommandResult"
+	ReportService_SubscribeProvisioning_FullMethodName    = "/reportpb.ReportService/SubscribeProvisioning"
+	ReportService_SendProvisioningResult_FullMethodName   = "/reportpb.ReportService/SendProvisioningResult"
+	ReportService_SendShutdownNotice_FullMethodName       = "/reportpb.ReportService/SendShutdownNotice"
+	ReportService_SendCrashReport_FullMethodName          = "/reportpb.ReportService/SendCrashReport"
+	ReportService_Heartbeat_FullMethodName                = "/reportpb.ReportService/Heartbeat"
+	ReportService_SendNetworkQualityReport_FullMethodName = "/reportpb.ReportService/SendNetworkQualityReport"
+	ReportService_SendAccessSummary_FullMethodName        = "/reportpb.ReportService/SendAccessSummary"
+	ReportService_SendCertRenewalReport_FullMethodName    = "/reportpb.ReportService/SendCertRenewalReport"
+	ReportService_SendPanelRestartReport_FullMethodName   = "/reportpb.ReportService/SendPanelRestartReport"
+	ReportService_SendStatusBatch_FullMethodName          = "/reportpb.ReportService/SendStatusBatch"
 )
 
 // ReportServiceClient is the client API for ReportService service.
@@ -36,6 +51,51 @@ type ReportServiceClient interface {
 	SendSubscriptionReport(ctx context.Context, in *SubscriptionReportRequest, opts ...grpc.CallOption) (*ReportResponse, error)
 	// SendOnlineUsersReport sends online users data to xhub
 	SendOnlineUsersReport(ctx context.Context, in *OnlineUsersReportRequest, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SendCombinedReport sends the status, online users and optionally subscriptions in one
+	// request. Used only when xhub advertises the "combined-report" capability.
+	SendCombinedReport(ctx context.Context, in *CombinedReportRequest, opts ...grpc.CallOption) (*ReportResponse, error)
+	// StreamReports carries status reports over one long-lived stream (report_stream). xhub
+	// acknowledges every report and may ask the agent to slow down.
+	StreamReports(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[StreamReportRequest, StreamReportAck], error)
+	// SendBackupReport sends a snapshot of the 3x-ui inbound configuration whenever it changes
+	// (inbound_backup), so xhub can restore the panel after a disaster
+	SendBackupReport(ctx context.Context, in *BackupReportRequest, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SubscribeCommands delivers the commands xhub issues to this agent (command_channel). The
+	// agent keeps the stream open, runs the allowed commands one at a time and reports each
+	// outcome with SendCommandResult.
+	SubscribeCommands(ctx context.Context, in *CommandSubscription, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Command], error)
+	// SendCommandResult reports the outcome of a command received on SubscribeCommands
+	SendCommandResult(ctx context.Context, in *CommandResult, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SubscribeProvisioning delivers the 3x-ui client (user) changes xhub pushes to this agent
+	// (provisioning_channel). The agent applies them one at a time and reports each outcome with
+	// SendProvisioningResult; a request repeating an applied idempotency key is not applied again.
+	SubscribeProvisioning(ctx context.Context, in *ProvisioningSubscription, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProvisioningRequest], error)
+	// SendProvisioningResult reports the outcome of a request received on SubscribeProvisioning
+	SendProvisioningResult(ctx context.Context, in *ProvisioningResult, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SendShutdownNotice tells xhub the agent is going offline on purpose, after the queued
+	// reports were delivered, so the node is not flagged as failed
+	SendShutdownNotice(ctx context.Context, in *ShutdownNotice, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SendCrashReport tells xhub the previous agent run ended with a panic, sent on the next
+	// start from the crash dump written before the process exited
+	SendCrashReport(ctx context.Context, in *CrashReport, opts ...grpc.CallOption) (*ReportResponse, error)
+	// Heartbeat tells xhub the agent is alive, every few seconds and independently of the
+	// status collection, so a broken 3x-ui panel is not mistaken for a dead agent
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SendNetworkQualityReport sends the latency, jitter, loss and optional throughput measured
+	// by a network probe run, on the network_probe_interval schedule or on a network_probe command
+	SendNetworkQualityReport(ctx context.Context, in *NetworkQualityReport, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SendAccessSummary sends the per-user connection counts and top destinations found in the
+	// Xray access log during an access_summary_interval, for abuse and policy analysis
+	SendAccessSummary(ctx context.Context, in *AccessSummary, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SendCertRenewalReport sends the outcome of a certificate renewal, started because a
+	// certificate was about to expire or by a cert_renew command
+	SendCertRenewalReport(ctx context.Context, in *CertRenewalReport, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SendPanelRestartReport tells xhub the panel watchdog restarted the 3x-ui service after
+	// its status requests failed panel_watchdog_failures times in a row
+	SendPanelRestartReport(ctx context.Context, in *PanelRestartReport, opts ...grpc.CallOption) (*ReportResponse, error)
+	// SendStatusBatch sends the status samples collected every status interval since the last
+	// batch (report_batch_size), giving xhub fine-grained time series with fewer RPCs
+	SendStatusBatch(ctx context.Context, in *StatusBatchRequest, opts ...grpc.CallOption) (*ReportResponse, error)
 }
 
 type reportServiceClient struct {
@@ -76,6 +136,177 @@ func (c *reportServiceClient) SendOnlineUsersReport(ctx context.Context, in *Onl
 	return out, nil
 }
 
+func (c *reportServiceClient) SendCombinedReport(ctx context.Context, in *CombinedReportRequest, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendCombinedReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) StreamReports(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[StreamReportRequest, StreamReportAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ReportService_ServiceDesc.Streams[0], ReportService_StreamReports_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamReportRequest, StreamReportAck]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReportService_StreamReportsClient = grpc.BidiStreamingClient[StreamReportRequest, StreamReportAck]
+
+func (c *reportServiceClient) SendBackupReport(ctx context.Context, in *BackupReportRequest, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendBackupReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) SubscribeCommands(ctx context.Context, in *CommandSubscription, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Command], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ReportService_ServiceDesc.Streams[1], ReportService_SubscribeCommands_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CommandSubscription, Command]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReportService_SubscribeCommandsClient = grpc.ServerStreamingClient[Command]
+
+func (c *reportServiceClient) SendCommandResult(ctx context.Context, in *CommandResult, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendCommandResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) SubscribeProvisioning(ctx context.Context, in *ProvisioningSubscription, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProvisioningRequest], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ReportService_ServiceDesc.Streams[2], ReportService_SubscribeProvisioning_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProvisioningSubscription, ProvisioningRequest]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReportService_SubscribeProvisioningClient = grpc.ServerStreamingClient[ProvisioningRequest]
+
+func (c *reportServiceClient) SendProvisioningResult(ctx context.Context, in *ProvisioningResult, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendProvisioningResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) SendShutdownNotice(ctx context.Context, in *ShutdownNotice, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendShutdownNotice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) SendCrashReport(ctx context.Context, in *CrashReport, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendCrashReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_Heartbeat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) SendNetworkQualityReport(ctx context.Context, in *NetworkQualityReport, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendNetworkQualityReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) SendAccessSummary(ctx context.Context, in *AccessSummary, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendAccessSummary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) SendCertRenewalReport(ctx context.Context, in *CertRenewalReport, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendCertRenewalReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) SendPanelRestartReport(ctx context.Context, in *PanelRestartReport, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendPanelRestartReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) SendStatusBatch(ctx context.Context, in *StatusBatchRequest, opts ...grpc.CallOption) (*ReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, ReportService_SendStatusBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ReportServiceServer is the server API for ReportService service.
 // All implementations must embed UnimplementedReportServiceServer
 // for forward compatibility.
@@ -88,6 +319,51 @@ type ReportServiceServer interface {
 	SendSubscriptionReport(context.Context, *SubscriptionReportRequest) (*ReportResponse, error)
 	// SendOnlineUsersReport sends online users data to xhub
 	SendOnlineUsersReport(context.Context, *OnlineUsersReportRequest) (*ReportResponse, error)
+	// SendCombinedReport sends the status, online users and optionally subscriptions in one
+	// request. Used only when xhub advertises the "combined-report" capability.
+	SendCombinedReport(context.Context, *CombinedReportRequest) (*ReportResponse, error)
+	// StreamReports carries status reports over one long-lived stream (report_stream). xhub
+	// acknowledges every report and may ask the agent to slow down.
+	StreamReports(grpc.BidiStreamingServer[StreamReportRequest, StreamReportAck]) error
+	// SendBackupReport sends a snapshot of the 3x-ui inbound configuration whenever it changes
+	// (inbound_backup), so xhub can restore the panel after a disaster
+	SendBackupReport(context.Context, *BackupReportRequest) (*ReportResponse, error)
+	// SubscribeCommands delivers the commands xhub issues to this agent (command_channel). The
+	// agent keeps the stream open, runs the allowed commands one at a time and reports each
+	// outcome with SendCommandResult.
+	SubscribeCommands(*CommandSubscription, grpc.ServerStreamingServer[Command]) error
+	// SendCommandResult reports the outcome of a command received on SubscribeCommands
+	SendCommandResult(context.Context, *CommandResult) (*ReportResponse, error)
+	// SubscribeProvisioning delivers the 3x-ui client (user) changes xhub pushes to this agent
+	// (provisioning_channel). The agent applies them one at a time and reports each outcome with
+	// SendProvisioningResult; a request repeating an applied idempotency key is not applied again.
+	SubscribeProvisioning(*ProvisioningSubscription, grpc.ServerStreamingServer[ProvisioningRequest]) error
+	// SendProvisioningResult reports the outcome of a request received on SubscribeProvisioning
+	SendProvisioningResult(context.Context, *ProvisioningResult) (*ReportResponse, error)
+	// SendShutdownNotice tells xhub the agent is going offline on purpose, after the queued
+	// reports were delivered, so the node is not flagged as failed
+	SendShutdownNotice(context.Context, *ShutdownNotice) (*ReportResponse, error)
+	// SendCrashReport tells xhub the previous agent run ended with a panic, sent on the next
+	// start from the crash dump written before the process exited
+	SendCrashReport(context.Context, *CrashReport) (*ReportResponse, error)
+	// Heartbeat tells xhub the agent is alive, every few seconds and independently of the
+	// status collection, so a broken 3x-ui panel is not mistaken for a dead agent
+	Heartbeat(context.Context, *HeartbeatRequest) (*ReportResponse, error)
+	// SendNetworkQualityReport sends the latency, jitter, loss and optional throughput measured
+	// by a network probe run, on the network_probe_interval schedule or on a network_probe command
+	SendNetworkQualityReport(context.Context, *NetworkQualityReport) (*ReportResponse, error)
+	// SendAccessSummary sends the per-user connection counts and top destinations found in the
+	// Xray access log during an access_summary_interval, for abuse and policy analysis
+	SendAccessSummary(context.Context, *AccessSummary) (*ReportResponse, error)
+	// SendCertRenewalReport sends the outcome of a certificate renewal, started because a
+	// certificate was about to expire or by a cert_renew command
+	SendCertRenewalReport(context.Context, *CertRenewalReport) (*ReportResponse, error)
+	// SendPanelRestartReport tells xhub the panel watchdog restarted the 3x-ui service after
+	// its status requests failed panel_watchdog_failures times in a row
+	SendPanelRestartReport(context.Context, *PanelRestartReport) (*ReportResponse, error)
+	// SendStatusBatch sends the status samples collected every status interval since the last
+	// batch (report_batch_size), giving xhub fine-grained time series with fewer RPCs
+	SendStatusBatch(context.Context, *StatusBatchRequest) (*ReportResponse, error)
 	mustEmbedUnimplementedReportServiceServer()
 }
 
@@ -107,6 +383,51 @@ func (UnimplementedReportServiceServer) SendSubscriptionReport(context.Context,
 func (UnimplementedReportServiceServer) SendOnlineUsersReport(context.Context, *OnlineUsersReportRequest) (*ReportResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendOnlineUsersReport not implemented")
 }
+func (UnimplementedReportServiceServer) SendCombinedReport(context.Context, *CombinedReportRequest) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendCombinedReport not implemented")
+}
+func (UnimplementedReportServiceServer) StreamReports(grpc.BidiStreamingServer[StreamReportRequest, StreamReportAck]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamReports not implemented")
+}
+func (UnimplementedReportServiceServer) SendBackupReport(context.Context, *BackupReportRequest) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendBackupReport not implemented")
+}
+func (UnimplementedReportServiceServer) SubscribeCommands(*CommandSubscription, grpc.ServerStreamingServer[Command]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeCommands not implemented")
+}
+func (UnimplementedReportServiceServer) SendCommandResult(context.Context, *CommandResult) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendCommandResult not implemented")
+}
+func (UnimplementedReportServiceServer) SubscribeProvisioning(*ProvisioningSubscription, grpc.ServerStreamingServer[ProvisioningRequest]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeProvisioning not implemented")
+}
+func (UnimplementedReportServiceServer) SendProvisioningResult(context.Context, *ProvisioningResult) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendProvisioningResult not implemented")
+}
+func (UnimplementedReportServiceServer) SendShutdownNotice(context.Context, *ShutdownNotice) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendShutdownNotice not implemented")
+}
+func (UnimplementedReportServiceServer) SendCrashReport(context.Context, *CrashReport) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendCrashReport not implemented")
+}
+func (UnimplementedReportServiceServer) Heartbeat(context.Context, *HeartbeatRequest) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedReportServiceServer) SendNetworkQualityReport(context.Context, *NetworkQualityReport) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendNetworkQualityReport not implemented")
+}
+func (UnimplementedReportServiceServer) SendAccessSummary(context.Context, *AccessSummary) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendAccessSummary not implemented")
+}
+func (UnimplementedReportServiceServer) SendCertRenewalReport(context.Context, *CertRenewalReport) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendCertRenewalReport not implemented")
+}
+func (UnimplementedReportServiceServer) SendPanelRestartReport(context.Context, *PanelRestartReport) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendPanelRestartReport not implemented")
+}
+func (UnimplementedReportServiceServer) SendStatusBatch(context.Context, *StatusBatchRequest) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendStatusBatch not implemented")
+}
 func (UnimplementedReportServiceServer) mustEmbedUnimplementedReportServiceServer() {}
 func (UnimplementedReportServiceServer) testEmbeddedByValue()                       {}
 
@@ -182,6 +503,251 @@ func _ReportService_SendOnlineUsersReport_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ReportService_SendCombinedReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CombinedReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendCombinedReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendCombinedReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendCombinedReport(ctx, req.(*CombinedReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_StreamReports_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ReportServiceServer).StreamReports(&grpc.GenericServerStream[StreamReportRequest, StreamReportAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReportService_StreamReportsServer = grpc.BidiStreamingServer[StreamReportRequest, StreamReportAck]
+
+func _ReportService_SendBackupReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BackupReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendBackupReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendBackupReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendBackupReport(ctx, req.(*BackupReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_SubscribeCommands_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CommandSubscription)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReportServiceServer).SubscribeCommands(m, &grpc.GenericServerStream[CommandSubscription, Command]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReportService_SubscribeCommandsServer = grpc.ServerStreamingServer[Command]
+
+func _ReportService_SendCommandResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandResult)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendCommandResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendCommandResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendCommandResult(ctx, req.(*CommandResult))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_SubscribeProvisioning_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProvisioningSubscription)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReportServiceServer).SubscribeProvisioning(m, &grpc.GenericServerStream[ProvisioningSubscription, ProvisioningRequest]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReportService_SubscribeProvisioningServer = grpc.ServerStreamingServer[ProvisioningRequest]
+
+func _ReportService_SendProvisioningResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProvisioningResult)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendProvisioningResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendProvisioningResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendProvisioningResult(ctx, req.(*ProvisioningResult))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_SendShutdownNotice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShutdownNotice)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendShutdownNotice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendShutdownNotice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendShutdownNotice(ctx, req.(*ShutdownNotice))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_SendCrashReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CrashReport)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendCrashReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendCrashReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendCrashReport(ctx, req.(*CrashReport))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_SendNetworkQualityReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NetworkQualityReport)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendNetworkQualityReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendNetworkQualityReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendNetworkQualityReport(ctx, req.(*NetworkQualityReport))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_SendAccessSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccessSummary)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendAccessSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendAccessSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendAccessSummary(ctx, req.(*AccessSummary))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_SendCertRenewalReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertRenewalReport)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendCertRenewalReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendCertRenewalReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendCertRenewalReport(ctx, req.(*CertRenewalReport))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_SendPanelRestartReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PanelRestartReport)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendPanelRestartReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendPanelRestartReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendPanelRestartReport(ctx, req.(*PanelRestartReport))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_SendStatusBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).SendStatusBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_SendStatusBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).SendStatusBatch(ctx, req.(*StatusBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ReportService_ServiceDesc is the grpc.ServiceDesc for ReportService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -201,7 +767,72 @@ var ReportService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendOnlineUsersReport",
 			Handler:    _ReportService_SendOnlineUsersReport_Handler,
 		},
+		{
+			MethodName: "SendCombinedReport",
+			Handler:    _ReportService_SendCombinedReport_Handler,
+		},
+		{
+			MethodName: "SendBackupReport",
+			Handler:    _ReportService_SendBackupReport_Handler,
+		},
+		{
+			MethodName: "SendCommandResult",
+			Handler:    _ReportService_SendCommandResult_Handler,
+		},
+		{
+			MethodName: "SendProvisioningResult",
+			Handler:    _ReportService_SendProvisioningResult_Handler,
+		},
+		{
+			MethodName: "SendShutdownNotice",
+			Handler:    _ReportService_SendShutdownNotice_Handler,
+		},
+		{
+			MethodName: "SendCrashReport",
+			Handler:    _ReportService_SendCrashReport_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _ReportService_Heartbeat_Handler,
+		},
+		{
+			MethodName: "SendNetworkQualityReport",
+			Handler:    _ReportService_SendNetworkQualityReport_Handler,
+		},
+		{
+			MethodName: "SendAccessSummary",
+			Handler:    _ReportService_SendAccessSummary_Handler,
+		},
+		{
+			MethodName: "SendCertRenewalReport",
+			Handler:    _ReportService_SendCertRenewalReport_Handler,
+		},
+		{
+			MethodName: "SendPanelRestartReport",
+			Handler:    _ReportService_SendPanelRestartReport_Handler,
+		},
+		{
+			MethodName: "SendStatusBatch",
+			Handler:    _ReportService_SendStatusBatch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamReports",
+			Handler:       _ReportService_StreamReports_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SubscribeCommands",
+			Handler:       _ReportService_SubscribeCommands_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeProvisioning",
+			Handler:       _ReportService_SubscribeProvisioning_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "report.proto",
 }